@@ -0,0 +1,244 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"testing"
+	"time"
+
+	"OwlWhisper/internal/core"
+
+	"github.com/libp2p/go-libp2p"
+	dht "github.com/libp2p/go-libp2p-kad-dht"
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/core/protocol"
+)
+
+// benchProtocolID - протокол для замера пропускной способности сырых потоков
+const benchProtocolID = protocol.ID("/owl-whisper/bench/1.0.0")
+
+func TestMain(m *testing.M) {
+	flag.Parse()
+	// Узлы очень активно логируют, что искажает результаты
+	if !testing.Verbose() {
+		log.SetOutput(io.Discard)
+	}
+	os.Exit(m.Run())
+}
+
+// newBenchNodes создает count соединенных по цепочке узлов
+func newBenchNodes(b *testing.B, ctx context.Context, count int) []*core.Node {
+	b.Helper()
+
+	nodes := make([]*core.Node, 0, count)
+	for i := 0; i < count; i++ {
+		// Слушаем только TCP на loopback: результаты не зависят от сети и NAT
+		node, err := core.NewNode(ctx, core.DefaultNodeConfig(),
+			libp2p.ListenAddrStrings("/ip4/127.0.0.1/tcp/0"),
+		)
+		if err != nil {
+			b.Fatalf("не удалось создать узел: %v", err)
+		}
+		nodes = append(nodes, node)
+	}
+
+	for i := 1; i < count; i++ {
+		prev := nodes[i-1].GetHost()
+		cur := nodes[i].GetHost()
+		if err := prev.Connect(ctx, peer.AddrInfo{ID: cur.ID(), Addrs: cur.Addrs()}); err != nil {
+			b.Fatalf("не удалось соединить узлы: %v", err)
+		}
+	}
+
+	return nodes
+}
+
+// closeBenchNodes останавливает все узлы бенчмарка
+func closeBenchNodes(nodes []*core.Node) {
+	for _, node := range nodes {
+		node.Close()
+	}
+}
+
+// BenchmarkMessageRoundTrip меряет задержку "сообщение - эхо-ответ" между двумя узлами
+func BenchmarkMessageRoundTrip(b *testing.B) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	nodes := newBenchNodes(b, ctx, 2)
+	defer closeBenchNodes(nodes)
+	sender, echo := nodes[0], nodes[1]
+
+	echo.SetMessageHandler(func(from peer.ID, message []byte) {
+		echo.SendMessage(from, string(message))
+	})
+	replies := make(chan struct{}, 1)
+	sender.SetMessageHandler(func(peer.ID, []byte) {
+		replies <- struct{}{}
+	})
+
+	target := echo.GetHost().ID()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := sender.SendMessage(target, "ping"); err != nil {
+			b.Fatalf("не удалось отправить сообщение: %v", err)
+		}
+		select {
+		case <-replies:
+		case <-time.After(10 * time.Second):
+			b.Fatal("не дождались ответа")
+		}
+	}
+}
+
+// BenchmarkMessageThroughput меряет количество сообщений в секунду через обработчик потоков
+func BenchmarkMessageThroughput(b *testing.B) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	nodes := newBenchNodes(b, ctx, 2)
+	defer closeBenchNodes(nodes)
+	sender, receiver := nodes[0], nodes[1]
+
+	received := make(chan struct{}, 1024)
+	receiver.SetMessageHandler(func(peer.ID, []byte) {
+		received <- struct{}{}
+	})
+
+	target := receiver.GetHost().ID()
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	go func() {
+		for i := 0; i < b.N; i++ {
+			if err := sender.SendMessage(target, "benchmark message"); err != nil {
+				return
+			}
+		}
+	}()
+
+	for i := 0; i < b.N; i++ {
+		select {
+		case <-received:
+		case <-time.After(10 * time.Second):
+			b.Fatalf("получено только %d из %d сообщений", i, b.N)
+		}
+	}
+}
+
+// BenchmarkStreamThroughput меряет пропускную способность сырого потока при
+// разном размере окна записи
+func BenchmarkStreamThroughput(b *testing.B) {
+	for _, window := range []int{4 << 10, 16 << 10, 64 << 10, 256 << 10} {
+		b.Run(fmt.Sprintf("window=%dKiB", window>>10), func(b *testing.B) {
+			benchStreamThroughput(b, window)
+		})
+	}
+}
+
+// benchStreamThroughput меряет пропускную способность потока при записи блоками window байт
+func benchStreamThroughput(b *testing.B, window int) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	nodes := newBenchNodes(b, ctx, 2)
+	defer closeBenchNodes(nodes)
+	sender, receiver := nodes[0].GetHost(), nodes[1].GetHost()
+
+	done := make(chan int64, 1)
+	receiver.SetStreamHandler(benchProtocolID, func(s network.Stream) {
+		defer s.Close()
+		n, _ := io.Copy(io.Discard, s)
+		done <- n
+	})
+
+	stream, err := sender.NewStream(ctx, receiver.ID(), benchProtocolID)
+	if err != nil {
+		b.Fatalf("не удалось открыть поток: %v", err)
+	}
+
+	chunk := make([]byte, window)
+	b.SetBytes(int64(window))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := stream.Write(chunk); err != nil {
+			b.Fatalf("ошибка записи в поток: %v", err)
+		}
+	}
+	stream.CloseWrite()
+
+	select {
+	case n := <-done:
+		if n != int64(window)*int64(b.N) {
+			b.Fatalf("получено %d байт вместо %d", n, int64(window)*int64(b.N))
+		}
+	case <-time.After(30 * time.Second):
+		b.Fatal("не дождались окончания передачи")
+	}
+	b.StopTimer()
+	stream.Close()
+}
+
+// BenchmarkDHTFindPeer меряет время поиска пира через DHT в локальной цепочке узлов
+func BenchmarkDHTFindPeer(b *testing.B) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	const chainLength = 5
+	nodes := newBenchNodes(b, ctx, chainLength)
+	defer closeBenchNodes(nodes)
+
+	dhts := make([]*dht.IpfsDHT, 0, chainLength)
+	for _, node := range nodes {
+		kadDHT, err := dht.New(ctx, node.GetHost(),
+			dht.Mode(dht.ModeServer),
+			dht.ProtocolPrefix("/owl-whisper-bench"),
+			dht.DisableAutoRefresh(),
+		)
+		if err != nil {
+			b.Fatalf("не удалось создать DHT: %v", err)
+		}
+		defer kadDHT.Close()
+		dhts = append(dhts, kadDHT)
+	}
+
+	// Переподключаем цепочку, чтобы DHT заполнили таблицы маршрутизации
+	for i := 1; i < chainLength; i++ {
+		prev := nodes[i-1].GetHost()
+		prev.Network().ClosePeer(nodes[i].GetHost().ID())
+		cur := nodes[i].GetHost()
+		if err := prev.Connect(ctx, peer.AddrInfo{ID: cur.ID(), Addrs: cur.Addrs()}); err != nil {
+			b.Fatalf("не удалось соединить узлы: %v", err)
+		}
+	}
+	time.Sleep(500 * time.Millisecond)
+
+	seeker := dhts[0]
+	seekerHost := nodes[0].GetHost()
+	target := nodes[chainLength-1].GetHost().ID()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		lookupCtx, lookupCancel := context.WithTimeout(ctx, 10*time.Second)
+		_, err := seeker.FindPeer(lookupCtx, target)
+		lookupCancel()
+		if err != nil {
+			b.Fatalf("не удалось найти пира: %v", err)
+		}
+
+		// Сбрасываем найденное, чтобы следующий поиск не был локальным
+		b.StopTimer()
+		seekerHost.Network().ClosePeer(target)
+		seekerHost.Peerstore().ClearAddrs(target)
+		seeker.RoutingTable().RemovePeer(target)
+		b.StartTimer()
+	}
+}
@@ -0,0 +1,184 @@
+// owlbench собирает результаты бенчмарков OwlWhisper в JSON отчет для
+// отслеживания регрессий. Сами бенчмарки живут в bench_test.go и запускаются
+// через go test, поэтому пакет testing не попадает в собранную программу:
+//
+//	go test -run '^$' -bench . -benchmem -json ./cmd/owlbench | owlbench -o report.json
+//
+// Понимается и обычный текстовый вывод go test -bench
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// BenchResult - результат одного бенчмарка в формате для отслеживания регрессий
+type BenchResult struct {
+	Name        string  `json:"name"`
+	Iterations  int     `json:"iterations"`
+	NsPerOp     int64   `json:"ns_per_op"`
+	OpsPerSec   float64 `json:"ops_per_sec"`
+	BytesPerSec float64 `json:"bytes_per_sec,omitempty"`
+	AllocsPerOp int64   `json:"allocs_per_op"`
+	BytesPerOp  int64   `json:"alloc_bytes_per_op"`
+}
+
+// BenchReport - полный отчет прогона бенчмарков
+type BenchReport struct {
+	Timestamp time.Time     `json:"timestamp"`
+	GoVersion string        `json:"go_version"`
+	GOOS      string        `json:"goos"`
+	GOARCH    string        `json:"goarch"`
+	NumCPU    int           `json:"num_cpu"`
+	Results   []BenchResult `json:"results"`
+}
+
+// testEvent - событие вывода go test -json (test2json)
+type testEvent struct {
+	Action string `json:"Action"`
+	Output string `json:"Output"`
+}
+
+func main() {
+	output := flag.String("o", "", "Файл для JSON отчета (по умолчанию stdout)")
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "Использование: go test -run '^$' -bench . -benchmem -json ./cmd/owlbench | owlbench [-o отчет.json] [вывод go test]")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	input := io.Reader(os.Stdin)
+	if flag.NArg() > 0 {
+		file, err := os.Open(flag.Arg(0))
+		if err != nil {
+			log.Fatalf("❌ %v", err)
+		}
+		defer file.Close()
+		input = file
+	}
+
+	report, err := parseBenchOutput(input)
+	if err != nil {
+		log.Fatalf("❌ Не удалось прочитать вывод go test: %v", err)
+	}
+	if len(report.Results) == 0 {
+		log.Fatal("❌ Во входных данных нет результатов бенчмарков")
+	}
+
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		log.Fatalf("❌ Не удалось сериализовать отчет: %v", err)
+	}
+
+	if *output == "" {
+		fmt.Println(string(data))
+		return
+	}
+	if err := os.WriteFile(*output, data, 0644); err != nil {
+		log.Fatalf("❌ Не удалось записать отчет: %v", err)
+	}
+	log.Printf("📄 Отчет сохранен в %s (бенчмарков: %d)", *output, len(report.Results))
+}
+
+// parseBenchOutput собирает отчет из вывода go test -bench. В режиме -json
+// строка результата может прийти несколькими событиями, поэтому вывод
+// сначала склеивается обратно в текст
+func parseBenchOutput(r io.Reader) (BenchReport, error) {
+	report := BenchReport{
+		Timestamp: time.Now().UTC(),
+		GoVersion: runtime.Version(),
+		GOOS:      runtime.GOOS,
+		GOARCH:    runtime.GOARCH,
+		NumCPU:    runtime.NumCPU(),
+	}
+
+	var text strings.Builder
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		var event testEvent
+		if strings.HasPrefix(line, "{") && json.Unmarshal([]byte(line), &event) == nil {
+			if event.Action == "output" {
+				text.WriteString(event.Output)
+			}
+			continue
+		}
+		text.WriteString(line + "\n")
+	}
+	if err := scanner.Err(); err != nil {
+		return report, err
+	}
+
+	for _, line := range strings.Split(text.String(), "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(line, "goos: "):
+			report.GOOS = strings.TrimPrefix(line, "goos: ")
+		case strings.HasPrefix(line, "goarch: "):
+			report.GOARCH = strings.TrimPrefix(line, "goarch: ")
+		case strings.HasPrefix(line, "Benchmark"):
+			if result, procs, ok := parseBenchLine(line); ok {
+				report.Results = append(report.Results, result)
+				report.NumCPU = procs
+			}
+		}
+	}
+	return report, nil
+}
+
+// parseBenchLine разбирает строку результата вида
+// "BenchmarkName-8  1000  1234 ns/op  56.7 MB/s  89 B/op  2 allocs/op".
+// Строки без результата (родительские бенчмарки, сообщения) пропускаются
+func parseBenchLine(line string) (BenchResult, int, bool) {
+	fields := strings.Fields(line)
+	if len(fields) < 4 || len(fields)%2 != 0 {
+		return BenchResult{}, 0, false
+	}
+	iterations, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return BenchResult{}, 0, false
+	}
+
+	// Суффикс -N - значение GOMAXPROCS при прогоне; при 1 его нет
+	name, procs := strings.TrimPrefix(fields[0], "Benchmark"), 1
+	if i := strings.LastIndex(name, "-"); i > 0 {
+		if n, err := strconv.Atoi(name[i+1:]); err == nil {
+			name, procs = name[:i], n
+		}
+	}
+
+	result := BenchResult{Name: name, Iterations: iterations}
+	measured := false
+	for i := 2; i < len(fields); i += 2 {
+		value, err := strconv.ParseFloat(fields[i], 64)
+		if err != nil {
+			return BenchResult{}, 0, false
+		}
+		switch fields[i+1] {
+		case "ns/op":
+			result.NsPerOp = int64(math.Round(value))
+			if value > 0 {
+				result.OpsPerSec = 1e9 / value
+			}
+			measured = true
+		case "MB/s":
+			result.BytesPerSec = value * 1e6
+		case "B/op":
+			result.BytesPerOp = int64(value)
+		case "allocs/op":
+			result.AllocsPerOp = int64(value)
+		}
+	}
+	return result, procs, measured
+}
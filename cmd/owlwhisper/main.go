@@ -5,9 +5,18 @@ import (
 	"os"
 
 	"OwlWhisper/internal/app"
+	"OwlWhisper/internal/service"
 )
 
 func main() {
+	// owlwhisper service <install|uninstall|run> - работа фоновой службой ОС
+	if len(os.Args) > 1 && os.Args[1] == "service" {
+		if err := service.Command(os.Args[2:], newDaemon); err != nil {
+			log.Fatalf("❌ %v", err)
+		}
+		return
+	}
+
 	// Создаем приложение
 	application, err := app.NewApp()
 	if err != nil {
@@ -20,3 +29,12 @@ func main() {
 		os.Exit(1)
 	}
 }
+
+// newDaemon создает приложение для запуска службой
+func newDaemon() (service.Daemon, error) {
+	application, err := app.NewApp()
+	if err != nil {
+		return nil, err
+	}
+	return application, nil
+}
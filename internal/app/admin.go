@@ -0,0 +1,130 @@
+package app
+
+import (
+	"fmt"
+	"log"
+	"slices"
+
+	"OwlWhisper/internal/core"
+	"OwlWhisper/pkg/config"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+// adminStats - ответ на команду stats
+type adminStats struct {
+	Version             string                  `json:"version"`
+	Status              string                  `json:"status"`
+	Uptime              string                  `json:"uptime"`
+	Peers               int                     `json:"peers"`
+	Discovery           core.DiscoveryReadiness `json:"discovery"`
+	Events              core.EventQueueStats    `json:"events"`
+	Relays              []core.RelayStatus      `json:"relays,omitempty"`
+	Runtime             core.RuntimeStats       `json:"runtime"`
+	RejectedConnections uint64                  `json:"rejected_connections"`
+	Banned              int                     `json:"banned"`
+}
+
+// adminReload - ответ на команду reload
+type adminReload struct {
+	RestartRequired bool `json:"restart_required"`
+}
+
+// applyAccessLists передает узлу администраторов и заблокированных пиров из
+// настроек, пропуская некорректные PeerID
+func applyAccessLists(node *core.Node, cfg *config.Config) {
+	node.SetAdminPeers(parsePeerIDs(cfg.Daemon.AdminPeers, "admin_peers"))
+	node.SetBannedPeers(parsePeerIDs(cfg.Network.BannedPeers, "banned_peers"))
+}
+
+// parsePeerIDs разбирает список PeerID, пропуская некорректные
+func parsePeerIDs(ids []string, option string) []peer.ID {
+	var result []peer.ID
+	for _, s := range ids {
+		id, err := peer.Decode(s)
+		if err != nil {
+			log.Printf("⚠️ Некорректный PeerID в %s: %s", option, s)
+			continue
+		}
+		result = append(result, id)
+	}
+	return result
+}
+
+// handleAdmin выполняет команду администратора узла (см. core.ADMIN_PROTOCOL_ID)
+func (app *App) handleAdmin(from peer.ID, request core.AdminRequest) (any, error) {
+	switch request.Command {
+	case core.AdminStats:
+		return app.adminStats(), nil
+	case core.AdminRotateLogs:
+		if app.logs == nil {
+			return nil, fmt.Errorf("журнал не пишется в файл (logging.output_file)")
+		}
+		return app.logs.Rotate()
+	case core.AdminBan, core.AdminUnban:
+		if len(request.Args) != 1 {
+			return nil, fmt.Errorf("использование: %s <PeerID>", request.Command)
+		}
+		id, err := peer.Decode(request.Args[0])
+		if err != nil {
+			return nil, fmt.Errorf("некорректный PeerID: %w", err)
+		}
+		if id == from {
+			return nil, fmt.Errorf("нельзя заблокировать администратора")
+		}
+		return nil, app.setBanned(id, request.Command == core.AdminBan)
+	case core.AdminReload:
+		updated, err := config.LoadConfig("")
+		if err != nil {
+			return nil, fmt.Errorf("не удалось прочитать конфигурацию: %w", err)
+		}
+		restartRequired, err := app.UpdateSettings(updated)
+		if err != nil {
+			return nil, err
+		}
+		return adminReload{RestartRequired: restartRequired}, nil
+	default:
+		return nil, fmt.Errorf("неизвестная команда: %s", request.Command)
+	}
+}
+
+// adminStats собирает состояние узла для администратора
+func (app *App) adminStats() adminStats {
+	report := app.healthReport()
+	return adminStats{
+		Version:             core.Version,
+		Status:              report.Status,
+		Uptime:              report.Uptime,
+		Peers:               len(app.node.GetPeers()),
+		Discovery:           app.discovery.Readiness(),
+		Events:              app.node.EventQueueStats(),
+		Relays:              app.node.Relays(),
+		Runtime:             app.node.GetRuntimeStats(),
+		RejectedConnections: app.node.RejectedConnections(),
+		Banned:              len(app.node.BannedPeers()),
+	}
+}
+
+// setBanned блокирует пира или снимает блокировку и сохраняет список в настройках
+func (app *App) setBanned(id peer.ID, banned bool) error {
+	app.settingsMu.Lock()
+	defer app.settingsMu.Unlock()
+
+	updated := app.config.Clone()
+	list := slices.DeleteFunc(updated.Network.BannedPeers, func(s string) bool { return s == id.String() })
+	if banned {
+		if err := app.node.BanPeer(id); err != nil {
+			return err
+		}
+		list = append(list, id.String())
+	} else {
+		app.node.UnbanPeer(id)
+	}
+	updated.Network.BannedPeers = list
+
+	if err := updated.SaveConfig(""); err != nil {
+		return fmt.Errorf("не удалось сохранить настройки: %w", err)
+	}
+	*app.config = *updated
+	return nil
+}
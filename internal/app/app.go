@@ -6,10 +6,22 @@ import (
 	"log"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"slices"
+	"sync"
+	"sync/atomic"
 	"syscall"
+	"time"
 
+	"OwlWhisper/internal/backup"
 	"OwlWhisper/internal/core"
+	"OwlWhisper/internal/notify"
+	"OwlWhisper/internal/storage"
 	"OwlWhisper/internal/tui"
+	"OwlWhisper/pkg/config"
+	"OwlWhisper/pkg/interfaces"
+
+	"github.com/libp2p/go-libp2p/core/peer"
 )
 
 // App представляет собой основное приложение
@@ -17,50 +29,509 @@ type App struct {
 	node      *core.Node
 	discovery *core.DiscoveryManager
 	tui       *tui.Handler
+	notifier  *notify.Dispatcher
+	messages  *storage.MessageStore
+	contacts  *storage.ContactStore
+	media     *storage.MediaStore
+	outbox    *storage.OutboxStore
+	prefs     *storage.PreferenceStore
+	backups   *backup.Scheduler
+	logs      *logFile
 	ctx       context.Context
 	cancel    context.CancelFunc
+
+	shutdownOnce sync.Once
+	// state и startedAt - для проверок здоровья (см. health.go)
+	state     atomic.Int32
+	startedAt time.Time
+
+	settingsMu sync.Mutex
+	config     *config.Config
 }
 
 // NewApp создает новое приложение
 func NewApp() (*App, error) {
 	ctx, cancel := context.WithCancel(context.Background())
 
+	// Загружаем конфигурацию
+	_, statErr := os.Stat(config.DefaultPath())
+	firstRun := os.IsNotExist(statErr)
+	cfg, err := config.LoadConfig("")
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("не удалось загрузить конфигурацию: %w", err)
+	}
+
+	// Журнал в файл, если он задан в настройках
+	logs, err := openLogFile(cfg.Logging.OutputFile, cfg.Logging.Console)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	// При первом запуске в терминале предлагаем мастер настройки
+	identityPath := filepath.Join(config.DefaultDir(), "identity.key")
+	if firstRun && tui.IsInteractive() {
+		if err := tui.RunSetupWizard(os.Stdin, cfg, identityPath); err != nil {
+			cancel()
+			return nil, fmt.Errorf("ошибка первичной настройки: %w", err)
+		}
+	}
+
+	// Загружаем постоянный ключ личности, чтобы PeerID не менялся
+	privKey, created, err := core.LoadOrCreateIdentity(identityPath)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("не удалось загрузить ключ личности: %w", err)
+	}
+	if created {
+		log.Printf("🔑 Создан новый ключ личности: %s", identityPath)
+	}
+
 	// Создаем узел
-	node, err := core.NewNode(ctx)
+	nodeConfig := nodeConfigFrom(cfg)
+	nodeConfig.PrivateKey = privKey
+	node, err := core.NewNode(ctx, nodeConfig)
 	if err != nil {
 		cancel()
 		return nil, fmt.Errorf("не удалось создать узел: %w", err)
 	}
 
 	// Создаем менеджер обнаружения
-	discovery := core.NewDiscoveryManager(ctx, node.GetHost())
+	discovery := newDiscovery(ctx, node, cfg)
+	node.SetNetworkChangeHandler(func(core.NetworkChanged) {
+		discovery.Refresh()
+	})
+	node.SetPeerResolver(core.NewPeerResolver(node, discovery))
+	discovery.SetSeenPeerCheck(node.SeenPeer)
+	discovery.SetPeerFoundHandler(node.PublishPeerFound)
+	discovery.SetLANPeerHandler(node.PublishLANPeer)
+	if cfg.Privacy.LANNickname {
+		discovery.SetLANNickname(cfg.Profile.Nickname)
+	}
+	node.SetPrewarmEnabled(cfg.Chat.Prewarm)
+	applyAccessLists(node, cfg)
+
+	// Открываем историю сообщений
+	messages, err := storage.NewMessageStore(filepath.Join(config.DefaultDir(), "messages.jsonl"))
+	if err != nil {
+		node.Close()
+		cancel()
+		return nil, fmt.Errorf("не удалось открыть историю сообщений: %w", err)
+	}
+
+	// Счетчики непрочитанных публикуем событиями ядра для всех фронтендов
+	messages.SetUnreadHandler(func(peerID string, unread int) {
+		if id, err := peer.Decode(peerID); err == nil {
+			node.PublishUnreadCount(id, unread)
+		}
+	})
+
+	// Открываем хранилище вложений: одинаковые файлы хранятся один раз
+	media, err := storage.NewMediaStore(filepath.Join(config.DefaultDir(), "media"))
+	if err != nil {
+		node.Close()
+		cancel()
+		return nil, fmt.Errorf("не удалось открыть хранилище вложений: %w", err)
+	}
+	messages.SetMediaRepository(media)
+
+	// Вложения отдаются контактам, которые потеряли свою копию
+	node.SetAttachmentResolver(func(hash string) (string, bool) {
+		return storage.ResolveAttachment(ctx, messages, media, hash)
+	})
+
+	// Открываем контакты
+	contacts, err := storage.NewContactStore(filepath.Join(config.DefaultDir(), "contacts.json"))
+	if err != nil {
+		node.Close()
+		cancel()
+		return nil, fmt.Errorf("не удалось открыть контакты: %w", err)
+	}
+
+	// Открываем очередь отложенных сообщений
+	outbox, err := storage.NewOutboxStore(filepath.Join(config.DefaultDir(), "outbox.json"))
+	if err != nil {
+		node.Close()
+		cancel()
+		return nil, fmt.Errorf("не удалось открыть очередь сообщений: %w", err)
+	}
+
+	// Политика last seen "только контакты" и защита от запросов незнакомцев
+	// проверяют пира по списку контактов
+	node.SetContactLookup(func(id peer.ID) bool {
+		_, err := contacts.GetContact(ctx, id.String())
+		return err == nil
+	})
+
+	// Политики ретрансляции контактов применяются при наборе адресов
+	applyContactRelayModes(ctx, node, contacts)
+
+	// Метки пиров (семья, работа, бот) влияют на приоритет соединений
+	tags, err := storage.NewPeerTagStore(filepath.Join(config.DefaultDir(), "peer_tags.json"))
+	if err == nil {
+		err = node.LoadPeerTags(tags)
+	}
+	if err != nil {
+		node.Close()
+		cancel()
+		return nil, fmt.Errorf("не удалось открыть метки пиров: %w", err)
+	}
+
+	// Метаданные контактов (звук, цвет, заметки) хранятся в ядре, а не в GUI
+	metadata, err := storage.NewContactMetadataStore(filepath.Join(config.DefaultDir(), "contact_metadata.json"))
+	if err == nil {
+		err = node.LoadContactMetadata(metadata)
+	}
+	if err != nil {
+		node.Close()
+		cancel()
+		return nil, fmt.Errorf("не удалось открыть метаданные контактов: %w", err)
+	}
+
+	// Подтверждения личности (владение аккаунтами) отдаются контактам
+	attestations, err := storage.NewAttestationStore(filepath.Join(config.DefaultDir(), "attestations.json"))
+	if err == nil {
+		err = node.LoadAttestations(attestations)
+	}
+	if err != nil {
+		node.Close()
+		cancel()
+		return nil, fmt.Errorf("не удалось открыть подтверждения личности: %w", err)
+	}
+
+	// Хранилище доверия: смены ключей контактов применяются автоматически
+	transitions, err := storage.NewKeyTransitionStore(filepath.Join(config.DefaultDir(), "key_transitions.json"))
+	if err == nil {
+		err = node.LoadKeyTransitions(transitions)
+	}
+	if err != nil {
+		node.Close()
+		cancel()
+		return nil, fmt.Errorf("не удалось открыть хранилище смен ключей: %w", err)
+	}
+	node.SetKeyTransitionHandler(func(t core.KeyTransition) error {
+		return migrateContact(ctx, contacts, t)
+	})
+
+	// Почтовые ящики: записи профиля контактов, свои ящики и роль ящика
+	profiles, err := storage.NewProfileRecordStore(filepath.Join(config.DefaultDir(), "profile_records.json"))
+	if err == nil {
+		err = node.LoadProfileRecords(profiles)
+	}
+	if err != nil {
+		node.Close()
+		cancel()
+		return nil, fmt.Errorf("не удалось открыть записи профиля: %w", err)
+	}
+	if err := node.SetMailboxes(parseAddrInfos(cfg.Mailbox.Mailboxes, "mailboxes")); err != nil {
+		log.Printf("❌ Не удалось опубликовать почтовые ящики: %v", err)
+	}
+	if len(cfg.Mailbox.ServeFor) > 0 {
+		mailbox, err := storage.NewMailboxStore(filepath.Join(config.DefaultDir(), "mailbox.json"))
+		if err != nil {
+			node.Close()
+			cancel()
+			return nil, fmt.Errorf("не удалось открыть почтовый ящик: %w", err)
+		}
+		retention := time.Duration(cfg.Mailbox.RetentionHours) * time.Hour
+		if err := node.ServeMailbox(parsePeerIDs(cfg.Mailbox.ServeFor, "serve_for"), mailbox, retention); err != nil {
+			node.Close()
+			cancel()
+			return nil, fmt.Errorf("не удалось запустить почтовый ящик: %w", err)
+		}
+	}
+
+	// События безопасности сохраняем в журнал аудита с цепочкой хешей
+	audit, err := storage.NewAuditLog(filepath.Join(config.DefaultDir(), "audit.log"))
+	if audit == nil {
+		node.Close()
+		cancel()
+		return nil, fmt.Errorf("не удалось открыть журнал аудита: %w", err)
+	}
+	if err != nil {
+		log.Printf("⚠️ Целостность журнала аудита нарушена: %v", err)
+	}
+	node.SetAuditSink(func(event core.SecurityEvent) error {
+		return audit.Append(event)
+	})
+
+	// Открываем настройки уведомлений диалогов
+	prefs, err := storage.NewPreferenceStore(filepath.Join(config.DefaultDir(), "conversations.json"))
+	if err != nil {
+		node.Close()
+		cancel()
+		return nil, fmt.Errorf("не удалось открыть настройки диалогов: %w", err)
+	}
+	applyConversationShaping(ctx, node, prefs)
 
 	// Создаем TUI обработчик
-	tuiHandler := tui.NewHandler(node)
+	tuiHandler := tui.NewHandler(node, messages, contacts, cfg)
+
+	// Системные уведомления о сообщениях и передачах файлов
+	notifier := notify.NewDispatcher(notify.NewDefault("OwlWhisper", cfg.Notifications.Enabled), tuiHandler.DisplayName)
+	notifier.SetSchedule(notifyScheduleFrom(cfg))
+	notifier.SetPreferences(prefs)
+	tuiHandler.SetNotifier(notifier)
+	tuiHandler.SetPreferences(prefs)
 
 	app := &App{
 		node:      node,
 		discovery: discovery,
 		tui:       tuiHandler,
+		notifier:  notifier,
+		messages:  messages,
+		contacts:  contacts,
+		media:     media,
+		outbox:    outbox,
+		prefs:     prefs,
+		logs:      logs,
 		ctx:       ctx,
 		cancel:    cancel,
+		config:    cfg,
 	}
+	tuiHandler.SetExporter(app.ExportConversation)
+	tuiHandler.SetMediaRepository(media)
+	tuiHandler.SetBackups(app)
+	tuiHandler.SetDiscovery(discovery)
+	tuiHandler.SetCleaner(app.CleanupStorage)
+	tuiHandler.SetKeyRotator(app.RotateIdentity)
+	tuiHandler.SetWiper(app.SecureWipe, WipeConfirmation)
+	node.SetAdminHandler(app.handleAdmin)
 
 	return app, nil
 }
 
+// nodeConfigFrom переносит пользовательскую конфигурацию в параметры узла
+func nodeConfigFrom(cfg *config.Config) core.NodeConfig {
+	nodeConfig := core.DefaultNodeConfig()
+
+	nodeConfig.ListenPort = cfg.Network.ListenPort
+	nodeConfig.EnableNAT = cfg.Network.EnableNAT
+	nodeConfig.EnableHolePunching = cfg.Network.EnableHolePunch
+	nodeConfig.EnableRelay = cfg.Network.EnableRelay
+	nodeConfig.RelayService = slices.Contains(cfg.Daemon.ServerRoles, "relay")
+	nodeConfig.HideIP = cfg.Privacy.HideIP
+	nodeConfig.UserAgent = cfg.Privacy.UserAgent
+	nodeConfig.Stealth = cfg.Privacy.Stealth
+	nodeConfig.StatusMessage = cfg.Profile.Status
+	nodeConfig.TransportPolicy = core.TransportPolicy{
+		Prefer: cfg.Network.PreferTransport,
+		Relay:  core.RelayMode(cfg.Network.RelayMode),
+	}
+	nodeConfig.WebSocketPort = cfg.Network.WebSocketPort
+	nodeConfig.Multipath = cfg.Network.Multipath
+	nodeConfig.PeerCacheSize = cfg.Network.PeerCacheSize
+	nodeConfig.RelayNodes = obfuscateEndpoints(parseAddrInfos(cfg.Network.RelayNodes, "relay_nodes"), cfg)
+	nodeConfig.PinnedRelays = obfuscateEndpoints(parseAddrInfos(cfg.Network.PinnedRelays, "pinned_relays"), cfg)
+
+	if cfg.Transfers.DownloadDir != "" {
+		nodeConfig.Transfers.DownloadDir = cfg.Transfers.DownloadDir
+	}
+	if policy, err := core.ParseLastSeenPolicy(cfg.Privacy.LastSeen); err == nil {
+		nodeConfig.LastSeenPolicy = policy
+	} else {
+		log.Printf("⚠️ %v", err)
+	}
+
+	nodeConfig.ContactRequests.Difficulty = cfg.Privacy.ContactRequestDifficulty
+	nodeConfig.ContactRequests.PerPeerLimit = cfg.Privacy.ContactRequestsPerPeer
+	nodeConfig.ContactRequests.GlobalLimit = cfg.Privacy.ContactRequestsPerHour
+
+	nodeConfig.Transfers.AutoAccept = cfg.Transfers.AutoAccept
+	nodeConfig.Transfers.AutoAcceptMaxSize = cfg.Transfers.AutoAcceptMaxSizeMB << 20
+	nodeConfig.Transfers.AutoAcceptExtensions = cfg.Transfers.AutoAcceptExtensions
+	nodeConfig.Transfers.MaxIncomingSize = cfg.Transfers.MaxIncomingSizeMB << 20
+	nodeConfig.Transfers.BlockedExtensions = cfg.Transfers.BlockedExtensions
+	nodeConfig.Transfers.WarnedExtensions = cfg.Transfers.WarnedExtensions
+	for _, id := range cfg.Transfers.AutoAcceptPeers {
+		peerID, err := peer.Decode(id)
+		if err != nil {
+			log.Printf("⚠️ Некорректный PeerID в auto_accept_peers: %s", id)
+			continue
+		}
+		nodeConfig.Transfers.AutoAcceptPeers = append(nodeConfig.Transfers.AutoAcceptPeers, peerID)
+	}
+
+	return nodeConfig
+}
+
+// applyContactRelayModes передает узлу политики ретрансляции из контактов
+func applyContactRelayModes(ctx context.Context, node *core.Node, contacts *storage.ContactStore) {
+	all, err := contacts.GetAllContacts(ctx)
+	if err != nil {
+		log.Printf("⚠️ Не удалось прочитать контакты: %v", err)
+		return
+	}
+	for _, contact := range all {
+		if contact.RelayMode == "" {
+			continue
+		}
+		id, err := peer.Decode(contact.PeerID)
+		if err != nil {
+			continue
+		}
+		if err := node.SetPeerRelayMode(id, core.RelayMode(contact.RelayMode)); err != nil {
+			log.Printf("⚠️ Контакт %s: %v", contact.Nickname, err)
+		}
+	}
+}
+
+// applyConversationShaping передает узлу защиту трафика и скрытого
+// отправителя из настроек диалогов
+func applyConversationShaping(ctx context.Context, node *core.Node, prefs *storage.PreferenceStore) {
+	all, err := prefs.GetAllPrefs(ctx)
+	if err != nil {
+		log.Printf("⚠️ Не удалось прочитать настройки диалогов: %v", err)
+		return
+	}
+	for _, p := range all {
+		if !p.Padding && p.CoverTraffic == 0 && !p.SealedSender {
+			continue
+		}
+		id, err := peer.Decode(p.PeerID)
+		if err != nil {
+			continue
+		}
+		node.SetSealedSender(id, p.SealedSender)
+		if err := node.SetTrafficShaping(id, shapingFrom(p)); err != nil {
+			log.Printf("⚠️ Диалог %s: %v", id.ShortString(), err)
+		}
+	}
+}
+
+// shapingFrom переносит настройки диалога в защиту трафика узла
+func shapingFrom(p *interfaces.ConversationPrefs) core.TrafficShaping {
+	return core.TrafficShaping{Padding: p.Padding, CoverInterval: p.CoverTraffic}
+}
+
+// parseAddrInfos разбирает адреса пиров вида /ip4/.../p2p/<PeerID>,
+// пропуская некорректные
+func parseAddrInfos(addrs []string, option string) []peer.AddrInfo {
+	var infos []peer.AddrInfo
+	for _, addr := range addrs {
+		info, err := peer.AddrInfoFromString(addr)
+		if err != nil {
+			log.Printf("⚠️ Некорректный адрес в %s: %s", option, addr)
+			continue
+		}
+		infos = append(infos, *info)
+	}
+	return infos
+}
+
+// obfuscateEndpoints маскирует под HTTPS конечные точки, для которых это
+// указано в network.obfuscation
+func obfuscateEndpoints(infos []peer.AddrInfo, cfg *config.Config) []peer.AddrInfo {
+	for i, info := range infos {
+		o, ok := cfg.Network.Obfuscation[info.ID.String()]
+		if !ok {
+			continue
+		}
+		obfuscated, err := core.ObfuscateEndpoint(info, core.Obfuscation{Host: o.Host, Port: o.Port, SNI: o.SNI})
+		if err != nil {
+			log.Printf("⚠️ Маскировка %s: %v", info.ID.ShortString(), err)
+			continue
+		}
+		infos[i] = obfuscated
+	}
+	return infos
+}
+
+// newDiscovery создает менеджер обнаружения: собственный DHT или поиск
+// через доверенного помощника, если он указан в настройках. В режиме
+// скрытия IP поиск в локальной сети выключен: mDNS раскрыл бы адреса узла
+func newDiscovery(ctx context.Context, node *core.Node, cfg *config.Config) *core.DiscoveryManager {
+	discovery := newDiscoveryManager(ctx, node, cfg)
+	if node.HidesIP() {
+		discovery.DisableLAN()
+		log.Println("🕶️ Поиск в локальной сети выключен в режиме скрытия IP")
+	}
+	return discovery
+}
+
+// newDiscoveryManager выбирает между собственным DHT и помощником
+func newDiscoveryManager(ctx context.Context, node *core.Node, cfg *config.Config) *core.DiscoveryManager {
+	if cfg.Network.DHTHelper != "" {
+		helper, err := peer.AddrInfoFromString(cfg.Network.DHTHelper)
+		if err == nil {
+			return core.NewProxyDiscoveryManager(ctx, node.GetHost(), obfuscateEndpoints([]peer.AddrInfo{*helper}, cfg)[0])
+		}
+		log.Printf("⚠️ Некорректный адрес помощника DHT, используется собственный DHT: %v", err)
+	}
+
+	bootstrap := obfuscateEndpoints(parseAddrInfos(cfg.Network.BootstrapNodes, "bootstrap_nodes"), cfg)
+	discovery := core.NewDiscoveryManager(ctx, node.GetHost(), bootstrap...)
+
+	var clients []peer.ID
+	for _, id := range cfg.Network.DHTHelperClients {
+		clientID, err := peer.Decode(id)
+		if err != nil {
+			log.Printf("⚠️ Некорректный PeerID в dht_helper_clients: %s", id)
+			continue
+		}
+		clients = append(clients, clientID)
+	}
+	if len(clients) > 0 {
+		if err := discovery.ServeDHTProxy(clients); err != nil {
+			log.Printf("⚠️ %v", err)
+		}
+	}
+	return discovery
+}
+
+// notifyScheduleFrom переносит настройки режима "не беспокоить" в расписание уведомлений
+func notifyScheduleFrom(cfg *config.Config) notify.Schedule {
+	return notify.Schedule{
+		DoNotDisturb: cfg.Notifications.DoNotDisturb,
+		QuietFrom:    cfg.Notifications.QuietFrom,
+		QuietTo:      cfg.Notifications.QuietTo,
+	}
+}
+
 // Run запускает приложение
 func (app *App) Run() error {
+	app.startedAt = time.Now()
+	if err := app.startHealthServer(app.config.Daemon.HealthListen); err != nil {
+		return err
+	}
+
 	// Запускаем узел
 	if err := app.node.Start(); err != nil {
 		return fmt.Errorf("не удалось запустить узел: %w", err)
 	}
 
+	// Доставляем отложенные сообщения и напоминания
+	app.node.StartOutbox(app.outbox)
+
+	// Проверяем обновления, если пользователь это включил
+	if app.config.Updates.Enabled {
+		app.startUpdateChecks()
+	}
+
+	// Резервные копии по расписанию и по запросу
+	app.setupBackups()
+
+	// Отладочный режим обнаружения утечек для долгих сессий
+	if os.Getenv("OWLWHISPER_DEBUG_LEAKS") != "" {
+		app.node.StartLeakDetector(core.DefaultLeakDetectorConfig())
+	}
+
 	// Запускаем discovery
 	if err := app.discovery.Start(); err != nil {
 		return fmt.Errorf("не удалось запустить discovery: %w", err)
 	}
 
+	// Заранее соединяемся с последними собеседниками
+	go app.prewarmRecent(app.config.Chat.PrewarmRecent)
+	app.state.Store(stateRunning)
+
+	// Под systemd (Type=notify) сообщаем о готовности и включаем сторожевой таймер
+	sdNotify("READY=1\nSTATUS=Узел запущен")
+	if interval := sdWatchdogInterval(); interval > 0 {
+		go app.runWatchdog(interval)
+	}
+
 	// Обрабатываем сигналы для graceful shutdown
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
@@ -73,16 +544,28 @@ func (app *App) Run() error {
 		}
 	}()
 
-	// Ждем сигнала завершения
-	<-sigChan
-	log.Println("\n🛑 Получен сигнал завершения, останавливаем приложение...")
+	// Ждем сигнала завершения или остановки изнутри (например, после /wipe)
+	select {
+	case <-sigChan:
+		log.Println("\n🛑 Получен сигнал завершения, останавливаем приложение...")
+	case <-app.ctx.Done():
+	}
 
 	// Graceful shutdown
 	return app.Shutdown()
 }
 
-// Shutdown корректно останавливает приложение
+// Shutdown корректно останавливает приложение. Повторные вызовы ничего не делают
 func (app *App) Shutdown() error {
+	app.shutdownOnce.Do(app.shutdown)
+	return nil
+}
+
+// shutdown останавливает discovery, узел и фоновые задачи
+func (app *App) shutdown() {
+	app.state.Store(stateStopping)
+	sdNotify("STOPPING=1")
+
 	// Останавливаем discovery
 	if err := app.discovery.Stop(); err != nil {
 		log.Printf("⚠️ Ошибка остановки discovery: %v", err)
@@ -97,5 +580,4 @@ func (app *App) Shutdown() error {
 	app.cancel()
 
 	log.Println("👋 Приложение остановлено")
-	return nil
 }
@@ -0,0 +1,97 @@
+package app
+
+import (
+	"errors"
+	"log"
+	"os"
+	"time"
+
+	"OwlWhisper/internal/backup"
+	"OwlWhisper/internal/core"
+	"OwlWhisper/pkg/config"
+)
+
+// errBackupsDisabled - место хранения копий не настроено
+var errBackupsDisabled = errors.New("резервное копирование не настроено (backups.destination)")
+
+// setupBackups готовит резервное копирование, если задано место хранения,
+// и запускает расписание, если оно включено
+func (app *App) setupBackups() {
+	cfg := app.Settings().Backups
+	if cfg.Destination == "" {
+		return
+	}
+
+	passphrase := cfg.Passphrase
+	if passphrase == "" {
+		passphrase = os.Getenv("OWLWHISPER_BACKUP_PASSPHRASE")
+	}
+	target, err := backup.NewTarget(cfg.Destination, backup.Credentials{
+		Username:  cfg.Username,
+		Password:  cfg.Password,
+		AccessKey: cfg.S3AccessKey,
+		SecretKey: cfg.S3SecretKey,
+		Region:    cfg.S3Region,
+	})
+	if err != nil {
+		log.Printf("⚠️ Резервное копирование отключено: %v", err)
+		return
+	}
+	scheduler, err := backup.NewScheduler(target, passphrase, config.DefaultDir(), cfg.Keep)
+	if err != nil {
+		log.Printf("⚠️ Резервное копирование отключено: %v", err)
+		return
+	}
+	app.backups = scheduler
+
+	if cfg.Enabled {
+		interval := time.Duration(cfg.IntervalHours) * time.Hour
+		go scheduler.Run(app.ctx, interval, app.publishBackup)
+	}
+}
+
+// BackupNow делает резервную копию в фоне; результат приходит событием core.EventBackup
+func (app *App) BackupNow() {
+	if app.backups == nil {
+		app.publishBackup(backup.Result{}, errBackupsDisabled)
+		return
+	}
+	go func() {
+		app.publishBackup(app.backups.BackupNow(app.ctx))
+	}()
+}
+
+// ListBackups возвращает имена резервных копий от старых к новым
+func (app *App) ListBackups() ([]string, error) {
+	if app.backups == nil {
+		return nil, errBackupsDisabled
+	}
+	return app.backups.List(app.ctx)
+}
+
+// RestoreFromBackup восстанавливает файлы состояния из копии name (пусто -
+// самая новая). Восстановление идет в фоне, результат приходит событием
+// core.EventBackup; данные вступают в силу после перезапуска
+func (app *App) RestoreFromBackup(name string) {
+	if app.backups == nil {
+		app.publishBackup(backup.Result{Name: name}, errBackupsDisabled)
+		return
+	}
+	go func() {
+		app.publishBackup(app.backups.Restore(app.ctx, name))
+	}()
+}
+
+// publishBackup сообщает фронтендам результат копирования или восстановления
+func (app *App) publishBackup(result backup.Result, err error) {
+	status := core.BackupStatus{
+		Name:     result.Name,
+		Size:     result.Size,
+		Restored: result.Restored,
+		Removed:  result.Removed,
+	}
+	if err != nil {
+		status.Error = err.Error()
+	}
+	app.node.PublishBackupStatus(status)
+}
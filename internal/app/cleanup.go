@@ -0,0 +1,44 @@
+package app
+
+import (
+	"OwlWhisper/internal/core"
+	"OwlWhisper/internal/storage"
+	"OwlWhisper/pkg/interfaces"
+)
+
+// CleanupStorage выполняет очистку хранилища в фоне. Ход и результат
+// приходят событиями core.EventCleanupProgress
+func (app *App) CleanupStorage(opts storage.CleanupOptions) {
+	if opts.Self == "" {
+		opts.Self = app.node.GetHost().ID().String()
+	}
+
+	go func() {
+		progress := func(done, total int) {
+			app.node.PublishCleanupProgress(core.CleanupProgress{
+				Operation: opts.Operation,
+				Done:      done,
+				Total:     total,
+			})
+		}
+
+		var result *interfaces.CleanupResult
+		var err error
+		if opts.Operation == storage.CleanupMediaGC {
+			result, err = app.media.CollectGarbage(app.ctx, progress)
+		} else {
+			result, err = storage.RunCleanup(app.ctx, app.messages, opts, progress)
+		}
+
+		final := core.CleanupProgress{Operation: opts.Operation, Finished: true}
+		if result != nil {
+			final.Messages = result.Messages
+			final.Files = result.Files
+			final.FreedBytes = result.FreedBytes
+		}
+		if err != nil {
+			final.Error = err.Error()
+		}
+		app.node.PublishCleanupProgress(final)
+	}()
+}
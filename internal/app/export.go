@@ -0,0 +1,39 @@
+package app
+
+import (
+	"OwlWhisper/internal/core"
+	"OwlWhisper/internal/storage"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+// ExportConversation выгружает диалог с пиром в файл формата storage.ExportJSON
+// или storage.ExportHTML. Экспорт идет в фоне, ход и результат приходят
+// событиями core.EventExportProgress
+func (app *App) ExportConversation(peerID peer.ID, format, path string) {
+	opts := storage.ExportOptions{
+		Self:     app.node.GetHost().ID().String(),
+		PeerID:   peerID.String(),
+		SelfName: app.Settings().Profile.Nickname,
+		PeerName: app.tui.DisplayName(peerID),
+		Format:   format,
+		Path:     path,
+	}
+
+	go func() {
+		err := storage.ExportConversation(app.ctx, app.messages, opts, func(done, total int) {
+			app.node.PublishExportProgress(core.ExportProgress{
+				PeerID: peerID,
+				Path:   path,
+				Done:   done,
+				Total:  total,
+			})
+		})
+
+		result := core.ExportProgress{PeerID: peerID, Path: path, Finished: true}
+		if err != nil {
+			result.Error = err.Error()
+		}
+		app.node.PublishExportProgress(result)
+	}()
+}
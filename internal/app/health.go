@@ -0,0 +1,157 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"time"
+)
+
+// Состояние приложения для проверок здоровья
+const (
+	stateStarting int32 = iota
+	stateRunning
+	stateStopping
+)
+
+const (
+	// healthTimeout - предельное время чтения запроса проверки
+	healthTimeout = 5 * time.Second
+	// eventQueueHighWater - доля заполнения буфера событий, при которой
+	// потребитель считается не успевающим
+	eventQueueHighWater = 0.9
+)
+
+// healthCheck - результат одной проверки готовности
+type healthCheck struct {
+	OK     bool   `json:"ok"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// healthReport - ответ /healthz и /readyz
+type healthReport struct {
+	Status string                 `json:"status"`
+	Uptime string                 `json:"uptime"`
+	Checks map[string]healthCheck `json:"checks,omitempty"`
+}
+
+// startHealthServer запускает HTTP-сервер проверок для контейнеров и
+// сторожевых таймеров: /healthz - процесс жив, /readyz - узел готов к работе.
+// Сокет, переданный systemd, важнее адреса из конфигурации; без обоих
+// сервер не запускается. Сервер останавливается вместе с приложением
+func (app *App) startHealthServer(addr string) error {
+	listener, err := sdActivatedListener()
+	if err != nil {
+		return fmt.Errorf("не удалось принять сокет от systemd: %w", err)
+	}
+	if listener == nil {
+		if addr == "" {
+			return nil
+		}
+		if listener, err = net.Listen("tcp", addr); err != nil {
+			return fmt.Errorf("не удалось открыть адрес проверок здоровья: %w", err)
+		}
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", app.handleHealthz)
+	mux.HandleFunc("/readyz", app.handleReadyz)
+	server := &http.Server{Handler: mux, ReadHeaderTimeout: healthTimeout}
+
+	go func() {
+		if err := server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			log.Printf("⚠️ Сервер проверок здоровья остановлен: %v", err)
+		}
+	}()
+	go func() {
+		<-app.ctx.Done()
+		server.Close()
+	}()
+	log.Printf("🩺 Проверки здоровья: http://%s/healthz, /readyz", listener.Addr())
+	return nil
+}
+
+// handleHealthz отвечает, жив ли процесс: 503 только во время остановки
+func (app *App) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	report := app.healthReport()
+	writeHealth(w, report, report.Status != "stopping")
+}
+
+// livenessCheck - узел запущен и разбирает события; от сети не зависит,
+// чтобы работа без интернета не приводила к перезапуску
+func (app *App) livenessCheck() healthCheck {
+	if state := app.healthReport().Status; state != "running" {
+		return healthCheck{Detail: state}
+	}
+	return app.eventQueueCheck()
+}
+
+// eventQueueCheck проверяет, успевает ли потребитель разбирать события
+func (app *App) eventQueueCheck() healthCheck {
+	queue := app.node.EventQueueStats()
+	return healthCheck{
+		OK:     float64(queue.Pending) < eventQueueHighWater*float64(queue.Capacity),
+		Detail: fmt.Sprintf("в очереди %d из %d, отброшено %d", queue.Pending, queue.Capacity, queue.Dropped),
+	}
+}
+
+// handleReadyz отвечает, готов ли узел: запущен, поиск в глобальной сети
+// работает и потребитель событий успевает их разбирать
+func (app *App) handleReadyz(w http.ResponseWriter, r *http.Request) {
+	report := app.healthReport()
+	report.Checks = make(map[string]healthCheck)
+
+	report.Checks["started"] = healthCheck{OK: report.Status == "running", Detail: report.Status}
+
+	readiness := app.discovery.Readiness()
+	dht := healthCheck{OK: readiness.Ready}
+	switch {
+	case readiness.ViaHelper && readiness.Ready:
+		dht.Detail = "помощник подключен"
+	case readiness.ViaHelper:
+		dht.Detail = "помощник не подключен"
+	case readiness.RoutingTableSize > 0:
+		dht.Detail = fmt.Sprintf("в таблице маршрутизации %d пиров", readiness.RoutingTableSize)
+	default:
+		dht.Detail = "таблица маршрутизации пуста"
+	}
+	report.Checks["dht"] = dht
+
+	// После заполнения таблицы связь с bootstrap-узлами больше не нужна
+	report.Checks["bootstrap"] = healthCheck{
+		OK:     readiness.BootstrapConnected > 0 || readiness.RoutingTableSize > 0,
+		Detail: fmt.Sprintf("подключено %d из %d", readiness.BootstrapConnected, readiness.BootstrapTotal),
+	}
+
+	report.Checks["events"] = app.eventQueueCheck()
+
+	ready := true
+	for _, check := range report.Checks {
+		ready = ready && check.OK
+	}
+	writeHealth(w, report, ready)
+}
+
+// healthReport возвращает состояние и время работы приложения
+func (app *App) healthReport() healthReport {
+	status := "starting"
+	switch app.state.Load() {
+	case stateRunning:
+		status = "running"
+	case stateStopping:
+		status = "stopping"
+	}
+	return healthReport{Status: status, Uptime: time.Since(app.startedAt).Round(time.Second).String()}
+}
+
+// writeHealth отвечает отчетом с кодом 200 или 503
+func writeHealth(w http.ResponseWriter, report healthReport, ok bool) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if !ok {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(report)
+}
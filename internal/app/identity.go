@@ -0,0 +1,62 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"OwlWhisper/internal/core"
+	"OwlWhisper/pkg/config"
+	"OwlWhisper/pkg/interfaces"
+)
+
+// migrateContact переносит контакт, сменивший ключ, на новый PeerID.
+// Имя и настройки сохраняются, запись со старым PeerID удаляется
+func migrateContact(ctx context.Context, contacts interfaces.IContactRepository, t core.KeyTransition) error {
+	contact, err := contacts.GetContact(ctx, t.OldPeerID.String())
+	if err != nil {
+		return err
+	}
+
+	migrated := *contact
+	migrated.PeerID = t.NewPeerID.String()
+	migrated.IsOnline = false
+	if err := contacts.SaveContact(ctx, &migrated); err != nil {
+		return err
+	}
+	return contacts.DeleteContact(ctx, t.OldPeerID.String())
+}
+
+// RotateIdentity создает новый ключ личности, подписывает переход старым и
+// новым ключом и рассылает его контактам. Новый ключ начинает действовать
+// после перезапуска; старый сохраняется рядом как identity.key.old.
+// Возвращает переход и число контактов, получивших его сразу
+func (app *App) RotateIdentity() (core.KeyTransition, int, error) {
+	newKey, err := core.GenerateIdentity()
+	if err != nil {
+		return core.KeyTransition{}, 0, err
+	}
+	transition, err := app.node.CreateKeyTransition(newKey)
+	if err != nil {
+		return core.KeyTransition{}, 0, err
+	}
+
+	identityPath := filepath.Join(config.DefaultDir(), "identity.key")
+	if err := os.Rename(identityPath, identityPath+".old"); err != nil && !os.IsNotExist(err) {
+		return transition, 0, fmt.Errorf("не удалось сохранить старый ключ: %w", err)
+	}
+	if err := core.SaveIdentity(identityPath, newKey); err != nil {
+		// Возвращаем старый ключ, чтобы не остаться без личности
+		os.Rename(identityPath+".old", identityPath)
+		return transition, 0, fmt.Errorf("не удалось сохранить новый ключ: %w", err)
+	}
+
+	delivered, err := app.node.AnnounceKeyTransition(transition)
+	if err != nil {
+		return transition, delivered, err
+	}
+	log.Printf("🔑 Новый ключ личности сохранен, PeerID после перезапуска: %s", transition.NewPeerID)
+	return transition, delivered, nil
+}
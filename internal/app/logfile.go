@@ -0,0 +1,89 @@
+package app
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// logFile - журнал приложения в файле (logging.output_file) с ротацией по
+// команде администратора
+type logFile struct {
+	mu   sync.Mutex
+	path string
+	file *os.File
+	// console - прежний вывод log, если журнал дублируется в консоль
+	console io.Writer
+}
+
+// logRotated - ответ на команду rotate-logs
+type logRotated struct {
+	Previous string `json:"previous"`
+}
+
+// openLogFile направляет журнал в файл path. Пустой путь оставляет вывод
+// как есть и возвращает nil
+func openLogFile(path string, console bool) (*logFile, error) {
+	if path == "" {
+		return nil, nil
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось открыть файл журнала: %w", err)
+	}
+
+	l := &logFile{path: path, file: file}
+	if console {
+		l.console = log.Writer()
+	}
+	log.SetOutput(l)
+	return l, nil
+}
+
+// Write пишет строку журнала в файл и, если нужно, в консоль
+func (l *logFile) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.console != nil {
+		l.console.Write(p)
+	}
+	return l.file.Write(p)
+}
+
+// Close закрывает файл журнала и возвращает вывод log в консоль
+func (l *logFile) Close() error {
+	// Вывод переключаем до блокировки: log вызывает Write под своей блокировкой
+	if l.console != nil {
+		log.SetOutput(l.console)
+	} else {
+		log.SetOutput(os.Stderr)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.file.Close()
+}
+
+// Rotate переименовывает текущий файл журнала, добавляя время ротации, и
+// начинает новый
+func (l *logFile) Rotate() (logRotated, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	previous := l.path + "." + time.Now().Format("20060102-150405")
+	if err := os.Rename(l.path, previous); err != nil {
+		return logRotated{}, fmt.Errorf("не удалось переименовать журнал: %w", err)
+	}
+	file, err := os.OpenFile(l.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		// Продолжаем писать в переименованный файл, чтобы не потерять журнал
+		return logRotated{}, fmt.Errorf("не удалось открыть новый журнал: %w", err)
+	}
+	l.file.Close()
+	l.file = file
+	return logRotated{Previous: previous}, nil
+}
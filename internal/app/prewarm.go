@@ -0,0 +1,50 @@
+package app
+
+import (
+	"log"
+	"sort"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+// prewarmRecent заранее соединяется с limit собеседниками, с которыми
+// переписка была последней
+func (app *App) prewarmRecent(limit int) {
+	if limit <= 0 || !app.config.Chat.Prewarm {
+		return
+	}
+
+	contacts, err := app.contacts.GetAllContacts(app.ctx)
+	if err != nil {
+		log.Printf("⚠️ Не удалось прочитать контакты для прогрева: %v", err)
+		return
+	}
+
+	type recent struct {
+		id   peer.ID
+		last int64
+	}
+	self := app.node.GetHost().ID().String()
+	var conversations []recent
+	for _, contact := range contacts {
+		id, err := peer.Decode(contact.PeerID)
+		if err != nil {
+			continue
+		}
+		message, err := app.messages.GetLastMessage(app.ctx, self, contact.PeerID)
+		if err != nil || message == nil {
+			continue
+		}
+		conversations = append(conversations, recent{id: id, last: message.Timestamp.UnixNano()})
+	}
+	sort.Slice(conversations, func(i, j int) bool {
+		return conversations[i].last > conversations[j].last
+	})
+
+	if len(conversations) > limit {
+		conversations = conversations[:limit]
+	}
+	for _, c := range conversations {
+		app.node.PrewarmConnection(c.id)
+	}
+}
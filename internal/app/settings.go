@@ -0,0 +1,93 @@
+package app
+
+import (
+	"fmt"
+	"reflect"
+
+	"OwlWhisper/pkg/config"
+	"OwlWhisper/pkg/interfaces"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+// Settings возвращает копию текущих настроек для отображения во фронтенде
+func (app *App) Settings() *config.Config {
+	app.settingsMu.Lock()
+	defer app.settingsMu.Unlock()
+
+	return app.config.Clone()
+}
+
+// UpdateSettings проверяет, сохраняет и применяет новые настройки.
+// Возвращает true, если часть изменений вступит в силу только после перезапуска
+func (app *App) UpdateSettings(updated *config.Config) (bool, error) {
+	if err := updated.Validate(); err != nil {
+		return false, fmt.Errorf("некорректные настройки: %w", err)
+	}
+
+	app.settingsMu.Lock()
+	defer app.settingsMu.Unlock()
+
+	if err := updated.SaveConfig(""); err != nil {
+		return false, fmt.Errorf("не удалось сохранить настройки: %w", err)
+	}
+
+	// Сетевые параметры задаются при создании узла; блокировки применяются сразу
+	current, next := app.config.Network, updated.Network
+	current.BannedPeers, next.BannedPeers = nil, nil
+	restartRequired := !reflect.DeepEqual(current, next)
+
+	// Правила приема файлов и настройки приватности применяются сразу
+	nodeConfig := nodeConfigFrom(updated)
+	app.node.SetTransferPolicy(nodeConfig.Transfers)
+	if err := app.node.SetContactRequestPolicy(nodeConfig.ContactRequests); err != nil {
+		return false, err
+	}
+	if err := app.node.SetLastSeenPolicy(nodeConfig.LastSeenPolicy); err != nil {
+		return false, err
+	}
+	if err := app.node.SetStatusMessage(nodeConfig.StatusMessage); err != nil {
+		return false, err
+	}
+	app.notifier.SetSchedule(notifyScheduleFrom(updated))
+	app.node.SetPrewarmEnabled(updated.Chat.Prewarm)
+	applyAccessLists(app.node, updated)
+
+	// Строка клиента и скрытый режим задаются при создании узла
+	if app.config.Privacy.UserAgent != updated.Privacy.UserAgent || app.config.Privacy.Stealth != updated.Privacy.Stealth {
+		restartRequired = true
+	}
+
+	// Выбор сервиса уведомлений делается при запуске
+	if app.config.Notifications.Enabled != updated.Notifications.Enabled {
+		restartRequired = true
+	}
+
+	// Копируем по значению: фронтенды держат указатель на общую конфигурацию
+	*app.config = *updated.Clone()
+	return restartRequired, nil
+}
+
+// ConversationPrefs возвращает настройки уведомлений диалога с пиром
+func (app *App) ConversationPrefs(peerID peer.ID) (*interfaces.ConversationPrefs, error) {
+	prefs, err := app.prefs.GetPrefs(app.ctx, peerID.String())
+	if err != nil || prefs != nil {
+		return prefs, err
+	}
+	return &interfaces.ConversationPrefs{PeerID: peerID.String()}, nil
+}
+
+// SetConversationPrefs сохраняет настройки диалога. Уведомления сразу
+// учитываются диспетчером для всех фронтендов, защита трафика и скрытый
+// отправитель - узлом
+func (app *App) SetConversationPrefs(prefs *interfaces.ConversationPrefs) error {
+	id, err := peer.Decode(prefs.PeerID)
+	if err != nil {
+		return fmt.Errorf("некорректный PeerID: %w", err)
+	}
+	if err := app.node.SetTrafficShaping(id, shapingFrom(prefs)); err != nil {
+		return err
+	}
+	app.node.SetSealedSender(id, prefs.SealedSender)
+	return app.prefs.SavePrefs(app.ctx, prefs)
+}
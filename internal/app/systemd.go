@@ -0,0 +1,89 @@
+package app
+
+import (
+	"log"
+	"net"
+	"os"
+	"strconv"
+	"time"
+)
+
+// sdListenFDsStart - первый дескриптор, который systemd передает при
+// активации по сокету (SD_LISTEN_FDS_START)
+const sdListenFDsStart = 3
+
+// sdNotify сообщает systemd о состоянии службы (протокол sd_notify).
+// Без NOTIFY_SOCKET (запуск не из systemd или не Type=notify) ничего не делает
+func sdNotify(state string) {
+	socket := os.Getenv("NOTIFY_SOCKET")
+	if socket == "" {
+		return
+	}
+	// Сокет в абстрактном пространстве имен Linux
+	if socket[0] == '@' {
+		socket = "\x00" + socket[1:]
+	}
+
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		log.Printf("⚠️ Не удалось связаться с systemd: %v", err)
+		return
+	}
+	defer conn.Close()
+	if _, err := conn.Write([]byte(state)); err != nil {
+		log.Printf("⚠️ Не удалось отправить состояние systemd: %v", err)
+	}
+}
+
+// sdWatchdogInterval возвращает, как часто сообщать systemd, что служба
+// жива (половина WatchdogSec), или 0, если сторожевой таймер не включен
+func sdWatchdogInterval() time.Duration {
+	usec, err := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64)
+	if err != nil || usec <= 0 {
+		return 0
+	}
+	if pid := os.Getenv("WATCHDOG_PID"); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return 0
+	}
+	return time.Duration(usec) * time.Microsecond / 2
+}
+
+// sdActivatedListener возвращает сокет, открытый systemd при активации по
+// сокету, или nil, если процесс запущен без нее
+func sdActivatedListener() (net.Listener, error) {
+	if os.Getenv("LISTEN_PID") != strconv.Itoa(os.Getpid()) {
+		return nil, nil
+	}
+	count, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || count < 1 {
+		return nil, nil
+	}
+	// Дочерние процессы не должны считать сокет своим
+	os.Unsetenv("LISTEN_PID")
+	os.Unsetenv("LISTEN_FDS")
+	os.Unsetenv("LISTEN_FDNAMES")
+
+	file := os.NewFile(sdListenFDsStart, "systemd-socket")
+	defer file.Close()
+	return net.FileListener(file)
+}
+
+// runWatchdog сообщает systemd, что служба жива, пока проходит внутренняя
+// проверка. Зависший узел перестает отвечать, и systemd перезапускает его
+func (app *App) runWatchdog(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-app.ctx.Done():
+			return
+		case <-ticker.C:
+			if check := app.livenessCheck(); check.OK {
+				sdNotify("WATCHDOG=1")
+			} else {
+				log.Printf("⚠️ Проверка для systemd не пройдена: %s", check.Detail)
+			}
+		}
+	}
+}
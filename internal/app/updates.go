@@ -0,0 +1,33 @@
+package app
+
+import (
+	"log"
+	"time"
+
+	"OwlWhisper/internal/core"
+	"OwlWhisper/internal/updater"
+)
+
+// startUpdateChecks запускает периодическую проверку подписанного манифеста.
+// О новой версии сообщает событие core.EventUpdateAvailable; установка
+// остается решением пользователя
+func (app *App) startUpdateChecks() {
+	cfg := app.Settings().Updates
+
+	checker, err := updater.NewChecker(cfg.ManifestURL, cfg.PublicKey, core.Version)
+	if err != nil {
+		log.Printf("⚠️ Проверка обновлений отключена: %v", err)
+		return
+	}
+
+	interval := time.Duration(cfg.CheckIntervalHours) * time.Hour
+	go checker.Run(app.ctx, interval, func(release updater.Release) {
+		app.node.PublishUpdateAvailable(core.UpdateAvailable{
+			Current:   core.Version,
+			Version:   release.Version,
+			Published: release.Published,
+			URL:       release.URL,
+			Notes:     release.Notes,
+		})
+	})
+}
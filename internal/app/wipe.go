@@ -0,0 +1,90 @@
+package app
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"OwlWhisper/internal/storage"
+	"OwlWhisper/pkg/config"
+)
+
+// WipeConfirmation - фраза, которую нужно ввести для экстренного уничтожения
+// данных. Парольной фразы у ключа личности нет, поэтому подтверждение -
+// явно набранная фраза, а не случайное нажатие
+const WipeConfirmation = "уничтожить всё"
+
+// SecureWipe экстренно уничтожает ключ личности, историю, контакты, кэши,
+// журнал, принятые файлы и конфигурацию и останавливает приложение. Из
+// директории загрузок, выбранной пользователем, уничтожаются только файлы,
+// записанные в историю как принятые. При notify подключенные контакты
+// перед этим получают подписанное уведомление, что ключу больше нельзя доверять.
+// Резервные копии во внешнем хранилище не затрагиваются
+func (app *App) SecureWipe(confirmation string, notify bool) error {
+	if confirmation != WipeConfirmation {
+		return fmt.Errorf("неверная фраза подтверждения")
+	}
+
+	if notify {
+		delivered, err := app.node.NotifyIdentityDestroyed()
+		if err != nil {
+			log.Printf("⚠️ Не удалось уведомить контакты: %v", err)
+		} else {
+			log.Printf("💥 Уведомление об уничтожении получили контакты: %d", delivered)
+		}
+	}
+
+	app.settingsMu.Lock()
+	logPath := app.config.Logging.OutputFile
+	app.settingsMu.Unlock()
+	received := app.messages.ReceivedFiles(app.node.GetHost().ID().String())
+
+	// Сначала останавливаем узел и фоновые задачи, чтобы они не дописали файлы
+	app.Shutdown()
+
+	shredded, err := storage.ShredDir(config.DefaultDir())
+	dirs := []string{
+		filepath.Join(os.TempDir(), "owlwhisper-clipboard"),
+		filepath.Join(os.TempDir(), "owlwhisper"),
+	}
+	for _, dir := range dirs {
+		n, dirErr := storage.ShredDir(dir)
+		shredded += n
+		if err == nil {
+			err = dirErr
+		}
+	}
+
+	// Директорию загрузок можно сменить, например на ~/Downloads: там
+	// уничтожаются только принятые файлы, а не вся директория
+	for _, path := range received {
+		fileErr := storage.ShredFile(path)
+		if fileErr == nil {
+			shredded++
+		} else if err == nil && !os.IsNotExist(fileErr) {
+			err = fileErr
+		}
+	}
+
+	// Журнал содержит текст сообщений: уничтожаем его и копии после ротации
+	if logPath != "" {
+		if app.logs != nil {
+			app.logs.Close()
+		}
+		rotated, _ := filepath.Glob(logPath + ".*")
+		for _, path := range append([]string{logPath}, rotated...) {
+			fileErr := storage.ShredFile(path)
+			if fileErr == nil {
+				shredded++
+			} else if err == nil && !os.IsNotExist(fileErr) {
+				err = fileErr
+			}
+		}
+	}
+	log.Printf("💥 Данные уничтожены, файлов: %d", shredded)
+	if err != nil {
+		return fmt.Errorf("часть данных не удалось уничтожить: %w", err)
+	}
+	return nil
+}
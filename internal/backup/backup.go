@@ -0,0 +1,215 @@
+package backup
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// StateFiles - файлы состояния в директории данных, попадающие в резервную копию.
+// Вложения и загрузки не копируются: их можно запросить у пиров заново
+var StateFiles = []string{
+	"identity.key",
+	"config.json",
+	"messages.jsonl",
+	"messages.drafts.json",
+	"contacts.json",
+	"outbox.json",
+	"peer_tags.json",
+	"contact_metadata.json",
+	"attestations.json",
+	"key_transitions.json",
+	"conversations.json",
+	"profile_records.json",
+	"mailbox.json",
+	"audit.log",
+}
+
+const (
+	// magic - сигнатура зашифрованной резервной копии
+	magic = "OWLBAK1\n"
+	// saltSize - размер соли для вывода ключа из пароля
+	saltSize = 16
+	// snapshotLimit - максимальный размер распакованного файла состояния
+	snapshotLimit = 1 << 30
+)
+
+// Параметры Argon2id для вывода ключа из пароля
+const (
+	argonTime    = 2
+	argonMemory  = 64 * 1024
+	argonThreads = 4
+	argonKeyLen  = 32
+)
+
+var (
+	// ErrBadPassphrase - копию не удалось расшифровать: неверный пароль или копия повреждена
+	ErrBadPassphrase = errors.New("неверный пароль резервной копии или копия повреждена")
+
+	// ErrNotBackup - данные не являются резервной копией OwlWhisper
+	ErrNotBackup = errors.New("файл не является резервной копией OwlWhisper")
+)
+
+// Snapshot упаковывает файлы состояния из dir в tar.gz. Отсутствующие
+// файлы пропускаются
+func Snapshot(dir string, files []string) ([]byte, error) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+
+	for _, name := range files {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("не удалось прочитать %s: %w", name, err)
+		}
+
+		header := &tar.Header{
+			Name:    name,
+			Mode:    0600,
+			Size:    int64(len(data)),
+			ModTime: time.Now(),
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			return nil, err
+		}
+		if _, err := tw.Write(data); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Extract распаковывает снимок в dir. Восстанавливаются только файлы из
+// списка files, каждый пишется атомарно через временный файл
+func Extract(snapshot []byte, dir string, files []string) ([]string, error) {
+	allowed := make(map[string]bool, len(files))
+	for _, name := range files {
+		allowed[name] = true
+	}
+
+	gz, err := gzip.NewReader(bytes.NewReader(snapshot))
+	if err != nil {
+		return nil, ErrNotBackup
+	}
+	defer gz.Close()
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+
+	var restored []string
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return restored, fmt.Errorf("копия повреждена: %w", err)
+		}
+		// Имена сверяются со списком, поэтому пути вида ../x не пройдут
+		if header.Typeflag != tar.TypeReg || !allowed[header.Name] {
+			continue
+		}
+
+		data, err := io.ReadAll(io.LimitReader(tr, snapshotLimit))
+		if err != nil {
+			return restored, fmt.Errorf("копия повреждена: %w", err)
+		}
+		path := filepath.Join(dir, header.Name)
+		if err := os.WriteFile(path+".tmp", data, 0600); err != nil {
+			return restored, fmt.Errorf("не удалось восстановить %s: %w", header.Name, err)
+		}
+		if err := os.Rename(path+".tmp", path); err != nil {
+			return restored, fmt.Errorf("не удалось восстановить %s: %w", header.Name, err)
+		}
+		restored = append(restored, header.Name)
+	}
+	return restored, nil
+}
+
+// Encrypt шифрует данные паролем: ключ выводится Argon2id со случайной солью,
+// шифрование - AES-256-GCM
+func Encrypt(plain []byte, passphrase string) ([]byte, error) {
+	if passphrase == "" {
+		return nil, fmt.Errorf("пароль резервной копии не задан")
+	}
+
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	aead, err := newAEAD(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	header := make([]byte, 0, len(magic)+saltSize+len(nonce))
+	header = append(header, magic...)
+	header = append(header, salt...)
+	header = append(header, nonce...)
+
+	// Заголовок аутентифицируется вместе с данными
+	out := make([]byte, len(header), len(header)+len(plain)+aead.Overhead())
+	copy(out, header)
+	return aead.Seal(out, nonce, plain, header), nil
+}
+
+// Decrypt расшифровывает данные, зашифрованные Encrypt
+func Decrypt(data []byte, passphrase string) ([]byte, error) {
+	if len(data) < len(magic)+saltSize || string(data[:len(magic)]) != magic {
+		return nil, ErrNotBackup
+	}
+
+	salt := data[len(magic) : len(magic)+saltSize]
+	aead, err := newAEAD(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	headerLen := len(magic) + saltSize + aead.NonceSize()
+	if len(data) < headerLen+aead.Overhead() {
+		return nil, ErrNotBackup
+	}
+
+	nonce := data[len(magic)+saltSize : headerLen]
+	plain, err := aead.Open(nil, nonce, data[headerLen:], data[:headerLen])
+	if err != nil {
+		return nil, ErrBadPassphrase
+	}
+	return plain, nil
+}
+
+// newAEAD выводит ключ из пароля и соли
+func newAEAD(passphrase string, salt []byte) (cipher.AEAD, error) {
+	key := argon2.IDKey([]byte(passphrase), salt, argonTime, argonMemory, argonThreads, argonKeyLen)
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,235 @@
+package backup
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// s3Target хранит копии в S3-совместимом хранилище (AWS, MinIO, Backblaze и т.п.).
+// Запросы подписываются AWS Signature V4, адресация бакета - path-style
+type s3Target struct {
+	endpoint *url.URL
+	bucket   string
+	prefix   string
+	creds    Credentials
+	client   *http.Client
+}
+
+// newS3Target разбирает адрес s3://bucket/префикс?endpoint=https://host
+func newS3Target(parsed *url.URL, creds Credentials) (*s3Target, error) {
+	if parsed.Host == "" {
+		return nil, fmt.Errorf("в адресе S3 не указан бакет")
+	}
+	if creds.AccessKey == "" || creds.SecretKey == "" {
+		return nil, fmt.Errorf("для S3 нужны ключи доступа")
+	}
+
+	endpoint := parsed.Query().Get("endpoint")
+	if endpoint == "" {
+		region := creds.Region
+		if region == "" {
+			region = "us-east-1"
+		}
+		endpoint = "https://s3." + region + ".amazonaws.com"
+	}
+	endpointURL, err := url.Parse(endpoint)
+	if err != nil || endpointURL.Scheme != "https" || endpointURL.Host == "" {
+		return nil, fmt.Errorf("адрес S3 должен быть https URL: %s", endpoint)
+	}
+	if creds.Region == "" {
+		creds.Region = "us-east-1"
+	}
+
+	prefix := strings.Trim(parsed.Path, "/")
+	if prefix != "" {
+		prefix += "/"
+	}
+	return &s3Target{
+		endpoint: endpointURL,
+		bucket:   parsed.Host,
+		prefix:   prefix,
+		creds:    creds,
+		client:   &http.Client{Timeout: requestTimeout},
+	}, nil
+}
+
+func (t *s3Target) Put(ctx context.Context, name string, data []byte) error {
+	_, err := t.do(ctx, http.MethodPut, t.prefix+name, nil, data)
+	return err
+}
+
+func (t *s3Target) Get(ctx context.Context, name string) ([]byte, error) {
+	return t.do(ctx, http.MethodGet, t.prefix+name, nil, nil)
+}
+
+func (t *s3Target) Delete(ctx context.Context, name string) error {
+	_, err := t.do(ctx, http.MethodDelete, t.prefix+name, nil, nil)
+	return err
+}
+
+func (t *s3Target) List(ctx context.Context) ([]string, error) {
+	var names []string
+	token := ""
+	for {
+		query := url.Values{"list-type": {"2"}, "prefix": {t.prefix + backupPrefix}}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+		body, err := t.do(ctx, http.MethodGet, "", query, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		var result struct {
+			Contents []struct {
+				Key string `xml:"Key"`
+			} `xml:"Contents"`
+			IsTruncated           bool   `xml:"IsTruncated"`
+			NextContinuationToken string `xml:"NextContinuationToken"`
+		}
+		if err := xml.Unmarshal(body, &result); err != nil {
+			return nil, fmt.Errorf("некорректный ответ S3: %w", err)
+		}
+		for _, object := range result.Contents {
+			if name := path.Base(object.Key); isBackupName(name) {
+				names = append(names, name)
+			}
+		}
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			return names, nil
+		}
+		token = result.NextContinuationToken
+	}
+}
+
+// do выполняет подписанный запрос к объекту key (пустой key - к бакету)
+func (t *s3Target) do(ctx context.Context, method, key string, query url.Values, body []byte) ([]byte, error) {
+	target := *t.endpoint
+	target.Path = "/" + t.bucket
+	if key != "" {
+		target.Path += "/" + key
+	}
+	target.RawPath = s3EscapePath(target.Path)
+	target.RawQuery = s3CanonicalQuery(query)
+
+	req, err := http.NewRequestWithContext(ctx, method, target.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.ContentLength = int64(len(body))
+	t.sign(req, body, time.Now().UTC())
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, backupLimit))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("S3 %s %s: %s", method, key, resp.Status)
+	}
+	return data, nil
+}
+
+// sign добавляет к запросу подпись AWS Signature V4
+func (t *s3Target) sign(req *http.Request, body []byte, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	payloadHash := sha256Hex(body)
+
+	req.Header.Set("x-amz-date", amzDate)
+	req.Header.Set("x-amz-content-sha256", payloadHash)
+
+	signedHeaders := "host;x-amz-content-sha256;x-amz-date"
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		"host:" + req.URL.Host + "\n" +
+			"x-amz-content-sha256:" + payloadHash + "\n" +
+			"x-amz-date:" + amzDate + "\n",
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + t.creds.Region + "/s3/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(canonicalRequest))
+
+	key := hmacSHA256([]byte("AWS4"+t.creds.SecretKey), date)
+	key = hmacSHA256(key, t.creds.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		t.creds.AccessKey, scope, signedHeaders, signature))
+}
+
+// s3EscapePath кодирует путь по правилам SigV4: все, кроме unreserved и '/'
+func s3EscapePath(p string) string {
+	segments := strings.Split(p, "/")
+	for i, segment := range segments {
+		segments[i] = s3Escape(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+// s3CanonicalQuery кодирует параметры запроса в каноническом порядке
+func s3CanonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var parts []string
+	for _, key := range keys {
+		for _, value := range query[key] {
+			parts = append(parts, s3Escape(key)+"="+s3Escape(value))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// s3Escape кодирует строку, оставляя только символы unreserved из RFC 3986
+func s3Escape(s string) string {
+	var b strings.Builder
+	for _, c := range []byte(s) {
+		if ('A' <= c && c <= 'Z') || ('a' <= c && c <= 'z') || ('0' <= c && c <= '9') ||
+			c == '-' || c == '_' || c == '.' || c == '~' {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// sha256Hex возвращает SHA-256 данных в hex
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// hmacSHA256 вычисляет HMAC-SHA256
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
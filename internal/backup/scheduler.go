@@ -0,0 +1,149 @@
+package backup
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+)
+
+// minInterval - резервные копии не делаются чаще этого
+const minInterval = time.Hour
+
+// Result - итог создания или восстановления копии
+type Result struct {
+	Name     string
+	Size     int
+	Restored []string // восстановленные файлы; только для Restore
+	Removed  []string // копии, удаленные политикой хранения
+}
+
+// Scheduler периодически делает зашифрованные копии файлов состояния
+// и удаляет старые копии сверх Keep
+type Scheduler struct {
+	target     Target
+	passphrase string
+	dir        string
+	files      []string
+	keep       int
+}
+
+// NewScheduler создает планировщик копий файлов StateFiles из dir.
+// keep - сколько последних копий хранить (0 - все)
+func NewScheduler(target Target, passphrase, dir string, keep int) (*Scheduler, error) {
+	if passphrase == "" {
+		return nil, fmt.Errorf("пароль резервной копии не задан")
+	}
+	return &Scheduler{
+		target:     target,
+		passphrase: passphrase,
+		dir:        dir,
+		files:      StateFiles,
+		keep:       keep,
+	}, nil
+}
+
+// Run делает копию сразу и затем каждые interval, пока не отменен ctx.
+// Результат каждой попытки передается onResult
+func (s *Scheduler) Run(ctx context.Context, interval time.Duration, onResult func(Result, error)) {
+	if interval < minInterval {
+		interval = minInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		result, err := s.BackupNow(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		onResult(result, err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// BackupNow делает копию, загружает ее в хранилище и применяет политику хранения
+func (s *Scheduler) BackupNow(ctx context.Context) (Result, error) {
+	snapshot, err := Snapshot(s.dir, s.files)
+	if err != nil {
+		return Result{}, err
+	}
+	encrypted, err := Encrypt(snapshot, s.passphrase)
+	if err != nil {
+		return Result{}, err
+	}
+
+	result := Result{Name: backupName(time.Now()), Size: len(encrypted)}
+	if err := s.target.Put(ctx, result.Name, encrypted); err != nil {
+		return result, fmt.Errorf("не удалось загрузить копию: %w", err)
+	}
+
+	removed, err := s.prune(ctx)
+	result.Removed = removed
+	if err != nil {
+		// Копия уже сохранена, ошибка очистки не должна ее перечеркивать
+		log.Printf("⚠️ Не удалось удалить старые резервные копии: %v", err)
+	}
+	return result, nil
+}
+
+// List возвращает копии в хранилище от старых к новым
+func (s *Scheduler) List(ctx context.Context) ([]string, error) {
+	return sortedBackups(ctx, s.target)
+}
+
+// Restore загружает копию name (пусто - самую новую), расшифровывает ее
+// и восстанавливает файлы состояния в директорию данных. Открытые хранилища
+// приложения подхватят восстановленные файлы после перезапуска
+func (s *Scheduler) Restore(ctx context.Context, name string) (Result, error) {
+	if name == "" {
+		names, err := s.List(ctx)
+		if err != nil {
+			return Result{}, err
+		}
+		if len(names) == 0 {
+			return Result{}, fmt.Errorf("резервных копий нет")
+		}
+		name = names[len(names)-1]
+	}
+	if !isBackupName(name) {
+		return Result{}, fmt.Errorf("некорректное имя резервной копии: %s", name)
+	}
+
+	data, err := s.target.Get(ctx, name)
+	if err != nil {
+		return Result{Name: name}, fmt.Errorf("не удалось загрузить копию: %w", err)
+	}
+	snapshot, err := Decrypt(data, s.passphrase)
+	if err != nil {
+		return Result{Name: name}, err
+	}
+
+	restored, err := Extract(snapshot, s.dir, s.files)
+	return Result{Name: name, Size: len(data), Restored: restored}, err
+}
+
+// prune удаляет самые старые копии сверх лимита keep
+func (s *Scheduler) prune(ctx context.Context) ([]string, error) {
+	if s.keep <= 0 {
+		return nil, nil
+	}
+	names, err := s.List(ctx)
+	if err != nil || len(names) <= s.keep {
+		return nil, err
+	}
+
+	var removed []string
+	for _, name := range names[:len(names)-s.keep] {
+		if err := s.target.Delete(ctx, name); err != nil {
+			return removed, err
+		}
+		removed = append(removed, name)
+	}
+	return removed, nil
+}
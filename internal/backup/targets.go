@@ -0,0 +1,225 @@
+package backup
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// backupLimit - максимальный размер загружаемой резервной копии
+const backupLimit = 2 << 30
+
+// requestTimeout - предельное время одного запроса к удаленному хранилищу
+const requestTimeout = 10 * time.Minute
+
+// Target - место хранения резервных копий
+type Target interface {
+	// Put сохраняет копию под именем name
+	Put(ctx context.Context, name string, data []byte) error
+	// Get загружает копию
+	Get(ctx context.Context, name string) ([]byte, error)
+	// List возвращает имена копий
+	List(ctx context.Context) ([]string, error)
+	// Delete удаляет копию
+	Delete(ctx context.Context, name string) error
+}
+
+// Credentials - учетные данные удаленного хранилища
+type Credentials struct {
+	// Username и Password - для WebDAV (Basic-аутентификация)
+	Username string
+	Password string
+	// AccessKey, SecretKey и Region - для S3-совместимых хранилищ
+	AccessKey string
+	SecretKey string
+	Region    string
+}
+
+// NewTarget выбирает хранилище по адресу назначения:
+//   - путь или file:///путь - директория (например, смонтированный диск);
+//   - https://... - WebDAV;
+//   - s3://bucket/префикс?endpoint=https://... - S3-совместимое хранилище.
+//
+// Удаленные хранилища принимаются только по HTTPS
+func NewTarget(destination string, creds Credentials) (Target, error) {
+	parsed, err := url.Parse(destination)
+	if err != nil || parsed.Scheme == "" || len(parsed.Scheme) == 1 {
+		// Путь без схемы (включая C:\ в Windows)
+		return &dirTarget{dir: destination}, nil
+	}
+
+	switch parsed.Scheme {
+	case "file":
+		return &dirTarget{dir: filepath.FromSlash(parsed.Path)}, nil
+	case "https":
+		return &webdavTarget{base: strings.TrimSuffix(parsed.String(), "/") + "/", creds: creds,
+			client: &http.Client{Timeout: requestTimeout}}, nil
+	case "s3":
+		return newS3Target(parsed, creds)
+	default:
+		return nil, fmt.Errorf("неподдерживаемое место резервных копий: %s", destination)
+	}
+}
+
+// dirTarget хранит копии в директории
+type dirTarget struct {
+	dir string
+}
+
+func (t *dirTarget) Put(ctx context.Context, name string, data []byte) error {
+	if err := os.MkdirAll(t.dir, 0700); err != nil {
+		return err
+	}
+	path := filepath.Join(t.dir, filepath.Base(name))
+	if err := os.WriteFile(path+".tmp", data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+func (t *dirTarget) Get(ctx context.Context, name string) ([]byte, error) {
+	return os.ReadFile(filepath.Join(t.dir, filepath.Base(name)))
+}
+
+func (t *dirTarget) List(ctx context.Context) ([]string, error) {
+	entries, err := os.ReadDir(t.dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, entry := range entries {
+		if entry.Type().IsRegular() && isBackupName(entry.Name()) {
+			names = append(names, entry.Name())
+		}
+	}
+	return names, nil
+}
+
+func (t *dirTarget) Delete(ctx context.Context, name string) error {
+	return os.Remove(filepath.Join(t.dir, filepath.Base(name)))
+}
+
+// webdavTarget хранит копии на WebDAV-сервере (Nextcloud, ownCloud и т.п.)
+type webdavTarget struct {
+	base   string
+	creds  Credentials
+	client *http.Client
+}
+
+func (t *webdavTarget) Put(ctx context.Context, name string, data []byte) error {
+	_, err := t.do(ctx, http.MethodPut, name, bytes.NewReader(data), nil)
+	return err
+}
+
+func (t *webdavTarget) Get(ctx context.Context, name string) ([]byte, error) {
+	return t.do(ctx, http.MethodGet, name, nil, nil)
+}
+
+func (t *webdavTarget) Delete(ctx context.Context, name string) error {
+	_, err := t.do(ctx, http.MethodDelete, name, nil, nil)
+	return err
+}
+
+func (t *webdavTarget) List(ctx context.Context) ([]string, error) {
+	body, err := t.do(ctx, "PROPFIND", "", strings.NewReader(propfindBody), map[string]string{
+		"Depth":        "1",
+		"Content-Type": "application/xml",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		Responses []struct {
+			Href string `xml:"href"`
+		} `xml:"response"`
+	}
+	if err := xml.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("некорректный ответ WebDAV: %w", err)
+	}
+
+	var names []string
+	for _, response := range result.Responses {
+		href, err := url.PathUnescape(response.Href)
+		if err != nil {
+			continue
+		}
+		if name := path.Base(strings.TrimSuffix(href, "/")); isBackupName(name) {
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
+
+// propfindBody запрашивает только список файлов без свойств
+const propfindBody = `<?xml version="1.0" encoding="utf-8"?><propfind xmlns="DAV:"><prop><resourcetype/></prop></propfind>`
+
+// do выполняет запрос к файлу name в директории WebDAV
+func (t *webdavTarget) do(ctx context.Context, method, name string, body io.Reader, headers map[string]string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, method, t.base+url.PathEscape(name), body)
+	if err != nil {
+		return nil, err
+	}
+	if t.creds.Username != "" {
+		req.SetBasicAuth(t.creds.Username, t.creds.Password)
+	}
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, backupLimit))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("WebDAV %s %s: %s", method, name, resp.Status)
+	}
+	return data, nil
+}
+
+// backupPrefix и backupExt задают имена копий: owlwhisper-20060102-150405.owlbak
+const (
+	backupPrefix = "owlwhisper-"
+	backupExt    = ".owlbak"
+)
+
+// backupName возвращает имя копии, созданной в момент t. Имена
+// сортируются лексикографически в порядке создания
+func backupName(t time.Time) string {
+	return backupPrefix + t.UTC().Format("20060102-150405") + backupExt
+}
+
+// isBackupName проверяет, что файл - резервная копия OwlWhisper
+func isBackupName(name string) bool {
+	return strings.HasPrefix(name, backupPrefix) && strings.HasSuffix(name, backupExt)
+}
+
+// sortedBackups возвращает копии хранилища от старых к новым
+func sortedBackups(ctx context.Context, target Target) ([]string, error) {
+	names, err := target.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+	return names, nil
+}
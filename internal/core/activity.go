@@ -0,0 +1,58 @@
+package core
+
+import (
+	"log"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/connmgr"
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+// Вклад действий в оценку активности пира для менеджера соединений
+const (
+	activityMessage     = 5
+	activityStream      = 3
+	activityFile        = 10
+	activityScreenShare = 10
+)
+
+const (
+	// activityTagName - убывающая метка активности в менеджере соединений
+	activityTagName = "owl-activity"
+	// activityDecayInterval - как часто оценка активности уменьшается
+	activityDecayInterval = time.Minute
+	// activityDecayFactor - доля оценки, остающаяся после каждого интервала:
+	// без общения пир опускается в общий ряд примерно за час
+	activityDecayFactor = 0.95
+	// maxActivityScore ограничивает оценку, чтобы один бурный диалог
+	// не перевешивал все метки и защиты
+	maxActivityScore = 100
+)
+
+// startActivityScoring регистрирует убывающую метку активности. Пиры,
+// с которыми идет общение, закрываются менеджером соединений последними,
+// после простаивающих пиров DHT
+func (n *Node) startActivityScoring() {
+	decayer, ok := connmgr.SupportsDecay(n.host.ConnManager())
+	if !ok {
+		return
+	}
+
+	tag, err := decayer.RegisterDecayingTag(activityTagName, activityDecayInterval,
+		connmgr.DecayLinear(activityDecayFactor), connmgr.BumpSumBounded(0, maxActivityScore))
+	if err != nil {
+		log.Printf("⚠️ Не удалось зарегистрировать метку активности: %v", err)
+		return
+	}
+	n.activity = tag
+}
+
+// bumpActivity учитывает действие с пиром в его оценке
+func (n *Node) bumpActivity(id peer.ID, points int) {
+	if n.activity == nil {
+		return
+	}
+	if err := n.activity.Bump(id, points); err != nil {
+		log.Printf("⚠️ Не удалось обновить активность %s: %v", id.ShortString(), err)
+	}
+}
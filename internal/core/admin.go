@@ -0,0 +1,174 @@
+package core
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+// ADMIN_PROTOCOL_ID - протокол удаленного управления своим узлом без SSH
+const ADMIN_PROTOCOL_ID = "/owl-whisper/admin/1.0.0"
+
+// Команды управления узлом
+const (
+	// AdminStats - состояние узла и счетчики
+	AdminStats = "stats"
+	// AdminRotateLogs - начать новый файл журнала
+	AdminRotateLogs = "rotate-logs"
+	// AdminBan - запретить соединения с пиром (аргумент - PeerID)
+	AdminBan = "ban"
+	// AdminUnban - снять запрет с пира (аргумент - PeerID)
+	AdminUnban = "unban"
+	// AdminReload - перечитать конфигурацию с диска
+	AdminReload = "reload"
+)
+
+const (
+	// adminTimeout - предельное время выполнения команды
+	adminTimeout = 30 * time.Second
+	// adminLimit - максимальный размер запроса и ответа
+	adminLimit = 256 * 1024
+)
+
+// ErrAdminDenied - пир не входит в список администраторов узла
+var ErrAdminDenied = errors.New("нет прав на управление узлом")
+
+// AdminRequest - команда администратора
+type AdminRequest struct {
+	Command string   `json:"command"`
+	Args    []string `json:"args,omitempty"`
+}
+
+// adminResponse - ответ узла на команду
+type adminResponse struct {
+	OK     bool            `json:"ok"`
+	Error  string          `json:"error,omitempty"`
+	Result json.RawMessage `json:"result,omitempty"`
+}
+
+// AdminHandler выполняет команду администратора. Результат передается
+// администратору в виде JSON
+type AdminHandler func(from peer.ID, request AdminRequest) (any, error)
+
+// adminAccess - кто может управлять узлом и кто выполняет команды
+type adminAccess struct {
+	mu      sync.RWMutex
+	peers   map[peer.ID]bool
+	handler AdminHandler
+}
+
+// SetAdminPeers задает пиров, которым разрешено управлять узлом; пустой
+// список запрещает управление
+func (n *Node) SetAdminPeers(ids []peer.ID) {
+	peers := make(map[peer.ID]bool, len(ids))
+	for _, id := range ids {
+		peers[id] = true
+	}
+	n.admin.mu.Lock()
+	n.admin.peers = peers
+	n.admin.mu.Unlock()
+}
+
+// SetAdminHandler задает исполнителя команд администратора (обычно приложение)
+func (n *Node) SetAdminHandler(handler AdminHandler) {
+	n.admin.mu.Lock()
+	n.admin.handler = handler
+	n.admin.mu.Unlock()
+}
+
+// SendAdminCommand выполняет команду на узле to, где мы указаны
+// администратором, и возвращает результат в виде JSON
+func (n *Node) SendAdminCommand(ctx context.Context, to peer.ID, command string, args ...string) (json.RawMessage, error) {
+	ctx, cancel := context.WithTimeout(ctx, adminTimeout)
+	defer cancel()
+
+	stream, err := n.newStream(ctx, to, ADMIN_PROTOCOL_ID)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось открыть поток к %s: %w", to.ShortString(), err)
+	}
+	defer stream.Close()
+	stream.SetDeadline(time.Now().Add(adminTimeout))
+
+	data, err := json.Marshal(AdminRequest{Command: command, Args: args})
+	if err != nil {
+		return nil, err
+	}
+	if _, err := stream.Write(append(data, '\n')); err != nil {
+		return nil, fmt.Errorf("не удалось отправить команду: %w", err)
+	}
+
+	line, err := bufio.NewReader(io.LimitReader(stream, adminLimit)).ReadBytes('\n')
+	if err != nil {
+		return nil, fmt.Errorf("узел %s не ответил: %w", to.ShortString(), err)
+	}
+	var response adminResponse
+	if err := json.Unmarshal(line, &response); err != nil {
+		return nil, fmt.Errorf("некорректный ответ узла: %w", err)
+	}
+	if !response.OK {
+		return nil, errors.New(response.Error)
+	}
+	return response.Result, nil
+}
+
+// handleAdminStream выполняет команду, если пир входит в список
+// администраторов. Попытки посторонних попадают в журнал безопасности
+func (n *Node) handleAdminStream(stream network.Stream) {
+	defer stream.Close()
+	stream.SetDeadline(time.Now().Add(adminTimeout))
+	remotePeer := stream.Conn().RemotePeer()
+
+	n.admin.mu.RLock()
+	allowed := n.admin.peers[remotePeer]
+	handler := n.admin.handler
+	n.admin.mu.RUnlock()
+
+	if !allowed || handler == nil {
+		n.emitSecurity(SecurityAdminDenied, SeverityWarning, remotePeer,
+			stream.Conn().RemoteMultiaddr().String(), "попытка управления узлом без прав")
+		writeAdminResponse(stream, nil, ErrAdminDenied)
+		return
+	}
+
+	line, err := bufio.NewReader(io.LimitReader(stream, adminLimit)).ReadBytes('\n')
+	if err != nil {
+		stream.Reset()
+		return
+	}
+	var request AdminRequest
+	if err := json.Unmarshal(line, &request); err != nil {
+		writeAdminResponse(stream, nil, fmt.Errorf("некорректная команда"))
+		return
+	}
+
+	log.Printf("🛠️ Команда администратора %s: %s %s", remotePeer.ShortString(), request.Command, strings.Join(request.Args, " "))
+	result, err := handler(remotePeer, request)
+	writeAdminResponse(stream, result, err)
+}
+
+// writeAdminResponse отправляет результат команды или ошибку
+func writeAdminResponse(stream network.Stream, result any, err error) {
+	response := adminResponse{OK: err == nil}
+	if err != nil {
+		response.Error = err.Error()
+	} else if result != nil {
+		data, marshalErr := json.Marshal(result)
+		if marshalErr != nil {
+			response = adminResponse{Error: marshalErr.Error()}
+		} else {
+			response.Result = data
+		}
+	}
+	data, _ := json.Marshal(response)
+	stream.Write(append(data, '\n'))
+}
@@ -0,0 +1,187 @@
+package core
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+// ATTACHMENT_PROTOCOL_ID - протокол повторного запроса вложения по SHA-256.
+// Пир, у которого есть копия, отвечает обычной передачей по FILE_PROTOCOL_ID
+const ATTACHMENT_PROTOCOL_ID = "/owl-whisper/attachment/1.0.0"
+
+const (
+	// attachmentRequestLimit - максимальный размер запроса (хеш и перевод строки)
+	attachmentRequestLimit = 128
+	// attachmentRequestTimeout - сколько ждать ответа на запрос
+	attachmentRequestTimeout = 30 * time.Second
+	// attachmentExpectTTL - сколько ждать передачи после согласия пира
+	attachmentExpectTTL = 10 * time.Minute
+)
+
+// Ответы на запрос вложения
+const (
+	attachmentMissing byte = 0
+	attachmentFound   byte = 1
+)
+
+// ErrAttachmentNotFound - ни один из опрошенных пиров не отдал вложение
+var ErrAttachmentNotFound = errors.New("вложение не найдено у пиров")
+
+// AttachmentResolver возвращает путь к локальной копии вложения по SHA-256
+type AttachmentResolver func(hash string) (path string, ok bool)
+
+// attachmentRequests - разрешение отдавать вложения и ожидаемые передачи
+type attachmentRequests struct {
+	mu       sync.Mutex
+	resolver AttachmentResolver
+	// expected - запрошенные у конкретных пиров хеши и срок, до которого их
+	// передача принимается без вопроса пользователю
+	expected map[attachmentKey]time.Time
+}
+
+// attachmentKey - запрошенное вложение: хеш и пир, который обещал его отдать.
+// Тот же хеш от другого пира не считается запрошенным
+type attachmentKey struct {
+	provider peer.ID
+	hash     string
+}
+
+// SetAttachmentResolver подключает поиск локальных копий вложений. Без него
+// узел не отдает вложения по запросам пиров
+func (n *Node) SetAttachmentResolver(resolver AttachmentResolver) {
+	n.attachments.mu.Lock()
+	n.attachments.resolver = resolver
+	n.attachments.mu.Unlock()
+}
+
+// FetchAttachment запрашивает вложение с хешем hash у providers по очереди,
+// пока кто-то не согласится его отдать. Сам файл приходит обычной передачей
+// от согласившегося пира и принимается автоматически, если его содержимое
+// совпадает с хешем; в событии EventFileReceived у него Requested
+func (n *Node) FetchAttachment(ctx context.Context, hash string, providers []peer.ID) (peer.ID, error) {
+	hash = strings.ToLower(hash)
+
+	for _, provider := range providers {
+		if provider == n.host.ID() {
+			continue
+		}
+		// Ожидание ставится до запроса: пир начинает передачу сразу после ответа
+		n.attachments.expect(provider, hash)
+		found, err := n.requestAttachment(ctx, provider, hash)
+		if err != nil {
+			n.attachments.forget(provider, hash)
+			log.Printf("⚠️ Запрос вложения у %s не удался: %v", provider.ShortString(), err)
+			continue
+		}
+		if found {
+			return provider, nil
+		}
+		n.attachments.forget(provider, hash)
+	}
+
+	return "", ErrAttachmentNotFound
+}
+
+// requestAttachment спрашивает одного пира, есть ли у него вложение
+func (n *Node) requestAttachment(ctx context.Context, provider peer.ID, hash string) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, attachmentRequestTimeout)
+	defer cancel()
+
+	stream, err := n.newStream(ctx, provider, ATTACHMENT_PROTOCOL_ID)
+	if err != nil {
+		return false, fmt.Errorf("не удалось открыть поток: %w", err)
+	}
+	defer stream.Close()
+	stream.SetDeadline(time.Now().Add(attachmentRequestTimeout))
+
+	if _, err := stream.Write([]byte(hash + "\n")); err != nil {
+		return false, err
+	}
+	reply := make([]byte, 1)
+	if _, err := io.ReadFull(stream, reply); err != nil {
+		return false, err
+	}
+	return reply[0] == attachmentFound, nil
+}
+
+// handleAttachmentStream отвечает на запрос вложения. Вложения отдаются только
+// контактам, чтобы посторонние не могли проверять, какие файлы есть у узла
+func (n *Node) handleAttachmentStream(stream network.Stream) {
+	defer stream.Close()
+	remotePeer := stream.Conn().RemotePeer()
+	stream.SetDeadline(time.Now().Add(attachmentRequestTimeout))
+
+	line, err := bufio.NewReader(io.LimitReader(stream, attachmentRequestLimit)).ReadString('\n')
+	if err != nil {
+		stream.Reset()
+		return
+	}
+	hash := strings.ToLower(strings.TrimSpace(line))
+
+	n.attachments.mu.Lock()
+	resolver := n.attachments.resolver
+	n.attachments.mu.Unlock()
+
+	path, ok := "", false
+	if resolver != nil && n.isKnownContact(remotePeer) {
+		path, ok = resolver(hash)
+	}
+	if !ok {
+		stream.Write([]byte{attachmentMissing})
+		return
+	}
+
+	if _, err := stream.Write([]byte{attachmentFound}); err != nil {
+		return
+	}
+	log.Printf("📎 %s запросил вложение %s", remotePeer.ShortString(), shortHash(hash))
+	n.SendFile(remotePeer, path, PriorityBackground)
+}
+
+// expect отмечает хеш как запрошенный у provider
+func (a *attachmentRequests) expect(provider peer.ID, hash string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.expected == nil {
+		a.expected = make(map[attachmentKey]time.Time)
+	}
+	a.expected[attachmentKey{provider, hash}] = time.Now().Add(attachmentExpectTTL)
+}
+
+// forget снимает ожидание хеша от provider
+func (a *attachmentRequests) forget(provider peer.ID, hash string) {
+	a.mu.Lock()
+	delete(a.expected, attachmentKey{provider, hash})
+	a.mu.Unlock()
+}
+
+// claim проверяет, что передача с таким хешем была запрошена у provider,
+// и снимает ожидание
+func (a *attachmentRequests) claim(provider peer.ID, hash string) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	key := attachmentKey{provider, strings.ToLower(hash)}
+	deadline, ok := a.expected[key]
+	delete(a.expected, key)
+	return ok && time.Now().Before(deadline)
+}
+
+// shortHash сокращает хеш для логов
+func shortHash(hash string) string {
+	if len(hash) > 12 {
+		return hash[:12]
+	}
+	return hash
+}
@@ -0,0 +1,494 @@
+package core
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"OwlWhisper/pkg/interfaces"
+
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+// ATTESTATION_PROTOCOL_ID - протокол запроса подтверждений личности контакта
+const ATTESTATION_PROTOCOL_ID = "/owl-whisper/attestations/1.0.0"
+
+// Сервисы внешних аккаунтов, для которых проверяется доказательство
+const (
+	// AttestationGitHub - аккаунт GitHub; доказательство - публичный gist владельца
+	AttestationGitHub = "github"
+	// AttestationWeb - домен; доказательство - файл WebProofPath на этом домене по HTTPS
+	AttestationWeb = "web"
+)
+
+// WebProofPath - путь доказательства для домена. Путь фиксирован: иначе
+// доказательством служила бы любая страница, которую можно опубликовать на
+// чужом домене (комментарий, форум, issue)
+const WebProofPath = "/.well-known/owlwhisper.txt"
+
+const (
+	// attestationPrefix - начало текстового вида подтверждения для публикации
+	attestationPrefix = "owlwhisper-attestation:"
+	// attestationTimeout - предельное время запроса подтверждений и загрузки доказательства
+	attestationTimeout = 15 * time.Second
+	// attestationProofLimit - сколько байт доказательства загружать
+	attestationProofLimit = 256 * 1024
+	// maxAttestations - сколько подтверждений может опубликовать узел
+	maxAttestations = 16
+)
+
+var (
+	// ErrAttestationSignature - подпись подтверждения не прошла проверку
+	ErrAttestationSignature = errors.New("подпись подтверждения недействительна")
+
+	// ErrAttestationProof - доказательство не найдено по указанному адресу
+	ErrAttestationProof = errors.New("доказательство не найдено")
+)
+
+// Attestation - подписанное ключом узла утверждение "этот PeerID владеет
+// аккаунтом Account в сервисе Service". ProofURL не подписывается: адрес
+// публикации становится известен только после нее
+type Attestation struct {
+	PeerID    peer.ID   `json:"peer_id"`
+	Service   string    `json:"service"`
+	Account   string    `json:"account"`
+	IssuedAt  time.Time `json:"issued_at"`
+	Signature []byte    `json:"signature"`
+	ProofURL  string    `json:"proof_url,omitempty"`
+}
+
+// AttestationCheck - результат проверки подтверждения контакта
+type AttestationCheck struct {
+	Attestation Attestation
+	// SignatureValid - подпись сделана ключом PeerID
+	SignatureValid bool
+	// ProofFound - текст подтверждения опубликован по ProofURL
+	ProofFound bool
+	Error      string
+}
+
+// attestations - опубликованные узлом подтверждения
+type attestations struct {
+	mu   sync.RWMutex
+	repo interfaces.IAttestationRepository
+	own  []Attestation
+}
+
+// statement возвращает подписываемый текст подтверждения
+func (a Attestation) statement() []byte {
+	return []byte(fmt.Sprintf("owl-whisper attestation\npeer:%s\nservice:%s\naccount:%s\nissued:%s\n",
+		a.PeerID, a.Service, a.Account, a.IssuedAt.UTC().Format(time.RFC3339)))
+}
+
+// Encode возвращает текстовый вид подтверждения для публикации в сервисе
+func (a Attestation) Encode() string {
+	signed := a
+	signed.ProofURL = ""
+	data, _ := json.Marshal(signed)
+	return attestationPrefix + base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeAttestation разбирает текстовый вид подтверждения
+func DecodeAttestation(text string) (Attestation, error) {
+	text = strings.TrimSpace(text)
+	if !strings.HasPrefix(text, attestationPrefix) {
+		return Attestation{}, fmt.Errorf("это не подтверждение OwlWhisper")
+	}
+	data, err := base64.RawURLEncoding.DecodeString(strings.TrimPrefix(text, attestationPrefix))
+	if err != nil {
+		return Attestation{}, fmt.Errorf("некорректное подтверждение: %w", err)
+	}
+	var a Attestation
+	if err := json.Unmarshal(data, &a); err != nil {
+		return Attestation{}, fmt.Errorf("некорректное подтверждение: %w", err)
+	}
+	return a, nil
+}
+
+// CreateAttestation подписывает утверждение о владении аккаунтом. Его текстовый
+// вид (Encode) нужно опубликовать в сервисе и указать адрес в PublishAttestation
+func (n *Node) CreateAttestation(service, account string) (Attestation, error) {
+	service = strings.ToLower(strings.TrimSpace(service))
+	account = strings.TrimSpace(account)
+	if err := checkAttestationAccount(service, account); err != nil {
+		return Attestation{}, err
+	}
+
+	key := n.host.Peerstore().PrivKey(n.host.ID())
+	if key == nil {
+		return Attestation{}, fmt.Errorf("закрытый ключ узла недоступен")
+	}
+	a := Attestation{
+		PeerID:   n.host.ID(),
+		Service:  service,
+		Account:  account,
+		IssuedAt: time.Now().UTC().Truncate(time.Second),
+	}
+	signature, err := key.Sign(a.statement())
+	if err != nil {
+		return Attestation{}, fmt.Errorf("не удалось подписать подтверждение: %w", err)
+	}
+	a.Signature = signature
+	return a, nil
+}
+
+// LoadAttestations загружает свои подтверждения и сохраняет в хранилище изменения
+func (n *Node) LoadAttestations(repo interfaces.IAttestationRepository) error {
+	records, err := repo.GetAttestations(context.Background())
+	if err != nil {
+		return fmt.Errorf("не удалось загрузить подтверждения: %w", err)
+	}
+
+	var own []Attestation
+	for _, record := range records {
+		a, err := DecodeAttestation(record.Statement)
+		if err != nil || a.PeerID != n.host.ID() {
+			continue
+		}
+		a.ProofURL = record.ProofURL
+		own = append(own, a)
+	}
+
+	n.attestations.mu.Lock()
+	n.attestations.repo = repo
+	n.attestations.own = own
+	n.attestations.mu.Unlock()
+	return nil
+}
+
+// PublishAttestation делает подтверждение доступным контактам. Подтверждение
+// того же аккаунта заменяется, что позволяет обновить адрес доказательства
+func (n *Node) PublishAttestation(a Attestation, proofURL string) error {
+	if a.PeerID != n.host.ID() {
+		return fmt.Errorf("подтверждение выдано другому PeerID")
+	}
+	if err := VerifyAttestation(a); err != nil {
+		return err
+	}
+	if proofURL != "" {
+		if err := checkProofURL(a, proofURL); err != nil {
+			return err
+		}
+	}
+	a.ProofURL = proofURL
+
+	n.attestations.mu.Lock()
+	own := make([]Attestation, 0, len(n.attestations.own)+1)
+	for _, existing := range n.attestations.own {
+		if existing.Service != a.Service || !strings.EqualFold(existing.Account, a.Account) {
+			own = append(own, existing)
+		}
+	}
+	if len(own) >= maxAttestations {
+		n.attestations.mu.Unlock()
+		return fmt.Errorf("опубликовано максимальное число подтверждений: %d", maxAttestations)
+	}
+	own = append(own, a)
+	n.attestations.own = own
+	n.attestations.mu.Unlock()
+
+	return n.saveAttestations(own)
+}
+
+// RemoveAttestation снимает подтверждение аккаунта с публикации
+func (n *Node) RemoveAttestation(service, account string) (bool, error) {
+	n.attestations.mu.Lock()
+	own := make([]Attestation, 0, len(n.attestations.own))
+	for _, existing := range n.attestations.own {
+		if existing.Service != service || !strings.EqualFold(existing.Account, account) {
+			own = append(own, existing)
+		}
+	}
+	removed := len(own) != len(n.attestations.own)
+	n.attestations.own = own
+	n.attestations.mu.Unlock()
+
+	if !removed {
+		return false, nil
+	}
+	return true, n.saveAttestations(own)
+}
+
+// Attestations возвращает свои опубликованные подтверждения
+func (n *Node) Attestations() []Attestation {
+	n.attestations.mu.RLock()
+	defer n.attestations.mu.RUnlock()
+
+	return append([]Attestation(nil), n.attestations.own...)
+}
+
+// saveAttestations сохраняет свои подтверждения в хранилище
+func (n *Node) saveAttestations(own []Attestation) error {
+	n.attestations.mu.RLock()
+	repo := n.attestations.repo
+	n.attestations.mu.RUnlock()
+	if repo == nil {
+		return nil
+	}
+
+	records := make([]interfaces.AttestationRecord, 0, len(own))
+	for _, a := range own {
+		records = append(records, interfaces.AttestationRecord{Statement: a.Encode(), ProofURL: a.ProofURL})
+	}
+	if err := repo.SaveAttestations(context.Background(), records); err != nil {
+		return fmt.Errorf("не удалось сохранить подтверждения: %w", err)
+	}
+	return nil
+}
+
+// RequestAttestations запрашивает у контакта его подтверждения. Подписи
+// не проверяются - для этого есть VerifyAttestation и CheckAttestations
+func (n *Node) RequestAttestations(ctx context.Context, id peer.ID) ([]Attestation, error) {
+	ctx, cancel := context.WithTimeout(ctx, attestationTimeout)
+	defer cancel()
+
+	stream, err := n.newStream(ctx, id, ATTESTATION_PROTOCOL_ID)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось открыть поток к %s: %w", id.ShortString(), err)
+	}
+	defer stream.Close()
+	stream.SetDeadline(time.Now().Add(attestationTimeout))
+
+	var list []Attestation
+	if err := json.NewDecoder(io.LimitReader(stream, 64*1024)).Decode(&list); err != nil {
+		return nil, fmt.Errorf("некорректный ответ от %s: %w", id.ShortString(), err)
+	}
+	if len(list) > maxAttestations {
+		list = list[:maxAttestations]
+	}
+	return list, nil
+}
+
+// CheckAttestations запрашивает подтверждения контакта и проверяет каждое.
+// fetchProofs включает загрузку доказательств из внешних сервисов: запросы
+// раскрывают сервисам, чьи подтверждения проверяются
+func (n *Node) CheckAttestations(ctx context.Context, id peer.ID, fetchProofs bool) ([]AttestationCheck, error) {
+	list, err := n.RequestAttestations(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	checks := make([]AttestationCheck, 0, len(list))
+	for _, a := range list {
+		check := AttestationCheck{Attestation: a}
+		if a.PeerID != id {
+			check.Error = "подтверждение выдано другому PeerID"
+		} else if err := VerifyAttestation(a); err != nil {
+			check.Error = err.Error()
+		} else {
+			check.SignatureValid = true
+			if fetchProofs {
+				if err := VerifyAttestationProof(ctx, a); err != nil {
+					check.Error = err.Error()
+				} else {
+					check.ProofFound = true
+				}
+			}
+		}
+		checks = append(checks, check)
+	}
+	return checks, nil
+}
+
+// handleAttestationStream отдает свои подтверждения контактам
+func (n *Node) handleAttestationStream(stream network.Stream) {
+	defer stream.Close()
+	stream.SetDeadline(time.Now().Add(attestationTimeout))
+
+	list := []Attestation{}
+	if n.isKnownContact(stream.Conn().RemotePeer()) {
+		list = n.Attestations()
+	}
+	json.NewEncoder(stream).Encode(list)
+}
+
+// VerifyAttestation проверяет подпись подтверждения ключом из PeerID
+func VerifyAttestation(a Attestation) error {
+	if err := checkAttestationAccount(a.Service, a.Account); err != nil {
+		return err
+	}
+	pub, err := a.PeerID.ExtractPublicKey()
+	if err != nil {
+		return fmt.Errorf("не удалось извлечь ключ из PeerID: %w", err)
+	}
+	ok, err := pub.Verify(a.statement(), a.Signature)
+	if err != nil || !ok {
+		return ErrAttestationSignature
+	}
+	return nil
+}
+
+// VerifyAttestationProof загружает доказательство и проверяет, что там
+// опубликован текст подтверждения, а адрес принадлежит заявленному аккаунту.
+// Владелец gist сверяется через API GitHub, а не по пути в адресе
+func VerifyAttestationProof(ctx context.Context, a Attestation) error {
+	if a.ProofURL == "" {
+		return fmt.Errorf("адрес доказательства не указан")
+	}
+	if err := checkProofURL(a, a.ProofURL); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, attestationTimeout)
+	defer cancel()
+
+	var proof string
+	switch a.Service {
+	case AttestationGitHub:
+		gist, err := fetchGist(ctx, a.ProofURL)
+		if err != nil {
+			return err
+		}
+		if !strings.EqualFold(gist.Owner.Login, a.Account) {
+			return fmt.Errorf("gist принадлежит %s, а не %s", gist.Owner.Login, a.Account)
+		}
+		for _, file := range gist.Files {
+			proof += file.Content + "\n"
+		}
+	default:
+		body, final, err := fetchProof(ctx, a.ProofURL)
+		if err != nil {
+			return err
+		}
+		if err := checkProofURL(a, final.String()); err != nil {
+			return err
+		}
+		proof = string(body)
+	}
+
+	if !strings.Contains(proof, a.Encode()) {
+		return ErrAttestationProof
+	}
+	return nil
+}
+
+// proofClient загружает доказательства и не следует перенаправлениям на
+// другой хост: иначе адрес на домене или в gist мог бы указать на чужую
+// страницу
+var proofClient = &http.Client{
+	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		if len(via) >= 5 {
+			return fmt.Errorf("слишком много перенаправлений")
+		}
+		if req.URL.Scheme != "https" || !strings.EqualFold(req.URL.Hostname(), via[0].URL.Hostname()) {
+			return fmt.Errorf("перенаправление на другой адрес %s запрещено", req.URL.Redacted())
+		}
+		return nil
+	},
+}
+
+// fetchProof загружает адрес и возвращает содержимое и адрес, с которого
+// оно в итоге получено
+func fetchProof(ctx context.Context, proofURL string) ([]byte, *url.URL, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, proofURL, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	resp, err := proofClient.Do(req)
+	if err != nil {
+		return nil, nil, fmt.Errorf("не удалось загрузить доказательство: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil, fmt.Errorf("не удалось загрузить доказательство: %s", resp.Status)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, attestationProofLimit))
+	if err != nil {
+		return nil, nil, err
+	}
+	return body, resp.Request.URL, nil
+}
+
+// githubGist - нужная часть ответа api.github.com/gists/<id>
+type githubGist struct {
+	Owner struct {
+		Login string `json:"login"`
+	} `json:"owner"`
+	Files map[string]struct {
+		Content string `json:"content"`
+	} `json:"files"`
+}
+
+// fetchGist загружает gist из адреса доказательства через API GitHub
+func fetchGist(ctx context.Context, proofURL string) (githubGist, error) {
+	parsed, err := url.Parse(proofURL)
+	if err != nil {
+		return githubGist{}, err
+	}
+	// gist.github.com/<owner>/<id> и gist.githubusercontent.com/<owner>/<id>/raw/...
+	parts := strings.Split(strings.Trim(parsed.Path, "/"), "/")
+	if len(parts) < 2 || !isGistID(parts[1]) {
+		return githubGist{}, fmt.Errorf("в адресе доказательства нет ID gist")
+	}
+
+	body, _, err := fetchProof(ctx, "https://api.github.com/gists/"+parts[1])
+	if err != nil {
+		return githubGist{}, err
+	}
+	var gist githubGist
+	if err := json.Unmarshal(body, &gist); err != nil {
+		return githubGist{}, fmt.Errorf("некорректный ответ GitHub: %w", err)
+	}
+	return gist, nil
+}
+
+// isGistID проверяет, что строка похожа на ID gist
+func isGistID(id string) bool {
+	if id == "" || len(id) > 64 {
+		return false
+	}
+	for _, r := range id {
+		if (r < '0' || r > '9') && (r < 'a' || r > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// checkAttestationAccount проверяет сервис и имя аккаунта
+func checkAttestationAccount(service, account string) error {
+	if account == "" || len(account) > 253 || strings.ContainsAny(account, "/ \t\n") {
+		return fmt.Errorf("некорректный аккаунт %q", account)
+	}
+	switch service {
+	case AttestationGitHub, AttestationWeb:
+		return nil
+	default:
+		return fmt.Errorf("неизвестный сервис %q (поддерживаются %s и %s)", service, AttestationGitHub, AttestationWeb)
+	}
+}
+
+// checkProofURL проверяет, что доказательство опубликовано от имени аккаунта:
+// gist владельца на GitHub или WebProofPath на самом домене
+func checkProofURL(a Attestation, proofURL string) error {
+	parsed, err := url.Parse(proofURL)
+	if err != nil || parsed.Scheme != "https" {
+		return fmt.Errorf("адрес доказательства должен быть https URL")
+	}
+	host := strings.ToLower(parsed.Hostname())
+
+	switch a.Service {
+	case AttestationGitHub:
+		owner := strings.ToLower(strings.SplitN(strings.TrimPrefix(parsed.Path, "/"), "/", 2)[0])
+		if (host == "gist.github.com" || host == "gist.githubusercontent.com") && owner == strings.ToLower(a.Account) {
+			return nil
+		}
+		return fmt.Errorf("доказательство GitHub должно быть gist пользователя %s", a.Account)
+	case AttestationWeb:
+		if host == strings.ToLower(a.Account) && parsed.Port() == "" && parsed.Path == WebProofPath && parsed.RawQuery == "" {
+			return nil
+		}
+		return fmt.Errorf("доказательство должно быть размещено по адресу https://%s%s", a.Account, WebProofPath)
+	}
+	return fmt.Errorf("неизвестный сервис %q", a.Service)
+}
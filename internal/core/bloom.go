@@ -0,0 +1,57 @@
+package core
+
+import (
+	"hash/fnv"
+	"math"
+)
+
+// bloomFilter - вероятностное множество: отвечает "точно не было" или
+// "вероятно было" и занимает фиксированную память при любом числе элементов
+type bloomFilter struct {
+	bits   []uint64
+	hashes uint64
+}
+
+// newBloomFilter создает фильтр на expected элементов с долей ложных
+// срабатываний falsePositive
+func newBloomFilter(expected int, falsePositive float64) *bloomFilter {
+	m := math.Ceil(-float64(expected) * math.Log(falsePositive) / (math.Ln2 * math.Ln2))
+	k := math.Max(1, math.Round(m/float64(expected)*math.Ln2))
+	return &bloomFilter{
+		bits:   make([]uint64, (uint64(m)+63)/64),
+		hashes: uint64(k),
+	}
+}
+
+// add добавляет элемент
+func (f *bloomFilter) add(data []byte) {
+	h1, h2 := bloomHashes(data)
+	size := uint64(len(f.bits)) * 64
+	for i := uint64(0); i < f.hashes; i++ {
+		bit := (h1 + i*h2) % size
+		f.bits[bit/64] |= 1 << (bit % 64)
+	}
+}
+
+// has сообщает, мог ли элемент быть добавлен
+func (f *bloomFilter) has(data []byte) bool {
+	h1, h2 := bloomHashes(data)
+	size := uint64(len(f.bits)) * 64
+	for i := uint64(0); i < f.hashes; i++ {
+		bit := (h1 + i*h2) % size
+		if f.bits[bit/64]&(1<<(bit%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// bloomHashes возвращает две независимые хеш-функции для двойного хеширования
+func bloomHashes(data []byte) (uint64, uint64) {
+	h := fnv.New64a()
+	h.Write(data)
+	h1 := h.Sum64()
+	h.Write([]byte{0x5a})
+	h2 := h.Sum64() | 1
+	return h1, h2
+}
@@ -0,0 +1,176 @@
+package core
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/event"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/core/protocol"
+)
+
+// Возможности пира. Пир сообщает их протоколом identify при каждом
+// соединении: возможность есть, если пир объявил соответствующий протокол
+const (
+	CapabilityChat            = "chat"
+	CapabilityStreams         = "streams"
+	CapabilityFiles           = "files"
+	CapabilityPresence        = "presence"
+	CapabilityContactRequests = "contact_requests"
+	CapabilityScreenShare     = "screen_share"
+	CapabilityDHTProxy        = "dht_proxy"
+	CapabilityAttachments     = "attachments"
+	CapabilityPeerLookup      = "peer_lookup"
+	CapabilityIntroductions   = "introductions"
+	CapabilityContactCards    = "contact_cards"
+	CapabilityStatus          = "status"
+	CapabilityAttestations    = "attestations"
+	CapabilityKeyTransitions  = "key_transitions"
+	CapabilityIdentityWipe    = "identity_wipe"
+	CapabilityPadding         = "padding"
+	CapabilitySealedSender    = "sealed_sender"
+)
+
+// capabilityProtocols сопоставляет протоколы возможностям
+var capabilityProtocols = map[protocol.ID]string{
+	PROTOCOL_ID:                    CapabilityChat,
+	STREAM_PROTOCOL_ID:             CapabilityStreams,
+	FILE_PROTOCOL_ID:               CapabilityFiles,
+	PRESENCE_PROTOCOL_ID:           CapabilityPresence,
+	CONTACT_PROTOCOL_ID:            CapabilityContactRequests,
+	SCREEN_PROTOCOL_ID:             CapabilityScreenShare,
+	DHT_PROXY_PROTOCOL_ID:          CapabilityDHTProxy,
+	ATTACHMENT_PROTOCOL_ID:         CapabilityAttachments,
+	PEER_LOOKUP_PROTOCOL_ID:        CapabilityPeerLookup,
+	INTRODUCE_PROTOCOL_ID:          CapabilityIntroductions,
+	CONTACT_CARD_PROTOCOL_ID:       CapabilityContactCards,
+	STATUS_PROTOCOL_ID:             CapabilityStatus,
+	ATTESTATION_PROTOCOL_ID:        CapabilityAttestations,
+	KEY_TRANSITION_PROTOCOL_ID:     CapabilityKeyTransitions,
+	IDENTITY_DESTROYED_PROTOCOL_ID: CapabilityIdentityWipe,
+	PADDED_PROTOCOL_ID:             CapabilityPadding,
+	SEALED_PROTOCOL_ID:             CapabilitySealedSender,
+}
+
+// PeerCapabilities - возможности пира; полезная нагрузка события EventPeerCapabilities
+type PeerCapabilities struct {
+	PeerID       peer.ID         `json:"peer_id"`
+	AgentVersion string          `json:"agent_version"`
+	Capabilities map[string]bool `json:"capabilities"`
+	// UpdatedAt - когда пир последний раз сообщал возможности; результат
+	// берется из кэша, если пир сейчас не подключен
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Has сообщает, поддерживает ли пир возможность
+func (c PeerCapabilities) Has(capability string) bool {
+	return c.Capabilities[capability]
+}
+
+// capabilityCache хранит последние известные возможности пиров
+type capabilityCache struct {
+	mu    sync.RWMutex
+	peers map[peer.ID]PeerCapabilities
+}
+
+// watchCapabilities обновляет кэш возможностей по событиям identify
+func (n *Node) watchCapabilities() error {
+	sub, err := n.host.EventBus().Subscribe([]interface{}{
+		new(event.EvtPeerIdentificationCompleted),
+		new(event.EvtPeerProtocolsUpdated),
+	})
+	if err != nil {
+		return fmt.Errorf("не удалось подписаться на события identify: %w", err)
+	}
+
+	go func() {
+		defer sub.Close()
+		for {
+			select {
+			case <-n.ctx.Done():
+				return
+			case e, ok := <-sub.Out():
+				if !ok {
+					return
+				}
+				switch e := e.(type) {
+				// У пира в скрытом режиме протоколы запрашиваются отдельно,
+				// поэтому обновление не должно задерживать цикл событий
+				case event.EvtPeerIdentificationCompleted:
+					go n.refreshCapabilities(e.Peer)
+				case event.EvtPeerProtocolsUpdated:
+					go n.refreshCapabilities(e.Peer)
+				}
+			}
+		}
+	}()
+	return nil
+}
+
+// refreshCapabilities перечитывает возможности пира из peerstore
+// и публикует событие, если они изменились
+func (n *Node) refreshCapabilities(id peer.ID) {
+	protocols, err := n.host.Peerstore().GetProtocols(id)
+	if err != nil {
+		log.Printf("⚠️ Не удалось получить протоколы %s: %v", id.ShortString(), err)
+		return
+	}
+
+	caps := PeerCapabilities{
+		PeerID:       id,
+		Capabilities: make(map[string]bool),
+		UpdatedAt:    time.Now(),
+	}
+	if agent, err := n.host.Peerstore().Get(id, "AgentVersion"); err == nil {
+		caps.AgentVersion, _ = agent.(string)
+	}
+	if n.peerIsStealth(id) {
+		hidden, err := n.stealthProtocolsOf(id)
+		if err != nil {
+			log.Printf("⚠️ Не удалось получить скрытые протоколы %s: %v", id.ShortString(), err)
+		}
+		protocols = append(protocols, hidden...)
+	}
+	for _, p := range protocols {
+		if capability, ok := capabilityProtocols[p]; ok {
+			caps.Capabilities[capability] = true
+		}
+	}
+
+	n.capabilities.mu.Lock()
+	previous, known := n.capabilities.peers[id]
+	n.capabilities.peers[id] = caps
+	n.capabilities.mu.Unlock()
+
+	if !known || !sameCapabilities(previous, caps) {
+		n.emit(EventPeerCapabilities, caps)
+	}
+}
+
+// sameCapabilities сравнивает наборы возможностей и версию клиента
+func sameCapabilities(a, b PeerCapabilities) bool {
+	if a.AgentVersion != b.AgentVersion || len(a.Capabilities) != len(b.Capabilities) {
+		return false
+	}
+	for capability := range a.Capabilities {
+		if !b.Capabilities[capability] {
+			return false
+		}
+	}
+	return true
+}
+
+// GetPeerCapabilities возвращает возможности пира. Для подключенного пира
+// они актуальны, для отключенного - последние известные из кэша
+func (n *Node) GetPeerCapabilities(id peer.ID) (PeerCapabilities, error) {
+	n.capabilities.mu.RLock()
+	caps, ok := n.capabilities.peers[id]
+	n.capabilities.mu.RUnlock()
+
+	if !ok {
+		return PeerCapabilities{}, fmt.Errorf("возможности пира %s еще неизвестны", id.ShortString())
+	}
+	return caps, nil
+}
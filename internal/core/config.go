@@ -0,0 +1,104 @@
+package core
+
+import (
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/crypto"
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+// NodeConfig - параметры узла, задаваемые встраивающим приложением
+type NodeConfig struct {
+	// PrivateKey - ключ личности узла. Если не задан, генерируется временный
+	// и PeerID меняется при каждом запуске
+	PrivateKey crypto.PrivKey
+
+	// ListenPort - порт для входящих соединений (0 - выбрать автоматически)
+	ListenPort int
+	// EnableNAT включает определение внешнего адреса и работу с NAT
+	EnableNAT bool
+	// EnableHolePunching включает пробивание NAT
+	EnableHolePunching bool
+	// EnableRelay включает Circuit Relay v2 как запасной путь соединения
+	EnableRelay bool
+	// RelayService - узел сам ретранслирует соединения других участников
+	// (роль relay в карточке сервера); требует EnableRelay
+	RelayService bool
+
+	// HideIP - режим скрытия IP: узел анонсирует только адреса через
+	// ретрансляторы, не пробивает NAT и не раскрывает адрес через AutoNAT.
+	// Пиры соединяются с узлом только через ретранслятор
+	HideIP bool
+
+	// RelayNodes - известные ретрансляторы для резервирования
+	RelayNodes []peer.AddrInfo
+	// PinnedRelays - ретрансляторы, которые пробуются первыми и резервируются всегда
+	PinnedRelays []peer.AddrInfo
+
+	// TransportPolicy - предпочитаемый транспорт и режим ретрансляции по умолчанию
+	TransportPolicy TransportPolicy
+
+	// ConnectionLimits - ограничение частоты входящих соединений по IP и подсети
+	ConnectionLimits ConnectionLimits
+
+	// StreamWriteTimeout - предельное время одной записи в поток данных.
+	// Ноль отключает дедлайны (запись может зависнуть на остановившемся пире)
+	StreamWriteTimeout time.Duration
+
+	// StreamSendBufferSize - размер буфера отправки каждого потока в байтах
+	StreamSendBufferSize int
+
+	// MaxConcurrentTransfers - сколько передач планировщик выполняет одновременно
+	MaxConcurrentTransfers int
+
+	// Transfers - правила автоприема и место сохранения файлов
+	Transfers TransferPolicy
+
+	// ContactRequests - защита от массовых запросов на добавление в контакты
+	ContactRequests ContactRequestPolicy
+
+	// LastSeenPolicy - кому сообщать время последней активности (пусто - всем)
+	LastSeenPolicy LastSeenPolicy
+
+	// StatusMessage - статус, который видят контакты ("в отпуске")
+	StatusMessage string
+
+	// WebSocketPort - порт, на котором узел принимает WebSocket (обычно за
+	// обратным прокси с TLS на :443 для клиентов с маскировкой); 0 - выключен
+	WebSocketPort int
+
+	// PeerCacheSize - сколько пиров помнит кэш адресов (0 - 4096)
+	PeerCacheSize int
+
+	// Multipath - держать измерения всех соединений с контактами и отправлять
+	// короткие служебные сообщения по самому быстрому исправному пути
+	Multipath bool
+
+	// UserAgent - строка клиента, которую видят пиры в identify.
+	// Пусто - "OwlWhisper/<версия>" (в скрытом режиме - нейтральная строка)
+	UserAgent string
+
+	// Stealth - скрытый режим: через identify объявляется только протокол
+	// рукопожатия, а протоколы OwlWhisper открываются через него только
+	// контактам
+	Stealth bool
+}
+
+// DefaultNodeConfig возвращает параметры узла по умолчанию
+func DefaultNodeConfig() NodeConfig {
+	return NodeConfig{
+		EnableNAT:              true,
+		EnableHolePunching:     true,
+		EnableRelay:            true,
+		ConnectionLimits:       DefaultConnectionLimits(),
+		StreamWriteTimeout:     30 * time.Second,
+		StreamSendBufferSize:   1 << 20,
+		MaxConcurrentTransfers: 3,
+		ContactRequests:        DefaultContactRequestPolicy(),
+		LastSeenPolicy:         LastSeenEveryone,
+		Multipath:              true,
+		Transfers: TransferPolicy{
+			DownloadDir: DefaultDownloadDir(),
+		},
+	}
+}
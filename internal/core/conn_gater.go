@@ -0,0 +1,274 @@
+package core
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/connmgr"
+	"github.com/libp2p/go-libp2p/core/control"
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/multiformats/go-multiaddr"
+	manet "github.com/multiformats/go-multiaddr/net"
+)
+
+// gaterIdleTimeout - через сколько забывать корзину адреса без новых соединений
+const gaterIdleTimeout = 10 * time.Minute
+
+// ConnectionLimits - ограничение частоты входящих соединений с одного адреса
+// и из одной подсети (/24 для IPv4, /48 для IPv6). Нулевая частота отключает
+// соответствующее ограничение
+type ConnectionLimits struct {
+	// PerIPRate - сколько новых соединений в секунду разрешено с одного IP
+	PerIPRate float64
+	// PerIPBurst - сколько соединений с одного IP допускается разом
+	PerIPBurst int
+	// PerSubnetRate - сколько новых соединений в секунду разрешено из подсети
+	PerSubnetRate float64
+	// PerSubnetBurst - сколько соединений из подсети допускается разом
+	PerSubnetBurst int
+}
+
+// DefaultConnectionLimits возвращает ограничения по умолчанию
+func DefaultConnectionLimits() ConnectionLimits {
+	return ConnectionLimits{
+		PerIPRate:      1,
+		PerIPBurst:     10,
+		PerSubnetRate:  5,
+		PerSubnetBurst: 50,
+	}
+}
+
+// tokenBucket - корзина токенов одного адреса или подсети
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// take пополняет корзину за прошедшее время и забирает токен, если он есть
+func (b *tokenBucket) take(rate float64, burst int, now time.Time) bool {
+	b.tokens += now.Sub(b.last).Seconds() * rate
+	if b.tokens > float64(burst) {
+		b.tokens = float64(burst)
+	}
+	b.last = now
+
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// connGater ограничивает частоту входящих соединений по IP и подсети
+type connGater struct {
+	mu        sync.Mutex
+	limits    ConnectionLimits
+	ips       map[string]*tokenBucket
+	subnets   map[string]*tokenBucket
+	lastPrune time.Time
+	rejected  uint64
+
+	// onReject сообщает об отклоненном соединении; вызывается вне блокировки
+	onReject func(addr string)
+
+	// policies запрещают прямые или ретранслируемые соединения с отдельными пирами
+	policies *transportPolicies
+
+	// banned - пиры, соединения с которыми запрещены (под mu)
+	banned map[peer.ID]bool
+}
+
+// Проверяем соответствие интерфейсу libp2p
+var _ connmgr.ConnectionGater = (*connGater)(nil)
+
+// newConnGater создает ограничитель с заданными лимитами
+func newConnGater(limits ConnectionLimits) *connGater {
+	return &connGater{
+		limits:  limits,
+		ips:     make(map[string]*tokenBucket),
+		subnets: make(map[string]*tokenBucket),
+	}
+}
+
+// SetConnectionLimits меняет ограничения входящих соединений во время работы
+func (n *Node) SetConnectionLimits(limits ConnectionLimits) {
+	n.gater.mu.Lock()
+	n.gater.limits = limits
+	n.gater.mu.Unlock()
+}
+
+// RejectedConnections возвращает число отклоненных из-за лимитов соединений
+func (n *Node) RejectedConnections() uint64 {
+	n.gater.mu.Lock()
+	defer n.gater.mu.Unlock()
+	return n.gater.rejected
+}
+
+// InterceptPeerDial запрещает исходящие соединения с заблокированными пирами
+func (g *connGater) InterceptPeerDial(id peer.ID) bool { return !g.isBanned(id) }
+
+// InterceptAddrDial применяет политику ретрансляции пира к набираемому адресу
+func (g *connGater) InterceptAddrDial(id peer.ID, addr multiaddr.Multiaddr) bool {
+	return g.policies == nil || g.policies.allowAddr(id, addr)
+}
+
+// InterceptAccept проверяет лимиты адреса и подсети входящего соединения
+func (g *connGater) InterceptAccept(addrs network.ConnMultiaddrs) bool {
+	ip, err := manet.ToIP(addrs.RemoteMultiaddr())
+	if err != nil || ip.IsLoopback() {
+		// Адреса без IP (relay) ограничиваются на стороне ретранслятора
+		return true
+	}
+	if g.allow(ip, time.Now()) {
+		return true
+	}
+	if g.onReject != nil {
+		g.onReject(addrs.RemoteMultiaddr().String())
+	}
+	return false
+}
+
+// InterceptSecured применяет политику ретрансляции к входящим соединениям,
+// когда личность пира уже известна
+func (g *connGater) InterceptSecured(dir network.Direction, id peer.ID, addrs network.ConnMultiaddrs) bool {
+	if g.isBanned(id) {
+		return false
+	}
+	if dir != network.DirInbound || g.policies == nil {
+		return true
+	}
+	return g.policies.allowAddr(id, addrs.RemoteMultiaddr())
+}
+
+// InterceptUpgraded разрешает установленные соединения
+func (g *connGater) InterceptUpgraded(network.Conn) (bool, control.DisconnectReason) {
+	return true, 0
+}
+
+// isBanned проверяет, заблокирован ли пир
+func (g *connGater) isBanned(id peer.ID) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.banned[id]
+}
+
+// SetBannedPeers заменяет список заблокированных пиров и разрывает
+// соединения с ними
+func (n *Node) SetBannedPeers(ids []peer.ID) {
+	banned := make(map[peer.ID]bool, len(ids))
+	for _, id := range ids {
+		banned[id] = true
+	}
+	n.gater.mu.Lock()
+	n.gater.banned = banned
+	n.gater.mu.Unlock()
+
+	for _, id := range ids {
+		n.host.Network().ClosePeer(id)
+	}
+}
+
+// BanPeer запрещает соединения с пиром и разрывает текущие
+func (n *Node) BanPeer(id peer.ID) error {
+	if id == n.host.ID() {
+		return fmt.Errorf("нельзя заблокировать собственный узел")
+	}
+	n.gater.mu.Lock()
+	if n.gater.banned == nil {
+		n.gater.banned = make(map[peer.ID]bool)
+	}
+	n.gater.banned[id] = true
+	n.gater.mu.Unlock()
+
+	n.host.Network().ClosePeer(id)
+	return nil
+}
+
+// UnbanPeer снимает запрет на соединения с пиром. Возвращает false, если
+// пир не был заблокирован
+func (n *Node) UnbanPeer(id peer.ID) bool {
+	n.gater.mu.Lock()
+	defer n.gater.mu.Unlock()
+
+	if !n.gater.banned[id] {
+		return false
+	}
+	delete(n.gater.banned, id)
+	return true
+}
+
+// BannedPeers возвращает заблокированных пиров
+func (n *Node) BannedPeers() []peer.ID {
+	n.gater.mu.Lock()
+	defer n.gater.mu.Unlock()
+
+	ids := make([]peer.ID, 0, len(n.gater.banned))
+	for id := range n.gater.banned {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// allow забирает токены из корзин адреса и подсети
+func (g *connGater) allow(ip net.IP, now time.Time) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.pruneLocked(now)
+
+	allowed := true
+	if g.limits.PerIPRate > 0 {
+		allowed = bucketFor(g.ips, ip.String(), g.limits.PerIPBurst, now).take(g.limits.PerIPRate, g.limits.PerIPBurst, now)
+	}
+	if allowed && g.limits.PerSubnetRate > 0 {
+		subnet := subnetOf(ip)
+		allowed = bucketFor(g.subnets, subnet, g.limits.PerSubnetBurst, now).take(g.limits.PerSubnetRate, g.limits.PerSubnetBurst, now)
+	}
+
+	if !allowed {
+		g.rejected++
+		// Логируем не каждое отклонение, чтобы атака не засоряла лог
+		if g.rejected&(g.rejected-1) == 0 {
+			log.Printf("🛡️ Отклонено входящих соединений сверх лимита: %d (последнее с %s)", g.rejected, ip)
+		}
+	}
+	return allowed
+}
+
+// pruneLocked забывает корзины адресов, с которых давно не было соединений
+func (g *connGater) pruneLocked(now time.Time) {
+	if now.Sub(g.lastPrune) < gaterIdleTimeout {
+		return
+	}
+	g.lastPrune = now
+
+	for _, buckets := range []map[string]*tokenBucket{g.ips, g.subnets} {
+		for key, b := range buckets {
+			if now.Sub(b.last) > gaterIdleTimeout {
+				delete(buckets, key)
+			}
+		}
+	}
+}
+
+// bucketFor возвращает корзину ключа, создавая полную при первом обращении
+func bucketFor(buckets map[string]*tokenBucket, key string, burst int, now time.Time) *tokenBucket {
+	b, ok := buckets[key]
+	if !ok {
+		b = &tokenBucket{tokens: float64(burst), last: now}
+		buckets[key] = b
+	}
+	return b
+}
+
+// subnetOf возвращает подсеть адреса: /24 для IPv4 и /48 для IPv6
+func subnetOf(ip net.IP) string {
+	if v4 := ip.To4(); v4 != nil {
+		return v4.Mask(net.CIDRMask(24, 32)).String() + "/24"
+	}
+	return ip.Mask(net.CIDRMask(48, 128)).String() + "/48"
+}
@@ -0,0 +1,273 @@
+package core
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/core/peerstore"
+	"github.com/multiformats/go-multiaddr"
+)
+
+// CONTACT_CARD_PROTOCOL_ID - протокол обмена карточками контактов
+const CONTACT_CARD_PROTOCOL_ID = "/owl-whisper/contact-card/1.0.0"
+
+const (
+	// contactCardLimit - максимальный размер карточки
+	contactCardLimit = 16 * 1024
+	// contactCardTimeout - предельное время передачи карточки
+	contactCardTimeout = 30 * time.Second
+	// contactCardPendingLimit - сколько полученных карточек ждут решения
+	contactCardPendingLimit = 50
+	// contactCardMaxAddrs - сколько адресов передается в карточке
+	contactCardMaxAddrs = 16
+	// contactCardNicknameLimit - максимальная длина имени в карточке
+	contactCardNicknameLimit = 64
+)
+
+var (
+	// ErrContactCardNotFound - карточка не найдена среди полученных
+	ErrContactCardNotFound = errors.New("карточка контакта не найдена")
+
+	// errContactCardFingerprint - отпечаток в карточке не соответствует PeerID
+	errContactCardFingerprint = errors.New("отпечаток ключа не совпадает с PeerID")
+)
+
+// ContactCard - карточка контакта, полученная от другого контакта;
+// полезная нагрузка EventContactCard. Отпечаток проверен по PeerID
+type ContactCard struct {
+	ID          uint64                `json:"id"`
+	PeerID      peer.ID               `json:"peer_id"`
+	Nickname    string                `json:"nickname,omitempty"`
+	Fingerprint string                `json:"fingerprint"`
+	Addrs       []multiaddr.Multiaddr `json:"-"`
+	From        peer.ID               `json:"from"`
+	ReceivedAt  time.Time             `json:"received_at"`
+}
+
+// contactCardWire - карточка в том виде, в котором она передается по сети
+type contactCardWire struct {
+	PeerID      string   `json:"peer_id"`
+	Nickname    string   `json:"nickname,omitempty"`
+	Fingerprint string   `json:"fingerprint"`
+	Addrs       []string `json:"addrs,omitempty"`
+}
+
+// contactCards хранит полученные карточки до решения пользователя
+type contactCards struct {
+	mu      sync.Mutex
+	nextID  uint64
+	pending map[uint64]ContactCard
+}
+
+// SendContactCard отправляет контакту to карточку пира subject под именем
+// nickname. Вместе с отпечатком ключа передаются известные адреса subject
+func (n *Node) SendContactCard(to, subject peer.ID, nickname string) error {
+	if to == subject {
+		return fmt.Errorf("нельзя отправить пиру его собственную карточку")
+	}
+	fingerprint, err := Fingerprint(subject)
+	if err != nil {
+		return err
+	}
+
+	wire := contactCardWire{
+		PeerID:      subject.String(),
+		Nickname:    truncateNickname(nickname),
+		Fingerprint: fingerprint,
+	}
+	addrs := n.host.Peerstore().Addrs(subject)
+	if subject == n.host.ID() {
+		addrs = n.host.Addrs()
+	}
+	if len(addrs) == 0 {
+		addrs = n.peerCache.lookup(subject)
+	}
+	for _, addr := range addrs {
+		if len(wire.Addrs) >= contactCardMaxAddrs {
+			break
+		}
+		wire.Addrs = append(wire.Addrs, addr.String())
+	}
+
+	stream, err := n.newStream(n.ctx, to, CONTACT_CARD_PROTOCOL_ID)
+	if err != nil {
+		return fmt.Errorf("не удалось открыть поток к %s: %w", to.ShortString(), err)
+	}
+	defer stream.Close()
+	stream.SetDeadline(time.Now().Add(contactCardTimeout))
+
+	data, err := json.Marshal(wire)
+	if err != nil {
+		return err
+	}
+	if _, err := stream.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("не удалось отправить карточку: %w", err)
+	}
+
+	reply, err := bufio.NewReader(io.LimitReader(stream, contactCardLimit)).ReadString('\n')
+	if err != nil {
+		return fmt.Errorf("пир %s не подтвердил карточку: %w", to.ShortString(), err)
+	}
+	if reply = strings.TrimSpace(reply); reply != "ok" {
+		return fmt.Errorf("карточка отклонена: %s", reply)
+	}
+	return nil
+}
+
+// ContactCards возвращает полученные карточки, от старых к новым
+func (n *Node) ContactCards() []ContactCard {
+	c := &n.contactCards
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	cards := make([]ContactCard, 0, len(c.pending))
+	for _, card := range c.pending {
+		cards = append(cards, card)
+	}
+	sort.Slice(cards, func(i, j int) bool { return cards[i].ID < cards[j].ID })
+	return cards
+}
+
+// AcceptContactCard забирает карточку и запоминает адреса пира, чтобы
+// фронтенд добавил его в контакты
+func (n *Node) AcceptContactCard(id uint64) (ContactCard, error) {
+	c := &n.contactCards
+	c.mu.Lock()
+	card, ok := c.pending[id]
+	delete(c.pending, id)
+	c.mu.Unlock()
+
+	if !ok {
+		return ContactCard{}, ErrContactCardNotFound
+	}
+	if len(card.Addrs) > 0 {
+		n.peerCache.remember(card.PeerID, card.Addrs)
+		n.host.Peerstore().AddAddrs(card.PeerID, card.Addrs, peerstore.TempAddrTTL)
+	}
+	return card, nil
+}
+
+// RejectContactCards отбрасывает карточки по ID; без аргументов - все
+func (n *Node) RejectContactCards(ids ...uint64) int {
+	c := &n.contactCards
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(ids) == 0 {
+		count := len(c.pending)
+		c.pending = nil
+		return count
+	}
+
+	count := 0
+	for _, id := range ids {
+		if _, ok := c.pending[id]; ok {
+			delete(c.pending, id)
+			count++
+		}
+	}
+	return count
+}
+
+// handleContactCardStream принимает карточку от контакта и проверяет,
+// что отпечаток соответствует ключу в PeerID
+func (n *Node) handleContactCardStream(stream network.Stream) {
+	defer stream.Close()
+	stream.SetDeadline(time.Now().Add(contactCardTimeout))
+	remotePeer := stream.Conn().RemotePeer()
+
+	line, err := bufio.NewReader(io.LimitReader(stream, contactCardLimit)).ReadBytes('\n')
+	if err != nil {
+		stream.Reset()
+		return
+	}
+	if !n.isKnownContact(remotePeer) {
+		fmt.Fprintln(stream, "карточки принимаются только от контактов")
+		return
+	}
+
+	var wire contactCardWire
+	if err := json.Unmarshal(line, &wire); err != nil {
+		fmt.Fprintln(stream, "некорректная карточка")
+		return
+	}
+	card, err := parseContactCard(wire)
+	if err != nil {
+		log.Printf("🚫 Карточка от %s отклонена: %v", remotePeer.ShortString(), err)
+		if errors.Is(err, errContactCardFingerprint) {
+			n.emitSecurity(SecurityVerificationFailed, SeverityWarning, remotePeer,
+				stream.Conn().RemoteMultiaddr().String(), err.Error())
+		}
+		fmt.Fprintln(stream, err.Error())
+		return
+	}
+	card.From = remotePeer
+	card.ReceivedAt = time.Now()
+
+	c := &n.contactCards
+	c.mu.Lock()
+	if len(c.pending) >= contactCardPendingLimit {
+		c.mu.Unlock()
+		fmt.Fprintln(stream, "слишком много карточек ждут решения")
+		return
+	}
+	if c.pending == nil {
+		c.pending = make(map[uint64]ContactCard)
+	}
+	c.nextID++
+	card.ID = c.nextID
+	c.pending[card.ID] = card
+	c.mu.Unlock()
+
+	fmt.Fprintln(stream, "ok")
+	n.emit(EventContactCard, card)
+}
+
+// parseContactCard проверяет карточку из сети
+func parseContactCard(wire contactCardWire) (ContactCard, error) {
+	id, err := peer.Decode(wire.PeerID)
+	if err != nil {
+		return ContactCard{}, fmt.Errorf("некорректный PeerID: %w", err)
+	}
+	fingerprint, err := Fingerprint(id)
+	if err != nil {
+		return ContactCard{}, err
+	}
+	if !strings.EqualFold(strings.ReplaceAll(wire.Fingerprint, " ", ""), strings.ReplaceAll(fingerprint, " ", "")) {
+		return ContactCard{}, errContactCardFingerprint
+	}
+
+	card := ContactCard{
+		PeerID:      id,
+		Nickname:    truncateNickname(wire.Nickname),
+		Fingerprint: fingerprint,
+	}
+	for _, s := range wire.Addrs {
+		if len(card.Addrs) >= contactCardMaxAddrs {
+			break
+		}
+		if addr, err := multiaddr.NewMultiaddr(s); err == nil {
+			card.Addrs = append(card.Addrs, addr)
+		}
+	}
+	return card, nil
+}
+
+// truncateNickname обрезает имя до contactCardNicknameLimit символов
+func truncateNickname(nickname string) string {
+	nickname = strings.TrimSpace(nickname)
+	if runes := []rune(nickname); len(runes) > contactCardNicknameLimit {
+		nickname = string(runes[:contactCardNicknameLimit])
+	}
+	return nickname
+}
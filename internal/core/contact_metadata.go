@@ -0,0 +1,158 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"OwlWhisper/pkg/interfaces"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+// Общепринятые ключи метаданных контакта. Фронтенды могут задавать и свои
+const (
+	// MetadataNotificationSound - звук уведомления для контакта
+	MetadataNotificationSound = "notification_sound"
+	// MetadataColor - цвет контакта в списке и диалоге
+	MetadataColor = "color"
+	// MetadataAlias - локальный псевдоним вместо имени контакта
+	MetadataAlias = "alias"
+	// MetadataNotes - заметки о контакте
+	MetadataNotes = "notes"
+)
+
+const (
+	// maxMetadataKeyLength - максимальная длина ключа метаданных
+	maxMetadataKeyLength = 64
+	// maxMetadataValueSize - максимальный размер одного значения
+	maxMetadataValueSize = 4 * 1024
+	// maxMetadataKeys - сколько ключей может быть у одного контакта
+	maxMetadataKeys = 32
+)
+
+// contactMetadata - метаданные контактов, хранящиеся в ядре, чтобы они не
+// зависели от настроек конкретного графического клиента
+type contactMetadata struct {
+	mu       sync.RWMutex
+	repo     interfaces.IContactMetadataRepository
+	metadata map[peer.ID]map[string]interfaces.ContactMetadataValue
+}
+
+// LoadContactMetadata загружает метаданные из хранилища и сохраняет в него изменения
+func (n *Node) LoadContactMetadata(repo interfaces.IContactMetadataRepository) error {
+	all, err := repo.GetAllContactMetadata(context.Background())
+	if err != nil {
+		return fmt.Errorf("не удалось загрузить метаданные контактов: %w", err)
+	}
+
+	n.metadata.mu.Lock()
+	defer n.metadata.mu.Unlock()
+
+	n.metadata.repo = repo
+	n.metadata.metadata = make(map[peer.ID]map[string]interfaces.ContactMetadataValue, len(all))
+	for rawID, metadata := range all {
+		id, err := peer.Decode(rawID)
+		if err != nil {
+			log.Printf("⚠️ Некорректный PeerID в метаданных: %s", rawID)
+			continue
+		}
+		n.metadata.metadata[id] = metadata
+	}
+	return nil
+}
+
+// SetContactMetadata задает значение метаданных пира; пустое значение удаляет ключ
+func (n *Node) SetContactMetadata(id peer.ID, key string, value []byte) error {
+	key = strings.TrimSpace(key)
+	if key == "" || len(key) > maxMetadataKeyLength || strings.ContainsAny(key, " \t\n") {
+		return fmt.Errorf("некорректный ключ метаданных %q", key)
+	}
+	if len(value) > maxMetadataValueSize {
+		return fmt.Errorf("значение %s больше %d байт", key, maxMetadataValueSize)
+	}
+
+	m := &n.metadata
+	m.mu.Lock()
+	if m.metadata == nil {
+		m.metadata = make(map[peer.ID]map[string]interfaces.ContactMetadataValue)
+	}
+	metadata := m.metadata[id]
+	if len(value) == 0 {
+		delete(metadata, key)
+	} else {
+		if _, ok := metadata[key]; !ok && len(metadata) >= maxMetadataKeys {
+			m.mu.Unlock()
+			return fmt.Errorf("у пира уже %d ключей метаданных", maxMetadataKeys)
+		}
+		if metadata == nil {
+			metadata = make(map[string]interfaces.ContactMetadataValue)
+			m.metadata[id] = metadata
+		}
+		metadata[key] = interfaces.ContactMetadataValue{
+			Value:     append([]byte(nil), value...),
+			UpdatedAt: time.Now(),
+		}
+	}
+	if len(metadata) == 0 {
+		delete(m.metadata, id)
+	}
+	snapshot := copyMetadata(metadata)
+	repo := m.repo
+	m.mu.Unlock()
+
+	if repo == nil {
+		return nil
+	}
+	if err := repo.SaveContactMetadata(context.Background(), id.String(), snapshot); err != nil {
+		return fmt.Errorf("не удалось сохранить метаданные контакта: %w", err)
+	}
+	return nil
+}
+
+// GetContactMetadata возвращает значение метаданных пира
+func (n *Node) GetContactMetadata(id peer.ID, key string) ([]byte, bool) {
+	n.metadata.mu.RLock()
+	defer n.metadata.mu.RUnlock()
+
+	value, ok := n.metadata.metadata[id][key]
+	if !ok {
+		return nil, false
+	}
+	return append([]byte(nil), value.Value...), true
+}
+
+// GetAllContactMetadata возвращает все метаданные пира
+func (n *Node) GetAllContactMetadata(id peer.ID) map[string]interfaces.ContactMetadataValue {
+	n.metadata.mu.RLock()
+	defer n.metadata.mu.RUnlock()
+
+	return copyMetadata(n.metadata.metadata[id])
+}
+
+// ContactMetadataKeys возвращает ключи метаданных пира по алфавиту
+func (n *Node) ContactMetadataKeys(id peer.ID) []string {
+	n.metadata.mu.RLock()
+	defer n.metadata.mu.RUnlock()
+
+	keys := make([]string, 0, len(n.metadata.metadata[id]))
+	for key := range n.metadata.metadata[id] {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// copyMetadata копирует набор метаданных вместе со значениями
+func copyMetadata(metadata map[string]interfaces.ContactMetadataValue) map[string]interfaces.ContactMetadataValue {
+	copied := make(map[string]interfaces.ContactMetadataValue, len(metadata))
+	for key, value := range metadata {
+		value.Value = append([]byte(nil), value.Value...)
+		copied[key] = value
+	}
+	return copied
+}
@@ -0,0 +1,417 @@
+package core
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/bits"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+// CONTACT_PROTOCOL_ID - протокол запросов на добавление в контакты от незнакомых пиров
+const CONTACT_PROTOCOL_ID = "/owl-whisper/contact/1.0.0"
+
+const (
+	// contactRequestLimit - максимальный размер запроса в байтах
+	contactRequestLimit = 8 * 1024
+	// contactRequestTimeout - предельное время обмена по протоколу запросов
+	contactRequestTimeout = 2 * time.Minute
+	// MaxStampDifficulty - самая большая сложность штампа, которую узел
+	// согласен вычислять и требовать: по умолчанию требуется 20 бит, а каждый
+	// бит сверху удваивает работу
+	MaxStampDifficulty = 24
+	// stampValidity - сколько действует штамп proof-of-work
+	stampValidity = 48 * time.Hour
+	// stampDateFormat - формат даты в штампе hashcash
+	stampDateFormat = "060102150405"
+)
+
+var (
+	// ErrContactRequestNotFound - запрос не найден в карантине
+	ErrContactRequestNotFound = errors.New("запрос на добавление не найден")
+
+	// errInvalidStamp - штамп proof-of-work не прошел проверку
+	errInvalidStamp = errors.New("недействительный штамп proof-of-work")
+)
+
+// ContactRequestPolicy - защита от массовых запросов незнакомых пиров
+type ContactRequestPolicy struct {
+	// Difficulty - сколько ведущих нулевых бит требует штамп hashcash (0 - без PoW)
+	Difficulty int
+	// PerPeerLimit - сколько запросов принимать от одного пира за Window
+	PerPeerLimit int
+	// GlobalLimit - сколько запросов принимать от всех пиров за Window
+	GlobalLimit int
+	// Window - окно ограничения частоты
+	Window time.Duration
+	// QuarantineLimit - максимальное число ожидающих решения запросов
+	QuarantineLimit int
+}
+
+// Validate проверяет политику. Сложность выше MaxStampDifficulty
+// отправители вычислять откажутся, и запросы перестанут приходить
+func (p ContactRequestPolicy) Validate() error {
+	if p.Difficulty < 0 || p.Difficulty > MaxStampDifficulty {
+		return fmt.Errorf("сложность proof-of-work должна быть от 0 до %d бит", MaxStampDifficulty)
+	}
+	if p.PerPeerLimit < 0 || p.GlobalLimit < 0 || p.QuarantineLimit < 0 || p.Window < 0 {
+		return fmt.Errorf("лимиты запросов не могут быть отрицательными")
+	}
+	return nil
+}
+
+// DefaultContactRequestPolicy возвращает защиту по умолчанию: ~1 секунда
+// вычислений на запрос и не более 30 запросов в час
+func DefaultContactRequestPolicy() ContactRequestPolicy {
+	return ContactRequestPolicy{
+		Difficulty:      20,
+		PerPeerLimit:    3,
+		GlobalLimit:     30,
+		Window:          time.Hour,
+		QuarantineLimit: 100,
+	}
+}
+
+// ContactRequest - входящий запрос в карантине; полезная нагрузка EventContactRequest
+type ContactRequest struct {
+	ID         uint64    `json:"id"`
+	PeerID     peer.ID   `json:"peer_id"`
+	Nickname   string    `json:"nickname,omitempty"`
+	Text       string    `json:"text"`
+	ReceivedAt time.Time `json:"received_at"`
+}
+
+// contactRequestWire - запрос в том виде, в котором он передается по сети
+type contactRequestWire struct {
+	Stamp    string `json:"stamp"`
+	Nickname string `json:"nickname,omitempty"`
+	Text     string `json:"text"`
+}
+
+// contactRequestGuard хранит карантин, историю частоты и использованные штампы
+type contactRequestGuard struct {
+	mu         sync.Mutex
+	policy     ContactRequestPolicy
+	nextID     uint64
+	quarantine map[uint64]ContactRequest
+	perPeer    map[peer.ID][]time.Time
+	global     []time.Time
+	stamps     map[string]time.Time
+}
+
+// newContactRequestGuard создает защиту с заданной политикой
+func newContactRequestGuard(policy ContactRequestPolicy) *contactRequestGuard {
+	return &contactRequestGuard{
+		policy:     policy,
+		quarantine: make(map[uint64]ContactRequest),
+		perPeer:    make(map[peer.ID][]time.Time),
+		stamps:     make(map[string]time.Time),
+	}
+}
+
+// SetContactRequestPolicy меняет защиту от запросов во время работы
+func (n *Node) SetContactRequestPolicy(policy ContactRequestPolicy) error {
+	if err := policy.Validate(); err != nil {
+		return err
+	}
+
+	n.contactRequests.mu.Lock()
+	n.contactRequests.policy = policy
+	n.contactRequests.mu.Unlock()
+	return nil
+}
+
+// SendContactRequest отправляет незнакомому пиру запрос на добавление в контакты.
+// Перед отправкой вычисляется штамп proof-of-work нужной получателю сложности
+func (n *Node) SendContactRequest(peerID peer.ID, nickname, text string) error {
+	stream, err := n.newStream(n.ctx, peerID, CONTACT_PROTOCOL_ID)
+	if err != nil {
+		return fmt.Errorf("не удалось открыть поток к %s: %w", peerID.ShortString(), err)
+	}
+	defer stream.Close()
+	stream.SetDeadline(time.Now().Add(contactRequestTimeout))
+
+	// Получатель сообщает требуемую сложность первой строкой
+	reader := bufio.NewReader(io.LimitReader(stream, contactRequestLimit))
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return fmt.Errorf("пир %s не ответил: %w", peerID.ShortString(), err)
+	}
+	difficulty, err := strconv.Atoi(strings.TrimSpace(line))
+	if err != nil || difficulty < 0 {
+		return fmt.Errorf("пир %s запросил некорректную сложность: %q", peerID.ShortString(), line)
+	}
+	if difficulty > MaxStampDifficulty {
+		return fmt.Errorf("пир %s запросил слишком большую сложность: %d бит (не больше %d)", peerID.ShortString(), difficulty, MaxStampDifficulty)
+	}
+
+	ctx, cancel := context.WithTimeout(n.ctx, contactRequestTimeout)
+	defer cancel()
+	stamp, err := mintStamp(ctx, contactStampResource(n.host.ID(), peerID), difficulty, time.Now())
+	if err != nil {
+		return fmt.Errorf("не удалось вычислить штамп: %w", err)
+	}
+	data, err := json.Marshal(contactRequestWire{Stamp: stamp, Nickname: nickname, Text: text})
+	if err != nil {
+		return err
+	}
+	if _, err := stream.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("не удалось отправить запрос: %w", err)
+	}
+
+	reply, err := reader.ReadString('\n')
+	if err != nil {
+		return fmt.Errorf("пир %s не подтвердил запрос: %w", peerID.ShortString(), err)
+	}
+	if reply = strings.TrimSpace(reply); reply != "ok" {
+		return fmt.Errorf("запрос отклонен: %s", reply)
+	}
+	return nil
+}
+
+// ContactRequests возвращает запросы в карантине, от старых к новым
+func (n *Node) ContactRequests() []ContactRequest {
+	g := n.contactRequests
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	requests := make([]ContactRequest, 0, len(g.quarantine))
+	for _, r := range g.quarantine {
+		requests = append(requests, r)
+	}
+	sort.Slice(requests, func(i, j int) bool { return requests[i].ID < requests[j].ID })
+	return requests
+}
+
+// AcceptContactRequest убирает запрос из карантина и возвращает его, чтобы
+// фронтенд добавил пира в контакты
+func (n *Node) AcceptContactRequest(id uint64) (ContactRequest, error) {
+	g := n.contactRequests
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	request, ok := g.quarantine[id]
+	if !ok {
+		return ContactRequest{}, ErrContactRequestNotFound
+	}
+	delete(g.quarantine, id)
+	return request, nil
+}
+
+// RejectContactRequests отклоняет запросы по ID; без аргументов - все.
+// Возвращает число отклоненных запросов
+func (n *Node) RejectContactRequests(ids ...uint64) int {
+	g := n.contactRequests
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if len(ids) == 0 {
+		count := len(g.quarantine)
+		g.quarantine = make(map[uint64]ContactRequest)
+		return count
+	}
+
+	count := 0
+	for _, id := range ids {
+		if _, ok := g.quarantine[id]; ok {
+			delete(g.quarantine, id)
+			count++
+		}
+	}
+	return count
+}
+
+// handleContactStream проверяет входящий запрос и помещает его в карантин
+func (n *Node) handleContactStream(stream network.Stream) {
+	defer stream.Close()
+	stream.SetDeadline(time.Now().Add(contactRequestTimeout))
+	remotePeer := stream.Conn().RemotePeer()
+
+	// Контакты не проходят проверок: защита нужна только от незнакомых пиров
+	trusted := n.isKnownContact(remotePeer)
+
+	g := n.contactRequests
+	g.mu.Lock()
+	difficulty := g.policy.Difficulty
+	g.mu.Unlock()
+	if trusted {
+		difficulty = 0
+	}
+
+	if _, err := fmt.Fprintf(stream, "%d\n", difficulty); err != nil {
+		stream.Reset()
+		return
+	}
+
+	reader := bufio.NewReader(io.LimitReader(stream, contactRequestLimit))
+	line, err := reader.ReadBytes('\n')
+	if err != nil {
+		stream.Reset()
+		return
+	}
+	var wire contactRequestWire
+	if err := json.Unmarshal(line, &wire); err != nil {
+		fmt.Fprintln(stream, "некорректный запрос")
+		return
+	}
+
+	request, err := g.admit(n.host.ID(), remotePeer, wire, trusted, time.Now())
+	if err != nil {
+		log.Printf("🚫 Запрос от %s отклонен: %v", remotePeer.ShortString(), err)
+		if errors.Is(err, errInvalidStamp) {
+			n.emitSecurity(SecurityVerificationFailed, SeverityWarning, remotePeer,
+				stream.Conn().RemoteMultiaddr().String(), err.Error())
+		}
+		fmt.Fprintln(stream, err.Error())
+		return
+	}
+
+	fmt.Fprintln(stream, "ok")
+	n.emit(EventContactRequest, request)
+}
+
+// admit применяет ограничения частоты, проверяет штамп и помещает запрос
+// в карантин. Запросы от контактов (trusted) не ограничиваются
+func (g *contactRequestGuard) admit(self, remote peer.ID, wire contactRequestWire, trusted bool, now time.Time) (ContactRequest, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.expireLocked(now)
+
+	if !trusted {
+		if err := g.checkLocked(self, remote, wire.Stamp, now); err != nil {
+			return ContactRequest{}, err
+		}
+	}
+
+	g.nextID++
+	request := ContactRequest{
+		ID:         g.nextID,
+		PeerID:     remote,
+		Nickname:   wire.Nickname,
+		Text:       wire.Text,
+		ReceivedAt: now,
+	}
+	g.quarantine[request.ID] = request
+	return request, nil
+}
+
+// checkLocked проверяет ограничения частоты и штамп незнакомого пира
+func (g *contactRequestGuard) checkLocked(self, remote peer.ID, stamp string, now time.Time) error {
+	if g.policy.PerPeerLimit > 0 && len(g.perPeer[remote]) >= g.policy.PerPeerLimit {
+		return errors.New("слишком много запросов от пира")
+	}
+	if g.policy.GlobalLimit > 0 && len(g.global) >= g.policy.GlobalLimit {
+		return errors.New("слишком много запросов, попробуйте позже")
+	}
+	if g.policy.QuarantineLimit > 0 && len(g.quarantine) >= g.policy.QuarantineLimit {
+		return errors.New("очередь запросов переполнена")
+	}
+
+	if err := verifyStamp(stamp, contactStampResource(remote, self), g.policy.Difficulty, now); err != nil {
+		return err
+	}
+	if _, used := g.stamps[stamp]; used {
+		return fmt.Errorf("%w: уже использован", errInvalidStamp)
+	}
+	g.stamps[stamp] = now
+
+	g.perPeer[remote] = append(g.perPeer[remote], now)
+	g.global = append(g.global, now)
+	return nil
+}
+
+// expireLocked забывает события старше окна ограничения и просроченные штампы
+func (g *contactRequestGuard) expireLocked(now time.Time) {
+	cutoff := now.Add(-g.policy.Window)
+	for id, times := range g.perPeer {
+		if times = dropBefore(times, cutoff); len(times) == 0 {
+			delete(g.perPeer, id)
+		} else {
+			g.perPeer[id] = times
+		}
+	}
+	g.global = dropBefore(g.global, cutoff)
+
+	for stamp, seen := range g.stamps {
+		if now.Sub(seen) > stampValidity {
+			delete(g.stamps, stamp)
+		}
+	}
+}
+
+// dropBefore удаляет из упорядоченного списка моменты раньше cutoff
+func dropBefore(times []time.Time, cutoff time.Time) []time.Time {
+	i := sort.Search(len(times), func(i int) bool { return !times[i].Before(cutoff) })
+	return times[i:]
+}
+
+// contactStampResource связывает штамп с отправителем и получателем
+func contactStampResource(from, to peer.ID) string {
+	return from.String() + ">" + to.String()
+}
+
+// mintStamp вычисляет штамп hashcash вида 1:биты:дата:ресурс:счетчик.
+// Перебор прекращается, когда истекает ctx
+func mintStamp(ctx context.Context, resource string, difficulty int, now time.Time) (string, error) {
+	prefix := fmt.Sprintf("1:%d:%s:%s:", difficulty, now.UTC().Format(stampDateFormat), resource)
+	for counter := uint64(0); ; counter++ {
+		if counter%4096 == 0 {
+			if err := ctx.Err(); err != nil {
+				return "", err
+			}
+		}
+		stamp := prefix + strconv.FormatUint(counter, 16)
+		if leadingZeroBits(stamp) >= difficulty {
+			return stamp, nil
+		}
+	}
+}
+
+// verifyStamp проверяет ресурс, срок действия и сложность штампа
+func verifyStamp(stamp, resource string, difficulty int, now time.Time) error {
+	if difficulty <= 0 {
+		return nil
+	}
+
+	parts := strings.Split(stamp, ":")
+	if len(parts) != 5 || parts[0] != "1" {
+		return fmt.Errorf("%w: неверный формат", errInvalidStamp)
+	}
+	if parts[3] != resource {
+		return fmt.Errorf("%w: выписан для другого получателя", errInvalidStamp)
+	}
+	issued, err := time.Parse(stampDateFormat, parts[2])
+	if err != nil || now.Sub(issued) > stampValidity || issued.Sub(now) > time.Hour {
+		return fmt.Errorf("%w: срок действия истек", errInvalidStamp)
+	}
+	if leadingZeroBits(stamp) < difficulty {
+		return fmt.Errorf("%w: недостаточная сложность", errInvalidStamp)
+	}
+	return nil
+}
+
+// leadingZeroBits считает ведущие нулевые биты SHA-256 от строки
+func leadingZeroBits(s string) int {
+	sum := sha256.Sum256([]byte(s))
+	zeros := 0
+	for _, b := range sum {
+		if b != 0 {
+			return zeros + bits.LeadingZeros8(b)
+		}
+		zeros += 8
+	}
+	return zeros
+}
@@ -0,0 +1,39 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestMintStampVerifies(t *testing.T) {
+	now := time.Now()
+	stamp, err := mintStamp(context.Background(), "a>b", 8, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := verifyStamp(stamp, "a>b", 8, now); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMintStampCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := mintStamp(ctx, "a>b", MaxStampDifficulty, time.Now()); !errors.Is(err, context.Canceled) {
+		t.Fatalf("перебор не остановлен отменой: %v", err)
+	}
+}
+
+func TestContactRequestPolicyDifficultyBound(t *testing.T) {
+	policy := DefaultContactRequestPolicy()
+	policy.Difficulty = MaxStampDifficulty
+	if err := policy.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	policy.Difficulty = MaxStampDifficulty + 1
+	if policy.Validate() == nil {
+		t.Fatal("принята сложность, которую отправители не вычислят")
+	}
+}
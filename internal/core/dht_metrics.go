@@ -0,0 +1,198 @@
+package core
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+// Операции DHT, для которых ведется статистика
+const (
+	// DHTProvide - анонс узла (запись провайдера rendezvous)
+	DHTProvide = "provide"
+	// DHTFindProviders - поиск анонсированных участников
+	DHTFindProviders = "find_providers"
+	// DHTFindPeer - поиск адресов конкретного пира
+	DHTFindPeer = "find_peer"
+)
+
+// dhtHistoryHours - за сколько последних часов хранится почасовая статистика
+const dhtHistoryHours = 24
+
+// dhtLatencyBounds - верхние границы корзин гистограммы задержек;
+// последняя корзина принимает все, что дольше
+var dhtLatencyBounds = []time.Duration{
+	100 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	2 * time.Second,
+	5 * time.Second,
+	10 * time.Second,
+	30 * time.Second,
+	time.Minute,
+}
+
+// DHTOperationStats - статистика одной операции DHT. Помогает понять,
+// почему не находится друг: неудачи FindPeer/FindProviders говорят о
+// проблемах с DHT, а неудачи Provide - о том, что не виден наш анонс
+type DHTOperationStats struct {
+	Operation string `json:"operation"`
+	Successes int    `json:"successes"`
+	Failures  int    `json:"failures"`
+	// Empty - операции поиска, завершившиеся без результатов
+	Empty       int       `json:"empty"`
+	LastSuccess time.Time `json:"last_success,omitempty"`
+	LastFailure time.Time `json:"last_failure,omitempty"`
+	LastError   string    `json:"last_error,omitempty"`
+	// Latency - гистограмма длительности операций
+	Latency []DHTLatencyBucket `json:"latency"`
+	// Hourly - итоги по часам, от старых к новым
+	Hourly []DHTHourlyStats `json:"hourly"`
+}
+
+// DHTLatencyBucket - корзина гистограммы: операции не дольше UpperBound
+// (нулевая граница - дольше всех остальных)
+type DHTLatencyBucket struct {
+	UpperBound time.Duration `json:"upper_bound"`
+	Count      int           `json:"count"`
+}
+
+// DHTHourlyStats - итоги операции за час, начинающийся в Start
+type DHTHourlyStats struct {
+	Start     time.Time `json:"start"`
+	Successes int       `json:"successes"`
+	Failures  int       `json:"failures"`
+}
+
+// dhtMetrics собирает статистику операций DHT
+type dhtMetrics struct {
+	mu    sync.Mutex
+	stats map[string]*DHTOperationStats
+}
+
+// observe учитывает завершенную операцию. empty - поиск прошел без ошибок,
+// но ничего не нашел
+func (m *dhtMetrics) observe(operation string, started time.Time, err error, empty bool) {
+	now := time.Now()
+	elapsed := now.Sub(started)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.stats == nil {
+		m.stats = make(map[string]*DHTOperationStats)
+	}
+	stats, ok := m.stats[operation]
+	if !ok {
+		stats = &DHTOperationStats{Operation: operation}
+		for _, bound := range dhtLatencyBounds {
+			stats.Latency = append(stats.Latency, DHTLatencyBucket{UpperBound: bound})
+		}
+		stats.Latency = append(stats.Latency, DHTLatencyBucket{})
+		m.stats[operation] = stats
+	}
+
+	bucket := len(dhtLatencyBounds)
+	for i, bound := range dhtLatencyBounds {
+		if elapsed <= bound {
+			bucket = i
+			break
+		}
+	}
+	stats.Latency[bucket].Count++
+
+	hour := now.Truncate(time.Hour)
+	if n := len(stats.Hourly); n == 0 || !stats.Hourly[n-1].Start.Equal(hour) {
+		stats.Hourly = append(stats.Hourly, DHTHourlyStats{Start: hour})
+		if len(stats.Hourly) > dhtHistoryHours {
+			stats.Hourly = stats.Hourly[len(stats.Hourly)-dhtHistoryHours:]
+		}
+	}
+	current := &stats.Hourly[len(stats.Hourly)-1]
+
+	if err != nil {
+		stats.Failures++
+		stats.LastFailure = now
+		stats.LastError = err.Error()
+		current.Failures++
+		return
+	}
+	stats.Successes++
+	stats.LastSuccess = now
+	current.Successes++
+	if empty {
+		stats.Empty++
+	}
+}
+
+// snapshot возвращает копию статистики в порядке Provide, FindProviders, FindPeer
+func (m *dhtMetrics) snapshot() []DHTOperationStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var result []DHTOperationStats
+	for _, operation := range []string{DHTProvide, DHTFindProviders, DHTFindPeer} {
+		stats, ok := m.stats[operation]
+		if !ok {
+			continue
+		}
+		copied := *stats
+		copied.Latency = append([]DHTLatencyBucket(nil), stats.Latency...)
+		copied.Hourly = append([]DHTHourlyStats(nil), stats.Hourly...)
+		result = append(result, copied)
+	}
+	return result
+}
+
+// DHTStats возвращает статистику операций DHT с момента запуска
+func (dm *DiscoveryManager) DHTStats() []DHTOperationStats {
+	return dm.metrics.snapshot()
+}
+
+// advertise анонсирует узел в DHT, учитывая результат в статистике
+func (dm *DiscoveryManager) advertise(ctx context.Context) (time.Duration, error) {
+	started := time.Now()
+	ttl, err := dm.routingDiscovery.Advertise(ctx, RENDEZVOUS_TAG)
+	dm.metrics.observe(DHTProvide, started, err, false)
+	return ttl, err
+}
+
+// findProviders ищет анонсированных участников; статистика учитывается,
+// когда поиск завершится и канал закроется
+func (dm *DiscoveryManager) findProviders(ctx context.Context, namespace string) (<-chan peer.AddrInfo, error) {
+	started := time.Now()
+	found, err := dm.routingDiscovery.FindPeers(ctx, namespace)
+	if err != nil {
+		dm.metrics.observe(DHTFindProviders, started, err, false)
+		return nil, err
+	}
+
+	out := make(chan peer.AddrInfo)
+	go func() {
+		defer close(out)
+		count := 0
+		for p := range found {
+			count++
+			select {
+			case out <- p:
+			case <-ctx.Done():
+			}
+		}
+		dm.metrics.observe(DHTFindProviders, started, ctx.Err(), count == 0)
+	}()
+	return out, nil
+}
+
+// FindPeer ищет адреса пира в DHT
+func (dm *DiscoveryManager) FindPeer(ctx context.Context, id peer.ID) (peer.AddrInfo, error) {
+	if dm.dht == nil {
+		return peer.AddrInfo{}, ErrDHTUnavailable
+	}
+
+	started := time.Now()
+	info, err := dm.dht.FindPeer(ctx, id)
+	dm.metrics.observe(DHTFindPeer, started, err, false)
+	return info, err
+}
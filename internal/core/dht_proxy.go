@@ -0,0 +1,286 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+// DHT_PROXY_PROTOCOL_ID - протокол делегирования поиска в DHT доверенному
+// узлу-помощнику. Через помощника идут только анонсы и поиск пиров: сообщения
+// по-прежнему передаются напрямую и шифруются сквозным образом
+const DHT_PROXY_PROTOCOL_ID = "/owl-whisper/dht-proxy/1.0.0"
+
+const (
+	// dhtProxyTimeout - предельное время одного запроса к помощнику
+	dhtProxyTimeout = 60 * time.Second
+	// dhtProxyRefresh - как часто клиент обновляет анонс и список пиров
+	dhtProxyRefresh = 10 * time.Minute
+	// dhtProxyCacheTTL - сколько помощник хранит результаты поиска
+	dhtProxyCacheTTL = 5 * time.Minute
+	// dhtProxyRegistrationTTL - сколько живет анонс клиента у помощника
+	dhtProxyRegistrationTTL = time.Hour
+	// dhtProxyMaxPeers - максимальное число пиров в одном ответе
+	dhtProxyMaxPeers = 50
+)
+
+// Операции протокола
+const (
+	dhtProxyAdvertise = "advertise"
+	dhtProxyFindPeers = "find_peers"
+)
+
+type dhtProxyRequest struct {
+	Op        string `json:"op"`
+	Namespace string `json:"namespace"`
+}
+
+type dhtProxyResponse struct {
+	Peers []peer.AddrInfo `json:"peers,omitempty"`
+	Error string          `json:"error,omitempty"`
+}
+
+// dhtProxyServer - сторона помощника: выполняет поиск в DHT для разрешенных
+// клиентов, кэширует результаты и хранит анонсы клиентов. Анонс у помощника
+// лишь ускоряет поиск между его клиентами: в DHT клиент публикует свою
+// запись провайдера сам (см. provideDirectly), иначе остальная сеть его
+// не нашла бы
+type dhtProxyServer struct {
+	dm      *DiscoveryManager
+	allowed map[peer.ID]bool
+
+	mu         sync.Mutex
+	cache      map[string]dhtProxyCacheEntry
+	registered map[string]map[peer.ID]time.Time
+}
+
+type dhtProxyCacheEntry struct {
+	peers   []peer.AddrInfo
+	expires time.Time
+}
+
+// ServeDHTProxy делает узел помощником для перечисленных клиентов. Подлинность
+// клиента гарантирует защищенный канал libp2p: PeerID нельзя подделать
+func (dm *DiscoveryManager) ServeDHTProxy(clients []peer.ID) error {
+	if dm.routingDiscovery == nil {
+		return fmt.Errorf("DHT недоступен, узел не может быть помощником")
+	}
+
+	server := &dhtProxyServer{
+		dm:         dm,
+		allowed:    make(map[peer.ID]bool, len(clients)),
+		cache:      make(map[string]dhtProxyCacheEntry),
+		registered: make(map[string]map[peer.ID]time.Time),
+	}
+	for _, id := range clients {
+		server.allowed[id] = true
+	}
+
+	dm.notifee.node.SetStreamHandler(DHT_PROXY_PROTOCOL_ID, server.handleStream)
+	log.Printf("🛰️ Узел работает помощником DHT для %d клиентов", len(clients))
+	return nil
+}
+
+// handleStream обслуживает один запрос клиента
+func (s *dhtProxyServer) handleStream(stream network.Stream) {
+	defer stream.Close()
+	stream.SetDeadline(time.Now().Add(dhtProxyTimeout))
+
+	remote := stream.Conn().RemotePeer()
+	if !s.allowed[remote] {
+		stream.Reset()
+		return
+	}
+
+	var req dhtProxyRequest
+	var resp dhtProxyResponse
+	if err := json.NewDecoder(stream).Decode(&req); err != nil {
+		resp.Error = "некорректный запрос"
+	} else if req.Namespace == "" {
+		resp.Error = "не указано пространство имен"
+	} else {
+		switch req.Op {
+		case dhtProxyAdvertise:
+			s.register(req.Namespace, remote)
+		case dhtProxyFindPeers:
+			resp.Peers = s.findPeers(req.Namespace, remote)
+		default:
+			resp.Error = fmt.Sprintf("неизвестная операция: %s", req.Op)
+		}
+	}
+
+	if err := json.NewEncoder(stream).Encode(resp); err != nil {
+		log.Printf("⚠️ Не удалось ответить клиенту DHT: %v", err)
+	}
+}
+
+// register запоминает анонс клиента
+func (s *dhtProxyServer) register(namespace string, client peer.ID) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.registered[namespace] == nil {
+		s.registered[namespace] = make(map[peer.ID]time.Time)
+	}
+	s.registered[namespace][client] = time.Now().Add(dhtProxyRegistrationTTL)
+}
+
+// findPeers возвращает пиров из кэша или DHT вместе с анонсами других клиентов
+func (s *dhtProxyServer) findPeers(namespace string, client peer.ID) []peer.AddrInfo {
+	now := time.Now()
+
+	s.mu.Lock()
+	entry, cached := s.cache[namespace]
+	s.mu.Unlock()
+
+	if !cached || now.After(entry.expires) {
+		entry = dhtProxyCacheEntry{peers: s.lookup(namespace), expires: now.Add(dhtProxyCacheTTL)}
+		s.mu.Lock()
+		s.cache[namespace] = entry
+		s.mu.Unlock()
+	}
+
+	host := s.dm.notifee.node
+	var result []peer.AddrInfo
+	seen := map[peer.ID]bool{client: true, host.ID(): true}
+	add := func(info peer.AddrInfo) {
+		if seen[info.ID] || len(info.Addrs) == 0 || len(result) >= dhtProxyMaxPeers {
+			return
+		}
+		seen[info.ID] = true
+		result = append(result, info)
+	}
+
+	s.mu.Lock()
+	for id, expires := range s.registered[namespace] {
+		if now.After(expires) {
+			delete(s.registered[namespace], id)
+			continue
+		}
+		add(host.Peerstore().PeerInfo(id))
+	}
+	s.mu.Unlock()
+
+	for _, info := range entry.peers {
+		add(info)
+	}
+	return result
+}
+
+// lookup выполняет поиск пиров в DHT
+func (s *dhtProxyServer) lookup(namespace string) []peer.AddrInfo {
+	ctx, cancel := context.WithTimeout(s.dm.ctx, dhtProxyTimeout/2)
+	defer cancel()
+
+	peerChan, err := s.dm.findProviders(ctx, namespace)
+	if err != nil {
+		log.Printf("⚠️ Помощник DHT: ошибка поиска: %v", err)
+		return nil
+	}
+
+	var peers []peer.AddrInfo
+	for info := range peerChan {
+		if len(peers) < dhtProxyMaxPeers {
+			peers = append(peers, info)
+		}
+	}
+	return peers
+}
+
+// runDHTProxyClient периодически анонсирует узел через помощника и подключается
+// к найденным пирам вместо участия в DHT
+func (dm *DiscoveryManager) runDHTProxyClient() {
+	dm.announceLoop(func() (time.Duration, bool) {
+		if err := dm.refreshViaProxy(); err != nil {
+			log.Printf("⚠️ Помощник DHT недоступен: %v", err)
+			return dhtProxyRefresh, false
+		}
+		return dhtProxyRefresh, true
+	})
+}
+
+// refreshViaProxy обновляет анонс и подключается к пирам из ответа помощника
+func (dm *DiscoveryManager) refreshViaProxy() error {
+	host := dm.notifee.node
+	if err := host.Connect(dm.ctx, *dm.dhtProxy); err != nil {
+		return err
+	}
+
+	// Операции помощника учитываются в статистике как собственные операции DHT
+	started := time.Now()
+	_, err := dm.proxyRequest(dhtProxyAdvertise)
+	dm.metrics.observe(DHTProvide, started, err, false)
+	if err != nil {
+		return err
+	}
+	started = time.Now()
+	peers, err := dm.proxyRequest(dhtProxyFindPeers)
+	dm.metrics.observe(DHTFindProviders, started, err, len(peers) == 0)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("🛰️ Помощник DHT вернул %d участников", len(peers))
+	for _, info := range peers {
+		dm.notifee.handleFound(PeerFound{AddrInfo: info, Source: DiscoverySourceDHTProvider, Namespace: RENDEZVOUS_TAG})
+	}
+
+	dm.provideDirectly()
+	return nil
+}
+
+// provideDirectly публикует запись провайдера узла в DHT, когда прежняя
+// подходит к концу срока: помощник не может анонсировать клиента от его
+// имени, а без записи клиента не найдут пиры вне списка помощника
+func (dm *DiscoveryManager) provideDirectly() {
+	if dm.proxyProvider == nil || time.Now().Before(dm.nextProvide) {
+		return
+	}
+
+	started := time.Now()
+	ttl, err := dm.proxyProvider.Advertise(dm.ctx, RENDEZVOUS_TAG)
+	dm.metrics.observe(DHTProvide, started, err, false)
+	if err != nil {
+		log.Printf("⚠️ Не удалось анонсироваться в глобальной сети: %v", err)
+		dm.nextProvide = time.Now().Add(announceRetryDelay)
+		return
+	}
+	log.Printf("📢 Анонсировались в глобальной сети, TTL: %v", ttl)
+	// Обновляем с запасом, пока запись еще действует
+	next := ttl * 3 / 4
+	if next < minReannounceInterval {
+		next = minReannounceInterval
+	}
+	dm.nextProvide = time.Now().Add(next)
+}
+
+// proxyRequest выполняет одну операцию у помощника
+func (dm *DiscoveryManager) proxyRequest(op string) ([]peer.AddrInfo, error) {
+	ctx, cancel := context.WithTimeout(dm.ctx, dhtProxyTimeout)
+	defer cancel()
+
+	stream, err := dm.notifee.node.NewStream(ctx, dm.dhtProxy.ID, DHT_PROXY_PROTOCOL_ID)
+	if err != nil {
+		return nil, err
+	}
+	defer stream.Close()
+	stream.SetDeadline(time.Now().Add(dhtProxyTimeout))
+
+	if err := json.NewEncoder(stream).Encode(dhtProxyRequest{Op: op, Namespace: RENDEZVOUS_TAG}); err != nil {
+		return nil, err
+	}
+	var resp dhtProxyResponse
+	if err := json.NewDecoder(stream).Decode(&resp); err != nil {
+		return nil, fmt.Errorf("помощник отклонил запрос: %w", err)
+	}
+	if resp.Error != "" {
+		return nil, fmt.Errorf("помощник: %s", resp.Error)
+	}
+	return resp.Peers, nil
+}
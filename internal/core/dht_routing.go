@@ -0,0 +1,195 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	kbucket "github.com/libp2p/go-libp2p-kbucket"
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+const (
+	// routingBucketSize - вместимость k-корзины (параметр k Kademlia)
+	routingBucketSize = 20
+	// routingHealthyPeers - размер таблицы, начиная с которого она считается заполненной
+	routingHealthyPeers = 60
+	// routingStaleRefresh - через сколько без обновления корзины таблица считается устаревшей
+	routingStaleRefresh = time.Hour
+)
+
+// RoutingBucketStats - состояние одной k-корзины таблицы маршрутизации.
+// Added и Removed считаются относительно предыдущего снимка
+type RoutingBucketStats struct {
+	CPL         int
+	Peers       int
+	Capacity    int
+	Added       int
+	Removed     int
+	LastRefresh time.Time
+}
+
+// RoutingTableReport - снимок таблицы маршрутизации DHT с оценкой ее здоровья
+type RoutingTableReport struct {
+	TakenAt time.Time
+	Peers   int
+	Buckets []RoutingBucketStats
+	// Added, Removed и Interval - изменения с предыдущего снимка
+	Added    int
+	Removed  int
+	Interval time.Duration
+	// ChurnPerHour - смена пиров в час (добавленные и удаленные)
+	ChurnPerHour float64
+	LastRefresh  time.Time
+	// Health - оценка здоровья таблицы от 0 до 100
+	Health   int
+	Problems []string
+}
+
+// routingSnapshot - предыдущий снимок таблицы: пиры и их корзины
+type routingSnapshot struct {
+	mu    sync.Mutex
+	peers map[peer.ID]int
+	taken time.Time
+}
+
+// RoutingTableReport снимает таблицу маршрутизации DHT по корзинам и
+// сравнивает ее с предыдущим снимком
+func (dm *DiscoveryManager) RoutingTableReport() (RoutingTableReport, error) {
+	if dm.dht == nil {
+		return RoutingTableReport{}, ErrDHTUnavailable
+	}
+
+	rt := dm.dht.RoutingTable()
+	self := kbucket.ConvertPeerID(dm.dht.PeerID())
+	now := time.Now()
+
+	current := make(map[peer.ID]int)
+	for _, info := range rt.GetPeerInfos() {
+		current[info.Id] = kbucket.CommonPrefixLen(self, kbucket.ConvertPeerID(info.Id))
+	}
+
+	dm.routing.mu.Lock()
+	previous, previousAt := dm.routing.peers, dm.routing.taken
+	dm.routing.peers, dm.routing.taken = current, now
+	dm.routing.mu.Unlock()
+
+	buckets := make(map[int]*RoutingBucketStats)
+	bucket := func(cpl int) *RoutingBucketStats {
+		if buckets[cpl] == nil {
+			buckets[cpl] = &RoutingBucketStats{CPL: cpl, Capacity: routingBucketSize}
+		}
+		return buckets[cpl]
+	}
+
+	report := RoutingTableReport{TakenAt: now, Peers: len(current)}
+	for id, cpl := range current {
+		bucket(cpl).Peers++
+		if _, ok := previous[id]; previous != nil && !ok {
+			bucket(cpl).Added++
+			report.Added++
+		}
+	}
+	for id, cpl := range previous {
+		if _, ok := current[id]; !ok {
+			bucket(cpl).Removed++
+			report.Removed++
+		}
+	}
+	for cpl, refreshed := range rt.GetTrackedCplsForRefresh() {
+		if refreshed.IsZero() {
+			continue
+		}
+		bucket(cpl).LastRefresh = refreshed
+		if refreshed.After(report.LastRefresh) {
+			report.LastRefresh = refreshed
+		}
+	}
+
+	for _, stats := range buckets {
+		report.Buckets = append(report.Buckets, *stats)
+	}
+	sort.Slice(report.Buckets, func(i, j int) bool { return report.Buckets[i].CPL < report.Buckets[j].CPL })
+
+	if !previousAt.IsZero() {
+		report.Interval = now.Sub(previousAt)
+		if hours := report.Interval.Hours(); hours > 0 {
+			report.ChurnPerHour = float64(report.Added+report.Removed) / hours
+		}
+	}
+	report.Health, report.Problems = routingHealth(report)
+	return report, nil
+}
+
+// ForceRefreshRoutingTable немедленно обновляет все корзины таблицы
+// маршрутизации и ждет завершения обновления
+func (dm *DiscoveryManager) ForceRefreshRoutingTable(ctx context.Context) error {
+	if dm.dht == nil {
+		return ErrDHTUnavailable
+	}
+
+	select {
+	case err := <-dm.dht.ForceRefresh():
+		if err != nil {
+			return fmt.Errorf("не удалось обновить таблицу маршрутизации: %w", err)
+		}
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// routingHealth оценивает таблицу маршрутизации: заполненность (40 баллов),
+// покрытие ближних корзин без пропусков (30), свежесть обновления (20) и
+// умеренную смену пиров (10)
+func routingHealth(report RoutingTableReport) (int, []string) {
+	if report.Peers == 0 {
+		return 0, []string{"таблица маршрутизации пуста"}
+	}
+
+	var problems []string
+	score := 40.0 * float64(min(report.Peers, routingHealthyPeers)) / routingHealthyPeers
+	if report.Peers < routingHealthyPeers {
+		problems = append(problems, fmt.Sprintf("в таблице %d пиров из желаемых %d", report.Peers, routingHealthyPeers))
+	}
+
+	// Корзины от 0 до самой глубокой непустой должны быть заполнены:
+	// пропуск означает, что часть пространства ключей недоступна
+	deepest, filled := 0, 0
+	for _, bucket := range report.Buckets {
+		if bucket.Peers > 0 {
+			deepest = max(deepest, bucket.CPL)
+			filled++
+		}
+	}
+	score += 30.0 * float64(filled) / float64(deepest+1)
+	if missing := deepest + 1 - filled; missing > 0 {
+		problems = append(problems, fmt.Sprintf("пустых корзин: %d", missing))
+	}
+
+	switch age := time.Since(report.LastRefresh); {
+	case report.LastRefresh.IsZero():
+		problems = append(problems, "таблица еще не обновлялась")
+	case age <= routingStaleRefresh:
+		score += 20
+	case age <= 3*routingStaleRefresh:
+		score += 10
+		problems = append(problems, "таблица давно не обновлялась")
+	default:
+		problems = append(problems, "таблица давно не обновлялась")
+	}
+
+	// Смена больше половины таблицы в час говорит о нестабильных пирах
+	switch churn := report.ChurnPerHour / float64(report.Peers); {
+	case report.Interval == 0 || churn < 0.5:
+		score += 10
+	case churn < 1:
+		score += 5
+	default:
+		problems = append(problems, fmt.Sprintf("высокая смена пиров: %.0f в час", report.ChurnPerHour))
+	}
+
+	return int(score + 0.5), problems
+}
@@ -2,33 +2,120 @@ package core
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"time"
 
 	dht "github.com/libp2p/go-libp2p-kad-dht"
+	"github.com/libp2p/go-libp2p/core/event"
 	"github.com/libp2p/go-libp2p/core/host"
+	"github.com/libp2p/go-libp2p/core/network"
 	"github.com/libp2p/go-libp2p/core/peer"
-	"github.com/libp2p/go-libp2p/p2p/discovery/mdns"
 	"github.com/libp2p/go-libp2p/p2p/discovery/routing"
 )
 
 // DISCOVERY_TAG - "секретное слово" для поиска участников через mDNS
 const DISCOVERY_TAG = "owl-whisper-mdns"
 
+const (
+	// announceRetryDelay - пауза перед повтором неудавшегося анонса
+	announceRetryDelay = time.Minute
+	// minReannounceInterval - анонс не обновляется чаще этого
+	minReannounceInterval = 5 * time.Minute
+)
+
+// RENDEZVOUS_TAG - пространство имен для поиска участников в глобальной сети
+const RENDEZVOUS_TAG = "owl-whisper-global-rendezvous"
+
+// localDiscovery - поиск в локальной сети (mDNS); nil в сборках с тегом nomdns
+type localDiscovery interface {
+	Start() error
+	Close() error
+	// SetNickname задает имя, видимое участникам локальной сети
+	SetNickname(nickname string)
+}
+
+// DiscoverySource - каким механизмом найден пир
+type DiscoverySource string
+
+const (
+	// DiscoverySourceMDNS - поиск в локальной сети
+	DiscoverySourceMDNS DiscoverySource = "mdns"
+	// DiscoverySourceDHTProvider - анонсы в DHT (самостоятельно или через помощника)
+	DiscoverySourceDHTProvider DiscoverySource = "dht-provider"
+	// DiscoverySourceRendezvous - поиск среди анонсированных в пространстве имен
+	// при разрешении конкретного пира
+	DiscoverySourceRendezvous DiscoverySource = "rendezvous"
+	// DiscoverySourceDHT - FindPeer в DHT при разрешении конкретного пира
+	DiscoverySourceDHT DiscoverySource = "dht"
+	// DiscoverySourceCache - адреса из кэша пиров
+	DiscoverySourceCache DiscoverySource = "cache"
+)
+
+// PeerFound - найденный участник; полезная нагрузка EventPeerFound.
+// Namespace - пространство имен, в котором пир анонсировался (пусто, если
+// источник не использует пространства имен)
+type PeerFound struct {
+	AddrInfo  peer.AddrInfo
+	Source    DiscoverySource
+	Namespace string
+	// Known - пир встречался раньше (см. Node.SeenPeer)
+	Known bool
+	// Nickname - имя, которое пир сам объявил в локальной сети (подпись
+	// проверена, но имя выбрано пиром и ничем не подтверждено)
+	Nickname string
+}
+
+// Tag возвращает источник вместе с пространством имен для rendezvous
+// ("rendezvous:<ns>"), чтобы приложение могло маршрутизировать находки
+func (f PeerFound) Tag() string {
+	if f.Source == DiscoverySourceRendezvous && f.Namespace != "" {
+		return string(f.Source) + ":" + f.Namespace
+	}
+	return string(f.Source)
+}
+
 // DiscoveryNotifee обрабатывает события обнаружения новых участников сети
 type DiscoveryNotifee struct {
 	node host.Host
 	ctx  context.Context
+	// seen - встречался ли пир раньше (см. Node.SeenPeer); может быть nil
+	seen func(peer.ID) bool
+	// found - получатель сведений о найденных пирах; может быть nil
+	found func(PeerFound)
+	// lan - участники, видимые сейчас в локальной сети
+	lan lanPeers
 }
 
 // HandlePeerFound вызывается, когда mDNS находит нового участника
 func (n *DiscoveryNotifee) HandlePeerFound(pi peer.AddrInfo) {
+	n.handleFound(PeerFound{AddrInfo: pi, Source: DiscoverySourceMDNS, Namespace: DISCOVERY_TAG})
+}
+
+// handleFound сообщает о найденном участнике и подключается к нему
+func (n *DiscoveryNotifee) handleFound(found PeerFound) {
+	pi := found.AddrInfo
 	// Пропускаем, если нашли самого себя
 	if pi.ID == n.node.ID() {
 		return
 	}
-	log.Printf("📢 Обнаружен новый участник: %s", pi.ID.String())
+	if n.node.Network().Connectedness(pi.ID) == network.Connected {
+		return
+	}
+	found.Known = n.seen != nil && n.seen(pi.ID)
+	who := pi.ID.String()
+	if found.Nickname != "" {
+		who = found.Nickname + " " + who
+	}
+	if found.Known {
+		log.Printf("📢 Обнаружен знакомый участник (%s): %s", found.Source, who)
+	} else {
+		log.Printf("📢 Обнаружен новый участник (%s): %s", found.Source, who)
+	}
+	if n.found != nil {
+		n.found(found)
+	}
 
 	// Пытаемся подключиться к найденному участнику
 	err := n.node.Connect(n.ctx, pi)
@@ -41,25 +128,54 @@ func (n *DiscoveryNotifee) HandlePeerFound(pi peer.AddrInfo) {
 
 // DiscoveryManager управляет всеми механизмами обнаружения
 type DiscoveryManager struct {
-	mdnsService      mdns.Service
+	mdnsService      localDiscovery
 	dht              *dht.IpfsDHT
 	routingDiscovery *routing.RoutingDiscovery
 	notifee          *DiscoveryNotifee
 	ctx              context.Context
+
+	// reannounce - внеочередное обновление анонса (например, после смены сети)
+	reannounce chan struct{}
+
+	// dhtProxy - помощник, которому делегирован поиск (режим для слабых устройств)
+	dhtProxy *peer.AddrInfo
+	// proxyProvider - DHT в режиме клиента, через который узел с помощником
+	// сам публикует свою запись провайдера; nextProvide - когда ее обновить
+	proxyProvider *routing.RoutingDiscovery
+	nextProvide   time.Time
+
+	// metrics - успешность и длительность операций DHT
+	metrics dhtMetrics
+
+	// routing - предыдущий снимок таблицы маршрутизации для подсчета изменений
+	routing routingSnapshot
+
+	// bootstrap - узлы входа в DHT (в режиме помощника - сам помощник)
+	bootstrap []peer.AddrInfo
 }
 
-// NewDiscoveryManager создает новый менеджер обнаружения
-func NewDiscoveryManager(ctx context.Context, node host.Host) *DiscoveryManager {
+// ErrDHTUnavailable - узел не участвует в DHT (DHT не создан или поиск делегирован помощнику)
+var ErrDHTUnavailable = errors.New("DHT недоступен")
+
+// NewDiscoveryManager создает новый менеджер обнаружения. bootstrap заменяет
+// bootstrap-узлы DHT по умолчанию (например, замаскированными адресами)
+func NewDiscoveryManager(ctx context.Context, node host.Host, bootstrap ...peer.AddrInfo) *DiscoveryManager {
 	notifee := &DiscoveryNotifee{
 		node: node,
 		ctx:  ctx,
 	}
 
 	// Создаем mDNS сервис
-	mdnsService := mdns.NewMdnsService(node, DISCOVERY_TAG, notifee)
+	mdnsService := newMdnsService(node, notifee)
 
 	// Создаем DHT
-	kadDHT, err := dht.New(ctx, node)
+	var dhtOpts []dht.Option
+	if len(bootstrap) > 0 {
+		dhtOpts = append(dhtOpts, dht.BootstrapPeers(bootstrap...))
+	} else {
+		bootstrap = dht.GetDefaultBootstrapPeerAddrInfos()
+	}
+	kadDHT, err := dht.New(ctx, node, dhtOpts...)
 	if err != nil {
 		log.Printf("⚠️ Не удалось создать DHT: %v", err)
 	} else {
@@ -79,16 +195,85 @@ func NewDiscoveryManager(ctx context.Context, node host.Host) *DiscoveryManager
 		routingDiscovery: routingDiscovery,
 		notifee:          notifee,
 		ctx:              ctx,
+		reannounce:       make(chan struct{}, 1),
+		bootstrap:        bootstrap,
+	}
+}
+
+// NewProxyDiscoveryManager создает менеджер обнаружения, который не участвует
+// в DHT сам, а делегирует поиск доверенному помощнику. Это экономит батарею
+// и трафик мобильных устройств. Собственная запись провайдера по-прежнему
+// публикуется в DHT напрямую, но лишь раз за срок ее жизни
+func NewProxyDiscoveryManager(ctx context.Context, node host.Host, helper peer.AddrInfo) *DiscoveryManager {
+	notifee := &DiscoveryNotifee{
+		node: node,
+		ctx:  ctx,
+	}
+
+	// Запись провайдера подписывается ключом самого узла, поэтому публиковать
+	// ее приходится самому. DHT в режиме клиента не обслуживает чужие запросы
+	// и не обновляет таблицу маршрутизации: поиск ближайших узлов начинается
+	// с помощника и выполняется только при обновлении записи
+	var provider *routing.RoutingDiscovery
+	kadDHT, err := dht.New(ctx, node,
+		dht.Mode(dht.ModeClient),
+		dht.DisableAutoRefresh(),
+		dht.BootstrapPeers(helper),
+	)
+	if err != nil {
+		log.Printf("⚠️ Не удалось создать DHT для анонса: %v", err)
+	} else {
+		provider = routing.NewRoutingDiscovery(kadDHT)
+	}
+
+	return &DiscoveryManager{
+		mdnsService:   newMdnsService(node, notifee),
+		notifee:       notifee,
+		ctx:           ctx,
+		reannounce:    make(chan struct{}, 1),
+		dhtProxy:      &helper,
+		proxyProvider: provider,
+		bootstrap:     []peer.AddrInfo{helper},
+	}
+}
+
+// SetSeenPeerCheck задает проверку "встречался ли пир раньше" для
+// найденных участников (обычно Node.SeenPeer)
+func (dm *DiscoveryManager) SetSeenPeerCheck(seen func(peer.ID) bool) {
+	dm.notifee.seen = seen
+}
+
+// SetPeerFoundHandler задает получателя сведений о найденных участниках
+// (обычно Node.PublishPeerFound)
+func (dm *DiscoveryManager) SetPeerFoundHandler(found func(PeerFound)) {
+	dm.notifee.found = found
+}
+
+// SetLANNickname задает имя, которое видят участники локальной сети еще до
+// подключения; пустое имя перестает его показывать. Имя подписывается
+// ключом узла
+func (dm *DiscoveryManager) SetLANNickname(nickname string) {
+	if dm.mdnsService != nil {
+		dm.mdnsService.SetNickname(nickname)
 	}
 }
 
+// DisableLAN выключает обнаружение в локальной сети; вызывается до Start.
+// Нужно в режиме скрытия IP: mDNS объявляет локальные адреса узла
+func (dm *DiscoveryManager) DisableLAN() {
+	dm.mdnsService = nil
+}
+
 // Start запускает все механизмы обнаружения
 func (dm *DiscoveryManager) Start() error {
 	// Запускаем mDNS discovery
-	if err := dm.mdnsService.Start(); err != nil {
-		return fmt.Errorf("не удалось запустить mDNS: %w", err)
+	if dm.mdnsService != nil {
+		if err := dm.mdnsService.Start(); err != nil {
+			return fmt.Errorf("не удалось запустить mDNS: %w", err)
+		}
+		log.Println("📡 Сервис mDNS запущен. Идет поиск других участников...")
+		go dm.expireLANPeers()
 	}
-	log.Println("📡 Сервис mDNS запущен. Идет поиск других участников...")
 
 	// Запускаем DHT discovery для глобальной сети
 	if dm.dht != nil && dm.routingDiscovery != nil {
@@ -96,6 +281,15 @@ func (dm *DiscoveryManager) Start() error {
 		log.Println("🌐 DHT discovery запущен для глобальной сети")
 	}
 
+	// Смена собственных адресов требует нового анонса
+	dm.watchLocalAddresses()
+
+	// Или делегируем поиск помощнику
+	if dm.dhtProxy != nil {
+		go dm.runDHTProxyClient()
+		log.Printf("🛰️ Поиск в глобальной сети делегирован помощнику %s", dm.dhtProxy.ID.ShortString())
+	}
+
 	return nil
 }
 
@@ -124,17 +318,12 @@ func (dm *DiscoveryManager) startDHTDiscovery() {
 	// Ждем немного для стабилизации
 	time.Sleep(2 * time.Second)
 
-	// Анонсируемся в глобальной сети
-	ttl, err := dm.routingDiscovery.Advertise(dm.ctx, "owl-whisper-global-rendezvous")
-	if err != nil {
-		log.Printf("⚠️ Не удалось анонсироваться в глобальной сети: %v", err)
-	} else {
-		log.Printf("📢 Анонсировались в глобальной сети, TTL: %v", ttl)
-	}
+	// Анонсируемся в глобальной сети и обновляем анонс, пока узел работает
+	go dm.keepAnnounced()
 
 	// Начинаем поиск других участников
 	log.Println("🔍 Поиск участников в глобальной сети...")
-	peerChan, err := dm.routingDiscovery.FindPeers(dm.ctx, "owl-whisper-global-rendezvous")
+	peerChan, err := dm.findProviders(dm.ctx, RENDEZVOUS_TAG)
 	if err != nil {
 		log.Printf("⚠️ Ошибка поиска в глобальной сети: %v", err)
 		return
@@ -148,6 +337,153 @@ func (dm *DiscoveryManager) startDHTDiscovery() {
 		log.Printf("🌐 Найден участник в глобальной сети: %s", p.ID.ShortString())
 
 		// Передаем найденного пира в notifee для подключения
-		dm.notifee.HandlePeerFound(p)
+		dm.notifee.handleFound(PeerFound{AddrInfo: p, Source: DiscoverySourceDHTProvider, Namespace: RENDEZVOUS_TAG})
 	}
 }
+
+// keepAnnounced анонсирует узел в DHT и повторяет анонс до истечения TTL
+// записи провайдера, иначе через несколько часов простоя узел перестает
+// находиться. Reannounce обновляет анонс вне очереди
+func (dm *DiscoveryManager) keepAnnounced() {
+	dm.announceLoop(func() (time.Duration, bool) {
+		ttl, err := dm.advertise(dm.ctx)
+		if err != nil {
+			log.Printf("⚠️ Не удалось анонсироваться в глобальной сети: %v", err)
+			return announceRetryDelay, false
+		}
+		log.Printf("📢 Анонсировались в глобальной сети, TTL: %v", ttl)
+		// Обновляем с запасом, пока запись еще действует
+		next := ttl * 3 / 4
+		if next < minReannounceInterval {
+			next = minReannounceInterval
+		}
+		return next, true
+	})
+}
+
+// announceLoop вызывает announce сразу и затем через возвращенную им паузу.
+// Reannounce приближает следующий вызов, но не ближе minReannounceInterval
+// от последнего успешного анонса: адреса узла могут меняться часто
+func (dm *DiscoveryManager) announceLoop(announce func() (next time.Duration, ok bool)) {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	due := time.Now()
+	var last time.Time
+
+	for {
+		select {
+		case <-dm.ctx.Done():
+			return
+		case <-dm.reannounce:
+			earliest := last.Add(minReannounceInterval)
+			if !earliest.Before(due) {
+				continue
+			}
+			if !timer.Stop() {
+				select {
+				case <-timer.C:
+				default:
+				}
+			}
+			due = earliest
+			timer.Reset(time.Until(due))
+			continue
+		case <-timer.C:
+		}
+
+		next, ok := announce()
+		if ok {
+			last = time.Now()
+		}
+		due = time.Now().Add(next)
+		timer.Reset(next)
+	}
+}
+
+// Refresh перезапускает поиск после смены сети: заново подключается
+// к bootstrap-узлам, обновляет таблицу маршрутизации DHT и анонс
+func (dm *DiscoveryManager) Refresh() {
+	if dm.dht != nil {
+		go func() {
+			if err := dm.dht.Bootstrap(dm.ctx); err != nil {
+				log.Printf("⚠️ Не удалось подключиться к bootstrap узлам: %v", err)
+				return
+			}
+			if err := <-dm.dht.RefreshRoutingTable(); err != nil {
+				log.Printf("⚠️ Не удалось обновить таблицу маршрутизации DHT: %v", err)
+			}
+		}()
+	}
+	dm.Reannounce()
+}
+
+// DiscoveryReadiness - готовность поиска в глобальной сети
+type DiscoveryReadiness struct {
+	// BootstrapConnected из BootstrapTotal узлов входа подключены
+	// (в режиме помощника - сам помощник)
+	BootstrapConnected int
+	BootstrapTotal     int
+	// RoutingTableSize - пиров в таблице маршрутизации DHT; 0 в режиме помощника
+	RoutingTableSize int
+	// ViaHelper - поиск делегирован помощнику
+	ViaHelper bool
+	// Ready - узел может анонсироваться и искать участников
+	Ready bool
+}
+
+// Readiness проверяет, готов ли поиск в глобальной сети: в таблице
+// маршрутизации DHT есть пиры или подключен помощник
+func (dm *DiscoveryManager) Readiness() DiscoveryReadiness {
+	host := dm.notifee.node
+	readiness := DiscoveryReadiness{BootstrapTotal: len(dm.bootstrap)}
+	for _, info := range dm.bootstrap {
+		if host.Network().Connectedness(info.ID) == network.Connected {
+			readiness.BootstrapConnected++
+		}
+	}
+
+	switch {
+	case dm.dht != nil:
+		readiness.RoutingTableSize = dm.dht.RoutingTable().Size()
+		readiness.Ready = readiness.RoutingTableSize > 0
+	case dm.dhtProxy != nil:
+		readiness.ViaHelper = true
+		readiness.Ready = readiness.BootstrapConnected > 0
+	}
+	return readiness
+}
+
+// Reannounce обновляет анонс узла (в DHT или через помощника) вне очереди,
+// но не чаще minReannounceInterval
+func (dm *DiscoveryManager) Reannounce() {
+	select {
+	case dm.reannounce <- struct{}{}:
+	default:
+	}
+}
+
+// watchLocalAddresses обновляет анонс, когда меняются адреса узла
+func (dm *DiscoveryManager) watchLocalAddresses() {
+	sub, err := dm.notifee.node.EventBus().Subscribe(new(event.EvtLocalAddressesUpdated))
+	if err != nil {
+		log.Printf("⚠️ Не удалось подписаться на смену адресов: %v", err)
+		return
+	}
+
+	go func() {
+		defer sub.Close()
+		for {
+			select {
+			case <-dm.ctx.Done():
+				return
+			case e, ok := <-sub.Out():
+				if !ok {
+					return
+				}
+				if update := e.(event.EvtLocalAddressesUpdated); update.Diffs {
+					dm.Reannounce()
+				}
+			}
+		}
+	}()
+}
@@ -0,0 +1,34 @@
+package core
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestReannounceRateLimited(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	dm := &DiscoveryManager{ctx: ctx, reannounce: make(chan struct{}, 1)}
+
+	announced := make(chan struct{}, 10)
+	go dm.announceLoop(func() (time.Duration, bool) {
+		announced <- struct{}{}
+		return time.Hour, true
+	})
+
+	select {
+	case <-announced:
+	case <-time.After(time.Second):
+		t.Fatal("первый анонс не выполнен")
+	}
+	for i := 0; i < 3; i++ {
+		dm.Reannounce()
+		time.Sleep(10 * time.Millisecond)
+	}
+	select {
+	case <-announced:
+		t.Fatal("внеочередной анонс раньше minReannounceInterval")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
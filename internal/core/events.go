@@ -0,0 +1,291 @@
+package core
+
+import (
+	"log"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+// eventBufferSize - размер буфера канала событий ядра
+const eventBufferSize = 256
+
+// EventType - тип события ядра
+type EventType string
+
+const (
+	// EventMessageReceived - получено текстовое сообщение (см. MessageEvent)
+	EventMessageReceived EventType = "message_received"
+	// EventUnreadCountChanged - изменилось число непрочитанных в диалоге (см. UnreadCountChanged)
+	EventUnreadCountChanged EventType = "unread_count_changed"
+	// EventPeerConnected - установлено соединение с пиром (см. PeerEvent)
+	EventPeerConnected EventType = "peer_connected"
+	// EventPeerDisconnected - соединение с пиром разорвано (см. PeerEvent)
+	EventPeerDisconnected EventType = "peer_disconnected"
+
+	// EventNetworkChanged - сменилась сеть или адрес интерфейса (см. NetworkChanged)
+	EventNetworkChanged EventType = "network_changed"
+	// EventResumed - система проснулась после сна, соединения проверены (см. Resumed)
+	EventResumed EventType = "resumed"
+
+	// EventPeerFound - механизм обнаружения нашел участника (см. PeerFound)
+	EventPeerFound EventType = "peer_found"
+	// EventLANPeerAppeared - участник появился в локальной сети (см. LANPeer)
+	EventLANPeerAppeared EventType = "lan_peer_appeared"
+	// EventLANPeerExpired - участник перестал отвечать в локальной сети (см. LANPeer)
+	EventLANPeerExpired EventType = "lan_peer_expired"
+
+	// EventPeerCapabilities - стали известны или изменились возможности пира (см. PeerCapabilities)
+	EventPeerCapabilities EventType = "peer_capabilities"
+
+	// EventLeakSuspected - метрика рантайма монотонно растет (см. LeakSuspected)
+	EventLeakSuspected EventType = "leak_suspected"
+
+	// EventStreamOpened - открыт поток данных (см. StreamInfo)
+	EventStreamOpened EventType = "stream_opened"
+	// EventStreamData - получены данные из потока (см. StreamData)
+	EventStreamData EventType = "stream_data"
+	// EventStreamProgress - данные потока записаны получателю (см. StreamProgress)
+	EventStreamProgress EventType = "stream_progress"
+	// EventStreamClosed - поток данных закрыт (см. StreamInfo)
+	EventStreamClosed EventType = "stream_closed"
+
+	// EventScreenShare - демонстрация экрана началась или закончилась (см. ScreenShareState)
+	EventScreenShare EventType = "screen_share"
+	// EventScreenFrame - получен кадр демонстрации экрана (см. ScreenFrame)
+	EventScreenFrame EventType = "screen_frame"
+
+	// EventFileOffer - входящий файл ждет решения пользователя (см. IncomingFileOffer)
+	EventFileOffer EventType = "file_offer"
+	// EventFileReceived - прием файла завершен (см. FileReceived)
+	EventFileReceived EventType = "file_received"
+	// EventFileProgress - ход отправки или приема файла (см. FileProgress)
+	EventFileProgress EventType = "file_progress"
+
+	// EventTransferQueue - изменилось состояние очереди передач (см. TransferQueueState)
+	EventTransferQueue EventType = "transfer_queue"
+
+	// EventContactRequest - незнакомый пир просит добавить его в контакты (см. ContactRequest)
+	EventContactRequest EventType = "contact_request"
+
+	// EventScheduledMessage - отложенное сообщение отправлено или не ушло (см. ScheduledDispatch)
+	EventScheduledMessage EventType = "scheduled_message"
+
+	// EventRelayReservation - резервирование на ретрансляторе получено,
+	// продлено или потеряно (см. RelayReservationChanged)
+	EventRelayReservation EventType = "relay_reservation"
+
+	// EventUpdateAvailable - вышла новая версия приложения (см. UpdateAvailable)
+	EventUpdateAvailable EventType = "update_available"
+
+	// EventExportProgress - ход экспорта диалога в файл (см. ExportProgress)
+	EventExportProgress EventType = "export_progress"
+
+	// EventCleanupProgress - ход очистки хранилища (см. CleanupProgress)
+	EventCleanupProgress EventType = "cleanup_progress"
+
+	// EventBackup - резервная копия создана, восстановлена или не удалась (см. BackupStatus)
+	EventBackup EventType = "backup"
+
+	// EventIntroduction - знакомство через общий контакт ждет решения,
+	// состоялось или не удалось (см. Introduction)
+	EventIntroduction EventType = "introduction"
+
+	// EventContactCard - контакт прислал карточку другого пира (см. ContactCard)
+	EventContactCard EventType = "contact_card"
+
+	// EventStatusChanged - контакт сменил или снял статус (см. StatusMessage)
+	EventStatusChanged EventType = "status_changed"
+
+	// EventKeyTransition - контакт сменил ключ личности (см. KeyTransition)
+	EventKeyTransition EventType = "key_transition"
+
+	// EventIdentityDestroyed - контакт уничтожил свою личность (см. IdentityDestroyed)
+	EventIdentityDestroyed EventType = "identity_destroyed"
+
+	// EventMailboxRetrieved - из почтового ящика получены письма (см. MailboxRetrieved)
+	EventMailboxRetrieved EventType = "mailbox_retrieved"
+)
+
+// Event - событие ядра для внешних потребителей (TUI, встраивающие приложения)
+type Event struct {
+	Type      EventType
+	Timestamp time.Time
+	Payload   interface{}
+}
+
+// MessageDelivery - путь, которым пришло сообщение
+type MessageDelivery string
+
+const (
+	// DeliveryDirect - сообщение пришло напрямую от отправителя
+	DeliveryDirect MessageDelivery = "direct"
+	// DeliveryMailbox - сообщение пришло через почтовый ящик, пока мы были не в сети
+	DeliveryMailbox MessageDelivery = "mailbox"
+	// DeliverySealed - сообщение пришло напрямую со скрытым отправителем
+	DeliverySealed MessageDelivery = "sealed"
+)
+
+// MessageEvent - полезная нагрузка события EventMessageReceived
+type MessageEvent struct {
+	PeerID   peer.ID         `json:"peer_id"`
+	Text     string          `json:"text"`
+	Delivery MessageDelivery `json:"delivery"`
+	// SentAt - время отправки по часам отправителя; только для писем из
+	// ящика и со скрытым отправителем
+	SentAt time.Time `json:"sent_at,omitempty"`
+}
+
+// MailboxRetrieved - полезная нагрузка события EventMailboxRetrieved
+type MailboxRetrieved struct {
+	Mailbox   peer.ID `json:"mailbox"`
+	Delivered int     `json:"delivered"` // сколько новых писем передано в диалоги
+}
+
+// UnreadCountChanged - полезная нагрузка события EventUnreadCountChanged
+type UnreadCountChanged struct {
+	PeerID peer.ID `json:"peer_id"`
+	Unread int     `json:"unread"`
+}
+
+// ExportProgress - полезная нагрузка события EventExportProgress
+type ExportProgress struct {
+	PeerID   peer.ID `json:"peer_id"`
+	Path     string  `json:"path"`
+	Done     int     `json:"done"`
+	Total    int     `json:"total"`
+	Finished bool    `json:"finished"`
+	Error    string  `json:"error,omitempty"`
+}
+
+// CleanupProgress - полезная нагрузка события EventCleanupProgress
+type CleanupProgress struct {
+	Operation string `json:"operation"`
+	Done      int    `json:"done"`
+	Total     int    `json:"total"`
+	Finished  bool   `json:"finished"`
+	// Messages, Files и FreedBytes заполняются по завершении
+	Messages   int    `json:"messages,omitempty"`
+	Files      int    `json:"files,omitempty"`
+	FreedBytes int64  `json:"freed_bytes,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// BackupStatus - полезная нагрузка события EventBackup
+type BackupStatus struct {
+	Name     string   `json:"name"`
+	Size     int      `json:"size"`
+	Restored []string `json:"restored,omitempty"` // восстановленные файлы
+	Removed  []string `json:"removed,omitempty"`  // копии, удаленные политикой хранения
+	Error    string   `json:"error,omitempty"`
+}
+
+// UpdateAvailable - полезная нагрузка события EventUpdateAvailable.
+// Ядро только сообщает о релизе и ничего не устанавливает само
+type UpdateAvailable struct {
+	Current   string    `json:"current"`
+	Version   string    `json:"version"`
+	Published time.Time `json:"published"`
+	URL       string    `json:"url"`
+	Notes     string    `json:"notes"`
+}
+
+// PeerEvent - полезная нагрузка событий подключения и отключения пира
+type PeerEvent struct {
+	PeerID peer.ID `json:"peer_id"`
+	Addr   string  `json:"addr"`
+}
+
+// Events возвращает канал событий узла
+func (n *Node) Events() <-chan Event {
+	return n.events
+}
+
+// PublishUnreadCount сообщает фронтендам новое число непрочитанных сообщений
+// в диалоге с пиром, чтобы все они показывали одинаковые счетчики
+func (n *Node) PublishUnreadCount(peerID peer.ID, unread int) {
+	n.emit(EventUnreadCountChanged, UnreadCountChanged{PeerID: peerID, Unread: unread})
+}
+
+// PublishExportProgress сообщает фронтендам ход экспорта диалога
+func (n *Node) PublishExportProgress(progress ExportProgress) {
+	n.emit(EventExportProgress, progress)
+}
+
+// PublishCleanupProgress сообщает фронтендам ход очистки хранилища
+func (n *Node) PublishCleanupProgress(progress CleanupProgress) {
+	n.emit(EventCleanupProgress, progress)
+}
+
+// PublishBackupStatus сообщает фронтендам результат резервного копирования
+// или восстановления
+func (n *Node) PublishBackupStatus(status BackupStatus) {
+	n.emit(EventBackup, status)
+}
+
+// PublishUpdateAvailable сообщает фронтендам о новой версии приложения
+func (n *Node) PublishUpdateAvailable(update UpdateAvailable) {
+	n.emit(EventUpdateAvailable, update)
+}
+
+// PublishPeerFound публикует найденного участника (см. DiscoveryManager.SetPeerFoundHandler)
+func (n *Node) PublishPeerFound(found PeerFound) {
+	n.emit(EventPeerFound, found)
+}
+
+// PublishLANPeer публикует появление или уход участника локальной сети
+// (см. DiscoveryManager.SetLANPeerHandler)
+func (n *Node) PublishLANPeer(lanPeer LANPeer, visible bool) {
+	if visible {
+		n.emit(EventLANPeerAppeared, lanPeer)
+	} else {
+		n.emit(EventLANPeerExpired, lanPeer)
+	}
+}
+
+// emit публикует событие, не блокируя ядро, если потребитель не успевает
+func (n *Node) emit(eventType EventType, payload interface{}) {
+	event := Event{
+		Type:      eventType,
+		Timestamp: time.Now(),
+		Payload:   payload,
+	}
+
+	select {
+	case n.events <- event:
+	default:
+		n.dropped.Add(1)
+		log.Printf("⚠️ Буфер событий переполнен, событие %s отброшено", eventType)
+	}
+}
+
+// EventQueueStats - заполненность буфера событий; по ней видно, успевает ли
+// потребитель событий
+type EventQueueStats struct {
+	Pending  int `json:"pending"`
+	Capacity int `json:"capacity"`
+	// Dropped - сколько событий отброшено из-за переполнения с момента запуска
+	Dropped uint64 `json:"dropped"`
+}
+
+// EventQueueStats возвращает заполненность буфера событий
+func (n *Node) EventQueueStats() EventQueueStats {
+	return EventQueueStats{Pending: len(n.events), Capacity: cap(n.events), Dropped: n.dropped.Load()}
+}
+
+// emitBlocking публикует событие, дожидаясь места в буфере. Используется для
+// данных, потеря которых недопустима: медленный потребитель тормозит чтение
+func (n *Node) emitBlocking(eventType EventType, payload interface{}) bool {
+	event := Event{
+		Type:      eventType,
+		Timestamp: time.Now(),
+		Payload:   payload,
+	}
+
+	select {
+	case n.events <- event:
+		return true
+	case <-n.ctx.Done():
+		return false
+	}
+}
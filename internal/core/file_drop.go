@@ -0,0 +1,56 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/multiformats/go-multiaddr"
+	manet "github.com/multiformats/go-multiaddr/net"
+)
+
+// ErrNoLANAddrs - у участника нет адресов в локальной сети
+var ErrNoLANAddrs = errors.New("у участника нет адресов в локальной сети")
+
+// SendFileToNearbyPeer отправляет файл участнику локальной сети, найденному
+// через mDNS (см. DiscoveryManager.GetLANPeers). Соединение устанавливается
+// только по его локальным адресам, без DHT и ретрансляторов, поэтому
+// работает и без интернета. Получатель подтверждает прием, если мы не в его
+// контактах. Возвращает ID передачи в очереди
+func (n *Node) SendFileToNearbyPeer(lanPeer LANPeer, path string) (uint64, error) {
+	id := lanPeer.AddrInfo.ID
+	addrs := lanAddrs(lanPeer.AddrInfo.Addrs)
+	if len(addrs) == 0 {
+		return 0, fmt.Errorf("%s: %w", id.ShortString(), ErrNoLANAddrs)
+	}
+	if info, err := os.Stat(path); err != nil {
+		return 0, err
+	} else if info.IsDir() {
+		return 0, fmt.Errorf("%s - директория", path)
+	}
+
+	transferID := n.transfers.Enqueue(id, filepath.Base(path), PriorityUser, func(ctx context.Context) error {
+		if err := n.host.Connect(ctx, peer.AddrInfo{ID: id, Addrs: addrs}); err != nil {
+			return fmt.Errorf("не удалось подключиться к %s в локальной сети: %w", id.ShortString(), err)
+		}
+		return n.sendFile(ctx, id, path, true)
+	})
+	return transferID, nil
+}
+
+// lanAddrs оставляет прямые адреса локальной сети
+func lanAddrs(addrs []multiaddr.Multiaddr) []multiaddr.Multiaddr {
+	var result []multiaddr.Multiaddr
+	for _, addr := range addrs {
+		if _, err := addr.ValueForProtocol(multiaddr.P_CIRCUIT); err == nil {
+			continue
+		}
+		if manet.IsPrivateAddr(addr) || manet.IsIPLoopback(addr) {
+			result = append(result, addr)
+		}
+	}
+	return result
+}
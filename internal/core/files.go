@@ -0,0 +1,433 @@
+package core
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+// FILE_PROTOCOL_ID - протокол передачи файлов: заголовок, решение получателя, содержимое
+const FILE_PROTOCOL_ID = "/owl-whisper/file/1.0.0"
+
+const (
+	// fileHeaderLimit - максимальный размер JSON заголовка файла
+	fileHeaderLimit = 64 * 1024
+	// fileChunkHashSize - сколько байт JSON занимает хеш одного блока в
+	// списке, который идет за заголовком; по нему считается предел списка
+	fileChunkHashSize = 2*sha256.Size + 3
+	// fileProgressParts - на сколько долей делится передача для EventFileProgress
+	fileProgressParts = 10
+	// fileOfferTimeout - сколько ждем решения пользователя по входящему файлу
+	fileOfferTimeout = 2 * time.Minute
+	// fileCopyBufferSize - размер буфера копирования содержимого
+	fileCopyBufferSize = 64 * 1024
+
+	fileAccepted byte = 1
+	fileRejected byte = 0
+)
+
+var (
+	// ErrFileRejected - получатель отказался от файла
+	ErrFileRejected = errors.New("получатель отклонил файл")
+	// ErrOfferNotFound - предложение файла не найдено или уже обработано
+	ErrOfferNotFound = errors.New("предложение файла не найдено")
+)
+
+// fileHeader - заголовок, который отправитель передает перед содержимым.
+// Хеши блоков в заголовок не входят: их список идет следующей строкой, и
+// его предел зависит от размера файла
+type fileHeader struct {
+	Name     string         `json:"name"`
+	Size     int64          `json:"size"`
+	Manifest *ChunkManifest `json:"manifest"`
+	// Nearby - файл отправлен участнику локальной сети (SendFileToNearbyPeer)
+	Nearby bool `json:"nearby,omitempty"`
+}
+
+// IncomingFileOffer - полезная нагрузка события EventFileOffer
+type IncomingFileOffer struct {
+	OfferID  uint64           `json:"offer_id"`
+	Offer    FileOffer        `json:"offer"`
+	Decision TransferDecision `json:"decision"`
+}
+
+// FileReceived - полезная нагрузка события EventFileReceived
+type FileReceived struct {
+	OfferID  uint64       `json:"offer_id"`
+	PeerID   peer.ID      `json:"peer_id"`
+	Path     string       `json:"path"`
+	Size     int64        `json:"size"`
+	Hash     string       `json:"hash,omitempty"` // SHA-256, только для проверенных файлов
+	Verified bool         `json:"verified"`
+	Safety   SafetyReport `json:"safety"`
+	Error    string       `json:"error,omitempty"`
+	// Requested - файл пришел в ответ на FetchAttachment
+	Requested bool `json:"requested,omitempty"`
+}
+
+// FileProgress - полезная нагрузка события EventFileProgress. Публикуется
+// при каждой пройденной десятой части файла; завершение сообщают
+// EventTransferQueue для отправки и EventFileReceived для приема
+type FileProgress struct {
+	PeerID   peer.ID `json:"peer_id"`
+	Name     string  `json:"name"`
+	Bytes    int64   `json:"bytes"`
+	Size     int64   `json:"size"`
+	Incoming bool    `json:"incoming"`
+}
+
+// fileProgress считает переданные байты и публикует EventFileProgress
+type fileProgress struct {
+	node     *Node
+	progress FileProgress
+	reported int64
+}
+
+// Write учитывает записанные байты, чтобы fileProgress можно было
+// подключить к копированию содержимого
+func (p *fileProgress) Write(data []byte) (int, error) {
+	p.add(len(data))
+	return len(data), nil
+}
+
+// add учитывает count байт и публикует событие на границе доли
+func (p *fileProgress) add(count int) {
+	p.progress.Bytes += int64(count)
+	if p.progress.Size <= 0 || p.progress.Bytes >= p.progress.Size {
+		return
+	}
+	if part := p.progress.Bytes * fileProgressParts / p.progress.Size; part > p.reported {
+		p.reported = part
+		p.node.emit(EventFileProgress, p.progress)
+	}
+}
+
+// offerReply - решение пользователя по предложению файла
+type offerReply struct {
+	accept bool
+	path   string
+}
+
+// pendingOffers - входящие файлы, ожидающие решения пользователя
+type pendingOffers struct {
+	mu     sync.Mutex
+	nextID uint64
+	offers map[uint64]chan offerReply
+}
+
+// SendFile ставит отправку файла в очередь передач и возвращает ID передачи
+func (n *Node) SendFile(peerID peer.ID, path string, priority TransferPriority) uint64 {
+	name := filepath.Base(path)
+	return n.transfers.Enqueue(peerID, name, priority, func(ctx context.Context) error {
+		return n.sendFile(ctx, peerID, path, false)
+	})
+}
+
+// sendFile передает файл: заголовок, ожидание решения, содержимое.
+// nearby отмечает файл, отправленный участнику локальной сети
+func (n *Node) sendFile(ctx context.Context, peerID peer.ID, path string, nearby bool) error {
+	n.bumpActivity(peerID, activityFile)
+	manifest, err := BuildManifest(path, ChecksumPerChunk, DefaultChunkSize)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("не удалось открыть %s: %w", path, err)
+	}
+	defer file.Close()
+
+	stream, err := n.newStream(ctx, peerID, FILE_PROTOCOL_ID)
+	if err != nil {
+		return fmt.Errorf("не удалось открыть поток к %s: %w", peerID.ShortString(), err)
+	}
+	defer stream.Close()
+
+	// Отмена передачи должна прерывать и ожидание решения, и запись
+	stop := context.AfterFunc(ctx, func() { stream.Reset() })
+	defer stop()
+
+	headerManifest := *manifest
+	headerManifest.ChunkHashes = nil
+	header, err := json.Marshal(fileHeader{Name: filepath.Base(path), Size: manifest.FileSize, Manifest: &headerManifest, Nearby: nearby})
+	if err != nil {
+		return fmt.Errorf("не удалось сериализовать заголовок: %w", err)
+	}
+	hashes, err := json.Marshal(manifest.ChunkHashes)
+	if err != nil {
+		return fmt.Errorf("не удалось сериализовать хеши блоков: %w", err)
+	}
+	header = append(append(append(header, '\n'), hashes...), '\n')
+	if _, err := stream.Write(header); err != nil {
+		return fmt.Errorf("не удалось отправить заголовок: %w", err)
+	}
+
+	reply := make([]byte, 1)
+	if _, err := io.ReadFull(stream, reply); err != nil {
+		return fmt.Errorf("не дождались ответа получателя: %w", err)
+	}
+	if reply[0] != fileAccepted {
+		return ErrFileRejected
+	}
+
+	log.Printf("📤 Отправка файла %s (%d байт) -> %s", filepath.Base(path), manifest.FileSize, peerID.ShortString())
+	progress := fileProgress{node: n, progress: FileProgress{PeerID: peerID, Name: filepath.Base(path), Size: manifest.FileSize}}
+	buf := make([]byte, fileCopyBufferSize)
+	for {
+		read, readErr := file.Read(buf)
+		if read > 0 {
+			if n.config.StreamWriteTimeout > 0 {
+				stream.SetWriteDeadline(time.Now().Add(n.config.StreamWriteTimeout))
+			}
+			if _, err := stream.Write(buf[:read]); err != nil {
+				return fmt.Errorf("ошибка отправки файла: %w", err)
+			}
+			progress.add(read)
+		}
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			return fmt.Errorf("ошибка чтения %s: %w", path, readErr)
+		}
+	}
+
+	return stream.CloseWrite()
+}
+
+// AcceptFileOffer принимает ожидающий файл; пустой path - путь по политике
+func (n *Node) AcceptFileOffer(offerID uint64, path string) error {
+	return n.offers.reply(offerID, offerReply{accept: true, path: path})
+}
+
+// RejectFileOffer отклоняет ожидающий файл
+func (n *Node) RejectFileOffer(offerID uint64) error {
+	return n.offers.reply(offerID, offerReply{accept: false})
+}
+
+// reply передает решение ожидающему обработчику
+func (p *pendingOffers) reply(offerID uint64, reply offerReply) error {
+	p.mu.Lock()
+	ch, ok := p.offers[offerID]
+	delete(p.offers, offerID)
+	p.mu.Unlock()
+
+	if !ok {
+		return fmt.Errorf("предложение #%d: %w", offerID, ErrOfferNotFound)
+	}
+	ch <- reply
+	return nil
+}
+
+// add регистрирует новое ожидающее предложение
+func (p *pendingOffers) add() (uint64, chan offerReply) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.offers == nil {
+		p.offers = make(map[uint64]chan offerReply)
+	}
+	p.nextID++
+	ch := make(chan offerReply, 1)
+	p.offers[p.nextID] = ch
+	return p.nextID, ch
+}
+
+// drop удаляет предложение без ответа (по таймауту)
+func (p *pendingOffers) drop(offerID uint64) {
+	p.mu.Lock()
+	delete(p.offers, offerID)
+	p.mu.Unlock()
+}
+
+// handleFileStream принимает входящий файл согласно политике или решению пользователя
+func (n *Node) handleFileStream(stream network.Stream) {
+	defer stream.Close()
+	remotePeer := stream.Conn().RemotePeer()
+	n.bumpActivity(remotePeer, activityFile)
+
+	reader := bufio.NewReader(io.LimitReader(stream, fileHeaderLimit))
+	line, err := reader.ReadBytes('\n')
+	if err != nil {
+		log.Printf("⚠️ Некорректный заголовок файла от %s: %v", remotePeer.ShortString(), err)
+		stream.Reset()
+		return
+	}
+
+	var header fileHeader
+	if err := json.Unmarshal(line, &header); err != nil || header.Manifest == nil || header.Size != header.Manifest.FileSize || header.Manifest.Validate() != nil {
+		log.Printf("⚠️ Некорректный заголовок файла от %s", remotePeer.ShortString())
+		stream.Reset()
+		return
+	}
+	if header.Manifest.Strategy == ChecksumPerChunk {
+		if reader, err = readChunkHashes(stream, reader, header.Manifest); err != nil {
+			log.Printf("⚠️ Некорректные хеши блоков файла от %s: %v", remotePeer.ShortString(), err)
+			stream.Reset()
+			return
+		}
+	}
+
+	offer := FileOffer{PeerID: remotePeer, Name: header.Name, Size: header.Size, Nearby: header.Nearby}
+	decision := n.EvaluateFileOffer(offer)
+	if decision.Blocked {
+		log.Printf("🚫 Файл %s от %s отклонен: %s", header.Name, remotePeer.ShortString(), decision.Reason)
+		stream.Write([]byte{fileRejected})
+		return
+	}
+	// Вложение, которое мы сами запросили заново у этого пира, не требует
+	// подтверждения; его содержимое потом сверяется с запрошенным хешем
+	requested := n.attachments.claim(remotePeer, header.Manifest.FileHash)
+	if requested {
+		decision.AutoAccept = true
+	}
+	// Файл от незнакомого устройства рядом принимается только с согласия пользователя
+	if offer.Nearby && !n.isKnownContact(remotePeer) {
+		decision.AutoAccept = false
+		decision.Reason = "файл от незнакомого устройства рядом"
+	}
+	offerID, replies := n.offers.add()
+
+	reply := offerReply{accept: decision.AutoAccept}
+	if !decision.AutoAccept {
+		n.emit(EventFileOffer, IncomingFileOffer{OfferID: offerID, Offer: offer, Decision: decision})
+		select {
+		case reply = <-replies:
+		case <-time.After(fileOfferTimeout):
+			n.offers.drop(offerID)
+			log.Printf("⌛ Предложение файла %s от %s истекло", header.Name, remotePeer.ShortString())
+		case <-n.ctx.Done():
+			n.offers.drop(offerID)
+		}
+	} else {
+		n.offers.drop(offerID)
+	}
+
+	if !reply.accept {
+		stream.Write([]byte{fileRejected})
+		return
+	}
+	path := decision.Path
+	if reply.path != "" {
+		path = reply.path
+	}
+
+	received := n.receiveFile(stream, reader, header, path, decision.Safety)
+	received.OfferID = offerID
+	received.PeerID = remotePeer
+	received.Requested = requested
+	if received.Error == "" && !received.Verified {
+		n.emitSecurity(SecurityVerificationFailed, SeverityWarning, remotePeer, "",
+			fmt.Sprintf("файл %s не совпадает с контрольными суммами отправителя", header.Name))
+	}
+	if requested && received.Error == "" && !received.Verified {
+		// Принятый без вопроса файл должен быть именно запрошенным вложением
+		os.Remove(path)
+		received.Error = "содержимое не совпадает с запрошенным вложением, файл удален"
+		received.Hash = ""
+	}
+	if received.Safety.Level == SafetyDangerous {
+		n.emitSecurity(SecurityDangerousFile, SeverityWarning, remotePeer, "",
+			fmt.Sprintf("файл %s: %v", header.Name, received.Safety.Reasons))
+	}
+	n.emit(EventFileReceived, received)
+}
+
+// readChunkHashes читает список хешей блоков, который идет за заголовком.
+// Предел списка считается по числу блоков файла. Возвращает читатель, в
+// буфере которого может оказаться начало содержимого
+func readChunkHashes(stream io.Reader, buffered *bufio.Reader, manifest *ChunkManifest) (*bufio.Reader, error) {
+	count := manifest.ChunkCount()
+	limit := int64(count)*fileChunkHashSize + 8
+	reader := bufio.NewReader(io.LimitReader(io.MultiReader(buffered, stream), limit))
+	line, err := reader.ReadBytes('\n')
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(line, &manifest.ChunkHashes); err != nil {
+		return nil, err
+	}
+	if len(manifest.ChunkHashes) != count {
+		return nil, fmt.Errorf("%w: %d блоков вместо %d", ErrManifestMismatch, len(manifest.ChunkHashes), count)
+	}
+	return reader, nil
+}
+
+// receiveFile подтверждает прием, проверяет начало содержимого и только
+// потом записывает файл и сверяет контрольные суммы
+func (n *Node) receiveFile(stream network.Stream, buffered *bufio.Reader, header fileHeader, path string, safety SafetyReport) FileReceived {
+	result := FileReceived{Path: path, Size: header.Size, Safety: safety}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		result.Error = err.Error()
+		stream.Write([]byte{fileRejected})
+		return result
+	}
+	if _, err := stream.Write([]byte{fileAccepted}); err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	// Часть содержимого могла попасть в буфер вместе с заголовком. Сигнатуру
+	// смотрим до записи на диск: замаскированный исполняемый файл не создается
+	content := bufio.NewReaderSize(io.LimitReader(io.MultiReader(buffered, stream), header.Size), fileCopyBufferSize)
+	peek, err := content.Peek(int(min(header.Size, 8)))
+	if err != nil {
+		result.Error = err.Error()
+		log.Printf("⚠️ Прием файла %s прерван: %v", header.Name, err)
+		return result
+	}
+	checkContentSafety(&result.Safety, peek, header.Name)
+	if result.Safety.Level == SafetyDangerous && safety.Level != SafetyDangerous {
+		stream.Reset()
+		result.Error = "содержимое не совпадает с расширением, файл не сохранен"
+		log.Printf("🚫 Файл %s отклонен: %v", header.Name, result.Safety.Reasons)
+		return result
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		stream.Reset()
+		result.Error = err.Error()
+		return result
+	}
+	progress := &fileProgress{node: n, progress: FileProgress{PeerID: stream.Conn().RemotePeer(), Name: header.Name, Size: header.Size, Incoming: true}}
+	written, err := io.CopyBuffer(io.MultiWriter(file, progress), content, make([]byte, fileCopyBufferSize))
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil && written != header.Size {
+		err = fmt.Errorf("получено %d байт из %d", written, header.Size)
+	}
+	if err != nil {
+		result.Error = err.Error()
+		log.Printf("⚠️ Прием файла %s прерван: %v", header.Name, err)
+		return result
+	}
+
+	verify, err := VerifyTransfer(path, header.Manifest)
+	if err != nil {
+		result.Error = err.Error()
+	} else {
+		result.Verified = verify.OK
+		if verify.OK {
+			result.Hash = header.Manifest.FileHash
+		}
+	}
+
+	log.Printf("📥 Получен файл %s (%d байт, проверен: %v)", path, written, result.Verified)
+	return result
+}
@@ -0,0 +1,46 @@
+package core
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"testing"
+)
+
+func TestChunkHashesAfterHeader(t *testing.T) {
+	// Файл на 300 МиБ: хеши блоков не поместились бы в fileHeaderLimit
+	manifest := &ChunkManifest{Strategy: ChecksumPerChunk, FileSize: 300 << 20, ChunkSize: DefaultChunkSize}
+	hashes := make([]string, manifest.ChunkCount())
+	for i := range hashes {
+		hashes[i] = string(bytes.Repeat([]byte("a"), 64))
+	}
+	line, err := json.Marshal(hashes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(line) <= fileHeaderLimit {
+		t.Fatalf("список хешей %d байт, тест должен превышать предел заголовка", len(line))
+	}
+
+	stream := bytes.NewReader(append(append(line, '\n'), "содержимое"...))
+	buffered := bufio.NewReader(io.LimitReader(stream, fileHeaderLimit))
+	reader, err := readChunkHashes(stream, buffered, manifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(manifest.ChunkHashes) != len(hashes) {
+		t.Fatalf("прочитано %d хешей из %d", len(manifest.ChunkHashes), len(hashes))
+	}
+	rest, err := io.ReadAll(io.MultiReader(reader, stream))
+	if err != nil || string(rest) != "содержимое" {
+		t.Fatalf("содержимое после хешей искажено: %q, %v", rest, err)
+	}
+
+	// Лишний блок не принимается
+	manifest.FileSize = DefaultChunkSize
+	extra := bytes.NewReader(append(line, '\n'))
+	if _, err := readChunkHashes(extra, bufio.NewReader(extra), manifest); err == nil {
+		t.Fatal("список длиннее числа блоков принят")
+	}
+}
@@ -0,0 +1,100 @@
+package core
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/libp2p/go-libp2p/core/crypto"
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+// GenerateIdentity создает новый Ed25519 ключ личности
+func GenerateIdentity() (crypto.PrivKey, error) {
+	priv, _, err := crypto.GenerateEd25519Key(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось сгенерировать ключ: %w", err)
+	}
+	return priv, nil
+}
+
+// LoadIdentity читает ключ личности из файла
+func LoadIdentity(path string) (crypto.PrivKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	priv, err := crypto.UnmarshalPrivateKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось разобрать ключ %s: %w", path, err)
+	}
+	return priv, nil
+}
+
+// SaveIdentity сохраняет ключ личности в файл, доступный только владельцу
+func SaveIdentity(path string, priv crypto.PrivKey) error {
+	data, err := crypto.MarshalPrivateKey(priv)
+	if err != nil {
+		return fmt.Errorf("не удалось сериализовать ключ: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("не удалось создать директорию для ключа: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return fmt.Errorf("не удалось сохранить ключ: %w", err)
+	}
+	return nil
+}
+
+// LoadOrCreateIdentity читает ключ личности или создает и сохраняет новый,
+// чтобы PeerID не менялся между запусками
+func LoadOrCreateIdentity(path string) (crypto.PrivKey, bool, error) {
+	priv, err := LoadIdentity(path)
+	if err == nil {
+		return priv, false, nil
+	}
+	if !os.IsNotExist(err) {
+		return nil, false, err
+	}
+
+	priv, err = GenerateIdentity()
+	if err != nil {
+		return nil, false, err
+	}
+	if err := SaveIdentity(path, priv); err != nil {
+		return nil, false, err
+	}
+	return priv, true, nil
+}
+
+// IdentityPeerID возвращает PeerID, соответствующий ключу
+func IdentityPeerID(priv crypto.PrivKey) (peer.ID, error) {
+	return peer.IDFromPrivateKey(priv)
+}
+
+// Fingerprint возвращает отпечаток ключа пира для сверки вне приложения:
+// SHA-256 открытого ключа группами по 4 hex-символа
+func Fingerprint(id peer.ID) (string, error) {
+	pub, err := id.ExtractPublicKey()
+	if err != nil {
+		return "", fmt.Errorf("не удалось извлечь ключ из PeerID: %w", err)
+	}
+	raw, err := crypto.MarshalPublicKey(pub)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(raw)
+	digits := strings.ToUpper(hex.EncodeToString(sum[:]))
+	groups := make([]string, 0, len(digits)/4)
+	for i := 0; i < len(digits); i += 4 {
+		groups = append(groups, digits[i:i+4])
+	}
+	return strings.Join(groups, " "), nil
+}
@@ -0,0 +1,495 @@
+package core
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/core/peerstore"
+	"github.com/libp2p/go-libp2p/core/record"
+	"github.com/multiformats/go-multiaddr"
+)
+
+// INTRODUCE_PROTOCOL_ID - протокол знакомства через общий контакт: если A и B
+// не находят друг друга, их общий контакт C передает между ними подписанные
+// подсказки адресов. Каждый шаг подтверждают C и B
+const INTRODUCE_PROTOCOL_ID = "/owl-whisper/introduce/1.0.0"
+
+const (
+	// introductionLimit - максимальный размер сообщения протокола
+	introductionLimit = 16 * 1024
+	// introductionTimeout - предельное время обмена одним сообщением
+	introductionTimeout = 30 * time.Second
+	// introductionTTL - сколько ждут решения и ответа на знакомство
+	introductionTTL = time.Hour
+	// introductionPendingLimit - сколько знакомств может ждать решения
+	introductionPendingLimit = 50
+	// introductionNoteLimit - максимальная длина пояснения к просьбе
+	introductionNoteLimit = 280
+	// hintValidity - насколько старой может быть подписанная подсказка адресов
+	hintValidity = introductionTTL
+)
+
+// Виды сообщений протокола знакомства
+const (
+	introKindRequest = "request" // A -> C: познакомь меня с B
+	introKindOffer   = "offer"   // C -> B: A хочет познакомиться
+	introKindReply   = "reply"   // B -> C: согласен, вот мои адреса для A
+	introKindHint    = "hint"    // C -> A: адреса B
+)
+
+// IntroductionStage - этап знакомства в событии EventIntroduction
+type IntroductionStage string
+
+const (
+	// IntroductionRequested - контакт просит познакомить его с Target; ждет решения
+	IntroductionRequested IntroductionStage = "requested"
+	// IntroductionOffered - общий контакт Via предлагает познакомиться с Requester; ждет решения
+	IntroductionOffered IntroductionStage = "offered"
+	// IntroductionConnected - знакомство состоялось, соединение установлено
+	IntroductionConnected IntroductionStage = "connected"
+	// IntroductionFailed - подсказка получена, но соединиться не удалось (см. Error)
+	IntroductionFailed IntroductionStage = "failed"
+)
+
+var (
+	// ErrIntroductionNotFound - знакомство не найдено среди ожидающих решения
+	ErrIntroductionNotFound = errors.New("знакомство не найдено")
+
+	// errIntroductionHint - подсказка адресов не прошла проверку
+	errIntroductionHint = errors.New("недействительная подсказка адресов")
+)
+
+// Introduction - знакомство через общий контакт; полезная нагрузка EventIntroduction.
+// Requester просит познакомить его с Target через Via
+type Introduction struct {
+	ID         uint64            `json:"id"`
+	Stage      IntroductionStage `json:"stage"`
+	Requester  peer.ID           `json:"requester"`
+	Target     peer.ID           `json:"target"`
+	Via        peer.ID           `json:"via"`
+	Note       string            `json:"note,omitempty"`
+	Error      string            `json:"error,omitempty"`
+	ReceivedAt time.Time         `json:"received_at"`
+}
+
+// introductionWire - сообщение протокола знакомства. Peer - второй участник:
+// цель для request, автор подсказки для offer и hint, адресат для reply
+type introductionWire struct {
+	Kind string `json:"kind"`
+	Peer string `json:"peer"`
+	Hint []byte `json:"hint"`
+	Note string `json:"note,omitempty"`
+}
+
+// pendingIntroduction - знакомство, ждущее решения, вместе с подсказкой
+type pendingIntroduction struct {
+	Introduction
+	hint []byte
+}
+
+// introductionPair - направление знакомства: кто и с кем
+type introductionPair struct {
+	from, to peer.ID
+}
+
+// introductions хранит ожидающие решения знакомства и разрешения на
+// пересылку ответов
+type introductions struct {
+	mu      sync.Mutex
+	nextID  uint64
+	pending map[uint64]*pendingIntroduction
+	// forwarding - одобренные нами знакомства: ответ to для from пересылается до срока
+	forwarding map[introductionPair]time.Time
+	// expected - наши просьбы: адреса to принимаются от посредника from до срока
+	expected map[introductionPair]time.Time
+}
+
+// RequestIntroduction просит общий контакт via познакомить нас с target.
+// via должен быть подключен; ответ придет событием EventIntroduction
+func (n *Node) RequestIntroduction(via, target peer.ID, note string) error {
+	if via == target || target == n.host.ID() || via == n.host.ID() {
+		return fmt.Errorf("некорректное знакомство")
+	}
+	if len(note) > introductionNoteLimit {
+		note = note[:introductionNoteLimit]
+	}
+
+	hint, err := n.signAddressHint()
+	if err != nil {
+		return err
+	}
+
+	n.introductions.allow(&n.introductions.expected, introductionPair{via, target})
+	if err := n.sendIntroduction(via, introductionWire{
+		Kind: introKindRequest,
+		Peer: target.String(),
+		Hint: hint,
+		Note: note,
+	}); err != nil {
+		n.introductions.revoke(&n.introductions.expected, introductionPair{via, target})
+		return err
+	}
+	return nil
+}
+
+// Introductions возвращает знакомства, ждущие решения, от старых к новым
+func (n *Node) Introductions() []Introduction {
+	s := &n.introductions
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.expireLocked(time.Now())
+	result := make([]Introduction, 0, len(s.pending))
+	for _, p := range s.pending {
+		result = append(result, p.Introduction)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].ID < result[j].ID })
+	return result
+}
+
+// AcceptIntroduction одобряет знакомство. Для просьбы контакта предложение
+// пересылается цели; для предложения наши адреса отправляются обратно
+// через посредника и мы сами пробуем соединиться
+func (n *Node) AcceptIntroduction(id uint64) (Introduction, error) {
+	s := &n.introductions
+	s.mu.Lock()
+	s.expireLocked(time.Now())
+	pending, ok := s.pending[id]
+	if ok {
+		delete(s.pending, id)
+	}
+	s.mu.Unlock()
+	if !ok {
+		return Introduction{}, ErrIntroductionNotFound
+	}
+
+	intro := pending.Introduction
+	switch intro.Stage {
+	case IntroductionRequested:
+		s.allow(&s.forwarding, introductionPair{intro.Requester, intro.Target})
+		err := n.sendIntroduction(intro.Target, introductionWire{
+			Kind: introKindOffer,
+			Peer: intro.Requester.String(),
+			Hint: pending.hint,
+			Note: intro.Note,
+		})
+		if err != nil {
+			s.revoke(&s.forwarding, introductionPair{intro.Requester, intro.Target})
+		}
+		return intro, err
+
+	case IntroductionOffered:
+		hint, err := n.signAddressHint()
+		if err != nil {
+			return intro, err
+		}
+		if err := n.sendIntroduction(intro.Via, introductionWire{
+			Kind: introKindReply,
+			Peer: intro.Requester.String(),
+			Hint: hint,
+		}); err != nil {
+			return intro, err
+		}
+		addrs, err := n.verifyAddressHint(pending.hint, intro.Requester)
+		if err != nil {
+			return intro, err
+		}
+		go n.connectIntroduced(intro, addrs)
+		return intro, nil
+	}
+	return intro, ErrIntroductionNotFound
+}
+
+// RejectIntroductions отклоняет знакомства по ID; без аргументов - все.
+// Отправитель об отказе не узнает. Возвращает число отклоненных
+func (n *Node) RejectIntroductions(ids ...uint64) int {
+	s := &n.introductions
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(ids) == 0 {
+		count := len(s.pending)
+		s.pending = nil
+		return count
+	}
+
+	count := 0
+	for _, id := range ids {
+		if _, ok := s.pending[id]; ok {
+			delete(s.pending, id)
+			count++
+		}
+	}
+	return count
+}
+
+// sendIntroduction отправляет сообщение протокола и ждет подтверждения
+func (n *Node) sendIntroduction(to peer.ID, wire introductionWire) error {
+	stream, err := n.newStream(n.ctx, to, INTRODUCE_PROTOCOL_ID)
+	if err != nil {
+		return fmt.Errorf("не удалось открыть поток к %s: %w", to.ShortString(), err)
+	}
+	defer stream.Close()
+	stream.SetDeadline(time.Now().Add(introductionTimeout))
+
+	data, err := json.Marshal(wire)
+	if err != nil {
+		return err
+	}
+	if _, err := stream.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("не удалось отправить знакомство: %w", err)
+	}
+
+	reply, err := bufio.NewReader(io.LimitReader(stream, introductionLimit)).ReadString('\n')
+	if err != nil {
+		return fmt.Errorf("пир %s не подтвердил знакомство: %w", to.ShortString(), err)
+	}
+	if reply = strings.TrimSpace(reply); reply != "ok" {
+		return fmt.Errorf("знакомство отклонено: %s", reply)
+	}
+	return nil
+}
+
+// handleIntroductionStream принимает сообщения протокола знакомства.
+// Все сообщения принимаются только от контактов
+func (n *Node) handleIntroductionStream(stream network.Stream) {
+	defer stream.Close()
+	stream.SetDeadline(time.Now().Add(introductionTimeout))
+	remotePeer := stream.Conn().RemotePeer()
+
+	line, err := bufio.NewReader(io.LimitReader(stream, introductionLimit)).ReadBytes('\n')
+	if err != nil {
+		stream.Reset()
+		return
+	}
+	var wire introductionWire
+	if err := json.Unmarshal(line, &wire); err != nil {
+		fmt.Fprintln(stream, "некорректное сообщение")
+		return
+	}
+	other, err := peer.Decode(wire.Peer)
+	if err != nil || other == remotePeer || other == n.host.ID() {
+		fmt.Fprintln(stream, "некорректный пир")
+		return
+	}
+	if !n.isKnownContact(remotePeer) {
+		fmt.Fprintln(stream, "знакомства принимаются только от контактов")
+		return
+	}
+
+	if err := n.receiveIntroduction(remotePeer, other, wire); err != nil {
+		log.Printf("🚫 Знакомство от %s отклонено: %v", remotePeer.ShortString(), err)
+		if errors.Is(err, errIntroductionHint) {
+			n.emitSecurity(SecurityVerificationFailed, SeverityWarning, remotePeer,
+				stream.Conn().RemoteMultiaddr().String(), err.Error())
+		}
+		fmt.Fprintln(stream, err.Error())
+		return
+	}
+	fmt.Fprintln(stream, "ok")
+}
+
+// receiveIntroduction обрабатывает проверенное по отправителю сообщение
+func (n *Node) receiveIntroduction(remote, other peer.ID, wire introductionWire) error {
+	s := &n.introductions
+	switch wire.Kind {
+	case introKindRequest:
+		// Мы - посредник: знакомить можно только со своим контактом
+		if !n.isKnownContact(other) {
+			return fmt.Errorf("пир не в контактах")
+		}
+		if _, err := n.verifyAddressHint(wire.Hint, remote); err != nil {
+			return err
+		}
+		return n.queueIntroduction(Introduction{
+			Stage:     IntroductionRequested,
+			Requester: remote,
+			Target:    other,
+			Via:       n.host.ID(),
+			Note:      wire.Note,
+		}, wire.Hint)
+
+	case introKindOffer:
+		if _, err := n.verifyAddressHint(wire.Hint, other); err != nil {
+			return err
+		}
+		return n.queueIntroduction(Introduction{
+			Stage:     IntroductionOffered,
+			Requester: other,
+			Target:    n.host.ID(),
+			Via:       remote,
+			Note:      wire.Note,
+		}, wire.Hint)
+
+	case introKindReply:
+		// Мы - посредник: пересылаем ответ, только если сами одобрили знакомство
+		pair := introductionPair{other, remote}
+		if !s.consume(&s.forwarding, pair) {
+			return fmt.Errorf("знакомство не одобрено")
+		}
+		if _, err := n.verifyAddressHint(wire.Hint, remote); err != nil {
+			return err
+		}
+		go func() {
+			if err := n.sendIntroduction(other, introductionWire{
+				Kind: introKindHint,
+				Peer: remote.String(),
+				Hint: wire.Hint,
+			}); err != nil {
+				log.Printf("⚠️ Не удалось передать адреса %s для %s: %v",
+					remote.ShortString(), other.ShortString(), err)
+			}
+		}()
+		return nil
+
+	case introKindHint:
+		if !s.consume(&s.expected, introductionPair{remote, other}) {
+			return fmt.Errorf("знакомство не запрашивалось")
+		}
+		addrs, err := n.verifyAddressHint(wire.Hint, other)
+		if err != nil {
+			return err
+		}
+		go n.connectIntroduced(Introduction{
+			Requester:  n.host.ID(),
+			Target:     other,
+			Via:        remote,
+			ReceivedAt: time.Now(),
+		}, addrs)
+		return nil
+	}
+	return fmt.Errorf("неизвестное сообщение %q", wire.Kind)
+}
+
+// queueIntroduction помещает знакомство в очередь решения и сообщает о нем
+func (n *Node) queueIntroduction(intro Introduction, hint []byte) error {
+	if len(intro.Note) > introductionNoteLimit {
+		intro.Note = intro.Note[:introductionNoteLimit]
+	}
+	intro.ReceivedAt = time.Now()
+
+	s := &n.introductions
+	s.mu.Lock()
+	s.expireLocked(intro.ReceivedAt)
+	if len(s.pending) >= introductionPendingLimit {
+		s.mu.Unlock()
+		return fmt.Errorf("слишком много знакомств ждут решения")
+	}
+	if s.pending == nil {
+		s.pending = make(map[uint64]*pendingIntroduction)
+	}
+	s.nextID++
+	intro.ID = s.nextID
+	s.pending[intro.ID] = &pendingIntroduction{Introduction: intro, hint: hint}
+	s.mu.Unlock()
+
+	n.emit(EventIntroduction, intro)
+	return nil
+}
+
+// connectIntroduced соединяется с пиром по адресам из подсказки
+func (n *Node) connectIntroduced(intro Introduction, addrs []multiaddr.Multiaddr) {
+	other := intro.Requester
+	if other == n.host.ID() {
+		other = intro.Target
+	}
+
+	n.host.Peerstore().AddAddrs(other, addrs, peerstore.TempAddrTTL)
+	ctx, cancel := context.WithTimeout(n.ctx, introductionTimeout)
+	defer cancel()
+
+	intro.Stage = IntroductionConnected
+	if err := n.host.Connect(ctx, peer.AddrInfo{ID: other, Addrs: addrs}); err != nil {
+		intro.Stage = IntroductionFailed
+		intro.Error = err.Error()
+		log.Printf("⚠️ Не удалось соединиться с %s после знакомства: %v", other.ShortString(), err)
+	} else {
+		log.Printf("🤝 Знакомство с %s через %s состоялось", other.ShortString(), intro.Via.ShortString())
+	}
+	n.emit(EventIntroduction, intro)
+}
+
+// signAddressHint подписывает наши текущие адреса ключом узла. Подсказка -
+// стандартный подписанный peer record libp2p, посредник не может ее подменить
+func (n *Node) signAddressHint() ([]byte, error) {
+	key := n.host.Peerstore().PrivKey(n.host.ID())
+	if key == nil {
+		return nil, fmt.Errorf("закрытый ключ узла недоступен")
+	}
+	rec := peer.PeerRecordFromAddrInfo(peer.AddrInfo{ID: n.host.ID(), Addrs: n.host.Addrs()})
+	envelope, err := record.Seal(rec, key)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось подписать адреса: %w", err)
+	}
+	return envelope.Marshal()
+}
+
+// verifyAddressHint проверяет подпись и свежесть подсказки автора author
+func (n *Node) verifyAddressHint(data []byte, author peer.ID) ([]multiaddr.Multiaddr, error) {
+	var rec peer.PeerRecord
+	envelope, err := record.ConsumeTypedEnvelope(data, &rec)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errIntroductionHint, err)
+	}
+	signer, err := peer.IDFromPublicKey(envelope.PublicKey)
+	if err != nil || signer != author || rec.PeerID != author {
+		return nil, fmt.Errorf("%w: подписана не %s", errIntroductionHint, author.ShortString())
+	}
+	// Seq в peer record - время создания в наносекундах
+	signed := time.Unix(0, int64(rec.Seq))
+	if age := time.Since(signed); age > hintValidity || age < -hintValidity {
+		return nil, fmt.Errorf("%w: устарела", errIntroductionHint)
+	}
+	if len(rec.Addrs) == 0 {
+		return nil, fmt.Errorf("%w: нет адресов", errIntroductionHint)
+	}
+	return rec.Addrs, nil
+}
+
+// allow разрешает пару до истечения introductionTTL
+func (s *introductions) allow(set *map[introductionPair]time.Time, pair introductionPair) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if *set == nil {
+		*set = make(map[introductionPair]time.Time)
+	}
+	(*set)[pair] = time.Now().Add(introductionTTL)
+}
+
+// revoke отменяет разрешение
+func (s *introductions) revoke(set *map[introductionPair]time.Time, pair introductionPair) {
+	s.mu.Lock()
+	delete(*set, pair)
+	s.mu.Unlock()
+}
+
+// consume проверяет и снимает разрешение; каждое используется один раз
+func (s *introductions) consume(set *map[introductionPair]time.Time, pair introductionPair) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	deadline, ok := (*set)[pair]
+	delete(*set, pair)
+	return ok && time.Now().Before(deadline)
+}
+
+// expireLocked удаляет знакомства, ждущие решения дольше introductionTTL
+func (s *introductions) expireLocked(now time.Time) {
+	for id, p := range s.pending {
+		if now.Sub(p.ReceivedAt) > introductionTTL {
+			delete(s.pending, id)
+		}
+	}
+}
@@ -0,0 +1,311 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"sync"
+	"time"
+
+	"OwlWhisper/pkg/interfaces"
+
+	"github.com/libp2p/go-libp2p/core/crypto"
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+// KEY_TRANSITION_PROTOCOL_ID - протокол оповещения контактов о смене ключа личности
+const KEY_TRANSITION_PROTOCOL_ID = "/owl-whisper/key-transition/1.0.0"
+
+const (
+	// keyTransitionTimeout - предельное время передачи записи о смене ключа
+	keyTransitionTimeout = 10 * time.Second
+	// keyTransitionAnnounce - сколько после смены ключа новый узел напоминает
+	// о ней контактам при подключении
+	keyTransitionAnnounce = 30 * 24 * time.Hour
+)
+
+// KeyTransition - запись о переходе с ключа OldPeerID на NewPeerID. Ее
+// подписывают оба ключа: старый подтверждает переход, новый - что он
+// действительно у владельца и переход не указывает на чужой PeerID.
+// Полезная нагрузка EventKeyTransition
+type KeyTransition struct {
+	OldPeerID    peer.ID   `json:"old_peer_id"`
+	NewPeerID    peer.ID   `json:"new_peer_id"`
+	IssuedAt     time.Time `json:"issued_at"`
+	OldSignature []byte    `json:"old_signature"`
+	NewSignature []byte    `json:"new_signature"`
+}
+
+// KeyTransitionHandler применяет принятую смену ключа контакта
+// (переносит контакт на новый PeerID)
+type KeyTransitionHandler func(KeyTransition) error
+
+// keyTransitions - известные смены ключей контактов и своя смена ключа
+type keyTransitions struct {
+	mu      sync.RWMutex
+	repo    interfaces.IKeyTransitionRepository
+	handler KeyTransitionHandler
+	// known - принятые переходы по старому PeerID
+	known map[peer.ID]KeyTransition
+	// own - переход на текущий ключ узла, о котором напоминаем контактам
+	own *KeyTransition
+}
+
+// statement возвращает подписываемый текст записи
+func (t KeyTransition) statement() []byte {
+	return []byte(fmt.Sprintf("owl-whisper key transition\nold:%s\nnew:%s\nissued:%s\n",
+		t.OldPeerID, t.NewPeerID, t.IssuedAt.UTC().Format(time.RFC3339)))
+}
+
+// Verify проверяет обе подписи записи
+func (t KeyTransition) Verify() error {
+	if t.OldPeerID == t.NewPeerID {
+		return fmt.Errorf("старый и новый PeerID совпадают")
+	}
+	for _, signer := range []struct {
+		id        peer.ID
+		signature []byte
+	}{{t.OldPeerID, t.OldSignature}, {t.NewPeerID, t.NewSignature}} {
+		pub, err := signer.id.ExtractPublicKey()
+		if err != nil {
+			return fmt.Errorf("не удалось извлечь ключ из %s: %w", signer.id.ShortString(), err)
+		}
+		if ok, err := pub.Verify(t.statement(), signer.signature); err != nil || !ok {
+			return fmt.Errorf("подпись %s недействительна", signer.id.ShortString())
+		}
+	}
+	return nil
+}
+
+// CreateKeyTransition подписывает переход с текущего ключа узла на newKey.
+// Узел продолжает работать со старым ключом до перезапуска
+func (n *Node) CreateKeyTransition(newKey crypto.PrivKey) (KeyTransition, error) {
+	oldKey := n.host.Peerstore().PrivKey(n.host.ID())
+	if oldKey == nil {
+		return KeyTransition{}, fmt.Errorf("закрытый ключ узла недоступен")
+	}
+	newID, err := peer.IDFromPrivateKey(newKey)
+	if err != nil {
+		return KeyTransition{}, err
+	}
+
+	t := KeyTransition{
+		OldPeerID: n.host.ID(),
+		NewPeerID: newID,
+		IssuedAt:  time.Now().UTC().Truncate(time.Second),
+	}
+	if t.OldSignature, err = oldKey.Sign(t.statement()); err != nil {
+		return KeyTransition{}, fmt.Errorf("не удалось подписать переход старым ключом: %w", err)
+	}
+	if t.NewSignature, err = newKey.Sign(t.statement()); err != nil {
+		return KeyTransition{}, fmt.Errorf("не удалось подписать переход новым ключом: %w", err)
+	}
+	return t, nil
+}
+
+// AnnounceKeyTransition сохраняет переход и рассылает его подключенным контактам.
+// Возвращает число контактов, получивших запись
+func (n *Node) AnnounceKeyTransition(t KeyTransition) (int, error) {
+	if err := t.Verify(); err != nil {
+		return 0, err
+	}
+	if err := n.saveKeyTransition(t); err != nil {
+		return 0, err
+	}
+
+	delivered := 0
+	for _, id := range n.host.Network().Peers() {
+		if !n.isKnownContact(id) {
+			continue
+		}
+		if err := n.pushKeyTransition(id, t); err != nil {
+			log.Printf("⚠️ Не удалось сообщить %s о смене ключа: %v", id.ShortString(), err)
+			continue
+		}
+		delivered++
+	}
+	return delivered, nil
+}
+
+// LoadKeyTransitions загружает известные смены ключей и сохраняет в хранилище новые
+func (n *Node) LoadKeyTransitions(repo interfaces.IKeyTransitionRepository) error {
+	records, err := repo.GetKeyTransitions(context.Background())
+	if err != nil {
+		return fmt.Errorf("не удалось загрузить смены ключей: %w", err)
+	}
+
+	known := make(map[peer.ID]KeyTransition, len(records))
+	var own *KeyTransition
+	for _, record := range records {
+		t, err := keyTransitionFromRecord(record)
+		if err != nil || t.Verify() != nil {
+			log.Printf("⚠️ Пропущена некорректная запись о смене ключа %s", record.OldPeerID)
+			continue
+		}
+		known[t.OldPeerID] = t
+		if t.NewPeerID == n.host.ID() && time.Since(t.IssuedAt) < keyTransitionAnnounce {
+			copied := t
+			own = &copied
+		}
+	}
+
+	n.keyTransitions.mu.Lock()
+	n.keyTransitions.repo = repo
+	n.keyTransitions.known = known
+	n.keyTransitions.own = own
+	n.keyTransitions.mu.Unlock()
+	return nil
+}
+
+// SetKeyTransitionHandler задает, как применять смену ключа контакта.
+// Без обработчика переход только сохраняется и публикуется событием
+func (n *Node) SetKeyTransitionHandler(handler KeyTransitionHandler) {
+	n.keyTransitions.mu.Lock()
+	n.keyTransitions.handler = handler
+	n.keyTransitions.mu.Unlock()
+}
+
+// CurrentPeerID возвращает последний известный PeerID пира с учетом
+// цепочки смен ключей
+func (n *Node) CurrentPeerID(id peer.ID) peer.ID {
+	n.keyTransitions.mu.RLock()
+	defer n.keyTransitions.mu.RUnlock()
+
+	seen := map[peer.ID]bool{id: true}
+	for {
+		t, ok := n.keyTransitions.known[id]
+		if !ok || seen[t.NewPeerID] {
+			return id
+		}
+		id = t.NewPeerID
+		seen[id] = true
+	}
+}
+
+// announceOwnTransition напоминает подключившемуся контакту о смене нашего ключа
+func (n *Node) announceOwnTransition(id peer.ID) {
+	n.keyTransitions.mu.RLock()
+	own := n.keyTransitions.own
+	n.keyTransitions.mu.RUnlock()
+
+	if own == nil || !n.isKnownContact(id) {
+		return
+	}
+	if err := n.pushKeyTransition(id, *own); err != nil {
+		log.Printf("⚠️ Не удалось напомнить %s о смене ключа: %v", id.ShortString(), err)
+	}
+}
+
+// pushKeyTransition передает запись о смене ключа пиру
+func (n *Node) pushKeyTransition(id peer.ID, t KeyTransition) error {
+	stream, err := n.newStream(n.ctx, id, KEY_TRANSITION_PROTOCOL_ID)
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+	stream.SetDeadline(time.Now().Add(keyTransitionTimeout))
+
+	return json.NewEncoder(stream).Encode(t)
+}
+
+// handleKeyTransitionStream принимает запись о смене ключа. Запись должен
+// прислать сам владелец (со старого или нового ключа), а старый PeerID
+// должен быть нашим контактом
+func (n *Node) handleKeyTransitionStream(stream network.Stream) {
+	defer stream.Close()
+	remotePeer := stream.Conn().RemotePeer()
+	stream.SetDeadline(time.Now().Add(keyTransitionTimeout))
+
+	var t KeyTransition
+	if err := json.NewDecoder(io.LimitReader(stream, 4096)).Decode(&t); err != nil {
+		stream.Reset()
+		return
+	}
+	if remotePeer != t.OldPeerID && remotePeer != t.NewPeerID {
+		return
+	}
+	if !n.isKnownContact(t.OldPeerID) {
+		return
+	}
+	if err := t.Verify(); err != nil {
+		log.Printf("🚫 Запись о смене ключа от %s отклонена: %v", remotePeer.ShortString(), err)
+		n.emitSecurity(SecurityVerificationFailed, SeverityWarning, remotePeer,
+			stream.Conn().RemoteMultiaddr().String(), "смена ключа: "+err.Error())
+		return
+	}
+
+	n.keyTransitions.mu.RLock()
+	_, known := n.keyTransitions.known[t.OldPeerID]
+	handler := n.keyTransitions.handler
+	n.keyTransitions.mu.RUnlock()
+	if known {
+		return
+	}
+
+	if err := n.saveKeyTransition(t); err != nil {
+		log.Printf("⚠️ %v", err)
+		return
+	}
+	if handler != nil {
+		if err := handler(t); err != nil {
+			log.Printf("⚠️ Не удалось перенести контакт на новый ключ: %v", err)
+		}
+	}
+
+	log.Printf("🔑 %s сменил ключ личности: новый PeerID %s", t.OldPeerID.ShortString(), t.NewPeerID.ShortString())
+	n.emitSecurity(SecurityKeyChanged, SeverityWarning, t.OldPeerID,
+		stream.Conn().RemoteMultiaddr().String(), "новый PeerID "+t.NewPeerID.String())
+	n.emit(EventKeyTransition, t)
+}
+
+// saveKeyTransition запоминает переход и сохраняет его в хранилище
+func (n *Node) saveKeyTransition(t KeyTransition) error {
+	n.keyTransitions.mu.Lock()
+	if n.keyTransitions.known == nil {
+		n.keyTransitions.known = make(map[peer.ID]KeyTransition)
+	}
+	n.keyTransitions.known[t.OldPeerID] = t
+	repo := n.keyTransitions.repo
+	n.keyTransitions.mu.Unlock()
+
+	if repo == nil {
+		return nil
+	}
+	if err := repo.SaveKeyTransition(context.Background(), keyTransitionRecord(t)); err != nil {
+		return fmt.Errorf("не удалось сохранить смену ключа: %w", err)
+	}
+	return nil
+}
+
+// keyTransitionRecord преобразует переход в запись хранилища
+func keyTransitionRecord(t KeyTransition) interfaces.KeyTransitionRecord {
+	return interfaces.KeyTransitionRecord{
+		OldPeerID:    t.OldPeerID.String(),
+		NewPeerID:    t.NewPeerID.String(),
+		IssuedAt:     t.IssuedAt,
+		OldSignature: t.OldSignature,
+		NewSignature: t.NewSignature,
+	}
+}
+
+// keyTransitionFromRecord восстанавливает переход из записи хранилища
+func keyTransitionFromRecord(record interfaces.KeyTransitionRecord) (KeyTransition, error) {
+	oldID, err := peer.Decode(record.OldPeerID)
+	if err != nil {
+		return KeyTransition{}, err
+	}
+	newID, err := peer.Decode(record.NewPeerID)
+	if err != nil {
+		return KeyTransition{}, err
+	}
+	return KeyTransition{
+		OldPeerID:    oldID,
+		NewPeerID:    newID,
+		IssuedAt:     record.IssuedAt,
+		OldSignature: record.OldSignature,
+		NewSignature: record.NewSignature,
+	}, nil
+}
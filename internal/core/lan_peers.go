@@ -0,0 +1,155 @@
+package core
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/multiformats/go-multiaddr"
+)
+
+const (
+	// lanBrowseCycle - как часто заново опрашивать локальную сеть. zeroconf
+	// сообщает о каждом участнике один раз за TTL записи, поэтому для свежести
+	// поиск перезапускается
+	lanBrowseCycle = time.Minute
+	// lanPeerExpiry - через сколько без ответа участник считается ушедшим
+	lanPeerExpiry = 3 * time.Minute
+	// lanExpiryCheck - как часто проверять ушедших участников
+	lanExpiryCheck = 30 * time.Second
+)
+
+// LANPeer - участник, видимый в локальной сети через mDNS; полезная нагрузка
+// событий EventLANPeerAppeared и EventLANPeerExpired
+type LANPeer struct {
+	AddrInfo peer.AddrInfo
+	// Nickname - имя, объявленное самим участником (см. PeerFound.Nickname)
+	Nickname  string
+	FirstSeen time.Time
+	LastSeen  time.Time
+}
+
+// lanPeers - участники, видимые сейчас в локальной сети
+type lanPeers struct {
+	mu    sync.Mutex
+	peers map[peer.ID]*LANPeer
+	// changed - получатель событий о появлении и уходе участников; может быть nil
+	changed func(lanPeer LANPeer, visible bool)
+}
+
+// see отмечает ответ участника. Возвращает, появился ли он только что и
+// изменились ли его адреса или имя
+func (l *lanPeers) see(found PeerFound) (lanPeer LANPeer, appeared, changed bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	existing, ok := l.peers[found.AddrInfo.ID]
+	if !ok {
+		if l.peers == nil {
+			l.peers = make(map[peer.ID]*LANPeer)
+		}
+		existing = &LANPeer{FirstSeen: now}
+		l.peers[found.AddrInfo.ID] = existing
+	}
+	changed = !ok || existing.Nickname != found.Nickname || !sameAddrs(existing.AddrInfo.Addrs, found.AddrInfo.Addrs)
+	existing.AddrInfo = found.AddrInfo
+	existing.Nickname = found.Nickname
+	existing.LastSeen = now
+	return *existing, !ok, changed
+}
+
+// expire удаляет участников, не отвечавших дольше lanPeerExpiry
+func (l *lanPeers) expire(now time.Time) []LANPeer {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var expired []LANPeer
+	for id, lanPeer := range l.peers {
+		if now.Sub(lanPeer.LastSeen) > lanPeerExpiry {
+			expired = append(expired, *lanPeer)
+			delete(l.peers, id)
+		}
+	}
+	return expired
+}
+
+// notify сообщает о появлении или уходе участника
+func (l *lanPeers) notify(lanPeer LANPeer, visible bool) {
+	l.mu.Lock()
+	changed := l.changed
+	l.mu.Unlock()
+
+	if changed != nil {
+		changed(lanPeer, visible)
+	}
+}
+
+// lanSeen отмечает участника, ответившего через mDNS. О новых участниках
+// и о смене их адресов или имени сообщается как о находке
+func (n *DiscoveryNotifee) lanSeen(found PeerFound) {
+	if found.AddrInfo.ID == n.node.ID() {
+		return
+	}
+	lanPeer, appeared, changed := n.lan.see(found)
+	if appeared {
+		n.lan.notify(lanPeer, true)
+	}
+	if changed {
+		n.handleFound(found)
+	}
+}
+
+// GetLANPeers возвращает участников, видимых сейчас в локальной сети,
+// начиная с недавно ответивших
+func (dm *DiscoveryManager) GetLANPeers() []LANPeer {
+	l := &dm.notifee.lan
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	result := make([]LANPeer, 0, len(l.peers))
+	for _, lanPeer := range l.peers {
+		result = append(result, *lanPeer)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].LastSeen.After(result[j].LastSeen) })
+	return result
+}
+
+// SetLANPeerHandler задает получателя событий о появлении и уходе
+// участников локальной сети (обычно Node.PublishLANPeer)
+func (dm *DiscoveryManager) SetLANPeerHandler(handler func(lanPeer LANPeer, visible bool)) {
+	dm.notifee.lan.mu.Lock()
+	dm.notifee.lan.changed = handler
+	dm.notifee.lan.mu.Unlock()
+}
+
+// expireLANPeers периодически убирает ушедших участников локальной сети
+func (dm *DiscoveryManager) expireLANPeers() {
+	ticker := time.NewTicker(lanExpiryCheck)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-dm.ctx.Done():
+			return
+		case now := <-ticker.C:
+			for _, lanPeer := range dm.notifee.lan.expire(now) {
+				dm.notifee.lan.notify(lanPeer, false)
+			}
+		}
+	}
+}
+
+// sameAddrs сравнивает наборы адресов без учета порядка
+func sameAddrs(a, b []multiaddr.Multiaddr) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for _, addr := range a {
+		if !multiaddr.Contains(b, addr) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,175 @@
+package core
+
+import (
+	"context"
+	"log"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// RuntimeStats - снимок состояния рантайма и сетевых ресурсов узла
+type RuntimeStats struct {
+	Timestamp   time.Time `json:"timestamp"`
+	Goroutines  int       `json:"goroutines"`
+	OpenConns   int       `json:"open_conns"`
+	OpenStreams int       `json:"open_streams"`
+	HeapAlloc   uint64    `json:"heap_alloc"`
+	HeapObjects uint64    `json:"heap_objects"`
+	NumGC       uint32    `json:"num_gc"`
+}
+
+// LeakSuspected - полезная нагрузка события EventLeakSuspected
+type LeakSuspected struct {
+	Metric  string        `json:"metric"`
+	Samples []uint64      `json:"samples"`
+	Window  time.Duration `json:"window"`
+}
+
+// LeakDetectorConfig - параметры режима обнаружения утечек
+type LeakDetectorConfig struct {
+	// Interval - период между снимками статистики
+	Interval time.Duration
+	// Samples - сколько подряд растущих снимков считается подозрением на утечку
+	Samples int
+}
+
+// DefaultLeakDetectorConfig возвращает параметры по умолчанию
+func DefaultLeakDetectorConfig() LeakDetectorConfig {
+	return LeakDetectorConfig{
+		Interval: 30 * time.Second,
+		Samples:  10,
+	}
+}
+
+// leakDetector периодически снимает статистику и ищет монотонный рост
+type leakDetector struct {
+	node    *Node
+	config  LeakDetectorConfig
+	history map[string][]uint64
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+// GetRuntimeStats возвращает текущую статистику рантайма и сетевых ресурсов
+func (n *Node) GetRuntimeStats() RuntimeStats {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	stats := RuntimeStats{
+		Timestamp:   time.Now(),
+		Goroutines:  runtime.NumGoroutine(),
+		HeapAlloc:   mem.HeapAlloc,
+		HeapObjects: mem.HeapObjects,
+		NumGC:       mem.NumGC,
+	}
+
+	conns := n.host.Network().Conns()
+	stats.OpenConns = len(conns)
+	for _, conn := range conns {
+		stats.OpenStreams += len(conn.GetStreams())
+	}
+
+	return stats
+}
+
+// StartLeakDetector включает отладочный режим обнаружения утечек.
+// Повторный вызов перезапускает детектор с новыми параметрами.
+func (n *Node) StartLeakDetector(config LeakDetectorConfig) {
+	defaults := DefaultLeakDetectorConfig()
+	if config.Interval <= 0 {
+		config.Interval = defaults.Interval
+	}
+	if config.Samples < 2 {
+		config.Samples = defaults.Samples
+	}
+
+	n.StopLeakDetector()
+
+	ctx, cancel := context.WithCancel(n.ctx)
+	detector := &leakDetector{
+		node:    n,
+		config:  config,
+		history: make(map[string][]uint64),
+		cancel:  cancel,
+	}
+
+	n.leakMu.Lock()
+	n.leakDetector = detector
+	n.leakMu.Unlock()
+
+	detector.wg.Add(1)
+	go detector.run(ctx)
+	log.Printf("🔬 Режим обнаружения утечек включен (интервал %v, окно %d)", config.Interval, config.Samples)
+}
+
+// StopLeakDetector выключает режим обнаружения утечек
+func (n *Node) StopLeakDetector() {
+	n.leakMu.Lock()
+	detector := n.leakDetector
+	n.leakDetector = nil
+	n.leakMu.Unlock()
+
+	if detector != nil {
+		detector.cancel()
+		detector.wg.Wait()
+	}
+}
+
+// run снимает статистику с заданным интервалом до отмены контекста
+func (d *leakDetector) run(ctx context.Context) {
+	defer d.wg.Done()
+
+	ticker := time.NewTicker(d.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			d.sample(d.node.GetRuntimeStats())
+		}
+	}
+}
+
+// sample добавляет снимок в историю и проверяет каждую метрику на рост
+func (d *leakDetector) sample(stats RuntimeStats) {
+	metrics := map[string]uint64{
+		"goroutines":   uint64(stats.Goroutines),
+		"open_streams": uint64(stats.OpenStreams),
+		"heap_objects": stats.HeapObjects,
+	}
+
+	for metric, value := range metrics {
+		samples := append(d.history[metric], value)
+		if len(samples) > d.config.Samples {
+			samples = samples[len(samples)-d.config.Samples:]
+		}
+		d.history[metric] = samples
+
+		if len(samples) == d.config.Samples && growsMonotonically(samples) {
+			log.Printf("⚠️ Подозрение на утечку: %s растет %d замеров подряд (%d -> %d)",
+				metric, len(samples), samples[0], samples[len(samples)-1])
+
+			d.node.emit(EventLeakSuspected, LeakSuspected{
+				Metric:  metric,
+				Samples: append([]uint64(nil), samples...),
+				Window:  d.config.Interval * time.Duration(len(samples)-1),
+			})
+
+			// Начинаем окно заново, чтобы не повторять событие на каждом замере
+			d.history[metric] = nil
+		}
+	}
+}
+
+// growsMonotonically проверяет, что значения не убывают и в целом выросли
+func growsMonotonically(samples []uint64) bool {
+	for i := 1; i < len(samples); i++ {
+		if samples[i] < samples[i-1] {
+			return false
+		}
+	}
+	return samples[len(samples)-1] > samples[0]
+}
@@ -0,0 +1,461 @@
+package core
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"OwlWhisper/pkg/interfaces"
+
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+// Протоколы почтового ящика: отправитель оставляет письмо, ящик доставляет
+// письма владельцу, когда тот подключается, а владелец сам забирает их при
+// запуске и после восстановления сети
+const (
+	MAILBOX_PROTOCOL_ID          = "/owl-whisper/mailbox/1.0.0"
+	MAILBOX_DELIVERY_PROTOCOL_ID = "/owl-whisper/mailbox-delivery/1.0.0"
+	MAILBOX_FETCH_PROTOCOL_ID    = "/owl-whisper/mailbox-fetch/1.0.0"
+)
+
+const (
+	// mailboxTimeout - предельное время сдачи или доставки писем
+	mailboxTimeout = 30 * time.Second
+	// mailboxEnvelopeLimit - максимальный размер одного письма
+	mailboxEnvelopeLimit = 64 * 1024
+	// mailboxOwnerQuota - сколько писем ящик хранит для одного владельца
+	mailboxOwnerQuota = 1000
+	// mailboxDepositWindow - окно ограничения частоты сдачи писем
+	mailboxDepositWindow = 10 * time.Minute
+	// mailboxPeerDeposits - сколько писем один пир может сдать за окно, чтобы
+	// не занять квоту владельца целиком
+	mailboxPeerDeposits = 30
+	// mailboxTotalDeposits - сколько писем ящик принимает за окно от всех
+	// пиров: одноразовые PeerID обходят ограничение на одного пира
+	mailboxTotalDeposits = 300
+	// mailboxExpireInterval - как часто ящик удаляет просроченные письма
+	mailboxExpireInterval = time.Hour
+	// mailboxSealOverhead - одноразовый ключ, nonce и тег GCM в письме
+	mailboxSealOverhead = mailboxKeySize + 12 + 16
+)
+
+// ErrNoMailbox - у контакта нет почтовых ящиков для писем, пока он не в сети
+var ErrNoMailbox = errors.New("у контакта нет почтового ящика")
+
+// mailboxDeposit - письмо, оставленное в почтовом ящике. Получатель нужен
+// ящику для доставки; отправитель, время и текст есть только в шифротексте
+type mailboxDeposit struct {
+	To         string `json:"to"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// mailboxDelivery - письмо, доставляемое владельцу ящика
+type mailboxDelivery struct {
+	ID         string `json:"id"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// mailboxService - роль почтового ящика: письма для владельцев, пока они не в сети
+type mailboxService struct {
+	mu         sync.Mutex
+	repo       interfaces.IMailboxRepository
+	owners     map[peer.ID]bool
+	delivering map[peer.ID]bool
+	storageKey []byte
+	retention  time.Duration
+	expiring   bool
+	deposits   map[peer.ID][]time.Time
+	total      []time.Time
+}
+
+// admitLocked учитывает письмо от remote, если он и все пиры вместе не
+// превысили ограничение частоты за mailboxDepositWindow
+func (m *mailboxService) admitLocked(remote peer.ID, now time.Time) bool {
+	cutoff := now.Add(-mailboxDepositWindow)
+	for id, times := range m.deposits {
+		if times = dropBefore(times, cutoff); len(times) == 0 {
+			delete(m.deposits, id)
+		} else {
+			m.deposits[id] = times
+		}
+	}
+	m.total = dropBefore(m.total, cutoff)
+
+	if len(m.deposits[remote]) >= mailboxPeerDeposits || len(m.total) >= mailboxTotalDeposits {
+		return false
+	}
+	if m.deposits == nil {
+		m.deposits = make(map[peer.ID][]time.Time)
+	}
+	m.deposits[remote] = append(m.deposits[remote], now)
+	m.total = append(m.total, now)
+	return true
+}
+
+// ServeMailbox делает узел почтовым ящиком для владельцев owners (обычно
+// своих устройств на постоянно работающем сервере). Письма хранятся не
+// дольше retention. Пустой список выключает прием писем
+func (n *Node) ServeMailbox(owners []peer.ID, repo interfaces.IMailboxRepository, retention time.Duration) error {
+	if retention <= 0 {
+		return fmt.Errorf("срок хранения писем должен быть положительным")
+	}
+	key := n.host.Peerstore().PrivKey(n.host.ID())
+	if key == nil {
+		return fmt.Errorf("закрытый ключ узла недоступен")
+	}
+	storageKey, err := deriveStorageKey(key)
+	if err != nil {
+		return err
+	}
+
+	set := make(map[peer.ID]bool, len(owners))
+	for _, id := range owners {
+		set[id] = true
+	}
+	n.mailbox.mu.Lock()
+	n.mailbox.owners = set
+	n.mailbox.repo = repo
+	n.mailbox.storageKey = storageKey
+	n.mailbox.retention = retention
+	startExpiry := !n.mailbox.expiring
+	n.mailbox.expiring = true
+	n.mailbox.mu.Unlock()
+
+	if startExpiry {
+		go n.runMailboxExpiry()
+	}
+
+	for _, id := range owners {
+		if len(n.host.Network().ConnsToPeer(id)) > 0 {
+			go n.deliverMailbox(id)
+		}
+	}
+	return nil
+}
+
+// runMailboxExpiry периодически удаляет письма, которые владельцы не забрали
+// за срок хранения
+func (n *Node) runMailboxExpiry() {
+	ticker := time.NewTicker(mailboxExpireInterval)
+	defer ticker.Stop()
+
+	for {
+		n.expireMailbox()
+		select {
+		case <-ticker.C:
+		case <-n.ctx.Done():
+			return
+		}
+	}
+}
+
+// expireMailbox удаляет просроченные письма
+func (n *Node) expireMailbox() {
+	n.mailbox.mu.Lock()
+	repo := n.mailbox.repo
+	retention := n.mailbox.retention
+	n.mailbox.mu.Unlock()
+	if repo == nil {
+		return
+	}
+
+	removed, err := repo.DeleteExpiredEnvelopes(n.ctx, time.Now().Add(-retention))
+	if err != nil {
+		log.Printf("⚠️ Не удалось удалить просроченные письма: %v", err)
+		return
+	}
+	if removed > 0 {
+		log.Printf("🗑️ Удалено просроченных писем из почтового ящика: %d", removed)
+	}
+}
+
+// pendingEnvelopes возвращает непросроченные письма владельца
+func (n *Node) pendingEnvelopes(repo interfaces.IMailboxRepository, owner peer.ID) ([]interfaces.MailboxEnvelope, error) {
+	n.expireMailbox()
+	return repo.GetEnvelopes(n.ctx, n.mailbox.tag(owner))
+}
+
+// tag возвращает метку владельца, под которой хранятся его письма
+func (m *mailboxService) tag(owner peer.ID) string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return storageTag(m.storageKey, owner)
+}
+
+// validEnvelopeSize проверяет, что размер письма совпадает с одним из
+// классов размера; письма другой длины выдавали бы длину текста
+func validEnvelopeSize(size int) bool {
+	for _, padded := range mailboxPadSizes {
+		if size == padded+mailboxSealOverhead {
+			return true
+		}
+	}
+	return false
+}
+
+// depositMessage оставляет зашифрованное письмо в почтовом ящике контакта из
+// его записи профиля. Возвращает ErrNoMailbox, если ящиков нет
+func (n *Node) depositMessage(to peer.ID, text string) error {
+	record, ok := n.peerProfile(to)
+	if !ok || len(record.Mailboxes) == 0 {
+		return ErrNoMailbox
+	}
+
+	ciphertext, err := n.sealLetterTo(record, text)
+	if err != nil {
+		return err
+	}
+
+	var lastErr error = ErrNoMailbox
+	for _, mailbox := range parseMailboxAddrs(record.Mailboxes) {
+		if lastErr = n.depositTo(mailbox, to, ciphertext); lastErr == nil {
+			return nil
+		}
+		log.Printf("⚠️ Почтовый ящик %s недоступен: %v", mailbox.ID.ShortString(), lastErr)
+	}
+	return lastErr
+}
+
+// sealLetterTo подписывает письмо контакту и шифрует его ключом для писем из
+// записи профиля контакта
+func (n *Node) sealLetterTo(record interfaces.ProfileRecord, text string) ([]byte, error) {
+	to, err := peer.Decode(record.PeerID)
+	if err != nil {
+		return nil, err
+	}
+	letter := mailboxLetter{ID: newMailboxID(), From: n.host.ID(), To: to, Text: text, SentAt: time.Now().UTC()}
+	key := n.host.Peerstore().PrivKey(n.host.ID())
+	if key == nil {
+		return nil, fmt.Errorf("закрытый ключ узла недоступен")
+	}
+	if letter.Signature, err = key.Sign(letter.statement()); err != nil {
+		return nil, fmt.Errorf("не удалось подписать письмо: %w", err)
+	}
+	return sealLetter(letter, record.MailboxKey)
+}
+
+// depositTo сдает письмо в один почтовый ящик
+func (n *Node) depositTo(mailbox peer.AddrInfo, to peer.ID, ciphertext []byte) error {
+	ctx, cancel := context.WithTimeout(n.ctx, mailboxTimeout)
+	defer cancel()
+
+	if err := n.host.Connect(ctx, mailbox); err != nil {
+		return err
+	}
+	stream, err := n.newStream(ctx, mailbox.ID, MAILBOX_PROTOCOL_ID)
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+	stream.SetDeadline(time.Now().Add(mailboxTimeout))
+
+	data, err := json.Marshal(mailboxDeposit{To: to.String(), Ciphertext: ciphertext})
+	if err != nil {
+		return err
+	}
+	if _, err := stream.Write(append(data, '\n')); err != nil {
+		return err
+	}
+	reply, err := bufio.NewReader(io.LimitReader(stream, 1024)).ReadString('\n')
+	if err != nil {
+		return fmt.Errorf("ящик не подтвердил прием: %w", err)
+	}
+	if reply = strings.TrimSpace(reply); reply != "ok" {
+		return errors.New(reply)
+	}
+	return nil
+}
+
+// handleMailboxStream принимает письмо для одного из владельцев ящика
+func (n *Node) handleMailboxStream(stream network.Stream) {
+	defer stream.Close()
+	stream.SetDeadline(time.Now().Add(mailboxTimeout))
+
+	line, err := bufio.NewReader(io.LimitReader(stream, 2*mailboxEnvelopeLimit)).ReadBytes('\n')
+	if err != nil {
+		stream.Reset()
+		return
+	}
+	var deposit mailboxDeposit
+	if err := json.Unmarshal(line, &deposit); err != nil {
+		fmt.Fprintln(stream, "некорректное письмо")
+		return
+	}
+	owner, err := peer.Decode(deposit.To)
+	if err != nil {
+		fmt.Fprintln(stream, "некорректный получатель")
+		return
+	}
+	if !validEnvelopeSize(len(deposit.Ciphertext)) {
+		fmt.Fprintln(stream, "некорректный размер письма")
+		return
+	}
+
+	remote := stream.Conn().RemotePeer()
+	n.mailbox.mu.Lock()
+	repo := n.mailbox.repo
+	serving := n.mailbox.owners[owner]
+	allowed := serving && repo != nil && n.mailbox.admitLocked(remote, time.Now())
+	n.mailbox.mu.Unlock()
+	if !serving || repo == nil {
+		fmt.Fprintln(stream, "ящик не принимает письма для этого получателя")
+		return
+	}
+	if !allowed {
+		log.Printf("🚫 Письмо от %s в почтовый ящик отклонено: слишком часто", remote.ShortString())
+		fmt.Fprintln(stream, "слишком много писем, попробуйте позже")
+		return
+	}
+
+	stored, err := n.pendingEnvelopes(repo, owner)
+	if err != nil {
+		fmt.Fprintln(stream, "ящик недоступен")
+		return
+	}
+	if len(stored) >= mailboxOwnerQuota {
+		fmt.Fprintln(stream, "ящик получателя переполнен")
+		return
+	}
+
+	envelope := interfaces.MailboxEnvelope{
+		ID:         newMailboxID(),
+		To:         n.mailbox.tag(owner),
+		Ciphertext: deposit.Ciphertext,
+		StoredAt:   time.Now(),
+	}
+	if err := repo.SaveEnvelope(n.ctx, envelope); err != nil {
+		log.Printf("⚠️ Не удалось сохранить письмо: %v", err)
+		fmt.Fprintln(stream, "ящик недоступен")
+		return
+	}
+	fmt.Fprintln(stream, "ok")
+	log.Printf("📬 Письмо для %s принято в почтовый ящик", owner.ShortString())
+
+	if len(n.host.Network().ConnsToPeer(owner)) > 0 {
+		go n.deliverMailbox(owner)
+	}
+}
+
+// begin отмечает начало доставки писем владельцу. false - владелец не
+// обслуживается или доставка ему уже идет
+func (m *mailboxService) begin(owner peer.ID) (interfaces.IMailboxRepository, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !m.owners[owner] || m.repo == nil || m.delivering[owner] {
+		return nil, false
+	}
+	if m.delivering == nil {
+		m.delivering = make(map[peer.ID]bool)
+	}
+	m.delivering[owner] = true
+	return m.repo, true
+}
+
+// end отмечает окончание доставки писем владельцу
+func (m *mailboxService) end(owner peer.ID) {
+	m.mu.Lock()
+	delete(m.delivering, owner)
+	m.mu.Unlock()
+}
+
+// serves проверяет, служит ли узел почтовым ящиком для владельца
+func (m *mailboxService) serves(owner peer.ID) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.owners[owner]
+}
+
+// deliverMailbox доставляет письма подключившемуся владельцу ящика
+func (n *Node) deliverMailbox(owner peer.ID) {
+	repo, ok := n.mailbox.begin(owner)
+	if !ok {
+		return
+	}
+	defer n.mailbox.end(owner)
+
+	envelopes, err := n.pendingEnvelopes(repo, owner)
+	if err != nil || len(envelopes) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(n.ctx, mailboxTimeout)
+	defer cancel()
+	stream, err := n.newStream(ctx, owner, MAILBOX_DELIVERY_PROTOCOL_ID)
+	if err != nil {
+		return
+	}
+	defer stream.Close()
+	n.sendLetters(stream, owner, repo, envelopes)
+}
+
+// handleMailboxFetchStream отдает письма владельцу, который сам пришел за ними
+func (n *Node) handleMailboxFetchStream(stream network.Stream) {
+	defer stream.Close()
+	owner := stream.Conn().RemotePeer()
+	if !n.mailbox.serves(owner) {
+		stream.Reset()
+		return
+	}
+	// Если письма уже доставляются, владелец получит их в том потоке
+	repo, ok := n.mailbox.begin(owner)
+	if !ok {
+		return
+	}
+	defer n.mailbox.end(owner)
+
+	envelopes, err := n.pendingEnvelopes(repo, owner)
+	if err != nil {
+		stream.Reset()
+		return
+	}
+	n.sendLetters(stream, owner, repo, envelopes)
+}
+
+// sendLetters передает письма владельцу по одному. Письмо удаляется только
+// после подтверждения владельца
+func (n *Node) sendLetters(stream network.Stream, owner peer.ID, repo interfaces.IMailboxRepository, envelopes []interfaces.MailboxEnvelope) {
+	reader := bufio.NewReader(io.LimitReader(stream, int64(len(envelopes))*16))
+	delivered := 0
+	for _, envelope := range envelopes {
+		stream.SetDeadline(time.Now().Add(mailboxTimeout))
+		data, err := json.Marshal(mailboxDelivery{ID: envelope.ID, Ciphertext: envelope.Ciphertext})
+		if err != nil {
+			return
+		}
+		if _, err := stream.Write(append(data, '\n')); err != nil {
+			break
+		}
+		reply, err := reader.ReadString('\n')
+		if err != nil || strings.TrimSpace(reply) != "ok" {
+			break
+		}
+		if err := repo.DeleteEnvelope(n.ctx, envelope.ID); err != nil {
+			log.Printf("⚠️ Не удалось удалить доставленное письмо: %v", err)
+		}
+		delivered++
+	}
+	if delivered > 0 {
+		log.Printf("📬 Доставлено писем %s: %d", owner.ShortString(), delivered)
+	}
+}
+
+// newMailboxID возвращает случайный ID письма
+func newMailboxID() string {
+	id := make([]byte, 16)
+	rand.Read(id)
+	return hex.EncodeToString(id)
+}
@@ -0,0 +1,230 @@
+package core
+
+import (
+	"bufio"
+	"context"
+	"crypto/ecdh"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+const (
+	// mailboxSeenTTL - сколько помнить ID полученных писем, чтобы не показать
+	// повторно письмо, подтверждение которого потерялось
+	mailboxSeenTTL = 7 * 24 * time.Hour
+	// mailboxDirectWindow - насколько время отправки письма может отличаться
+	// от получения того же сообщения напрямую, чтобы считаться повтором
+	mailboxDirectWindow = 2 * time.Minute
+	// mailboxDirectTTL - сколько помнить прямые сообщения для сверки с
+	// письмами, которые ящик доставит позже
+	mailboxDirectTTL = 24 * time.Hour
+)
+
+// mailboxDedup отсеивает повторы писем и письма, уже пришедшие напрямую
+type mailboxDedup struct {
+	mu      sync.Mutex
+	letters map[string]time.Time
+	recent  map[[sha256.Size]byte][]time.Time
+}
+
+// directKey - ключ прямого сообщения: отправитель и текст
+func directKey(from peer.ID, text string) [sha256.Size]byte {
+	return sha256.Sum256([]byte(from.String() + "\n" + text))
+}
+
+// direct запоминает сообщение, полученное напрямую
+func (d *mailboxDedup) direct(from peer.ID, text string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.recent == nil {
+		d.recent = make(map[[sha256.Size]byte][]time.Time)
+	}
+	d.pruneLocked(time.Now())
+	key := directKey(from, text)
+	d.recent[key] = append(d.recent[key], time.Now())
+}
+
+// duplicate проверяет, получено ли письмо раньше из ящика или напрямую.
+// Совпавшее прямое сообщение засчитывается только одному письму
+func (d *mailboxDedup) duplicate(letter mailboxLetter) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.pruneLocked(time.Now())
+	if _, ok := d.letters[letter.ID]; ok {
+		return true
+	}
+	key := directKey(letter.From, letter.Text)
+	for i, at := range d.recent[key] {
+		if diff := at.Sub(letter.SentAt); diff > -mailboxDirectWindow && diff < mailboxDirectWindow {
+			d.recent[key] = append(d.recent[key][:i], d.recent[key][i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// remember запоминает ID переданного в диалог письма
+func (d *mailboxDedup) remember(id string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.letters == nil {
+		d.letters = make(map[string]time.Time)
+	}
+	d.letters[id] = time.Now()
+}
+
+// pruneLocked забывает устаревшие записи
+func (d *mailboxDedup) pruneLocked(now time.Time) {
+	for id, at := range d.letters {
+		if now.Sub(at) > mailboxSeenTTL {
+			delete(d.letters, id)
+		}
+	}
+	for key, times := range d.recent {
+		kept := times[:0]
+		for _, at := range times {
+			if now.Sub(at) < mailboxDirectTTL {
+				kept = append(kept, at)
+			}
+		}
+		if len(kept) == 0 {
+			delete(d.recent, key)
+		} else {
+			d.recent[key] = kept
+		}
+	}
+}
+
+// RetrieveMailbox забирает письма из своих почтовых ящиков. Узел вызывает его
+// при запуске и после восстановления сети; письма, пришедшие пока мы в
+// сети, ящик доставляет сам
+func (n *Node) RetrieveMailbox() {
+	n.profiles.mu.RLock()
+	mailboxes := parseMailboxAddrs(n.profiles.own.Mailboxes)
+	n.profiles.mu.RUnlock()
+
+	for _, mailbox := range mailboxes {
+		if err := n.retrieveFrom(mailbox); err != nil {
+			log.Printf("⚠️ Не удалось забрать письма из почтового ящика %s: %v", mailbox.ID.ShortString(), err)
+		}
+	}
+}
+
+// retrieveFrom забирает письма из одного почтового ящика
+func (n *Node) retrieveFrom(mailbox peer.AddrInfo) error {
+	ctx, cancel := context.WithTimeout(n.ctx, mailboxTimeout)
+	defer cancel()
+
+	if err := n.host.Connect(ctx, mailbox); err != nil {
+		return err
+	}
+	stream, err := n.newStream(ctx, mailbox.ID, MAILBOX_FETCH_PROTOCOL_ID)
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+	return n.receiveLetters(stream, mailbox.ID)
+}
+
+// handleMailboxDeliveryStream принимает письма, которые доставляет свой ящик
+func (n *Node) handleMailboxDeliveryStream(stream network.Stream) {
+	defer stream.Close()
+	mailbox := stream.Conn().RemotePeer()
+	if !n.ownMailboxes()[mailbox] {
+		stream.Reset()
+		return
+	}
+	if err := n.receiveLetters(stream, mailbox); err != nil {
+		log.Printf("⚠️ Доставка писем из почтового ящика %s прервана: %v", mailbox.ShortString(), err)
+	}
+}
+
+// receiveLetters читает письма из потока ящика, расшифровывает их и передает
+// в диалоги как сообщения от отправителей. Каждое письмо подтверждается
+// только после того, как оно принято, чтобы ящик мог его удалить
+func (n *Node) receiveLetters(stream network.Stream, mailbox peer.ID) error {
+	key, err := n.mailboxKey()
+	if err != nil {
+		stream.Reset()
+		return err
+	}
+
+	delivered := 0
+	defer func() {
+		if delivered > 0 {
+			log.Printf("📬 Из почтового ящика %s получено писем: %d", mailbox.ShortString(), delivered)
+			n.emit(EventMailboxRetrieved, MailboxRetrieved{Mailbox: mailbox, Delivered: delivered})
+		}
+	}()
+
+	reader := bufio.NewReader(io.LimitReader(stream, int64(mailboxOwnerQuota)*2*mailboxEnvelopeLimit))
+	for {
+		stream.SetDeadline(time.Now().Add(mailboxTimeout))
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		var delivery mailboxDelivery
+		if err := json.Unmarshal(line, &delivery); err != nil {
+			stream.Reset()
+			return fmt.Errorf("некорректное письмо: %w", err)
+		}
+
+		// Подтверждаем и неразборчивые письма и повторы, чтобы они не
+		// копились в ящике
+		letter, err := n.openLetter(key, delivery.Ciphertext)
+		switch {
+		case err != nil:
+			log.Printf("🚫 Письмо из почтового ящика %s отклонено: %v", mailbox.ShortString(), err)
+		case n.mailboxDedup.duplicate(letter):
+			log.Printf("📬 Повтор письма от %s пропущен", letter.From.ShortString())
+		default:
+			message := MessageEvent{
+				PeerID:   letter.From,
+				Text:     letter.Text,
+				Delivery: DeliveryMailbox,
+				SentAt:   letter.SentAt,
+			}
+			if !n.dispatchMessage(message) {
+				stream.Reset()
+				return fmt.Errorf("письмо от %s не удалось передать в диалог", letter.From.ShortString())
+			}
+			n.mailboxDedup.remember(letter.ID)
+			delivered++
+		}
+		if _, err := fmt.Fprintln(stream, "ok"); err != nil {
+			return err
+		}
+	}
+}
+
+// openLetter расшифровывает письмо и проверяет, что оно подписано
+// отправителем и адресовано нам
+func (n *Node) openLetter(key *ecdh.PrivateKey, ciphertext []byte) (mailboxLetter, error) {
+	letter, err := openSealedLetter(key, ciphertext)
+	if err != nil {
+		return mailboxLetter{}, err
+	}
+	if letter.To != n.host.ID() {
+		return mailboxLetter{}, fmt.Errorf("письмо адресовано другому получателю")
+	}
+	if err := letter.verify(); err != nil {
+		n.emitSecurity(SecurityVerificationFailed, SeverityWarning, letter.From, "", err.Error())
+		return mailboxLetter{}, err
+	}
+	return letter, nil
+}
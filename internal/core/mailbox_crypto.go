@@ -0,0 +1,241 @@
+package core
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/hkdf"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/crypto"
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+// mailboxPadSizes - размеры, до которых дополняется письмо перед
+// шифрованием, чтобы ящик видел только класс размера, а не длину текста.
+// Последний размер оставляет место для одноразового ключа, nonce и тега GCM
+var mailboxPadSizes = []int{1024, 4096, 16384, 63 * 1024}
+
+const (
+	// mailboxKeySize - размер открытого ключа X25519 для писем
+	mailboxKeySize = 32
+	// mailboxKeyInfo - контекст вывода ключа для писем из ключа личности
+	mailboxKeyInfo = "owl-whisper mailbox key v1"
+	// mailboxSealInfo - контекст вывода ключа шифрования одного письма
+	mailboxSealInfo = "owl-whisper mailbox envelope v1"
+	// mailboxStorageInfo - контекст вывода ключа, которым ящик скрывает
+	// получателей в своем хранилище
+	mailboxStorageInfo = "owl-whisper mailbox storage v1"
+)
+
+// errEnvelopeOpen - письмо не удалось расшифровать своим ключом
+var errEnvelopeOpen = errors.New("письмо не удалось расшифровать")
+
+// mailboxLetter - содержимое письма. Отправитель и подпись находятся внутри
+// шифротекста, поэтому почтовый ящик не знает, от кого письмо
+type mailboxLetter struct {
+	ID        string    `json:"id"`
+	From      peer.ID   `json:"from"`
+	To        peer.ID   `json:"to"`
+	Text      string    `json:"text"`
+	SentAt    time.Time `json:"sent_at"`
+	Signature []byte    `json:"signature"`
+}
+
+// statement возвращает подписываемый текст письма. Получатель входит в
+// подпись, чтобы письмо нельзя было переслать другому
+func (l mailboxLetter) statement() []byte {
+	return []byte(fmt.Sprintf("owl-whisper mailbox letter\nid:%s\nfrom:%s\nto:%s\nsent:%s\ntext:%s",
+		l.ID, l.From, l.To, l.SentAt.UTC().Format(time.RFC3339Nano), l.Text))
+}
+
+// verify проверяет подпись письма ключом отправителя
+func (l mailboxLetter) verify() error {
+	pub, err := l.From.ExtractPublicKey()
+	if err != nil {
+		return err
+	}
+	ok, err := pub.Verify(l.statement(), l.Signature)
+	if err != nil || !ok {
+		return fmt.Errorf("подпись письма от %s недействительна", l.From.ShortString())
+	}
+	return nil
+}
+
+// mailboxKey выводит ключ X25519 для писем из ключа личности узла, поэтому
+// его не нужно хранить отдельно; смена ключа личности меняет и его
+func (n *Node) mailboxKey() (*ecdh.PrivateKey, error) {
+	key := n.host.Peerstore().PrivKey(n.host.ID())
+	if key == nil {
+		return nil, fmt.Errorf("закрытый ключ узла недоступен")
+	}
+	return deriveMailboxKey(key)
+}
+
+// deriveMailboxKey выводит ключ X25519 для писем из ключа личности
+func deriveMailboxKey(key crypto.PrivKey) (*ecdh.PrivateKey, error) {
+	raw, err := key.Raw()
+	if err != nil {
+		return nil, err
+	}
+	seed, err := hkdf.Key(sha256.New, raw, nil, mailboxKeyInfo, mailboxKeySize)
+	if err != nil {
+		return nil, err
+	}
+	return ecdh.X25519().NewPrivateKey(seed)
+}
+
+// deriveStorageKey выводит из ключа личности ящика ключ для скрытия
+// получателей в хранилище
+func deriveStorageKey(key crypto.PrivKey) ([]byte, error) {
+	raw, err := key.Raw()
+	if err != nil {
+		return nil, err
+	}
+	return hkdf.Key(sha256.New, raw, nil, mailboxStorageInfo, 32)
+}
+
+// storageTag возвращает метку получателя для хранилища ящика. Без ключа
+// ящика по метке нельзя узнать, кому адресованы письма
+func storageTag(storageKey []byte, owner peer.ID) string {
+	mac := hmac.New(sha256.New, storageKey)
+	mac.Write([]byte(owner))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// sealLetter сериализует письмо, дополняет его до класса размера и шифрует
+// для владельца ключа recipientKey
+func sealLetter(letter mailboxLetter, recipientKey []byte) ([]byte, error) {
+	data, err := json.Marshal(letter)
+	if err != nil {
+		return nil, err
+	}
+	padded, err := padLetter(data)
+	if err != nil {
+		return nil, err
+	}
+	return sealEnvelope(recipientKey, padded)
+}
+
+// openSealedLetter расшифровывает письмо своим ключом для писем. Подпись и
+// получателя проверяет вызывающий
+func openSealedLetter(key *ecdh.PrivateKey, sealed []byte) (mailboxLetter, error) {
+	padded, err := openEnvelope(key, sealed)
+	if err != nil {
+		return mailboxLetter{}, err
+	}
+	data, err := unpadLetter(padded)
+	if err != nil {
+		return mailboxLetter{}, err
+	}
+	var letter mailboxLetter
+	if err := json.Unmarshal(data, &letter); err != nil {
+		return mailboxLetter{}, fmt.Errorf("некорректное письмо: %w", err)
+	}
+	return letter, nil
+}
+
+// padLetter дополняет данные нулями до ближайшего класса размера. Формат:
+// длина данных (4 байта) || данные || нули
+func padLetter(data []byte) ([]byte, error) {
+	need := 4 + len(data)
+	for _, size := range mailboxPadSizes {
+		if need <= size {
+			padded := make([]byte, size)
+			binary.BigEndian.PutUint32(padded, uint32(len(data)))
+			copy(padded[4:], data)
+			return padded, nil
+		}
+	}
+	return nil, fmt.Errorf("письмо больше %d байт", mailboxPadSizes[len(mailboxPadSizes)-1]-4)
+}
+
+// unpadLetter убирает дополнение, добавленное padLetter
+func unpadLetter(padded []byte) ([]byte, error) {
+	if len(padded) < 4 {
+		return nil, errEnvelopeOpen
+	}
+	length := binary.BigEndian.Uint32(padded)
+	if uint64(length) > uint64(len(padded)-4) {
+		return nil, errEnvelopeOpen
+	}
+	return padded[4 : 4+length], nil
+}
+
+// sealEnvelope шифрует письмо для владельца ключа recipientKey: одноразовый
+// ключ X25519, общий секрет через HKDF и AES-256-GCM. Формат:
+// открытый одноразовый ключ || nonce || шифротекст
+func sealEnvelope(recipientKey []byte, plaintext []byte) ([]byte, error) {
+	recipient, err := ecdh.X25519().NewPublicKey(recipientKey)
+	if err != nil {
+		return nil, fmt.Errorf("некорректный ключ получателя: %w", err)
+	}
+	ephemeral, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := envelopeCipher(ephemeral, recipient, ephemeral.PublicKey().Bytes(), recipientKey)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	sealed := append(ephemeral.PublicKey().Bytes(), nonce...)
+	return aead.Seal(sealed, nonce, plaintext, nil), nil
+}
+
+// openEnvelope расшифровывает письмо своим ключом для писем
+func openEnvelope(key *ecdh.PrivateKey, sealed []byte) ([]byte, error) {
+	if len(sealed) < mailboxKeySize {
+		return nil, errEnvelopeOpen
+	}
+	ephemeralKey := sealed[:mailboxKeySize]
+	ephemeral, err := ecdh.X25519().NewPublicKey(ephemeralKey)
+	if err != nil {
+		return nil, errEnvelopeOpen
+	}
+	aead, err := envelopeCipher(key, ephemeral, ephemeralKey, key.PublicKey().Bytes())
+	if err != nil {
+		return nil, err
+	}
+
+	rest := sealed[mailboxKeySize:]
+	if len(rest) < aead.NonceSize() {
+		return nil, errEnvelopeOpen
+	}
+	plaintext, err := aead.Open(nil, rest[:aead.NonceSize()], rest[aead.NonceSize():], nil)
+	if err != nil {
+		return nil, errEnvelopeOpen
+	}
+	return plaintext, nil
+}
+
+// envelopeCipher выводит ключ AES-256-GCM из общего секрета X25519. Оба
+// открытых ключа входят в соль, привязывая ключ к паре участников
+func envelopeCipher(private *ecdh.PrivateKey, public *ecdh.PublicKey, ephemeralKey, recipientKey []byte) (cipher.AEAD, error) {
+	shared, err := private.ECDH(public)
+	if err != nil {
+		return nil, errEnvelopeOpen
+	}
+	salt := append(append([]byte(nil), ephemeralKey...), recipientKey...)
+	key, err := hkdf.Key(sha256.New, shared, salt, mailboxSealInfo, 32)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
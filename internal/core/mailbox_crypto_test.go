@@ -0,0 +1,186 @@
+package core
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/crypto"
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+// testIdentity создает ключ личности и PeerID для теста
+func testIdentity(t *testing.T) (crypto.PrivKey, peer.ID) {
+	t.Helper()
+	key, _, err := crypto.GenerateEd25519Key(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := peer.IDFromPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key, id
+}
+
+// testLetter подписывает письмо от from к to и шифрует его ключом для писем to
+func testLetter(t *testing.T, fromKey crypto.PrivKey, from, to peer.ID, toKey crypto.PrivKey, text string) (mailboxLetter, []byte) {
+	t.Helper()
+	letter := mailboxLetter{ID: newMailboxID(), From: from, To: to, Text: text, SentAt: time.Now().UTC()}
+	signature, err := fromKey.Sign(letter.statement())
+	if err != nil {
+		t.Fatal(err)
+	}
+	letter.Signature = signature
+
+	recipient, err := deriveMailboxKey(toKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed, err := sealLetter(letter, recipient.PublicKey().Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return letter, sealed
+}
+
+func TestMailboxCannotReadLetter(t *testing.T) {
+	senderKey, sender := testIdentity(t)
+	ownerKey, owner := testIdentity(t)
+	mailboxKey, mailbox := testIdentity(t)
+	_, sealed := testLetter(t, senderKey, sender, owner, ownerKey, "встречаемся в семь")
+
+	// Ящик не может расшифровать письмо ни своим ключом для писем, ни
+	// ключом, выведенным для хранилища
+	own, err := deriveMailboxKey(mailboxKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := openSealedLetter(own, sealed); !errors.Is(err, errEnvelopeOpen) {
+		t.Fatalf("ящик открыл чужое письмо: %v", err)
+	}
+
+	// В шифротексте нет ни текста, ни отправителя, ни получателя
+	for _, leak := range [][]byte{
+		[]byte("встречаемся"),
+		[]byte(sender), []byte(sender.String()),
+		[]byte(owner), []byte(owner.String()),
+		[]byte(mailbox.String()),
+	} {
+		if bytes.Contains(sealed, leak) {
+			t.Fatalf("шифротекст содержит %q", leak)
+		}
+	}
+}
+
+func TestMailboxOwnerOpensLetter(t *testing.T) {
+	senderKey, sender := testIdentity(t)
+	ownerKey, owner := testIdentity(t)
+	want, sealed := testLetter(t, senderKey, sender, owner, ownerKey, "привет")
+
+	key, err := deriveMailboxKey(ownerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	letter, err := openSealedLetter(key, sealed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if letter.ID != want.ID || letter.From != sender || letter.To != owner || letter.Text != want.Text {
+		t.Fatalf("письмо искажено: %+v", letter)
+	}
+	if err := letter.verify(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMailboxLetterTampering(t *testing.T) {
+	senderKey, sender := testIdentity(t)
+	ownerKey, owner := testIdentity(t)
+	_, sealed := testLetter(t, senderKey, sender, owner, ownerKey, "привет")
+
+	key, err := deriveMailboxKey(ownerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, i := range []int{0, mailboxKeySize, len(sealed) - 1} {
+		tampered := append([]byte(nil), sealed...)
+		tampered[i] ^= 1
+		if _, err := openSealedLetter(key, tampered); !errors.Is(err, errEnvelopeOpen) {
+			t.Fatalf("измененный байт %d не обнаружен: %v", i, err)
+		}
+	}
+}
+
+func TestMailboxLetterForgedSender(t *testing.T) {
+	_, sender := testIdentity(t)
+	forgerKey, _ := testIdentity(t)
+	ownerKey, owner := testIdentity(t)
+
+	// Письмо подписано не тем ключом, от имени которого отправлено
+	_, sealed := testLetter(t, forgerKey, sender, owner, ownerKey, "привет")
+	key, err := deriveMailboxKey(ownerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	letter, err := openSealedLetter(key, sealed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := letter.verify(); err == nil {
+		t.Fatal("поддельная подпись принята")
+	}
+}
+
+func TestMailboxLetterPadding(t *testing.T) {
+	senderKey, sender := testIdentity(t)
+	ownerKey, owner := testIdentity(t)
+
+	_, short := testLetter(t, senderKey, sender, owner, ownerKey, "да")
+	_, longer := testLetter(t, senderKey, sender, owner, ownerKey, string(bytes.Repeat([]byte("a"), 400)))
+	if len(short) != len(longer) {
+		t.Fatalf("размер письма выдает длину текста: %d и %d", len(short), len(longer))
+	}
+	for _, sealed := range [][]byte{short, longer} {
+		if !validEnvelopeSize(len(sealed)) {
+			t.Fatalf("размер %d не совпадает с классом размера", len(sealed))
+		}
+	}
+
+	tooLong := mailboxLetter{Text: string(bytes.Repeat([]byte("a"), mailboxPadSizes[len(mailboxPadSizes)-1]))}
+	key, err := deriveMailboxKey(ownerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sealLetter(tooLong, key.PublicKey().Bytes()); err == nil {
+		t.Fatal("письмо больше последнего класса размера принято")
+	}
+}
+
+func TestMailboxStorageTagHidesOwner(t *testing.T) {
+	mailboxKey, _ := testIdentity(t)
+	otherKey, _ := testIdentity(t)
+	_, owner := testIdentity(t)
+
+	storageKey, err := deriveStorageKey(mailboxKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherStorageKey, err := deriveStorageKey(otherKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tag := storageTag(storageKey, owner)
+	if tag != storageTag(storageKey, owner) {
+		t.Fatal("метка владельца нестабильна")
+	}
+	if bytes.Contains([]byte(tag), []byte(owner.String())) {
+		t.Fatal("метка содержит PeerID владельца")
+	}
+	if tag == storageTag(otherStorageKey, owner) {
+		t.Fatal("метка не зависит от ключа ящика")
+	}
+}
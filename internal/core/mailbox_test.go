@@ -0,0 +1,28 @@
+package core
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMailboxDepositLimit(t *testing.T) {
+	var service mailboxService
+	_, sender := testIdentity(t)
+	_, other := testIdentity(t)
+	now := time.Now()
+
+	for i := 0; i < mailboxPeerDeposits; i++ {
+		if !service.admitLocked(sender, now) {
+			t.Fatalf("письмо %d отклонено до достижения ограничения", i)
+		}
+	}
+	if service.admitLocked(sender, now) {
+		t.Fatal("пир превысил ограничение частоты")
+	}
+	if !service.admitLocked(other, now) {
+		t.Fatal("ограничение одного пира затронуло другого")
+	}
+	if !service.admitLocked(sender, now.Add(mailboxDepositWindow+time.Second)) {
+		t.Fatal("ограничение не сбросилось после окна")
+	}
+}
@@ -0,0 +1,285 @@
+//go:build !nomdns
+
+package core
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"log"
+	"math/rand"
+	"strings"
+	"sync"
+
+	"github.com/libp2p/go-libp2p/core/host"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/zeroconf/v2"
+	"github.com/multiformats/go-multiaddr"
+	manet "github.com/multiformats/go-multiaddr/net"
+)
+
+// TXT-записи mDNS. Адреса передаются так же, как в mDNS libp2p (dnsaddr=),
+// поэтому узлы без поддержки метаданных находят нас как раньше
+const (
+	mdnsDomain        = "local"
+	mdnsDNSAddrPrefix = "dnsaddr="
+	// mdnsNicknamePrefix - имя пользователя (только если он разрешил его показывать)
+	mdnsNicknamePrefix = "owl-nick="
+	// mdnsSignaturePrefix - подпись имени ключом узла: без нее любой в сети
+	// мог бы приписать чужому PeerID свое имя
+	mdnsSignaturePrefix = "owl-sig="
+	// mdnsNicknameLimit - максимальная длина имени в байтах (TXT-строка не длиннее 255)
+	mdnsNicknameLimit = 200
+)
+
+// mdnsService - обнаружение в локальной сети с метаданными в TXT-записях
+type mdnsService struct {
+	host     host.Host
+	notifee  *DiscoveryNotifee
+	instance string
+
+	mu       sync.Mutex
+	nickname string
+	server   *zeroconf.Server
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// newMdnsService создает сервис обнаружения в локальной сети
+func newMdnsService(node host.Host, notifee *DiscoveryNotifee) localDiscovery {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &mdnsService{
+		host:     node,
+		notifee:  notifee,
+		instance: randomInstanceName(32 + rand.Intn(32)),
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+}
+
+// Start регистрирует узел в mDNS и начинает поиск других участников
+func (s *mdnsService) Start() error {
+	addrs, err := s.announcedAddrs()
+	if err != nil {
+		return err
+	}
+	ips := mdnsIPs(addrs)
+	if len(ips) == 0 {
+		return errors.New("нет IP-адресов для анонса в локальной сети")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Порт обязателен для SRV-записи, но участники используют только TXT
+	server, err := zeroconf.RegisterProxy(s.instance, DISCOVERY_TAG, mdnsDomain, 4001,
+		s.instance, ips, s.txtRecords(addrs), nil)
+	if err != nil {
+		return err
+	}
+	s.server = server
+
+	s.wg.Add(1)
+	go s.browse()
+	return nil
+}
+
+// browse опрашивает локальную сеть циклами по lanBrowseCycle: в начале
+// каждого цикла все участники отвечают заново, что обновляет их свежесть
+func (s *mdnsService) browse() {
+	defer s.wg.Done()
+
+	for s.ctx.Err() == nil {
+		ctx, cancel := context.WithTimeout(s.ctx, lanBrowseCycle)
+		entries := make(chan *zeroconf.ServiceEntry, 1000)
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			for {
+				select {
+				case entry, ok := <-entries:
+					if !ok {
+						return
+					}
+					s.handleEntry(entry)
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+
+		err := zeroconf.Browse(ctx, DISCOVERY_TAG, mdnsDomain, entries)
+		if err != nil && s.ctx.Err() == nil {
+			log.Printf("⚠️ Ошибка поиска в локальной сети: %v", err)
+			<-ctx.Done()
+		}
+		cancel()
+		<-done
+	}
+}
+
+// Close снимает регистрацию и останавливает поиск
+func (s *mdnsService) Close() error {
+	s.cancel()
+	s.mu.Lock()
+	if s.server != nil {
+		s.server.Shutdown()
+	}
+	s.mu.Unlock()
+	s.wg.Wait()
+	return nil
+}
+
+// SetNickname задает имя, которое видят участники локальной сети; пустое
+// имя перестает его показывать. Работающий анонс обновляется сразу
+func (s *mdnsService) SetNickname(nickname string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.nickname = truncateMdnsNickname(nickname)
+	if s.server == nil {
+		return
+	}
+	addrs, err := s.announcedAddrs()
+	if err != nil {
+		log.Printf("⚠️ Не удалось обновить анонс в локальной сети: %v", err)
+		return
+	}
+	s.server.SetText(s.txtRecords(addrs))
+}
+
+// announcedAddrs возвращает локальные адреса узла с /p2p/<PeerID>
+func (s *mdnsService) announcedAddrs() ([]multiaddr.Multiaddr, error) {
+	listen, err := s.host.Network().InterfaceListenAddresses()
+	if err != nil {
+		return nil, err
+	}
+	return peer.AddrInfoToP2pAddrs(&peer.AddrInfo{ID: s.host.ID(), Addrs: listen})
+}
+
+// txtRecords собирает TXT-записи: адреса и, если задано, подписанное имя.
+// Вызывается под s.mu
+func (s *mdnsService) txtRecords(addrs []multiaddr.Multiaddr) []string {
+	var txts []string
+	for _, addr := range addrs {
+		// Адреса через ретрансляторы в локальной сети не нужны
+		if manet.IsThinWaist(addr) {
+			txts = append(txts, mdnsDNSAddrPrefix+addr.String())
+		}
+	}
+	if s.nickname == "" {
+		return txts
+	}
+
+	key := s.host.Peerstore().PrivKey(s.host.ID())
+	if key == nil {
+		return txts
+	}
+	signature, err := key.Sign(mdnsNicknameStatement(s.host.ID(), s.nickname))
+	if err != nil {
+		log.Printf("⚠️ Не удалось подписать имя для локальной сети: %v", err)
+		return txts
+	}
+	return append(txts,
+		mdnsNicknamePrefix+s.nickname,
+		mdnsSignaturePrefix+base64.RawStdEncoding.EncodeToString(signature))
+}
+
+// handleEntry разбирает ответ mDNS и передает найденных участников notifee.
+// Имя принимается, только если подпись сходится с ключом PeerID
+func (s *mdnsService) handleEntry(entry *zeroconf.ServiceEntry) {
+	var addrs []multiaddr.Multiaddr
+	var nickname, signature string
+	for _, txt := range entry.Text {
+		switch {
+		case strings.HasPrefix(txt, mdnsDNSAddrPrefix):
+			if addr, err := multiaddr.NewMultiaddr(txt[len(mdnsDNSAddrPrefix):]); err == nil {
+				addrs = append(addrs, addr)
+			}
+		case strings.HasPrefix(txt, mdnsNicknamePrefix):
+			nickname = txt[len(mdnsNicknamePrefix):]
+		case strings.HasPrefix(txt, mdnsSignaturePrefix):
+			signature = txt[len(mdnsSignaturePrefix):]
+		}
+	}
+
+	infos, err := peer.AddrInfosFromP2pAddrs(addrs...)
+	if err != nil {
+		return
+	}
+	for _, info := range infos {
+		if info.ID == s.host.ID() {
+			continue
+		}
+		found := PeerFound{AddrInfo: info, Source: DiscoverySourceMDNS, Namespace: DISCOVERY_TAG}
+		if nickname != "" && verifyMdnsNickname(info.ID, nickname, signature) {
+			found.Nickname = nickname
+		}
+		go s.notifee.lanSeen(found)
+	}
+}
+
+// mdnsNicknameStatement возвращает подписываемый текст имени
+func mdnsNicknameStatement(id peer.ID, nickname string) []byte {
+	return []byte("owl-whisper mdns nickname\npeer:" + id.String() + "\nnickname:" + nickname + "\n")
+}
+
+// verifyMdnsNickname проверяет подпись имени ключом пира
+func verifyMdnsNickname(id peer.ID, nickname, signature string) bool {
+	sig, err := base64.RawStdEncoding.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+	pub, err := id.ExtractPublicKey()
+	if err != nil {
+		return false
+	}
+	ok, err := pub.Verify(mdnsNicknameStatement(id, nickname), sig)
+	return err == nil && ok
+}
+
+// truncateMdnsNickname обрезает имя по символам так, чтобы оно поместилось в TXT-запись
+func truncateMdnsNickname(nickname string) string {
+	nickname = truncateNickname(nickname)
+	for len(nickname) > mdnsNicknameLimit {
+		runes := []rune(nickname)
+		nickname = string(runes[:len(runes)-1])
+	}
+	return nickname
+}
+
+// mdnsIPs выбирает по одному IPv4 и IPv6 адресу для A и AAAA записей
+func mdnsIPs(addrs []multiaddr.Multiaddr) []string {
+	var ip4, ip6 string
+	for _, addr := range addrs {
+		first, _ := multiaddr.SplitFirst(addr)
+		if first == nil {
+			continue
+		}
+		if ip4 == "" && first.Protocol().Code == multiaddr.P_IP4 {
+			ip4 = first.Value()
+		} else if ip6 == "" && first.Protocol().Code == multiaddr.P_IP6 {
+			ip6 = first.Value()
+		}
+	}
+	var ips []string
+	for _, ip := range []string{ip4, ip6} {
+		if ip != "" {
+			ips = append(ips, ip)
+		}
+	}
+	return ips
+}
+
+// randomInstanceName возвращает случайное имя экземпляра mDNS, чтобы не
+// раскрывать в нем PeerID
+func randomInstanceName(length int) string {
+	const alphabet = "abcdefghijklmnopqrstuvwxyz0123456789"
+	name := make([]byte, length)
+	for i := range name {
+		name[i] = alphabet[rand.Intn(len(alphabet))]
+	}
+	return string(name)
+}
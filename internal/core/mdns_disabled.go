@@ -0,0 +1,10 @@
+//go:build nomdns
+
+package core
+
+import "github.com/libp2p/go-libp2p/core/host"
+
+// newMdnsService - сборка с тегом nomdns: поиск в локальной сети недоступен
+func newMdnsService(host.Host, *DiscoveryNotifee) localDiscovery {
+	return nil
+}
@@ -0,0 +1,240 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/core/protocol"
+	"github.com/libp2p/go-libp2p/p2p/protocol/ping"
+	msmux "github.com/multiformats/go-multistream"
+)
+
+const (
+	// pathProbeInterval - как часто измеряется задержка каждого соединения
+	pathProbeInterval = 30 * time.Second
+	// pathProbeTimeout - предельное время одного измерения
+	pathProbeTimeout = 5 * time.Second
+	// pathStaleAfter - измерение старше этого не используется для выбора пути
+	pathStaleAfter = 3 * pathProbeInterval
+	// multipathLimitedTag - причина использования ограниченного соединения
+	// через ретранслятор для коротких служебных сообщений
+	multipathLimitedTag = "owl-whisper-multipath"
+)
+
+// controlProtocols - короткие служебные протоколы, которые выгодно отправлять
+// по самому быстрому пути. Файлы и потоки данных идут как обычно: лимиты
+// трафика ретрансляторов для них слишком малы
+var controlProtocols = map[protocol.ID]bool{
+	PROTOCOL_ID:          true,
+	PADDED_PROTOCOL_ID:   true,
+	PRESENCE_PROTOCOL_ID: true,
+	STATUS_PROTOCOL_ID:   true,
+}
+
+// PathInfo - одно соединение с пиром и его последнее измерение
+type PathInfo struct {
+	Addr    string        `json:"addr"`
+	Relayed bool          `json:"relayed"`
+	RTT     time.Duration `json:"rtt"`
+	Healthy bool          `json:"healthy"`
+	Checked time.Time     `json:"checked"`
+}
+
+// pathStats - результат измерения соединения
+type pathStats struct {
+	rtt     time.Duration
+	healthy bool
+	checked time.Time
+}
+
+// multipath - измерения всех соединений с пирами, у которых их несколько.
+// Узел не закрывает соединение через ретранслятор после пробивания NAT:
+// оно остается запасным путем, пока его не закроет сам ретранслятор
+type multipath struct {
+	mu      sync.Mutex
+	enabled bool
+	paths   map[network.Conn]pathStats
+}
+
+// runMultipath периодически измеряет задержку соединений с контактами,
+// у которых больше одного пути
+func (n *Node) runMultipath() {
+	ticker := time.NewTicker(pathProbeInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-n.ctx.Done():
+			return
+		case <-ticker.C:
+			n.probePaths()
+		}
+	}
+}
+
+// probePaths измеряет соединения и забывает закрытые
+func (n *Node) probePaths() {
+	live := make(map[network.Conn]bool)
+	var wg sync.WaitGroup
+	for _, id := range n.host.Network().Peers() {
+		conns := n.host.Network().ConnsToPeer(id)
+		if len(conns) < 2 || !n.isKnownContact(id) {
+			continue
+		}
+		for _, conn := range conns {
+			live[conn] = true
+			wg.Add(1)
+			go func(conn network.Conn) {
+				defer wg.Done()
+				rtt, err := n.probeConn(conn)
+				n.multipath.mu.Lock()
+				n.multipath.paths[conn] = pathStats{rtt: rtt, healthy: err == nil, checked: time.Now()}
+				n.multipath.mu.Unlock()
+			}(conn)
+		}
+	}
+	wg.Wait()
+
+	n.multipath.mu.Lock()
+	for conn := range n.multipath.paths {
+		if !live[conn] {
+			delete(n.multipath.paths, conn)
+		}
+	}
+	n.multipath.mu.Unlock()
+}
+
+// probeConn измеряет задержку конкретного соединения протоколом ping
+func (n *Node) probeConn(conn network.Conn) (time.Duration, error) {
+	ctx, cancel := context.WithTimeout(n.ctx, pathProbeTimeout)
+	defer cancel()
+
+	stream, err := n.streamOnConn(ctx, conn, ping.ID)
+	if err != nil {
+		return 0, err
+	}
+	defer stream.Close()
+	stream.SetDeadline(time.Now().Add(pathProbeTimeout))
+
+	payload := make([]byte, ping.PingSize)
+	if _, err := rand.Read(payload); err != nil {
+		return 0, err
+	}
+	started := time.Now()
+	if _, err := stream.Write(payload); err != nil {
+		return 0, err
+	}
+	echo := make([]byte, ping.PingSize)
+	if _, err := io.ReadFull(stream, echo); err != nil {
+		return 0, err
+	}
+	if !bytes.Equal(payload, echo) {
+		return 0, io.ErrUnexpectedEOF
+	}
+	return time.Since(started), nil
+}
+
+// streamOnConn открывает поток протокола pid по конкретному соединению
+func (n *Node) streamOnConn(ctx context.Context, conn network.Conn, pid protocol.ID) (network.Stream, error) {
+	ctx = network.WithAllowLimitedConn(ctx, multipathLimitedTag)
+	stream, err := conn.NewStream(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		stream.SetDeadline(deadline)
+	}
+	if err := msmux.SelectProtoOrFail(pid, stream); err != nil {
+		stream.Reset()
+		return nil, err
+	}
+	stream.SetDeadline(time.Time{})
+	if err := stream.SetProtocol(pid); err != nil {
+		stream.Reset()
+		return nil, err
+	}
+	return stream, nil
+}
+
+// rankedPaths возвращает исправные соединения с пиром от самого быстрого
+func (n *Node) rankedPaths(id peer.ID) []network.Conn {
+	conns := n.host.Network().ConnsToPeer(id)
+	if len(conns) < 2 {
+		return nil
+	}
+
+	n.multipath.mu.Lock()
+	defer n.multipath.mu.Unlock()
+
+	if !n.multipath.enabled {
+		return nil
+	}
+	var ranked []network.Conn
+	for _, conn := range conns {
+		stats, ok := n.multipath.paths[conn]
+		if ok && stats.healthy && time.Since(stats.checked) < pathStaleAfter {
+			ranked = append(ranked, conn)
+		}
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		return n.multipath.paths[ranked[i]].rtt < n.multipath.paths[ranked[j]].rtt
+	})
+	return ranked
+}
+
+// multipathStream открывает поток служебного протокола по самому быстрому
+// исправному соединению, при неудаче переходя к следующему. false - выбирать
+// не из чего, поток открывается как обычно
+func (n *Node) multipathStream(ctx context.Context, id peer.ID, pid protocol.ID) (network.Stream, bool) {
+	if !controlProtocols[pid] {
+		return nil, false
+	}
+	for _, conn := range n.rankedPaths(id) {
+		stream, err := n.openOnPath(ctx, conn, pid)
+		if err == nil {
+			return stream, true
+		}
+		n.multipath.mu.Lock()
+		n.multipath.paths[conn] = pathStats{checked: time.Now()}
+		n.multipath.mu.Unlock()
+	}
+	return nil, false
+}
+
+// openOnPath открывает поток протокола по соединению с учетом скрытого режима пира
+func (n *Node) openOnPath(ctx context.Context, conn network.Conn, pid protocol.ID) (network.Stream, error) {
+	if !n.peerIsStealth(conn.RemotePeer()) {
+		return n.streamOnConn(ctx, conn, pid)
+	}
+	stream, err := n.streamOnConn(ctx, conn, STEALTH_PROTOCOL_ID)
+	if err != nil {
+		return nil, err
+	}
+	return stealthHandshake(stream, pid)
+}
+
+// Paths возвращает соединения с пиром и их последние измерения
+func (n *Node) Paths(id peer.ID) []PathInfo {
+	n.multipath.mu.Lock()
+	defer n.multipath.mu.Unlock()
+
+	var paths []PathInfo
+	for _, conn := range n.host.Network().ConnsToPeer(id) {
+		stats := n.multipath.paths[conn]
+		paths = append(paths, PathInfo{
+			Addr:    conn.RemoteMultiaddr().String(),
+			Relayed: conn.Stat().Limited || isRelayAddr(conn.RemoteMultiaddr()),
+			RTT:     stats.rtt,
+			Healthy: stats.healthy,
+			Checked: stats.checked,
+		})
+	}
+	return paths
+}
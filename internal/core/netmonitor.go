@@ -0,0 +1,238 @@
+package core
+
+import (
+	"context"
+	"log"
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/p2p/net/swarm"
+	"github.com/libp2p/go-libp2p/p2p/protocol/ping"
+)
+
+const (
+	// netPollInterval - как часто сверяются сетевые интерфейсы
+	netPollInterval = 5 * time.Second
+	// netSettleDelay - пауза после смены сети, чтобы старые соединения успели
+	// отвалиться, а новые адреса - появиться
+	netSettleDelay = 2 * time.Second
+	// reconnectTimeout - предельное время переподключения к одному пиру
+	reconnectTimeout = 30 * time.Second
+	// resumeThreshold - насколько настенные часы должны убежать вперед
+	// между опросами, чтобы считать, что система просыпалась из сна
+	resumeThreshold = 30 * time.Second
+	// probeTimeout - сколько ждать ответа на ping после пробуждения
+	probeTimeout = 5 * time.Second
+)
+
+// NetworkChanged - полезная нагрузка события EventNetworkChanged
+type NetworkChanged struct {
+	Added   []string `json:"added"`   // появившиеся адреса интерфейсов
+	Removed []string `json:"removed"` // пропавшие адреса интерфейсов
+	// Resumed - система проснулась после сна; соединения могли умереть,
+	// даже если адреса не изменились
+	Resumed bool `json:"resumed"`
+}
+
+// Resumed - полезная нагрузка события EventResumed
+type Resumed struct {
+	Slept time.Duration `json:"slept"` // примерная длительность сна
+	// DeadConnections - сколько соединений не ответило на ping и было закрыто
+	DeadConnections int `json:"dead_connections"`
+}
+
+// NetworkChangeHandler вызывается после смены сети, например чтобы
+// встраивающее приложение перезапустило обнаружение
+type NetworkChangeHandler func(change NetworkChanged)
+
+// SetNetworkChangeHandler задает обработчик смены сети
+func (n *Node) SetNetworkChangeHandler(handler NetworkChangeHandler) {
+	n.netMu.Lock()
+	n.onNetworkChange = handler
+	n.netMu.Unlock()
+}
+
+// watchNetwork опрашивает сетевые интерфейсы и реагирует на смену сети
+// (Wi-Fi, Ethernet, мобильная сеть, новый адрес). Опрос работает на всех
+// платформах без подписки на уведомления ОС
+func (n *Node) watchNetwork() {
+	ticker := time.NewTicker(netPollInterval)
+	defer ticker.Stop()
+
+	current := interfaceAddrs()
+	// Монотонные часы во сне стоят, настенные - нет: их скачок выдает пробуждение
+	lastTick := time.Now().Round(0)
+	for {
+		select {
+		case <-n.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		now := time.Now().Round(0)
+		slept := now.Sub(lastTick) - netPollInterval
+		lastTick = now
+		if slept > resumeThreshold {
+			n.handleResume(slept)
+			current = interfaceAddrs()
+			continue
+		}
+
+		next := interfaceAddrs()
+		added, removed := diffAddrs(current, next)
+		if len(added) == 0 && len(removed) == 0 {
+			continue
+		}
+		current = next
+
+		change := NetworkChanged{Added: added, Removed: removed}
+		log.Printf("🔄 Сеть изменилась: +%v -%v", added, removed)
+		n.emit(EventNetworkChanged, change)
+		n.recoverNetwork(change)
+	}
+}
+
+// recoverNetwork восстанавливает работу узла после смены сети
+func (n *Node) recoverNetwork(change NetworkChanged) {
+	important := n.importantPeers()
+
+	select {
+	case <-time.After(netSettleDelay):
+	case <-n.ctx.Done():
+		return
+	}
+	n.restoreConnectivity(change, important)
+}
+
+// restoreConnectivity обновляет резервирования на ретрансляторах, перезапускает
+// обнаружение и переподключает важных пиров
+func (n *Node) restoreConnectivity(change NetworkChanged, important []peer.ID) {
+	n.relays.wake()
+	go n.RetrieveMailbox()
+
+	n.netMu.Lock()
+	handler := n.onNetworkChange
+	n.netMu.Unlock()
+	if handler != nil {
+		handler(change)
+	}
+
+	for _, id := range important {
+		if n.host.Network().Connectedness(id) == network.Connected {
+			continue
+		}
+		go n.reconnect(id)
+	}
+}
+
+// handleResume проверяет соединения после сна: не ответившие на ping
+// закрываются сразу, а не через минуты по таймаутам транспорта
+func (n *Node) handleResume(slept time.Duration) {
+	log.Printf("☀️ Пробуждение после сна (~%s), проверяем соединения", slept.Round(time.Second))
+	important := n.importantPeers()
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	dead := 0
+	for _, conn := range n.host.Network().Conns() {
+		wg.Add(1)
+		go func(conn network.Conn) {
+			defer wg.Done()
+			if n.probe(conn.RemotePeer()) {
+				return
+			}
+			conn.Close()
+			mu.Lock()
+			dead++
+			mu.Unlock()
+		}(conn)
+	}
+	wg.Wait()
+
+	n.emit(EventResumed, Resumed{Slept: slept, DeadConnections: dead})
+	n.restoreConnectivity(NetworkChanged{Resumed: true}, important)
+}
+
+// probe проверяет пира ping-запросом
+func (n *Node) probe(id peer.ID) bool {
+	ctx, cancel := context.WithTimeout(n.ctx, probeTimeout)
+	defer cancel()
+
+	result, ok := <-ping.Ping(ctx, n.host, id)
+	return ok && result.Error == nil
+}
+
+// importantPeers - подключенные пиры, связь с которыми нужно восстановить
+// в первую очередь: контакты, помеченные и защищенные от закрытия
+// (ретрансляторы). Пиры с большим числом меток идут первыми
+func (n *Node) importantPeers() []peer.ID {
+	var peers []peer.ID
+	for _, id := range n.host.Network().Peers() {
+		if n.isKnownContact(id) || n.tagCount(id) > 0 || n.host.ConnManager().IsProtected(id, "") {
+			peers = append(peers, id)
+		}
+	}
+	sort.SliceStable(peers, func(i, j int) bool { return n.tagCount(peers[i]) > n.tagCount(peers[j]) })
+	return peers
+}
+
+// reconnect переподключается к пиру по известным адресам
+func (n *Node) reconnect(id peer.ID) {
+	ctx, cancel := context.WithTimeout(n.ctx, reconnectTimeout)
+	defer cancel()
+
+	// Неудачи в старой сети не должны откладывать попытку в новой
+	if sw, ok := n.host.Network().(*swarm.Swarm); ok {
+		sw.Backoff().Clear(id)
+	}
+	err := n.host.Connect(ctx, n.host.Peerstore().PeerInfo(id))
+	n.reconnects.attempt(id, err)
+	if err != nil {
+		log.Printf("⚠️ Не удалось переподключиться к %s: %v", id.ShortString(), err)
+		return
+	}
+	log.Printf("🔗 Переподключились к %s", id.ShortString())
+}
+
+// interfaceAddrs возвращает адреса поднятых интерфейсов, кроме loopback
+func interfaceAddrs() map[string]bool {
+	addrs := make(map[string]bool)
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return addrs
+	}
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+		ifaceAddrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, addr := range ifaceAddrs {
+			addrs[iface.Name+" "+addr.String()] = true
+		}
+	}
+	return addrs
+}
+
+// diffAddrs возвращает появившиеся и пропавшие адреса
+func diffAddrs(before, after map[string]bool) (added, removed []string) {
+	for addr := range after {
+		if !before[addr] {
+			added = append(added, addr)
+		}
+	}
+	for addr := range before {
+		if !after[addr] {
+			removed = append(removed, addr)
+		}
+	}
+	sort.Strings(added)
+	sort.Strings(removed)
+	return added, removed
+}
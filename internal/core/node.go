@@ -3,10 +3,16 @@ package core
 import (
 	"bufio"
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/libp2p/go-libp2p"
+	"github.com/libp2p/go-libp2p/core/connmgr"
 	"github.com/libp2p/go-libp2p/core/host"
 	"github.com/libp2p/go-libp2p/core/network"
 	"github.com/libp2p/go-libp2p/core/peer"
@@ -17,7 +23,10 @@ import (
 const PROTOCOL_ID = "/owl-whisper/1.0.0"
 
 // NetworkEventLogger логирует события сети для мониторинга
-type NetworkEventLogger struct{}
+// и публикует события подключения пиров
+type NetworkEventLogger struct {
+	node *Node
+}
 
 // Listen вызывается при запуске сети
 func (nel *NetworkEventLogger) Listen(network.Network, multiaddr.Multiaddr) {}
@@ -28,11 +37,25 @@ func (nel *NetworkEventLogger) ListenClose(network.Network, multiaddr.Multiaddr)
 // Connected вызывается при успешном соединении
 func (nel *NetworkEventLogger) Connected(net network.Network, conn network.Conn) {
 	log.Printf("🔗 EVENT: Успешное соединение с %s", conn.RemotePeer().ShortString())
+	if nel.node != nil {
+		nel.node.reconnects.connected(conn.RemotePeer())
+		nel.node.rememberConnected(conn.RemotePeer(), conn.RemoteMultiaddr())
+		if len(net.ConnsToPeer(conn.RemotePeer())) == 1 {
+			go nel.node.fetchStatus(conn.RemotePeer())
+			go nel.node.announceOwnTransition(conn.RemotePeer())
+			go nel.node.fetchProfileRecord(conn.RemotePeer())
+			go nel.node.deliverMailbox(conn.RemotePeer())
+		}
+		nel.node.emit(EventPeerConnected, PeerEvent{PeerID: conn.RemotePeer(), Addr: conn.RemoteMultiaddr().String()})
+	}
 }
 
 // Disconnected вызывается при разрыве соединения
 func (nel *NetworkEventLogger) Disconnected(net network.Network, conn network.Conn) {
 	log.Printf("🔌 EVENT: Соединение с %s разорвано", conn.RemotePeer().ShortString())
+	if nel.node != nil {
+		nel.node.emit(EventPeerDisconnected, PeerEvent{PeerID: conn.RemotePeer(), Addr: conn.RemoteMultiaddr().String()})
+	}
 }
 
 // OpenedStream вызывается при открытии потока
@@ -41,49 +64,288 @@ func (nel *NetworkEventLogger) OpenedStream(net network.Network, stream network.
 // ClosedStream вызывается при закрытии потока
 func (nel *NetworkEventLogger) ClosedStream(net network.Network, stream network.Stream) {}
 
+// MessageHandler вызывается для каждого входящего сообщения
+type MessageHandler func(peerID peer.ID, message []byte)
+
 // Node представляет собой libp2p узел
 type Node struct {
-	host host.Host
-	ctx  context.Context
+	host   host.Host
+	ctx    context.Context
+	cancel context.CancelFunc
+	config NodeConfig
+
+	handlerMu sync.RWMutex
+	handler   MessageHandler
+
+	events    chan Event
+	dropped   atomic.Uint64
+	security  securityEvents
+	gater     *connGater
+	streams   *streamRegistry
+	transfers *TransferScheduler
+	offers    pendingOffers
+
+	policyMu sync.RWMutex
+	policy   TransferPolicy
+
+	leakMu       sync.Mutex
+	leakDetector *leakDetector
+
+	outboxMu sync.Mutex
+	outbox   *outbox
+
+	contactRequests *contactRequestGuard
+
+	relays *relayManager
+
+	screens     screenShares
+	attachments attachmentRequests
+
+	capabilities capabilityCache
+	tags         peerTags
+	metadata     contactMetadata
+	activity     connmgr.DecayingTag
+
+	netMu           sync.Mutex
+	onNetworkChange NetworkChangeHandler
+	reconnects      reconnectTracker
+	peerCache       peerCache
+	introductions   introductions
+	contactCards    contactCards
+	statuses        peerStatuses
+	attestations    attestations
+	keyTransitions  keyTransitions
+	admin           adminAccess
+	profiles        profileRecords
+	mailbox         mailboxService
+	mailboxDedup    mailboxDedup
+	sealed          sealedSender
+	shaping         trafficShaping
+	stealth         stealthProtocols
+	prewarm         prewarming
+	multipath       multipath
+
+	transportPolicy *transportPolicies
+
+	presenceMu     sync.RWMutex
+	lastSeenPolicy LastSeenPolicy
+	isContact      func(peer.ID) bool
+	lastActive     time.Time
 }
 
-// NewNode создает новый libp2p узел
-func NewNode(ctx context.Context) (*Node, error) {
+// NewNode создает новый libp2p узел. Дополнительные опции libp2p
+// применяются поверх стандартных (например, для тестовых узлов)
+func NewNode(ctx context.Context, config NodeConfig, extraOpts ...libp2p.Option) (*Node, error) {
 	// Создаем новый узел libp2p с опциями для глобальной сети
-	opts := []libp2p.Option{
+	var opts []libp2p.Option
+
+	if config.HideIP {
+		if !config.EnableRelay || !relaySupported {
+			return nil, fmt.Errorf("режим скрытия IP требует включенной ретрансляции")
+		}
+		// Ни прямых адресов, ни пробивания NAT: собеседники видят только
+		// ретранслятор. Служебные пиры DHT набираются напрямую
+		config.EnableNAT = false
+		config.EnableHolePunching = false
+		config.TransportPolicy.Relay = RelayAlways
+	}
+	if err := config.ContactRequests.Validate(); err != nil {
+		return nil, err
+	}
+	if config.RelayService && (!config.EnableRelay || !relaySupported || config.HideIP) {
+		return nil, fmt.Errorf("служба ретрансляции требует включенной ретрансляции и несовместима со скрытием IP")
+	}
+
+	if config.PrivateKey != nil {
+		opts = append(opts, libp2p.Identity(config.PrivateKey))
+	}
+
+	opts = append(opts, transportOptions()...)
+
+	// Версия клиента сообщается пирам через identify. В скрытом режиме
+	// вместо нее нейтральная строка, чтобы узел не выделялся среди других
+	userAgent := config.UserAgent
+	if userAgent == "" {
+		userAgent = "OwlWhisper/" + Version
+		if config.Stealth {
+			userAgent = stealthUserAgent
+		}
+	}
+	opts = append(opts, libp2p.UserAgent(userAgent))
+
+	if config.ListenPort > 0 {
+		opts = append(opts, libp2p.ListenAddrStrings(
+			fmt.Sprintf("/ip4/0.0.0.0/tcp/%d", config.ListenPort),
+			fmt.Sprintf("/ip4/0.0.0.0/udp/%d/quic-v1", config.ListenPort),
+			fmt.Sprintf("/ip6/::/tcp/%d", config.ListenPort),
+			fmt.Sprintf("/ip6/::/udp/%d/quic-v1", config.ListenPort),
+		))
+	}
+
+	if config.WebSocketPort > 0 {
+		if !websocketSupported {
+			return nil, fmt.Errorf("порт WebSocket задан, но сборка без WebSocket (тег nows)")
+		}
+		listen := []string{
+			fmt.Sprintf("/ip4/0.0.0.0/tcp/%d/ws", config.WebSocketPort),
+			fmt.Sprintf("/ip6/::/tcp/%d/ws", config.WebSocketPort),
+		}
+		// Явный адрес отключает адреса libp2p по умолчанию, возвращаем их
+		if config.ListenPort == 0 {
+			listen = append(listen,
+				"/ip4/0.0.0.0/tcp/0", "/ip4/0.0.0.0/udp/0/quic-v1",
+				"/ip6/::/tcp/0", "/ip6/::/udp/0/quic-v1")
+		}
+		opts = append(opts, libp2p.ListenAddrStrings(listen...))
+	}
+
+	if config.EnableNAT {
 		// Включаем встроенный сервис для автоматического определения
 		// внешнего IP и работы с NAT (использует STUN)
-		libp2p.EnableNATService(),
+		opts = append(opts, libp2p.EnableNATService())
+	}
 
+	if config.EnableHolePunching && relaySupported {
 		// Включаем "пробивание дыр" в NAT. Это и есть hole punching
-		libp2p.EnableHolePunching(),
+		opts = append(opts, libp2p.EnableHolePunching())
+	}
 
-		// Включаем поддержку Relay V2. Это наш fallback.
-		// Опция listen говорит, что наш узел может сам выступать
-		// ретранслятором для других (помогает сети)
-		libp2p.EnableRelay(),
+	if config.EnableRelay && relaySupported {
+		// Включаем поддержку Relay V2. Это наш fallback
+		opts = append(opts, libp2p.EnableRelay())
+		if config.RelayService {
+			// Сами ретранслируем соединения других участников; libp2p
+			// включает службу, когда узел достижим из внешней сети
+			opts = append(opts, libp2p.EnableRelayService())
+		}
+	} else {
+		opts = append(opts, libp2p.DisableRelay())
 	}
+	// Адреса через ретрансляторы с резервированием анонсируем вместе со своими,
+	// а в режиме скрытия IP - вместо своих
+	relays := newRelayManager(config.RelayNodes, config.PinnedRelays)
+	if config.EnableRelay && relaySupported {
+		opts = append(opts, libp2p.AddrsFactory(func(addrs []multiaddr.Multiaddr) []multiaddr.Multiaddr {
+			if config.HideIP {
+				return relays.circuitAddrs()
+			}
+			return append(addrs, relays.circuitAddrs()...)
+		}))
+	}
+	// Ограничиваем частоту входящих соединений с одного адреса и подсети
+	gater := newConnGater(config.ConnectionLimits)
+	opts = append(opts, libp2p.ConnectionGater(gater))
+
+	// Политика транспорта: порядок набора адресов и запрет прямых или
+	// ретранслируемых соединений с отдельными пирами
+	policies := newTransportPolicies(config.TransportPolicy)
+	policies.hideIP = config.HideIP
+	// С самими ретрансляторами узел соединяется напрямую при любой политике,
+	// иначе резервирование не сделать
+	for _, info := range append(config.RelayNodes, config.PinnedRelays...) {
+		policies.exempt(info.ID)
+	}
+	gater.policies = policies
+	opts = append(opts, libp2p.DialRanker(policies.rank))
+	opts = append(opts, extraOpts...)
 
 	h, err := libp2p.New(opts...)
 	if err != nil {
 		return nil, fmt.Errorf("не удалось создать узел libp2p: %w", err)
 	}
 
+	ctx, cancel := context.WithCancel(ctx)
 	node := &Node{
-		host: h,
-		ctx:  ctx,
+		host:   h,
+		ctx:    ctx,
+		cancel: cancel,
+		config: config,
+		events: make(chan Event, eventBufferSize),
+		security: securityEvents{
+			ch: make(chan SecurityEvent, securityBufferSize),
+		},
+		streams: newStreamRegistry(),
+		gater:   gater,
+		relays:  relays,
+		policy:  config.Transfers,
+
+		transportPolicy: policies,
+
+		contactRequests: newContactRequestGuard(config.ContactRequests),
+
+		lastSeenPolicy: config.LastSeenPolicy,
+		lastActive:     time.Now(),
+
+		statuses: peerStatuses{own: StatusMessage{
+			PeerID:    h.ID(),
+			Text:      config.StatusMessage,
+			UpdatedAt: time.Now(),
+		}},
+
+		capabilities: capabilityCache{peers: make(map[peer.ID]PeerCapabilities)},
+	}
+	relays.attach(node)
+	policies.mu.Lock()
+	policies.isContact = node.isKnownContact
+	policies.mu.Unlock()
+	node.transfers = newTransferScheduler(ctx, node, config.MaxConcurrentTransfers)
+	gater.onReject = func(addr string) {
+		node.emitSecurity(SecurityBlockedDial, SeverityWarning, "", addr, "превышен лимит входящих соединений")
+	}
+
+	// Устанавливаем обработчик для нашего протокола. В скрытом режиме
+	// протоколы OwlWhisper доступны только через рукопожатие
+	node.stealth.enabled = config.Stealth
+	node.multipath.enabled = config.Multipath
+	node.peerCache.capacity = config.PeerCacheSize
+	node.peerCache.keep = node.isKnownContact
+	node.multipath.paths = make(map[network.Conn]pathStats)
+	if config.Stealth {
+		h.SetStreamHandler(STEALTH_PROTOCOL_ID, node.handleStealthStream)
+		log.Println("🥷 Скрытый режим: протоколы OwlWhisper не объявляются через identify")
 	}
+	node.setStreamHandler(PROTOCOL_ID, node.handleStream)
+	node.setStreamHandler(STREAM_PROTOCOL_ID, node.handleDataStream)
+	node.setStreamHandler(FILE_PROTOCOL_ID, node.handleFileStream)
+	node.setStreamHandler(PRESENCE_PROTOCOL_ID, node.handlePresenceStream)
+	node.setStreamHandler(CONTACT_PROTOCOL_ID, node.handleContactStream)
+	node.setStreamHandler(SCREEN_PROTOCOL_ID, node.handleScreenStream)
+	node.setStreamHandler(ATTACHMENT_PROTOCOL_ID, node.handleAttachmentStream)
+	node.setStreamHandler(PEER_LOOKUP_PROTOCOL_ID, node.handlePeerLookupStream)
+	node.setStreamHandler(INTRODUCE_PROTOCOL_ID, node.handleIntroductionStream)
+	node.setStreamHandler(CONTACT_CARD_PROTOCOL_ID, node.handleContactCardStream)
+	node.setStreamHandler(STATUS_PROTOCOL_ID, node.handleStatusStream)
+	node.setStreamHandler(ATTESTATION_PROTOCOL_ID, node.handleAttestationStream)
+	node.setStreamHandler(KEY_TRANSITION_PROTOCOL_ID, node.handleKeyTransitionStream)
+	node.setStreamHandler(IDENTITY_DESTROYED_PROTOCOL_ID, node.handleIdentityDestroyedStream)
+	node.setStreamHandler(PADDED_PROTOCOL_ID, node.handlePaddedStream)
+	node.setStreamHandler(ADMIN_PROTOCOL_ID, node.handleAdminStream)
+	node.setStreamHandler(PROFILE_PROTOCOL_ID, node.handleProfileStream)
+	node.setStreamHandler(MAILBOX_PROTOCOL_ID, node.handleMailboxStream)
+	node.setStreamHandler(MAILBOX_DELIVERY_PROTOCOL_ID, node.handleMailboxDeliveryStream)
+	node.setStreamHandler(MAILBOX_FETCH_PROTOCOL_ID, node.handleMailboxFetchStream)
+	node.setStreamHandler(SEALED_PROTOCOL_ID, node.handleSealedStream)
 
-	// Устанавливаем обработчик для нашего протокола
-	h.SetStreamHandler(PROTOCOL_ID, node.handleStream)
+	// Активное общение защищает соединение от закрытия менеджером соединений
+	node.startActivityScoring()
+
+	// Возможности пиров узнаем из их ответа identify
+	if err := node.watchCapabilities(); err != nil {
+		h.Close()
+		cancel()
+		return nil, err
+	}
 
 	// Устанавливаем Network Notifiee для мониторинга событий сети
-	h.Network().Notify(&NetworkEventLogger{})
+	h.Network().Notify(&NetworkEventLogger{node: node})
 
 	log.Printf("✅ Узел создан. Ваш PeerID: %s", h.ID().String())
 	log.Println("Адреса для прослушивания:")
 	for _, addr := range h.Addrs() {
-		fmt.Printf("  %s/p2p/%s\n", addr, h.ID().String())
+		log.Printf("  %s/p2p/%s", addr, h.ID().String())
+	}
+	if config.HideIP {
+		log.Println("🕶️ Режим скрытия IP: соединения с собеседниками только через ретрансляторы")
 	}
 
 	return node, nil
@@ -91,12 +353,25 @@ func NewNode(ctx context.Context) (*Node, error) {
 
 // Start запускает узел
 func (n *Node) Start() error {
+	if n.config.EnableRelay && relaySupported {
+		go n.relays.run(n.ctx)
+	}
+	go n.watchNetwork()
+	go n.RetrieveMailbox()
+	if n.config.Multipath {
+		go n.runMultipath()
+	}
 	log.Println("🚀 Узел запущен")
 	return nil
 }
 
 // Close останавливает узел
 func (n *Node) Close() error {
+	n.StopLeakDetector()
+	n.StopOutbox()
+	n.closeSealedRoutes()
+	n.cancel()
+	n.streams.closeAll()
 	return n.host.Close()
 }
 
@@ -110,11 +385,54 @@ func (n *Node) GetPeers() []peer.ID {
 	return n.host.Network().Peers()
 }
 
+// SetMessageHandler устанавливает обработчик входящих сообщений.
+// Если обработчик не задан, сообщения публикуются событием EventMessageReceived.
+func (n *Node) SetMessageHandler(handler MessageHandler) {
+	n.handlerMu.Lock()
+	n.handler = handler
+	n.handlerMu.Unlock()
+}
+
 // SendMessage отправляет сообщение конкретному пиру
 func (n *Node) SendMessage(peerID peer.ID, message string) error {
-	// Открываем новый поток для каждого сообщения
-	stream, err := n.host.NewStream(n.ctx, peerID, PROTOCOL_ID)
+	// Скрытый отправитель не переходит на обычную отправку, только в
+	// почтовый ящик, где отправитель тоже скрыт
+	if n.SealedSender(peerID) {
+		err := n.sendSealed(peerID, message)
+		if err != nil {
+			if depositErr := n.depositMessage(peerID, message); depositErr != nil {
+				return fmt.Errorf("скрытая отправка к %s не удалась: %w", peerID.ShortString(), err)
+			}
+			log.Printf("📬 Вам -> %s (в почтовый ящик): %s", peerID.ShortString(), message)
+		} else {
+			log.Printf("🕶️ Вам -> %s: %s", peerID.ShortString(), message)
+		}
+		n.MarkActive()
+		n.bumpActivity(peerID, activityMessage)
+		return nil
+	}
+	if n.TrafficShaping(peerID).Padding {
+		err := n.sendPadded(peerID, paddedFrameMessage, []byte(message))
+		if err == nil {
+			log.Printf("📤 Вам -> %s: %s", peerID.ShortString(), message)
+			n.MarkActive()
+			n.bumpActivity(peerID, activityMessage)
+			return nil
+		}
+		log.Printf("⚠️ Дополненная отправка к %s не удалась, отправляем обычным протоколом: %v", peerID.ShortString(), err)
+	}
+
+	// Открываем новый поток для каждого сообщения. Если контакт не в сети,
+	// оставляем письмо в его почтовом ящике
+	stream, err := n.newStream(n.ctx, peerID, PROTOCOL_ID)
 	if err != nil {
+		if depositErr := n.depositMessage(peerID, message); depositErr == nil {
+			log.Printf("📬 Вам -> %s (в почтовый ящик): %s", peerID.ShortString(), message)
+			n.MarkActive()
+			return nil
+		} else if !errors.Is(depositErr, ErrNoMailbox) {
+			log.Printf("⚠️ Не удалось оставить письмо %s: %v", peerID.ShortString(), depositErr)
+		}
 		return fmt.Errorf("не удалось открыть поток к %s: %w", peerID.ShortString(), err)
 	}
 	defer stream.Close()
@@ -126,6 +444,8 @@ func (n *Node) SendMessage(peerID peer.ID, message string) error {
 	}
 
 	log.Printf("📤 Вам -> %s: %s", peerID.ShortString(), message)
+	n.MarkActive()
+	n.bumpActivity(peerID, activityMessage)
 	return nil
 }
 
@@ -159,7 +479,30 @@ func (n *Node) handleStream(stream network.Stream) {
 			stream.Close()
 			return
 		}
-		// Выводим полученное сообщение
-		fmt.Printf("📥 От %s: %s", remotePeer.ShortString(), str)
+		if !n.deliverMessage(remotePeer, strings.TrimSuffix(str, "\n")) {
+			stream.Reset()
+			return
+		}
+	}
+}
+
+// deliverMessage передает входящее сообщение обработчику или публикует событием.
+// Возвращает false, если событие не удалось опубликовать
+func (n *Node) deliverMessage(remotePeer peer.ID, text string) bool {
+	n.mailboxDedup.direct(remotePeer, text)
+	return n.dispatchMessage(MessageEvent{PeerID: remotePeer, Text: text, Delivery: DeliveryDirect})
+}
+
+// dispatchMessage передает сообщение обработчику или публикует событием
+func (n *Node) dispatchMessage(message MessageEvent) bool {
+	n.bumpActivity(message.PeerID, activityMessage)
+	n.handlerMu.RLock()
+	handler := n.handler
+	n.handlerMu.RUnlock()
+
+	if handler != nil {
+		handler(message.PeerID, []byte(message.Text))
+		return true
 	}
+	return n.emitBlocking(EventMessageReceived, message)
 }
@@ -0,0 +1,66 @@
+package core
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/multiformats/go-multiaddr"
+)
+
+// defaultObfuscationPort - порт HTTPS, на котором соединение не выделяется
+const defaultObfuscationPort = 443
+
+// Obfuscation - маскировка соединения с ретранслятором или bootstrap-узлом
+// под обычный HTTPS: WebSocket поверх TLS вместо узнаваемых DPI libp2p/QUIC.
+// На стороне узла WebSocket обычно принимает обратный прокси с TLS (:443)
+type Obfuscation struct {
+	// Host - домен или IP, к которому идет подключение (например, фронт CDN)
+	Host string `json:"host"`
+	// Port - порт TLS; 0 - 443
+	Port int `json:"port,omitempty"`
+	// SNI - имя в TLS SNI и заголовке Host; пусто - совпадает с Host.
+	// Позволяет указать имя, разрешенное в сети, при подключении к фронту
+	SNI string `json:"sni,omitempty"`
+}
+
+// Addr возвращает адрес вида /dns/<host>/tcp/443/tls/sni/<sni>/ws
+func (o Obfuscation) Addr() (multiaddr.Multiaddr, error) {
+	if !websocketSupported {
+		return nil, fmt.Errorf("маскировка недоступна: сборка без WebSocket (тег nows)")
+	}
+	if o.Host == "" {
+		return nil, fmt.Errorf("не задан адрес для маскировки")
+	}
+	port := o.Port
+	if port == 0 {
+		port = defaultObfuscationPort
+	}
+	if port < 0 || port > 65535 {
+		return nil, fmt.Errorf("некорректный порт маскировки: %d", port)
+	}
+	hostPart := "/dns/" + o.Host
+	if ip := net.ParseIP(o.Host); ip != nil {
+		hostPart = "/ip4/" + o.Host
+		if ip.To4() == nil {
+			hostPart = "/ip6/" + o.Host
+		}
+	}
+	// SNI не может быть IP-адресом: без явного имени оно берется из домена
+	tlsPart := "/tls"
+	if sni := o.SNI; sni != "" {
+		tlsPart += "/sni/" + sni
+	}
+	return multiaddr.NewMultiaddr(hostPart + "/tcp/" + strconv.Itoa(port) + tlsPart + "/ws")
+}
+
+// ObfuscateEndpoint заменяет адреса конечной точки замаскированным. Прочие
+// адреса отбрасываются: попытка соединиться по ним выдала бы libp2p для DPI
+func ObfuscateEndpoint(info peer.AddrInfo, o Obfuscation) (peer.AddrInfo, error) {
+	addr, err := o.Addr()
+	if err != nil {
+		return info, err
+	}
+	return peer.AddrInfo{ID: info.ID, Addrs: []multiaddr.Multiaddr{addr}}, nil
+}
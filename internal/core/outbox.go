@@ -0,0 +1,194 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"OwlWhisper/pkg/interfaces"
+
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+// outboxInterval - как часто проверять наступление времени отправки
+const outboxInterval = time.Second
+
+// ScheduledDispatch - полезная нагрузка события EventScheduledMessage
+type ScheduledDispatch struct {
+	ID       string    `json:"id"`
+	PeerID   peer.ID   `json:"peer_id"`
+	Text     string    `json:"text"`
+	SendAt   time.Time `json:"send_at"`
+	Reminder bool      `json:"reminder"`
+	Error    string    `json:"error,omitempty"`
+}
+
+// outbox доставляет отложенные сообщения, когда наступило время и пир в сети
+type outbox struct {
+	node   *Node
+	repo   interfaces.IOutboxRepository
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	// failed - сообщения, о неудачной отправке которых уже сообщили
+	failed map[string]bool
+}
+
+// StartOutbox запускает доставку отложенных сообщений из repo.
+// Сообщения, время которых прошло, пока приложение было закрыто, уходят сразу
+func (n *Node) StartOutbox(repo interfaces.IOutboxRepository) {
+	n.StopOutbox()
+
+	ctx, cancel := context.WithCancel(n.ctx)
+	o := &outbox{
+		node:   n,
+		repo:   repo,
+		cancel: cancel,
+		failed: make(map[string]bool),
+	}
+
+	n.outboxMu.Lock()
+	n.outbox = o
+	n.outboxMu.Unlock()
+
+	o.wg.Add(1)
+	go o.run(ctx)
+}
+
+// StopOutbox останавливает доставку отложенных сообщений
+func (n *Node) StopOutbox() {
+	n.outboxMu.Lock()
+	o := n.outbox
+	n.outbox = nil
+	n.outboxMu.Unlock()
+
+	if o != nil {
+		o.cancel()
+		o.wg.Wait()
+	}
+}
+
+// ScheduleMessage ставит сообщение пиру в очередь на время sendAt и возвращает
+// его ID. Сообщение самому себе становится напоминанием
+func (n *Node) ScheduleMessage(peerID peer.ID, text string, sendAt time.Time) (string, error) {
+	repo, err := n.outboxRepo()
+	if err != nil {
+		return "", err
+	}
+
+	msg := &interfaces.ScheduledMessage{
+		ToPeer:    peerID.String(),
+		Content:   text,
+		SendAt:    sendAt,
+		CreatedAt: time.Now(),
+	}
+	if err := repo.SaveScheduled(n.ctx, msg); err != nil {
+		return "", err
+	}
+	return msg.ID, nil
+}
+
+// CancelScheduledMessage отменяет еще не отправленное сообщение
+func (n *Node) CancelScheduledMessage(id string) error {
+	repo, err := n.outboxRepo()
+	if err != nil {
+		return err
+	}
+	return repo.DeleteScheduled(n.ctx, id)
+}
+
+// ScheduledMessages возвращает ожидающие отправки сообщения
+func (n *Node) ScheduledMessages() ([]*interfaces.ScheduledMessage, error) {
+	repo, err := n.outboxRepo()
+	if err != nil {
+		return nil, err
+	}
+	return repo.GetScheduled(n.ctx)
+}
+
+// outboxRepo возвращает хранилище запущенной очереди
+func (n *Node) outboxRepo() (interfaces.IOutboxRepository, error) {
+	n.outboxMu.Lock()
+	defer n.outboxMu.Unlock()
+
+	if n.outbox == nil {
+		return nil, fmt.Errorf("очередь отложенных сообщений не запущена")
+	}
+	return n.outbox.repo, nil
+}
+
+// run проверяет очередь с заданным интервалом до отмены контекста
+func (o *outbox) run(ctx context.Context) {
+	defer o.wg.Done()
+
+	ticker := time.NewTicker(outboxInterval)
+	defer ticker.Stop()
+
+	for {
+		o.dispatchDue(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// dispatchDue отправляет сообщения, время которых наступило. Сообщения
+// пирам не в сети остаются в очереди до их подключения
+func (o *outbox) dispatchDue(ctx context.Context) {
+	messages, err := o.repo.GetScheduled(ctx)
+	if err != nil {
+		log.Printf("⚠️ Не удалось прочитать очередь отложенных сообщений: %v", err)
+		return
+	}
+
+	self := o.node.host.ID()
+	now := time.Now()
+	for _, msg := range messages {
+		if msg.SendAt.After(now) {
+			// Сообщения отсортированы по времени отправки
+			return
+		}
+
+		peerID, err := peer.Decode(msg.ToPeer)
+		if err != nil {
+			log.Printf("⚠️ Некорректный получатель отложенного сообщения %s: %v", msg.ID, err)
+			o.repo.DeleteScheduled(ctx, msg.ID)
+			continue
+		}
+
+		dispatch := ScheduledDispatch{
+			ID:       msg.ID,
+			PeerID:   peerID,
+			Text:     msg.Content,
+			SendAt:   msg.SendAt,
+			Reminder: peerID == self,
+		}
+
+		if !dispatch.Reminder {
+			if o.node.host.Network().Connectedness(peerID) != network.Connected {
+				continue
+			}
+			if err := o.node.SendMessage(peerID, msg.Content); err != nil {
+				// Повторим на следующей проверке, но сообщим только один раз
+				if !o.failed[msg.ID] {
+					o.failed[msg.ID] = true
+					dispatch.Error = err.Error()
+					o.node.emit(EventScheduledMessage, dispatch)
+				}
+				continue
+			}
+		}
+
+		if err := o.repo.DeleteScheduled(ctx, msg.ID); err != nil {
+			log.Printf("⚠️ Не удалось удалить отправленное сообщение из очереди: %v", err)
+		}
+		delete(o.failed, msg.ID)
+		o.node.emit(EventScheduledMessage, dispatch)
+	}
+}
@@ -0,0 +1,188 @@
+package core
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/multiformats/go-multiaddr"
+)
+
+const (
+	// defaultPeerCacheSize - сколько пиров помнит кэш адресов по умолчанию
+	defaultPeerCacheSize = 4096
+	// peerCacheEvictBatch - доля кэша, освобождаемая за раз, чтобы не
+	// пересчитывать оценки при каждом новом пире
+	peerCacheEvictBatch = 16
+	// peerCacheHitBonus - на сколько каждое удачное использование адресов
+	// продлевает жизнь записи при вытеснении
+	peerCacheHitBonus = time.Hour
+	// seenFilterSize и seenFilterError - размер и точность фильтра
+	// "встречался ли пир когда-либо"
+	seenFilterSize  = 100000
+	seenFilterError = 0.01
+)
+
+// peerCache помнит адреса, по которым пиры были доступны. Peerstore забывает
+// адреса отключившихся пиров через несколько минут, а кэш позволяет
+// попробовать их снова после перезапуска сети или долгого перерыва.
+// Размер кэша ограничен: при переполнении вытесняются давно не виденные и
+// редко пригождавшиеся пиры, контакты не вытесняются никогда
+type peerCache struct {
+	mu       sync.Mutex
+	capacity int
+	peers    map[peer.ID]*cachedPeer
+	// seen - все пиры, которых узел когда-либо видел, включая вытесненных
+	seen *bloomFilter
+	// keep - пиры, которых нельзя вытеснять (контакты)
+	keep func(peer.ID) bool
+}
+
+// cachedPeer - последние известные адреса пира
+type cachedPeer struct {
+	addrs    []multiaddr.Multiaddr
+	lastSeen time.Time
+	hits     int
+}
+
+// CachedPeer - запись кэша адресов
+type CachedPeer struct {
+	AddrInfo peer.AddrInfo
+	LastSeen time.Time
+	Hits     int
+}
+
+// score - чем больше, тем дольше запись остается в кэше
+func (p *cachedPeer) score() time.Time {
+	return p.lastSeen.Add(time.Duration(p.hits) * peerCacheHitBonus)
+}
+
+// remember сохраняет адреса подключившегося пира
+func (c *peerCache) remember(id peer.ID, addrs []multiaddr.Multiaddr) {
+	if len(addrs) == 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.peers == nil {
+		c.peers = make(map[peer.ID]*cachedPeer)
+		c.seen = newBloomFilter(seenFilterSize, seenFilterError)
+	}
+	c.seen.add([]byte(id))
+
+	if cached, ok := c.peers[id]; ok {
+		cached.addrs = append([]multiaddr.Multiaddr(nil), addrs...)
+		cached.lastSeen = time.Now()
+		return
+	}
+	if len(c.peers) >= c.limit() {
+		c.evictLocked()
+	}
+	c.peers[id] = &cachedPeer{
+		addrs:    append([]multiaddr.Multiaddr(nil), addrs...),
+		lastSeen: time.Now(),
+	}
+}
+
+// lookup возвращает сохраненные адреса пира
+func (c *peerCache) lookup(id peer.ID) []multiaddr.Multiaddr {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	cached, ok := c.peers[id]
+	if !ok {
+		return nil
+	}
+	cached.hits++
+	return append([]multiaddr.Multiaddr(nil), cached.addrs...)
+}
+
+// seenBefore сообщает, встречался ли пир когда-либо. Возможны редкие ложные
+// "да", но не ложные "нет"
+func (c *peerCache) seenBefore(id peer.ID) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.peers[id]; ok {
+		return true
+	}
+	return c.seen != nil && c.seen.has([]byte(id))
+}
+
+// limit возвращает вместимость кэша
+func (c *peerCache) limit() int {
+	if c.capacity > 0 {
+		return c.capacity
+	}
+	return defaultPeerCacheSize
+}
+
+// evictLocked вытесняет записи с наименьшей оценкой
+func (c *peerCache) evictLocked() {
+	type candidate struct {
+		id    peer.ID
+		score time.Time
+	}
+	candidates := make([]candidate, 0, len(c.peers))
+	for id, cached := range c.peers {
+		if c.keep != nil && c.keep(id) {
+			continue
+		}
+		candidates = append(candidates, candidate{id, cached.score()})
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].score.Before(candidates[j].score)
+	})
+
+	evict := c.limit() / peerCacheEvictBatch
+	if evict < 1 {
+		evict = 1
+	}
+	for i := 0; i < evict && i < len(candidates); i++ {
+		delete(c.peers, candidates[i].id)
+	}
+}
+
+// rememberConnected сохраняет адреса пира из peerstore и адрес соединения
+func (n *Node) rememberConnected(id peer.ID, remote multiaddr.Multiaddr) {
+	addrs := n.host.Peerstore().Addrs(id)
+	if remote != nil && !multiaddr.Contains(addrs, remote) {
+		addrs = append(addrs, remote)
+	}
+	n.peerCache.remember(id, addrs)
+}
+
+// CachedPeerAddrs возвращает последние адреса, по которым пир был доступен
+func (n *Node) CachedPeerAddrs(id peer.ID) []multiaddr.Multiaddr {
+	return n.peerCache.lookup(id)
+}
+
+// SeenPeer сообщает, встречался ли пир этому узлу когда-либо (в том числе
+// вытесненный из кэша). Для проверок в discovery, без загрузки всего кэша
+func (n *Node) SeenPeer(id peer.ID) bool {
+	return n.peerCache.seenBefore(id)
+}
+
+// CachedPeers возвращает до limit записей кэша, начиная с недавно виденных
+func (n *Node) CachedPeers(limit int) []CachedPeer {
+	c := &n.peerCache
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	peers := make([]CachedPeer, 0, len(c.peers))
+	for id, cached := range c.peers {
+		peers = append(peers, CachedPeer{
+			AddrInfo: peer.AddrInfo{ID: id, Addrs: append([]multiaddr.Multiaddr(nil), cached.addrs...)},
+			LastSeen: cached.lastSeen,
+			Hits:     cached.hits,
+		})
+	}
+	sort.Slice(peers, func(i, j int) bool { return peers[i].LastSeen.After(peers[j].LastSeen) })
+	if limit > 0 && len(peers) > limit {
+		peers = peers[:limit]
+	}
+	return peers
+}
@@ -0,0 +1,113 @@
+package core
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/multiformats/go-multiaddr"
+)
+
+// PEER_LOOKUP_PROTOCOL_ID - протокол "знаешь, где сейчас этот пир?" между
+// контактами. Отвечает только контакт, у которого искомый пир тоже в контактах
+const PEER_LOOKUP_PROTOCOL_ID = "/owl-whisper/peer-lookup/1.0.0"
+
+const (
+	// peerLookupLimit - максимальный размер запроса и ответа
+	peerLookupLimit = 16 * 1024
+	// peerLookupTimeout - сколько ждать ответа одного контакта
+	peerLookupTimeout = 10 * time.Second
+	// peerLookupMaxAddrs - сколько адресов отдавать в ответе
+	peerLookupMaxAddrs = 16
+)
+
+type peerLookupRequest struct {
+	PeerID string `json:"peer_id"`
+}
+
+type peerLookupResponse struct {
+	Addrs []string `json:"addrs,omitempty"`
+}
+
+// askPeerLookup спрашивает контакт helper об адресах пира target
+func (n *Node) askPeerLookup(ctx context.Context, helper, target peer.ID) ([]multiaddr.Multiaddr, error) {
+	ctx, cancel := context.WithTimeout(ctx, peerLookupTimeout)
+	defer cancel()
+
+	stream, err := n.newStream(ctx, helper, PEER_LOOKUP_PROTOCOL_ID)
+	if err != nil {
+		return nil, err
+	}
+	defer stream.Close()
+	stream.SetDeadline(time.Now().Add(peerLookupTimeout))
+
+	data, err := json.Marshal(peerLookupRequest{PeerID: target.String()})
+	if err != nil {
+		return nil, err
+	}
+	if _, err := stream.Write(append(data, '\n')); err != nil {
+		return nil, err
+	}
+
+	line, err := bufio.NewReader(io.LimitReader(stream, peerLookupLimit)).ReadBytes('\n')
+	if err != nil {
+		return nil, err
+	}
+	var resp peerLookupResponse
+	if err := json.Unmarshal(line, &resp); err != nil {
+		return nil, fmt.Errorf("некорректный ответ: %w", err)
+	}
+
+	var addrs []multiaddr.Multiaddr
+	for _, s := range resp.Addrs {
+		if addr, err := multiaddr.NewMultiaddr(s); err == nil {
+			addrs = append(addrs, addr)
+		}
+	}
+	return addrs, nil
+}
+
+// handlePeerLookupStream отвечает контакту адресами общего контакта.
+// Посторонним и про посторонних ничего не сообщается
+func (n *Node) handlePeerLookupStream(stream network.Stream) {
+	defer stream.Close()
+	remotePeer := stream.Conn().RemotePeer()
+	stream.SetDeadline(time.Now().Add(peerLookupTimeout))
+
+	line, err := bufio.NewReader(io.LimitReader(stream, peerLookupLimit)).ReadBytes('\n')
+	if err != nil {
+		stream.Reset()
+		return
+	}
+	var req peerLookupRequest
+	if err := json.Unmarshal(line, &req); err != nil {
+		stream.Reset()
+		return
+	}
+
+	var resp peerLookupResponse
+	target, err := peer.Decode(req.PeerID)
+	if err == nil && target != remotePeer && n.isKnownContact(remotePeer) && n.isKnownContact(target) {
+		addrs := n.host.Peerstore().Addrs(target)
+		if len(addrs) == 0 {
+			addrs = n.peerCache.lookup(target)
+		}
+		for _, addr := range addrs {
+			if len(resp.Addrs) >= peerLookupMaxAddrs {
+				break
+			}
+			resp.Addrs = append(resp.Addrs, addr.String())
+		}
+	}
+
+	data, err := json.Marshal(resp)
+	if err != nil {
+		return
+	}
+	stream.Write(append(data, '\n'))
+}
@@ -0,0 +1,196 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+
+	"OwlWhisper/pkg/interfaces"
+
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+// peerTagWeight - вес каждой метки пира в оценке менеджера соединений:
+// помеченные пиры закрываются последними
+const peerTagWeight = 20
+
+// maxPeerTagLength - максимальная длина метки
+const maxPeerTagLength = 32
+
+// peerTags - метки пиров, заданные встраивающим приложением
+type peerTags struct {
+	mu   sync.RWMutex
+	repo interfaces.IPeerTagRepository
+	tags map[peer.ID]map[string]bool
+}
+
+// LoadPeerTags загружает метки из хранилища и сохраняет в него изменения
+func (n *Node) LoadPeerTags(repo interfaces.IPeerTagRepository) error {
+	all, err := repo.GetAllPeerTags(context.Background())
+	if err != nil {
+		return fmt.Errorf("не удалось загрузить метки пиров: %w", err)
+	}
+
+	n.tags.mu.Lock()
+	n.tags.repo = repo
+	n.tags.tags = make(map[peer.ID]map[string]bool, len(all))
+	for rawID, tags := range all {
+		id, err := peer.Decode(rawID)
+		if err != nil {
+			log.Printf("⚠️ Некорректный PeerID в метках: %s", rawID)
+			continue
+		}
+		n.tags.tags[id] = make(map[string]bool, len(tags))
+		for _, tag := range tags {
+			n.tags.tags[id][tag] = true
+		}
+	}
+	n.tags.mu.Unlock()
+
+	for _, id := range n.taggedPeers() {
+		n.updateTagWeight(id)
+	}
+	return nil
+}
+
+// SetPeerTag добавляет пиру метку (например "family", "work", "bot")
+func (n *Node) SetPeerTag(id peer.ID, tag string) error {
+	tag, err := normalizeTag(tag)
+	if err != nil {
+		return err
+	}
+	return n.changePeerTags(id, func(tags map[string]bool) { tags[tag] = true })
+}
+
+// RemovePeerTag снимает с пира метку
+func (n *Node) RemovePeerTag(id peer.ID, tag string) error {
+	tag, err := normalizeTag(tag)
+	if err != nil {
+		return err
+	}
+	return n.changePeerTags(id, func(tags map[string]bool) { delete(tags, tag) })
+}
+
+// GetPeerTags возвращает метки пира по алфавиту
+func (n *Node) GetPeerTags(id peer.ID) []string {
+	n.tags.mu.RLock()
+	defer n.tags.mu.RUnlock()
+
+	return sortedTags(n.tags.tags[id])
+}
+
+// GetPeersByTag возвращает пиров с меткой
+func (n *Node) GetPeersByTag(tag string) []peer.ID {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+
+	n.tags.mu.RLock()
+	defer n.tags.mu.RUnlock()
+
+	var peers []peer.ID
+	for id, tags := range n.tags.tags {
+		if tags[tag] {
+			peers = append(peers, id)
+		}
+	}
+	sort.Slice(peers, func(i, j int) bool { return peers[i] < peers[j] })
+	return peers
+}
+
+// BroadcastToTag отправляет сообщение подключенным пирам с меткой
+// и возвращает число получателей
+func (n *Node) BroadcastToTag(tag, message string) int {
+	sent := 0
+	for _, id := range n.GetPeersByTag(tag) {
+		if n.host.Network().Connectedness(id) != network.Connected {
+			continue
+		}
+		if err := n.SendMessage(id, message); err != nil {
+			log.Printf("⚠️ Не удалось отправить сообщение к %s: %v", id.ShortString(), err)
+			continue
+		}
+		sent++
+	}
+	return sent
+}
+
+// changePeerTags изменяет метки пира и сохраняет их
+func (n *Node) changePeerTags(id peer.ID, change func(map[string]bool)) error {
+	n.tags.mu.Lock()
+	if n.tags.tags == nil {
+		n.tags.tags = make(map[peer.ID]map[string]bool)
+	}
+	tags := n.tags.tags[id]
+	if tags == nil {
+		tags = make(map[string]bool)
+		n.tags.tags[id] = tags
+	}
+	change(tags)
+	if len(tags) == 0 {
+		delete(n.tags.tags, id)
+	}
+	list := sortedTags(tags)
+	repo := n.tags.repo
+	n.tags.mu.Unlock()
+
+	n.updateTagWeight(id)
+	if repo == nil {
+		return nil
+	}
+	if err := repo.SavePeerTags(context.Background(), id.String(), list); err != nil {
+		return fmt.Errorf("не удалось сохранить метки пира: %w", err)
+	}
+	return nil
+}
+
+// updateTagWeight передает менеджеру соединений вес меток пира
+func (n *Node) updateTagWeight(id peer.ID) {
+	count := len(n.GetPeerTags(id))
+	if count == 0 {
+		n.host.ConnManager().UntagPeer(id, "owl-tags")
+		return
+	}
+	n.host.ConnManager().TagPeer(id, "owl-tags", count*peerTagWeight)
+}
+
+// taggedPeers возвращает всех пиров с метками
+func (n *Node) taggedPeers() []peer.ID {
+	n.tags.mu.RLock()
+	defer n.tags.mu.RUnlock()
+
+	peers := make([]peer.ID, 0, len(n.tags.tags))
+	for id := range n.tags.tags {
+		peers = append(peers, id)
+	}
+	return peers
+}
+
+// tagCount возвращает число меток пира
+func (n *Node) tagCount(id peer.ID) int {
+	n.tags.mu.RLock()
+	defer n.tags.mu.RUnlock()
+
+	return len(n.tags.tags[id])
+}
+
+// normalizeTag приводит метку к нижнему регистру и проверяет ее
+func normalizeTag(tag string) (string, error) {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if tag == "" || len(tag) > maxPeerTagLength || strings.ContainsAny(tag, " \t,") {
+		return "", fmt.Errorf("некорректная метка %q", tag)
+	}
+	return tag, nil
+}
+
+// sortedTags возвращает метки по алфавиту
+func sortedTags(tags map[string]bool) []string {
+	list := make([]string, 0, len(tags))
+	for tag := range tags {
+		list = append(list, tag)
+	}
+	sort.Strings(list)
+	return list
+}
@@ -0,0 +1,153 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+// PRESENCE_PROTOCOL_ID - протокол запроса времени последней активности пира
+const PRESENCE_PROTOCOL_ID = "/owl-whisper/presence/1.0.0"
+
+// presenceTimeout - предельное время запроса присутствия
+const presenceTimeout = 10 * time.Second
+
+// LastSeenPolicy определяет, кому узел сообщает время последней активности
+type LastSeenPolicy string
+
+const (
+	// LastSeenEveryone - время видно любому пиру
+	LastSeenEveryone LastSeenPolicy = "everyone"
+	// LastSeenContacts - время видно только контактам (см. SetContactLookup)
+	LastSeenContacts LastSeenPolicy = "contacts"
+	// LastSeenNobody - время не сообщается никому
+	LastSeenNobody LastSeenPolicy = "nobody"
+)
+
+// Presence - ответ на запрос присутствия
+type Presence struct {
+	PeerID peer.ID `json:"peer_id"`
+	// LastSeen - время последней активности; nil, если пир его скрывает
+	LastSeen *time.Time `json:"last_seen,omitempty"`
+	// Status - статус пира; только для контактов
+	Status *StatusMessage `json:"status,omitempty"`
+}
+
+// ParseLastSeenPolicy проверяет значение политики; пустое означает LastSeenEveryone
+func ParseLastSeenPolicy(value string) (LastSeenPolicy, error) {
+	switch policy := LastSeenPolicy(value); policy {
+	case "":
+		return LastSeenEveryone, nil
+	case LastSeenEveryone, LastSeenContacts, LastSeenNobody:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("неизвестная политика last seen: %s", value)
+	}
+}
+
+// SetLastSeenPolicy задает, кому протокол присутствия сообщает время
+// последней активности. Проверка выполняется на стороне узла, а не клиента
+func (n *Node) SetLastSeenPolicy(policy LastSeenPolicy) error {
+	if _, err := ParseLastSeenPolicy(string(policy)); err != nil {
+		return err
+	}
+
+	n.presenceMu.Lock()
+	n.lastSeenPolicy = policy
+	n.presenceMu.Unlock()
+	return nil
+}
+
+// LastSeenPolicy возвращает текущую политику
+func (n *Node) LastSeenPolicy() LastSeenPolicy {
+	n.presenceMu.RLock()
+	defer n.presenceMu.RUnlock()
+
+	if n.lastSeenPolicy == "" {
+		return LastSeenEveryone
+	}
+	return n.lastSeenPolicy
+}
+
+// SetContactLookup задает проверку "пир в контактах" для политики LastSeenContacts.
+// Без нее политика контактов ведет себя как LastSeenNobody
+func (n *Node) SetContactLookup(isContact func(peer.ID) bool) {
+	n.presenceMu.Lock()
+	n.isContact = isContact
+	n.presenceMu.Unlock()
+}
+
+// isKnownContact проверяет пира через функцию SetContactLookup
+func (n *Node) isKnownContact(id peer.ID) bool {
+	n.presenceMu.RLock()
+	defer n.presenceMu.RUnlock()
+
+	return n.isContact != nil && n.isContact(id)
+}
+
+// MarkActive отмечает активность пользователя (ввод, отправку сообщения)
+func (n *Node) MarkActive() {
+	n.presenceMu.Lock()
+	n.lastActive = time.Now()
+	n.presenceMu.Unlock()
+}
+
+// RequestPresence запрашивает у пира время его последней активности
+func (n *Node) RequestPresence(peerID peer.ID) (Presence, error) {
+	stream, err := n.newStream(n.ctx, peerID, PRESENCE_PROTOCOL_ID)
+	if err != nil {
+		return Presence{}, fmt.Errorf("не удалось открыть поток к %s: %w", peerID.ShortString(), err)
+	}
+	defer stream.Close()
+	stream.SetDeadline(time.Now().Add(presenceTimeout))
+
+	var presence Presence
+	if err := json.NewDecoder(io.LimitReader(stream, 4096)).Decode(&presence); err != nil {
+		return Presence{}, fmt.Errorf("некорректный ответ присутствия от %s: %w", peerID.ShortString(), err)
+	}
+	presence.PeerID = peerID
+	return presence, nil
+}
+
+// handlePresenceStream отвечает на запрос присутствия согласно политике
+func (n *Node) handlePresenceStream(stream network.Stream) {
+	defer stream.Close()
+	stream.SetDeadline(time.Now().Add(presenceTimeout))
+
+	presence := Presence{PeerID: n.host.ID()}
+	if lastSeen, ok := n.lastSeenFor(stream.Conn().RemotePeer()); ok {
+		presence.LastSeen = &lastSeen
+	}
+	if status, ok := n.statusFor(stream.Conn().RemotePeer()); ok {
+		presence.Status = &status
+	}
+
+	if err := json.NewEncoder(stream).Encode(presence); err != nil {
+		log.Printf("⚠️ Не удалось ответить на запрос присутствия: %v", err)
+	}
+}
+
+// lastSeenFor возвращает время активности, если политика разрешает его показать пиру
+func (n *Node) lastSeenFor(remote peer.ID) (time.Time, bool) {
+	n.presenceMu.RLock()
+	defer n.presenceMu.RUnlock()
+
+	if n.lastActive.IsZero() {
+		return time.Time{}, false
+	}
+	switch n.lastSeenPolicy {
+	case LastSeenNobody:
+		return time.Time{}, false
+	case LastSeenContacts:
+		if n.isContact == nil || !n.isContact(remote) {
+			return time.Time{}, false
+		}
+	}
+	// Точность до минуты: точные метки позволяют следить за активностью
+	return n.lastActive.Truncate(time.Minute), true
+}
@@ -0,0 +1,86 @@
+package core
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+const (
+	// prewarmTimeout - сколько длится одна попытка заранее соединиться с пиром
+	prewarmTimeout = 90 * time.Second
+	// prewarmCooldown - не чаще одной попытки к пиру за это время
+	prewarmCooldown = 2 * time.Minute
+)
+
+// prewarming - заблаговременные соединения с пирами, которым пользователь,
+// вероятно, скоро напишет
+type prewarming struct {
+	mu       sync.Mutex
+	disabled bool
+	resolver *PeerResolver
+	inflight map[peer.ID]bool
+	last     map[peer.ID]time.Time
+}
+
+// SetPeerResolver задает поиск пиров для прогрева соединений. Без него
+// прогрев использует только уже известные адреса
+func (n *Node) SetPeerResolver(resolver *PeerResolver) {
+	n.prewarm.mu.Lock()
+	n.prewarm.resolver = resolver
+	n.prewarm.mu.Unlock()
+}
+
+// SetPrewarmEnabled включает или выключает прогрев соединений
+func (n *Node) SetPrewarmEnabled(enabled bool) {
+	n.prewarm.mu.Lock()
+	n.prewarm.disabled = !enabled
+	n.prewarm.mu.Unlock()
+}
+
+// PrewarmConnection в фоне находит пира и соединяется с ним, чтобы первое
+// сообщение ушло без задержки на поиск. Вызывается, например, при открытии
+// диалога. Ошибки не сообщаются: при отправке пир будет искаться как обычно
+func (n *Node) PrewarmConnection(id peer.ID) {
+	if id == n.host.ID() || n.host.Network().Connectedness(id) == network.Connected {
+		return
+	}
+
+	p := &n.prewarm
+	p.mu.Lock()
+	if p.disabled || p.inflight[id] || time.Since(p.last[id]) < prewarmCooldown {
+		p.mu.Unlock()
+		return
+	}
+	if p.inflight == nil {
+		p.inflight = make(map[peer.ID]bool)
+		p.last = make(map[peer.ID]time.Time)
+	}
+	p.inflight[id] = true
+	p.last[id] = time.Now()
+	resolver := p.resolver
+	p.mu.Unlock()
+
+	go func() {
+		defer func() {
+			p.mu.Lock()
+			delete(p.inflight, id)
+			p.mu.Unlock()
+		}()
+
+		ctx, cancel := context.WithTimeout(n.ctx, prewarmTimeout)
+		defer cancel()
+
+		if resolver != nil {
+			resolver.Resolve(ctx, id)
+			return
+		}
+		addrs := append(n.host.Peerstore().Addrs(id), n.CachedPeerAddrs(id)...)
+		if len(addrs) > 0 {
+			n.host.Connect(ctx, peer.AddrInfo{ID: id, Addrs: addrs})
+		}
+	}()
+}
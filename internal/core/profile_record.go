@@ -0,0 +1,251 @@
+package core
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"OwlWhisper/pkg/interfaces"
+
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/multiformats/go-multiaddr"
+)
+
+// PROFILE_PROTOCOL_ID - протокол запроса записи профиля контакта
+const PROFILE_PROTOCOL_ID = "/owl-whisper/profile/1.0.0"
+
+const (
+	// profileTimeout - предельное время запроса записи профиля
+	profileTimeout = 10 * time.Second
+	// profileLimit - максимальный размер записи профиля
+	profileLimit = 16 * 1024
+	// profileMaxMailboxes - сколько почтовых ящиков можно указать в записи
+	profileMaxMailboxes = 4
+)
+
+// profileRecords - своя запись профиля и записи контактов
+type profileRecords struct {
+	mu    sync.RWMutex
+	repo  interfaces.IProfileRecordRepository
+	own   interfaces.ProfileRecord
+	peers map[peer.ID]interfaces.ProfileRecord
+}
+
+// profileStatement возвращает подписываемый текст записи профиля
+func profileStatement(record interfaces.ProfileRecord) []byte {
+	return []byte(fmt.Sprintf("owl-whisper profile\npeer:%s\nmailbox-key:%s\nmailboxes:%s\nupdated:%s\n",
+		record.PeerID, base64.StdEncoding.EncodeToString(record.MailboxKey),
+		strings.Join(record.Mailboxes, " "), record.UpdatedAt.UTC().Format(time.RFC3339Nano)))
+}
+
+// verifyProfileRecord проверяет запись профиля пира id
+func verifyProfileRecord(id peer.ID, record interfaces.ProfileRecord) error {
+	if record.PeerID != id.String() {
+		return fmt.Errorf("запись профиля выдана другому PeerID")
+	}
+	if len(record.MailboxKey) != mailboxKeySize {
+		return fmt.Errorf("некорректный ключ для писем в записи профиля")
+	}
+	if len(record.Mailboxes) > profileMaxMailboxes {
+		return fmt.Errorf("в записи профиля больше %d почтовых ящиков", profileMaxMailboxes)
+	}
+	pub, err := id.ExtractPublicKey()
+	if err != nil {
+		return err
+	}
+	ok, err := pub.Verify(profileStatement(record), record.Signature)
+	if err != nil || !ok {
+		return fmt.Errorf("подпись записи профиля недействительна")
+	}
+	return nil
+}
+
+// LoadProfileRecords загружает сохраненные записи профиля контактов
+func (n *Node) LoadProfileRecords(repo interfaces.IProfileRecordRepository) error {
+	records, err := repo.GetProfileRecords(context.Background())
+	if err != nil {
+		return fmt.Errorf("не удалось загрузить записи профиля: %w", err)
+	}
+
+	peers := make(map[peer.ID]interfaces.ProfileRecord, len(records))
+	for _, record := range records {
+		id, err := peer.Decode(record.PeerID)
+		if err != nil || verifyProfileRecord(id, record) != nil {
+			continue
+		}
+		peers[id] = record
+	}
+
+	n.profiles.mu.Lock()
+	n.profiles.repo = repo
+	n.profiles.peers = peers
+	n.profiles.mu.Unlock()
+	return nil
+}
+
+// SetMailboxes подписывает свою запись профиля со списком почтовых ящиков,
+// через которые контакты пишут, пока мы не в сети. Контакты получают
+// запись при следующем подключении
+func (n *Node) SetMailboxes(mailboxes []peer.AddrInfo) error {
+	if len(mailboxes) > profileMaxMailboxes {
+		return fmt.Errorf("можно указать не больше %d почтовых ящиков", profileMaxMailboxes)
+	}
+	mailboxKey, err := n.mailboxKey()
+	if err != nil {
+		return err
+	}
+
+	record := interfaces.ProfileRecord{
+		PeerID:     n.host.ID().String(),
+		MailboxKey: mailboxKey.PublicKey().Bytes(),
+		UpdatedAt:  time.Now().UTC(),
+	}
+	for _, mailbox := range mailboxes {
+		if len(mailbox.Addrs) == 0 {
+			record.Mailboxes = append(record.Mailboxes, "/p2p/"+mailbox.ID.String())
+			continue
+		}
+		addrs, err := peer.AddrInfoToP2pAddrs(&mailbox)
+		if err != nil {
+			return err
+		}
+		for _, addr := range addrs {
+			record.Mailboxes = append(record.Mailboxes, addr.String())
+		}
+	}
+
+	key := n.host.Peerstore().PrivKey(n.host.ID())
+	if key == nil {
+		return fmt.Errorf("закрытый ключ узла недоступен")
+	}
+	if record.Signature, err = key.Sign(profileStatement(record)); err != nil {
+		return fmt.Errorf("не удалось подписать запись профиля: %w", err)
+	}
+
+	n.profiles.mu.Lock()
+	n.profiles.own = record
+	n.profiles.mu.Unlock()
+	return nil
+}
+
+// peerProfile возвращает последнюю известную запись профиля пира
+func (n *Node) peerProfile(id peer.ID) (interfaces.ProfileRecord, bool) {
+	n.profiles.mu.RLock()
+	defer n.profiles.mu.RUnlock()
+
+	record, ok := n.profiles.peers[id]
+	return record, ok
+}
+
+// ownMailboxes возвращает PeerID своих почтовых ящиков
+func (n *Node) ownMailboxes() map[peer.ID]bool {
+	n.profiles.mu.RLock()
+	defer n.profiles.mu.RUnlock()
+
+	mailboxes := make(map[peer.ID]bool)
+	for _, info := range parseMailboxAddrs(n.profiles.own.Mailboxes) {
+		mailboxes[info.ID] = true
+	}
+	return mailboxes
+}
+
+// fetchProfileRecord запрашивает запись профиля контакта и сохраняет ее,
+// если она новее известной
+func (n *Node) fetchProfileRecord(id peer.ID) {
+	if !n.isKnownContact(id) {
+		return
+	}
+	ctx, cancel := context.WithTimeout(n.ctx, profileTimeout)
+	defer cancel()
+
+	stream, err := n.newStream(ctx, id, PROFILE_PROTOCOL_ID)
+	if err != nil {
+		return
+	}
+	defer stream.Close()
+	stream.SetDeadline(time.Now().Add(profileTimeout))
+
+	line, err := bufio.NewReader(io.LimitReader(stream, profileLimit)).ReadBytes('\n')
+	if err != nil {
+		return
+	}
+	var record interfaces.ProfileRecord
+	if err := json.Unmarshal(line, &record); err != nil {
+		return
+	}
+	if err := verifyProfileRecord(id, record); err != nil {
+		log.Printf("🚫 Запись профиля %s отклонена: %v", id.ShortString(), err)
+		n.emitSecurity(SecurityVerificationFailed, SeverityWarning, id,
+			stream.Conn().RemoteMultiaddr().String(), err.Error())
+		return
+	}
+
+	n.profiles.mu.Lock()
+	existing, ok := n.profiles.peers[id]
+	if ok && !record.UpdatedAt.After(existing.UpdatedAt) {
+		n.profiles.mu.Unlock()
+		return
+	}
+	if n.profiles.peers == nil {
+		n.profiles.peers = make(map[peer.ID]interfaces.ProfileRecord)
+	}
+	n.profiles.peers[id] = record
+	repo := n.profiles.repo
+	n.profiles.mu.Unlock()
+
+	if repo != nil {
+		if err := repo.SaveProfileRecord(n.ctx, record); err != nil {
+			log.Printf("⚠️ Не удалось сохранить запись профиля %s: %v", id.ShortString(), err)
+		}
+	}
+}
+
+// handleProfileStream отдает свою запись профиля контакту
+func (n *Node) handleProfileStream(stream network.Stream) {
+	defer stream.Close()
+	stream.SetDeadline(time.Now().Add(profileTimeout))
+
+	if !n.isKnownContact(stream.Conn().RemotePeer()) {
+		stream.Reset()
+		return
+	}
+
+	n.profiles.mu.RLock()
+	record := n.profiles.own
+	n.profiles.mu.RUnlock()
+	if len(record.Signature) == 0 {
+		stream.Reset()
+		return
+	}
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		stream.Reset()
+		return
+	}
+	stream.Write(append(data, '\n'))
+}
+
+// parseMailboxAddrs разбирает адреса почтовых ящиков, объединяя адреса
+// одного ящика и пропуская некорректные
+func parseMailboxAddrs(addrs []string) []peer.AddrInfo {
+	var parsed []multiaddr.Multiaddr
+	for _, s := range addrs {
+		if addr, err := multiaddr.NewMultiaddr(s); err == nil {
+			parsed = append(parsed, addr)
+		}
+	}
+	infos, err := peer.AddrInfosFromP2pAddrs(parsed...)
+	if err != nil {
+		return nil
+	}
+	return infos
+}
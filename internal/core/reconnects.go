@@ -0,0 +1,104 @@
+package core
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+// ReconnectRecord - история переподключений к пиру. Attempts считает
+// неудачные попытки подряд и сбрасывается, как только пир снова на связи
+type ReconnectRecord struct {
+	PeerID      peer.ID   `json:"peer_id"`
+	Attempts    int       `json:"attempts"`
+	Total       int       `json:"total"` // все попытки за время работы узла
+	LastAttempt time.Time `json:"last_attempt,omitempty"`
+	LastSuccess time.Time `json:"last_success,omitempty"`
+	LastFailure time.Time `json:"last_failure,omitempty"`
+	LastError   string    `json:"last_error,omitempty"`
+}
+
+// reconnectTracker учитывает исходы переподключений
+type reconnectTracker struct {
+	mu      sync.Mutex
+	records map[peer.ID]*ReconnectRecord
+}
+
+// record возвращает запись пира, создавая ее; вызывается под t.mu
+func (t *reconnectTracker) record(id peer.ID) *ReconnectRecord {
+	if t.records == nil {
+		t.records = make(map[peer.ID]*ReconnectRecord)
+	}
+	rec, ok := t.records[id]
+	if !ok {
+		rec = &ReconnectRecord{PeerID: id}
+		t.records[id] = rec
+	}
+	return rec
+}
+
+// attempt учитывает исход попытки переподключения
+func (t *reconnectTracker) attempt(id peer.ID, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	rec := t.record(id)
+	now := time.Now()
+	rec.Total++
+	rec.LastAttempt = now
+	if err != nil {
+		rec.Attempts++
+		rec.LastFailure = now
+		rec.LastError = err.Error()
+		return
+	}
+	rec.Attempts = 0
+	rec.LastSuccess = now
+}
+
+// connected сбрасывает счетчик неудач, когда пир подключился любым путем,
+// в том числе сам
+func (t *reconnectTracker) connected(id peer.ID) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	rec, ok := t.records[id]
+	if !ok || rec.Attempts == 0 {
+		return
+	}
+	rec.Attempts = 0
+	rec.LastSuccess = time.Now()
+}
+
+// GetReconnectRecord возвращает историю переподключений к пиру
+func (n *Node) GetReconnectRecord(id peer.ID) (ReconnectRecord, bool) {
+	n.reconnects.mu.Lock()
+	defer n.reconnects.mu.Unlock()
+
+	rec, ok := n.reconnects.records[id]
+	if !ok {
+		return ReconnectRecord{PeerID: id}, false
+	}
+	return *rec, true
+}
+
+// GetReconnectRecords возвращает историю переподключений ко всем пирам,
+// начиная с самых проблемных
+func (n *Node) GetReconnectRecords() []ReconnectRecord {
+	n.reconnects.mu.Lock()
+	records := make([]ReconnectRecord, 0, len(n.reconnects.records))
+	for _, rec := range n.reconnects.records {
+		records = append(records, *rec)
+	}
+	n.reconnects.mu.Unlock()
+
+	sort.Slice(records, func(i, j int) bool {
+		if records[i].Attempts != records[j].Attempts {
+			return records[i].Attempts > records[j].Attempts
+		}
+		return records[i].PeerID < records[j].PeerID
+	})
+	return records
+}
@@ -0,0 +1,6 @@
+//go:build !norelay
+
+package core
+
+// relaySupported - собран ли Circuit Relay v2 (тег сборки norelay его отключает)
+const relaySupported = true
@@ -0,0 +1,7 @@
+//go:build norelay
+
+package core
+
+// relaySupported - сборка с тегом norelay: ретрансляция и hole punching
+// выключены независимо от настроек
+const relaySupported = false
@@ -0,0 +1,313 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/p2p/protocol/circuitv2/client"
+	"github.com/multiformats/go-multiaddr"
+)
+
+const (
+	// relayCheckInterval - как часто проверяются и обновляются резервирования
+	relayCheckInterval = 30 * time.Second
+	// relayRenewBefore - за сколько до истечения резервирование продлевается
+	relayRenewBefore = 2 * time.Minute
+	// relayReserveTimeout - предельное время запроса резервирования
+	relayReserveTimeout = 30 * time.Second
+	// minRelayReservations - сколько резервирований держать помимо закрепленных
+	minRelayReservations = 2
+)
+
+// Состояния резервирования в событии EventRelayReservation
+const (
+	RelayReserved = "reserved"
+	RelayRenewed  = "renewed"
+	RelayLost     = "lost"
+)
+
+// RelayStatus - состояние ретранслятора для встраивающего приложения
+type RelayStatus struct {
+	PeerID   peer.ID       `json:"peer_id"`
+	Pinned   bool          `json:"pinned"`
+	Reserved bool          `json:"reserved"`
+	Expires  time.Time     `json:"expires,omitempty"`
+	RTT      time.Duration `json:"rtt"`
+	Error    string        `json:"error,omitempty"`
+}
+
+// RelayReservationChanged - полезная нагрузка события EventRelayReservation
+type RelayReservationChanged struct {
+	PeerID  peer.ID   `json:"peer_id"`
+	State   string    `json:"state"` // RelayReserved, RelayRenewed, RelayLost
+	Expires time.Time `json:"expires,omitempty"`
+	Error   string    `json:"error,omitempty"`
+}
+
+// relayEntry - известный ретранслятор и его резервирование
+type relayEntry struct {
+	info        peer.AddrInfo
+	pinned      bool
+	reservation *client.Reservation
+	lastError   string
+}
+
+// relayManager держит резервирования на ретрансляторах: закрепленные
+// пользователем пробуются первыми и резервируются всегда, остальные -
+// пока не наберется minRelayReservations
+type relayManager struct {
+	node *Node
+
+	mu     sync.Mutex
+	relays []*relayEntry
+	kick   chan struct{}
+}
+
+// newRelayManager создается до хоста libp2p, потому что хост запрашивает
+// адреса через него; узел подключается позже через attach
+func newRelayManager(candidates, pinned []peer.AddrInfo) *relayManager {
+	m := &relayManager{
+		kick: make(chan struct{}, 1),
+	}
+	for _, info := range pinned {
+		m.relays = append(m.relays, &relayEntry{info: info, pinned: true})
+	}
+	for _, info := range candidates {
+		if m.find(info.ID) == nil {
+			m.relays = append(m.relays, &relayEntry{info: info})
+		}
+	}
+	return m
+}
+
+// attach связывает менеджер с созданным узлом
+func (m *relayManager) attach(node *Node) {
+	m.mu.Lock()
+	m.node = node
+	m.mu.Unlock()
+}
+
+// find возвращает запись ретранслятора; вызывается под m.mu или до запуска
+func (m *relayManager) find(id peer.ID) *relayEntry {
+	for _, entry := range m.relays {
+		if entry.info.ID == id {
+			return entry
+		}
+	}
+	return nil
+}
+
+// run поддерживает резервирования до остановки узла
+func (m *relayManager) run(ctx context.Context) {
+	ticker := time.NewTicker(relayCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		m.maintain(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-m.kick:
+		}
+	}
+}
+
+// wake запускает внеочередную проверку
+func (m *relayManager) wake() {
+	select {
+	case m.kick <- struct{}{}:
+	default:
+	}
+}
+
+// maintain продлевает истекающие резервирования, замечает потерянные
+// и добирает новые в порядке приоритета
+func (m *relayManager) maintain(ctx context.Context) {
+	m.mu.Lock()
+	relays := append([]*relayEntry(nil), m.relays...)
+	m.mu.Unlock()
+
+	// Закрепленные ретрансляторы всегда первыми
+	sort.SliceStable(relays, func(i, j int) bool { return relays[i].pinned && !relays[j].pinned })
+
+	h := m.node.host
+	reserved := 0
+	for _, entry := range relays {
+		m.mu.Lock()
+		reservation := entry.reservation
+		m.mu.Unlock()
+
+		if reservation != nil && h.Network().Connectedness(entry.info.ID) != network.Connected {
+			m.lose(entry, "соединение с ретранслятором потеряно")
+			reservation = nil
+		}
+
+		switch {
+		case reservation != nil && time.Until(reservation.Expiration) > relayRenewBefore:
+			reserved++
+		case reservation != nil:
+			if m.reserve(ctx, entry, RelayRenewed) {
+				reserved++
+			}
+		case entry.pinned || reserved < minRelayReservations:
+			if m.reserve(ctx, entry, RelayReserved) {
+				reserved++
+			}
+		}
+	}
+}
+
+// reserve запрашивает или продлевает резервирование
+func (m *relayManager) reserve(ctx context.Context, entry *relayEntry, state string) bool {
+	reserveCtx, cancel := context.WithTimeout(ctx, relayReserveTimeout)
+	defer cancel()
+
+	reservation, err := client.Reserve(reserveCtx, m.node.host, entry.info)
+	if err != nil {
+		if state == RelayRenewed {
+			m.lose(entry, err.Error())
+		} else {
+			m.mu.Lock()
+			entry.lastError = err.Error()
+			m.mu.Unlock()
+		}
+		return false
+	}
+
+	// Соединение с ретранслятором не должен закрыть менеджер соединений
+	m.node.host.ConnManager().Protect(entry.info.ID, "relay")
+
+	m.mu.Lock()
+	entry.reservation = reservation
+	entry.lastError = ""
+	m.mu.Unlock()
+
+	if state == RelayReserved {
+		log.Printf("📡 Резервирование на ретрансляторе %s до %s", entry.info.ID.ShortString(), reservation.Expiration.Format("15:04"))
+	}
+	m.node.emit(EventRelayReservation, RelayReservationChanged{
+		PeerID:  entry.info.ID,
+		State:   state,
+		Expires: reservation.Expiration,
+	})
+	return true
+}
+
+// lose сбрасывает резервирование и сообщает об этом
+func (m *relayManager) lose(entry *relayEntry, reason string) {
+	m.mu.Lock()
+	had := entry.reservation != nil
+	entry.reservation = nil
+	entry.lastError = reason
+	m.mu.Unlock()
+
+	m.node.host.ConnManager().Unprotect(entry.info.ID, "relay")
+	if !had {
+		return
+	}
+
+	log.Printf("⚠️ Резервирование на ретрансляторе %s потеряно: %s", entry.info.ID.ShortString(), reason)
+	m.node.emit(EventRelayReservation, RelayReservationChanged{
+		PeerID: entry.info.ID,
+		State:  RelayLost,
+		Error:  reason,
+	})
+}
+
+// circuitAddrs возвращает адреса узла через ретрансляторы с действующим
+// резервированием; их узел анонсирует наряду с собственными
+func (m *relayManager) circuitAddrs() []multiaddr.Multiaddr {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.node == nil {
+		return nil
+	}
+	var addrs []multiaddr.Multiaddr
+	for _, entry := range m.relays {
+		if entry.reservation == nil || time.Now().After(entry.reservation.Expiration) {
+			continue
+		}
+		suffix, err := multiaddr.NewMultiaddr(fmt.Sprintf("/p2p/%s/p2p-circuit", entry.info.ID))
+		if err != nil {
+			continue
+		}
+		for _, addr := range m.node.host.Peerstore().Addrs(entry.info.ID) {
+			addrs = append(addrs, addr.Encapsulate(suffix))
+		}
+	}
+	return addrs
+}
+
+// Relays возвращает известные ретрансляторы, их резервирования и RTT
+func (n *Node) Relays() []RelayStatus {
+	n.relays.mu.Lock()
+	defer n.relays.mu.Unlock()
+
+	statuses := make([]RelayStatus, 0, len(n.relays.relays))
+	for _, entry := range n.relays.relays {
+		status := RelayStatus{
+			PeerID: entry.info.ID,
+			Pinned: entry.pinned,
+			RTT:    n.host.Peerstore().LatencyEWMA(entry.info.ID),
+			Error:  entry.lastError,
+		}
+		if entry.reservation != nil {
+			status.Reserved = true
+			status.Expires = entry.reservation.Expiration
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
+
+// PinRelay закрепляет ретранслятор (например, собственный VPS пользователя):
+// он пробуется первым и резервируется всегда
+func (n *Node) PinRelay(info peer.AddrInfo) error {
+	if !n.config.EnableRelay || !relaySupported {
+		return fmt.Errorf("ретрансляция выключена")
+	}
+
+	n.relays.mu.Lock()
+	if entry := n.relays.find(info.ID); entry != nil {
+		entry.pinned = true
+		if len(info.Addrs) > 0 {
+			entry.info = info
+		}
+	} else {
+		n.relays.relays = append(n.relays.relays, &relayEntry{info: info, pinned: true})
+	}
+	n.relays.mu.Unlock()
+
+	n.transportPolicy.exempt(info.ID)
+	n.relays.wake()
+	return nil
+}
+
+// UnpinRelay снимает закрепление; резервирование остается, пока
+// ретранслятор нужен для минимального числа резервирований
+func (n *Node) UnpinRelay(id peer.ID) error {
+	n.relays.mu.Lock()
+	defer n.relays.mu.Unlock()
+
+	entry := n.relays.find(id)
+	if entry == nil || !entry.pinned {
+		return fmt.Errorf("ретранслятор %s не закреплен", id.ShortString())
+	}
+	entry.pinned = false
+	return nil
+}
+
+// HidesIP сообщает, работает ли узел в режиме скрытия IP: соединения с
+// собеседниками идут только через ретрансляторы, а не напрямую
+func (n *Node) HidesIP() bool {
+	return n.config.HideIP
+}
@@ -0,0 +1,205 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/multiformats/go-multiaddr"
+)
+
+// Стратегии поиска пира в порядке применения
+const (
+	// StrategyPeerstore - адреса, уже известные libp2p (или пир уже подключен)
+	StrategyPeerstore = "peerstore"
+	// StrategyPeerCache - адреса, по которым пир был доступен раньше
+	StrategyPeerCache = "peer_cache"
+	// StrategyDHT - FindPeer в DHT
+	StrategyDHT = "dht"
+	// StrategyRendezvous - поиск среди анонсированных в rendezvous участников
+	StrategyRendezvous = "rendezvous"
+	// StrategyContacts - запрос адресов у подключенных общих контактов
+	StrategyContacts = "contacts"
+)
+
+// strategyTimeout - сколько времени дается одной стратегии
+const strategyTimeout = 30 * time.Second
+
+// ErrPeerNotFound возвращается, когда ни одна стратегия не дала соединения
+var ErrPeerNotFound = errors.New("пир не найден")
+
+// errNoAddrs - стратегия не нашла ни одного адреса
+var errNoAddrs = errors.New("адреса не найдены")
+
+// ResolveAttempt - результат одной стратегии
+type ResolveAttempt struct {
+	Strategy string
+	Duration time.Duration
+	Err      error
+}
+
+// PeerResolution - итог поиска пира: с какими адресами и какой стратегией
+// удалось соединиться, и что было опробовано до этого
+type PeerResolution struct {
+	AddrInfo peer.AddrInfo
+	Strategy string
+	Attempts []ResolveAttempt
+}
+
+// PeerResolver ищет пира последовательно: peerstore, кэш адресов, DHT,
+// rendezvous и общие контакты. Каждая стратегия считается успешной только
+// если по найденным адресам удалось соединиться
+type PeerResolver struct {
+	node      *Node
+	discovery *DiscoveryManager
+}
+
+// NewPeerResolver создает поиск пиров. discovery может быть nil - тогда
+// стратегии DHT и rendezvous пропускаются
+func NewPeerResolver(node *Node, discovery *DiscoveryManager) *PeerResolver {
+	return &PeerResolver{node: node, discovery: discovery}
+}
+
+// Resolve находит пира и соединяется с ним
+func (r *PeerResolver) Resolve(ctx context.Context, id peer.ID) (*PeerResolution, error) {
+	if id == r.node.host.ID() {
+		return nil, fmt.Errorf("нельзя искать самого себя")
+	}
+
+	result := &PeerResolution{}
+	if r.node.host.Network().Connectedness(id) == network.Connected {
+		result.AddrInfo = r.node.host.Peerstore().PeerInfo(id)
+		result.Strategy = StrategyPeerstore
+		return result, nil
+	}
+
+	// Соединение само отмечает пира как встреченного, поэтому проверяем заранее
+	known := r.node.SeenPeer(id)
+	strategies := []struct {
+		name string
+		find func(context.Context, peer.ID) ([]multiaddr.Multiaddr, error)
+		// source и namespace - как сообщить о находке (EventPeerFound);
+		// пустой source - стратегия не является обнаружением
+		source    DiscoverySource
+		namespace string
+	}{
+		{StrategyPeerstore, r.fromPeerstore, "", ""},
+		{StrategyPeerCache, r.fromCache, DiscoverySourceCache, ""},
+		{StrategyDHT, r.fromDHT, DiscoverySourceDHT, ""},
+		{StrategyRendezvous, r.fromRendezvous, DiscoverySourceRendezvous, RENDEZVOUS_TAG},
+		{StrategyContacts, r.fromContacts, "", ""},
+	}
+
+	for _, strategy := range strategies {
+		if ctx.Err() != nil {
+			return result, ctx.Err()
+		}
+
+		started := time.Now()
+		info, err := r.try(ctx, id, strategy.find)
+		result.Attempts = append(result.Attempts, ResolveAttempt{
+			Strategy: strategy.name,
+			Duration: time.Since(started),
+			Err:      err,
+		})
+		if err == nil {
+			result.AddrInfo = info
+			result.Strategy = strategy.name
+			if strategy.source != "" {
+				r.node.PublishPeerFound(PeerFound{
+					AddrInfo:  info,
+					Source:    strategy.source,
+					Namespace: strategy.namespace,
+					Known:     known,
+				})
+			}
+			return result, nil
+		}
+	}
+	return result, ErrPeerNotFound
+}
+
+// try получает адреса стратегией find и пробует по ним соединиться
+func (r *PeerResolver) try(ctx context.Context, id peer.ID,
+	find func(context.Context, peer.ID) ([]multiaddr.Multiaddr, error)) (peer.AddrInfo, error) {
+	ctx, cancel := context.WithTimeout(ctx, strategyTimeout)
+	defer cancel()
+
+	addrs, err := find(ctx, id)
+	if err != nil {
+		return peer.AddrInfo{}, err
+	}
+	if len(addrs) == 0 {
+		return peer.AddrInfo{}, errNoAddrs
+	}
+
+	info := peer.AddrInfo{ID: id, Addrs: addrs}
+	if err := r.node.host.Connect(ctx, info); err != nil {
+		return info, err
+	}
+	return info, nil
+}
+
+func (r *PeerResolver) fromPeerstore(ctx context.Context, id peer.ID) ([]multiaddr.Multiaddr, error) {
+	return r.node.host.Peerstore().Addrs(id), nil
+}
+
+func (r *PeerResolver) fromCache(ctx context.Context, id peer.ID) ([]multiaddr.Multiaddr, error) {
+	return r.node.peerCache.lookup(id), nil
+}
+
+func (r *PeerResolver) fromDHT(ctx context.Context, id peer.ID) ([]multiaddr.Multiaddr, error) {
+	if r.discovery == nil {
+		return nil, ErrDHTUnavailable
+	}
+	info, err := r.discovery.FindPeer(ctx, id)
+	return info.Addrs, err
+}
+
+func (r *PeerResolver) fromRendezvous(ctx context.Context, id peer.ID) ([]multiaddr.Multiaddr, error) {
+	if r.discovery == nil || r.discovery.routingDiscovery == nil {
+		return nil, ErrDHTUnavailable
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	found, err := r.discovery.findProviders(ctx, RENDEZVOUS_TAG)
+	if err != nil {
+		return nil, err
+	}
+	for info := range found {
+		if info.ID == id {
+			return info.Addrs, nil
+		}
+	}
+	return nil, ctx.Err()
+}
+
+// fromContacts спрашивает подключенные контакты, знают ли они адреса пира
+func (r *PeerResolver) fromContacts(ctx context.Context, id peer.ID) ([]multiaddr.Multiaddr, error) {
+	var addrs []multiaddr.Multiaddr
+	asked := 0
+	for _, helper := range r.node.host.Network().Peers() {
+		if helper == id || !r.node.isKnownContact(helper) {
+			continue
+		}
+		asked++
+		found, err := r.node.askPeerLookup(ctx, helper, id)
+		if err != nil {
+			continue
+		}
+		for _, addr := range found {
+			if !multiaddr.Contains(addrs, addr) {
+				addrs = append(addrs, addr)
+			}
+		}
+	}
+	if asked == 0 {
+		return nil, fmt.Errorf("нет подключенных контактов")
+	}
+	return addrs, nil
+}
@@ -0,0 +1,359 @@
+package core
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"io"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+// SCREEN_PROTOCOL_ID - протокол демонстрации экрана кадрами из JPEG-плиток.
+// Передаются только изменившиеся плитки, раз в screenKeyframeInterval - все
+const SCREEN_PROTOCOL_ID = "/owl-whisper/screen/1.0.0"
+
+const (
+	// screenKeyframeInterval - как часто отправлять кадр целиком, чтобы
+	// получатель восстановился после потерь и смены размера
+	screenKeyframeInterval = 10 * time.Second
+	// screenWriteTimeout - предельное время отправки одного кадра
+	screenWriteTimeout = 10 * time.Second
+	// maxScreenHeader и maxScreenPayload ограничивают размер кадра от пира
+	maxScreenHeader  = 1 << 20
+	maxScreenPayload = 32 << 20
+	// maxScreenSide - максимальная ширина и высота кадра
+	maxScreenSide = 8192
+)
+
+// ErrScreenShareActive возвращается при повторном запуске демонстрации тому же пиру
+var ErrScreenShareActive = errors.New("демонстрация экрана этому пиру уже идет")
+
+// FrameSource - источник кадров демонстрации. Захват экрана зависит от ОС,
+// поэтому его предоставляет встраивающее приложение
+type FrameSource interface {
+	CaptureFrame() (image.Image, error)
+}
+
+// ScreenShareOptions - параметры демонстрации экрана
+type ScreenShareOptions struct {
+	FPS      int // кадров в секунду (по умолчанию 2)
+	Quality  int // качество JPEG 1-100 (по умолчанию 60)
+	TileSize int // сторона плитки в пикселях (по умолчанию 64)
+}
+
+// ScreenShareState - полезная нагрузка события EventScreenShare
+type ScreenShareState struct {
+	PeerID   peer.ID `json:"peer_id"`
+	Incoming bool    `json:"incoming"`
+	Active   bool    `json:"active"`
+	Error    string  `json:"error,omitempty"`
+}
+
+// ScreenFrame - полезная нагрузка события EventScreenFrame: текущее
+// изображение экрана собеседника и области, изменившиеся в этом кадре
+type ScreenFrame struct {
+	PeerID  peer.ID           `json:"peer_id"`
+	Seq     uint64            `json:"seq"`
+	Image   *image.RGBA       `json:"-"`
+	Changed []image.Rectangle `json:"changed"`
+}
+
+// screenHeader - заголовок кадра на проводе
+type screenHeader struct {
+	Seq    uint64       `json:"seq"`
+	Width  int          `json:"width"`
+	Height int          `json:"height"`
+	Tiles  []screenTile `json:"tiles"`
+}
+
+type screenTile struct {
+	X    int `json:"x"`
+	Y    int `json:"y"`
+	Size int `json:"size"`
+}
+
+// screenShares - активные исходящие демонстрации
+type screenShares struct {
+	mu     sync.Mutex
+	active map[peer.ID]chan struct{}
+}
+
+// StartScreenShare начинает демонстрацию экрана пиру кадрами из source
+func (n *Node) StartScreenShare(peerID peer.ID, source FrameSource, opts ScreenShareOptions) error {
+	if opts.FPS <= 0 {
+		opts.FPS = 2
+	}
+	if opts.Quality <= 0 || opts.Quality > 100 {
+		opts.Quality = 60
+	}
+	if opts.TileSize <= 0 {
+		opts.TileSize = 64
+	}
+
+	n.screens.mu.Lock()
+	if n.screens.active == nil {
+		n.screens.active = make(map[peer.ID]chan struct{})
+	}
+	if _, ok := n.screens.active[peerID]; ok {
+		n.screens.mu.Unlock()
+		return ErrScreenShareActive
+	}
+	stop := make(chan struct{})
+	n.screens.active[peerID] = stop
+	n.screens.mu.Unlock()
+
+	stream, err := n.newStream(n.ctx, peerID, SCREEN_PROTOCOL_ID)
+	if err != nil {
+		n.finishScreenShare(peerID, stop)
+		return fmt.Errorf("не удалось начать демонстрацию экрана: %w", err)
+	}
+
+	n.bumpActivity(peerID, activityScreenShare)
+	n.emit(EventScreenShare, ScreenShareState{PeerID: peerID, Active: true})
+	go n.runScreenShare(peerID, stream, source, opts, stop)
+	return nil
+}
+
+// StopScreenShare останавливает демонстрацию экрана пиру
+func (n *Node) StopScreenShare(peerID peer.ID) error {
+	n.screens.mu.Lock()
+	stop, ok := n.screens.active[peerID]
+	n.screens.mu.Unlock()
+
+	if !ok {
+		return fmt.Errorf("демонстрация экрана пиру %s не идет", peerID.ShortString())
+	}
+	n.finishScreenShare(peerID, stop)
+	return nil
+}
+
+// finishScreenShare снимает демонстрацию с учета; повторный вызов безопасен
+func (n *Node) finishScreenShare(peerID peer.ID, stop chan struct{}) {
+	n.screens.mu.Lock()
+	defer n.screens.mu.Unlock()
+
+	if n.screens.active[peerID] == stop {
+		delete(n.screens.active, peerID)
+		close(stop)
+	}
+}
+
+// runScreenShare захватывает и отправляет кадры до остановки или ошибки
+func (n *Node) runScreenShare(peerID peer.ID, stream network.Stream, source FrameSource, opts ScreenShareOptions, stop chan struct{}) {
+	ticker := time.NewTicker(time.Second / time.Duration(opts.FPS))
+	defer ticker.Stop()
+
+	encoder := &tileEncoder{tileSize: opts.TileSize, quality: opts.Quality}
+	writer := bufio.NewWriter(stream)
+	var shareErr error
+
+loop:
+	for {
+		select {
+		case <-stop:
+			break loop
+		case <-n.ctx.Done():
+			break loop
+		case <-ticker.C:
+		}
+
+		frame, err := source.CaptureFrame()
+		if err != nil {
+			shareErr = fmt.Errorf("захват экрана: %w", err)
+			break
+		}
+
+		stream.SetWriteDeadline(time.Now().Add(screenWriteTimeout))
+		if err := encoder.writeFrame(writer, frame); err != nil {
+			shareErr = err
+			break
+		}
+		if err := writer.Flush(); err != nil {
+			shareErr = err
+			break
+		}
+	}
+
+	if shareErr != nil {
+		stream.Reset()
+		log.Printf("⚠️ Демонстрация экрана %s прервана: %v", peerID.ShortString(), shareErr)
+	} else {
+		stream.Close()
+	}
+	n.finishScreenShare(peerID, stop)
+
+	state := ScreenShareState{PeerID: peerID}
+	if shareErr != nil {
+		state.Error = shareErr.Error()
+	}
+	n.emit(EventScreenShare, state)
+}
+
+// tileEncoder сравнивает кадр с предыдущим по плиткам и кодирует изменившиеся
+type tileEncoder struct {
+	tileSize int
+	quality  int
+
+	seq      uint64
+	hashes   map[image.Point]uint64
+	width    int
+	height   int
+	lastFull time.Time
+	canvas   *image.RGBA
+}
+
+// writeFrame пишет кадр: длина заголовка, заголовок и JPEG изменившихся плиток
+func (e *tileEncoder) writeFrame(w io.Writer, frame image.Image) error {
+	bounds := frame.Bounds()
+	if bounds.Dx() <= 0 || bounds.Dy() <= 0 || bounds.Dx() > maxScreenSide || bounds.Dy() > maxScreenSide {
+		return fmt.Errorf("некорректный размер кадра %dx%d", bounds.Dx(), bounds.Dy())
+	}
+
+	keyframe := bounds.Dx() != e.width || bounds.Dy() != e.height || time.Since(e.lastFull) >= screenKeyframeInterval
+	if keyframe {
+		e.width, e.height = bounds.Dx(), bounds.Dy()
+		e.hashes = make(map[image.Point]uint64)
+		e.canvas = image.NewRGBA(image.Rect(0, 0, e.width, e.height))
+		e.lastFull = time.Now()
+	}
+	draw.Draw(e.canvas, e.canvas.Bounds(), frame, bounds.Min, draw.Src)
+
+	e.seq++
+	header := screenHeader{Seq: e.seq, Width: e.width, Height: e.height}
+	var payload bytes.Buffer
+	for y := 0; y < e.height; y += e.tileSize {
+		for x := 0; x < e.width; x += e.tileSize {
+			rect := image.Rect(x, y, x+e.tileSize, y+e.tileSize).Intersect(e.canvas.Bounds())
+			tile := e.canvas.SubImage(rect).(*image.RGBA)
+
+			origin := image.Pt(x, y)
+			sum := tileHash(tile)
+			if previous, ok := e.hashes[origin]; ok && previous == sum {
+				continue
+			}
+			e.hashes[origin] = sum
+
+			start := payload.Len()
+			if err := jpeg.Encode(&payload, tile, &jpeg.Options{Quality: e.quality}); err != nil {
+				return err
+			}
+			header.Tiles = append(header.Tiles, screenTile{X: x, Y: y, Size: payload.Len() - start})
+		}
+	}
+
+	data, err := json.Marshal(header)
+	if err != nil {
+		return err
+	}
+	if err := binary.Write(w, binary.BigEndian, uint32(len(data))); err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	_, err = w.Write(payload.Bytes())
+	return err
+}
+
+// tileHash - быстрый хеш пикселей плитки для поиска изменений
+func tileHash(tile *image.RGBA) uint64 {
+	h := fnv.New64a()
+	bounds := tile.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		start := tile.PixOffset(bounds.Min.X, y)
+		h.Write(tile.Pix[start : start+bounds.Dx()*4])
+	}
+	return h.Sum64()
+}
+
+// handleScreenStream принимает демонстрацию экрана. Принимаются только
+// контакты: незнакомец не может без спроса показывать изображения
+func (n *Node) handleScreenStream(stream network.Stream) {
+	remote := stream.Conn().RemotePeer()
+	if !n.isKnownContact(remote) {
+		log.Printf("⚠️ Отклонена демонстрация экрана от незнакомого пира %s", remote.ShortString())
+		stream.Reset()
+		return
+	}
+
+	n.bumpActivity(remote, activityScreenShare)
+	n.emit(EventScreenShare, ScreenShareState{PeerID: remote, Incoming: true, Active: true})
+	err := n.readScreenFrames(remote, bufio.NewReader(stream))
+
+	state := ScreenShareState{PeerID: remote, Incoming: true}
+	if err != nil && err != io.EOF {
+		stream.Reset()
+		state.Error = err.Error()
+		log.Printf("⚠️ Демонстрация экрана от %s прервана: %v", remote.ShortString(), err)
+	} else {
+		stream.Close()
+	}
+	n.emit(EventScreenShare, state)
+}
+
+// readScreenFrames собирает кадры из плиток и публикует их событиями
+func (n *Node) readScreenFrames(remote peer.ID, r io.Reader) error {
+	var canvas *image.RGBA
+	for {
+		var headerLen uint32
+		if err := binary.Read(r, binary.BigEndian, &headerLen); err != nil {
+			return err
+		}
+		if headerLen > maxScreenHeader {
+			return fmt.Errorf("заголовок кадра слишком большой")
+		}
+		data := make([]byte, headerLen)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return err
+		}
+
+		var header screenHeader
+		if err := json.Unmarshal(data, &header); err != nil {
+			return fmt.Errorf("некорректный заголовок кадра: %w", err)
+		}
+		if header.Width <= 0 || header.Height <= 0 || header.Width > maxScreenSide || header.Height > maxScreenSide {
+			return fmt.Errorf("некорректный размер кадра %dx%d", header.Width, header.Height)
+		}
+		if canvas == nil || canvas.Bounds().Dx() != header.Width || canvas.Bounds().Dy() != header.Height {
+			canvas = image.NewRGBA(image.Rect(0, 0, header.Width, header.Height))
+		}
+
+		total := 0
+		changed := make([]image.Rectangle, 0, len(header.Tiles))
+		for _, t := range header.Tiles {
+			total += t.Size
+			if t.Size <= 0 || total > maxScreenPayload {
+				return fmt.Errorf("некорректный размер плитки")
+			}
+			tileReader := io.LimitReader(r, int64(t.Size))
+			tile, err := jpeg.Decode(tileReader)
+			if err != nil {
+				return fmt.Errorf("не удалось декодировать плитку: %w", err)
+			}
+			// Декодер может не дочитать хвост плитки
+			if _, err := io.Copy(io.Discard, tileReader); err != nil {
+				return err
+			}
+			rect := tile.Bounds().Sub(tile.Bounds().Min).Add(image.Pt(t.X, t.Y)).Intersect(canvas.Bounds())
+			draw.Draw(canvas, rect, tile, tile.Bounds().Min, draw.Src)
+			changed = append(changed, rect)
+		}
+
+		// Потребитель получает собственную копию кадра
+		snapshot := image.NewRGBA(canvas.Bounds())
+		copy(snapshot.Pix, canvas.Pix)
+		n.emit(EventScreenFrame, ScreenFrame{PeerID: remote, Seq: header.Seq, Image: snapshot, Changed: changed})
+	}
+}
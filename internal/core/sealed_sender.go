@@ -0,0 +1,257 @@
+package core
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p"
+	"github.com/libp2p/go-libp2p/core/crypto"
+	"github.com/libp2p/go-libp2p/core/host"
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/multiformats/go-multiaddr"
+)
+
+// SEALED_PROTOCOL_ID - чат-протокол со скрытым отправителем. Письмо приходит
+// с одноразового PeerID, а настоящий отправитель и его подпись находятся
+// внутри шифротекста
+const SEALED_PROTOCOL_ID = "/owl-whisper/sealed/1.0.0"
+
+const (
+	// sealedTimeout - предельное время отправки одного письма
+	sealedTimeout = 30 * time.Second
+	// sealedIdleTimeout - через сколько простоя закрывается одноразовый узел
+	// для отправки контакту
+	sealedIdleTimeout = 10 * time.Minute
+)
+
+// sealedSender - диалоги со скрытым отправителем и одноразовые узлы для них
+type sealedSender struct {
+	mu      sync.Mutex
+	enabled map[peer.ID]bool
+	routes  map[peer.ID]*sealedRoute
+}
+
+// sealedRoute - одноразовый узел, через который письма уходят одному
+// контакту. У каждого контакта свой, чтобы контакты не могли связать
+// письма между собой
+type sealedRoute struct {
+	host  host.Host
+	timer *time.Timer
+	used  time.Time
+}
+
+// SetSealedSender включает или выключает скрытого отправителя для диалога
+// с пиром. Включенный режим не переходит на обычную отправку: если письмо
+// не удалось передать напрямую, оно оставляется в почтовом ящике контакта,
+// где отправитель тоже скрыт внутри шифротекста. Без ящика сообщение не
+// отправляется
+func (n *Node) SetSealedSender(id peer.ID, enabled bool) {
+	n.sealed.mu.Lock()
+	defer n.sealed.mu.Unlock()
+
+	if !enabled {
+		delete(n.sealed.enabled, id)
+		if route := n.sealed.routes[id]; route != nil {
+			route.timer.Stop()
+			route.host.Close()
+			delete(n.sealed.routes, id)
+		}
+		return
+	}
+	if n.sealed.enabled == nil {
+		n.sealed.enabled = make(map[peer.ID]bool)
+	}
+	n.sealed.enabled[id] = true
+}
+
+// SealedSender сообщает, включен ли скрытый отправитель для диалога с пиром
+func (n *Node) SealedSender(id peer.ID) bool {
+	n.sealed.mu.Lock()
+	defer n.sealed.mu.Unlock()
+
+	return n.sealed.enabled[id]
+}
+
+// SealedSenderReady проверяет, может ли контакт принимать письма со скрытым
+// отправителем: он объявил протокол и прислал запись профиля с ключом для писем
+func (n *Node) SealedSenderReady(id peer.ID) error {
+	caps, err := n.GetPeerCapabilities(id)
+	if err != nil {
+		return err
+	}
+	if !caps.Has(CapabilitySealedSender) {
+		return fmt.Errorf("%s не поддерживает скрытого отправителя", id.ShortString())
+	}
+	if _, ok := n.peerProfile(id); !ok {
+		return fmt.Errorf("%s еще не прислал ключ для писем", id.ShortString())
+	}
+	return nil
+}
+
+// sendSealed отправляет сообщение со скрытым отправителем
+func (n *Node) sendSealed(to peer.ID, text string) error {
+	if err := n.SealedSenderReady(to); err != nil {
+		return err
+	}
+	record, _ := n.peerProfile(to)
+	sealed, err := n.sealLetterTo(record, text)
+	if err != nil {
+		return err
+	}
+
+	addrs := n.host.Peerstore().Addrs(to)
+	if n.config.HideIP {
+		// Одноразовый узел тоже не должен раскрывать IP контакту
+		var relayed []multiaddr.Multiaddr
+		for _, addr := range addrs {
+			if isRelayAddr(addr) {
+				relayed = append(relayed, addr)
+			}
+		}
+		addrs = relayed
+	}
+	if len(addrs) == 0 {
+		return fmt.Errorf("адреса %s неизвестны", to.ShortString())
+	}
+
+	route, err := n.sealedRoute(to)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(n.ctx, sealedTimeout)
+	defer cancel()
+	if err := route.Connect(ctx, peer.AddrInfo{ID: to, Addrs: addrs}); err != nil {
+		return fmt.Errorf("не удалось подключиться к %s: %w", to.ShortString(), err)
+	}
+	stream, err := route.NewStream(ctx, to, SEALED_PROTOCOL_ID)
+	if err != nil {
+		return fmt.Errorf("не удалось открыть поток к %s: %w", to.ShortString(), err)
+	}
+	defer stream.Close()
+	stream.SetDeadline(time.Now().Add(sealedTimeout))
+
+	if _, err := stream.Write(sealed); err != nil {
+		return err
+	}
+	if err := stream.CloseWrite(); err != nil {
+		return err
+	}
+	reply, err := bufio.NewReader(io.LimitReader(stream, 1024)).ReadString('\n')
+	if err != nil {
+		return fmt.Errorf("%s не подтвердил прием: %w", to.ShortString(), err)
+	}
+	if reply = strings.TrimSpace(reply); reply != "ok" {
+		return fmt.Errorf("%s отклонил письмо: %s", to.ShortString(), reply)
+	}
+	return nil
+}
+
+// sealedRoute возвращает одноразовый узел для писем контакту, создавая его
+// при необходимости. Узел закрывается после sealedIdleTimeout простоя
+func (n *Node) sealedRoute(to peer.ID) (host.Host, error) {
+	n.sealed.mu.Lock()
+	defer n.sealed.mu.Unlock()
+
+	if route := n.sealed.routes[to]; route != nil {
+		route.used = time.Now()
+		route.timer.Reset(sealedIdleTimeout)
+		return route.host, nil
+	}
+
+	key, _, err := crypto.GenerateEd25519Key(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	opts := append(transportOptions(),
+		libp2p.Identity(key),
+		libp2p.NoListenAddrs,
+		libp2p.UserAgent(stealthUserAgent),
+	)
+	h, err := libp2p.New(opts...)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось создать одноразовый узел: %w", err)
+	}
+
+	route := &sealedRoute{host: h, used: time.Now()}
+	route.timer = time.AfterFunc(sealedIdleTimeout, func() {
+		n.sealed.mu.Lock()
+		defer n.sealed.mu.Unlock()
+
+		// Узел могли взять снова, пока таймер ждал блокировку
+		if n.sealed.routes[to] != route || time.Since(route.used) < sealedIdleTimeout {
+			return
+		}
+		delete(n.sealed.routes, to)
+		h.Close()
+	})
+	if n.sealed.routes == nil {
+		n.sealed.routes = make(map[peer.ID]*sealedRoute)
+	}
+	n.sealed.routes[to] = route
+	return h, nil
+}
+
+// closeSealedRoutes закрывает все одноразовые узлы
+func (n *Node) closeSealedRoutes() {
+	n.sealed.mu.Lock()
+	defer n.sealed.mu.Unlock()
+
+	for id, route := range n.sealed.routes {
+		route.timer.Stop()
+		route.host.Close()
+		delete(n.sealed.routes, id)
+	}
+}
+
+// handleSealedStream принимает письмо со скрытым отправителем. Транспорт
+// знает только одноразовый PeerID; отправитель проверяется по подписи
+// после расшифровки и должен быть контактом
+func (n *Node) handleSealedStream(stream network.Stream) {
+	defer stream.Close()
+	stream.SetDeadline(time.Now().Add(sealedTimeout))
+
+	sealed, err := io.ReadAll(io.LimitReader(stream, mailboxEnvelopeLimit+1))
+	if err != nil || !validEnvelopeSize(len(sealed)) {
+		stream.Reset()
+		return
+	}
+	key, err := n.mailboxKey()
+	if err != nil {
+		stream.Reset()
+		return
+	}
+	letter, err := n.openLetter(key, sealed)
+	if err != nil {
+		log.Printf("🚫 Письмо со скрытым отправителем отклонено: %v", err)
+		stream.Reset()
+		return
+	}
+	if !n.isKnownContact(letter.From) || n.gater.isBanned(letter.From) {
+		log.Printf("🚫 Письмо со скрытым отправителем от %s не из контактов отклонено", letter.From.ShortString())
+		stream.Reset()
+		return
+	}
+
+	if !n.mailboxDedup.duplicate(letter) {
+		message := MessageEvent{
+			PeerID:   letter.From,
+			Text:     letter.Text,
+			Delivery: DeliverySealed,
+			SentAt:   letter.SentAt,
+		}
+		if !n.dispatchMessage(message) {
+			stream.Reset()
+			return
+		}
+		n.mailboxDedup.remember(letter.ID)
+	}
+	fmt.Fprintln(stream, "ok")
+}
@@ -0,0 +1,105 @@
+package core
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+// securityBufferSize - размер буфера канала событий безопасности
+const securityBufferSize = 64
+
+// SecuritySeverity - важность события безопасности
+type SecuritySeverity string
+
+const (
+	SeverityInfo     SecuritySeverity = "info"
+	SeverityWarning  SecuritySeverity = "warning"
+	SeverityCritical SecuritySeverity = "critical"
+)
+
+// SecurityEventType - тип события безопасности
+type SecurityEventType string
+
+const (
+	// SecurityKeyChanged - ключ известного пира изменился
+	SecurityKeyChanged SecurityEventType = "key_changed"
+	// SecurityVerificationFailed - не прошла проверка подписи, штампа или контрольной суммы
+	SecurityVerificationFailed SecurityEventType = "verification_failed"
+	// SecurityBlockedDial - входящее соединение отклонено ограничителем
+	SecurityBlockedDial SecurityEventType = "blocked_dial"
+	// SecurityPSKMismatch - пир использует другой ключ приватной сети
+	SecurityPSKMismatch SecurityEventType = "psk_mismatch"
+	// SecurityDowngrade - пир пытается согласовать более слабый протокол
+	SecurityDowngrade SecurityEventType = "downgrade_attempt"
+	// SecurityDangerousFile - получен файл, не прошедший проверку безопасности
+	SecurityDangerousFile SecurityEventType = "dangerous_file"
+	// SecurityIdentityDestroyed - контакт уничтожил свой ключ личности
+	SecurityIdentityDestroyed SecurityEventType = "identity_destroyed"
+	// SecurityAdminDenied - команда управления узлом от пира без прав
+	SecurityAdminDenied SecurityEventType = "admin_denied"
+)
+
+// SecurityEvent - событие безопасности для аудита
+type SecurityEvent struct {
+	Type      SecurityEventType `json:"type"`
+	Severity  SecuritySeverity  `json:"severity"`
+	Timestamp time.Time         `json:"timestamp"`
+	PeerID    peer.ID           `json:"peer_id,omitempty"`
+	Addr      string            `json:"addr,omitempty"`
+	Detail    string            `json:"detail"`
+}
+
+// AuditSink сохраняет события безопасности (например, в журнал аудита)
+type AuditSink func(event SecurityEvent) error
+
+// securityEvents - отдельный от Events канал событий безопасности
+type securityEvents struct {
+	ch chan SecurityEvent
+
+	mu   sync.Mutex
+	sink AuditSink
+}
+
+// SecurityEvents возвращает канал событий безопасности. В отличие от Events,
+// он не смешивается с потоком сообщений и не зависит от его потребителя
+func (n *Node) SecurityEvents() <-chan SecurityEvent {
+	return n.security.ch
+}
+
+// SetAuditSink задает хранилище событий безопасности. Каждое событие
+// записывается до публикации в канал, поэтому не теряется при его переполнении
+func (n *Node) SetAuditSink(sink AuditSink) {
+	n.security.mu.Lock()
+	n.security.sink = sink
+	n.security.mu.Unlock()
+}
+
+// emitSecurity записывает событие в журнал и публикует его, не блокируя ядро
+func (n *Node) emitSecurity(eventType SecurityEventType, severity SecuritySeverity, peerID peer.ID, addr, detail string) {
+	event := SecurityEvent{
+		Type:      eventType,
+		Severity:  severity,
+		Timestamp: time.Now(),
+		PeerID:    peerID,
+		Addr:      addr,
+		Detail:    detail,
+	}
+
+	n.security.mu.Lock()
+	sink := n.security.sink
+	if sink != nil {
+		if err := sink(event); err != nil {
+			log.Printf("⚠️ Не удалось записать событие безопасности в журнал: %v", err)
+		}
+	}
+	n.security.mu.Unlock()
+
+	select {
+	case n.security.ch <- event:
+	default:
+		log.Printf("⚠️ Буфер событий безопасности переполнен, событие %s отброшено", eventType)
+	}
+}
@@ -0,0 +1,195 @@
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/multiformats/go-multiaddr"
+	manet "github.com/multiformats/go-multiaddr/net"
+)
+
+// Роли сервера в карточке: в какие списки клиента его добавить
+const (
+	// ServerRoleRelay - ретранслятор (network.relay_nodes)
+	ServerRoleRelay = "relay"
+	// ServerRoleBootstrap - узел начальной загрузки DHT (network.bootstrap_nodes)
+	ServerRoleBootstrap = "bootstrap"
+)
+
+const (
+	// serverCardMaxAddrs - сколько адресов попадает в карточку
+	serverCardMaxAddrs = 16
+	// serverCardOperatorLimit - максимальная длина контакта оператора
+	serverCardOperatorLimit = 256
+	// ServerCardLimit - максимальный размер файла карточки
+	ServerCardLimit = 64 * 1024
+)
+
+var (
+	// ErrServerCardSignature - подпись карточки сервера не прошла проверку
+	ErrServerCardSignature = errors.New("подпись карточки сервера недействительна")
+
+	// ErrNoPublicAddrs - у узла пока нет публичных адресов для карточки
+	ErrNoPublicAddrs = errors.New("у узла нет публичных адресов")
+
+	// ErrRelayServiceDisabled - в карточке роль relay, но узел не ретранслирует
+	ErrRelayServiceDisabled = errors.New("служба ретрансляции узла выключена")
+)
+
+// ServerCard - подписанная ключом узла визитка своего сервера (ретранслятора
+// или bootstrap-узла): клиенты импортируют ее, чтобы добавить сервер в свои
+// списки за один шаг. Подпись проверяется по ключу в PeerID
+type ServerCard struct {
+	PeerID    peer.ID   `json:"peer_id"`
+	Addrs     []string  `json:"addrs"`
+	Roles     []string  `json:"roles"`
+	Operator  string    `json:"operator,omitempty"`
+	IssuedAt  time.Time `json:"issued_at"`
+	Signature []byte    `json:"signature"`
+}
+
+// statement возвращает подписываемый текст карточки
+func (c ServerCard) statement() []byte {
+	return []byte(fmt.Sprintf("owl-whisper server card\npeer:%s\naddrs:%s\nroles:%s\noperator:%s\nissued:%s\n",
+		c.PeerID, strings.Join(c.Addrs, " "), strings.Join(c.Roles, " "), c.Operator,
+		c.IssuedAt.UTC().Format(time.RFC3339)))
+}
+
+// Encode возвращает карточку в виде JSON для файла или сообщения
+func (c ServerCard) Encode() []byte {
+	data, _ := json.MarshalIndent(c, "", "  ")
+	return append(data, '\n')
+}
+
+// DecodeServerCard разбирает и проверяет карточку сервера
+func DecodeServerCard(data []byte) (ServerCard, error) {
+	if len(data) > ServerCardLimit {
+		return ServerCard{}, fmt.Errorf("карточка сервера больше %d байт", ServerCardLimit)
+	}
+	var card ServerCard
+	if err := json.Unmarshal(data, &card); err != nil {
+		return ServerCard{}, fmt.Errorf("некорректная карточка сервера: %w", err)
+	}
+	if err := VerifyServerCard(card); err != nil {
+		return ServerCard{}, err
+	}
+	return card, nil
+}
+
+// VerifyServerCard проверяет содержимое карточки и подпись ключом PeerID
+func VerifyServerCard(card ServerCard) error {
+	if err := checkServerRoles(card.Roles); err != nil {
+		return err
+	}
+	if len(card.Addrs) == 0 || len(card.Addrs) > serverCardMaxAddrs {
+		return fmt.Errorf("в карточке должно быть от 1 до %d адресов", serverCardMaxAddrs)
+	}
+	for _, s := range card.Addrs {
+		if _, err := multiaddr.NewMultiaddr(s); err != nil {
+			return fmt.Errorf("некорректный адрес в карточке %q: %w", s, err)
+		}
+	}
+	if len(card.Operator) > serverCardOperatorLimit {
+		return fmt.Errorf("контакт оператора длиннее %d байт", serverCardOperatorLimit)
+	}
+
+	pub, err := card.PeerID.ExtractPublicKey()
+	if err != nil {
+		return fmt.Errorf("не удалось извлечь ключ из PeerID: %w", err)
+	}
+	ok, err := pub.Verify(card.statement(), card.Signature)
+	if err != nil || !ok {
+		return ErrServerCardSignature
+	}
+	return nil
+}
+
+// P2pAddrs возвращает адреса сервера вида /ip4/.../p2p/<PeerID> для
+// списков relay_nodes и bootstrap_nodes
+func (c ServerCard) P2pAddrs() []string {
+	suffix := "/p2p/" + c.PeerID.String()
+	addrs := make([]string, 0, len(c.Addrs))
+	for _, addr := range c.Addrs {
+		addrs = append(addrs, addr+suffix)
+	}
+	return addrs
+}
+
+// HasRole проверяет, заявлена ли роль в карточке
+func (c ServerCard) HasRole(role string) bool {
+	for _, r := range c.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// CreateServerCard подписывает карточку узла с его публичными адресами.
+// Адреса ретрансляторов и локальной сети в карточку не попадают: клиенты
+// должны подключаться к серверу напрямую
+func (n *Node) CreateServerCard(roles []string, operator string) (ServerCard, error) {
+	if err := checkServerRoles(roles); err != nil {
+		return ServerCard{}, err
+	}
+	// Карточка не должна обещать ретрансляцию, которую узел не выполняет
+	if slices.Contains(roles, ServerRoleRelay) && !n.config.RelayService {
+		return ServerCard{}, ErrRelayServiceDisabled
+	}
+	operator = strings.TrimSpace(operator)
+	if len(operator) > serverCardOperatorLimit {
+		return ServerCard{}, fmt.Errorf("контакт оператора длиннее %d байт", serverCardOperatorLimit)
+	}
+
+	card := ServerCard{
+		PeerID:   n.host.ID(),
+		Roles:    roles,
+		Operator: operator,
+		IssuedAt: time.Now().UTC().Truncate(time.Second),
+	}
+	for _, addr := range n.host.Addrs() {
+		if len(card.Addrs) >= serverCardMaxAddrs {
+			break
+		}
+		if _, err := addr.ValueForProtocol(multiaddr.P_CIRCUIT); err == nil {
+			continue
+		}
+		if manet.IsPublicAddr(addr) {
+			card.Addrs = append(card.Addrs, addr.String())
+		}
+	}
+	if len(card.Addrs) == 0 {
+		return ServerCard{}, ErrNoPublicAddrs
+	}
+
+	key := n.host.Peerstore().PrivKey(n.host.ID())
+	if key == nil {
+		return ServerCard{}, fmt.Errorf("закрытый ключ узла недоступен")
+	}
+	signature, err := key.Sign(card.statement())
+	if err != nil {
+		return ServerCard{}, fmt.Errorf("не удалось подписать карточку сервера: %w", err)
+	}
+	card.Signature = signature
+	return card, nil
+}
+
+// checkServerRoles проверяет, что роли заданы и известны
+func checkServerRoles(roles []string) error {
+	if len(roles) == 0 {
+		return fmt.Errorf("в карточке сервера не указаны роли")
+	}
+	for _, role := range roles {
+		switch role {
+		case ServerRoleRelay, ServerRoleBootstrap:
+		default:
+			return fmt.Errorf("неизвестная роль сервера: %s", role)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,170 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+// STATUS_PROTOCOL_ID - протокол рассылки статуса ("в отпуске") контактам
+// при его изменении. При подключении статус забирается протоколом присутствия
+const STATUS_PROTOCOL_ID = "/owl-whisper/status/1.0.0"
+
+const (
+	// maxStatusLength - максимальная длина статуса в символах
+	maxStatusLength = 140
+	// statusTimeout - предельное время отправки статуса одному контакту
+	statusTimeout = 10 * time.Second
+)
+
+// StatusMessage - статус пира; полезная нагрузка EventStatusChanged.
+// Пустой Text означает, что статус снят
+type StatusMessage struct {
+	PeerID    peer.ID   `json:"peer_id"`
+	Text      string    `json:"text,omitempty"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// peerStatuses - свой статус и статусы контактов
+type peerStatuses struct {
+	mu    sync.RWMutex
+	own   StatusMessage
+	peers map[peer.ID]StatusMessage
+}
+
+// SetStatusMessage задает свой статус и рассылает его подключенным контактам.
+// Статус не связан с именем профиля и виден только контактам
+func (n *Node) SetStatusMessage(text string) error {
+	text = strings.TrimSpace(text)
+	if len([]rune(text)) > maxStatusLength {
+		return fmt.Errorf("статус длиннее %d символов", maxStatusLength)
+	}
+
+	n.statuses.mu.Lock()
+	if n.statuses.own.Text == text && !n.statuses.own.UpdatedAt.IsZero() {
+		n.statuses.mu.Unlock()
+		return nil
+	}
+	n.statuses.own = StatusMessage{PeerID: n.host.ID(), Text: text, UpdatedAt: time.Now()}
+	status := n.statuses.own
+	n.statuses.mu.Unlock()
+
+	for _, id := range n.host.Network().Peers() {
+		if !n.isKnownContact(id) {
+			continue
+		}
+		go func(id peer.ID) {
+			if err := n.pushStatus(id, status); err != nil {
+				log.Printf("⚠️ Не удалось отправить статус %s: %v", id.ShortString(), err)
+			}
+		}(id)
+	}
+	return nil
+}
+
+// StatusMessage возвращает свой статус
+func (n *Node) StatusMessage() string {
+	n.statuses.mu.RLock()
+	defer n.statuses.mu.RUnlock()
+
+	return n.statuses.own.Text
+}
+
+// PeerStatus возвращает последний известный статус контакта
+func (n *Node) PeerStatus(id peer.ID) (StatusMessage, bool) {
+	n.statuses.mu.RLock()
+	defer n.statuses.mu.RUnlock()
+
+	status, ok := n.statuses.peers[id]
+	return status, ok
+}
+
+// statusFor возвращает свой статус, если его можно показать пиру remote
+func (n *Node) statusFor(remote peer.ID) (StatusMessage, bool) {
+	if !n.isKnownContact(remote) {
+		return StatusMessage{}, false
+	}
+
+	n.statuses.mu.RLock()
+	defer n.statuses.mu.RUnlock()
+
+	return n.statuses.own, !n.statuses.own.UpdatedAt.IsZero()
+}
+
+// pushStatus отправляет статус контакту
+func (n *Node) pushStatus(id peer.ID, status StatusMessage) error {
+	stream, err := n.newStream(n.ctx, id, STATUS_PROTOCOL_ID)
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+	stream.SetDeadline(time.Now().Add(statusTimeout))
+
+	return json.NewEncoder(stream).Encode(status)
+}
+
+// handleStatusStream принимает статус контакта
+func (n *Node) handleStatusStream(stream network.Stream) {
+	defer stream.Close()
+	remotePeer := stream.Conn().RemotePeer()
+	stream.SetDeadline(time.Now().Add(statusTimeout))
+
+	if !n.isKnownContact(remotePeer) {
+		stream.Reset()
+		return
+	}
+
+	var status StatusMessage
+	if err := json.NewDecoder(io.LimitReader(stream, 4096)).Decode(&status); err != nil {
+		stream.Reset()
+		return
+	}
+	n.updatePeerStatus(remotePeer, status)
+}
+
+// updatePeerStatus запоминает статус пира и сообщает об изменении.
+// Более старые статусы, пришедшие с опозданием, игнорируются
+func (n *Node) updatePeerStatus(id peer.ID, status StatusMessage) {
+	status.PeerID = id
+	status.Text = strings.TrimSpace(status.Text)
+	if runes := []rune(status.Text); len(runes) > maxStatusLength {
+		status.Text = string(runes[:maxStatusLength])
+	}
+
+	n.statuses.mu.Lock()
+	previous, known := n.statuses.peers[id]
+	if known && (!status.UpdatedAt.After(previous.UpdatedAt) || previous.Text == status.Text) {
+		n.statuses.mu.Unlock()
+		return
+	}
+	if n.statuses.peers == nil {
+		n.statuses.peers = make(map[peer.ID]StatusMessage)
+	}
+	n.statuses.peers[id] = status
+	n.statuses.mu.Unlock()
+
+	// Первый пустой статус - не изменение, а его отсутствие
+	if !known && status.Text == "" {
+		return
+	}
+	n.emit(EventStatusChanged, status)
+}
+
+// fetchStatus забирает статус подключившегося контакта протоколом присутствия
+func (n *Node) fetchStatus(id peer.ID) {
+	if !n.isKnownContact(id) {
+		return
+	}
+	presence, err := n.RequestPresence(id)
+	if err != nil || presence.Status == nil {
+		return
+	}
+	n.updatePeerStatus(id, *presence.Status)
+}
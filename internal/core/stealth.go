@@ -0,0 +1,218 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/core/protocol"
+)
+
+// STEALTH_PROTOCOL_ID - единственный протокол, который узел в скрытом режиме
+// объявляет через identify. Имя намеренно не связано с OwlWhisper: через него
+// открываются потоки остальных протоколов после рукопожатия
+const STEALTH_PROTOCOL_ID = "/session/1.0.0"
+
+const (
+	// stealthTimeout - предельное время рукопожатия скрытого режима
+	stealthTimeout = 10 * time.Second
+	// stealthList - запрос списка скрытых протоколов
+	stealthList = "ls"
+	// maxStealthLine - максимальная длина строки рукопожатия
+	maxStealthLine = 256
+	// stealthUserAgent - строка клиента в скрытом режиме: как у узла на
+	// go-libp2p без собственной строки
+	stealthUserAgent = "github.com/libp2p/go-libp2p"
+)
+
+// Ответы на запрос протокола в рукопожатии
+const (
+	stealthAccepted = "ok"
+	stealthRejected = "na"
+)
+
+// stealthProtocols - протоколы OwlWhisper, скрытые от identify в скрытом режиме
+type stealthProtocols struct {
+	mu       sync.RWMutex
+	enabled  bool
+	handlers map[protocol.ID]network.StreamHandler
+}
+
+// tunneledStream - поток протокола, открытый через рукопожатие скрытого режима
+type tunneledStream struct {
+	network.Stream
+	protocol protocol.ID
+}
+
+// Protocol возвращает протокол, запрошенный в рукопожатии
+func (s *tunneledStream) Protocol() protocol.ID {
+	return s.protocol
+}
+
+// setStreamHandler регистрирует обработчик протокола OwlWhisper. В скрытом
+// режиме протокол не попадает в identify и доступен только через рукопожатие
+func (n *Node) setStreamHandler(id protocol.ID, handler network.StreamHandler) {
+	n.stealth.mu.Lock()
+	defer n.stealth.mu.Unlock()
+
+	if !n.stealth.enabled {
+		n.host.SetStreamHandler(id, handler)
+		return
+	}
+	if n.stealth.handlers == nil {
+		n.stealth.handlers = make(map[protocol.ID]network.StreamHandler)
+	}
+	n.stealth.handlers[id] = handler
+}
+
+// IsStealth сообщает, скрывает ли узел свои протоколы от наблюдателей
+func (n *Node) IsStealth() bool {
+	n.stealth.mu.RLock()
+	defer n.stealth.mu.RUnlock()
+
+	return n.stealth.enabled
+}
+
+// newStream открывает поток протокола OwlWhisper к пиру. Короткие служебные
+// протоколы идут по самому быстрому из нескольких соединений (см. multipath.go).
+// Если пир в скрытом режиме (объявляет только STEALTH_PROTOCOL_ID), поток
+// открывается через рукопожатие
+func (n *Node) newStream(ctx context.Context, id peer.ID, pid protocol.ID) (network.Stream, error) {
+	// С собеседником соединяемся по глобальной политике ретрансляции
+	n.transportPolicy.talk(id)
+	if stream, ok := n.multipathStream(ctx, id, pid); ok {
+		return stream, nil
+	}
+	if !n.peerIsStealth(id) {
+		return n.host.NewStream(ctx, id, pid)
+	}
+
+	stream, err := n.host.NewStream(ctx, id, STEALTH_PROTOCOL_ID)
+	if err != nil {
+		return nil, err
+	}
+	return stealthHandshake(stream, pid)
+}
+
+// stealthHandshake запрашивает протокол pid в открытом потоке рукопожатия
+func stealthHandshake(stream network.Stream, pid protocol.ID) (network.Stream, error) {
+	stream.SetDeadline(time.Now().Add(stealthTimeout))
+	if _, err := stream.Write([]byte(string(pid) + "\n")); err != nil {
+		stream.Reset()
+		return nil, err
+	}
+	reply, err := readStealthLine(stream)
+	if err != nil {
+		stream.Reset()
+		return nil, err
+	}
+	if reply != stealthAccepted {
+		stream.Reset()
+		return nil, fmt.Errorf("пир %s не поддерживает протокол %s", stream.Conn().RemotePeer().ShortString(), pid)
+	}
+	// Дедлайн рукопожатия не должен ограничивать сам протокол
+	stream.SetDeadline(time.Time{})
+	return &tunneledStream{Stream: stream, protocol: pid}, nil
+}
+
+// peerIsStealth сообщает, объявляет ли пир протокол скрытого режима
+func (n *Node) peerIsStealth(id peer.ID) bool {
+	supported, err := n.host.Peerstore().SupportsProtocols(id, STEALTH_PROTOCOL_ID)
+	return err == nil && len(supported) > 0
+}
+
+// stealthProtocolsOf запрашивает у пира в скрытом режиме список его протоколов
+func (n *Node) stealthProtocolsOf(id peer.ID) ([]protocol.ID, error) {
+	ctx, cancel := context.WithTimeout(n.ctx, stealthTimeout)
+	defer cancel()
+
+	stream, err := n.host.NewStream(ctx, id, STEALTH_PROTOCOL_ID)
+	if err != nil {
+		return nil, err
+	}
+	defer stream.Close()
+	stream.SetDeadline(time.Now().Add(stealthTimeout))
+
+	if _, err := stream.Write([]byte(stealthList + "\n")); err != nil {
+		return nil, err
+	}
+	var protocols []protocol.ID
+	if err := json.NewDecoder(io.LimitReader(stream, 16*1024)).Decode(&protocols); err != nil {
+		return nil, err
+	}
+	return protocols, nil
+}
+
+// handleStealthStream выполняет рукопожатие скрытого режима: отдает список
+// протоколов или передает поток обработчику запрошенного протокола. Оба
+// доступны только контактам, чья личность подтверждена ключом соединения;
+// остальным узел отвечает так же, как на неизвестный протокол, и не выдает,
+// что это OwlWhisper. Поэтому незнакомые пиры не могут прислать запрос на
+// добавление или письмо узлу в скрытом режиме
+func (n *Node) handleStealthStream(stream network.Stream) {
+	stream.SetDeadline(time.Now().Add(stealthTimeout))
+
+	request, err := readStealthLine(stream)
+	if err != nil {
+		stream.Reset()
+		return
+	}
+	if !n.isKnownContact(stream.Conn().RemotePeer()) {
+		if request == stealthList {
+			stream.Write([]byte("null\n"))
+		} else {
+			stream.Write([]byte(stealthRejected + "\n"))
+		}
+		stream.Close()
+		return
+	}
+
+	n.stealth.mu.RLock()
+	handler, ok := n.stealth.handlers[protocol.ID(request)]
+	var protocols []protocol.ID
+	if request == stealthList {
+		for id := range n.stealth.handlers {
+			protocols = append(protocols, id)
+		}
+	}
+	n.stealth.mu.RUnlock()
+
+	if request == stealthList {
+		json.NewEncoder(stream).Encode(protocols)
+		stream.Close()
+		return
+	}
+	if !ok {
+		stream.Write([]byte(stealthRejected + "\n"))
+		stream.Close()
+		return
+	}
+	if _, err := stream.Write([]byte(stealthAccepted + "\n")); err != nil {
+		stream.Reset()
+		return
+	}
+	stream.SetDeadline(time.Time{})
+	handler(&tunneledStream{Stream: stream, protocol: protocol.ID(request)})
+}
+
+// readStealthLine читает одну строку рукопожатия побайтно, чтобы не захватить
+// данные протокола, идущие следом
+func readStealthLine(r io.Reader) (string, error) {
+	line := make([]byte, 0, 64)
+	b := make([]byte, 1)
+	for len(line) < maxStealthLine {
+		if _, err := io.ReadFull(r, b); err != nil {
+			return "", err
+		}
+		if b[0] == '\n' {
+			return string(line), nil
+		}
+		line = append(line, b[0])
+	}
+	return "", fmt.Errorf("слишком длинная строка рукопожатия")
+}
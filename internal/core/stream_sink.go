@@ -0,0 +1,136 @@
+package core
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+// streamProgressStep - как часто (в байтах) публиковать EventStreamProgress
+const streamProgressStep = 1 << 20
+
+// StreamProgress - полезная нагрузка события EventStreamProgress
+type StreamProgress struct {
+	StreamID uint64  `json:"stream_id"`
+	PeerID   peer.ID `json:"peer_id"`
+	Bytes    uint64  `json:"bytes"`
+	Done     bool    `json:"done"`
+	Error    string  `json:"error,omitempty"`
+}
+
+// streamSink - получатель данных потока, в который ядро пишет напрямую
+type streamSink struct {
+	writer       io.Writer
+	file         *os.File
+	written      uint64
+	lastProgress uint64
+}
+
+// SetStreamSink направляет входящие данные потока напрямую в writer.
+// Вместо EventStreamData публикуются только EventStreamProgress.
+// Данные, прочитанные до вызова, уже были отданы событиями EventStreamData
+func (n *Node) SetStreamSink(streamID uint64, writer io.Writer) error {
+	return n.setStreamSink(streamID, &streamSink{writer: writer})
+}
+
+// SetStreamSinkFile создает файл path и пишет в него входящие данные потока.
+// Файл закрывается ядром при закрытии потока
+func (n *Node) SetStreamSinkFile(streamID uint64, path string) error {
+	if _, ok := n.streams.get(streamID); !ok {
+		return fmt.Errorf("поток #%d: %w", streamID, ErrStreamNotFound)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("не удалось создать директорию для %s: %w", path, err)
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("не удалось создать файл %s: %w", path, err)
+	}
+
+	if err := n.setStreamSink(streamID, &streamSink{writer: file, file: file}); err != nil {
+		file.Close()
+		os.Remove(path)
+		return err
+	}
+	return nil
+}
+
+// setStreamSink привязывает получателя к потоку
+func (n *Node) setStreamSink(streamID uint64, sink *streamSink) error {
+	ms, ok := n.streams.get(streamID)
+	if !ok {
+		return fmt.Errorf("поток #%d: %w", streamID, ErrStreamNotFound)
+	}
+
+	ms.sinkMu.Lock()
+	defer ms.sinkMu.Unlock()
+
+	if ms.sink != nil {
+		return fmt.Errorf("для потока #%d получатель уже задан", streamID)
+	}
+	ms.sink = sink
+	return nil
+}
+
+// deliverStreamData отдает прочитанные данные получателю или событием.
+// Возвращает false, если чтение потока нужно прекратить
+func (n *Node) deliverStreamData(ms *managedStream, remotePeer peer.ID, data []byte) bool {
+	ms.sinkMu.Lock()
+	sink := ms.sink
+	if sink == nil {
+		ms.sinkMu.Unlock()
+
+		// Буфер чтения переиспользуется, поэтому событию отдаем копию
+		payload := append([]byte(nil), data...)
+		return n.emitBlocking(EventStreamData, StreamData{StreamID: ms.id, PeerID: remotePeer, Data: payload})
+	}
+	defer ms.sinkMu.Unlock()
+
+	if _, err := sink.writer.Write(data); err != nil {
+		log.Printf("⚠️ Не удалось записать данные потока #%d получателю: %v", ms.id, err)
+		n.emit(EventStreamProgress, StreamProgress{
+			StreamID: ms.id,
+			PeerID:   remotePeer,
+			Bytes:    sink.written,
+			Done:     true,
+			Error:    err.Error(),
+		})
+		ms.sink = nil
+		if sink.file != nil {
+			sink.file.Close()
+		}
+		return false
+	}
+
+	sink.written += uint64(len(data))
+	if sink.written-sink.lastProgress >= streamProgressStep {
+		sink.lastProgress = sink.written
+		n.emit(EventStreamProgress, StreamProgress{StreamID: ms.id, PeerID: remotePeer, Bytes: sink.written})
+	}
+	return true
+}
+
+// releaseSink завершает работу получателя при закрытии потока
+func (n *Node) releaseSink(ms *managedStream, remotePeer peer.ID) {
+	ms.sinkMu.Lock()
+	sink := ms.sink
+	ms.sink = nil
+	ms.sinkMu.Unlock()
+
+	if sink == nil {
+		return
+	}
+
+	progress := StreamProgress{StreamID: ms.id, PeerID: remotePeer, Bytes: sink.written, Done: true}
+	if sink.file != nil {
+		if err := sink.file.Close(); err != nil {
+			progress.Error = err.Error()
+		}
+	}
+	n.emit(EventStreamProgress, progress)
+}
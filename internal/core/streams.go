@@ -0,0 +1,401 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+// STREAM_PROTOCOL_ID - протокол для произвольных потоков данных (передача файлов и т.п.)
+const STREAM_PROTOCOL_ID = "/owl-whisper/stream/1.0.0"
+
+// streamReadBufferSize - размер буфера чтения входящих данных потока
+const streamReadBufferSize = 32 * 1024
+
+// streamSendQueueLen - максимальное число блоков в очереди отправки потока
+const streamSendQueueLen = 1024
+
+var (
+	// ErrStreamNotFound возвращается для неизвестного или уже закрытого ID потока
+	ErrStreamNotFound = errors.New("поток не найден")
+
+	// ErrWouldBlock возвращается TryWriteStream, если буфер отправки заполнен
+	ErrWouldBlock = errors.New("буфер отправки заполнен")
+
+	// ErrStreamWriteTimeout возвращается, если запись не уложилась в дедлайн
+	ErrStreamWriteTimeout = errors.New("превышено время записи в поток")
+)
+
+// StreamStats - метрики буфера отправки потока для адаптации окна передачи
+type StreamStats struct {
+	StreamID         uint64        `json:"stream_id"`
+	QueuedBytes      int           `json:"queued_bytes"`
+	QueuedChunks     int           `json:"queued_chunks"`
+	BufferSize       int           `json:"buffer_size"`
+	BytesWritten     uint64        `json:"bytes_written"`
+	WriteTimeouts    uint64        `json:"write_timeouts"`
+	LastWriteLatency time.Duration `json:"last_write_latency"`
+}
+
+// StreamInfo - полезная нагрузка событий EventStreamOpened и EventStreamClosed
+type StreamInfo struct {
+	StreamID uint64  `json:"stream_id"`
+	PeerID   peer.ID `json:"peer_id"`
+	Incoming bool    `json:"incoming"`
+}
+
+// StreamData - полезная нагрузка события EventStreamData
+type StreamData struct {
+	StreamID uint64  `json:"stream_id"`
+	PeerID   peer.ID `json:"peer_id"`
+	Data     []byte  `json:"data"`
+}
+
+// managedStream - поток, зарегистрированный в реестре узла. Запись идет
+// через ограниченную очередь, которую разгребает отдельная горутина
+type managedStream struct {
+	id       uint64
+	stream   network.Stream
+	incoming bool
+
+	sendQueue  chan []byte
+	spaceFreed chan struct{}
+	writerDone chan struct{}
+
+	mu               sync.Mutex
+	sendClosed       bool
+	writeErr         error
+	queuedBytes      int
+	bytesWritten     uint64
+	writeTimeouts    uint64
+	lastWriteLatency time.Duration
+
+	sinkMu sync.Mutex
+	sink   *streamSink
+}
+
+// streamRegistry выдает потокам числовые ID, удобные для внешних API
+type streamRegistry struct {
+	mu      sync.Mutex
+	nextID  uint64
+	streams map[uint64]*managedStream
+}
+
+// newStreamRegistry создает пустой реестр потоков
+func newStreamRegistry() *streamRegistry {
+	return &streamRegistry{
+		streams: make(map[uint64]*managedStream),
+	}
+}
+
+// add регистрирует поток и возвращает присвоенный ID
+func (r *streamRegistry) add(stream network.Stream, incoming bool) *managedStream {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.nextID++
+	ms := &managedStream{
+		id:         r.nextID,
+		stream:     stream,
+		incoming:   incoming,
+		sendQueue:  make(chan []byte, streamSendQueueLen),
+		spaceFreed: make(chan struct{}, 1),
+		writerDone: make(chan struct{}),
+	}
+	r.streams[ms.id] = ms
+	return ms
+}
+
+// get возвращает поток по ID
+func (r *streamRegistry) get(id uint64) (*managedStream, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ms, ok := r.streams[id]
+	return ms, ok
+}
+
+// remove убирает поток из реестра, возвращая false, если его уже нет
+func (r *streamRegistry) remove(id uint64) (*managedStream, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ms, ok := r.streams[id]
+	if ok {
+		delete(r.streams, id)
+	}
+	return ms, ok
+}
+
+// closeAll закрывает и удаляет все потоки
+func (r *streamRegistry) closeAll() {
+	r.mu.Lock()
+	streams := r.streams
+	r.streams = make(map[uint64]*managedStream)
+	r.mu.Unlock()
+
+	for _, ms := range streams {
+		ms.stream.Reset()
+		ms.closeSend()
+	}
+}
+
+// tryEnqueue ставит данные в очередь отправки, если для них есть место
+func (ms *managedStream) tryEnqueue(data []byte, bufferSize int) (bool, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	if ms.writeErr != nil {
+		return false, ms.writeErr
+	}
+	if ms.sendClosed {
+		return false, ErrStreamNotFound
+	}
+
+	// Блок больше всего буфера пропускаем только в пустую очередь
+	if ms.queuedBytes > 0 && ms.queuedBytes+len(data) > bufferSize {
+		return false, nil
+	}
+
+	select {
+	case ms.sendQueue <- data:
+		ms.queuedBytes += len(data)
+		return true, nil
+	default:
+		return false, nil
+	}
+}
+
+// closeSend закрывает очередь отправки; уже поставленные данные будут дописаны
+func (ms *managedStream) closeSend() {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	if !ms.sendClosed {
+		ms.sendClosed = true
+		close(ms.sendQueue)
+	}
+}
+
+// stats возвращает текущие метрики потока
+func (ms *managedStream) stats(bufferSize int) StreamStats {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	return StreamStats{
+		StreamID:         ms.id,
+		QueuedBytes:      ms.queuedBytes,
+		QueuedChunks:     len(ms.sendQueue),
+		BufferSize:       bufferSize,
+		BytesWritten:     ms.bytesWritten,
+		WriteTimeouts:    ms.writeTimeouts,
+		LastWriteLatency: ms.lastWriteLatency,
+	}
+}
+
+// OpenStream открывает поток данных к пиру и возвращает его ID
+func (n *Node) OpenStream(peerID peer.ID) (uint64, error) {
+	stream, err := n.newStream(n.ctx, peerID, STREAM_PROTOCOL_ID)
+	if err != nil {
+		return 0, fmt.Errorf("не удалось открыть поток к %s: %w", peerID.ShortString(), err)
+	}
+
+	ms := n.streams.add(stream, false)
+	n.bumpActivity(peerID, activityStream)
+	n.emit(EventStreamOpened, StreamInfo{StreamID: ms.id, PeerID: peerID})
+	go n.writeLoop(ms)
+	go n.readStream(ms)
+
+	log.Printf("📂 Открыт поток #%d к %s", ms.id, peerID.ShortString())
+	return ms.id, nil
+}
+
+// WriteStream ставит данные в очередь отправки потока. Если буфер заполнен,
+// ждет освобождения места не дольше NodeConfig.StreamWriteTimeout
+func (n *Node) WriteStream(streamID uint64, data []byte) error {
+	ms, ok := n.streams.get(streamID)
+	if !ok {
+		return fmt.Errorf("поток #%d: %w", streamID, ErrStreamNotFound)
+	}
+
+	// Вызывающий может переиспользовать свой буфер сразу после возврата
+	chunk := append([]byte(nil), data...)
+
+	var deadline <-chan time.Time
+	if n.config.StreamWriteTimeout > 0 {
+		timer := time.NewTimer(n.config.StreamWriteTimeout)
+		defer timer.Stop()
+		deadline = timer.C
+	}
+
+	for {
+		queued, err := ms.tryEnqueue(chunk, n.config.StreamSendBufferSize)
+		if err != nil {
+			return fmt.Errorf("не удалось записать в поток #%d: %w", streamID, err)
+		}
+		if queued {
+			return nil
+		}
+
+		select {
+		case <-ms.spaceFreed:
+		case <-deadline:
+			return fmt.Errorf("поток #%d: %w", streamID, ErrStreamWriteTimeout)
+		case <-n.ctx.Done():
+			return n.ctx.Err()
+		}
+	}
+}
+
+// TryWriteStream ставит данные в очередь без ожидания. Возвращает
+// ErrWouldBlock, если в буфере отправки сейчас нет места
+func (n *Node) TryWriteStream(streamID uint64, data []byte) error {
+	ms, ok := n.streams.get(streamID)
+	if !ok {
+		return fmt.Errorf("поток #%d: %w", streamID, ErrStreamNotFound)
+	}
+
+	queued, err := ms.tryEnqueue(append([]byte(nil), data...), n.config.StreamSendBufferSize)
+	if err != nil {
+		return fmt.Errorf("не удалось записать в поток #%d: %w", streamID, err)
+	}
+	if !queued {
+		return ErrWouldBlock
+	}
+	return nil
+}
+
+// GetStreamStats возвращает метрики буфера отправки потока
+func (n *Node) GetStreamStats(streamID uint64) (StreamStats, error) {
+	ms, ok := n.streams.get(streamID)
+	if !ok {
+		return StreamStats{}, fmt.Errorf("поток #%d: %w", streamID, ErrStreamNotFound)
+	}
+	return ms.stats(n.config.StreamSendBufferSize), nil
+}
+
+// writeLoop дописывает очередь отправки в поток, ограничивая каждую запись дедлайном
+func (n *Node) writeLoop(ms *managedStream) {
+	defer close(ms.writerDone)
+
+	for data := range ms.sendQueue {
+		ms.mu.Lock()
+		failed := ms.writeErr != nil
+		ms.mu.Unlock()
+
+		var err error
+		var latency time.Duration
+		if !failed {
+			start := time.Now()
+			if n.config.StreamWriteTimeout > 0 {
+				ms.stream.SetWriteDeadline(start.Add(n.config.StreamWriteTimeout))
+			}
+			_, err = ms.stream.Write(data)
+			latency = time.Since(start)
+		}
+
+		ms.mu.Lock()
+		ms.queuedBytes -= len(data)
+		if !failed {
+			ms.lastWriteLatency = latency
+			if err == nil {
+				ms.bytesWritten += uint64(len(data))
+			} else if errors.Is(err, os.ErrDeadlineExceeded) {
+				ms.writeTimeouts++
+				ms.writeErr = ErrStreamWriteTimeout
+			} else {
+				ms.writeErr = err
+			}
+		}
+		ms.mu.Unlock()
+
+		if err != nil {
+			log.Printf("⚠️ Ошибка записи в поток #%d: %v", ms.id, err)
+			ms.stream.Reset()
+		}
+
+		select {
+		case ms.spaceFreed <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// CloseStream закрывает поток с указанным ID
+func (n *Node) CloseStream(streamID uint64) error {
+	ms, ok := n.streams.remove(streamID)
+	if !ok {
+		return fmt.Errorf("поток #%d: %w", streamID, ErrStreamNotFound)
+	}
+
+	// Дожидаемся отправки уже поставленных в очередь данных
+	ms.closeSend()
+	<-ms.writerDone
+
+	ms.mu.Lock()
+	writeErr := ms.writeErr
+	ms.mu.Unlock()
+
+	err := ms.stream.Close()
+	n.releaseSink(ms, ms.stream.Conn().RemotePeer())
+	n.emit(EventStreamClosed, StreamInfo{
+		StreamID: ms.id,
+		PeerID:   ms.stream.Conn().RemotePeer(),
+		Incoming: ms.incoming,
+	})
+	if writeErr != nil {
+		return fmt.Errorf("поток #%d закрыт с неотправленными данными: %w", streamID, writeErr)
+	}
+	if err != nil {
+		return fmt.Errorf("не удалось закрыть поток #%d: %w", streamID, err)
+	}
+	return nil
+}
+
+// handleDataStream регистрирует входящий поток данных
+func (n *Node) handleDataStream(stream network.Stream) {
+	ms := n.streams.add(stream, true)
+	remotePeer := stream.Conn().RemotePeer()
+	log.Printf("📂 Входящий поток #%d от %s", ms.id, remotePeer.ShortString())
+	n.bumpActivity(remotePeer, activityStream)
+
+	n.emit(EventStreamOpened, StreamInfo{StreamID: ms.id, PeerID: remotePeer, Incoming: true})
+	go n.writeLoop(ms)
+	n.readStream(ms)
+}
+
+// readStream читает поток до EOF, публикуя данные событиями EventStreamData
+func (n *Node) readStream(ms *managedStream) {
+	remotePeer := ms.stream.Conn().RemotePeer()
+	buf := make([]byte, streamReadBufferSize)
+
+	for {
+		read, err := ms.stream.Read(buf)
+		if read > 0 && !n.deliverStreamData(ms, remotePeer, buf[:read]) {
+			ms.stream.Reset()
+			break
+		}
+		if err != nil {
+			if err != io.EOF {
+				log.Printf("⚠️ Ошибка чтения потока #%d: %v", ms.id, err)
+			}
+			break
+		}
+	}
+
+	// Поток мог быть уже закрыт локально через CloseStream
+	if _, ok := n.streams.remove(ms.id); ok {
+		ms.closeSend()
+		<-ms.writerDone
+		ms.stream.Close()
+		n.releaseSink(ms, remotePeer)
+		n.emit(EventStreamClosed, StreamInfo{StreamID: ms.id, PeerID: remotePeer, Incoming: ms.incoming})
+	}
+}
@@ -0,0 +1,254 @@
+package core
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"log"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+// PADDED_PROTOCOL_ID - чат-протокол с сообщениями постоянного размера.
+// Им же передаются фиктивные сообщения, неотличимые по размеру от настоящих
+const PADDED_PROTOCOL_ID = "/owl-whisper/padded/1.0.0"
+
+const (
+	// paddingBlock - размер блока, до которого дополняется каждое сообщение
+	paddingBlock = 512
+	// paddedHeaderSize - вид кадра (1 байт) и длина полезной нагрузки (4 байта)
+	paddedHeaderSize = 5
+	// maxPaddedMessage - максимальный размер сообщения в дополненном протоколе
+	maxPaddedMessage = 64 * 1024
+	// minCoverInterval - минимальный средний интервал фиктивных сообщений
+	minCoverInterval = 10 * time.Second
+	// paddedTimeout - предельное время отправки одного кадра
+	paddedTimeout = 10 * time.Second
+)
+
+// Виды кадров дополненного протокола
+const (
+	paddedFrameMessage byte = 0
+	paddedFrameCover   byte = 1
+)
+
+// TrafficShaping - настройки защиты от анализа трафика для диалога с пиром
+type TrafficShaping struct {
+	// Padding - дополнять сообщения до размера, кратного paddingBlock
+	Padding bool `json:"padding"`
+	// CoverInterval - средний интервал фиктивных сообщений; 0 - не отправлять.
+	// Фиктивные сообщения всегда дополняются
+	CoverInterval time.Duration `json:"cover_interval"`
+}
+
+// ShapingStats - сколько стоит защита трафика диалога: байты полезной
+// нагрузки против байтов дополнения и фиктивных сообщений
+type ShapingStats struct {
+	PayloadBytes  int64 `json:"payload_bytes"`
+	PaddingBytes  int64 `json:"padding_bytes"`
+	CoverMessages int64 `json:"cover_messages"`
+	CoverBytes    int64 `json:"cover_bytes"`
+}
+
+// Overhead возвращает долю лишнего трафика относительно полезной нагрузки
+func (s ShapingStats) Overhead() float64 {
+	if s.PayloadBytes == 0 {
+		return 0
+	}
+	return float64(s.PaddingBytes+s.CoverBytes) / float64(s.PayloadBytes)
+}
+
+// trafficShaping - настройки и счетчики защиты трафика по пирам
+type trafficShaping struct {
+	mu       sync.Mutex
+	settings map[peer.ID]TrafficShaping
+	stats    map[peer.ID]*ShapingStats
+	// cover - остановка фоновой отправки фиктивных сообщений
+	cover map[peer.ID]chan struct{}
+}
+
+// SetTrafficShaping задает защиту трафика для диалога с пиром; нулевое
+// значение возвращает обычную отправку
+func (n *Node) SetTrafficShaping(id peer.ID, shaping TrafficShaping) error {
+	if shaping.CoverInterval != 0 && shaping.CoverInterval < minCoverInterval {
+		return fmt.Errorf("интервал фиктивных сообщений меньше %v", minCoverInterval)
+	}
+
+	s := &n.shaping
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.settings == nil {
+		s.settings = make(map[peer.ID]TrafficShaping)
+		s.cover = make(map[peer.ID]chan struct{})
+	}
+	previous := s.settings[id]
+	if shaping == (TrafficShaping{}) {
+		delete(s.settings, id)
+	} else {
+		s.settings[id] = shaping
+	}
+
+	if previous.CoverInterval != shaping.CoverInterval {
+		if stop, ok := s.cover[id]; ok {
+			close(stop)
+			delete(s.cover, id)
+		}
+		if shaping.CoverInterval > 0 {
+			stop := make(chan struct{})
+			s.cover[id] = stop
+			go n.runCoverTraffic(id, shaping.CoverInterval, stop)
+		}
+	}
+	return nil
+}
+
+// TrafficShaping возвращает настройки защиты трафика диалога с пиром
+func (n *Node) TrafficShaping(id peer.ID) TrafficShaping {
+	n.shaping.mu.Lock()
+	defer n.shaping.mu.Unlock()
+
+	return n.shaping.settings[id]
+}
+
+// ShapingStats возвращает затраты трафика на защиту диалога за эту сессию
+func (n *Node) ShapingStats(id peer.ID) ShapingStats {
+	n.shaping.mu.Lock()
+	defer n.shaping.mu.Unlock()
+
+	if stats, ok := n.shaping.stats[id]; ok {
+		return *stats
+	}
+	return ShapingStats{}
+}
+
+// countShaping учитывает отправленный кадр в статистике пира
+func (n *Node) countShaping(id peer.ID, kind byte, payload, frame int) {
+	n.shaping.mu.Lock()
+	defer n.shaping.mu.Unlock()
+
+	if n.shaping.stats == nil {
+		n.shaping.stats = make(map[peer.ID]*ShapingStats)
+	}
+	stats, ok := n.shaping.stats[id]
+	if !ok {
+		stats = &ShapingStats{}
+		n.shaping.stats[id] = stats
+	}
+	if kind == paddedFrameCover {
+		stats.CoverMessages++
+		stats.CoverBytes += int64(frame)
+		return
+	}
+	stats.PayloadBytes += int64(payload)
+	stats.PaddingBytes += int64(frame - payload)
+}
+
+// sendPadded отправляет кадр дополненного протокола
+func (n *Node) sendPadded(id peer.ID, kind byte, payload []byte) error {
+	if len(payload) > maxPaddedMessage {
+		return fmt.Errorf("сообщение больше %d байт", maxPaddedMessage)
+	}
+
+	frame := make([]byte, paddedFrameSize(len(payload)))
+	frame[0] = kind
+	binary.BigEndian.PutUint32(frame[1:paddedHeaderSize], uint32(len(payload)))
+	copy(frame[paddedHeaderSize:], payload)
+	// Дополнение случайное, чтобы не выделяться при сжатии или анализе содержимого
+	if _, err := rand.Read(frame[paddedHeaderSize+len(payload):]); err != nil {
+		return err
+	}
+
+	stream, err := n.newStream(n.ctx, id, PADDED_PROTOCOL_ID)
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+	stream.SetDeadline(time.Now().Add(paddedTimeout))
+
+	if _, err := stream.Write(frame); err != nil {
+		return err
+	}
+	n.countShaping(id, kind, len(payload), len(frame))
+	return nil
+}
+
+// handlePaddedStream принимает кадры дополненного протокола: сообщения
+// доставляются как обычные, фиктивные отбрасываются
+func (n *Node) handlePaddedStream(stream network.Stream) {
+	defer stream.Close()
+	remotePeer := stream.Conn().RemotePeer()
+	stream.SetDeadline(time.Now().Add(paddedTimeout))
+
+	header := make([]byte, paddedHeaderSize)
+	if _, err := io.ReadFull(stream, header); err != nil {
+		stream.Reset()
+		return
+	}
+	size := int(binary.BigEndian.Uint32(header[1:]))
+	if size > maxPaddedMessage {
+		stream.Reset()
+		return
+	}
+	frame := make([]byte, paddedFrameSize(size)-paddedHeaderSize)
+	if _, err := io.ReadFull(stream, frame); err != nil {
+		stream.Reset()
+		return
+	}
+
+	if header[0] != paddedFrameMessage {
+		return
+	}
+	if !n.deliverMessage(remotePeer, string(frame[:size])) {
+		stream.Reset()
+	}
+}
+
+// runCoverTraffic отправляет пиру фиктивные сообщения со случайными
+// интервалами в среднем interval, пока пир подключен и защита не выключена
+func (n *Node) runCoverTraffic(id peer.ID, interval time.Duration, stop chan struct{}) {
+	for {
+		// Равномерно от половины до полутора интервалов, чтобы не было ритма
+		jitter, err := rand.Int(rand.Reader, big.NewInt(int64(interval)))
+		if err != nil {
+			return
+		}
+		timer := time.NewTimer(interval/2 + time.Duration(jitter.Int64()))
+		select {
+		case <-n.ctx.Done():
+			timer.Stop()
+			return
+		case <-stop:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		// Фиктивные сообщения не должны будить сеть: только уже подключенным пирам
+		if n.host.Network().Connectedness(id) != network.Connected {
+			continue
+		}
+		if caps, err := n.GetPeerCapabilities(id); err != nil || !caps.Has(CapabilityPadding) {
+			continue
+		}
+		// Размер фиктивного сообщения как у типичного короткого текста
+		size, err := rand.Int(rand.Reader, big.NewInt(paddingBlock-paddedHeaderSize))
+		if err != nil {
+			return
+		}
+		if err := n.sendPadded(id, paddedFrameCover, make([]byte, size.Int64())); err != nil {
+			log.Printf("⚠️ Фиктивное сообщение для %s не отправлено: %v", id.ShortString(), err)
+		}
+	}
+}
+
+// paddedFrameSize возвращает размер кадра для полезной нагрузки size
+func paddedFrameSize(size int) int {
+	total := paddedHeaderSize + size
+	return (total + paddingBlock - 1) / paddingBlock * paddingBlock
+}
@@ -0,0 +1,218 @@
+package core
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// DefaultChunkSize - размер блока для поблочного хеширования по умолчанию
+const DefaultChunkSize = 256 * 1024
+
+const (
+	// minChunkSize и maxChunkSize - допустимый размер блока в чужом
+	// манифесте: блок такого размера выделяется в памяти при проверке
+	minChunkSize = 4 * 1024
+	maxChunkSize = 4 * 1024 * 1024
+)
+
+// ChecksumStrategy - способ проверки целостности передаваемого файла
+type ChecksumStrategy string
+
+const (
+	// ChecksumWholeFile - один SHA-256 на весь файл; при повреждении файл качается заново
+	ChecksumWholeFile ChecksumStrategy = "whole_file"
+	// ChecksumPerChunk - SHA-256 каждого блока и корень Merkle-дерева над ними,
+	// позволяет перезапросить только поврежденные диапазоны
+	ChecksumPerChunk ChecksumStrategy = "per_chunk"
+)
+
+// ErrManifestMismatch - файл не соответствует метаданным по размеру
+var ErrManifestMismatch = errors.New("файл не соответствует манифесту")
+
+// ChunkManifest - метаданные целостности файла, передаваемые вместе с ним
+type ChunkManifest struct {
+	Strategy    ChecksumStrategy `json:"strategy"`
+	FileSize    int64            `json:"file_size"`
+	ChunkSize   int              `json:"chunk_size,omitempty"`
+	FileHash    string           `json:"file_hash"`
+	ChunkHashes []string         `json:"chunk_hashes,omitempty"`
+	MerkleRoot  string           `json:"merkle_root,omitempty"`
+}
+
+// Validate проверяет манифест, полученный от пира, до проверки файла по нему
+func (m *ChunkManifest) Validate() error {
+	if m.FileSize < 0 {
+		return fmt.Errorf("%w: отрицательный размер", ErrManifestMismatch)
+	}
+	switch m.Strategy {
+	case ChecksumWholeFile:
+		return nil
+	case ChecksumPerChunk:
+		if m.ChunkSize < minChunkSize || m.ChunkSize > maxChunkSize {
+			return fmt.Errorf("%w: размер блока %d вне допустимых %d-%d", ErrManifestMismatch, m.ChunkSize, minChunkSize, maxChunkSize)
+		}
+		return nil
+	default:
+		return fmt.Errorf("неизвестная стратегия проверки: %s", m.Strategy)
+	}
+}
+
+// ChunkCount возвращает число блоков файла при поблочной стратегии
+func (m *ChunkManifest) ChunkCount() int {
+	if m.ChunkSize <= 0 {
+		return 0
+	}
+	return int((m.FileSize + int64(m.ChunkSize) - 1) / int64(m.ChunkSize))
+}
+
+// ByteRange - диапазон байт файла [Offset, Offset+Length)
+type ByteRange struct {
+	Offset int64 `json:"offset"`
+	Length int64 `json:"length"`
+}
+
+// VerifyResult - результат проверки полученного файла
+type VerifyResult struct {
+	OK              bool        `json:"ok"`
+	CorruptedChunks []int       `json:"corrupted_chunks,omitempty"`
+	CorruptedRanges []ByteRange `json:"corrupted_ranges,omitempty"`
+}
+
+// BuildManifest считает метаданные целостности файла по выбранной стратегии
+func BuildManifest(path string, strategy ChecksumStrategy, chunkSize int) (*ChunkManifest, error) {
+	if chunkSize <= 0 {
+		chunkSize = DefaultChunkSize
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось открыть %s: %w", path, err)
+	}
+	defer file.Close()
+
+	manifest := &ChunkManifest{Strategy: strategy}
+	fileHash := sha256.New()
+
+	switch strategy {
+	case ChecksumWholeFile:
+		size, err := io.Copy(fileHash, file)
+		if err != nil {
+			return nil, fmt.Errorf("не удалось прочитать %s: %w", path, err)
+		}
+		manifest.FileSize = size
+
+	case ChecksumPerChunk:
+		manifest.ChunkSize = chunkSize
+		buf := make([]byte, chunkSize)
+		for {
+			read, err := io.ReadFull(file, buf)
+			if read > 0 {
+				sum := sha256.Sum256(buf[:read])
+				manifest.ChunkHashes = append(manifest.ChunkHashes, hex.EncodeToString(sum[:]))
+				fileHash.Write(buf[:read])
+				manifest.FileSize += int64(read)
+			}
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				break
+			}
+			if err != nil {
+				return nil, fmt.Errorf("не удалось прочитать %s: %w", path, err)
+			}
+		}
+		manifest.MerkleRoot = merkleRoot(manifest.ChunkHashes)
+
+	default:
+		return nil, fmt.Errorf("неизвестная стратегия проверки: %s", strategy)
+	}
+
+	manifest.FileHash = hex.EncodeToString(fileHash.Sum(nil))
+	return manifest, nil
+}
+
+// VerifyTransfer проверяет файл по манифесту. Для поблочной стратегии
+// возвращает поврежденные блоки и диапазоны для повторного запроса
+func VerifyTransfer(path string, manifest *ChunkManifest) (*VerifyResult, error) {
+	if err := manifest.Validate(); err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось открыть %s: %w", path, err)
+	}
+	if info.Size() != manifest.FileSize {
+		return nil, fmt.Errorf("%w: размер %d вместо %d", ErrManifestMismatch, info.Size(), manifest.FileSize)
+	}
+
+	actual, err := BuildManifest(path, manifest.Strategy, manifest.ChunkSize)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &VerifyResult{OK: actual.FileHash == manifest.FileHash}
+	if manifest.Strategy != ChecksumPerChunk {
+		if !result.OK {
+			result.CorruptedRanges = []ByteRange{{Offset: 0, Length: manifest.FileSize}}
+		}
+		return result, nil
+	}
+
+	if len(actual.ChunkHashes) != len(manifest.ChunkHashes) {
+		return nil, fmt.Errorf("%w: %d блоков вместо %d", ErrManifestMismatch, len(actual.ChunkHashes), len(manifest.ChunkHashes))
+	}
+	for i, hash := range manifest.ChunkHashes {
+		if actual.ChunkHashes[i] != hash {
+			result.CorruptedChunks = append(result.CorruptedChunks, i)
+			result.CorruptedRanges = append(result.CorruptedRanges, manifest.ChunkRange(i))
+		}
+	}
+	// Блоки сверяются с манифестом, но и хеш всего файла должен совпасть:
+	// иначе подходит манифест с чужим FileHash и согласованными блоками
+	result.OK = result.OK && len(result.CorruptedChunks) == 0 && actual.MerkleRoot == manifest.MerkleRoot
+	return result, nil
+}
+
+// ChunkRange возвращает диапазон байт блока с индексом index
+func (m *ChunkManifest) ChunkRange(index int) ByteRange {
+	offset := int64(index) * int64(m.ChunkSize)
+	length := int64(m.ChunkSize)
+	if offset+length > m.FileSize {
+		length = m.FileSize - offset
+	}
+	return ByteRange{Offset: offset, Length: length}
+}
+
+// merkleRoot считает корень Merkle-дерева над хешами блоков.
+// Непарный последний узел уровня поднимается вверх без изменений
+func merkleRoot(hexHashes []string) string {
+	if len(hexHashes) == 0 {
+		sum := sha256.Sum256(nil)
+		return hex.EncodeToString(sum[:])
+	}
+
+	level := make([][]byte, 0, len(hexHashes))
+	for _, h := range hexHashes {
+		raw, _ := hex.DecodeString(h)
+		level = append(level, raw)
+	}
+
+	for len(level) > 1 {
+		next := make([][]byte, 0, (len(level)+1)/2)
+		for i := 0; i < len(level); i += 2 {
+			if i+1 == len(level) {
+				next = append(next, level[i])
+				continue
+			}
+			pair := sha256.New()
+			pair.Write(level[i])
+			pair.Write(level[i+1])
+			next = append(next, pair.Sum(nil))
+		}
+		level = next
+	}
+
+	return hex.EncodeToString(level[0])
+}
@@ -0,0 +1,53 @@
+package core
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// testFile записывает файл с содержимым data во временную директорию
+func testFile(t *testing.T, data []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestVerifyTransferForeignFileHash(t *testing.T) {
+	path := testFile(t, bytes.Repeat([]byte("owl"), DefaultChunkSize))
+	manifest, err := BuildManifest(path, ChecksumPerChunk, DefaultChunkSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Блоки совпадают с файлом, а хеш всего файла - от другого содержимого
+	foreign := sha256.Sum256([]byte("другой файл"))
+	manifest.FileHash = hex.EncodeToString(foreign[:])
+	result, err := VerifyTransfer(path, manifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.OK {
+		t.Fatal("манифест с чужим хешем файла прошел проверку")
+	}
+}
+
+func TestVerifyTransferChunkSizeBounds(t *testing.T) {
+	path := testFile(t, []byte("owl"))
+	manifest, err := BuildManifest(path, ChecksumPerChunk, DefaultChunkSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	manifest.ChunkSize = 1 << 40
+	if _, err := VerifyTransfer(path, manifest); !errors.Is(err, ErrManifestMismatch) {
+		t.Fatalf("огромный размер блока принят: %v", err)
+	}
+}
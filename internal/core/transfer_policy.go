@@ -0,0 +1,173 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+// FileOffer - предложение входящего файла от пира
+type FileOffer struct {
+	PeerID peer.ID `json:"peer_id"`
+	Name   string  `json:"name"`
+	Size   int64   `json:"size"`
+	// Nearby - файл отправлен через локальную сеть (SendFileToNearbyPeer)
+	Nearby bool `json:"nearby,omitempty"`
+}
+
+// TransferPolicy - правила автоприема файлов и место их сохранения
+type TransferPolicy struct {
+	// DownloadDir - директория для принятых файлов
+	DownloadDir string
+
+	// AutoAccept включает автоматический прием по правилам ниже
+	AutoAccept bool
+	// AutoAcceptMaxSize - максимальный размер файла для автоприема (0 - без ограничения)
+	AutoAcceptMaxSize int64
+	// AutoAcceptPeers - от кого принимать автоматически (пусто - от всех)
+	AutoAcceptPeers []peer.ID
+	// AutoAcceptExtensions - какие расширения принимать автоматически (пусто - любые)
+	AutoAcceptExtensions []string
+
+	// MaxIncomingSize - файлы больше отклоняются без вопроса (0 - без ограничения)
+	MaxIncomingSize int64
+	// BlockedExtensions - расширения, которые отклоняются без вопроса
+	BlockedExtensions []string
+	// WarnedExtensions - расширения, о которых предупреждать в дополнение к встроенным
+	WarnedExtensions []string
+}
+
+// TransferDecision - решение политики по предложению файла
+type TransferDecision struct {
+	// Blocked - файл отклоняется без вопроса пользователю (Reason - почему)
+	Blocked    bool         `json:"blocked,omitempty"`
+	AutoAccept bool         `json:"auto_accept"`
+	Path       string       `json:"path"`
+	Reason     string       `json:"reason,omitempty"`
+	Safety     SafetyReport `json:"safety"`
+}
+
+// Evaluate решает, можно ли принять файл без вопроса пользователю,
+// и выбирает путь сохранения, не перезаписывая существующие файлы.
+// Подозрительные файлы никогда не принимаются автоматически, а запрещенные
+// и слишком большие отклоняются сразу
+func (p TransferPolicy) Evaluate(offer FileOffer) TransferDecision {
+	decision := TransferDecision{
+		Path:   p.downloadPath(offer.Name),
+		Safety: p.checkOffer(offer),
+	}
+
+	switch {
+	case p.MaxIncomingSize > 0 && offer.Size > p.MaxIncomingSize:
+		decision.Blocked = true
+		decision.Reason = fmt.Sprintf("размер %d больше допустимого %d", offer.Size, p.MaxIncomingSize)
+	case len(p.BlockedExtensions) > 0 && hasExtension(p.BlockedExtensions, cleanOfferName(offer.Name)):
+		decision.Blocked = true
+		decision.Reason = fmt.Sprintf("расширение %s запрещено", filepath.Ext(cleanOfferName(offer.Name)))
+	case !p.AutoAccept:
+		decision.Reason = "автоприем выключен"
+	case decision.Safety.Level != SafetySafe:
+		decision.Reason = "файл не прошел проверку безопасности"
+	case p.AutoAcceptMaxSize > 0 && offer.Size > p.AutoAcceptMaxSize:
+		decision.Reason = fmt.Sprintf("размер %d больше лимита %d", offer.Size, p.AutoAcceptMaxSize)
+	case len(p.AutoAcceptPeers) > 0 && !containsPeer(p.AutoAcceptPeers, offer.PeerID):
+		decision.Reason = "отправитель не входит в список автоприема"
+	case len(p.AutoAcceptExtensions) > 0 && !hasExtension(p.AutoAcceptExtensions, offer.Name):
+		decision.Reason = "расширение не входит в список автоприема"
+	default:
+		decision.AutoAccept = true
+	}
+
+	return decision
+}
+
+// checkOffer оценивает опасность файла по имени с учетом своих расширений
+// для предупреждения
+func (p TransferPolicy) checkOffer(offer FileOffer) SafetyReport {
+	report := CheckOfferSafety(offer)
+	name := cleanOfferName(offer.Name)
+	if report.Level == SafetySafe && len(p.WarnedExtensions) > 0 && hasExtension(p.WarnedExtensions, name) {
+		report.raise(SafetyWarning, fmt.Sprintf("расширение %s отмечено в настройках", filepath.Ext(name)))
+	}
+	return report
+}
+
+// downloadPath возвращает свободный путь для файла в директории загрузок.
+// Имя от пира очищается от компонентов пути
+func (p TransferPolicy) downloadPath(name string) string {
+	base := filepath.Base(filepath.Clean("/" + strings.ReplaceAll(name, "\\", "/")))
+	if base == "/" || base == "." || base == ".." {
+		base = "file"
+	}
+
+	dir := p.DownloadDir
+	if dir == "" {
+		dir = DefaultDownloadDir()
+	}
+
+	path := filepath.Join(dir, base)
+	ext := filepath.Ext(base)
+	stem := strings.TrimSuffix(base, ext)
+	for i := 1; ; i++ {
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			return path
+		}
+		path = filepath.Join(dir, fmt.Sprintf("%s (%d)%s", stem, i, ext))
+	}
+}
+
+// DefaultDownloadDir возвращает директорию загрузок по умолчанию
+func DefaultDownloadDir() string {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return filepath.Join(os.TempDir(), "owlwhisper", "downloads")
+	}
+	return filepath.Join(homeDir, ".owlwhisper", "downloads")
+}
+
+// TransferPolicy возвращает текущие правила приема файлов
+func (n *Node) TransferPolicy() TransferPolicy {
+	n.policyMu.RLock()
+	defer n.policyMu.RUnlock()
+	return n.policy
+}
+
+// SetTransferPolicy меняет правила приема файлов во время работы
+func (n *Node) SetTransferPolicy(policy TransferPolicy) {
+	n.policyMu.Lock()
+	n.policy = policy
+	n.policyMu.Unlock()
+}
+
+// EvaluateFileOffer применяет текущие правила к предложению файла
+func (n *Node) EvaluateFileOffer(offer FileOffer) TransferDecision {
+	return n.TransferPolicy().Evaluate(offer)
+}
+
+// containsPeer проверяет наличие пира в списке
+func containsPeer(peers []peer.ID, id peer.ID) bool {
+	for _, p := range peers {
+		if p == id {
+			return true
+		}
+	}
+	return false
+}
+
+// hasExtension проверяет расширение файла без учета регистра
+func hasExtension(extensions []string, name string) bool {
+	ext := strings.ToLower(filepath.Ext(name))
+	for _, allowed := range extensions {
+		allowed = strings.ToLower(allowed)
+		if !strings.HasPrefix(allowed, ".") {
+			allowed = "." + allowed
+		}
+		if allowed == ext {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,37 @@
+package core
+
+import "testing"
+
+func TestTransferPolicyBlocks(t *testing.T) {
+	policy := TransferPolicy{
+		AutoAccept:        true,
+		MaxIncomingSize:   1 << 20,
+		BlockedExtensions: []string{"iso"},
+		WarnedExtensions:  []string{".docm"},
+	}
+
+	if decision := policy.Evaluate(FileOffer{Name: "image.ISO", Size: 10}); !decision.Blocked {
+		t.Fatal("запрещенное расширение не отклонено")
+	}
+	if decision := policy.Evaluate(FileOffer{Name: "movie.mp4", Size: 2 << 20}); !decision.Blocked {
+		t.Fatal("слишком большой файл не отклонен")
+	}
+	decision := policy.Evaluate(FileOffer{Name: "report.docm", Size: 10})
+	if decision.Blocked || decision.Safety.Level != SafetyWarning || decision.AutoAccept {
+		t.Fatalf("отмеченное расширение оценено неверно: %+v", decision)
+	}
+}
+
+func TestContentSafetyDisguisedExecutable(t *testing.T) {
+	report := SafetyReport{Level: SafetySafe}
+	checkContentSafety(&report, []byte("MZ\x90\x00"), "photo.jpg")
+	if report.Level != SafetyDangerous {
+		t.Fatal("исполняемый файл под видом картинки не обнаружен")
+	}
+
+	report = SafetyReport{Level: SafetySafe}
+	checkContentSafety(&report, []byte("\xff\xd8\xff\xe0"), "photo.jpg")
+	if report.Level != SafetySafe {
+		t.Fatalf("обычная картинка отмечена: %v", report.Reasons)
+	}
+}
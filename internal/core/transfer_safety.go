@@ -0,0 +1,126 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// SafetyLevel - оценка опасности входящего файла
+type SafetyLevel string
+
+const (
+	SafetySafe      SafetyLevel = "safe"
+	SafetyWarning   SafetyLevel = "warning"
+	SafetyDangerous SafetyLevel = "dangerous"
+)
+
+// SafetyReport - результат проверки имени или содержимого файла
+type SafetyReport struct {
+	Level   SafetyLevel `json:"level"`
+	Reasons []string    `json:"reasons,omitempty"`
+}
+
+// dangerousExtensions - расширения исполняемых и скриптовых файлов
+var dangerousExtensions = map[string]bool{
+	".exe": true, ".com": true, ".scr": true, ".pif": true, ".msi": true, ".msp": true,
+	".bat": true, ".cmd": true, ".ps1": true, ".vbs": true, ".vbe": true, ".js": true,
+	".jse": true, ".wsf": true, ".wsh": true, ".hta": true, ".cpl": true, ".lnk": true,
+	".reg": true, ".jar": true, ".apk": true, ".app": true, ".dmg": true, ".pkg": true,
+	".sh": true, ".run": true, ".deb": true, ".rpm": true, ".dll": true, ".so": true,
+}
+
+// warningExtensions - документы с макросами и архивы, способные скрыть содержимое
+var warningExtensions = map[string]bool{
+	".docm": true, ".xlsm": true, ".pptm": true, ".iso": true, ".img": true,
+	".zip": true, ".rar": true, ".7z": true, ".svg": true, ".html": true, ".htm": true,
+}
+
+// executableSignatures - сигнатуры исполняемых форматов в начале файла
+var executableSignatures = []struct {
+	magic []byte
+	name  string
+}{
+	{[]byte("MZ"), "Windows PE"},
+	{[]byte("\x7fELF"), "ELF"},
+	{[]byte("#!"), "скрипт с shebang"},
+	{[]byte{0xcf, 0xfa, 0xed, 0xfe}, "Mach-O"},
+	{[]byte{0xca, 0xfe, 0xba, 0xbe}, "Mach-O/Java class"},
+}
+
+// bidiControls - символы управления направлением текста, которыми маскируют расширение
+var bidiControls = []rune{'\u202a', '\u202b', '\u202c', '\u202d', '\u202e', '\u2066', '\u2067', '\u2068', '\u2069'}
+
+// CheckOfferSafety оценивает опасность файла по имени до начала приема
+func CheckOfferSafety(offer FileOffer) SafetyReport {
+	report := SafetyReport{Level: SafetySafe}
+	if cleanOfferName(offer.Name) != offer.Name {
+		report.raise(SafetyDangerous, "имя содержит символы смены направления текста")
+	}
+
+	base := strings.ToLower(filepath.Base(cleanOfferName(offer.Name)))
+	ext := filepath.Ext(base)
+	if dangerousExtensions[ext] {
+		report.raise(SafetyDangerous, fmt.Sprintf("исполняемое расширение %s", ext))
+	} else if warningExtensions[ext] {
+		report.raise(SafetyWarning, fmt.Sprintf("расширение %s может скрывать опасное содержимое", ext))
+	}
+
+	// "invoice.pdf.exe" - второе расширение рассчитано на невнимательность
+	if inner := filepath.Ext(strings.TrimSuffix(base, ext)); inner != "" && dangerousExtensions[ext] {
+		report.raise(SafetyDangerous, fmt.Sprintf("двойное расширение %s%s", inner, ext))
+	}
+
+	return report
+}
+
+// cleanOfferName убирает из имени файла символы смены направления текста,
+// которыми маскируют настоящее расширение
+func cleanOfferName(name string) string {
+	for _, r := range bidiControls {
+		name = strings.ReplaceAll(name, string(r), "")
+	}
+	return name
+}
+
+// checkContentSafety проверяет начало содержимого файла name: исполняемые
+// сигнатуры под видом безобидного расширения считаются опасными
+func checkContentSafety(report *SafetyReport, header []byte, name string) {
+	ext := strings.ToLower(filepath.Ext(cleanOfferName(name)))
+	for _, sig := range executableSignatures {
+		if bytes.HasPrefix(header, sig.magic) && !dangerousExtensions[ext] {
+			report.raise(SafetyDangerous, fmt.Sprintf("содержимое - %s, а расширение %q", sig.name, ext))
+		}
+	}
+}
+
+// CheckFileSafety проверяет содержимое файла на диске: исполняемые
+// сигнатуры под видом безобидного расширения считаются опасными
+func CheckFileSafety(path string) (SafetyReport, error) {
+	report := CheckOfferSafety(FileOffer{Name: filepath.Base(path)})
+
+	file, err := os.Open(path)
+	if err != nil {
+		return report, fmt.Errorf("не удалось открыть %s: %w", path, err)
+	}
+	defer file.Close()
+
+	header := make([]byte, 8)
+	read, err := io.ReadFull(file, header)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return report, fmt.Errorf("не удалось прочитать %s: %w", path, err)
+	}
+	checkContentSafety(&report, header[:read], path)
+	return report, nil
+}
+
+// raise повышает уровень опасности и добавляет причину
+func (r *SafetyReport) raise(level SafetyLevel, reason string) {
+	if level == SafetyDangerous || (level == SafetyWarning && r.Level == SafetySafe) {
+		r.Level = level
+	}
+	r.Reasons = append(r.Reasons, reason)
+}
@@ -0,0 +1,296 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+// ErrTransferNotFound возвращается для неизвестного ID передачи
+var ErrTransferNotFound = errors.New("передача не найдена")
+
+// TransferPriority - приоритет передачи в очереди
+type TransferPriority int
+
+const (
+	// PriorityBackground - фоновые передачи (синхронизация, предзагрузка)
+	PriorityBackground TransferPriority = iota
+	// PriorityUser - передачи, запущенные пользователем
+	PriorityUser
+)
+
+// TransferState - состояние передачи в очереди
+type TransferState string
+
+const (
+	TransferQueued    TransferState = "queued"
+	TransferRunning   TransferState = "running"
+	TransferCompleted TransferState = "completed"
+	TransferFailed    TransferState = "failed"
+	TransferCancelled TransferState = "cancelled"
+)
+
+// TransferFunc выполняет саму передачу; должна завершаться при отмене ctx
+type TransferFunc func(ctx context.Context) error
+
+// TransferJob - описание передачи для внешних потребителей
+type TransferJob struct {
+	ID         uint64           `json:"id"`
+	PeerID     peer.ID          `json:"peer_id"`
+	Name       string           `json:"name"`
+	Priority   TransferPriority `json:"priority"`
+	State      TransferState    `json:"state"`
+	Error      string           `json:"error,omitempty"`
+	EnqueuedAt time.Time        `json:"enqueued_at"`
+}
+
+// TransferQueueState - полезная нагрузка события EventTransferQueue
+type TransferQueueState struct {
+	Paused        bool          `json:"paused"`
+	MaxConcurrent int           `json:"max_concurrent"`
+	Pending       int           `json:"pending"`
+	Running       int           `json:"running"`
+	Changed       TransferJob   `json:"changed"`
+	Jobs          []TransferJob `json:"jobs"`
+}
+
+// scheduledTransfer - передача внутри планировщика
+type scheduledTransfer struct {
+	job    TransferJob
+	fn     TransferFunc
+	cancel context.CancelFunc
+}
+
+// TransferScheduler ограничивает число одновременных передач и решает,
+// какую запускать следующей: сначала по приоритету, затем в пользу пира
+// с наименьшим числом активных передач, затем в пользу пира, которого
+// дольше всех не обслуживали, и наконец по порядку постановки
+type TransferScheduler struct {
+	node *Node
+	ctx  context.Context
+
+	mu            sync.Mutex
+	nextID        uint64
+	maxConcurrent int
+	paused        bool
+	pending       []*scheduledTransfer
+	running       map[uint64]*scheduledTransfer
+	perPeer       map[peer.ID]int
+	dispatched    uint64
+	lastServed    map[peer.ID]uint64
+}
+
+// newTransferScheduler создает планировщик передач узла
+func newTransferScheduler(ctx context.Context, node *Node, maxConcurrent int) *TransferScheduler {
+	if maxConcurrent <= 0 {
+		maxConcurrent = 1
+	}
+	return &TransferScheduler{
+		node:          node,
+		ctx:           ctx,
+		maxConcurrent: maxConcurrent,
+		running:       make(map[uint64]*scheduledTransfer),
+		perPeer:       make(map[peer.ID]int),
+		lastServed:    make(map[peer.ID]uint64),
+	}
+}
+
+// Transfers возвращает планировщик передач узла
+func (n *Node) Transfers() *TransferScheduler {
+	return n.transfers
+}
+
+// Enqueue ставит передачу в очередь и возвращает ее ID
+func (s *TransferScheduler) Enqueue(peerID peer.ID, name string, priority TransferPriority, fn TransferFunc) uint64 {
+	s.mu.Lock()
+	s.nextID++
+	t := &scheduledTransfer{
+		job: TransferJob{
+			ID:         s.nextID,
+			PeerID:     peerID,
+			Name:       name,
+			Priority:   priority,
+			State:      TransferQueued,
+			EnqueuedAt: time.Now(),
+		},
+		fn: fn,
+	}
+	s.pending = append(s.pending, t)
+	s.publishLocked(t.job)
+	s.dispatchLocked()
+	s.mu.Unlock()
+
+	return t.job.ID
+}
+
+// Cancel отменяет ожидающую или выполняющуюся передачу
+func (s *TransferScheduler) Cancel(id uint64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if t, ok := s.running[id]; ok {
+		// Завершение обработает горутина передачи
+		t.cancel()
+		return nil
+	}
+
+	for i, t := range s.pending {
+		if t.job.ID == id {
+			s.pending = append(s.pending[:i], s.pending[i+1:]...)
+			t.job.State = TransferCancelled
+			s.publishLocked(t.job)
+			return nil
+		}
+	}
+
+	return fmt.Errorf("передача #%d: %w", id, ErrTransferNotFound)
+}
+
+// Pause приостанавливает запуск новых передач; уже идущие дорабатывают
+func (s *TransferScheduler) Pause() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.paused {
+		s.paused = true
+		log.Println("⏸️ Очередь передач приостановлена")
+		s.publishLocked(TransferJob{})
+	}
+}
+
+// Resume возобновляет запуск передач из очереди
+func (s *TransferScheduler) Resume() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.paused {
+		s.paused = false
+		log.Println("▶️ Очередь передач возобновлена")
+		s.publishLocked(TransferJob{})
+		s.dispatchLocked()
+	}
+}
+
+// SetMaxConcurrent меняет лимит одновременных передач
+func (s *TransferScheduler) SetMaxConcurrent(limit int) {
+	if limit <= 0 {
+		limit = 1
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.maxConcurrent = limit
+	s.dispatchLocked()
+}
+
+// State возвращает снимок состояния очереди
+func (s *TransferScheduler) State() TransferQueueState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.stateLocked(TransferJob{})
+}
+
+// dispatchLocked запускает передачи, пока есть свободные слоты
+func (s *TransferScheduler) dispatchLocked() {
+	for !s.paused && len(s.running) < s.maxConcurrent && len(s.pending) > 0 {
+		next := s.pickLocked()
+		t := s.pending[next]
+		s.pending = append(s.pending[:next], s.pending[next+1:]...)
+
+		ctx, cancel := context.WithCancel(s.ctx)
+		t.cancel = cancel
+		t.job.State = TransferRunning
+		s.running[t.job.ID] = t
+		s.perPeer[t.job.PeerID]++
+		s.dispatched++
+		s.lastServed[t.job.PeerID] = s.dispatched
+		s.publishLocked(t.job)
+
+		go s.run(ctx, t)
+	}
+}
+
+// pickLocked выбирает индекс следующей передачи в очереди
+func (s *TransferScheduler) pickLocked() int {
+	best := 0
+	for i := 1; i < len(s.pending); i++ {
+		cand, cur := s.pending[i].job, s.pending[best].job
+		if cand.Priority != cur.Priority {
+			if cand.Priority > cur.Priority {
+				best = i
+			}
+			continue
+		}
+		// Справедливость между пирами: меньше активных передач - раньше очередь
+		candRunning, curRunning := s.perPeer[cand.PeerID], s.perPeer[cur.PeerID]
+		if candRunning != curRunning {
+			if candRunning < curRunning {
+				best = i
+			}
+			continue
+		}
+		if s.lastServed[cand.PeerID] < s.lastServed[cur.PeerID] {
+			best = i
+		}
+	}
+	return best
+}
+
+// run выполняет передачу и освобождает слот по завершении
+func (s *TransferScheduler) run(ctx context.Context, t *scheduledTransfer) {
+	err := t.fn(ctx)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t.cancel()
+	delete(s.running, t.job.ID)
+	s.perPeer[t.job.PeerID]--
+	if s.perPeer[t.job.PeerID] <= 0 {
+		delete(s.perPeer, t.job.PeerID)
+	}
+
+	switch {
+	case err == nil:
+		t.job.State = TransferCompleted
+	case ctx.Err() != nil:
+		t.job.State = TransferCancelled
+	default:
+		t.job.State = TransferFailed
+		t.job.Error = err.Error()
+		log.Printf("⚠️ Передача #%d (%s) завершилась ошибкой: %v", t.job.ID, t.job.Name, err)
+	}
+	s.publishLocked(t.job)
+	s.dispatchLocked()
+}
+
+// stateLocked собирает снимок очереди
+func (s *TransferScheduler) stateLocked(changed TransferJob) TransferQueueState {
+	state := TransferQueueState{
+		Paused:        s.paused,
+		MaxConcurrent: s.maxConcurrent,
+		Pending:       len(s.pending),
+		Running:       len(s.running),
+		Changed:       changed,
+		Jobs:          make([]TransferJob, 0, len(s.pending)+len(s.running)),
+	}
+	for _, t := range s.running {
+		state.Jobs = append(state.Jobs, t.job)
+	}
+	for _, t := range s.pending {
+		state.Jobs = append(state.Jobs, t.job)
+	}
+	return state
+}
+
+// publishLocked публикует событие об изменении очереди
+func (s *TransferScheduler) publishLocked(changed TransferJob) {
+	s.node.emit(EventTransferQueue, s.stateLocked(changed))
+}
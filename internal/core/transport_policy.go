@@ -0,0 +1,247 @@
+package core
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/p2p/net/swarm"
+	"github.com/multiformats/go-multiaddr"
+)
+
+// Предпочитаемый транспорт исходящих соединений
+const (
+	TransportAuto = ""     // порядок libp2p по умолчанию (QUIC раньше TCP)
+	TransportQUIC = "quic" // QUIC сразу, TCP с задержкой
+	TransportTCP  = "tcp"  // TCP сразу, QUIC с задержкой
+)
+
+// RelayMode определяет, можно ли соединяться с пиром через ретранслятор
+type RelayMode string
+
+const (
+	// RelayAllow - прямое соединение, ретранслятор как запасной путь. Для
+	// отдельного пира означает "как в глобальной политике"
+	RelayAllow RelayMode = ""
+	// RelayAuto - прямое соединение и ретранслятор для отдельного пира, даже
+	// если глобальная политика требует только ретранслятор (например, для
+	// коллег в одной сети)
+	RelayAuto RelayMode = "auto"
+	// RelayNever - только прямые соединения (например, коллеги в одной сети)
+	RelayNever RelayMode = "direct"
+	// RelayAlways - только через ретранслятор. IP при этом скрывает только
+	// глобальный режим hide_ip: без него identify все равно сообщает пиру
+	// прямые адреса узла
+	RelayAlways RelayMode = "relay"
+)
+
+const (
+	// preferredTransportLead - на сколько непредпочтительный транспорт
+	// набирается позже предпочтительного
+	preferredTransportLead = 300 * time.Millisecond
+	// relayDialDelay - ретранслятор пробуется после прямых адресов
+	relayDialDelay = 500 * time.Millisecond
+)
+
+// TransportPolicy - глобальная политика транспорта
+type TransportPolicy struct {
+	// Prefer - предпочитаемый транспорт: TransportAuto, TransportQUIC, TransportTCP
+	Prefer string
+	// Relay - режим ретрансляции для собеседников без собственной политики:
+	// контактов и пиров, с которыми узел сам открывал потоки. Служебные пиры
+	// (bootstrap, DHT, ретрансляторы) набираются напрямую, иначе узел не
+	// найдет ни сеть, ни собеседников
+	Relay RelayMode
+}
+
+// ParseRelayMode проверяет режим ретрансляции; пустой и "default" означают
+// RelayAllow
+func ParseRelayMode(value string) (RelayMode, error) {
+	switch mode := RelayMode(value); mode {
+	case RelayAllow, RelayAuto, RelayNever, RelayAlways:
+		return mode, nil
+	case "default":
+		return RelayAllow, nil
+	default:
+		return "", fmt.Errorf("неизвестный режим ретрансляции: %s", value)
+	}
+}
+
+// Validate проверяет политику
+func (p TransportPolicy) Validate() error {
+	switch p.Prefer {
+	case TransportAuto, TransportQUIC, TransportTCP:
+	default:
+		return fmt.Errorf("неизвестный транспорт: %s", p.Prefer)
+	}
+	switch p.Relay {
+	case RelayAllow, RelayNever, RelayAlways:
+		return nil
+	default:
+		return fmt.Errorf("неизвестный глобальный режим ретрансляции: %s", p.Relay)
+	}
+}
+
+// transportPolicies применяет политики при наборе адресов и установке
+// соединения. Создается до хоста libp2p, так как используется его опциями
+type transportPolicies struct {
+	mu      sync.RWMutex
+	global  TransportPolicy
+	perPeer map[peer.ID]RelayMode
+	// infrastructure - ретрансляторы, с которыми можно соединяться напрямую
+	// при любой политике, иначе ретранслированный путь не построить
+	infrastructure map[peer.ID]bool
+	// talked - пиры, с которыми узел сам открывал потоки приложения
+	talked map[peer.ID]bool
+	// isContact сообщает, является ли пир контактом; вызывается вне mu
+	isContact func(peer.ID) bool
+	// hideIP - режим скрытия IP: с собеседниками только через ретранслятор,
+	// политики отдельных пиров его не ослабляют
+	hideIP bool
+}
+
+func newTransportPolicies(global TransportPolicy) *transportPolicies {
+	return &transportPolicies{
+		global:         global,
+		perPeer:        make(map[peer.ID]RelayMode),
+		infrastructure: make(map[peer.ID]bool),
+		talked:         make(map[peer.ID]bool),
+	}
+}
+
+// talk отмечает пира собеседником: к нему применяется глобальный режим
+func (p *transportPolicies) talk(id peer.ID) {
+	p.mu.Lock()
+	p.talked[id] = true
+	p.mu.Unlock()
+}
+
+// exempt разрешает прямые соединения с ретранслятором
+func (p *transportPolicies) exempt(id peer.ID) {
+	p.mu.Lock()
+	p.infrastructure[id] = true
+	p.mu.Unlock()
+}
+
+// relayMode возвращает режим ретрансляции для пира. Глобальный режим
+// действует только для собеседников, остальные пиры служебные
+func (p *transportPolicies) relayMode(id peer.ID) RelayMode {
+	p.mu.RLock()
+	if p.infrastructure[id] {
+		p.mu.RUnlock()
+		return RelayAllow
+	}
+	mode, own := p.perPeer[id]
+	if own && !p.hideIP {
+		p.mu.RUnlock()
+		return mode
+	}
+	global, talked, isContact := p.global.Relay, p.talked[id], p.isContact
+	p.mu.RUnlock()
+
+	if global == RelayAllow || own || talked || (isContact != nil && isContact(id)) {
+		return global
+	}
+	return RelayAllow
+}
+
+// allowAddr сообщает, разрешено ли соединение с пиром по этому адресу
+func (p *transportPolicies) allowAddr(id peer.ID, addr multiaddr.Multiaddr) bool {
+	relayed := isRelayAddr(addr)
+	switch p.relayMode(id) {
+	case RelayNever:
+		return !relayed
+	case RelayAlways:
+		return relayed
+	default:
+		return true
+	}
+}
+
+// rank задает порядок набора адресов с учетом предпочитаемого транспорта
+func (p *transportPolicies) rank(addrs []multiaddr.Multiaddr) []network.AddrDelay {
+	p.mu.RLock()
+	prefer := p.global.Prefer
+	p.mu.RUnlock()
+
+	if prefer == TransportAuto {
+		return swarm.DefaultDialRanker(addrs)
+	}
+
+	preferred := multiaddr.P_QUIC_V1
+	if prefer == TransportTCP {
+		preferred = multiaddr.P_TCP
+	}
+
+	ranked := make([]network.AddrDelay, 0, len(addrs))
+	for _, addr := range addrs {
+		var delay time.Duration
+		if isRelayAddr(addr) {
+			delay += relayDialDelay
+		}
+		if _, err := addr.ValueForProtocol(preferred); err != nil {
+			delay += preferredTransportLead
+		}
+		ranked = append(ranked, network.AddrDelay{Addr: addr, Delay: delay})
+	}
+	return ranked
+}
+
+// isRelayAddr сообщает, ведет ли адрес через ретранслятор
+func isRelayAddr(addr multiaddr.Multiaddr) bool {
+	_, err := addr.ValueForProtocol(multiaddr.P_CIRCUIT)
+	return err == nil
+}
+
+// SetTransportPolicy задает глобальную политику транспорта
+func (n *Node) SetTransportPolicy(policy TransportPolicy) error {
+	if err := policy.Validate(); err != nil {
+		return err
+	}
+
+	n.transportPolicy.mu.Lock()
+	n.transportPolicy.global = policy
+	n.transportPolicy.mu.Unlock()
+	return nil
+}
+
+// TransportPolicy возвращает глобальную политику транспорта
+func (n *Node) TransportPolicy() TransportPolicy {
+	n.transportPolicy.mu.RLock()
+	defer n.transportPolicy.mu.RUnlock()
+
+	return n.transportPolicy.global
+}
+
+// SetPeerRelayMode задает режим ретрансляции для отдельного пира; RelayAllow
+// возвращает пира к глобальной политике, а RelayAuto разрешает прямые
+// соединения при глобальном RelayAlways. В режиме скрытия IP политика пира
+// не действует. Уже открытые соединения, которые нарушают новый режим,
+// закрываются
+func (n *Node) SetPeerRelayMode(id peer.ID, mode RelayMode) error {
+	if _, err := ParseRelayMode(string(mode)); err != nil {
+		return err
+	}
+
+	n.transportPolicy.mu.Lock()
+	if mode == RelayAllow {
+		delete(n.transportPolicy.perPeer, id)
+	} else {
+		n.transportPolicy.perPeer[id] = mode
+	}
+	n.transportPolicy.mu.Unlock()
+
+	for _, conn := range n.host.Network().ConnsToPeer(id) {
+		if !n.transportPolicy.allowAddr(id, conn.RemoteMultiaddr()) {
+			conn.Close()
+		}
+	}
+	return nil
+}
+
+// PeerRelayMode возвращает режим ретрансляции, действующий для пира
+func (n *Node) PeerRelayMode(id peer.ID) RelayMode {
+	return n.transportPolicy.relayMode(id)
+}
@@ -0,0 +1,53 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+func TestGlobalRelayModeSkipsInfrastructure(t *testing.T) {
+	_, contact := testIdentity(t)
+	_, stranger := testIdentity(t)
+	_, dhtPeer := testIdentity(t)
+
+	policies := newTransportPolicies(TransportPolicy{Relay: RelayAlways})
+	policies.isContact = func(id peer.ID) bool { return id == contact }
+	policies.talk(stranger)
+
+	if mode := policies.relayMode(contact); mode != RelayAlways {
+		t.Fatalf("контакт: %q", mode)
+	}
+	if mode := policies.relayMode(stranger); mode != RelayAlways {
+		t.Fatalf("собеседник не из контактов: %q", mode)
+	}
+	// Пиры DHT и bootstrap набираются напрямую, иначе сеть не найти
+	if mode := policies.relayMode(dhtPeer); mode != RelayAllow {
+		t.Fatalf("служебный пир: %q", mode)
+	}
+}
+
+func TestPeerRelayModeOverrides(t *testing.T) {
+	_, coworker := testIdentity(t)
+	_, relay := testIdentity(t)
+
+	policies := newTransportPolicies(TransportPolicy{Relay: RelayAlways})
+	policies.isContact = func(peer.ID) bool { return true }
+	policies.exempt(relay)
+	policies.perPeer[coworker] = RelayAuto
+
+	// Коллеге в одной сети можно разрешить прямое соединение
+	if mode := policies.relayMode(coworker); mode != RelayAuto {
+		t.Fatalf("политика контакта не применена: %q", mode)
+	}
+	// С ретранслятором соединяемся напрямую и без hide_ip
+	if mode := policies.relayMode(relay); mode != RelayAllow {
+		t.Fatalf("ретранслятор: %q", mode)
+	}
+
+	// В режиме скрытия IP политика контакта его не ослабляет
+	policies.hideIP = true
+	if mode := policies.relayMode(coworker); mode != RelayAlways {
+		t.Fatalf("hide_ip ослаблен политикой контакта: %q", mode)
+	}
+}
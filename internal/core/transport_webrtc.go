@@ -0,0 +1,16 @@
+//go:build !nowebrtc
+
+package core
+
+import (
+	"github.com/libp2p/go-libp2p"
+	webrtc "github.com/libp2p/go-libp2p/p2p/transport/webrtc"
+	webtransport "github.com/libp2p/go-libp2p/p2p/transport/webtransport"
+)
+
+func init() {
+	optionalTransports = append(optionalTransports,
+		libp2p.Transport(webtransport.New),
+		libp2p.Transport(webrtc.New),
+	)
+}
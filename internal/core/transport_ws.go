@@ -0,0 +1,15 @@
+//go:build !nows
+
+package core
+
+import (
+	"github.com/libp2p/go-libp2p"
+	"github.com/libp2p/go-libp2p/p2p/transport/websocket"
+)
+
+// websocketSupported - собран ли транспорт WebSocket (тег сборки nows его отключает)
+const websocketSupported = true
+
+func init() {
+	optionalTransports = append(optionalTransports, libp2p.Transport(websocket.New))
+}
@@ -0,0 +1,7 @@
+//go:build nows
+
+package core
+
+// websocketSupported - сборка с тегом nows: WebSocket и маскировка
+// соединений под HTTPS недоступны
+const websocketSupported = false
@@ -0,0 +1,20 @@
+package core
+
+import (
+	"github.com/libp2p/go-libp2p"
+	quic "github.com/libp2p/go-libp2p/p2p/transport/quic"
+	"github.com/libp2p/go-libp2p/p2p/transport/tcp"
+)
+
+// Набор транспортов зависит от тегов сборки: nowebrtc убирает WebRTC Direct
+// и WebTransport, nows - WebSocket. TCP и QUIC доступны всегда
+var optionalTransports []libp2p.Option
+
+// transportOptions возвращает транспорты, собранные в эту версию библиотеки
+func transportOptions() []libp2p.Option {
+	opts := []libp2p.Option{
+		libp2p.Transport(tcp.NewTCPTransport),
+		libp2p.Transport(quic.NewTransport),
+	}
+	return append(opts, optionalTransports...)
+}
@@ -0,0 +1,5 @@
+package core
+
+// Version - версия приложения; при сборке релиза задается через
+// -ldflags "-X OwlWhisper/internal/core.Version=1.2.3"
+var Version = "0.1.0-dev"
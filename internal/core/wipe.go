@@ -0,0 +1,104 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+// IDENTITY_DESTROYED_PROTOCOL_ID - протокол уведомления контактов о том,
+// что личность уничтожена и больше не должна считаться доверенной
+const IDENTITY_DESTROYED_PROTOCOL_ID = "/owl-whisper/identity-destroyed/1.0.0"
+
+// identityDestroyedTimeout - сколько ждать доставки уведомления одному контакту
+const identityDestroyedTimeout = 5 * time.Second
+
+// IdentityDestroyed - подписанное уничтожаемым ключом уведомление;
+// полезная нагрузка EventIdentityDestroyed
+type IdentityDestroyed struct {
+	PeerID    peer.ID   `json:"peer_id"`
+	IssuedAt  time.Time `json:"issued_at"`
+	Signature []byte    `json:"signature"`
+}
+
+// statement возвращает подписываемый текст уведомления
+func (d IdentityDestroyed) statement() []byte {
+	return []byte(fmt.Sprintf("owl-whisper identity destroyed\npeer:%s\nissued:%s\n",
+		d.PeerID, d.IssuedAt.UTC().Format(time.RFC3339)))
+}
+
+// Verify проверяет подпись уведомления ключом PeerID
+func (d IdentityDestroyed) Verify() error {
+	pub, err := d.PeerID.ExtractPublicKey()
+	if err != nil {
+		return err
+	}
+	if ok, err := pub.Verify(d.statement(), d.Signature); err != nil || !ok {
+		return fmt.Errorf("подпись уведомления недействительна")
+	}
+	return nil
+}
+
+// NotifyIdentityDestroyed сообщает подключенным контактам, что ключ узла
+// уничтожен. Вызывается перед уничтожением ключа; возвращает число контактов,
+// получивших уведомление
+func (n *Node) NotifyIdentityDestroyed() (int, error) {
+	key := n.host.Peerstore().PrivKey(n.host.ID())
+	if key == nil {
+		return 0, fmt.Errorf("закрытый ключ узла недоступен")
+	}
+	notice := IdentityDestroyed{PeerID: n.host.ID(), IssuedAt: time.Now().UTC().Truncate(time.Second)}
+	signature, err := key.Sign(notice.statement())
+	if err != nil {
+		return 0, err
+	}
+	notice.Signature = signature
+
+	delivered := 0
+	for _, id := range n.host.Network().Peers() {
+		if !n.isKnownContact(id) {
+			continue
+		}
+		stream, err := n.newStream(n.ctx, id, IDENTITY_DESTROYED_PROTOCOL_ID)
+		if err != nil {
+			continue
+		}
+		stream.SetDeadline(time.Now().Add(identityDestroyedTimeout))
+		if err := json.NewEncoder(stream).Encode(notice); err == nil {
+			delivered++
+		}
+		stream.Close()
+	}
+	return delivered, nil
+}
+
+// handleIdentityDestroyedStream принимает уведомление об уничтожении личности контакта
+func (n *Node) handleIdentityDestroyedStream(stream network.Stream) {
+	defer stream.Close()
+	remotePeer := stream.Conn().RemotePeer()
+	stream.SetDeadline(time.Now().Add(identityDestroyedTimeout))
+
+	var notice IdentityDestroyed
+	if err := json.NewDecoder(io.LimitReader(stream, 4096)).Decode(&notice); err != nil {
+		stream.Reset()
+		return
+	}
+	if notice.PeerID != remotePeer || !n.isKnownContact(remotePeer) {
+		return
+	}
+	if err := notice.Verify(); err != nil {
+		n.emitSecurity(SecurityVerificationFailed, SeverityWarning, remotePeer,
+			stream.Conn().RemoteMultiaddr().String(), err.Error())
+		return
+	}
+
+	log.Printf("💥 %s сообщил об уничтожении своей личности", remotePeer.ShortString())
+	n.emitSecurity(SecurityIdentityDestroyed, SeverityCritical, remotePeer,
+		stream.Conn().RemoteMultiaddr().String(), "контакт уничтожил ключ личности")
+	n.emit(EventIdentityDestroyed, notice)
+}
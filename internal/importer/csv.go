@@ -0,0 +1,79 @@
+package importer
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// CSVSource разбирает простой CSV с заголовком и колонками
+// conversation, timestamp (RFC 3339), from, text и необязательной id.
+// Исходящие отмечаются значением "me" в колонке from
+type CSVSource struct{}
+
+// Name возвращает имя формата
+func (CSVSource) Name() string { return "csv" }
+
+// Parse читает CSV; порядок колонок определяется заголовком
+func (CSVSource) Parse(r io.Reader) ([]Conversation, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("не удалось прочитать заголовок: %w", err)
+	}
+	columns := make(map[string]int)
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, required := range []string{"conversation", "timestamp", "from", "text"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("нет колонки %q", required)
+		}
+	}
+	idColumn, hasID := columns["id"]
+
+	var conversations []Conversation
+	index := make(map[string]int)
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		field := func(name string) string {
+			if i := columns[name]; i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+
+		timestamp, err := time.Parse(time.RFC3339, field("timestamp"))
+		if err != nil {
+			return nil, fmt.Errorf("строка %d: некорректное время %q", line, field("timestamp"))
+		}
+		msg := Message{
+			Outgoing:  strings.EqualFold(field("from"), "me"),
+			Text:      field("text"),
+			Timestamp: timestamp,
+		}
+		if hasID && idColumn < len(record) {
+			msg.ID = record[idColumn]
+		}
+
+		name := field("conversation")
+		i, ok := index[name]
+		if !ok {
+			i = len(conversations)
+			index[name] = i
+			conversations = append(conversations, Conversation{Name: name})
+		}
+		conversations[i].Messages = append(conversations[i].Messages, msg)
+	}
+	return conversations, nil
+}
@@ -0,0 +1,189 @@
+package importer
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"OwlWhisper/pkg/interfaces"
+)
+
+// Source разбирает экспорт истории другого мессенджера
+type Source interface {
+	// Name возвращает имя формата, например "telegram"
+	Name() string
+
+	// Parse читает экспорт и возвращает найденные диалоги
+	Parse(r io.Reader) ([]Conversation, error)
+}
+
+// Conversation - диалог из внешнего мессенджера
+type Conversation struct {
+	// Name - имя собеседника во внешнем мессенджере
+	Name     string
+	Messages []Message
+}
+
+// Message - сообщение из внешнего мессенджера
+type Message struct {
+	// ID - идентификатор во внешнем мессенджере; пустой допускается
+	ID        string
+	Outgoing  bool
+	Text      string
+	Timestamp time.Time
+}
+
+// Options управляет сопоставлением диалогов с локальными контактами
+type Options struct {
+	// Self - собственный PeerID, от имени которого сохраняются исходящие
+	Self string
+	// Mapping задает PeerID для имен собеседников явно; остальные
+	// сопоставляются с контактами по имени без учета регистра
+	Mapping map[string]string
+}
+
+// Result - итог импорта
+type Result struct {
+	Imported  int               `json:"imported"`
+	Duplicate int               `json:"duplicate"`
+	Matched   map[string]string `json:"matched"`   // имя собеседника -> PeerID
+	Unmatched []string          `json:"unmatched"` // диалоги без контакта, пропущены
+}
+
+var (
+	sourcesMu sync.RWMutex
+	sources   = make(map[string]Source)
+)
+
+// Register добавляет формат импорта
+func Register(source Source) {
+	sourcesMu.Lock()
+	defer sourcesMu.Unlock()
+
+	sources[source.Name()] = source
+}
+
+// Get возвращает формат импорта по имени
+func Get(name string) (Source, error) {
+	sourcesMu.RLock()
+	defer sourcesMu.RUnlock()
+
+	source, ok := sources[strings.ToLower(name)]
+	if !ok {
+		return nil, fmt.Errorf("неизвестный формат импорта: %s (доступны: %s)", name, strings.Join(namesLocked(), ", "))
+	}
+	return source, nil
+}
+
+// Names возвращает имена доступных форматов
+func Names() []string {
+	sourcesMu.RLock()
+	defer sourcesMu.RUnlock()
+
+	return namesLocked()
+}
+
+// namesLocked возвращает отсортированные имена форматов
+func namesLocked() []string {
+	names := make([]string, 0, len(sources))
+	for name := range sources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func init() {
+	Register(TelegramSource{})
+	Register(CSVSource{})
+}
+
+// Import разбирает экспорт и сохраняет сообщения в историю с пометкой источника.
+// Повторный импорт того же файла не создает дубликатов
+func Import(ctx context.Context, source Source, r io.Reader, messages interfaces.IMessageRepository, contacts interfaces.IContactRepository, opts Options) (*Result, error) {
+	conversations, err := source.Parse(r)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось разобрать экспорт %s: %w", source.Name(), err)
+	}
+
+	known, err := contacts.GetAllContacts(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось загрузить контакты: %w", err)
+	}
+
+	result := &Result{Matched: make(map[string]string)}
+	for _, conversation := range conversations {
+		peerID := resolvePeer(conversation.Name, known, opts.Mapping)
+		if peerID == "" {
+			result.Unmatched = append(result.Unmatched, conversation.Name)
+			continue
+		}
+		result.Matched[conversation.Name] = peerID
+
+		existing, err := messages.GetMessages(ctx, opts.Self, peerID, 0, 0)
+		if err != nil {
+			return nil, fmt.Errorf("не удалось прочитать историю: %w", err)
+		}
+		seen := make(map[string]bool, len(existing))
+		for _, msg := range existing {
+			seen[msg.ID] = true
+		}
+
+		for i, imported := range conversation.Messages {
+			id := importedID(source.Name(), conversation.Name, imported, i)
+			if seen[id] {
+				result.Duplicate++
+				continue
+			}
+
+			msg := &interfaces.Message{
+				ID:           id,
+				FromPeer:     peerID,
+				ToPeer:       opts.Self,
+				Content:      imported.Text,
+				Timestamp:    imported.Timestamp,
+				Type:         "text",
+				IsRead:       true,
+				ImportedFrom: source.Name(),
+			}
+			if imported.Outgoing {
+				msg.FromPeer, msg.ToPeer = opts.Self, peerID
+			}
+			if err := messages.SaveMessage(ctx, msg); err != nil {
+				return result, fmt.Errorf("не удалось сохранить сообщение: %w", err)
+			}
+			seen[id] = true
+			result.Imported++
+		}
+	}
+	return result, nil
+}
+
+// resolvePeer находит PeerID собеседника по явному сопоставлению или имени контакта
+func resolvePeer(name string, contacts []*interfaces.Contact, mapping map[string]string) string {
+	if peerID, ok := mapping[name]; ok {
+		return peerID
+	}
+	for _, c := range contacts {
+		if strings.EqualFold(c.Nickname, name) {
+			return c.PeerID
+		}
+	}
+	return ""
+}
+
+// importedID строит устойчивый ID импортированного сообщения
+func importedID(source, conversation string, msg Message, index int) string {
+	key := msg.ID
+	if key == "" {
+		key = fmt.Sprintf("%d:%d", index, msg.Timestamp.UnixNano())
+	}
+	sum := sha256.Sum256([]byte(source + "\x00" + conversation + "\x00" + key))
+	return hex.EncodeToString(sum[:16])
+}
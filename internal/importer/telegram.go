@@ -0,0 +1,129 @@
+package importer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TelegramSource разбирает result.json из Telegram Desktop
+// ("Экспорт данных" в формате JSON): отдельный чат или весь аккаунт
+type TelegramSource struct{}
+
+// telegramChat - личный чат в экспорте Telegram
+type telegramChat struct {
+	Name     string            `json:"name"`
+	Type     string            `json:"type"`
+	ID       int64             `json:"id"`
+	Messages []telegramMessage `json:"messages"`
+}
+
+// telegramMessage - сообщение в экспорте Telegram
+type telegramMessage struct {
+	ID           int64           `json:"id"`
+	Type         string          `json:"type"`
+	Date         string          `json:"date"`
+	DateUnixtime string          `json:"date_unixtime"`
+	FromID       string          `json:"from_id"`
+	Text         json.RawMessage `json:"text"`
+}
+
+// Name возвращает имя формата
+func (TelegramSource) Name() string { return "telegram" }
+
+// Parse читает экспорт Telegram. Импортируются только личные чаты
+func (TelegramSource) Parse(r io.Reader) ([]Conversation, error) {
+	var export struct {
+		telegramChat
+		Chats struct {
+			List []telegramChat `json:"list"`
+		} `json:"chats"`
+	}
+	if err := json.NewDecoder(r).Decode(&export); err != nil {
+		return nil, err
+	}
+
+	chats := export.Chats.List
+	if len(chats) == 0 && export.Type != "" {
+		chats = []telegramChat{export.telegramChat}
+	}
+
+	var conversations []Conversation
+	for _, chat := range chats {
+		if chat.Type != "personal_chat" {
+			continue
+		}
+
+		// В личном чате входящие подписаны ID собеседника
+		peerFrom := "user" + strconv.FormatInt(chat.ID, 10)
+		conversation := Conversation{Name: chat.Name}
+		for _, m := range chat.Messages {
+			if m.Type != "message" {
+				continue
+			}
+			text := telegramText(m.Text)
+			if text == "" {
+				continue
+			}
+			timestamp, err := telegramTime(m)
+			if err != nil {
+				return nil, fmt.Errorf("сообщение %d: %w", m.ID, err)
+			}
+			conversation.Messages = append(conversation.Messages, Message{
+				ID:        strconv.FormatInt(m.ID, 10),
+				Outgoing:  m.FromID != peerFrom,
+				Text:      text,
+				Timestamp: timestamp,
+			})
+		}
+		conversations = append(conversations, conversation)
+	}
+	return conversations, nil
+}
+
+// telegramText собирает текст: строку или массив из строк и форматированных фрагментов
+func telegramText(raw json.RawMessage) string {
+	var text string
+	if err := json.Unmarshal(raw, &text); err == nil {
+		return text
+	}
+
+	var parts []json.RawMessage
+	if err := json.Unmarshal(raw, &parts); err != nil {
+		return ""
+	}
+	var builder strings.Builder
+	for _, part := range parts {
+		var plain string
+		if err := json.Unmarshal(part, &plain); err == nil {
+			builder.WriteString(plain)
+			continue
+		}
+		var entity struct {
+			Text string `json:"text"`
+		}
+		if err := json.Unmarshal(part, &entity); err == nil {
+			builder.WriteString(entity.Text)
+		}
+	}
+	return builder.String()
+}
+
+// telegramTime берет время из date_unixtime, а в старых экспортах - из date
+func telegramTime(m telegramMessage) (time.Time, error) {
+	if m.DateUnixtime != "" {
+		seconds, err := strconv.ParseInt(m.DateUnixtime, 10, 64)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("некорректное время: %s", m.DateUnixtime)
+		}
+		return time.Unix(seconds, 0), nil
+	}
+	t, err := time.ParseInLocation("2006-01-02T15:04:05", m.Date, time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("некорректное время: %s", m.Date)
+	}
+	return t, nil
+}
@@ -0,0 +1,174 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"OwlWhisper/internal/core"
+	"OwlWhisper/pkg/interfaces"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+// maxBodyLength - максимальная длина текста сообщения в уведомлении
+const maxBodyLength = 120
+
+// Dispatcher превращает события ядра в уведомления с учетом режима "не беспокоить"
+type Dispatcher struct {
+	service interfaces.INotificationService
+	names   func(peer.ID) string
+	prefs   interfaces.IPreferenceRepository
+
+	mu       sync.Mutex
+	schedule Schedule
+}
+
+// NewDispatcher создает диспетчер уведомлений. names возвращает отображаемое
+// имя пира; если nil, используется короткий PeerID
+func NewDispatcher(service interfaces.INotificationService, names func(peer.ID) string) *Dispatcher {
+	if names == nil {
+		names = func(id peer.ID) string { return id.ShortString() }
+	}
+	return &Dispatcher{
+		service: service,
+		names:   names,
+	}
+}
+
+// SetSchedule применяет новое расписание режима "не беспокоить"
+func (d *Dispatcher) SetSchedule(schedule Schedule) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.schedule = schedule
+}
+
+// SetPreferences подключает настройки диалогов: уведомления от заглушенных
+// собеседников не показываются
+func (d *Dispatcher) SetPreferences(prefs interfaces.IPreferenceRepository) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.prefs = prefs
+}
+
+// Schedule возвращает текущее расписание
+func (d *Dispatcher) Schedule() Schedule {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.schedule
+}
+
+// HandleEvent показывает уведомление для события ядра, если оно того требует
+func (d *Dispatcher) HandleEvent(event core.Event) {
+	notification, ok := d.notificationFor(event)
+	if !ok {
+		return
+	}
+	d.Notify(notification)
+}
+
+// Notify показывает уведомление, если его не подавляет режим "не беспокоить"
+// или настройки диалога. Используется и для уведомлений, не связанных
+// с событиями ядра (звонки)
+func (d *Dispatcher) Notify(notification interfaces.Notification) {
+	if !notification.Urgent && d.suppressed(notification, time.Now()) {
+		return
+	}
+	if err := d.service.Notify(notification); err != nil {
+		log.Printf("⚠️ %v", err)
+	}
+}
+
+// suppressed проверяет режим "не беспокоить" и настройки диалога
+func (d *Dispatcher) suppressed(notification interfaces.Notification, now time.Time) bool {
+	d.mu.Lock()
+	schedule := d.schedule
+	prefs := d.prefs
+	d.mu.Unlock()
+
+	if schedule.Quiet(now) {
+		return true
+	}
+	if prefs == nil || notification.PeerID == "" {
+		return false
+	}
+
+	conversation, err := prefs.GetPrefs(context.Background(), notification.PeerID)
+	if err != nil {
+		log.Printf("⚠️ Не удалось прочитать настройки диалога: %v", err)
+		return false
+	}
+	return conversation.Muted(now, notification.Mention)
+}
+
+// notificationFor строит уведомление по событию ядра
+func (d *Dispatcher) notificationFor(event core.Event) (interfaces.Notification, bool) {
+	switch payload := event.Payload.(type) {
+	case core.MessageEvent:
+		return interfaces.Notification{
+			Title:  d.names(payload.PeerID),
+			Body:   truncate(payload.Text, maxBodyLength),
+			PeerID: payload.PeerID.String(),
+			Kind:   interfaces.NotificationMessage,
+			// Личное сообщение всегда адресовано пользователю
+			Mention: true,
+		}, true
+
+	case core.IncomingFileOffer:
+		return interfaces.Notification{
+			Title:  fmt.Sprintf("%s предлагает файл", d.names(payload.Offer.PeerID)),
+			Body:   payload.Offer.Name,
+			PeerID: payload.Offer.PeerID.String(),
+			Kind:   interfaces.NotificationFile,
+		}, true
+
+	case core.FileReceived:
+		notification := interfaces.Notification{
+			PeerID: payload.PeerID.String(),
+			Kind:   interfaces.NotificationTransfer,
+		}
+		if payload.Error != "" {
+			notification.Title = "Файл не получен"
+			notification.Body = payload.Error
+		} else {
+			notification.Title = fmt.Sprintf("Файл от %s получен", d.names(payload.PeerID))
+			notification.Body = filepath.Base(payload.Path)
+		}
+		return notification, true
+
+	case core.TransferQueueState:
+		switch payload.Changed.State {
+		case core.TransferCompleted:
+			return interfaces.Notification{
+				Title:  "Передача завершена",
+				Body:   payload.Changed.Name,
+				PeerID: payload.Changed.PeerID.String(),
+				Kind:   interfaces.NotificationTransfer,
+			}, true
+		case core.TransferFailed:
+			return interfaces.Notification{
+				Title:  "Передача не удалась",
+				Body:   fmt.Sprintf("%s: %s", payload.Changed.Name, payload.Changed.Error),
+				PeerID: payload.Changed.PeerID.String(),
+				Kind:   interfaces.NotificationTransfer,
+			}, true
+		}
+	}
+
+	return interfaces.Notification{}, false
+}
+
+// truncate обрезает строку до limit символов
+func truncate(s string, limit int) string {
+	runes := []rune(s)
+	if len(runes) <= limit {
+		return s
+	}
+	return string(runes[:limit-1]) + "…"
+}
@@ -0,0 +1,117 @@
+package notify
+
+import (
+	"fmt"
+	"log"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"OwlWhisper/pkg/interfaces"
+)
+
+// DesktopNotifier показывает системные уведомления через утилиты ОС:
+// notify-send (Linux/BSD), osascript (macOS), PowerShell (Windows)
+type DesktopNotifier struct {
+	appName string
+}
+
+// NewDesktopNotifier создает сервис системных уведомлений
+func NewDesktopNotifier(appName string) *DesktopNotifier {
+	return &DesktopNotifier{appName: appName}
+}
+
+// Available проверяет наличие нужной утилиты
+func (d *DesktopNotifier) Available() bool {
+	_, err := exec.LookPath(d.tool())
+	return err == nil
+}
+
+// Notify показывает уведомление
+func (d *DesktopNotifier) Notify(n interfaces.Notification) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		script := fmt.Sprintf("display notification %s with title %s", appleScriptString(n.Body), appleScriptString(n.Title))
+		cmd = exec.Command("osascript", "-e", script)
+	case "windows":
+		cmd = exec.Command("powershell", "-NoProfile", "-Command", windowsToastScript(d.appName, n))
+	default:
+		urgency := "normal"
+		if n.Urgent {
+			urgency = "critical"
+		}
+		cmd = exec.Command("notify-send", "--app-name", d.appName, "--urgency", urgency, n.Title, n.Body)
+	}
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("не удалось показать уведомление: %w", err)
+	}
+	return nil
+}
+
+// tool возвращает имя утилиты уведомлений для текущей ОС
+func (d *DesktopNotifier) tool() string {
+	switch runtime.GOOS {
+	case "darwin":
+		return "osascript"
+	case "windows":
+		return "powershell"
+	default:
+		return "notify-send"
+	}
+}
+
+// LogNotifier выводит уведомления в лог; используется, когда системных нет
+type LogNotifier struct{}
+
+// Available всегда true
+func (LogNotifier) Available() bool { return true }
+
+// Notify выводит уведомление в лог
+func (LogNotifier) Notify(n interfaces.Notification) error {
+	log.Printf("🔔 %s: %s", n.Title, n.Body)
+	return nil
+}
+
+// NoopNotifier игнорирует уведомления (уведомления выключены)
+type NoopNotifier struct{}
+
+// Available всегда true
+func (NoopNotifier) Available() bool { return true }
+
+// Notify ничего не делает
+func (NoopNotifier) Notify(interfaces.Notification) error { return nil }
+
+// NewDefault выбирает лучший доступный сервис уведомлений
+func NewDefault(appName string, enabled bool) interfaces.INotificationService {
+	if !enabled {
+		return NoopNotifier{}
+	}
+	if desktop := NewDesktopNotifier(appName); desktop.Available() {
+		return desktop
+	}
+	return LogNotifier{}
+}
+
+// appleScriptString экранирует строку для AppleScript
+func appleScriptString(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	return `"` + s + `"`
+}
+
+// windowsToastScript собирает PowerShell-скрипт для toast-уведомления
+func windowsToastScript(appName string, n interfaces.Notification) string {
+	escape := func(s string) string {
+		s = strings.ReplaceAll(s, "'", "''")
+		s = strings.ReplaceAll(s, "&", "&amp;")
+		s = strings.ReplaceAll(s, "<", "&lt;")
+		return strings.ReplaceAll(s, ">", "&gt;")
+	}
+	return fmt.Sprintf(`[Windows.UI.Notifications.ToastNotificationManager, Windows.UI.Notifications, ContentType = WindowsRuntime] | Out-Null
+$xml = [Windows.Data.Xml.Dom.XmlDocument, Windows.Data.Xml.Dom.XmlDocument, ContentType = WindowsRuntime]::New()
+$xml.LoadXml('<toast><visual><binding template="ToastGeneric"><text>%s</text><text>%s</text></binding></visual></toast>')
+[Windows.UI.Notifications.ToastNotificationManager]::CreateToastNotifier('%s').Show([Windows.UI.Notifications.ToastNotification]::New($xml))`,
+		escape(n.Title), escape(n.Body), escape(appName))
+}
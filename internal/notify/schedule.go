@@ -0,0 +1,67 @@
+package notify
+
+import (
+	"fmt"
+	"time"
+)
+
+// Schedule - режим "не беспокоить": вручную или по расписанию тихих часов.
+// Срочные уведомления (звонки) показываются всегда
+type Schedule struct {
+	// DoNotDisturb выключает обычные уведомления до ручного отключения
+	DoNotDisturb bool
+	// QuietFrom и QuietTo - начало и конец тихих часов в формате "15:04";
+	// пустые значения выключают расписание. Интервал может переходить через полночь
+	QuietFrom string
+	QuietTo   string
+}
+
+// Validate проверяет формат времени тихих часов
+func (s Schedule) Validate() error {
+	if (s.QuietFrom == "") != (s.QuietTo == "") {
+		return fmt.Errorf("тихие часы задаются парой начало/конец")
+	}
+	if s.QuietFrom == "" {
+		return nil
+	}
+	if _, err := parseClock(s.QuietFrom); err != nil {
+		return err
+	}
+	_, err := parseClock(s.QuietTo)
+	return err
+}
+
+// Quiet сообщает, подавляются ли обычные уведомления в момент t
+func (s Schedule) Quiet(t time.Time) bool {
+	if s.DoNotDisturb {
+		return true
+	}
+	if s.QuietFrom == "" || s.QuietTo == "" {
+		return false
+	}
+
+	from, err := parseClock(s.QuietFrom)
+	if err != nil {
+		return false
+	}
+	to, err := parseClock(s.QuietTo)
+	if err != nil {
+		return false
+	}
+
+	now := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute
+	if from <= to {
+		return now >= from && now < to
+	}
+	// Интервал через полночь, например 22:00-07:00
+	return now >= from || now < to
+}
+
+// parseClock переводит "15:04" в смещение от начала суток
+func parseClock(value string) (time.Duration, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("некорректное время %q, ожидается ЧЧ:ММ", value)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
@@ -0,0 +1,124 @@
+// Package service запускает узел как фоновую службу ОС: Windows (SCM) и
+// macOS (launchd). В Linux для этого есть systemd, см. deploy/systemd
+package service
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// Name - имя службы в системе
+	Name = "OwlWhisper"
+	// description - описание службы для списка служб ОС
+	description = "OwlWhisper P2P node"
+)
+
+// Daemon - приложение, которое служба запускает и останавливает
+type Daemon interface {
+	// Run работает до остановки и корректно завершает приложение
+	Run() error
+	// Shutdown останавливает работающее приложение
+	Shutdown() error
+}
+
+// Command выполняет команду управления службой:
+//
+//	install   - зарегистрировать службу и запустить ее
+//	uninstall - остановить службу и удалить регистрацию
+//	run       - работать как служба (так ее запускает ОС)
+func Command(args []string, newDaemon func() (Daemon, error)) error {
+	if len(args) != 1 {
+		return fmt.Errorf("использование: owlwhisper service <install|uninstall|run>")
+	}
+
+	switch args[0] {
+	case "install":
+		exe, err := executable()
+		if err != nil {
+			return err
+		}
+		return install(exe)
+	case "uninstall":
+		return uninstall()
+	case "run":
+		// У службы нет терминала: интерфейс не читает ввод, а события
+		// продолжают выводиться в журнал
+		if null, err := os.Open(os.DevNull); err == nil {
+			os.Stdin = null
+		}
+		return run(newDaemon)
+	default:
+		return fmt.Errorf("неизвестная команда службы: %s", args[0])
+	}
+}
+
+// runDaemon создает приложение и работает до его остановки
+func runDaemon(newDaemon func() (Daemon, error)) error {
+	daemon, err := newDaemon()
+	if err != nil {
+		return err
+	}
+	return daemon.Run()
+}
+
+// executable возвращает абсолютный путь к запущенной программе для
+// регистрации службы
+func executable() (string, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return "", fmt.Errorf("не удалось определить путь к программе: %w", err)
+	}
+	if resolved, err := filepath.EvalSymlinks(exe); err == nil {
+		exe = resolved
+	}
+	return exe, nil
+}
+
+// severity - важность строки журнала для системного журнала ОС
+type severity int
+
+const (
+	severityInfo severity = iota
+	severityWarning
+	severityError
+)
+
+// lineSeverity определяет важность строки по значку, с которого приложение
+// начинает предупреждения и ошибки
+func lineSeverity(line string) severity {
+	line = strings.TrimSpace(line)
+	switch {
+	case strings.HasPrefix(line, "❌"):
+		return severityError
+	case strings.HasPrefix(line, "⚠️"):
+		return severityWarning
+	default:
+		return severityInfo
+	}
+}
+
+// logWriter передает каждую строку log в системный журнал с ее важностью
+type logWriter func(severity severity, line string) error
+
+// Write реализует io.Writer для log.SetOutput
+func (w logWriter) Write(p []byte) (int, error) {
+	line := strings.TrimRight(string(p), "\n")
+	if line == "" {
+		return len(p), nil
+	}
+	if err := w(lineSeverity(line), line); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// routeLogs направляет журнал приложения в системный журнал ОС. Время
+// добавляет сам системный журнал
+func routeLogs(w logWriter) {
+	log.SetFlags(0)
+	log.SetOutput(w)
+}
@@ -0,0 +1,146 @@
+package service
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"log"
+	"log/syslog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+)
+
+// launchdLabel - метка агента launchd
+const launchdLabel = "org.owlwhisper.daemon"
+
+// launchAgentTemplate - описание агента: запуск при входе в систему и
+// перезапуск после сбоя. Вывод, не попавший в журнал (например, panic),
+// сохраняется в ~/Library/Logs
+const launchAgentTemplate = `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>Label</key>
+	<string>%s</string>
+	<key>ProgramArguments</key>
+	<array>
+		<string>%s</string>
+		<string>service</string>
+		<string>run</string>
+	</array>
+	<key>RunAtLoad</key>
+	<true/>
+	<key>KeepAlive</key>
+	<dict>
+		<key>SuccessfulExit</key>
+		<false/>
+	</dict>
+	<key>ProcessType</key>
+	<string>Background</string>
+	<key>StandardErrorPath</key>
+	<string>%s</string>
+</dict>
+</plist>
+`
+
+// install сохраняет LaunchAgent пользователя и загружает его в launchd
+func install(exe string) error {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return err
+	}
+	path := launchAgentPath(home)
+	if _, err := os.Stat(path); err == nil {
+		return fmt.Errorf("служба уже установлена: %s", path)
+	}
+
+	logs := filepath.Join(home, "Library", "Logs", Name)
+	if err := os.MkdirAll(logs, 0700); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	plist := fmt.Sprintf(launchAgentTemplate, launchdLabel, xmlEscape(exe), xmlEscape(filepath.Join(logs, "stderr.log")))
+	if err := os.WriteFile(path, []byte(plist), 0644); err != nil {
+		return fmt.Errorf("не удалось сохранить LaunchAgent: %w", err)
+	}
+
+	if err := launchctl("bootstrap", launchdDomain(), path); err != nil {
+		os.Remove(path)
+		return err
+	}
+	log.Printf("✅ Служба установлена и запущена; журнал: log stream --predicate 'process == \"%s\"'", filepath.Base(exe))
+	return nil
+}
+
+// uninstall выгружает агента из launchd и удаляет его описание
+func uninstall() error {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return err
+	}
+	path := launchAgentPath(home)
+	if _, err := os.Stat(path); err != nil {
+		return fmt.Errorf("служба не установлена: %s", path)
+	}
+
+	if err := launchctl("bootout", launchdDomain()+"/"+launchdLabel); err != nil {
+		log.Printf("⚠️ %v", err)
+	}
+	if err := os.Remove(path); err != nil {
+		return fmt.Errorf("не удалось удалить LaunchAgent: %w", err)
+	}
+	log.Println("✅ Служба удалена")
+	return nil
+}
+
+// run работает как агент launchd с журналом в системном журнале macOS
+// (Console.app, log stream)
+func run(newDaemon func() (Daemon, error)) error {
+	writer, err := syslog.New(syslog.LOG_INFO|syslog.LOG_USER, Name)
+	if err != nil {
+		log.Printf("⚠️ Системный журнал недоступен, вывод остается в stderr: %v", err)
+		return runDaemon(newDaemon)
+	}
+	defer writer.Close()
+	routeLogs(func(severity severity, line string) error {
+		switch severity {
+		case severityError:
+			return writer.Err(line)
+		case severityWarning:
+			return writer.Warning(line)
+		default:
+			return writer.Info(line)
+		}
+	})
+	return runDaemon(newDaemon)
+}
+
+// launchAgentPath возвращает путь к описанию агента
+func launchAgentPath(home string) string {
+	return filepath.Join(home, "Library", "LaunchAgents", launchdLabel+".plist")
+}
+
+// launchdDomain возвращает домен launchd сеанса пользователя
+func launchdDomain() string {
+	return "gui/" + strconv.Itoa(os.Getuid())
+}
+
+// launchctl выполняет команду launchctl
+func launchctl(args ...string) error {
+	output, err := exec.Command("launchctl", args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("launchctl %s: %w: %s", args[0], err, bytes.TrimSpace(output))
+	}
+	return nil
+}
+
+// xmlEscape экранирует строку для вставки в plist
+func xmlEscape(s string) string {
+	var buf bytes.Buffer
+	xml.EscapeText(&buf, []byte(s))
+	return buf.String()
+}
@@ -0,0 +1,23 @@
+//go:build !windows && !darwin
+
+package service
+
+import "errors"
+
+// errUseSystemd - на остальных системах службой управляет systemd
+var errUseSystemd = errors.New("установите службу через systemd: см. deploy/systemd")
+
+// install не поддерживается: используйте unit-файлы systemd
+func install(exe string) error {
+	return errUseSystemd
+}
+
+// uninstall не поддерживается: используйте systemctl disable
+func uninstall() error {
+	return errUseSystemd
+}
+
+// run работает без терминала; журнал собирает менеджер служб (journald)
+func run(newDaemon func() (Daemon, error)) error {
+	return runDaemon(newDaemon)
+}
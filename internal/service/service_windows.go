@@ -0,0 +1,167 @@
+package service
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"golang.org/x/sys/windows/svc"
+	"golang.org/x/sys/windows/svc/eventlog"
+	"golang.org/x/sys/windows/svc/mgr"
+)
+
+const (
+	// eventID - код событий в журнале Windows (источник без файла сообщений)
+	eventID = 1
+	// restartDelay - пауза перед перезапуском упавшей службы
+	restartDelay = 5 * time.Second
+	// stopTimeout - сколько ждать остановки службы при удалении
+	stopTimeout = 30 * time.Second
+)
+
+// install регистрирует службу в SCM с автозапуском и перезапуском после
+// сбоя, источник журнала событий и запускает службу
+func install(exe string) error {
+	m, err := mgr.Connect()
+	if err != nil {
+		return fmt.Errorf("нет доступа к диспетчеру служб (нужны права администратора): %w", err)
+	}
+	defer m.Disconnect()
+
+	if s, err := m.OpenService(Name); err == nil {
+		s.Close()
+		return fmt.Errorf("служба %s уже установлена", Name)
+	}
+
+	s, err := m.CreateService(Name, exe, mgr.Config{
+		DisplayName: Name,
+		Description: description,
+		StartType:   mgr.StartAutomatic,
+	}, "service", "run")
+	if err != nil {
+		return fmt.Errorf("не удалось создать службу: %w", err)
+	}
+	defer s.Close()
+
+	restart := mgr.RecoveryAction{Type: mgr.ServiceRestart, Delay: restartDelay}
+	if err := s.SetRecoveryActions([]mgr.RecoveryAction{restart, restart, restart}, uint32((24 * time.Hour).Seconds())); err != nil {
+		log.Printf("⚠️ Не удалось настроить перезапуск службы: %v", err)
+	}
+
+	if err := eventlog.InstallAsEventCreate(Name, eventlog.Error|eventlog.Warning|eventlog.Info); err != nil {
+		s.Delete()
+		return fmt.Errorf("не удалось зарегистрировать журнал событий: %w", err)
+	}
+
+	if err := s.Start(); err != nil {
+		return fmt.Errorf("служба установлена, но не запустилась: %w", err)
+	}
+	log.Printf("✅ Служба %s установлена и запущена; журнал - в \"Просмотре событий\" (Приложение)", Name)
+	return nil
+}
+
+// uninstall останавливает службу и удаляет ее регистрацию
+func uninstall() error {
+	m, err := mgr.Connect()
+	if err != nil {
+		return fmt.Errorf("нет доступа к диспетчеру служб (нужны права администратора): %w", err)
+	}
+	defer m.Disconnect()
+
+	s, err := m.OpenService(Name)
+	if err != nil {
+		return fmt.Errorf("служба %s не установлена", Name)
+	}
+	defer s.Close()
+
+	if status, err := s.Control(svc.Stop); err == nil {
+		deadline := time.Now().Add(stopTimeout)
+		for status.State != svc.Stopped && time.Now().Before(deadline) {
+			time.Sleep(300 * time.Millisecond)
+			if status, err = s.Query(); err != nil {
+				break
+			}
+		}
+	}
+
+	if err := s.Delete(); err != nil {
+		return fmt.Errorf("не удалось удалить службу: %w", err)
+	}
+	if err := eventlog.Remove(Name); err != nil {
+		log.Printf("⚠️ Не удалось удалить источник журнала событий: %v", err)
+	}
+	log.Printf("✅ Служба %s удалена", Name)
+	return nil
+}
+
+// run работает под управлением SCM с журналом в журнале событий Windows.
+// Запущенная вручную программа работает как обычно, с выводом в консоль
+func run(newDaemon func() (Daemon, error)) error {
+	isService, err := svc.IsWindowsService()
+	if err != nil {
+		return fmt.Errorf("не удалось определить режим запуска: %w", err)
+	}
+	if !isService {
+		return runDaemon(newDaemon)
+	}
+
+	events, err := eventlog.Open(Name)
+	if err != nil {
+		return fmt.Errorf("не удалось открыть журнал событий: %w", err)
+	}
+	defer events.Close()
+	routeLogs(func(severity severity, line string) error {
+		switch severity {
+		case severityError:
+			return events.Error(eventID, line)
+		case severityWarning:
+			return events.Warning(eventID, line)
+		default:
+			return events.Info(eventID, line)
+		}
+	})
+
+	return svc.Run(Name, &windowsService{newDaemon: newDaemon})
+}
+
+// windowsService связывает команды SCM с приложением
+type windowsService struct {
+	newDaemon func() (Daemon, error)
+}
+
+// Execute запускает приложение и останавливает его по команде SCM
+func (w *windowsService) Execute(args []string, requests <-chan svc.ChangeRequest, status chan<- svc.Status) (bool, uint32) {
+	status <- svc.Status{State: svc.StartPending}
+
+	daemon, err := w.newDaemon()
+	if err != nil {
+		log.Printf("❌ Не удалось создать приложение: %v", err)
+		return true, 1
+	}
+	done := make(chan error, 1)
+	go func() { done <- daemon.Run() }()
+
+	status <- svc.Status{State: svc.Running, Accepts: svc.AcceptStop | svc.AcceptShutdown}
+	for {
+		select {
+		case err := <-done:
+			if err != nil {
+				log.Printf("❌ Ошибка выполнения приложения: %v", err)
+				return true, 1
+			}
+			return false, 0
+		case request := <-requests:
+			switch request.Cmd {
+			case svc.Interrogate:
+				status <- request.CurrentStatus
+			case svc.Stop, svc.Shutdown:
+				status <- svc.Status{State: svc.StopPending}
+				daemon.Shutdown()
+				if err := <-done; err != nil {
+					log.Printf("⚠️ Ошибка при остановке: %v", err)
+				}
+				return false, 0
+			}
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package storage
+
+import (
+	"context"
+	"os"
+
+	"OwlWhisper/pkg/interfaces"
+)
+
+// FindAttachment возвращает сообщения, ссылающиеся на вложение с хешем hash
+func (s *MessageStore) FindAttachment(ctx context.Context, hash string) ([]*interfaces.Message, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var result []*interfaces.Message
+	for _, msg := range s.messages {
+		if msg.Attachment != nil && msg.Attachment.Hash == hash {
+			copied := *msg
+			result = append(result, &copied)
+		}
+	}
+	return result, nil
+}
+
+// ResolveAttachment находит локальную копию вложения: сначала в хранилище
+// вложений, затем по последнему известному пути из сообщений с этим хешем.
+// Если копии нет, вложение можно запросить у пиров заново
+func ResolveAttachment(ctx context.Context, messages interfaces.IMessageRepository, media interfaces.IMediaRepository, hash string) (string, bool) {
+	if media != nil {
+		if path, ok := media.Path(ctx, hash); ok {
+			return path, true
+		}
+	}
+
+	found, err := messages.FindAttachment(ctx, hash)
+	if err != nil {
+		return "", false
+	}
+	for _, msg := range found {
+		if info, err := os.Stat(msg.Content); err == nil && info.Mode().IsRegular() {
+			return msg.Content, true
+		}
+	}
+	return "", false
+}
+
+// ReceivedFiles возвращает пути файлов, принятых узлом self: содержимое
+// сообщений типа "file", адресованных ему
+func (s *MessageStore) ReceivedFiles(self string) []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var paths []string
+	for _, msg := range s.messages {
+		if msg.Type == "file" && msg.ToPeer == self && msg.Content != "" {
+			paths = append(paths, msg.Content)
+		}
+	}
+	return paths
+}
@@ -0,0 +1,74 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"OwlWhisper/pkg/interfaces"
+)
+
+// AttestationStore хранит свои подтверждения личности в JSON файле
+type AttestationStore struct {
+	mu      sync.RWMutex
+	path    string
+	records []interfaces.AttestationRecord
+}
+
+// Проверяем соответствие интерфейсу репозитория
+var _ interfaces.IAttestationRepository = (*AttestationStore)(nil)
+
+// NewAttestationStore открывает (или создает) хранилище подтверждений по пути path
+func NewAttestationStore(path string) (*AttestationStore, error) {
+	store := &AttestationStore{path: path}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("не удалось создать директорию подтверждений: %w", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("не удалось прочитать подтверждения: %w", err)
+	}
+	if err := json.Unmarshal(data, &store.records); err != nil {
+		return nil, fmt.Errorf("не удалось разобрать подтверждения: %w", err)
+	}
+	return store, nil
+}
+
+// SaveAttestations заменяет список подтверждений
+func (s *AttestationStore) SaveAttestations(ctx context.Context, records []interfaces.AttestationRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.records = append([]interfaces.AttestationRecord(nil), records...)
+	return s.persistLocked()
+}
+
+// GetAttestations возвращает копию подтверждений
+func (s *AttestationStore) GetAttestations(ctx context.Context) ([]interfaces.AttestationRecord, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return append([]interfaces.AttestationRecord(nil), s.records...), nil
+}
+
+// persistLocked атомарно записывает подтверждения на диск
+func (s *AttestationStore) persistLocked() error {
+	data, err := json.MarshalIndent(s.records, "", "  ")
+	if err != nil {
+		return fmt.Errorf("не удалось сериализовать подтверждения: %w", err)
+	}
+
+	tmpPath := s.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0600); err != nil {
+		return fmt.Errorf("не удалось сохранить подтверждения: %w", err)
+	}
+	return os.Rename(tmpPath, s.path)
+}
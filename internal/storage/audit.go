@@ -0,0 +1,170 @@
+package storage
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// AuditLog - журнал событий безопасности только для дописывания.
+// Каждая запись содержит хеш предыдущей, поэтому изменение или удаление
+// записей в середине журнала обнаруживается при проверке цепочки
+type AuditLog struct {
+	mu       sync.Mutex
+	path     string
+	seq      uint64
+	lastHash string
+}
+
+// AuditEntry - запись журнала аудита
+type AuditEntry struct {
+	Seq      uint64          `json:"seq"`
+	Time     time.Time       `json:"time"`
+	Event    json.RawMessage `json:"event"`
+	PrevHash string          `json:"prev_hash"`
+	Hash     string          `json:"hash"`
+}
+
+// NewAuditLog открывает (или создает) журнал аудита по пути path.
+// Если цепочка хешей нарушена, журнал открывается, а ошибка проверки
+// возвращается вместе с ним, чтобы приложение могло предупредить пользователя
+func NewAuditLog(path string) (*AuditLog, error) {
+	auditLog := &AuditLog{path: path}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("не удалось создать директорию журнала: %w", err)
+	}
+
+	last, err := auditLog.walk()
+	if last != nil {
+		auditLog.seq = last.Seq
+		auditLog.lastHash = last.Hash
+	}
+	return auditLog, err
+}
+
+// Append дописывает событие в журнал
+func (l *AuditLog) Append(event interface{}) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("не удалось сериализовать событие: %w", err)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	entry := AuditEntry{
+		Seq:      l.seq + 1,
+		Time:     time.Now().UTC(),
+		Event:    data,
+		PrevHash: l.lastHash,
+	}
+	entry.Hash = auditHash(entry)
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("не удалось сериализовать запись журнала: %w", err)
+	}
+
+	file, err := os.OpenFile(l.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("не удалось открыть журнал аудита: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("не удалось записать в журнал аудита: %w", err)
+	}
+	if err := file.Sync(); err != nil {
+		return fmt.Errorf("не удалось записать в журнал аудита: %w", err)
+	}
+
+	l.seq = entry.Seq
+	l.lastHash = entry.Hash
+	return nil
+}
+
+// Verify проверяет цепочку хешей всего журнала
+func (l *AuditLog) Verify() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	_, err := l.walk()
+	return err
+}
+
+// walk читает журнал, проверяя цепочку, и возвращает последнюю запись
+func (l *AuditLog) walk() (*AuditEntry, error) {
+	file, err := os.Open(l.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("не удалось открыть журнал аудита: %w", err)
+	}
+	defer file.Close()
+
+	var last *AuditEntry
+	var broken error
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var entry AuditEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			if broken == nil {
+				broken = fmt.Errorf("журнал аудита поврежден после записи %d", lastSeq(last))
+			}
+			continue
+		}
+
+		if broken == nil {
+			switch {
+			case entry.Seq != lastSeq(last)+1:
+				broken = fmt.Errorf("журнал аудита: пропущены записи перед %d", entry.Seq)
+			case entry.PrevHash != lastHash(last):
+				broken = fmt.Errorf("журнал аудита: запись %d не связана с предыдущей", entry.Seq)
+			case entry.Hash != auditHash(entry):
+				broken = fmt.Errorf("журнал аудита: запись %d изменена", entry.Seq)
+			}
+		}
+		copied := entry
+		last = &copied
+	}
+	if err := scanner.Err(); err != nil {
+		return last, fmt.Errorf("не удалось прочитать журнал аудита: %w", err)
+	}
+	return last, broken
+}
+
+// auditHash вычисляет хеш записи вместе с хешем предыдущей
+func auditHash(entry AuditEntry) string {
+	h := sha256.New()
+	h.Write([]byte(entry.PrevHash))
+	h.Write([]byte(strconv.FormatUint(entry.Seq, 10)))
+	h.Write([]byte(entry.Time.Format(time.RFC3339Nano)))
+	h.Write(entry.Event)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// lastSeq возвращает номер записи или 0
+func lastSeq(entry *AuditEntry) uint64 {
+	if entry == nil {
+		return 0
+	}
+	return entry.Seq
+}
+
+// lastHash возвращает хеш записи или пустую строку для начала цепочки
+func lastHash(entry *AuditEntry) string {
+	if entry == nil {
+		return ""
+	}
+	return entry.Hash
+}
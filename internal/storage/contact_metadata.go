@@ -0,0 +1,95 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"OwlWhisper/pkg/interfaces"
+)
+
+// ContactMetadataStore хранит метаданные контактов (звук, цвет, заметки) в JSON файле
+type ContactMetadataStore struct {
+	mu       sync.RWMutex
+	path     string
+	metadata map[string]map[string]interfaces.ContactMetadataValue
+}
+
+// Проверяем соответствие интерфейсу репозитория
+var _ interfaces.IContactMetadataRepository = (*ContactMetadataStore)(nil)
+
+// NewContactMetadataStore открывает (или создает) хранилище метаданных по пути path
+func NewContactMetadataStore(path string) (*ContactMetadataStore, error) {
+	store := &ContactMetadataStore{
+		path:     path,
+		metadata: make(map[string]map[string]interfaces.ContactMetadataValue),
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("не удалось создать директорию метаданных: %w", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("не удалось прочитать метаданные контактов: %w", err)
+	}
+	if err := json.Unmarshal(data, &store.metadata); err != nil {
+		return nil, fmt.Errorf("не удалось разобрать метаданные контактов: %w", err)
+	}
+	return store, nil
+}
+
+// SaveContactMetadata заменяет метаданные пира
+func (s *ContactMetadataStore) SaveContactMetadata(ctx context.Context, peerID string, metadata map[string]interfaces.ContactMetadataValue) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(metadata) == 0 {
+		delete(s.metadata, peerID)
+	} else {
+		s.metadata[peerID] = copyContactMetadata(metadata)
+	}
+	return s.persistLocked()
+}
+
+// GetAllContactMetadata возвращает копию метаданных всех пиров
+func (s *ContactMetadataStore) GetAllContactMetadata(ctx context.Context) (map[string]map[string]interfaces.ContactMetadataValue, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make(map[string]map[string]interfaces.ContactMetadataValue, len(s.metadata))
+	for id, metadata := range s.metadata {
+		result[id] = copyContactMetadata(metadata)
+	}
+	return result, nil
+}
+
+// persistLocked атомарно записывает метаданные на диск
+func (s *ContactMetadataStore) persistLocked() error {
+	data, err := json.MarshalIndent(s.metadata, "", "  ")
+	if err != nil {
+		return fmt.Errorf("не удалось сериализовать метаданные контактов: %w", err)
+	}
+
+	tmpPath := s.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0600); err != nil {
+		return fmt.Errorf("не удалось сохранить метаданные контактов: %w", err)
+	}
+	return os.Rename(tmpPath, s.path)
+}
+
+// copyContactMetadata копирует набор метаданных вместе со значениями
+func copyContactMetadata(metadata map[string]interfaces.ContactMetadataValue) map[string]interfaces.ContactMetadataValue {
+	copied := make(map[string]interfaces.ContactMetadataValue, len(metadata))
+	for key, value := range metadata {
+		value.Value = append([]byte(nil), value.Value...)
+		copied[key] = value
+	}
+	return copied
+}
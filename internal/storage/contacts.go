@@ -0,0 +1,157 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"OwlWhisper/pkg/interfaces"
+)
+
+// ContactStore хранит контакты в JSON файле, переписывая его при каждом изменении
+type ContactStore struct {
+	mu       sync.RWMutex
+	path     string
+	contacts map[string]*interfaces.Contact
+}
+
+// Проверяем соответствие интерфейсу репозитория
+var _ interfaces.IContactRepository = (*ContactStore)(nil)
+
+// NewContactStore открывает (или создает) список контактов по пути path
+func NewContactStore(path string) (*ContactStore, error) {
+	store := &ContactStore{
+		path:     path,
+		contacts: make(map[string]*interfaces.Contact),
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("не удалось создать директорию контактов: %w", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("не удалось прочитать контакты: %w", err)
+	}
+
+	var contacts []*interfaces.Contact
+	if err := json.Unmarshal(data, &contacts); err != nil {
+		return nil, fmt.Errorf("не удалось разобрать контакты: %w", err)
+	}
+	for _, c := range contacts {
+		// Статус "в сети" не переживает перезапуск
+		c.IsOnline = false
+		store.contacts[c.PeerID] = c
+	}
+	return store, nil
+}
+
+// SaveContact сохраняет новый или заменяет существующий контакт
+func (s *ContactStore) SaveContact(ctx context.Context, contact *interfaces.Contact) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if contact.AddedAt.IsZero() {
+		contact.AddedAt = time.Now()
+	}
+	copied := *contact
+	s.contacts[contact.PeerID] = &copied
+	return s.persistLocked()
+}
+
+// GetContact возвращает контакт по PeerID
+func (s *ContactStore) GetContact(ctx context.Context, peerID string) (*interfaces.Contact, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	c, ok := s.contacts[peerID]
+	if !ok {
+		return nil, fmt.Errorf("контакт %s не найден", peerID)
+	}
+	copied := *c
+	return &copied, nil
+}
+
+// GetAllContacts возвращает все контакты, отсортированные по имени
+func (s *ContactStore) GetAllContacts(ctx context.Context) ([]*interfaces.Contact, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make([]*interfaces.Contact, 0, len(s.contacts))
+	for _, c := range s.contacts {
+		copied := *c
+		result = append(result, &copied)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Nickname < result[j].Nickname
+	})
+	return result, nil
+}
+
+// UpdateContact обновляет существующий контакт
+func (s *ContactStore) UpdateContact(ctx context.Context, contact *interfaces.Contact) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.contacts[contact.PeerID]; !ok {
+		return fmt.Errorf("контакт %s не найден", contact.PeerID)
+	}
+	copied := *contact
+	s.contacts[contact.PeerID] = &copied
+	return s.persistLocked()
+}
+
+// DeleteContact удаляет контакт
+func (s *ContactStore) DeleteContact(ctx context.Context, peerID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.contacts[peerID]; !ok {
+		return fmt.Errorf("контакт %s не найден", peerID)
+	}
+	delete(s.contacts, peerID)
+	return s.persistLocked()
+}
+
+// UpdateLastSeen обновляет время последнего появления пира, если он в контактах
+func (s *ContactStore) UpdateLastSeen(ctx context.Context, peerID string, lastSeen time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, ok := s.contacts[peerID]
+	if !ok {
+		return nil
+	}
+	c.LastSeen = lastSeen
+	return s.persistLocked()
+}
+
+// persistLocked атомарно записывает контакты на диск
+func (s *ContactStore) persistLocked() error {
+	contacts := make([]*interfaces.Contact, 0, len(s.contacts))
+	for _, c := range s.contacts {
+		contacts = append(contacts, c)
+	}
+	sort.Slice(contacts, func(i, j int) bool {
+		return contacts[i].AddedAt.Before(contacts[j].AddedAt)
+	})
+
+	data, err := json.MarshalIndent(contacts, "", "  ")
+	if err != nil {
+		return fmt.Errorf("не удалось сериализовать контакты: %w", err)
+	}
+
+	tmpPath := s.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0600); err != nil {
+		return fmt.Errorf("не удалось сохранить контакты: %w", err)
+	}
+	return os.Rename(tmpPath, s.path)
+}
@@ -0,0 +1,99 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"OwlWhisper/pkg/interfaces"
+)
+
+// draftsPath возвращает путь файла черновиков рядом с историей:
+// messages.jsonl -> messages.drafts.json
+func draftsPath(historyPath string) string {
+	base := strings.TrimSuffix(historyPath, filepath.Ext(historyPath))
+	return base + ".drafts.json"
+}
+
+// loadDrafts читает черновики; отсутствующий файл означает пустой список
+func loadDrafts(path string) (map[string]*interfaces.Draft, error) {
+	drafts := make(map[string]*interfaces.Draft)
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return drafts, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("не удалось прочитать черновики: %w", err)
+	}
+
+	var list []*interfaces.Draft
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("не удалось разобрать черновики: %w", err)
+	}
+	for _, d := range list {
+		drafts[d.PeerID] = d
+	}
+	return drafts, nil
+}
+
+// SaveDraft сохраняет черновик диалога с пиром; пустой текст удаляет черновик
+func (s *MessageStore) SaveDraft(ctx context.Context, peerID, content string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if strings.TrimSpace(content) == "" {
+		if _, ok := s.drafts[peerID]; !ok {
+			return nil
+		}
+		delete(s.drafts, peerID)
+	} else {
+		s.drafts[peerID] = &interfaces.Draft{
+			PeerID:    peerID,
+			Content:   content,
+			UpdatedAt: time.Now(),
+		}
+	}
+	return s.persistDraftsLocked()
+}
+
+// GetDraft возвращает черновик диалога с пиром или nil
+func (s *MessageStore) GetDraft(ctx context.Context, peerID string) (*interfaces.Draft, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	d, ok := s.drafts[peerID]
+	if !ok {
+		return nil, nil
+	}
+	copied := *d
+	return &copied, nil
+}
+
+// persistDraftsLocked атомарно записывает черновики на диск
+func (s *MessageStore) persistDraftsLocked() error {
+	list := make([]*interfaces.Draft, 0, len(s.drafts))
+	for _, d := range s.drafts {
+		list = append(list, d)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].UpdatedAt.Before(list[j].UpdatedAt)
+	})
+
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return fmt.Errorf("не удалось сериализовать черновики: %w", err)
+	}
+
+	path := draftsPath(s.path)
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0600); err != nil {
+		return fmt.Errorf("не удалось сохранить черновики: %w", err)
+	}
+	return os.Rename(tmpPath, path)
+}
@@ -0,0 +1,187 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"OwlWhisper/pkg/interfaces"
+)
+
+// Форматы экспорта диалога
+const (
+	ExportJSON = "json"
+	ExportHTML = "html"
+)
+
+// progressStep - как часто сообщать о прогрессе записи
+const progressStep = 100
+
+// ExportOptions описывает экспортируемый диалог
+type ExportOptions struct {
+	Self     string // PeerID владельца истории
+	PeerID   string // PeerID собеседника
+	SelfName string
+	PeerName string
+	Format   string // ExportJSON или ExportHTML
+	Path     string
+}
+
+// ExportProgressFunc получает число выгруженных сообщений и их общее количество
+type ExportProgressFunc func(done, total int)
+
+// ConversationArchive - переносимый архив диалога в формате JSON
+type ConversationArchive struct {
+	Version    int                   `json:"version"`
+	ExportedAt time.Time             `json:"exported_at"`
+	Self       ArchiveParticipant    `json:"self"`
+	Peer       ArchiveParticipant    `json:"peer"`
+	Messages   []*interfaces.Message `json:"messages"`
+}
+
+// ArchiveParticipant - участник диалога в архиве
+type ArchiveParticipant struct {
+	PeerID string `json:"peer_id"`
+	Name   string `json:"name,omitempty"`
+}
+
+// ExportConversation выгружает диалог в файл, сообщая прогресс по мере записи
+// сообщений. Файл пишется во временный и переименовывается после успешной записи
+func ExportConversation(ctx context.Context, repo interfaces.IMessageRepository, opts ExportOptions, progress ExportProgressFunc) error {
+	if opts.Format != ExportJSON && opts.Format != ExportHTML {
+		return fmt.Errorf("неизвестный формат экспорта: %s", opts.Format)
+	}
+
+	messages, err := repo.GetMessages(ctx, opts.Self, opts.PeerID, 0, 0)
+	if err != nil {
+		return fmt.Errorf("не удалось прочитать историю: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(opts.Path), 0700); err != nil {
+		return fmt.Errorf("не удалось создать директорию экспорта: %w", err)
+	}
+	tmpPath := opts.Path + ".tmp"
+	file, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("не удалось создать файл экспорта: %w", err)
+	}
+
+	archive := &ConversationArchive{
+		Version:    1,
+		ExportedAt: time.Now(),
+		Self:       ArchiveParticipant{PeerID: opts.Self, Name: opts.SelfName},
+		Peer:       ArchiveParticipant{PeerID: opts.PeerID, Name: opts.PeerName},
+		Messages:   messages,
+	}
+
+	writer := bufio.NewWriter(file)
+	err = writeArchive(ctx, writer, opts.Format, archive, progress)
+	if err == nil {
+		err = writer.Flush()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("не удалось записать экспорт: %w", err)
+	}
+
+	return os.Rename(tmpPath, opts.Path)
+}
+
+// writeArchive пишет архив по одному сообщению, чтобы отмена и прогресс
+// работали и на длинных диалогах
+func writeArchive(ctx context.Context, w io.Writer, format string, archive *ConversationArchive, progress ExportProgressFunc) error {
+	var err error
+	if format == ExportJSON {
+		header, _ := json.MarshalIndent(struct {
+			Version    int                `json:"version"`
+			ExportedAt time.Time          `json:"exported_at"`
+			Self       ArchiveParticipant `json:"self"`
+			Peer       ArchiveParticipant `json:"peer"`
+		}{archive.Version, archive.ExportedAt, archive.Self, archive.Peer}, "", "  ")
+		// Дописываем массив сообщений в тот же объект
+		_, err = fmt.Fprintf(w, "%s,\n  \"messages\": [", bytes.TrimSuffix(header, []byte("\n}")))
+	} else {
+		err = transcriptTemplate.ExecuteTemplate(w, "head", archive)
+	}
+	if err != nil {
+		return err
+	}
+
+	total := len(archive.Messages)
+	for i, msg := range archive.Messages {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if format == ExportJSON {
+			separator := ","
+			if i == 0 {
+				separator = ""
+			}
+			data, _ := json.MarshalIndent(msg, "    ", "  ")
+			_, err = fmt.Fprintf(w, "%s\n    %s", separator, data)
+		} else {
+			err = transcriptTemplate.ExecuteTemplate(w, "message", transcriptMessage{msg, msg.FromPeer == archive.Self.PeerID})
+		}
+		if err != nil {
+			return err
+		}
+
+		if progress != nil && ((i+1)%progressStep == 0 || i+1 == total) {
+			progress(i+1, total)
+		}
+	}
+
+	if format == ExportJSON {
+		_, err = io.WriteString(w, "\n  ]\n}\n")
+		return err
+	}
+	return transcriptTemplate.ExecuteTemplate(w, "foot", archive)
+}
+
+// transcriptMessage - сообщение для HTML-шаблона
+type transcriptMessage struct {
+	*interfaces.Message
+	Own bool
+}
+
+// transcriptTemplate - оформление HTML-версии диалога
+var transcriptTemplate = template.Must(template.New("transcript").Funcs(template.FuncMap{
+	"time": func(t time.Time) string { return t.Format("02.01.2006 15:04") },
+}).Parse(`{{define "head"}}<!DOCTYPE html>
+<html lang="ru">
+<head>
+<meta charset="utf-8">
+<title>OwlWhisper: {{if .Peer.Name}}{{.Peer.Name}}{{else}}{{.Peer.PeerID}}{{end}}</title>
+<style>
+body { font-family: sans-serif; background: #f4f4f6; margin: 0; padding: 24px; }
+h1 { font-size: 18px; color: #333; }
+.meta { color: #888; font-size: 12px; margin-bottom: 16px; }
+.msg { max-width: 70%; margin: 6px 0; padding: 8px 12px; border-radius: 10px; background: #fff; clear: both; }
+.msg.own { float: right; background: #d9f2d9; }
+.msg .time { color: #999; font-size: 11px; }
+.msg .file { font-style: italic; }
+.end { clear: both; }
+</style>
+</head>
+<body>
+<h1>Диалог с {{if .Peer.Name}}{{.Peer.Name}}{{else}}{{.Peer.PeerID}}{{end}}</h1>
+<div class="meta">Экспортировано {{time .ExportedAt}}, сообщений: {{len .Messages}}</div>
+{{end}}{{define "message"}}<div class="msg{{if .Own}} own{{end}}">
+<div class="time">{{time .Timestamp}}</div>
+{{if eq .Type "file"}}<div class="file">📎 {{.Content}}</div>{{else}}<div>{{.Content}}</div>{{end}}
+</div>
+{{end}}{{define "foot"}}<div class="end"></div>
+</body>
+</html>
+{{end}}`))
@@ -0,0 +1,84 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"OwlWhisper/pkg/interfaces"
+)
+
+// KeyTransitionStore - хранилище доверия: записи о сменах ключей в JSON файле
+type KeyTransitionStore struct {
+	mu      sync.RWMutex
+	path    string
+	records []interfaces.KeyTransitionRecord
+}
+
+// Проверяем соответствие интерфейсу репозитория
+var _ interfaces.IKeyTransitionRepository = (*KeyTransitionStore)(nil)
+
+// NewKeyTransitionStore открывает (или создает) хранилище смен ключей по пути path
+func NewKeyTransitionStore(path string) (*KeyTransitionStore, error) {
+	store := &KeyTransitionStore{path: path}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("не удалось создать директорию смен ключей: %w", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("не удалось прочитать смены ключей: %w", err)
+	}
+	if err := json.Unmarshal(data, &store.records); err != nil {
+		return nil, fmt.Errorf("не удалось разобрать смены ключей: %w", err)
+	}
+	return store, nil
+}
+
+// SaveKeyTransition сохраняет запись, заменяя запись с тем же старым PeerID
+func (s *KeyTransitionStore) SaveKeyTransition(ctx context.Context, record interfaces.KeyTransitionRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	replaced := false
+	for i, existing := range s.records {
+		if existing.OldPeerID == record.OldPeerID {
+			s.records[i] = record
+			replaced = true
+			break
+		}
+	}
+	if !replaced {
+		s.records = append(s.records, record)
+	}
+	return s.persistLocked()
+}
+
+// GetKeyTransitions возвращает копию записей
+func (s *KeyTransitionStore) GetKeyTransitions(ctx context.Context) ([]interfaces.KeyTransitionRecord, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return append([]interfaces.KeyTransitionRecord(nil), s.records...), nil
+}
+
+// persistLocked атомарно записывает смены ключей на диск
+func (s *KeyTransitionStore) persistLocked() error {
+	data, err := json.MarshalIndent(s.records, "", "  ")
+	if err != nil {
+		return fmt.Errorf("не удалось сериализовать смены ключей: %w", err)
+	}
+
+	tmpPath := s.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0600); err != nil {
+		return fmt.Errorf("не удалось сохранить смены ключей: %w", err)
+	}
+	return os.Rename(tmpPath, s.path)
+}
@@ -0,0 +1,114 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"OwlWhisper/pkg/interfaces"
+)
+
+// MailboxStore хранит письма почтового ящика в JSON файле до доставки владельцу
+type MailboxStore struct {
+	mu        sync.RWMutex
+	path      string
+	envelopes []interfaces.MailboxEnvelope
+}
+
+// Проверяем соответствие интерфейсу репозитория
+var _ interfaces.IMailboxRepository = (*MailboxStore)(nil)
+
+// NewMailboxStore открывает (или создает) почтовый ящик по пути path
+func NewMailboxStore(path string) (*MailboxStore, error) {
+	store := &MailboxStore{path: path}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("не удалось создать директорию почтового ящика: %w", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("не удалось прочитать почтовый ящик: %w", err)
+	}
+	if err := json.Unmarshal(data, &store.envelopes); err != nil {
+		return nil, fmt.Errorf("не удалось разобрать почтовый ящик: %w", err)
+	}
+	return store, nil
+}
+
+// SaveEnvelope сохраняет письмо
+func (s *MailboxStore) SaveEnvelope(ctx context.Context, envelope interfaces.MailboxEnvelope) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.envelopes = append(s.envelopes, envelope)
+	return s.persistLocked()
+}
+
+// GetEnvelopes возвращает письма владельца to в порядке поступления
+func (s *MailboxStore) GetEnvelopes(ctx context.Context, to string) ([]interfaces.MailboxEnvelope, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var result []interfaces.MailboxEnvelope
+	for _, envelope := range s.envelopes {
+		if envelope.To == to {
+			result = append(result, envelope)
+		}
+	}
+	return result, nil
+}
+
+// DeleteEnvelope удаляет письмо; отсутствие письма не считается ошибкой
+func (s *MailboxStore) DeleteEnvelope(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, envelope := range s.envelopes {
+		if envelope.ID == id {
+			s.envelopes = append(s.envelopes[:i], s.envelopes[i+1:]...)
+			return s.persistLocked()
+		}
+	}
+	return nil
+}
+
+// DeleteExpiredEnvelopes удаляет письма, сохраненные раньше before
+func (s *MailboxStore) DeleteExpiredEnvelopes(ctx context.Context, before time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	kept := s.envelopes[:0]
+	for _, envelope := range s.envelopes {
+		if envelope.StoredAt.After(before) {
+			kept = append(kept, envelope)
+		}
+	}
+	removed := len(s.envelopes) - len(kept)
+	s.envelopes = kept
+	if removed == 0 {
+		return 0, nil
+	}
+	return removed, s.persistLocked()
+}
+
+// persistLocked атомарно записывает почтовый ящик на диск
+func (s *MailboxStore) persistLocked() error {
+	data, err := json.MarshalIndent(s.envelopes, "", "  ")
+	if err != nil {
+		return fmt.Errorf("не удалось сериализовать почтовый ящик: %w", err)
+	}
+
+	tmpPath := s.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0600); err != nil {
+		return fmt.Errorf("не удалось сохранить почтовый ящик: %w", err)
+	}
+	return os.Rename(tmpPath, s.path)
+}
@@ -0,0 +1,50 @@
+package storage
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"OwlWhisper/pkg/interfaces"
+)
+
+func TestMailboxStoreExpiry(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "mailbox.json")
+	store, err := NewMailboxStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	for _, envelope := range []interfaces.MailboxEnvelope{
+		{ID: "old", To: "owner", Ciphertext: []byte{1}, StoredAt: now.Add(-48 * time.Hour)},
+		{ID: "new", To: "owner", Ciphertext: []byte{2}, StoredAt: now},
+	} {
+		if err := store.SaveEnvelope(ctx, envelope); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	removed, err := store.DeleteExpiredEnvelopes(ctx, now.Add(-24*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed != 1 {
+		t.Fatalf("удалено %d писем, ожидалось 1", removed)
+	}
+
+	// Удаление сохраняется на диске
+	reopened, err := NewMailboxStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	envelopes, err := reopened.GetEnvelopes(ctx, "owner")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(envelopes) != 1 || envelopes[0].ID != "new" {
+		t.Fatalf("после удаления остались %+v", envelopes)
+	}
+}
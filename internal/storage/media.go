@@ -0,0 +1,296 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"OwlWhisper/pkg/interfaces"
+)
+
+// MediaStore - хранилище вложений с адресацией по SHA-256 содержимого.
+// Одинаковые файлы из разных сообщений и диалогов хранятся один раз,
+// а счетчик ссылок показывает, когда копию можно удалить
+type MediaStore struct {
+	mu      sync.Mutex
+	dir     string
+	entries map[string]*mediaEntry
+}
+
+// mediaEntry - запись индекса о сохраненном файле
+type mediaEntry struct {
+	Size int64    `json:"size"`
+	Ext  string   `json:"ext,omitempty"`
+	Refs []string `json:"refs,omitempty"`
+}
+
+// Проверяем соответствие интерфейсу репозитория
+var _ interfaces.IMediaRepository = (*MediaStore)(nil)
+
+// NewMediaStore открывает (или создает) хранилище вложений в директории dir
+func NewMediaStore(dir string) (*MediaStore, error) {
+	store := &MediaStore{
+		dir:     dir,
+		entries: make(map[string]*mediaEntry),
+	}
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("не удалось создать директорию вложений: %w", err)
+	}
+
+	data, err := os.ReadFile(store.indexPath())
+	if os.IsNotExist(err) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("не удалось прочитать индекс вложений: %w", err)
+	}
+	if err := json.Unmarshal(data, &store.entries); err != nil {
+		return nil, fmt.Errorf("не удалось разобрать индекс вложений: %w", err)
+	}
+	return store, nil
+}
+
+// Store переносит файл path с хешем hash в хранилище и возвращает путь
+// сохраненной копии. Если такое содержимое уже есть, файл path удаляется
+func (s *MediaStore) Store(ctx context.Context, path, hash string) (string, error) {
+	if !validHash(hash) {
+		return "", fmt.Errorf("некорректный хеш вложения: %q", hash)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if entry, ok := s.entries[hash]; ok {
+		blob := s.blobPath(hash, entry.Ext)
+		if _, err := os.Stat(blob); err == nil {
+			if filepath.Clean(path) != blob {
+				os.Remove(path)
+			}
+			return blob, nil
+		}
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", fmt.Errorf("не удалось открыть вложение: %w", err)
+	}
+	entry := &mediaEntry{Size: info.Size(), Ext: strings.ToLower(filepath.Ext(path))}
+	if previous, ok := s.entries[hash]; ok {
+		entry.Refs = previous.Refs
+	}
+
+	blob := s.blobPath(hash, entry.Ext)
+	if err := moveFile(path, blob); err != nil {
+		return "", fmt.Errorf("не удалось сохранить вложение: %w", err)
+	}
+	s.entries[hash] = entry
+	return blob, s.persistLocked()
+}
+
+// AddRef отмечает, что вложение hash используется ссылкой ref (ID сообщения)
+func (s *MediaStore) AddRef(ctx context.Context, hash, ref string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.entries[hash]
+	if !ok {
+		return fmt.Errorf("вложение %s не найдено", hash)
+	}
+	for _, existing := range entry.Refs {
+		if existing == ref {
+			return nil
+		}
+	}
+	entry.Refs = append(entry.Refs, ref)
+	return s.persistLocked()
+}
+
+// Release снимает ссылку ref. Когда на вложение больше никто не ссылается,
+// его копия удаляется и возвращается освобожденный размер. found сообщает,
+// была ли ссылка известна хранилищу
+func (s *MediaStore) Release(ctx context.Context, ref string) (freed int64, found bool, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for hash, entry := range s.entries {
+		i := indexOf(entry.Refs, ref)
+		if i < 0 {
+			continue
+		}
+
+		entry.Refs = append(entry.Refs[:i], entry.Refs[i+1:]...)
+		if len(entry.Refs) == 0 {
+			freed = s.removeLocked(hash, entry)
+		}
+		return freed, true, s.persistLocked()
+	}
+	return 0, false, nil
+}
+
+// Path возвращает путь к копии вложения, если она есть
+func (s *MediaStore) Path(ctx context.Context, hash string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.entries[hash]
+	if !ok {
+		return "", false
+	}
+	blob := s.blobPath(hash, entry.Ext)
+	if _, err := os.Stat(blob); err != nil {
+		return "", false
+	}
+	return blob, true
+}
+
+// Owns сообщает, что путь указывает внутрь хранилища вложений. Такие файлы
+// удаляются только через Release, иначе пропадут копии других сообщений
+func (s *MediaStore) Owns(path string) bool {
+	rel, err := filepath.Rel(s.dir, filepath.Clean(path))
+	return err == nil && rel != "." && !strings.HasPrefix(rel, "..")
+}
+
+// CollectGarbage удаляет вложения без ссылок и файлы, которых нет в индексе
+// (остаются после сбоя между сохранением файла и записью индекса)
+func (s *MediaStore) CollectGarbage(ctx context.Context, progress interfaces.CleanupProgressFunc) (*interfaces.CleanupResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result := &interfaces.CleanupResult{}
+	var blobs []string
+	err := filepath.WalkDir(s.dir, func(path string, d os.DirEntry, err error) error {
+		if err == nil && d.Type().IsRegular() && path != s.indexPath() && !strings.HasSuffix(path, ".tmp") {
+			blobs = append(blobs, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	total := len(blobs)
+	for i, blob := range blobs {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+
+		hash := strings.TrimSuffix(filepath.Base(blob), filepath.Ext(blob))
+		entry, ok := s.entries[hash]
+		if !ok || len(entry.Refs) == 0 || s.blobPath(hash, entry.Ext) != blob {
+			if info, err := os.Stat(blob); err == nil && os.Remove(blob) == nil {
+				result.Files++
+				result.FreedBytes += info.Size()
+			}
+		}
+
+		if progress != nil && ((i+1)%cleanupProgressStep == 0 || i+1 == total) {
+			progress(i+1, total)
+		}
+	}
+
+	// Записи индекса без ссылок или без файла больше не нужны
+	for hash, entry := range s.entries {
+		if _, err := os.Stat(s.blobPath(hash, entry.Ext)); len(entry.Refs) == 0 || err != nil {
+			delete(s.entries, hash)
+		}
+	}
+	return result, s.persistLocked()
+}
+
+// removeLocked удаляет копию вложения и его запись индекса
+func (s *MediaStore) removeLocked(hash string, entry *mediaEntry) int64 {
+	delete(s.entries, hash)
+	if err := os.Remove(s.blobPath(hash, entry.Ext)); err != nil {
+		return 0
+	}
+	return entry.Size
+}
+
+// blobPath возвращает путь копии: файлы раскладываются по подкаталогам
+// из первых двух символов хеша, чтобы не держать все в одной директории
+func (s *MediaStore) blobPath(hash, ext string) string {
+	return filepath.Join(s.dir, hash[:2], hash+ext)
+}
+
+// indexPath возвращает путь индекса вложений
+func (s *MediaStore) indexPath() string {
+	return filepath.Join(s.dir, "index.json")
+}
+
+// persistLocked атомарно записывает индекс на диск
+func (s *MediaStore) persistLocked() error {
+	data, err := json.MarshalIndent(s.entries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("не удалось сериализовать индекс вложений: %w", err)
+	}
+
+	tmpPath := s.indexPath() + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0600); err != nil {
+		return fmt.Errorf("не удалось сохранить индекс вложений: %w", err)
+	}
+	return os.Rename(tmpPath, s.indexPath())
+}
+
+// moveFile переносит файл, копируя его, если rename невозможен
+// (например, загрузки и хранилище на разных дисках)
+func moveFile(from, to string) error {
+	if err := os.MkdirAll(filepath.Dir(to), 0700); err != nil {
+		return err
+	}
+	if err := os.Rename(from, to); err == nil {
+		return nil
+	}
+
+	src, err := os.Open(from)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(to+".tmp", os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(dst, src)
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(to+".tmp", to)
+	}
+	if err != nil {
+		os.Remove(to + ".tmp")
+		return err
+	}
+	src.Close()
+	return os.Remove(from)
+}
+
+// validHash проверяет, что строка - шестнадцатеричный SHA-256
+func validHash(hash string) bool {
+	if len(hash) != 64 {
+		return false
+	}
+	for _, c := range hash {
+		if !strings.ContainsRune("0123456789abcdef", c) {
+			return false
+		}
+	}
+	return true
+}
+
+// indexOf возвращает позицию строки в срезе или -1
+func indexOf(values []string, value string) int {
+	for i, v := range values {
+		if v == value {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,331 @@
+package storage
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"OwlWhisper/pkg/interfaces"
+)
+
+// MessageStore хранит историю сообщений в файле JSON Lines.
+// Новые сообщения дописываются в конец, изменения переписывают файл целиком
+type MessageStore struct {
+	mu       sync.RWMutex
+	path     string
+	messages []*interfaces.Message
+
+	// lastRead - время последнего прочитанного сообщения каждого собеседника
+	lastRead map[string]time.Time
+	onUnread UnreadHandler
+
+	// drafts - черновики диалогов, хранятся в отдельном файле (см. draftsPath)
+	drafts map[string]*interfaces.Draft
+
+	// media - хранилище вложений; через него освобождаются общие копии файлов
+	media interfaces.IMediaRepository
+}
+
+// UnreadHandler вызывается при изменении числа непрочитанных сообщений от пира
+type UnreadHandler func(peerID string, unread int)
+
+// Проверяем соответствие интерфейсу репозитория
+var _ interfaces.IMessageRepository = (*MessageStore)(nil)
+
+// NewMessageStore открывает (или создает) историю сообщений по пути path
+func NewMessageStore(path string) (*MessageStore, error) {
+	store := &MessageStore{
+		path:     path,
+		lastRead: make(map[string]time.Time),
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("не удалось создать директорию истории: %w", err)
+	}
+
+	drafts, err := loadDrafts(draftsPath(path))
+	if err != nil {
+		return nil, err
+	}
+	store.drafts = drafts
+
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("не удалось открыть историю: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var msg interfaces.Message
+		if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil {
+			// Битая строка (например, после сбоя записи) не должна ломать всю историю
+			continue
+		}
+		store.messages = append(store.messages, &msg)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("не удалось прочитать историю: %w", err)
+	}
+
+	sort.SliceStable(store.messages, func(i, j int) bool {
+		return store.messages[i].Timestamp.Before(store.messages[j].Timestamp)
+	})
+	for _, msg := range store.messages {
+		if msg.IsRead {
+			store.advanceLastRead(msg.FromPeer, msg.Timestamp)
+		}
+	}
+	return store, nil
+}
+
+// SaveMessage сохраняет сообщение, присваивая ID, если он не задан
+func (s *MessageStore) SaveMessage(ctx context.Context, message *interfaces.Message) error {
+	if message.ID == "" {
+		message.ID = newMessageID()
+	}
+
+	data, err := json.Marshal(message)
+	if err != nil {
+		return fmt.Errorf("не удалось сериализовать сообщение: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	file, err := os.OpenFile(s.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("не удалось открыть историю: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("не удалось записать сообщение: %w", err)
+	}
+
+	// Импортированные сообщения бывают старше уже сохраненных: держим порядок по времени
+	i := sort.Search(len(s.messages), func(i int) bool {
+		return s.messages[i].Timestamp.After(message.Timestamp)
+	})
+	s.messages = append(s.messages, nil)
+	copy(s.messages[i+1:], s.messages[i:])
+	s.messages[i] = message
+	if message.IsRead {
+		s.advanceLastRead(message.FromPeer, message.Timestamp)
+	} else {
+		s.notifyUnreadLocked(message.FromPeer)
+	}
+	return nil
+}
+
+// GetMessages возвращает сообщения между двумя пирами в хронологическом порядке.
+// offset отсчитывается от самого нового сообщения, что удобно для прокрутки назад
+func (s *MessageStore) GetMessages(ctx context.Context, peer1, peer2 string, limit int, offset int) ([]*interfaces.Message, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var conversation []*interfaces.Message
+	for _, msg := range s.messages {
+		if isBetween(msg, peer1, peer2) {
+			conversation = append(conversation, msg)
+		}
+	}
+
+	end := len(conversation) - offset
+	if end <= 0 {
+		return nil, nil
+	}
+	start := 0
+	if limit > 0 && end-limit > 0 {
+		start = end - limit
+	}
+
+	result := make([]*interfaces.Message, end-start)
+	copy(result, conversation[start:end])
+	return result, nil
+}
+
+// GetLastMessage возвращает последнее сообщение между двумя пирами
+func (s *MessageStore) GetLastMessage(ctx context.Context, peer1, peer2 string) (*interfaces.Message, error) {
+	messages, err := s.GetMessages(ctx, peer1, peer2, 1, 0)
+	if err != nil || len(messages) == 0 {
+		return nil, err
+	}
+	return messages[0], nil
+}
+
+// DeleteMessage удаляет сообщение по ID
+func (s *MessageStore) DeleteMessage(ctx context.Context, messageID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, msg := range s.messages {
+		if msg.ID == messageID {
+			s.messages = append(s.messages[:i], s.messages[i+1:]...)
+			return s.rewriteLocked()
+		}
+	}
+	return fmt.Errorf("сообщение %s не найдено", messageID)
+}
+
+// SetUnreadHandler задает обработчик изменений счетчиков непрочитанных.
+// Обработчик вызывается под блокировкой хранилища и не должен обращаться к нему
+func (s *MessageStore) SetUnreadHandler(handler UnreadHandler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.onUnread = handler
+}
+
+// SetMediaRepository подключает хранилище вложений с дедупликацией: при очистке
+// вложения из него не удаляются напрямую, а теряют ссылку сообщения
+func (s *MessageStore) SetMediaRepository(media interfaces.IMediaRepository) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.media = media
+}
+
+// GetUnreadCount возвращает количество непрочитанных сообщений от пира
+func (s *MessageStore) GetUnreadCount(ctx context.Context, peerID string) (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.unreadLocked(peerID), nil
+}
+
+// GetUnreadCounts возвращает число непрочитанных сообщений по всем диалогам,
+// в которых они есть
+func (s *MessageStore) GetUnreadCounts(ctx context.Context) (map[string]int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	counts := make(map[string]int)
+	for _, msg := range s.messages {
+		if !msg.IsRead {
+			counts[msg.FromPeer]++
+		}
+	}
+	return counts, nil
+}
+
+// GetLastRead возвращает время последнего прочитанного сообщения от пира
+func (s *MessageStore) GetLastRead(ctx context.Context, peerID string) (time.Time, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.lastRead[peerID], nil
+}
+
+// MarkAsRead отмечает все сообщения от пира как прочитанные
+func (s *MessageStore) MarkAsRead(ctx context.Context, peerID string) error {
+	return s.MarkConversationRead(ctx, peerID, time.Time{})
+}
+
+// MarkConversationRead отмечает прочитанными сообщения от пира не новее upTo.
+// Нулевое upTo отмечает весь диалог
+func (s *MessageStore) MarkConversationRead(ctx context.Context, peerID string, upTo time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	changed := false
+	for _, msg := range s.messages {
+		if msg.FromPeer != peerID || msg.IsRead {
+			continue
+		}
+		if !upTo.IsZero() && msg.Timestamp.After(upTo) {
+			continue
+		}
+		msg.IsRead = true
+		s.advanceLastRead(peerID, msg.Timestamp)
+		changed = true
+	}
+	if !changed {
+		return nil
+	}
+	if err := s.rewriteLocked(); err != nil {
+		return err
+	}
+	s.notifyUnreadLocked(peerID)
+	return nil
+}
+
+// unreadLocked считает непрочитанные сообщения от пира
+func (s *MessageStore) unreadLocked(peerID string) int {
+	count := 0
+	for _, msg := range s.messages {
+		if msg.FromPeer == peerID && !msg.IsRead {
+			count++
+		}
+	}
+	return count
+}
+
+// notifyUnreadLocked сообщает обработчику новое число непрочитанных от пира
+func (s *MessageStore) notifyUnreadLocked(peerID string) {
+	if s.onUnread != nil {
+		s.onUnread(peerID, s.unreadLocked(peerID))
+	}
+}
+
+// advanceLastRead сдвигает отметку прочтения вперед
+func (s *MessageStore) advanceLastRead(peerID string, t time.Time) {
+	if t.After(s.lastRead[peerID]) {
+		s.lastRead[peerID] = t
+	}
+}
+
+// rewriteLocked атомарно переписывает файл истории из памяти
+func (s *MessageStore) rewriteLocked() error {
+	tmpPath := s.path + ".tmp"
+	file, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("не удалось переписать историю: %w", err)
+	}
+
+	writer := bufio.NewWriter(file)
+	encoder := json.NewEncoder(writer)
+	for _, msg := range s.messages {
+		if err := encoder.Encode(msg); err != nil {
+			file.Close()
+			os.Remove(tmpPath)
+			return fmt.Errorf("не удалось сериализовать сообщение: %w", err)
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		file.Close()
+		os.Remove(tmpPath)
+		return fmt.Errorf("не удалось переписать историю: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("не удалось переписать историю: %w", err)
+	}
+
+	return os.Rename(tmpPath, s.path)
+}
+
+// isBetween проверяет, что сообщение относится к диалогу двух пиров
+func isBetween(msg *interfaces.Message, peer1, peer2 string) bool {
+	return (msg.FromPeer == peer1 && msg.ToPeer == peer2) ||
+		(msg.FromPeer == peer2 && msg.ToPeer == peer1)
+}
+
+// newMessageID генерирует случайный ID сообщения
+func newMessageID() string {
+	buf := make([]byte, 16)
+	rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
@@ -0,0 +1,113 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"OwlWhisper/pkg/interfaces"
+)
+
+// OutboxStore хранит отложенные сообщения в JSON файле, переписывая его при каждом изменении
+type OutboxStore struct {
+	mu       sync.RWMutex
+	path     string
+	messages map[string]*interfaces.ScheduledMessage
+}
+
+// Проверяем соответствие интерфейсу репозитория
+var _ interfaces.IOutboxRepository = (*OutboxStore)(nil)
+
+// NewOutboxStore открывает (или создает) очередь отложенных сообщений по пути path
+func NewOutboxStore(path string) (*OutboxStore, error) {
+	store := &OutboxStore{
+		path:     path,
+		messages: make(map[string]*interfaces.ScheduledMessage),
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("не удалось создать директорию очереди: %w", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("не удалось прочитать очередь: %w", err)
+	}
+
+	var messages []*interfaces.ScheduledMessage
+	if err := json.Unmarshal(data, &messages); err != nil {
+		return nil, fmt.Errorf("не удалось разобрать очередь: %w", err)
+	}
+	for _, msg := range messages {
+		store.messages[msg.ID] = msg
+	}
+	return store, nil
+}
+
+// SaveScheduled сохраняет отложенное сообщение, присваивая ID, если он не задан
+func (s *OutboxStore) SaveScheduled(ctx context.Context, message *interfaces.ScheduledMessage) error {
+	if message.ID == "" {
+		message.ID = newMessageID()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	copied := *message
+	s.messages[message.ID] = &copied
+	return s.persistLocked()
+}
+
+// DeleteScheduled удаляет отложенное сообщение
+func (s *OutboxStore) DeleteScheduled(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.messages[id]; !ok {
+		return fmt.Errorf("отложенное сообщение %s не найдено", id)
+	}
+	delete(s.messages, id)
+	return s.persistLocked()
+}
+
+// GetScheduled возвращает отложенные сообщения в порядке отправки
+func (s *OutboxStore) GetScheduled(ctx context.Context) ([]*interfaces.ScheduledMessage, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.sortedLocked(), nil
+}
+
+// sortedLocked возвращает копии сообщений, отсортированные по времени отправки
+func (s *OutboxStore) sortedLocked() []*interfaces.ScheduledMessage {
+	result := make([]*interfaces.ScheduledMessage, 0, len(s.messages))
+	for _, msg := range s.messages {
+		copied := *msg
+		result = append(result, &copied)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].SendAt.Before(result[j].SendAt)
+	})
+	return result
+}
+
+// persistLocked атомарно записывает очередь на диск
+func (s *OutboxStore) persistLocked() error {
+	data, err := json.MarshalIndent(s.sortedLocked(), "", "  ")
+	if err != nil {
+		return fmt.Errorf("не удалось сериализовать очередь: %w", err)
+	}
+
+	tmpPath := s.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0600); err != nil {
+		return fmt.Errorf("не удалось сохранить очередь: %w", err)
+	}
+	return os.Rename(tmpPath, s.path)
+}
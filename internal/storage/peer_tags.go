@@ -0,0 +1,85 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"OwlWhisper/pkg/interfaces"
+)
+
+// PeerTagStore хранит метки пиров (семья, работа, бот) в JSON файле
+type PeerTagStore struct {
+	mu   sync.RWMutex
+	path string
+	tags map[string][]string
+}
+
+// Проверяем соответствие интерфейсу репозитория
+var _ interfaces.IPeerTagRepository = (*PeerTagStore)(nil)
+
+// NewPeerTagStore открывает (или создает) хранилище меток по пути path
+func NewPeerTagStore(path string) (*PeerTagStore, error) {
+	store := &PeerTagStore{
+		path: path,
+		tags: make(map[string][]string),
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("не удалось создать директорию меток: %w", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("не удалось прочитать метки пиров: %w", err)
+	}
+	if err := json.Unmarshal(data, &store.tags); err != nil {
+		return nil, fmt.Errorf("не удалось разобрать метки пиров: %w", err)
+	}
+	return store, nil
+}
+
+// SavePeerTags заменяет метки пира
+func (s *PeerTagStore) SavePeerTags(ctx context.Context, peerID string, tags []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(tags) == 0 {
+		delete(s.tags, peerID)
+	} else {
+		s.tags[peerID] = append([]string(nil), tags...)
+	}
+	return s.persistLocked()
+}
+
+// GetAllPeerTags возвращает копию меток всех пиров
+func (s *PeerTagStore) GetAllPeerTags(ctx context.Context) (map[string][]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make(map[string][]string, len(s.tags))
+	for id, tags := range s.tags {
+		result[id] = append([]string(nil), tags...)
+	}
+	return result, nil
+}
+
+// persistLocked атомарно записывает метки на диск
+func (s *PeerTagStore) persistLocked() error {
+	data, err := json.MarshalIndent(s.tags, "", "  ")
+	if err != nil {
+		return fmt.Errorf("не удалось сериализовать метки пиров: %w", err)
+	}
+
+	tmpPath := s.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0600); err != nil {
+		return fmt.Errorf("не удалось сохранить метки пиров: %w", err)
+	}
+	return os.Rename(tmpPath, s.path)
+}
@@ -0,0 +1,114 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"OwlWhisper/pkg/interfaces"
+)
+
+// PreferenceStore хранит настройки уведомлений диалогов в JSON файле
+type PreferenceStore struct {
+	mu    sync.RWMutex
+	path  string
+	prefs map[string]*interfaces.ConversationPrefs
+}
+
+// Проверяем соответствие интерфейсу репозитория
+var _ interfaces.IPreferenceRepository = (*PreferenceStore)(nil)
+
+// NewPreferenceStore открывает (или создает) настройки диалогов по пути path
+func NewPreferenceStore(path string) (*PreferenceStore, error) {
+	store := &PreferenceStore{
+		path:  path,
+		prefs: make(map[string]*interfaces.ConversationPrefs),
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("не удалось создать директорию настроек: %w", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("не удалось прочитать настройки диалогов: %w", err)
+	}
+
+	var prefs []*interfaces.ConversationPrefs
+	if err := json.Unmarshal(data, &prefs); err != nil {
+		return nil, fmt.Errorf("не удалось разобрать настройки диалогов: %w", err)
+	}
+	for _, p := range prefs {
+		store.prefs[p.PeerID] = p
+	}
+	return store, nil
+}
+
+// SavePrefs сохраняет настройки диалога. Настройки по умолчанию не хранятся
+func (s *PreferenceStore) SavePrefs(ctx context.Context, prefs *interfaces.ConversationPrefs) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if *prefs == (interfaces.ConversationPrefs{PeerID: prefs.PeerID}) {
+		delete(s.prefs, prefs.PeerID)
+	} else {
+		copied := *prefs
+		s.prefs[prefs.PeerID] = &copied
+	}
+	return s.persistLocked()
+}
+
+// GetPrefs возвращает настройки диалога или nil
+func (s *PreferenceStore) GetPrefs(ctx context.Context, peerID string) (*interfaces.ConversationPrefs, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	p, ok := s.prefs[peerID]
+	if !ok {
+		return nil, nil
+	}
+	copied := *p
+	return &copied, nil
+}
+
+// GetAllPrefs возвращает настройки всех диалогов
+func (s *PreferenceStore) GetAllPrefs(ctx context.Context) ([]*interfaces.ConversationPrefs, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.sortedLocked(), nil
+}
+
+// sortedLocked возвращает копии настроек, отсортированные по PeerID
+func (s *PreferenceStore) sortedLocked() []*interfaces.ConversationPrefs {
+	result := make([]*interfaces.ConversationPrefs, 0, len(s.prefs))
+	for _, p := range s.prefs {
+		copied := *p
+		result = append(result, &copied)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].PeerID < result[j].PeerID
+	})
+	return result
+}
+
+// persistLocked атомарно записывает настройки на диск
+func (s *PreferenceStore) persistLocked() error {
+	data, err := json.MarshalIndent(s.sortedLocked(), "", "  ")
+	if err != nil {
+		return fmt.Errorf("не удалось сериализовать настройки диалогов: %w", err)
+	}
+
+	tmpPath := s.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0600); err != nil {
+		return fmt.Errorf("не удалось сохранить настройки диалогов: %w", err)
+	}
+	return os.Rename(tmpPath, s.path)
+}
@@ -0,0 +1,85 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"OwlWhisper/pkg/interfaces"
+)
+
+// ProfileRecordStore хранит записи профиля контактов в JSON файле, чтобы
+// писать им через почтовые ящики и после перезапуска
+type ProfileRecordStore struct {
+	mu      sync.RWMutex
+	path    string
+	records []interfaces.ProfileRecord
+}
+
+// Проверяем соответствие интерфейсу репозитория
+var _ interfaces.IProfileRecordRepository = (*ProfileRecordStore)(nil)
+
+// NewProfileRecordStore открывает (или создает) хранилище записей профиля по пути path
+func NewProfileRecordStore(path string) (*ProfileRecordStore, error) {
+	store := &ProfileRecordStore{path: path}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("не удалось создать директорию записей профиля: %w", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("не удалось прочитать записи профиля: %w", err)
+	}
+	if err := json.Unmarshal(data, &store.records); err != nil {
+		return nil, fmt.Errorf("не удалось разобрать записи профиля: %w", err)
+	}
+	return store, nil
+}
+
+// SaveProfileRecord сохраняет запись, заменяя запись того же пира
+func (s *ProfileRecordStore) SaveProfileRecord(ctx context.Context, record interfaces.ProfileRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	replaced := false
+	for i, existing := range s.records {
+		if existing.PeerID == record.PeerID {
+			s.records[i] = record
+			replaced = true
+			break
+		}
+	}
+	if !replaced {
+		s.records = append(s.records, record)
+	}
+	return s.persistLocked()
+}
+
+// GetProfileRecords возвращает копию записей
+func (s *ProfileRecordStore) GetProfileRecords(ctx context.Context) ([]interfaces.ProfileRecord, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return append([]interfaces.ProfileRecord(nil), s.records...), nil
+}
+
+// persistLocked атомарно записывает записи профиля на диск
+func (s *ProfileRecordStore) persistLocked() error {
+	data, err := json.MarshalIndent(s.records, "", "  ")
+	if err != nil {
+		return fmt.Errorf("не удалось сериализовать записи профиля: %w", err)
+	}
+
+	tmpPath := s.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0600); err != nil {
+		return fmt.Errorf("не удалось сохранить записи профиля: %w", err)
+	}
+	return os.Rename(tmpPath, s.path)
+}
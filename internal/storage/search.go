@@ -0,0 +1,110 @@
+package storage
+
+import (
+	"context"
+	"strings"
+	"unicode/utf8"
+
+	"OwlWhisper/pkg/interfaces"
+)
+
+// snippetRadius - сколько символов показывать по обе стороны от совпадения
+const snippetRadius = 40
+
+// SearchMessages ищет сообщения, содержащие все слова запроса без учета регистра.
+// Результаты упорядочены от новых к старым и разбиты на страницы фильтрами
+func (s *MessageStore) SearchMessages(ctx context.Context, query string, filters interfaces.MessageSearchFilters) (*interfaces.MessageSearchResult, error) {
+	terms := strings.Fields(strings.ToLower(query))
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := &interfaces.MessageSearchResult{}
+	for i := len(s.messages) - 1; i >= 0; i-- {
+		msg := s.messages[i]
+		if !matchesFilters(msg, filters) {
+			continue
+		}
+
+		content := strings.ToLower(msg.Content)
+		position, matched := matchTerms(content, terms)
+		if !matched {
+			continue
+		}
+
+		result.Total++
+		if result.Total <= filters.Offset {
+			continue
+		}
+		if filters.Limit > 0 && len(result.Hits) >= filters.Limit {
+			continue
+		}
+
+		hit := *msg
+		result.Hits = append(result.Hits, interfaces.MessageHit{
+			Message: &hit,
+			Snippet: snippet(msg.Content, content, position),
+		})
+	}
+	return result, nil
+}
+
+// matchesFilters проверяет отправителя, диалог и период сообщения
+func matchesFilters(msg *interfaces.Message, filters interfaces.MessageSearchFilters) bool {
+	if filters.FromPeer != "" && msg.FromPeer != filters.FromPeer {
+		return false
+	}
+	if filters.Conversation != "" && msg.FromPeer != filters.Conversation && msg.ToPeer != filters.Conversation {
+		return false
+	}
+	if !filters.Since.IsZero() && msg.Timestamp.Before(filters.Since) {
+		return false
+	}
+	if !filters.Until.IsZero() && msg.Timestamp.After(filters.Until) {
+		return false
+	}
+	return true
+}
+
+// matchTerms проверяет, что текст содержит все слова, и возвращает
+// байтовую позицию первого из них. Пустой запрос подходит под любой текст
+func matchTerms(content string, terms []string) (int, bool) {
+	position := -1
+	for _, term := range terms {
+		index := strings.Index(content, term)
+		if index < 0 {
+			return 0, false
+		}
+		if position < 0 || index < position {
+			position = index
+		}
+	}
+	if position < 0 {
+		position = 0
+	}
+	return position, true
+}
+
+// snippet вырезает фрагмент исходного текста вокруг совпадения. Позиция
+// найдена в тексте в нижнем регистре, поэтому пересчитывается в символы
+func snippet(original, lowered string, position int) string {
+	runes := []rune(original)
+	center := utf8.RuneCountInString(lowered[:position])
+	if center > len(runes) {
+		center = len(runes)
+	}
+
+	start := center - snippetRadius
+	prefix := "…"
+	if start <= 0 {
+		start = 0
+		prefix = ""
+	}
+	end := center + snippetRadius
+	suffix := "…"
+	if end >= len(runes) {
+		end = len(runes)
+		suffix = ""
+	}
+	return prefix + string(runes[start:end]) + suffix
+}
@@ -0,0 +1,239 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"OwlWhisper/pkg/interfaces"
+)
+
+// Операции очистки хранилища
+const (
+	CleanupMedia        = "media"
+	CleanupConversation = "conversation"
+	CleanupVacuum       = "vacuum"
+	CleanupMediaGC      = "media_gc" // выполняется IMediaRepository.CollectGarbage
+)
+
+// cleanupProgressStep - как часто сообщать о прогрессе очистки
+const cleanupProgressStep = 50
+
+// CleanupOptions описывает операцию очистки
+type CleanupOptions struct {
+	Operation string // CleanupMedia, CleanupConversation, CleanupVacuum или CleanupMediaGC
+	Self      string // PeerID владельца истории (для CleanupConversation)
+	PeerID    string // PeerID собеседника (для CleanupConversation)
+	// RemoveMedia - удалить вместе с диалогом файлы его вложений
+	RemoveMedia bool
+	// Before - граница для CleanupMedia: удаляются вложения старше нее
+	Before time.Time
+}
+
+// RunCleanup выполняет операцию очистки над историей сообщений
+func RunCleanup(ctx context.Context, repo interfaces.IMessageRepository, opts CleanupOptions, progress interfaces.CleanupProgressFunc) (*interfaces.CleanupResult, error) {
+	switch opts.Operation {
+	case CleanupMedia:
+		return repo.DeleteMediaOlderThan(ctx, opts.Before, progress)
+	case CleanupConversation:
+		return repo.ClearConversation(ctx, opts.Self, opts.PeerID, opts.RemoveMedia, progress)
+	case CleanupVacuum:
+		return repo.Vacuum(ctx)
+	default:
+		return nil, fmt.Errorf("неизвестная операция очистки: %s", opts.Operation)
+	}
+}
+
+// GetStorageUsage считает место, занятое историей и вложениями, по диалогам.
+// Диалог определяется собеседником относительно self; вложения - это файлы
+// из сообщений типа "file", которые еще лежат на диске
+func (s *MessageStore) GetStorageUsage(ctx context.Context, self string) (*interfaces.StorageUsage, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	usage := &interfaces.StorageUsage{}
+	byPeer := make(map[string]*interfaces.ConversationUsage)
+	// Одна копия вложения может использоваться в нескольких диалогах,
+	// в общий итог она входит один раз
+	counted := make(map[string]bool)
+	for _, msg := range s.messages {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		peerID := msg.FromPeer
+		if peerID == self {
+			peerID = msg.ToPeer
+		}
+		conversation, ok := byPeer[peerID]
+		if !ok {
+			conversation = &interfaces.ConversationUsage{PeerID: peerID}
+			byPeer[peerID] = conversation
+		}
+
+		conversation.Messages++
+		conversation.MessageBytes += messageSize(msg)
+		if size, ok := mediaSize(msg); ok {
+			conversation.MediaFiles++
+			conversation.MediaBytes += size
+			if !counted[msg.Content] {
+				counted[msg.Content] = true
+				usage.MediaBytes += size
+			}
+		}
+	}
+
+	for _, conversation := range byPeer {
+		usage.Conversations = append(usage.Conversations, *conversation)
+		usage.MessageBytes += conversation.MessageBytes
+	}
+	sort.Slice(usage.Conversations, func(i, j int) bool {
+		a, b := usage.Conversations[i], usage.Conversations[j]
+		return a.MessageBytes+a.MediaBytes > b.MessageBytes+b.MediaBytes
+	})
+
+	if info, err := os.Stat(s.path); err == nil {
+		usage.HistoryFileBytes = info.Size()
+	}
+	return usage, nil
+}
+
+// ClearConversation удаляет все сообщения диалога двух пиров. Если removeMedia,
+// с диска удаляются и файлы вложений диалога
+func (s *MessageStore) ClearConversation(ctx context.Context, peer1, peer2 string, removeMedia bool, progress interfaces.CleanupProgressFunc) (*interfaces.CleanupResult, error) {
+	s.mu.Lock()
+	result := &interfaces.CleanupResult{}
+	var media []*interfaces.Message
+	kept := make([]*interfaces.Message, 0, len(s.messages))
+	for _, msg := range s.messages {
+		if !isBetween(msg, peer1, peer2) {
+			kept = append(kept, msg)
+			continue
+		}
+		result.Messages++
+		result.FreedBytes += messageSize(msg)
+		if removeMedia && msg.Type == "file" {
+			media = append(media, msg)
+		}
+	}
+	if result.Messages == 0 {
+		s.mu.Unlock()
+		return result, nil
+	}
+
+	previous := s.messages
+	s.messages = kept
+	if err := s.rewriteLocked(); err != nil {
+		s.messages = previous
+		s.mu.Unlock()
+		return nil, err
+	}
+	s.notifyUnreadLocked(peer1)
+	s.notifyUnreadLocked(peer2)
+	repo := s.media
+	s.mu.Unlock()
+
+	// Файлы удаляем уже без блокировки: на медленном диске это долго
+	err := deleteMediaFiles(ctx, repo, media, result, progress)
+	return result, err
+}
+
+// DeleteMediaOlderThan удаляет с диска вложения из сообщений старше before.
+// Сами сообщения остаются в истории, чтобы диалог не терял контекст
+func (s *MessageStore) DeleteMediaOlderThan(ctx context.Context, before time.Time, progress interfaces.CleanupProgressFunc) (*interfaces.CleanupResult, error) {
+	s.mu.RLock()
+	var media []*interfaces.Message
+	for _, msg := range s.messages {
+		if msg.Type == "file" && msg.Timestamp.Before(before) {
+			media = append(media, msg)
+		}
+	}
+	repo := s.media
+	s.mu.RUnlock()
+
+	result := &interfaces.CleanupResult{}
+	err := deleteMediaFiles(ctx, repo, media, result, progress)
+	return result, err
+}
+
+// Vacuum переписывает файл истории из памяти: пропадают битые строки и
+// устаревшие версии сообщений, оставшиеся после сбоев записи
+func (s *MessageStore) Vacuum(ctx context.Context) (*interfaces.CleanupResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var before int64
+	if info, err := os.Stat(s.path); err == nil {
+		before = info.Size()
+	}
+	if err := s.rewriteLocked(); err != nil {
+		return nil, err
+	}
+
+	result := &interfaces.CleanupResult{}
+	if info, err := os.Stat(s.path); err == nil && before > info.Size() {
+		result.FreedBytes = before - info.Size()
+	}
+	return result, nil
+}
+
+// deleteMediaFiles удаляет файлы вложений сообщений, учитывая освобожденное
+// место. Вложения из хранилища repo только теряют ссылку сообщения и удаляются,
+// когда ссылок не остается. Уже удаленные файлы пропускаются без ошибки
+func deleteMediaFiles(ctx context.Context, repo interfaces.IMediaRepository, messages []*interfaces.Message, result *interfaces.CleanupResult, progress interfaces.CleanupProgressFunc) error {
+	total := len(messages)
+	for i, msg := range messages {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		released := false
+		if repo != nil {
+			freed, found, err := repo.Release(ctx, msg.ID)
+			if err != nil {
+				return err
+			}
+			released = found
+			if freed > 0 {
+				result.Files++
+				result.FreedBytes += freed
+			}
+		}
+		owned := repo != nil && repo.Owns(msg.Content)
+		if info, err := os.Stat(msg.Content); !released && !owned && err == nil && info.Mode().IsRegular() {
+			if err := os.Remove(msg.Content); err == nil {
+				result.Files++
+				result.FreedBytes += info.Size()
+			}
+		}
+
+		if progress != nil && ((i+1)%cleanupProgressStep == 0 || i+1 == total) {
+			progress(i+1, total)
+		}
+	}
+	return nil
+}
+
+// messageSize возвращает размер сообщения в файле истории
+func messageSize(msg *interfaces.Message) int64 {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return 0
+	}
+	return int64(len(data)) + 1
+}
+
+// mediaSize возвращает размер файла вложения, если он еще на диске
+func mediaSize(msg *interfaces.Message) (int64, bool) {
+	if msg.Type != "file" || msg.Content == "" {
+		return 0, false
+	}
+	info, err := os.Stat(msg.Content)
+	if err != nil || !info.Mode().IsRegular() {
+		return 0, false
+	}
+	return info.Size(), true
+}
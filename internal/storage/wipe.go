@@ -0,0 +1,75 @@
+package storage
+
+import (
+	"crypto/rand"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// ShredFile перезаписывает файл случайными данными, сбрасывает их на диск
+// и удаляет файл. Это лучшее, что можно сделать без контроля над носителем:
+// журналируемые файловые системы и SSD могут сохранить старые блоки
+func ShredFile(path string) error {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return err
+	}
+	if info.Mode().IsRegular() && info.Size() > 0 {
+		file, err := os.OpenFile(path, os.O_WRONLY, 0)
+		if err == nil {
+			_, err = io.CopyN(file, rand.Reader, info.Size())
+			if err == nil {
+				err = file.Sync()
+			}
+			file.Close()
+		}
+		if err != nil {
+			// Файл все равно удаляется: лучше без перезаписи, чем оставить
+			os.Remove(path)
+			return err
+		}
+	}
+	return os.Remove(path)
+}
+
+// ShredDir уничтожает все файлы директории dir (включая вложенные) и саму
+// директорию. Ошибки отдельных файлов не останавливают очистку; возвращается
+// число уничтоженных файлов и первая ошибка. Отсутствующая директория -
+// не ошибка: уничтожать нечего
+func ShredDir(dir string) (int, error) {
+	if _, err := os.Lstat(dir); os.IsNotExist(err) {
+		return 0, nil
+	}
+
+	var files []string
+	var firstErr error
+	filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			return nil
+		}
+		if !d.IsDir() {
+			files = append(files, path)
+		}
+		return nil
+	})
+
+	shredded := 0
+	for _, path := range files {
+		if err := ShredFile(path); err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		shredded++
+	}
+	if err := os.RemoveAll(dir); err != nil && firstErr == nil {
+		firstErr = err
+	}
+	return shredded, firstErr
+}
@@ -0,0 +1,40 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestShredDirMissing(t *testing.T) {
+	shredded, err := ShredDir(filepath.Join(t.TempDir(), "missing"))
+	if err != nil {
+		t.Fatalf("отсутствующая директория считается ошибкой: %v", err)
+	}
+	if shredded != 0 {
+		t.Fatalf("уничтожено файлов: %d, ожидалось 0", shredded)
+	}
+}
+
+func TestShredDir(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "data")
+	if err := os.MkdirAll(filepath.Join(dir, "nested"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"a.json", filepath.Join("nested", "b.log")} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("секрет"), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	shredded, err := ShredDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if shredded != 2 {
+		t.Fatalf("уничтожено файлов: %d, ожидалось 2", shredded)
+	}
+	if _, err := os.Lstat(dir); !os.IsNotExist(err) {
+		t.Fatalf("директория не удалена: %v", err)
+	}
+}
@@ -0,0 +1,193 @@
+package tui
+
+import (
+	"context"
+	"log"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"OwlWhisper/internal/core"
+	"OwlWhisper/internal/storage"
+	"OwlWhisper/pkg/interfaces"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+// recordFile сохраняет в историю сообщение о принятом файле. Содержимое
+// сообщения - путь к файлу, а проверенные файлы получают ссылку по хешу
+// и переносятся в хранилище вложений, чтобы дубликаты не копились
+func (h *Handler) recordFile(received core.FileReceived) {
+	ctx := context.Background()
+	path := received.Path
+	msg := &interfaces.Message{
+		FromPeer:  received.PeerID.String(),
+		ToPeer:    h.node.GetHost().ID().String(),
+		Timestamp: time.Now(),
+		Type:      "file",
+		IsRead:    true,
+	}
+	if received.Hash != "" {
+		msg.Attachment = &interfaces.Attachment{
+			Hash: received.Hash,
+			Name: filepath.Base(received.Path),
+			Size: received.Size,
+		}
+	}
+
+	stored := h.media != nil && received.Hash != ""
+	if stored {
+		blob, err := h.media.Store(ctx, path, received.Hash)
+		if err != nil {
+			log.Printf("⚠️ %v", err)
+			stored = false
+		} else if blob != path {
+			log.Printf("📦 Файл в хранилище вложений: %s", blob)
+			path = blob
+		}
+	}
+
+	msg.Content = path
+	if err := h.messages.SaveMessage(ctx, msg); err != nil {
+		log.Printf("⚠️ Не удалось сохранить сообщение: %v", err)
+		return
+	}
+	if stored {
+		if err := h.media.AddRef(ctx, received.Hash, msg.ID); err != nil {
+			log.Printf("⚠️ %v", err)
+		}
+	}
+}
+
+// restoreAttachment возвращает на место заново полученное вложение: файл
+// попадает в хранилище, а сообщения с этим хешем снова на него ссылаются
+func (h *Handler) restoreAttachment(received core.FileReceived) {
+	ctx := context.Background()
+	if h.media == nil {
+		return
+	}
+
+	blob, err := h.media.Store(ctx, received.Path, received.Hash)
+	if err != nil {
+		log.Printf("⚠️ %v", err)
+		return
+	}
+	messages, err := h.messages.FindAttachment(ctx, received.Hash)
+	if err != nil {
+		log.Printf("⚠️ %v", err)
+		return
+	}
+	for _, msg := range messages {
+		if err := h.media.AddRef(ctx, received.Hash, msg.ID); err != nil {
+			log.Printf("⚠️ %v", err)
+		}
+	}
+	log.Printf("📎 Вложение восстановлено: %s", blob)
+}
+
+// listAttachments обрабатывает /files: вложения текущего диалога и их наличие
+func (h *Handler) listAttachments() {
+	current, attachments, ok := h.conversationAttachments()
+	if !ok {
+		return
+	}
+	if len(attachments) == 0 {
+		log.Printf("📎 В диалоге с %s нет вложений", h.DisplayName(current))
+		return
+	}
+
+	ctx := context.Background()
+	log.Printf("📎 Вложения диалога с %s:", h.DisplayName(current))
+	for _, msg := range attachments {
+		state := "нет на диске, /refetch " + shortHash(msg.Attachment.Hash)
+		if _, ok := storage.ResolveAttachment(ctx, h.messages, h.media, msg.Attachment.Hash); ok {
+			state = "есть"
+		}
+		log.Printf("   %s %s (%s) - %s", shortHash(msg.Attachment.Hash), msg.Attachment.Name,
+			formatBytes(msg.Attachment.Size), state)
+	}
+}
+
+// refetchAttachment обрабатывает /refetch <хеш>: запрашивает удаленное
+// вложение сначала у отправителя, затем у остальных подключенных пиров
+func (h *Handler) refetchAttachment(prefix string) {
+	if prefix == "" {
+		log.Println("❌ Использование: /refetch <хеш> (см. /files)")
+		return
+	}
+	_, attachments, ok := h.conversationAttachments()
+	if !ok {
+		return
+	}
+
+	var target *interfaces.Message
+	for _, msg := range attachments {
+		if strings.HasPrefix(msg.Attachment.Hash, strings.ToLower(prefix)) {
+			target = msg
+			break
+		}
+	}
+	if target == nil {
+		log.Printf("❌ Вложение %s не найдено в диалоге", prefix)
+		return
+	}
+
+	hash := target.Attachment.Hash
+	if path, ok := storage.ResolveAttachment(context.Background(), h.messages, h.media, hash); ok {
+		log.Printf("📎 Вложение уже есть: %s", path)
+		return
+	}
+
+	var providers []peer.ID
+	if sender, err := peer.Decode(target.FromPeer); err == nil {
+		providers = append(providers, sender)
+	}
+	for _, p := range h.node.GetPeers() {
+		if p.String() != target.FromPeer {
+			providers = append(providers, p)
+		}
+	}
+
+	log.Printf("📎 Запрашиваю %s заново...", target.Attachment.Name)
+	go func() {
+		provider, err := h.node.FetchAttachment(context.Background(), hash, providers)
+		if err != nil {
+			log.Printf("❌ %s: %v", target.Attachment.Name, err)
+			return
+		}
+		log.Printf("📎 %s отправляет %s", h.DisplayName(provider), target.Attachment.Name)
+	}()
+}
+
+// conversationAttachments возвращает сообщения с вложениями текущего диалога
+func (h *Handler) conversationAttachments() (peer.ID, []*interfaces.Message, bool) {
+	h.mu.Lock()
+	current := h.current
+	h.mu.Unlock()
+	if current == "" {
+		log.Println("❌ Сначала выберите собеседника: /chat <peer>")
+		return "", nil, false
+	}
+
+	messages, err := h.messages.GetMessages(context.Background(), h.node.GetHost().ID().String(), current.String(), 0, 0)
+	if err != nil {
+		log.Printf("❌ %v", err)
+		return "", nil, false
+	}
+
+	var attachments []*interfaces.Message
+	for _, msg := range messages {
+		if msg.Attachment != nil {
+			attachments = append(attachments, msg)
+		}
+	}
+	return current, attachments, true
+}
+
+// shortHash сокращает хеш вложения для вывода
+func shortHash(hash string) string {
+	if len(hash) > 12 {
+		return hash[:12]
+	}
+	return hash
+}
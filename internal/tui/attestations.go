@@ -0,0 +1,172 @@
+package tui
+
+import (
+	"context"
+	"log"
+	"strings"
+
+	"OwlWhisper/internal/core"
+)
+
+// handleAttestations обрабатывает /attest [new|proof|remove]: подтверждения
+// владения внешними аккаунтами, которые видят контакты
+func (h *Handler) handleAttestations(args []string) {
+	const usage = "❌ Использование: /attest [new <github|web> <аккаунт>|proof <сервис> <аккаунт> <url>|remove <сервис> <аккаунт>]"
+
+	if len(args) == 0 {
+		h.showAttestations()
+		return
+	}
+
+	switch {
+	case args[0] == "new" && len(args) == 3:
+		attestation, err := h.node.CreateAttestation(args[1], args[2])
+		if err == nil {
+			err = h.node.PublishAttestation(attestation, "")
+		}
+		if err != nil {
+			log.Printf("❌ %v", err)
+			return
+		}
+		log.Printf("🪪 Опубликуйте этот текст (%s), затем укажите адрес: /attest proof %s %s <url>",
+			proofHint(attestation.Service, attestation.Account), attestation.Service, attestation.Account)
+		log.Println(attestation.Encode())
+
+	case args[0] == "proof" && len(args) == 4:
+		for _, attestation := range h.node.Attestations() {
+			if attestation.Service == args[1] && strings.EqualFold(attestation.Account, args[2]) {
+				if err := h.node.PublishAttestation(attestation, args[3]); err != nil {
+					log.Printf("❌ %v", err)
+					return
+				}
+				log.Printf("✅ Доказательство для %s:%s сохранено", attestation.Service, attestation.Account)
+				return
+			}
+		}
+		log.Printf("❌ Подтверждение %s:%s не найдено, сначала /attest new", args[1], args[2])
+
+	case args[0] == "remove" && len(args) == 3:
+		removed, err := h.node.RemoveAttestation(args[1], args[2])
+		switch {
+		case err != nil:
+			log.Printf("❌ %v", err)
+		case !removed:
+			log.Printf("❌ Подтверждение %s:%s не найдено", args[1], args[2])
+		default:
+			log.Printf("🗑️ Подтверждение %s:%s снято", args[1], args[2])
+		}
+
+	default:
+		log.Println(usage)
+	}
+}
+
+// showAttestations выводит свои подтверждения
+func (h *Handler) showAttestations() {
+	attestations := h.node.Attestations()
+	if len(attestations) == 0 {
+		log.Println("🪪 Подтверждений нет. /attest new <github|web> <аккаунт>")
+		return
+	}
+	log.Printf("🪪 Ваши подтверждения (%d):", len(attestations))
+	for _, a := range attestations {
+		proof := a.ProofURL
+		if proof == "" {
+			proof = "(адрес доказательства не указан)"
+		}
+		log.Printf("  %s:%s - %s", a.Service, a.Account, proof)
+	}
+}
+
+// checkAttestations обрабатывает /proofs <peer> [fetch]: проверяет
+// подтверждения контакта; с fetch загружает доказательства из сервисов
+func (h *Handler) checkAttestations(args []string) {
+	if len(args) == 0 || len(args) > 2 || (len(args) == 2 && args[1] != "fetch") {
+		log.Println("❌ Использование: /proofs <peer> [fetch]")
+		return
+	}
+	id, err := h.resolvePeer(args[0])
+	if err != nil {
+		log.Printf("❌ %v", err)
+		return
+	}
+	fetch := len(args) == 2
+
+	go func() {
+		checks, err := h.node.CheckAttestations(context.Background(), id, fetch)
+		if err != nil {
+			log.Printf("❌ %v", err)
+			return
+		}
+		if len(checks) == 0 {
+			log.Printf("🪪 У %s нет подтверждений", h.DisplayName(id))
+			return
+		}
+		log.Printf("🪪 Подтверждения %s:", h.DisplayName(id))
+		for _, check := range checks {
+			a := check.Attestation
+			switch {
+			case check.Error != "":
+				log.Printf("  ❌ %s:%s - %s", a.Service, a.Account, check.Error)
+			case check.ProofFound:
+				log.Printf("  ✅ %s:%s - подпись и доказательство %s", a.Service, a.Account, a.ProofURL)
+			default:
+				log.Printf("  ☑️ %s:%s - подпись верна, доказательство не проверялось", a.Service, a.Account)
+			}
+		}
+	}()
+}
+
+// rotateKey обрабатывает /rotate-key confirm: создает новый ключ личности.
+// Контакты, получившие подписанную запись о переходе, переносят контакт сами
+func (h *Handler) rotateKey(args []string) {
+	if h.rotator == nil {
+		log.Println("❌ Смена ключа недоступна")
+		return
+	}
+	if len(args) != 1 || args[0] != "confirm" {
+		log.Println("⚠️ Смена ключа меняет PeerID. Контакты, которые сейчас не в сети,")
+		log.Println("   узнают о ней при следующем подключении. Для продолжения: /rotate-key confirm")
+		return
+	}
+
+	transition, delivered, err := h.rotator()
+	if err != nil {
+		log.Printf("❌ %v", err)
+		return
+	}
+	log.Printf("🔑 Новый PeerID: %s", transition.NewPeerID)
+	log.Printf("   Сообщено контактам: %d. Перезапустите приложение, чтобы перейти на новый ключ", delivered)
+}
+
+// proofHint подсказывает, где опубликовать доказательство
+func proofHint(service, account string) string {
+	if service == core.AttestationGitHub {
+		return "в публичном gist пользователя " + account
+	}
+	return "по адресу https://" + account + core.WebProofPath
+}
+
+// wipe обрабатывает /wipe [notify] <фраза>: уничтожает ключ личности и все
+// данные и завершает приложение. С notify контакты получают уведомление
+func (h *Handler) wipe(args []string) {
+	if h.wiper == nil {
+		log.Println("❌ Уничтожение данных недоступно")
+		return
+	}
+	notify := len(args) > 0 && args[0] == "notify"
+	if notify {
+		args = args[1:]
+	}
+	if strings.Join(args, " ") != h.wipeWord {
+		log.Println("⚠️ Будут безвозвратно уничтожены ключ личности, история, контакты и настройки.")
+		log.Printf("   Для продолжения: /wipe [notify] %s", h.wipeWord)
+		return
+	}
+
+	if err := h.wiper(h.wipeWord, notify); err != nil {
+		log.Printf("❌ %v", err)
+		return
+	}
+	log.Println("💥 Данные уничтожены")
+}
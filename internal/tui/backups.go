@@ -0,0 +1,70 @@
+package tui
+
+import (
+	"log"
+	"strings"
+)
+
+// BackupService - резервное копирование, доступное из TUI
+type BackupService interface {
+	// BackupNow делает копию в фоне, результат приходит событием ядра
+	BackupNow()
+	// ListBackups возвращает имена копий от старых к новым
+	ListBackups() ([]string, error)
+	// RestoreFromBackup восстанавливает копию в фоне (пусто - самую новую)
+	RestoreFromBackup(name string)
+}
+
+// SetBackups подключает резервное копирование для /backup, /backups и /restore
+func (h *Handler) SetBackups(backups BackupService) {
+	h.backups = backups
+}
+
+// handleBackupCommand обрабатывает команды резервного копирования
+func (h *Handler) handleBackupCommand(message string) bool {
+	fields := strings.Fields(message)
+	if len(fields) == 0 {
+		return false
+	}
+
+	switch fields[0] {
+	case "/backup", "/backups", "/restore":
+	default:
+		return false
+	}
+	if h.backups == nil {
+		log.Println("❌ Резервное копирование недоступно")
+		return true
+	}
+
+	switch fields[0] {
+	case "/backup":
+		log.Println("💾 Создаю резервную копию...")
+		h.backups.BackupNow()
+
+	case "/backups":
+		names, err := h.backups.ListBackups()
+		if err != nil {
+			log.Printf("❌ %v", err)
+			return true
+		}
+		if len(names) == 0 {
+			log.Println("💾 Резервных копий нет")
+			return true
+		}
+		log.Printf("💾 Резервные копии (%d):", len(names))
+		for _, name := range names {
+			log.Printf("   %s", name)
+		}
+
+	case "/restore":
+		name := argAt(fields, 1)
+		if name == "" {
+			log.Println("💾 Восстанавливаю самую новую резервную копию...")
+		} else {
+			log.Printf("💾 Восстанавливаю %s...", name)
+		}
+		h.backups.RestoreFromBackup(name)
+	}
+	return true
+}
@@ -0,0 +1,141 @@
+package tui
+
+import (
+	"context"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"OwlWhisper/internal/core"
+	"OwlWhisper/pkg/interfaces"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+// sendContactCard обрабатывает /card <кому> <контакт|me>: делится с контактом
+// карточкой другого контакта или своей
+func (h *Handler) sendContactCard(args []string) {
+	if len(args) != 2 {
+		log.Println("❌ Использование: /card <кому> <контакт|me>")
+		return
+	}
+	to, err := h.resolvePeer(args[0])
+	if err != nil {
+		log.Printf("❌ %v", err)
+		return
+	}
+
+	var subject peer.ID
+	var nickname string
+	if args[1] == "me" {
+		subject = h.node.GetHost().ID()
+		nickname = h.config.Profile.Nickname
+	} else {
+		contact, err := h.findContact(args[1])
+		if err != nil {
+			log.Printf("❌ %v", err)
+			return
+		}
+		if subject, err = peer.Decode(contact.PeerID); err != nil {
+			log.Printf("❌ %v", err)
+			return
+		}
+		nickname = contact.Nickname
+	}
+
+	go func() {
+		if err := h.node.SendContactCard(to, subject, nickname); err != nil {
+			log.Printf("❌ %v", err)
+			return
+		}
+		log.Printf("📇 Карточка %s отправлена %s", h.DisplayName(subject), h.DisplayName(to))
+	}()
+}
+
+// handleContactCards обрабатывает /cards [add <id> [имя]|drop <id...|all>]
+func (h *Handler) handleContactCards(args []string) {
+	if len(args) == 0 {
+		h.showContactCards()
+		return
+	}
+	if len(args) < 2 || (args[0] != "add" && args[0] != "drop") {
+		log.Println("❌ Использование: /cards [add <id> [имя]|drop <id...|all>]")
+		return
+	}
+
+	if args[0] == "drop" {
+		if args[1] == "all" {
+			log.Printf("🗑️ Отброшено карточек: %d", h.node.RejectContactCards())
+			return
+		}
+		var ids []uint64
+		for _, arg := range args[1:] {
+			id, err := strconv.ParseUint(arg, 10, 64)
+			if err != nil {
+				log.Printf("❌ Некорректный ID карточки: %s", arg)
+				return
+			}
+			ids = append(ids, id)
+		}
+		log.Printf("🗑️ Отброшено карточек: %d", h.node.RejectContactCards(ids...))
+		return
+	}
+
+	id, err := strconv.ParseUint(args[1], 10, 64)
+	if err != nil {
+		log.Println("❌ Использование: /cards add <id> [имя]")
+		return
+	}
+	card, err := h.node.AcceptContactCard(id)
+	if err != nil {
+		log.Printf("❌ %v", err)
+		return
+	}
+
+	nickname := strings.Join(args[2:], " ")
+	if nickname == "" {
+		nickname = card.Nickname
+	}
+	if nickname == "" {
+		nickname = card.PeerID.ShortString()
+	}
+	contact := &interfaces.Contact{
+		PeerID:   card.PeerID.String(),
+		Nickname: nickname,
+		AddedAt:  time.Now(),
+		IsOnline: h.isConnected(card.PeerID),
+	}
+	if err := h.contacts.SaveContact(context.Background(), contact); err != nil {
+		log.Printf("❌ Не удалось сохранить контакт: %v", err)
+		return
+	}
+	log.Printf("✅ %s добавлен в контакты", nickname)
+}
+
+// showContactCards выводит полученные карточки
+func (h *Handler) showContactCards() {
+	cards := h.node.ContactCards()
+	if len(cards) == 0 {
+		log.Println("📇 Карточек нет")
+		return
+	}
+
+	log.Printf("📇 Полученные карточки (%d):", len(cards))
+	for _, card := range cards {
+		log.Printf("  [%d] %s %s от %s", card.ID, card.Nickname, card.PeerID.ShortString(), h.DisplayName(card.From))
+		log.Printf("      отпечаток: %s", card.Fingerprint)
+	}
+	log.Println("  /cards add <id> [имя] - добавить, /cards drop <id|all> - отбросить")
+}
+
+// printContactCard выводит новую карточку
+func (h *Handler) printContactCard(card core.ContactCard) {
+	name := card.Nickname
+	if name == "" {
+		name = card.PeerID.ShortString()
+	}
+	log.Printf("📇 %s поделился контактом %s (%s)", h.DisplayName(card.From), name, card.PeerID.ShortString())
+	log.Printf("   отпечаток: %s", card.Fingerprint)
+	log.Printf("   /cards add %d - добавить, /cards drop %d - отбросить", card.ID, card.ID)
+}
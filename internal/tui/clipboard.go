@@ -0,0 +1,107 @@
+package tui
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// ErrClipboardUnavailable - в системе нет поддерживаемой утилиты буфера обмена
+var ErrClipboardUnavailable = errors.New("утилита буфера обмена не найдена (нужен wl-paste, xclip, pbpaste или powershell)")
+
+// ClipboardContent - содержимое буфера обмена
+type ClipboardContent struct {
+	Text  string
+	Image []byte // PNG, если в буфере картинка
+}
+
+// readClipboard читает буфер обмена через системные утилиты.
+// Картинки поддерживаются в Wayland (wl-paste) и X11 (xclip)
+func readClipboard() (*ClipboardContent, error) {
+	switch runtime.GOOS {
+	case "darwin":
+		text, err := runClipboardTool("pbpaste")
+		if err != nil {
+			return nil, err
+		}
+		return &ClipboardContent{Text: string(text)}, nil
+
+	case "windows":
+		text, err := runClipboardTool("powershell", "-NoProfile", "-Command", "Get-Clipboard -Raw")
+		if err != nil {
+			return nil, err
+		}
+		return &ClipboardContent{Text: strings.TrimRight(string(text), "\r\n")}, nil
+	}
+
+	if os.Getenv("WAYLAND_DISPLAY") != "" {
+		if _, err := exec.LookPath("wl-paste"); err == nil {
+			return readClipboardWith(
+				[]string{"wl-paste", "--list-types"},
+				[]string{"wl-paste", "--no-newline", "--type", "image/png"},
+				[]string{"wl-paste", "--no-newline"},
+			)
+		}
+	}
+
+	return readClipboardWith(
+		[]string{"xclip", "-selection", "clipboard", "-t", "TARGETS", "-o"},
+		[]string{"xclip", "-selection", "clipboard", "-t", "image/png", "-o"},
+		[]string{"xclip", "-selection", "clipboard", "-o"},
+	)
+}
+
+// readClipboardWith сначала проверяет наличие картинки, затем читает текст
+func readClipboardWith(listTypes, readImage, readText []string) (*ClipboardContent, error) {
+	types, err := runClipboardTool(listTypes[0], listTypes[1:]...)
+	if err != nil {
+		return nil, err
+	}
+
+	if bytes.Contains(types, []byte("image/png")) {
+		image, err := runClipboardTool(readImage[0], readImage[1:]...)
+		if err != nil {
+			return nil, err
+		}
+		return &ClipboardContent{Image: image}, nil
+	}
+
+	text, err := runClipboardTool(readText[0], readText[1:]...)
+	if err != nil {
+		return nil, err
+	}
+	return &ClipboardContent{Text: string(text)}, nil
+}
+
+// runClipboardTool запускает утилиту и возвращает ее вывод
+func runClipboardTool(name string, args ...string) ([]byte, error) {
+	if _, err := exec.LookPath(name); err != nil {
+		return nil, ErrClipboardUnavailable
+	}
+
+	out, err := exec.Command(name, args...).Output()
+	if err != nil {
+		return nil, fmt.Errorf("не удалось прочитать буфер обмена (%s): %w", name, err)
+	}
+	return out, nil
+}
+
+// saveClipboardImage сохраняет картинку из буфера во временный файл для отправки
+func saveClipboardImage(image []byte) (string, error) {
+	dir := filepath.Join(os.TempDir(), "owlwhisper-clipboard")
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(dir, fmt.Sprintf("clipboard-%s.png", time.Now().Format("20060102-150405")))
+	if err := os.WriteFile(path, image, 0600); err != nil {
+		return "", err
+	}
+	return path, nil
+}
@@ -0,0 +1,280 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"OwlWhisper/internal/core"
+	"OwlWhisper/pkg/interfaces"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+// DisplayName возвращает псевдоним, имя контакта или короткий ID пира
+func (h *Handler) DisplayName(peerID peer.ID) string {
+	if alias, ok := h.node.GetContactMetadata(peerID, core.MetadataAlias); ok {
+		return string(alias)
+	}
+	if c, err := h.contacts.GetContact(context.Background(), peerID.String()); err == nil && c.Nickname != "" {
+		return c.Nickname
+	}
+	// Имя из локальной сети пир выбрал сам, поэтому показываем его вместе с PeerID
+	h.mu.Lock()
+	lanName := h.lanNames[peerID]
+	h.mu.Unlock()
+	if lanName != "" {
+		return fmt.Sprintf("%s (%s)", lanName, peerID.ShortString())
+	}
+	return peerID.ShortString()
+}
+
+// showContacts обрабатывает /contacts
+func (h *Handler) showContacts() {
+	contacts, err := h.contacts.GetAllContacts(context.Background())
+	if err != nil {
+		log.Printf("❌ Не удалось загрузить контакты: %v", err)
+		return
+	}
+	if len(contacts) == 0 {
+		log.Println("📇 Контактов нет. Добавьте: /add <peer> <имя>")
+		return
+	}
+
+	unread, err := h.messages.GetUnreadCounts(context.Background())
+	if err != nil {
+		log.Printf("⚠️ Не удалось получить непрочитанные: %v", err)
+	}
+
+	log.Printf("📇 Контакты (%d):", len(contacts))
+	for _, c := range contacts {
+		status := "⚪"
+		if c.IsOnline {
+			status = "🟢"
+		}
+		lastSeen := "никогда"
+		if !c.LastSeen.IsZero() {
+			lastSeen = c.LastSeen.Format("02.01 15:04")
+		}
+		badge := ""
+		if n := unread[c.PeerID]; n > 0 {
+			badge = fmt.Sprintf(" [%d]", n)
+		}
+		log.Printf("  %s %s%s (%s), был в сети: %s", status, c.Nickname, badge, shortID(c.PeerID), lastSeen)
+	}
+}
+
+// addContact обрабатывает /add <peer> <имя>
+func (h *Handler) addContact(args []string) {
+	if len(args) < 2 {
+		log.Println("❌ Использование: /add <peer> <имя>")
+		return
+	}
+
+	peerID, err := h.resolvePeer(args[0])
+	if err != nil {
+		log.Printf("❌ %v", err)
+		return
+	}
+	nickname := strings.Join(args[1:], " ")
+
+	contact := &interfaces.Contact{
+		PeerID:   peerID.String(),
+		Nickname: nickname,
+		AddedAt:  time.Now(),
+		IsOnline: h.isConnected(peerID),
+	}
+	if contact.IsOnline {
+		contact.LastSeen = time.Now()
+	}
+	if err := h.contacts.SaveContact(context.Background(), contact); err != nil {
+		log.Printf("❌ Не удалось сохранить контакт: %v", err)
+		return
+	}
+	log.Printf("✅ %s добавлен в контакты как %s", peerID.ShortString(), nickname)
+}
+
+// renameContact обрабатывает /rename <контакт> <новое имя>
+func (h *Handler) renameContact(args []string) {
+	if len(args) < 2 {
+		log.Println("❌ Использование: /rename <контакт> <новое имя>")
+		return
+	}
+
+	contact, err := h.findContact(args[0])
+	if err != nil {
+		log.Printf("❌ %v", err)
+		return
+	}
+	contact.Nickname = strings.Join(args[1:], " ")
+	if err := h.contacts.UpdateContact(context.Background(), contact); err != nil {
+		log.Printf("❌ Не удалось переименовать контакт: %v", err)
+		return
+	}
+	log.Printf("✅ Контакт переименован в %s", contact.Nickname)
+}
+
+// removeContact обрабатывает /remove <контакт>
+func (h *Handler) removeContact(args []string) {
+	if len(args) != 1 {
+		log.Println("❌ Использование: /remove <контакт>")
+		return
+	}
+
+	contact, err := h.findContact(args[0])
+	if err != nil {
+		log.Printf("❌ %v", err)
+		return
+	}
+	if err := h.contacts.DeleteContact(context.Background(), contact.PeerID); err != nil {
+		log.Printf("❌ Не удалось удалить контакт: %v", err)
+		return
+	}
+	log.Printf("🗑️ Контакт %s удален", contact.Nickname)
+}
+
+// setNickname обрабатывает /nick <имя>: меняет имя в собственном профиле
+func (h *Handler) setNickname(args []string) {
+	if len(args) == 0 {
+		log.Println("❌ Использование: /nick <имя>")
+		return
+	}
+
+	h.config.Profile.Nickname = strings.Join(args, " ")
+	if err := h.config.SaveConfig(""); err != nil {
+		log.Printf("❌ Не удалось сохранить профиль: %v", err)
+		return
+	}
+	if h.config.Privacy.LANNickname && h.discovery != nil {
+		h.discovery.SetLANNickname(h.config.Profile.Nickname)
+	}
+	log.Printf("✅ Ваше имя: %s", h.config.Profile.Nickname)
+}
+
+// setStatus обрабатывает /status [текст]: задает статус, который видят
+// контакты; без текста статус снимается
+func (h *Handler) setStatus(args []string) {
+	status := strings.Join(args, " ")
+	if err := h.node.SetStatusMessage(status); err != nil {
+		log.Printf("❌ %v", err)
+		return
+	}
+
+	h.config.Profile.Status = status
+	if err := h.config.SaveConfig(""); err != nil {
+		log.Printf("❌ Не удалось сохранить профиль: %v", err)
+		return
+	}
+	if status == "" {
+		log.Println("✅ Статус снят")
+		return
+	}
+	log.Printf("✅ Ваш статус: %s", status)
+}
+
+// showProfile обрабатывает /profile
+func (h *Handler) showProfile() {
+	nickname := h.config.Profile.Nickname
+	if nickname == "" {
+		nickname = "(не задано, /nick <имя>)"
+	}
+	log.Printf("🦉 Имя: %s", nickname)
+	if status := h.node.StatusMessage(); status != "" {
+		log.Printf("💬 Статус: %s", status)
+	}
+	log.Printf("🆔 PeerID: %s", h.node.GetHost().ID())
+	if fingerprint, err := core.Fingerprint(h.node.GetHost().ID()); err == nil {
+		log.Printf("🔑 Отпечаток: %s", fingerprint)
+	}
+	if h.node.IsStealth() {
+		log.Println("🥷 Скрытый режим: протоколы видны только после рукопожатия")
+	}
+}
+
+// updatePresence отмечает контакт в сети или не в сети по событиям подключения
+func (h *Handler) updatePresence(peerID peer.ID, online bool) {
+	contact, err := h.contacts.GetContact(context.Background(), peerID.String())
+	if err != nil {
+		return
+	}
+	contact.IsOnline = online
+	contact.LastSeen = time.Now()
+	if err := h.contacts.UpdateContact(context.Background(), contact); err != nil {
+		log.Printf("⚠️ Не удалось обновить контакт: %v", err)
+	}
+}
+
+// showPresence обрабатывает /seen <peer>: запрашивает у пира время его
+// последней активности, если его настройки приватности это разрешают
+func (h *Handler) showPresence(args []string) {
+	if len(args) != 1 {
+		log.Println("❌ Использование: /seen <peer>")
+		return
+	}
+
+	peerID, err := h.resolvePeer(args[0])
+	if err != nil {
+		log.Printf("❌ %v", err)
+		return
+	}
+	presence, err := h.node.RequestPresence(peerID)
+	if err != nil {
+		log.Printf("❌ %v", err)
+		return
+	}
+	if presence.Status != nil && presence.Status.Text != "" {
+		log.Printf("💬 %s: %s", h.DisplayName(peerID), presence.Status.Text)
+	}
+	if presence.LastSeen == nil {
+		log.Printf("🙈 %s скрывает время последней активности", h.DisplayName(peerID))
+		return
+	}
+	log.Printf("👁️ %s был активен %s", h.DisplayName(peerID), presence.LastSeen.Format("02.01 15:04"))
+}
+
+// findContact ищет контакт по имени или фрагменту PeerID
+func (h *Handler) findContact(query string) (*interfaces.Contact, error) {
+	contacts, err := h.contacts.GetAllContacts(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []*interfaces.Contact
+	for _, c := range contacts {
+		if strings.EqualFold(c.Nickname, query) {
+			return c, nil
+		}
+		if strings.Contains(c.PeerID, query) {
+			matches = append(matches, c)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("контакт %q не найден", query)
+	case 1:
+		return matches[0], nil
+	default:
+		return nil, fmt.Errorf("под %q подходят несколько контактов, уточните", query)
+	}
+}
+
+// isConnected проверяет, подключен ли пир сейчас
+func (h *Handler) isConnected(peerID peer.ID) bool {
+	for _, p := range h.node.GetPeers() {
+		if p == peerID {
+			return true
+		}
+	}
+	return false
+}
+
+// shortID возвращает короткую форму строкового PeerID
+func shortID(id string) string {
+	if peerID, err := peer.Decode(id); err == nil {
+		return peerID.ShortString()
+	}
+	return id
+}
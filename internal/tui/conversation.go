@@ -0,0 +1,279 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"OwlWhisper/internal/storage"
+	"OwlWhisper/pkg/config"
+	"OwlWhisper/pkg/interfaces"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+// historyPageSize - сколько сообщений показывать за одну страницу истории
+const historyPageSize = 20
+
+// selectConversation обрабатывает /chat <peer>: выбирает собеседника
+// для личных сообщений и показывает последние сообщения диалога
+func (h *Handler) selectConversation(query string) {
+	peerID, err := h.resolvePeer(query)
+	if err != nil {
+		log.Printf("❌ %v", err)
+		return
+	}
+
+	h.mu.Lock()
+	h.current = peerID
+	h.scrollOffset = 0
+	h.mu.Unlock()
+
+	// Пока пользователь читает историю, соединение уже устанавливается
+	h.node.PrewarmConnection(peerID)
+
+	log.Printf("💬 Диалог с %s. /all - вернуться к рассылке всем", h.DisplayName(peerID))
+	h.showHistory(historyPageSize, 0)
+
+	if err := h.messages.MarkConversationRead(context.Background(), peerID.String(), time.Now()); err != nil {
+		log.Printf("⚠️ Не удалось отметить диалог прочитанным: %v", err)
+	}
+
+	if draft, err := h.messages.GetDraft(context.Background(), peerID.String()); err == nil && draft != nil {
+		log.Printf("📝 Черновик: %s", draft.Content)
+	}
+}
+
+// handleDraft обрабатывает /draft [текст]: сохраняет черновик открытого
+// диалога, а без текста показывает его. /draft - удаляет черновик
+func (h *Handler) handleDraft(text string) {
+	h.mu.Lock()
+	current := h.current
+	h.mu.Unlock()
+	if current == "" {
+		log.Println("❌ Сначала выберите собеседника: /chat <peer>")
+		return
+	}
+
+	ctx := context.Background()
+	switch text {
+	case "":
+		draft, err := h.messages.GetDraft(ctx, current.String())
+		if err != nil {
+			log.Printf("❌ %v", err)
+			return
+		}
+		if draft == nil {
+			log.Println("📝 Черновика нет")
+			return
+		}
+		log.Printf("📝 Черновик от %s: %s", draft.UpdatedAt.Format("02.01 15:04"), draft.Content)
+	case "-":
+		if err := h.messages.SaveDraft(ctx, current.String(), ""); err != nil {
+			log.Printf("❌ %v", err)
+			return
+		}
+		log.Println("🗑️ Черновик удален")
+	default:
+		if err := h.messages.SaveDraft(ctx, current.String(), text); err != nil {
+			log.Printf("❌ Не удалось сохранить черновик: %v", err)
+			return
+		}
+		log.Println("📝 Черновик сохранен")
+	}
+}
+
+// leaveConversation обрабатывает /all: сообщения снова уходят всем пирам
+func (h *Handler) leaveConversation() {
+	h.mu.Lock()
+	h.current = ""
+	h.mu.Unlock()
+	log.Println("📢 Сообщения отправляются всем подключенным пирам")
+}
+
+// handleHistory обрабатывает /history [n]
+func (h *Handler) handleHistory(args []string) {
+	limit := historyPageSize
+	if len(args) > 0 {
+		n, err := strconv.Atoi(args[0])
+		if err != nil || n <= 0 {
+			log.Println("❌ Использование: /history [количество]")
+			return
+		}
+		limit = n
+	}
+
+	h.mu.Lock()
+	h.scrollOffset = 0
+	h.mu.Unlock()
+	h.showHistory(limit, 0)
+}
+
+// scrollBack обрабатывает /more: показывает предыдущую страницу истории
+func (h *Handler) scrollBack() {
+	h.mu.Lock()
+	h.scrollOffset += historyPageSize
+	offset := h.scrollOffset
+	h.mu.Unlock()
+
+	if !h.showHistory(historyPageSize, offset) {
+		log.Println("📜 Это начало истории")
+	}
+}
+
+// showHistory выводит сообщения текущего диалога; возвращает false, если выводить нечего
+func (h *Handler) showHistory(limit, offset int) bool {
+	h.mu.Lock()
+	current := h.current
+	h.mu.Unlock()
+
+	if current == "" {
+		log.Println("❌ Сначала выберите собеседника: /chat <peer>")
+		return true
+	}
+
+	self := h.node.GetHost().ID().String()
+	messages, err := h.messages.GetMessages(context.Background(), self, current.String(), limit, offset)
+	if err != nil {
+		log.Printf("❌ Не удалось загрузить историю: %v", err)
+		return true
+	}
+	if len(messages) == 0 {
+		if offset == 0 {
+			log.Println("📜 История пуста")
+			return true
+		}
+		return false
+	}
+
+	for _, msg := range messages {
+		fmt.Println(h.formatMessage(msg, self))
+	}
+	return true
+}
+
+// searchHistory обрабатывает /search <текст>: ищет в открытом диалоге
+// или во всей истории, если диалог не выбран
+func (h *Handler) searchHistory(query string) {
+	if query == "" {
+		log.Println("❌ Использование: /search <текст>")
+		return
+	}
+
+	h.mu.Lock()
+	current := h.current
+	h.mu.Unlock()
+
+	filters := interfaces.MessageSearchFilters{Limit: historyPageSize}
+	if current != "" {
+		filters.Conversation = current.String()
+	}
+	result, err := h.messages.SearchMessages(context.Background(), query, filters)
+	if err != nil {
+		log.Printf("❌ Ошибка поиска: %v", err)
+		return
+	}
+	if result.Total == 0 {
+		log.Printf("🔍 По запросу %q ничего не найдено", query)
+		return
+	}
+
+	log.Printf("🔍 Найдено: %d (показаны последние %d)", result.Total, len(result.Hits))
+	self := h.node.GetHost().ID().String()
+	for _, hit := range result.Hits {
+		msg := *hit.Message
+		msg.Content = hit.Snippet
+		fmt.Println(h.formatMessage(&msg, self))
+	}
+}
+
+// exportConversation обрабатывает /export <json|html> [файл]
+func (h *Handler) exportConversation(args []string) {
+	if len(args) == 0 || (args[0] != storage.ExportJSON && args[0] != storage.ExportHTML) {
+		log.Println("❌ Использование: /export <json|html> [файл]")
+		return
+	}
+	if h.exporter == nil {
+		log.Println("❌ Экспорт недоступен")
+		return
+	}
+
+	h.mu.Lock()
+	current := h.current
+	h.mu.Unlock()
+	if current == "" {
+		log.Println("❌ Сначала выберите собеседника: /chat <peer>")
+		return
+	}
+
+	format := args[0]
+	path := filepath.Join(config.DefaultDir(), "exports",
+		fmt.Sprintf("%s-%s.%s", current.ShortString(), time.Now().Format("20060102-150405"), format))
+	if len(args) > 1 {
+		path = strings.Join(args[1:], " ")
+	}
+
+	log.Printf("📤 Экспорт диалога с %s в %s...", h.DisplayName(current), path)
+	h.exporter(current, format, path)
+}
+
+// recordMessage сохраняет сообщение в историю
+func (h *Handler) recordMessage(from, to peer.ID, text string, isRead bool) {
+	msg := &interfaces.Message{
+		FromPeer:  from.String(),
+		ToPeer:    to.String(),
+		Content:   text,
+		Timestamp: time.Now(),
+		Type:      "text",
+		IsRead:    isRead,
+	}
+	if err := h.messages.SaveMessage(context.Background(), msg); err != nil {
+		log.Printf("⚠️ Не удалось сохранить сообщение: %v", err)
+	}
+}
+
+// resolvePeer находит подключенного пира по полному ID или его фрагменту
+func (h *Handler) resolvePeer(query string) (peer.ID, error) {
+	if query == "" {
+		return "", fmt.Errorf("укажите пира: /chat <peer>")
+	}
+	if id, err := peer.Decode(query); err == nil {
+		return id, nil
+	}
+	if contact, err := h.findContact(query); err == nil {
+		return peer.Decode(contact.PeerID)
+	}
+
+	var matches []peer.ID
+	for _, p := range h.node.GetPeers() {
+		if strings.Contains(p.String(), query) {
+			matches = append(matches, p)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf("пир %q не найден среди подключенных", query)
+	case 1:
+		return matches[0], nil
+	default:
+		return "", fmt.Errorf("под %q подходят несколько пиров, уточните", query)
+	}
+}
+
+// formatMessage форматирует сообщение истории для вывода
+func (h *Handler) formatMessage(msg *interfaces.Message, self string) string {
+	author := "Вы"
+	if msg.FromPeer != self {
+		if id, err := peer.Decode(msg.FromPeer); err == nil {
+			author = h.DisplayName(id)
+		} else {
+			author = msg.FromPeer
+		}
+	}
+	return fmt.Sprintf("[%s] %s: %s", msg.Timestamp.Format("02.01 15:04"), author, msg.Content)
+}
@@ -0,0 +1,215 @@
+package tui
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"OwlWhisper/internal/core"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+// runEvents выводит события ядра в консоль, пока канал событий открыт
+func (h *Handler) runEvents() {
+	for event := range h.node.Events() {
+		h.printEvent(event)
+		if h.notifier != nil {
+			h.notifier.HandleEvent(event)
+		}
+	}
+}
+
+// runSecurityEvents выводит предупреждения безопасности, пока канал открыт
+func (h *Handler) runSecurityEvents() {
+	for event := range h.node.SecurityEvents() {
+		if event.Severity == core.SeverityInfo {
+			continue
+		}
+		who := event.Addr
+		if event.PeerID != "" {
+			who = h.DisplayName(event.PeerID)
+		}
+		log.Printf("🛡️ [%s] %s %s: %s", event.Severity, event.Type, who, event.Detail)
+	}
+}
+
+// printEvent выводит одно событие ядра в понятном пользователю виде
+func (h *Handler) printEvent(event core.Event) {
+	switch payload := event.Payload.(type) {
+	case core.MessageEvent:
+		// Сообщение в открытом диалоге считается прочитанным сразу
+		h.mu.Lock()
+		isRead := h.current == payload.PeerID
+		h.mu.Unlock()
+		h.recordMessage(payload.PeerID, h.node.GetHost().ID(), payload.Text, isRead)
+		if payload.Delivery == core.DeliveryMailbox {
+			fmt.Printf("📬 От %s (из почтового ящика, отправлено %s): %s\n",
+				h.DisplayName(payload.PeerID), payload.SentAt.Local().Format("02.01 15:04"), payload.Text)
+		} else if payload.Delivery == core.DeliverySealed {
+			fmt.Printf("🕶️ От %s: %s\n", h.DisplayName(payload.PeerID), payload.Text)
+		} else {
+			fmt.Printf("📥 От %s: %s\n", h.DisplayName(payload.PeerID), payload.Text)
+		}
+
+	case core.PeerFound:
+		// О самой находке уже сообщил механизм обнаружения
+		if payload.Nickname != "" {
+			h.mu.Lock()
+			if h.lanNames == nil {
+				h.lanNames = make(map[peer.ID]string)
+			}
+			h.lanNames[payload.AddrInfo.ID] = payload.Nickname
+			h.mu.Unlock()
+		}
+
+	case core.LANPeer:
+		if event.Type == core.EventLANPeerAppeared {
+			log.Printf("📡 Рядом: %s", h.lanPeerName(payload))
+		} else {
+			log.Printf("📡 Больше не рядом: %s", h.lanPeerName(payload))
+		}
+
+	case core.PeerEvent:
+		online := event.Type == core.EventPeerConnected
+		h.updatePresence(payload.PeerID, online)
+		if online {
+			log.Printf("🟢 %s в сети", h.DisplayName(payload.PeerID))
+		} else {
+			log.Printf("⚪ %s отключился", h.DisplayName(payload.PeerID))
+		}
+
+	case core.IncomingFileOffer:
+		if payload.Offer.Nearby {
+			log.Printf("📡 Устройство рядом %s предлагает файл %s (%d байт)",
+				h.DisplayName(payload.Offer.PeerID), payload.Offer.Name, payload.Offer.Size)
+		} else {
+			log.Printf("📎 %s предлагает файл %s (%d байт)",
+				h.DisplayName(payload.Offer.PeerID), payload.Offer.Name, payload.Offer.Size)
+		}
+		if payload.Decision.Safety.Level != core.SafetySafe {
+			log.Printf("⚠️ Файл может быть опасен: %v", payload.Decision.Safety.Reasons)
+		}
+		log.Printf("   /accept %d - принять, /reject %d - отклонить", payload.OfferID, payload.OfferID)
+
+	case core.FileReceived:
+		switch {
+		case payload.Error != "":
+			log.Printf("❌ Файл от %s не получен: %s", h.DisplayName(payload.PeerID), payload.Error)
+		case !payload.Verified:
+			log.Printf("⚠️ Файл %s получен, но не прошел проверку целостности", payload.Path)
+		default:
+			log.Printf("✅ Файл сохранен: %s", payload.Path)
+		}
+		switch {
+		case payload.Error != "":
+		case payload.Requested && payload.Verified:
+			h.restoreAttachment(payload)
+		default:
+			h.recordFile(payload)
+		}
+		if payload.Safety.Level == core.SafetyDangerous {
+			log.Printf("⚠️ Осторожно, файл может быть опасен: %v", payload.Safety.Reasons)
+		}
+
+	case core.FileProgress:
+		direction := "📤"
+		if payload.Incoming {
+			direction = "📥"
+		}
+		log.Printf("%s %s: %d%% (%d из %d байт)", direction, payload.Name,
+			payload.Bytes*100/payload.Size, payload.Bytes, payload.Size)
+
+	case core.TransferQueueState:
+		switch payload.Changed.State {
+		case core.TransferCompleted:
+			log.Printf("✅ Передача %s завершена", payload.Changed.Name)
+		case core.TransferFailed:
+			log.Printf("❌ Передача %s не удалась: %s", payload.Changed.Name, payload.Changed.Error)
+		}
+
+	case core.ContactRequest:
+		h.printContactRequest(payload)
+
+	case core.Introduction:
+		h.printIntroduction(payload)
+
+	case core.ContactCard:
+		h.printContactCard(payload)
+
+	case core.KeyTransition:
+		log.Printf("🔑 %s сменил ключ личности, контакт перенесен на %s",
+			h.DisplayName(payload.NewPeerID), payload.NewPeerID.ShortString())
+
+	case core.MailboxRetrieved:
+		log.Printf("📬 Получено писем, пока вы были не в сети: %d", payload.Delivered)
+
+	case core.IdentityDestroyed:
+		log.Printf("💥 %s уничтожил свою личность: сообщениям с этого PeerID больше нельзя доверять",
+			h.DisplayName(payload.PeerID))
+
+	case core.StatusMessage:
+		if payload.Text == "" {
+			log.Printf("💬 %s снял статус", h.DisplayName(payload.PeerID))
+		} else {
+			log.Printf("💬 %s: %s", h.DisplayName(payload.PeerID), payload.Text)
+		}
+
+	case core.ScreenShareState:
+		switch {
+		case payload.Active && payload.Incoming:
+			log.Printf("🖥️ %s показывает экран (просмотр доступен в графическом клиенте)", h.DisplayName(payload.PeerID))
+		case payload.Error != "":
+			log.Printf("❌ Демонстрация экрана с %s прервана: %s", h.DisplayName(payload.PeerID), payload.Error)
+		case !payload.Active:
+			log.Printf("🖥️ Демонстрация экрана с %s завершена", h.DisplayName(payload.PeerID))
+		}
+
+	case core.ScheduledDispatch:
+		h.printScheduledDispatch(payload)
+
+	case core.ExportProgress:
+		switch {
+		case !payload.Finished:
+			log.Printf("📤 Экспорт: %d/%d сообщений", payload.Done, payload.Total)
+		case payload.Error != "":
+			log.Printf("❌ Экспорт не удался: %s", payload.Error)
+		default:
+			log.Printf("✅ Диалог экспортирован: %s", payload.Path)
+		}
+
+	case core.CleanupProgress:
+		switch {
+		case !payload.Finished:
+			log.Printf("🧹 Очистка: %d/%d", payload.Done, payload.Total)
+		case payload.Error != "":
+			log.Printf("❌ Очистка не удалась: %s", payload.Error)
+		default:
+			log.Printf("✅ Очистка завершена: сообщений %d, файлов %d, освобождено %s",
+				payload.Messages, payload.Files, formatBytes(payload.FreedBytes))
+		}
+
+	case core.BackupStatus:
+		switch {
+		case payload.Error != "":
+			log.Printf("❌ Резервное копирование: %s", payload.Error)
+		case len(payload.Restored) > 0:
+			log.Printf("✅ Восстановлено из %s: %s", payload.Name, strings.Join(payload.Restored, ", "))
+			log.Println("   Перезапустите приложение, чтобы применить восстановленные данные")
+		default:
+			log.Printf("✅ Резервная копия %s (%s)", payload.Name, formatBytes(int64(payload.Size)))
+			if len(payload.Removed) > 0 {
+				log.Printf("   Удалены старые копии: %s", strings.Join(payload.Removed, ", "))
+			}
+		}
+
+	case core.UpdateAvailable:
+		log.Printf("⬆️ Доступна версия %s (у вас %s): %s", payload.Version, payload.Current, payload.URL)
+		if payload.Notes != "" {
+			log.Printf("   %s", payload.Notes)
+		}
+
+	case core.LeakSuspected:
+		log.Printf("🔬 Возможная утечка: %s %v", payload.Metric, payload.Samples)
+	}
+}
@@ -2,26 +2,102 @@ package tui
 
 import (
 	"bufio"
+	"context"
 	"log"
 	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"sync"
 
 	"OwlWhisper/internal/core"
+	"OwlWhisper/internal/notify"
+	"OwlWhisper/internal/storage"
+	"OwlWhisper/pkg/config"
+	"OwlWhisper/pkg/interfaces"
+
+	"github.com/libp2p/go-libp2p/core/peer"
 )
 
 // Handler обрабатывает пользовательский ввод
 type Handler struct {
-	node *core.Node
-	mu   sync.Mutex
+	node     *core.Node
+	messages interfaces.IMessageRepository
+	contacts interfaces.IContactRepository
+	config   *config.Config
+	notifier *notify.Dispatcher
+	exporter func(peerID peer.ID, format, path string)
+	cleaner  func(opts storage.CleanupOptions)
+	rotator  func() (core.KeyTransition, int, error)
+	wiper    func(confirmation string, notify bool) error
+	wipeWord string
+	prefs    interfaces.IPreferenceRepository
+	media    interfaces.IMediaRepository
+	backups  BackupService
+	mu       sync.Mutex
+
+	discovery *core.DiscoveryManager
+	// lanNames - имена, которые участники локальной сети объявили сами (mDNS)
+	lanNames map[peer.ID]string
+
+	// current - собеседник выбранного диалога; пусто - рассылка всем
+	current      peer.ID
+	scrollOffset int
 }
 
 // NewHandler создает новый TUI обработчик
-func NewHandler(node *core.Node) *Handler {
+func NewHandler(node *core.Node, messages interfaces.IMessageRepository, contacts interfaces.IContactRepository, cfg *config.Config) *Handler {
 	return &Handler{
-		node: node,
+		node:     node,
+		messages: messages,
+		contacts: contacts,
+		config:   cfg,
 	}
 }
 
+// SetNotifier подключает системные уведомления о событиях ядра
+func (h *Handler) SetNotifier(notifier *notify.Dispatcher) {
+	h.notifier = notifier
+}
+
+// SetPreferences подключает настройки уведомлений диалогов для /mute и /unmute
+func (h *Handler) SetPreferences(prefs interfaces.IPreferenceRepository) {
+	h.prefs = prefs
+}
+
+// SetMediaRepository подключает хранилище вложений: принятые файлы
+// переносятся в него и хранятся в одном экземпляре
+func (h *Handler) SetMediaRepository(media interfaces.IMediaRepository) {
+	h.media = media
+}
+
+// SetDiscovery подключает менеджер обнаружения для сетевой диагностики (/dht)
+func (h *Handler) SetDiscovery(discovery *core.DiscoveryManager) {
+	h.discovery = discovery
+}
+
+// SetExporter подключает фоновый экспорт диалогов для команды /export
+func (h *Handler) SetExporter(exporter func(peerID peer.ID, format, path string)) {
+	h.exporter = exporter
+}
+
+// SetCleaner подключает фоновую очистку хранилища для команды /cleanup
+func (h *Handler) SetCleaner(cleaner func(opts storage.CleanupOptions)) {
+	h.cleaner = cleaner
+}
+
+// SetKeyRotator подключает смену ключа личности для команды /rotate-key
+func (h *Handler) SetKeyRotator(rotator func() (core.KeyTransition, int, error)) {
+	h.rotator = rotator
+}
+
+// SetWiper подключает экстренное уничтожение данных для команды /wipe;
+// phrase - фраза, которую нужно ввести для подтверждения
+func (h *Handler) SetWiper(wiper func(confirmation string, notify bool) error, phrase string) {
+	h.wiper = wiper
+	h.wipeWord = phrase
+}
+
 // Start запускает обработку пользовательского ввода
 func (h *Handler) Start() error {
 	log.Println("🦉 Добро пожаловать в Owl Whisper!")
@@ -30,14 +106,76 @@ func (h *Handler) Start() error {
 	log.Println("Доступные команды:")
 	log.Println("  /help          - Показать справку")
 	log.Println("  /peers         - Показать подключенных пиров")
+	log.Println("  /paste         - Отправить содержимое буфера обмена в диалог")
+	log.Println("  /attach <путь> - Отправить файл в диалог")
+	log.Println("  /accept <id>   - Принять входящий файл")
+	log.Println("  /reject <id>   - Отклонить входящий файл")
+	log.Println("  /chat <peer>   - Открыть диалог с пиром")
+	log.Println("  /all           - Вернуться к рассылке всем")
+	log.Println("  /history [n]   - Показать последние сообщения диалога")
+	log.Println("  /more          - Прокрутить историю назад")
+	log.Println("  /search <текст> - Найти сообщения в истории")
+	log.Println("  /draft [текст|-] - Черновик диалога")
+	log.Println("  /export <json|html> [файл] - Экспортировать диалог")
+	log.Println("  /import <telegram|csv> <файл> - Импортировать историю")
+	log.Println("  /files         - Вложения диалога")
+	log.Println("  /refetch <хеш> - Скачать удаленное вложение заново")
+	log.Println("  /storage       - Сколько места занимают диалоги")
+	log.Println("  /backup        - Сделать резервную копию (/backups - список)")
+	log.Println("  /restore [имя] - Восстановить из резервной копии")
+	log.Println("  /cleanup <media <дней>|chat [media]|vacuum|gc> - Очистить хранилище")
+	log.Println("  /schedule <время> <текст> - Отправить сообщение позже")
+	log.Println("  /remind <время> <текст> - Напомнить себе")
+	log.Println("  /scheduled     - Показать отложенные сообщения")
+	log.Println("  /unschedule <id> - Отменить отложенное сообщение")
+	log.Println("  /mute [1h|forever|mentions] - Заглушить диалог")
+	log.Println("  /unmute        - Включить уведомления диалога")
+	log.Println("  /shaping [pad on|off|cover <30s|off>] - Защита диалога от анализа трафика")
+	log.Println("  /sealed [on|off] - Скрытый отправитель в открытом диалоге")
+	log.Println("  /contacts      - Показать контакты")
+	log.Println("  /add <peer> <имя> - Добавить контакт")
+	log.Println("  /rename <контакт> <имя> - Переименовать контакт")
+	log.Println("  /remove <контакт> - Удалить контакт")
+	log.Println("  /nick <имя>    - Задать свое имя")
+	log.Println("  /profile       - Показать свой профиль")
+	log.Println("  /status [текст] - Статус для контактов (без текста - снять)")
+	log.Println("  /attest [new <github|web> <аккаунт>] - Подтверждения владения аккаунтами")
+	log.Println("  /proofs <peer> [fetch] - Проверить подтверждения контакта")
+	log.Println("  /rotate-key confirm - Сменить ключ личности и сообщить контактам")
+	log.Println("  /wipe [notify] <фраза> - Экстренно уничтожить ключ и все данные")
+	log.Println("  /seen <peer>   - Когда пир был активен")
+	log.Println("  /request <peer> [текст] - Попросить добавить в контакты")
+	log.Println("  /requests      - Входящие запросы на добавление")
+	log.Println("  /tag <peer> <метка> - Пометить пира (/untag, /tagged <метка>)")
+	log.Println("  /meta <peer> [set <ключ> <значение>|unset <ключ>] - Метаданные контакта")
+	log.Println("  /introduce <через> <кому> [текст] - Попросить общий контакт познакомить")
+	log.Println("  /intros [accept <id>|deny <id|all>] - Знакомства, ждущие решения")
+	log.Println("  /card <кому> <контакт|me> - Поделиться карточкой контакта")
+	log.Println("  /cards [add <id>|drop <id|all>] - Полученные карточки контактов")
+	log.Println("  /servercard [export|import <файл>] - Карточка своего сервера")
+	log.Println("  /admin <узел> <stats|rotate-logs|ban|unban|reload> - Управление своим сервером")
+	log.Println("  /relays [pin <адрес>|unpin <peer>] - Ретрансляторы и резервирования")
+	log.Println("  /transport <контакт> [default|auto|direct|relay] - Политика соединения с контактом")
+	log.Println("  /caps <peer>   - Что поддерживает клиент пира")
+	log.Println("  /dht           - Статистика операций DHT")
+	log.Println("  /dhtinfo [refresh] - Таблица маршрутизации DHT и ее здоровье")
+	log.Println("  /nearby        - Участники в локальной сети")
+	log.Println("  /drop <n> <путь> - Отправить файл участнику из /nearby")
+	log.Println("  /find <peer>   - Найти пира и соединиться с ним")
+	log.Println("  /paths <peer>  - Соединения с пиром и их задержка")
 	log.Println("  /quit          - Выйти из приложения")
 	log.Println()
 	log.Println("Просто введите сообщение для отправки всем подключенным пирам")
 	log.Println()
 
+	// События ядра (сообщения, файлы, подключения) выводим параллельно вводу
+	go h.runEvents()
+	go h.runSecurityEvents()
+
 	scanner := bufio.NewScanner(os.Stdin)
 	for scanner.Scan() {
 		message := scanner.Text()
+		h.node.MarkActive()
 
 		// Обрабатываем команды
 		if message == "/quit" {
@@ -55,20 +193,292 @@ func (h *Handler) Start() error {
 			continue
 		}
 
-		// Отправляем сообщение всем пирам
+		if message == "/paste" {
+			h.pasteClipboard()
+			continue
+		}
+
+		if message == "/attach" || strings.HasPrefix(message, "/attach ") {
+			h.attachFile(strings.TrimSpace(strings.TrimPrefix(message, "/attach")))
+			continue
+		}
+
+		if message == "/chat" || strings.HasPrefix(message, "/chat ") {
+			h.selectConversation(strings.TrimSpace(strings.TrimPrefix(message, "/chat")))
+			continue
+		}
+
+		if message == "/all" {
+			h.leaveConversation()
+			continue
+		}
+
+		if message == "/history" || strings.HasPrefix(message, "/history ") {
+			h.handleHistory(strings.Fields(message)[1:])
+			continue
+		}
+
+		if message == "/more" {
+			h.scrollBack()
+			continue
+		}
+
+		if message == "/export" || strings.HasPrefix(message, "/export ") {
+			h.exportConversation(strings.Fields(message)[1:])
+			continue
+		}
+
+		if message == "/import" || strings.HasPrefix(message, "/import ") {
+			h.importHistory(strings.Fields(message)[1:])
+			continue
+		}
+
+		if message == "/files" {
+			h.listAttachments()
+			continue
+		}
+
+		if message == "/refetch" || strings.HasPrefix(message, "/refetch ") {
+			h.refetchAttachment(strings.TrimSpace(strings.TrimPrefix(message, "/refetch")))
+			continue
+		}
+
+		if message == "/storage" {
+			h.showStorageUsage()
+			continue
+		}
+
+		if message == "/cleanup" || strings.HasPrefix(message, "/cleanup ") {
+			h.cleanupStorage(strings.Fields(message)[1:])
+			continue
+		}
+
+		if message == "/draft" || strings.HasPrefix(message, "/draft ") {
+			h.handleDraft(strings.TrimSpace(strings.TrimPrefix(message, "/draft")))
+			continue
+		}
+
+		if message == "/search" || strings.HasPrefix(message, "/search ") {
+			h.searchHistory(strings.TrimSpace(strings.TrimPrefix(message, "/search")))
+			continue
+		}
+
+		if handled := h.handleScheduleCommand(message); handled {
+			continue
+		}
+
+		if handled := h.handleContactCommand(message); handled {
+			continue
+		}
+
+		if handled := h.handleNetworkCommand(message); handled {
+			continue
+		}
+
+		if handled := h.handleBackupCommand(message); handled {
+			continue
+		}
+
+		if strings.HasPrefix(message, "/accept ") || strings.HasPrefix(message, "/reject ") {
+			h.answerFileOffer(message)
+			continue
+		}
+
 		if message != "" {
-			h.node.BroadcastMessage(message)
+			h.sendMessage(message)
 		}
 	}
 
 	return scanner.Err()
 }
 
+// sendMessage отправляет сообщение собеседнику диалога или всем пирам
+// и сохраняет его в историю
+func (h *Handler) sendMessage(message string) {
+	h.mu.Lock()
+	current := h.current
+	h.mu.Unlock()
+
+	self := h.node.GetHost().ID()
+	if current != "" {
+		if err := h.node.SendMessage(current, message); err != nil {
+			log.Printf("❌ %v", err)
+			return
+		}
+		h.recordMessage(self, current, message, true)
+
+		// Отправленный текст больше не черновик
+		if err := h.messages.SaveDraft(context.Background(), current.String(), ""); err != nil {
+			log.Printf("⚠️ Не удалось удалить черновик: %v", err)
+		}
+		return
+	}
+
+	peers := h.node.GetPeers()
+	h.node.BroadcastMessage(message)
+	for _, p := range peers {
+		h.recordMessage(self, p, message, true)
+	}
+}
+
+// handleScheduleCommand обрабатывает команды отложенных сообщений и уведомлений
+func (h *Handler) handleScheduleCommand(message string) bool {
+	fields := strings.Fields(message)
+	if len(fields) == 0 {
+		return false
+	}
+
+	switch fields[0] {
+	case "/schedule":
+		h.scheduleMessage(fields[1:])
+	case "/remind":
+		h.scheduleReminder(fields[1:])
+	case "/scheduled":
+		h.showScheduled()
+	case "/unschedule":
+		h.cancelScheduled(fields[1:])
+	case "/mute":
+		h.muteConversation(fields[1:])
+	case "/unmute":
+		h.unmuteConversation()
+	case "/shaping":
+		h.shapeConversation(fields[1:])
+	case "/sealed":
+		h.sealConversation(fields[1:])
+	default:
+		return false
+	}
+	return true
+}
+
+// handleContactCommand обрабатывает команды контактов и профиля
+func (h *Handler) handleContactCommand(message string) bool {
+	fields := strings.Fields(message)
+	if len(fields) == 0 {
+		return false
+	}
+
+	switch fields[0] {
+	case "/contacts":
+		h.showContacts()
+	case "/add":
+		h.addContact(fields[1:])
+	case "/rename":
+		h.renameContact(fields[1:])
+	case "/remove":
+		h.removeContact(fields[1:])
+	case "/nick":
+		h.setNickname(fields[1:])
+	case "/profile":
+		h.showProfile()
+	case "/status":
+		h.setStatus(fields[1:])
+	case "/attest":
+		h.handleAttestations(fields[1:])
+	case "/proofs":
+		h.checkAttestations(fields[1:])
+	case "/rotate-key":
+		h.rotateKey(fields[1:])
+	case "/wipe":
+		h.wipe(fields[1:])
+	case "/seen":
+		h.showPresence(fields[1:])
+	case "/request":
+		h.sendContactRequest(fields[1:])
+	case "/requests":
+		h.showContactRequests()
+	case "/approve":
+		h.approveContactRequest(fields[1:])
+	case "/deny":
+		h.denyContactRequests(fields[1:])
+	case "/introduce":
+		h.requestIntroduction(fields[1:])
+	case "/intros":
+		h.handleIntroductions(fields[1:])
+	case "/card":
+		h.sendContactCard(fields[1:])
+	case "/cards":
+		h.handleContactCards(fields[1:])
+	case "/servercard":
+		h.handleServerCard(fields[1:])
+	case "/admin":
+		h.handleAdmin(fields[1:])
+	case "/tag":
+		h.tagPeer(fields[1:])
+	case "/untag":
+		h.untagPeer(fields[1:])
+	case "/tagged":
+		h.showTagged(fields[1:])
+	case "/meta":
+		h.handleContactMetadata(message)
+	default:
+		return false
+	}
+	return true
+}
+
 // showHelp показывает справку
 func (h *Handler) showHelp() {
 	log.Println("📚 Справка по командам:")
 	log.Println("  /help          - Показать эту справку")
 	log.Println("  /peers         - Показать подключенных пиров")
+	log.Println("  /paste         - Отправить текст или картинку из буфера обмена в диалог")
+	log.Println("  /attach <путь> - Отправить файл в открытый диалог")
+	log.Println("  /accept <id>   - Принять входящий файл")
+	log.Println("  /reject <id>   - Отклонить входящий файл")
+	log.Println("  /chat <peer>   - Открыть диалог с пиром")
+	log.Println("  /all           - Вернуться к рассылке всем")
+	log.Println("  /history [n]   - Показать последние сообщения диалога")
+	log.Println("  /more          - Прокрутить историю назад")
+	log.Println("  /search <текст> - Найти сообщения в истории")
+	log.Println("  /draft [текст|-] - Черновик диалога")
+	log.Println("  /export <json|html> [файл] - Экспортировать диалог")
+	log.Println("  /import <telegram|csv> <файл> - Импортировать историю")
+	log.Println("  /files         - Вложения диалога")
+	log.Println("  /refetch <хеш> - Скачать удаленное вложение заново")
+	log.Println("  /storage       - Сколько места занимают диалоги")
+	log.Println("  /backup        - Сделать резервную копию (/backups - список)")
+	log.Println("  /restore [имя] - Восстановить из резервной копии")
+	log.Println("  /cleanup <media <дней>|chat [media]|vacuum|gc> - Очистить хранилище")
+	log.Println("  /schedule <время> <текст> - Отправить сообщение позже")
+	log.Println("  /remind <время> <текст> - Напомнить себе")
+	log.Println("  /scheduled     - Показать отложенные сообщения")
+	log.Println("  /unschedule <id> - Отменить отложенное сообщение")
+	log.Println("  /mute [1h|forever|mentions] - Заглушить диалог")
+	log.Println("  /unmute        - Включить уведомления диалога")
+	log.Println("  /shaping [pad on|off|cover <30s|off>] - Защита диалога от анализа трафика")
+	log.Println("  /sealed [on|off] - Скрытый отправитель в открытом диалоге")
+	log.Println("  /contacts      - Показать контакты")
+	log.Println("  /add <peer> <имя> - Добавить контакт")
+	log.Println("  /rename <контакт> <имя> - Переименовать контакт")
+	log.Println("  /remove <контакт> - Удалить контакт")
+	log.Println("  /nick <имя>    - Задать свое имя")
+	log.Println("  /profile       - Показать свой профиль")
+	log.Println("  /status [текст] - Статус для контактов (без текста - снять)")
+	log.Println("  /attest [new <github|web> <аккаунт>] - Подтверждения владения аккаунтами")
+	log.Println("  /proofs <peer> [fetch] - Проверить подтверждения контакта")
+	log.Println("  /rotate-key confirm - Сменить ключ личности и сообщить контактам")
+	log.Println("  /wipe [notify] <фраза> - Экстренно уничтожить ключ и все данные")
+	log.Println("  /seen <peer>   - Когда пир был активен")
+	log.Println("  /request <peer> [текст] - Попросить добавить в контакты")
+	log.Println("  /requests      - Входящие запросы на добавление")
+	log.Println("  /tag <peer> <метка> - Пометить пира (/untag, /tagged <метка>)")
+	log.Println("  /meta <peer> [set <ключ> <значение>|unset <ключ>] - Метаданные контакта")
+	log.Println("  /introduce <через> <кому> [текст] - Попросить общий контакт познакомить")
+	log.Println("  /intros [accept <id>|deny <id|all>] - Знакомства, ждущие решения")
+	log.Println("  /card <кому> <контакт|me> - Поделиться карточкой контакта")
+	log.Println("  /cards [add <id>|drop <id|all>] - Полученные карточки контактов")
+	log.Println("  /servercard [export|import <файл>] - Карточка своего сервера")
+	log.Println("  /admin <узел> <stats|rotate-logs|ban|unban|reload> - Управление своим сервером")
+	log.Println("  /relays [pin <адрес>|unpin <peer>] - Ретрансляторы и резервирования")
+	log.Println("  /transport <контакт> [default|auto|direct|relay] - Политика соединения с контактом")
+	log.Println("  /caps <peer>   - Что поддерживает клиент пира")
+	log.Println("  /dht           - Статистика операций DHT")
+	log.Println("  /dhtinfo [refresh] - Таблица маршрутизации DHT и ее здоровье")
+	log.Println("  /nearby        - Участники в локальной сети")
+	log.Println("  /drop <n> <путь> - Отправить файл участнику из /nearby")
+	log.Println("  /find <peer>   - Найти пира и соединиться с ним")
+	log.Println("  /paths <peer>  - Соединения с пиром и их задержка")
 	log.Println("  /quit          - Выйти из приложения")
 	log.Println()
 	log.Println("💡 Просто введите текст для отправки сообщения всем подключенным пирам")
@@ -86,7 +496,103 @@ func (h *Handler) showPeers() {
 	}
 
 	log.Printf("🔌 Подключенные пиры (%d):", len(peers))
-	for _, peer := range peers {
-		log.Printf("  🟢 %s", peer.ShortString())
+	for _, p := range peers {
+		log.Printf("  🟢 %s (%s)", h.DisplayName(p), p.ShortString())
+	}
+}
+
+// pasteClipboard отправляет в открытый диалог текст или картинку из буфера
+// обмена. Без открытого диалога ничего не отправляется: содержимое буфера не
+// должно случайно уйти всем пирам
+func (h *Handler) pasteClipboard() {
+	h.mu.Lock()
+	current := h.current
+	h.mu.Unlock()
+	if current == "" {
+		log.Println("❌ Откройте диалог через /chat, чтобы вставить буфер обмена")
+		return
+	}
+
+	content, err := readClipboard()
+	if err != nil {
+		log.Printf("❌ %v", err)
+		return
+	}
+
+	if content.Image != nil {
+		path, err := saveClipboardImage(content.Image)
+		if err != nil {
+			log.Printf("❌ Не удалось сохранить картинку из буфера: %v", err)
+			return
+		}
+		transferID := h.node.SendFile(current, path, core.PriorityUser)
+		log.Printf("🖼️ Картинка из буфера (%d байт) поставлена в очередь для %s (передача #%d)",
+			len(content.Image), h.DisplayName(current), transferID)
+		return
+	}
+
+	// Протокол сообщений построчный, поэтому многострочный текст уходит по строкам
+	sent := false
+	for _, line := range strings.Split(strings.ReplaceAll(content.Text, "\r\n", "\n"), "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		h.sendMessage(line)
+		sent = true
+	}
+	if !sent {
+		log.Println("📋 Буфер обмена пуст")
+	}
+}
+
+// attachFile обрабатывает /attach: отправляет файл в открытый диалог
+func (h *Handler) attachFile(path string) {
+	if path == "" {
+		log.Println("❌ Использование: /attach <путь>")
+		return
+	}
+	h.mu.Lock()
+	current := h.current
+	h.mu.Unlock()
+	if current == "" {
+		log.Println("❌ Откройте диалог через /chat, чтобы отправить файл")
+		return
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		log.Printf("❌ %v", err)
+		return
+	}
+	if !info.Mode().IsRegular() {
+		log.Printf("❌ %s не является файлом", path)
+		return
+	}
+	transferID := h.node.SendFile(current, path, core.PriorityUser)
+	log.Printf("📎 Файл %s (%d байт) поставлен в очередь для %s (передача #%d)",
+		filepath.Base(path), info.Size(), h.DisplayName(current), transferID)
+}
+
+// answerFileOffer обрабатывает команды /accept и /reject
+func (h *Handler) answerFileOffer(command string) {
+	fields := strings.Fields(command)
+	if len(fields) != 2 {
+		log.Println("❌ Использование: /accept <id> или /reject <id>")
+		return
+	}
+
+	offerID, err := strconv.ParseUint(fields[1], 10, 64)
+	if err != nil {
+		log.Printf("❌ Некорректный номер предложения: %s", fields[1])
+		return
+	}
+
+	if fields[0] == "/accept" {
+		err = h.node.AcceptFileOffer(offerID, "")
+	} else {
+		err = h.node.RejectFileOffer(offerID)
+	}
+	if err != nil {
+		log.Printf("❌ %v", err)
 	}
 }
@@ -0,0 +1,51 @@
+package tui
+
+import (
+	"context"
+	"log"
+	"os"
+	"strings"
+
+	"OwlWhisper/internal/importer"
+)
+
+// importHistory обрабатывает /import <формат> <файл>: переносит историю
+// из другого мессенджера в диалоги с контактами того же имени
+func (h *Handler) importHistory(args []string) {
+	if len(args) < 2 {
+		log.Printf("❌ Использование: /import <%s> <файл>", strings.Join(importer.Names(), "|"))
+		return
+	}
+
+	source, err := importer.Get(args[0])
+	if err != nil {
+		log.Printf("❌ %v", err)
+		return
+	}
+
+	path := strings.Join(args[1:], " ")
+	file, err := os.Open(path)
+	if err != nil {
+		log.Printf("❌ Не удалось открыть файл: %v", err)
+		return
+	}
+	defer file.Close()
+
+	opts := importer.Options{Self: h.node.GetHost().ID().String()}
+	result, err := importer.Import(context.Background(), source, file, h.messages, h.contacts, opts)
+	if err != nil {
+		log.Printf("❌ %v", err)
+		if result == nil {
+			return
+		}
+	}
+
+	log.Printf("📥 Импортировано сообщений: %d, уже было: %d", result.Imported, result.Duplicate)
+	for name, peerID := range result.Matched {
+		log.Printf("   %s → %s", name, shortID(peerID))
+	}
+	if len(result.Unmatched) > 0 {
+		log.Printf("⚠️ Пропущены диалоги без контакта: %s", strings.Join(result.Unmatched, ", "))
+		log.Println("   Добавьте контакты с такими именами (/add) и повторите импорт")
+	}
+}
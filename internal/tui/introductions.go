@@ -0,0 +1,137 @@
+package tui
+
+import (
+	"log"
+	"strconv"
+	"strings"
+
+	"OwlWhisper/internal/core"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+// requestIntroduction обрабатывает /introduce <через> <кому> [текст]: просит
+// общий контакт познакомить нас с пиром, которого не удается найти
+func (h *Handler) requestIntroduction(args []string) {
+	if len(args) < 2 {
+		log.Println("❌ Использование: /introduce <через> <кому> [текст]")
+		return
+	}
+	via, err := h.resolvePeer(args[0])
+	if err != nil {
+		log.Printf("❌ %v", err)
+		return
+	}
+	target, err := h.resolvePeer(args[1])
+	if err != nil {
+		log.Printf("❌ %v", err)
+		return
+	}
+	note := strings.Join(args[2:], " ")
+
+	go func() {
+		if err := h.node.RequestIntroduction(via, target, note); err != nil {
+			log.Printf("❌ %v", err)
+			return
+		}
+		log.Printf("🤝 Просьба познакомить с %s отправлена %s", h.DisplayName(target), h.DisplayName(via))
+	}()
+}
+
+// handleIntroductions обрабатывает /intros [accept <id>|deny <id...|all>]
+func (h *Handler) handleIntroductions(args []string) {
+	if len(args) == 0 {
+		h.showIntroductions()
+		return
+	}
+	if len(args) < 2 || (args[0] != "accept" && args[0] != "deny") {
+		log.Println("❌ Использование: /intros [accept <id>|deny <id...|all>]")
+		return
+	}
+
+	if args[0] == "deny" {
+		if args[1] == "all" {
+			log.Printf("🗑️ Отклонено знакомств: %d", h.node.RejectIntroductions())
+			return
+		}
+		var ids []uint64
+		for _, arg := range args[1:] {
+			id, err := strconv.ParseUint(arg, 10, 64)
+			if err != nil {
+				log.Printf("❌ Некорректный ID знакомства: %s", arg)
+				return
+			}
+			ids = append(ids, id)
+		}
+		log.Printf("🗑️ Отклонено знакомств: %d", h.node.RejectIntroductions(ids...))
+		return
+	}
+
+	id, err := strconv.ParseUint(args[1], 10, 64)
+	if err != nil {
+		log.Println("❌ Использование: /intros accept <id>")
+		return
+	}
+	go func() {
+		intro, err := h.node.AcceptIntroduction(id)
+		if err != nil {
+			log.Printf("❌ %v", err)
+			return
+		}
+		if intro.Stage == core.IntroductionRequested {
+			log.Printf("🤝 Предложение передано %s", h.DisplayName(intro.Target))
+		} else {
+			log.Printf("🤝 Соединяемся с %s...", h.DisplayName(intro.Requester))
+		}
+	}()
+}
+
+// showIntroductions выводит знакомства, ждущие решения
+func (h *Handler) showIntroductions() {
+	intros := h.node.Introductions()
+	if len(intros) == 0 {
+		log.Println("🤝 Знакомств нет")
+		return
+	}
+
+	log.Printf("🤝 Знакомства (%d):", len(intros))
+	for _, intro := range intros {
+		log.Printf("  [%d] %s", intro.ID, h.describeIntroduction(intro))
+	}
+	log.Println("  /intros accept <id> - согласиться, /intros deny <id|all> - отклонить")
+}
+
+// printIntroduction выводит событие знакомства
+func (h *Handler) printIntroduction(intro core.Introduction) {
+	switch intro.Stage {
+	case core.IntroductionConnected:
+		log.Printf("🤝 Знакомство через %s состоялось: %s на связи", h.DisplayName(intro.Via), h.DisplayName(h.introducedPeer(intro)))
+	case core.IntroductionFailed:
+		log.Printf("❌ Знакомство через %s не удалось: %s", h.DisplayName(intro.Via), intro.Error)
+	default:
+		log.Printf("🤝 %s", h.describeIntroduction(intro))
+		log.Printf("   /intros accept %d - согласиться, /intros deny %d - отклонить", intro.ID, intro.ID)
+	}
+}
+
+// describeIntroduction описывает знакомство, ждущее решения
+func (h *Handler) describeIntroduction(intro core.Introduction) string {
+	text := ""
+	if intro.Stage == core.IntroductionRequested {
+		text = h.DisplayName(intro.Requester) + " просит познакомить с " + h.DisplayName(intro.Target)
+	} else {
+		text = h.DisplayName(intro.Via) + " предлагает познакомиться с " + intro.Requester.ShortString()
+	}
+	if intro.Note != "" {
+		text += ": " + intro.Note
+	}
+	return text
+}
+
+// introducedPeer возвращает второго участника знакомства
+func (h *Handler) introducedPeer(intro core.Introduction) peer.ID {
+	if intro.Requester == h.node.GetHost().ID() {
+		return intro.Target
+	}
+	return intro.Requester
+}
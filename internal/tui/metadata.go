@@ -0,0 +1,56 @@
+package tui
+
+import (
+	"log"
+	"strings"
+)
+
+// handleContactMetadata обрабатывает /meta <peer> [set <ключ> <значение>|unset <ключ>].
+// Значение берется из строки целиком, чтобы сохранить пробелы в заметках
+func (h *Handler) handleContactMetadata(message string) {
+	fields := strings.Fields(message)
+	if len(fields) < 2 {
+		log.Println("❌ Использование: /meta <peer> [set <ключ> <значение>|unset <ключ>]")
+		return
+	}
+	id, err := h.resolvePeer(fields[1])
+	if err != nil {
+		log.Printf("❌ %v", err)
+		return
+	}
+
+	switch {
+	case len(fields) == 2:
+		keys := h.node.ContactMetadataKeys(id)
+		if len(keys) == 0 {
+			log.Printf("🗂️ У %s нет метаданных", h.DisplayName(id))
+			return
+		}
+		log.Printf("🗂️ Метаданные %s:", h.DisplayName(id))
+		for _, key := range keys {
+			value, _ := h.node.GetContactMetadata(id, key)
+			log.Printf("  %s: %s", key, value)
+		}
+
+	case fields[2] == "set" && len(fields) >= 5:
+		value := strings.TrimSpace(message)
+		for _, field := range fields[:4] {
+			value = strings.TrimSpace(strings.TrimPrefix(value, field))
+		}
+		if err := h.node.SetContactMetadata(id, fields[3], []byte(value)); err != nil {
+			log.Printf("❌ %v", err)
+			return
+		}
+		log.Printf("🗂️ %s для %s сохранено", fields[3], h.DisplayName(id))
+
+	case fields[2] == "unset" && len(fields) == 4:
+		if err := h.node.SetContactMetadata(id, fields[3], nil); err != nil {
+			log.Printf("❌ %v", err)
+			return
+		}
+		log.Printf("🗂️ %s для %s удалено", fields[3], h.DisplayName(id))
+
+	default:
+		log.Println("❌ Использование: /meta <peer> [set <ключ> <значение>|unset <ключ>]")
+	}
+}
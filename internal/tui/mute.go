@@ -0,0 +1,106 @@
+package tui
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"OwlWhisper/pkg/interfaces"
+)
+
+// muteConversation обрабатывает /mute [длительность|forever|mentions]:
+// глушит уведомления открытого диалога; без аргумента - навсегда
+func (h *Handler) muteConversation(args []string) {
+	prefs, ok := h.currentPrefs()
+	if !ok {
+		return
+	}
+
+	mode := "forever"
+	if len(args) > 0 {
+		mode = args[0]
+	}
+
+	switch mode {
+	case "forever":
+		prefs.MutedForever = true
+		prefs.MutedUntil = time.Time{}
+	case "mentions":
+		prefs.MentionsOnly = true
+	default:
+		d, err := time.ParseDuration(mode)
+		if err != nil || d <= 0 {
+			log.Println("❌ Использование: /mute [1h|forever|mentions]")
+			return
+		}
+		prefs.MutedForever = false
+		prefs.MutedUntil = time.Now().Add(d)
+	}
+
+	if err := h.prefs.SavePrefs(context.Background(), prefs); err != nil {
+		log.Printf("❌ Не удалось сохранить настройки диалога: %v", err)
+		return
+	}
+	log.Printf("🔕 %s", describePrefs(prefs))
+}
+
+// unmuteConversation обрабатывает /unmute: возвращает уведомления диалога
+func (h *Handler) unmuteConversation() {
+	prefs, ok := h.currentPrefs()
+	if !ok {
+		return
+	}
+
+	// Защита трафика и скрытый отправитель к уведомлениям не относятся и сохраняются
+	cleared := &interfaces.ConversationPrefs{
+		PeerID:       prefs.PeerID,
+		Padding:      prefs.Padding,
+		CoverTraffic: prefs.CoverTraffic,
+		SealedSender: prefs.SealedSender,
+	}
+	if err := h.prefs.SavePrefs(context.Background(), cleared); err != nil {
+		log.Printf("❌ Не удалось сохранить настройки диалога: %v", err)
+		return
+	}
+	log.Println("🔔 Уведомления диалога включены")
+}
+
+// currentPrefs возвращает настройки открытого диалога
+func (h *Handler) currentPrefs() (*interfaces.ConversationPrefs, bool) {
+	if h.prefs == nil {
+		log.Println("❌ Настройки диалогов недоступны")
+		return nil, false
+	}
+
+	h.mu.Lock()
+	current := h.current
+	h.mu.Unlock()
+	if current == "" {
+		log.Println("❌ Сначала выберите собеседника: /chat <peer>")
+		return nil, false
+	}
+
+	prefs, err := h.prefs.GetPrefs(context.Background(), current.String())
+	if err != nil {
+		log.Printf("❌ %v", err)
+		return nil, false
+	}
+	if prefs == nil {
+		prefs = &interfaces.ConversationPrefs{PeerID: current.String()}
+	}
+	return prefs, true
+}
+
+// describePrefs описывает режим уведомлений диалога
+func describePrefs(prefs *interfaces.ConversationPrefs) string {
+	switch {
+	case prefs.MutedForever:
+		return "Уведомления диалога выключены"
+	case time.Now().Before(prefs.MutedUntil):
+		return "Уведомления диалога выключены до " + prefs.MutedUntil.Format("02.01 15:04")
+	case prefs.MentionsOnly:
+		return "Уведомления диалога только об упоминаниях"
+	default:
+		return "Уведомления диалога включены"
+	}
+}
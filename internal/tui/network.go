@@ -0,0 +1,414 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"OwlWhisper/internal/core"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+// handleNetworkCommand обрабатывает команды сетевых настроек
+func (h *Handler) handleNetworkCommand(message string) bool {
+	fields := strings.Fields(message)
+	if len(fields) == 0 {
+		return false
+	}
+
+	switch fields[0] {
+	case "/relays":
+		h.handleRelays(fields[1:])
+	case "/transport":
+		h.setContactRelayMode(fields[1:])
+	case "/caps":
+		h.showCapabilities(fields[1:])
+	case "/dht":
+		h.showDHTStats()
+	case "/dhtinfo":
+		h.showRoutingTable(fields[1:])
+	case "/nearby":
+		h.showLANPeers()
+	case "/drop":
+		h.dropFile(fields[1:])
+	case "/find":
+		h.findPeer(fields[1:])
+	case "/paths":
+		h.showPaths(fields[1:])
+	default:
+		return false
+	}
+	return true
+}
+
+// handleRelays обрабатывает /relays [pin <адрес>|unpin <peer>]
+func (h *Handler) handleRelays(args []string) {
+	if len(args) == 0 {
+		h.showRelays()
+		return
+	}
+
+	if len(args) != 2 || (args[0] != "pin" && args[0] != "unpin") {
+		log.Println("❌ Использование: /relays [pin <адрес>|unpin <peer>]")
+		return
+	}
+
+	if args[0] == "pin" {
+		info, err := peer.AddrInfoFromString(args[1])
+		if err != nil {
+			log.Printf("❌ Некорректный адрес ретранслятора: %v", err)
+			return
+		}
+		if err := h.node.PinRelay(*info); err != nil {
+			log.Printf("❌ %v", err)
+			return
+		}
+		log.Printf("📌 Ретранслятор %s закреплен", info.ID.ShortString())
+		return
+	}
+
+	id, err := peer.Decode(args[1])
+	if err != nil {
+		log.Printf("❌ Некорректный PeerID: %v", err)
+		return
+	}
+	if err := h.node.UnpinRelay(id); err != nil {
+		log.Printf("❌ %v", err)
+		return
+	}
+	log.Printf("📌 Закрепление ретранслятора %s снято", id.ShortString())
+}
+
+// showRelays выводит ретрансляторы и состояние резервирований
+func (h *Handler) showRelays() {
+	relays := h.node.Relays()
+	if len(relays) == 0 {
+		log.Println("📡 Ретрансляторы не настроены")
+		return
+	}
+
+	log.Printf("📡 Ретрансляторы (%d):", len(relays))
+	for _, relay := range relays {
+		line := "  " + relay.PeerID.ShortString()
+		if relay.Pinned {
+			line += " 📌"
+		}
+		if relay.Reserved {
+			line += " - резервирование до " + relay.Expires.Format("15:04")
+		} else {
+			line += " - без резервирования"
+		}
+		if relay.RTT > 0 {
+			line += ", RTT " + relay.RTT.Round(time.Millisecond).String()
+		}
+		if relay.Error != "" {
+			line += " (" + relay.Error + ")"
+		}
+		log.Println(line)
+	}
+}
+
+// setContactRelayMode обрабатывает /transport <контакт> [default|auto|direct|relay]:
+// показывает или меняет политику ретрансляции контакта
+func (h *Handler) setContactRelayMode(args []string) {
+	if len(args) == 0 || len(args) > 2 {
+		log.Println("❌ Использование: /transport <контакт> [default|auto|direct|relay]")
+		return
+	}
+
+	contact, err := h.findContact(args[0])
+	if err != nil {
+		log.Printf("❌ %v", err)
+		return
+	}
+	id, err := peer.Decode(contact.PeerID)
+	if err != nil {
+		log.Printf("❌ Некорректный PeerID контакта: %v", err)
+		return
+	}
+
+	if len(args) == 1 {
+		log.Printf("🔀 %s: %s", contact.Nickname, describeRelayMode(h.node.PeerRelayMode(id)))
+		return
+	}
+
+	mode, err := core.ParseRelayMode(args[1])
+	if err != nil {
+		log.Printf("❌ %v", err)
+		return
+	}
+	if err := h.node.SetPeerRelayMode(id, mode); err != nil {
+		log.Printf("❌ %v", err)
+		return
+	}
+	contact.RelayMode = string(mode)
+	if err := h.contacts.UpdateContact(context.Background(), contact); err != nil {
+		log.Printf("❌ Не удалось сохранить контакт: %v", err)
+		return
+	}
+	log.Printf("🔀 %s: %s", contact.Nickname, describeRelayMode(h.node.PeerRelayMode(id)))
+}
+
+// describeRelayMode описывает режим ретрансляции для пользователя
+func describeRelayMode(mode core.RelayMode) string {
+	switch mode {
+	case core.RelayNever:
+		return "только прямое соединение"
+	case core.RelayAlways:
+		return "только через ретранслятор"
+	default:
+		return "прямое соединение, ретранслятор как запасной путь"
+	}
+}
+
+// showCapabilities обрабатывает /caps <peer>: что поддерживает клиент пира
+func (h *Handler) showCapabilities(args []string) {
+	if len(args) != 1 {
+		log.Println("❌ Использование: /caps <peer>")
+		return
+	}
+
+	id, err := h.resolvePeer(args[0])
+	if err != nil {
+		log.Printf("❌ %v", err)
+		return
+	}
+	caps, err := h.node.GetPeerCapabilities(id)
+	if err != nil {
+		log.Printf("❌ %v", err)
+		return
+	}
+
+	var names []string
+	for capability := range caps.Capabilities {
+		names = append(names, capability)
+	}
+	sort.Strings(names)
+	log.Printf("🧩 %s (%s): %s", h.DisplayName(id), caps.AgentVersion, strings.Join(names, ", "))
+}
+
+// showPaths обрабатывает /paths <peer>: соединения с пиром и их задержка
+func (h *Handler) showPaths(args []string) {
+	if len(args) != 1 {
+		log.Println("❌ Использование: /paths <peer>")
+		return
+	}
+
+	id, err := h.resolvePeer(args[0])
+	if err != nil {
+		log.Printf("❌ %v", err)
+		return
+	}
+	paths := h.node.Paths(id)
+	if len(paths) == 0 {
+		log.Printf("🛤️ С %s нет соединений", h.DisplayName(id))
+		return
+	}
+
+	log.Printf("🛤️ Пути к %s:", h.DisplayName(id))
+	for _, path := range paths {
+		kind := "прямой"
+		if path.Relayed {
+			kind = "ретранслятор"
+		}
+		switch {
+		case path.Checked.IsZero():
+			log.Printf("   %s (%s): еще не измерен", path.Addr, kind)
+		case path.Healthy:
+			log.Printf("   %s (%s): %v", path.Addr, kind, path.RTT.Round(time.Millisecond))
+		default:
+			log.Printf("   %s (%s): не отвечает", path.Addr, kind)
+		}
+	}
+}
+
+// showDHTStats обрабатывает /dht: успешность и длительность операций DHT
+func (h *Handler) showDHTStats() {
+	if h.discovery == nil {
+		log.Println("❌ Обнаружение в глобальной сети не запущено")
+		return
+	}
+
+	stats := h.discovery.DHTStats()
+	if len(stats) == 0 {
+		log.Println("🌐 Операций DHT еще не было")
+		return
+	}
+	for _, op := range stats {
+		log.Printf("🌐 %s: успешно %d (без результатов %d), ошибок %d",
+			op.Operation, op.Successes, op.Empty, op.Failures)
+		if op.LastError != "" {
+			log.Printf("   последняя ошибка %s: %s", op.LastFailure.Format("15:04:05"), op.LastError)
+		}
+
+		var buckets []string
+		for _, bucket := range op.Latency {
+			if bucket.Count == 0 {
+				continue
+			}
+			bound := "дольше"
+			if bucket.UpperBound > 0 {
+				bound = "≤" + bucket.UpperBound.String()
+			}
+			buckets = append(buckets, fmt.Sprintf("%s: %d", bound, bucket.Count))
+		}
+		log.Printf("   задержки: %s", strings.Join(buckets, ", "))
+	}
+}
+
+// showRoutingTable обрабатывает /dhtinfo [refresh]: корзины таблицы
+// маршрутизации DHT и оценку ее здоровья
+func (h *Handler) showRoutingTable(args []string) {
+	if h.discovery == nil {
+		log.Println("❌ Обнаружение в глобальной сети не запущено")
+		return
+	}
+	if len(args) > 1 || (len(args) == 1 && args[0] != "refresh") {
+		log.Println("❌ Использование: /dhtinfo [refresh]")
+		return
+	}
+
+	if len(args) == 1 {
+		log.Println("🔄 Обновление таблицы маршрутизации...")
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+		err := h.discovery.ForceRefreshRoutingTable(ctx)
+		cancel()
+		if err != nil {
+			log.Printf("❌ %v", err)
+			return
+		}
+	}
+
+	report, err := h.discovery.RoutingTableReport()
+	if err != nil {
+		log.Printf("❌ %v", err)
+		return
+	}
+	log.Printf("🌐 Таблица маршрутизации: %d пиров, здоровье %d/100", report.Peers, report.Health)
+	if report.Interval > 0 {
+		log.Printf("   за %s: +%d −%d (%.1f в час)", report.Interval.Round(time.Second),
+			report.Added, report.Removed, report.ChurnPerHour)
+	}
+	for _, bucket := range report.Buckets {
+		line := fmt.Sprintf("   корзина %2d: %2d/%d", bucket.CPL, bucket.Peers, bucket.Capacity)
+		if bucket.Added > 0 || bucket.Removed > 0 {
+			line += fmt.Sprintf(" (+%d −%d)", bucket.Added, bucket.Removed)
+		}
+		if !bucket.LastRefresh.IsZero() {
+			line += ", обновлена " + bucket.LastRefresh.Format("15:04:05")
+		}
+		log.Println(line)
+	}
+	for _, problem := range report.Problems {
+		log.Printf("⚠️ %s", problem)
+	}
+}
+
+// showLANPeers обрабатывает /nearby: участники, видимые в локальной сети
+func (h *Handler) showLANPeers() {
+	if h.discovery == nil {
+		log.Println("❌ Обнаружение не запущено")
+		return
+	}
+
+	lanPeers := h.discovery.GetLANPeers()
+	if len(lanPeers) == 0 {
+		log.Println("📡 В локальной сети никого не видно")
+		return
+	}
+	log.Printf("📡 Рядом (%d):", len(lanPeers))
+	for i, lanPeer := range lanPeers {
+		log.Printf("  [%d] %s, отвечал %s назад", i+1, h.lanPeerName(lanPeer),
+			time.Since(lanPeer.LastSeen).Round(time.Second))
+	}
+	log.Println("   /drop <номер> <путь> - отправить файл")
+}
+
+// dropFile обрабатывает /drop <номер|peer> <путь>: отправляет файл
+// участнику локальной сети из списка /nearby
+func (h *Handler) dropFile(args []string) {
+	if len(args) < 2 {
+		log.Println("❌ Использование: /drop <номер|peer> <путь>")
+		return
+	}
+	if h.discovery == nil {
+		log.Println("❌ Обнаружение не запущено")
+		return
+	}
+
+	lanPeers := h.discovery.GetLANPeers()
+	var target *core.LANPeer
+	if i, err := strconv.Atoi(args[0]); err == nil {
+		if i >= 1 && i <= len(lanPeers) {
+			target = &lanPeers[i-1]
+		}
+	} else if id, err := peer.Decode(args[0]); err == nil {
+		for i := range lanPeers {
+			if lanPeers[i].AddrInfo.ID == id {
+				target = &lanPeers[i]
+			}
+		}
+	}
+	if target == nil {
+		log.Printf("❌ Участник %s не найден в локальной сети, см. /nearby", args[0])
+		return
+	}
+
+	path := strings.Join(args[1:], " ")
+	transferID, err := h.node.SendFileToNearbyPeer(*target, path)
+	if err != nil {
+		log.Printf("❌ %v", err)
+		return
+	}
+	log.Printf("📤 Файл %s поставлен в очередь для %s (передача #%d)",
+		filepath.Base(path), h.lanPeerName(*target), transferID)
+}
+
+// lanPeerName возвращает имя участника локальной сети: известных - как
+// обычно, остальных - по объявленному имени вместе с PeerID
+func (h *Handler) lanPeerName(lanPeer core.LANPeer) string {
+	id := lanPeer.AddrInfo.ID
+	if name := h.DisplayName(id); name != id.ShortString() || lanPeer.Nickname == "" {
+		return name
+	}
+	return fmt.Sprintf("%s (%s)", lanPeer.Nickname, id.ShortString())
+}
+
+// findPeer обрабатывает /find <peer>: ищет пира всеми стратегиями по очереди
+func (h *Handler) findPeer(args []string) {
+	if len(args) != 1 {
+		log.Println("❌ Использование: /find <peer>")
+		return
+	}
+	id, err := h.resolvePeer(args[0])
+	if err != nil {
+		log.Printf("❌ %v", err)
+		return
+	}
+
+	log.Printf("🔍 Ищем %s...", h.DisplayName(id))
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 3*time.Minute)
+		defer cancel()
+
+		result, err := core.NewPeerResolver(h.node, h.discovery).Resolve(ctx, id)
+		if result != nil {
+			for _, attempt := range result.Attempts {
+				if attempt.Err != nil {
+					log.Printf("   %s: %v (%s)", attempt.Strategy, attempt.Err, attempt.Duration.Round(time.Millisecond))
+				}
+			}
+		}
+		if err != nil {
+			log.Printf("❌ %s не найден: %v", h.DisplayName(id), err)
+			return
+		}
+		log.Printf("✅ %s найден через %s", h.DisplayName(id), result.Strategy)
+	}()
+}
@@ -0,0 +1,124 @@
+package tui
+
+import (
+	"context"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"OwlWhisper/internal/core"
+	"OwlWhisper/pkg/interfaces"
+)
+
+// sendContactRequest обрабатывает /request <peer> [текст]: просит незнакомого
+// пира добавить нас в контакты. Вычисление proof-of-work занимает время,
+// поэтому запрос отправляется в фоне
+func (h *Handler) sendContactRequest(args []string) {
+	if len(args) == 0 {
+		log.Println("❌ Использование: /request <peer> [текст]")
+		return
+	}
+
+	peerID, err := h.resolvePeer(args[0])
+	if err != nil {
+		log.Printf("❌ %v", err)
+		return
+	}
+	text := strings.Join(args[1:], " ")
+
+	log.Printf("📨 Отправляем запрос %s...", peerID.ShortString())
+	go func() {
+		if err := h.node.SendContactRequest(peerID, h.config.Profile.Nickname, text); err != nil {
+			log.Printf("❌ %v", err)
+			return
+		}
+		log.Printf("✅ Запрос доставлен %s", peerID.ShortString())
+	}()
+}
+
+// showContactRequests обрабатывает /requests
+func (h *Handler) showContactRequests() {
+	requests := h.node.ContactRequests()
+	if len(requests) == 0 {
+		log.Println("📨 Запросов нет")
+		return
+	}
+
+	log.Printf("📨 Запросы на добавление (%d):", len(requests))
+	for _, r := range requests {
+		log.Printf("  [%d] %s %s: %s", r.ID, r.Nickname, r.PeerID.ShortString(), r.Text)
+	}
+	log.Println("  /approve <id> [имя] - добавить, /deny <id|all> - отклонить")
+}
+
+// approveContactRequest обрабатывает /approve <id> [имя]: добавляет пира в контакты
+func (h *Handler) approveContactRequest(args []string) {
+	if len(args) == 0 {
+		log.Println("❌ Использование: /approve <id> [имя]")
+		return
+	}
+	id, err := strconv.ParseUint(args[0], 10, 64)
+	if err != nil {
+		log.Println("❌ Использование: /approve <id> [имя]")
+		return
+	}
+
+	request, err := h.node.AcceptContactRequest(id)
+	if err != nil {
+		log.Printf("❌ %v", err)
+		return
+	}
+
+	nickname := strings.Join(args[1:], " ")
+	if nickname == "" {
+		nickname = request.Nickname
+	}
+	if nickname == "" {
+		nickname = request.PeerID.ShortString()
+	}
+	contact := &interfaces.Contact{
+		PeerID:   request.PeerID.String(),
+		Nickname: nickname,
+		AddedAt:  time.Now(),
+		IsOnline: h.isConnected(request.PeerID),
+	}
+	if err := h.contacts.SaveContact(context.Background(), contact); err != nil {
+		log.Printf("❌ Не удалось сохранить контакт: %v", err)
+		return
+	}
+	log.Printf("✅ %s добавлен в контакты", nickname)
+}
+
+// denyContactRequests обрабатывает /deny <id...|all>
+func (h *Handler) denyContactRequests(args []string) {
+	if len(args) == 0 {
+		log.Println("❌ Использование: /deny <id...|all>")
+		return
+	}
+	if args[0] == "all" {
+		log.Printf("🗑️ Отклонено запросов: %d", h.node.RejectContactRequests())
+		return
+	}
+
+	var ids []uint64
+	for _, arg := range args {
+		id, err := strconv.ParseUint(arg, 10, 64)
+		if err != nil {
+			log.Printf("❌ Некорректный ID запроса: %s", arg)
+			return
+		}
+		ids = append(ids, id)
+	}
+	log.Printf("🗑️ Отклонено запросов: %d", h.node.RejectContactRequests(ids...))
+}
+
+// printContactRequest выводит новый запрос из карантина
+func (h *Handler) printContactRequest(request core.ContactRequest) {
+	name := request.Nickname
+	if name == "" {
+		name = request.PeerID.ShortString()
+	}
+	log.Printf("📨 %s просит добавить в контакты: %s", name, request.Text)
+	log.Printf("   /approve %d - добавить, /deny %d - отклонить", request.ID, request.ID)
+}
@@ -0,0 +1,159 @@
+package tui
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"OwlWhisper/internal/core"
+)
+
+// scheduleMessage обрабатывает /schedule <время> <текст>: отложенное
+// сообщение собеседнику открытого диалога
+func (h *Handler) scheduleMessage(args []string) {
+	if len(args) < 2 {
+		log.Println("❌ Использование: /schedule <ЧЧ:ММ|+30m> <текст>")
+		return
+	}
+
+	h.mu.Lock()
+	current := h.current
+	h.mu.Unlock()
+	if current == "" {
+		log.Println("❌ Сначала выберите собеседника: /chat <peer>")
+		return
+	}
+
+	sendAt, err := parseSendAt(args[0], time.Now())
+	if err != nil {
+		log.Printf("❌ %v", err)
+		return
+	}
+	id, err := h.node.ScheduleMessage(current, strings.Join(args[1:], " "), sendAt)
+	if err != nil {
+		log.Printf("❌ Не удалось запланировать сообщение: %v", err)
+		return
+	}
+	log.Printf("🕓 Сообщение для %s будет отправлено %s (id %s)",
+		h.DisplayName(current), sendAt.Format("02.01 15:04"), shortScheduledID(id))
+}
+
+// scheduleReminder обрабатывает /remind <время> <текст>
+func (h *Handler) scheduleReminder(args []string) {
+	if len(args) < 2 {
+		log.Println("❌ Использование: /remind <ЧЧ:ММ|+30m> <текст>")
+		return
+	}
+
+	sendAt, err := parseSendAt(args[0], time.Now())
+	if err != nil {
+		log.Printf("❌ %v", err)
+		return
+	}
+	id, err := h.node.ScheduleMessage(h.node.GetHost().ID(), strings.Join(args[1:], " "), sendAt)
+	if err != nil {
+		log.Printf("❌ Не удалось создать напоминание: %v", err)
+		return
+	}
+	log.Printf("⏰ Напоминание на %s (id %s)", sendAt.Format("02.01 15:04"), shortScheduledID(id))
+}
+
+// showScheduled обрабатывает /scheduled
+func (h *Handler) showScheduled() {
+	messages, err := h.node.ScheduledMessages()
+	if err != nil {
+		log.Printf("❌ %v", err)
+		return
+	}
+	if len(messages) == 0 {
+		log.Println("🕓 Отложенных сообщений нет")
+		return
+	}
+
+	self := h.node.GetHost().ID().String()
+	log.Printf("🕓 Отложенные сообщения (%d):", len(messages))
+	for _, msg := range messages {
+		to := "напоминание"
+		if msg.ToPeer != self {
+			to = "→ " + shortID(msg.ToPeer)
+		}
+		log.Printf("  [%s] %s %s: %s", shortScheduledID(msg.ID), msg.SendAt.Format("02.01 15:04"), to, msg.Content)
+	}
+}
+
+// cancelScheduled обрабатывает /unschedule <id>; достаточно начала ID
+func (h *Handler) cancelScheduled(args []string) {
+	if len(args) != 1 {
+		log.Println("❌ Использование: /unschedule <id>")
+		return
+	}
+
+	messages, err := h.node.ScheduledMessages()
+	if err != nil {
+		log.Printf("❌ %v", err)
+		return
+	}
+	var matches []string
+	for _, msg := range messages {
+		if strings.HasPrefix(msg.ID, args[0]) {
+			matches = append(matches, msg.ID)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		log.Printf("❌ Отложенное сообщение %s не найдено", args[0])
+	case 1:
+		if err := h.node.CancelScheduledMessage(matches[0]); err != nil {
+			log.Printf("❌ %v", err)
+			return
+		}
+		log.Printf("🗑️ Отложенное сообщение %s отменено", shortScheduledID(matches[0]))
+	default:
+		log.Printf("❌ Под %s подходят несколько сообщений, уточните", args[0])
+	}
+}
+
+// printScheduledDispatch выводит результат отправки отложенного сообщения
+func (h *Handler) printScheduledDispatch(dispatch core.ScheduledDispatch) {
+	switch {
+	case dispatch.Reminder:
+		fmt.Printf("⏰ Напоминание: %s\n", dispatch.Text)
+	case dispatch.Error != "":
+		log.Printf("⚠️ Отложенное сообщение для %s пока не отправлено: %s",
+			h.DisplayName(dispatch.PeerID), dispatch.Error)
+	default:
+		h.recordMessage(h.node.GetHost().ID(), dispatch.PeerID, dispatch.Text, true)
+		log.Printf("🕓 Отложенное сообщение отправлено %s", h.DisplayName(dispatch.PeerID))
+	}
+}
+
+// parseSendAt разбирает время отправки: "+30m"/"2h" от текущего момента
+// или "ЧЧ:ММ" - ближайшее такое время (сегодня или завтра)
+func parseSendAt(value string, now time.Time) (time.Time, error) {
+	if d, err := time.ParseDuration(strings.TrimPrefix(value, "+")); err == nil {
+		if d <= 0 {
+			return time.Time{}, fmt.Errorf("время отправки должно быть в будущем")
+		}
+		return now.Add(d), nil
+	}
+
+	clock, err := time.ParseInLocation("15:04", value, now.Location())
+	if err != nil {
+		return time.Time{}, fmt.Errorf("некорректное время %q, ожидается ЧЧ:ММ или +30m", value)
+	}
+	sendAt := time.Date(now.Year(), now.Month(), now.Day(), clock.Hour(), clock.Minute(), 0, 0, now.Location())
+	if !sendAt.After(now) {
+		sendAt = sendAt.AddDate(0, 0, 1)
+	}
+	return sendAt, nil
+}
+
+// shortScheduledID сокращает ID отложенного сообщения для вывода
+func shortScheduledID(id string) string {
+	if len(id) > 8 {
+		return id[:8]
+	}
+	return id
+}
@@ -0,0 +1,158 @@
+package tui
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"os"
+	"slices"
+	"strings"
+
+	"OwlWhisper/internal/core"
+)
+
+// handleServerCard обрабатывает /servercard [export <файл>|import <файл>]:
+// без аргументов выводит карточку своего сервера
+func (h *Handler) handleServerCard(args []string) {
+	switch {
+	case len(args) == 0:
+		if card, ok := h.createServerCard(); ok {
+			log.Printf("🪪 Карточка сервера:\n%s", card.Encode())
+		}
+	case len(args) == 2 && args[0] == "export":
+		card, ok := h.createServerCard()
+		if !ok {
+			return
+		}
+		if err := os.WriteFile(args[1], card.Encode(), 0644); err != nil {
+			log.Printf("❌ Не удалось сохранить карточку: %v", err)
+			return
+		}
+		log.Printf("✅ Карточка сервера сохранена: %s", args[1])
+	case len(args) == 2 && args[0] == "import":
+		h.importServerCard(args[1])
+	default:
+		log.Println("❌ Использование: /servercard [export <файл>|import <файл>]")
+	}
+}
+
+// createServerCard подписывает карточку своего узла с ролями из настроек
+func (h *Handler) createServerCard() (core.ServerCard, bool) {
+	if len(h.config.Daemon.ServerRoles) == 0 {
+		log.Println("❌ Укажите роли сервера в daemon.server_roles (relay, bootstrap)")
+		return core.ServerCard{}, false
+	}
+	card, err := h.node.CreateServerCard(h.config.Daemon.ServerRoles, h.config.Daemon.Operator)
+	if errors.Is(err, core.ErrNoPublicAddrs) {
+		log.Println("❌ У узла пока нет публичных адресов: проверьте проброс порта или подождите определения адреса")
+		return core.ServerCard{}, false
+	}
+	if errors.Is(err, core.ErrRelayServiceDisabled) {
+		log.Println("❌ Роль relay требует network.enable_relay; служба ретрансляции включается после перезапуска")
+		return core.ServerCard{}, false
+	}
+	if err != nil {
+		log.Printf("❌ %v", err)
+		return core.ServerCard{}, false
+	}
+	return card, true
+}
+
+// importServerCard проверяет карточку из файла и добавляет сервер в списки
+// ретрансляторов и bootstrap-узлов согласно его ролям
+func (h *Handler) importServerCard(path string) {
+	file, err := os.Open(path)
+	if err != nil {
+		log.Printf("❌ %v", err)
+		return
+	}
+	data, err := io.ReadAll(io.LimitReader(file, core.ServerCardLimit+1))
+	file.Close()
+	if err != nil {
+		log.Printf("❌ %v", err)
+		return
+	}
+	card, err := core.DecodeServerCard(data)
+	if err != nil {
+		log.Printf("❌ %v", err)
+		return
+	}
+
+	relays := len(h.config.Network.RelayNodes)
+	bootstrap := len(h.config.Network.BootstrapNodes)
+	for _, addr := range card.P2pAddrs() {
+		if card.HasRole(core.ServerRoleRelay) && !slices.Contains(h.config.Network.RelayNodes, addr) {
+			h.config.Network.RelayNodes = append(h.config.Network.RelayNodes, addr)
+		}
+		if card.HasRole(core.ServerRoleBootstrap) && !slices.Contains(h.config.Network.BootstrapNodes, addr) {
+			h.config.Network.BootstrapNodes = append(h.config.Network.BootstrapNodes, addr)
+		}
+	}
+	relays = len(h.config.Network.RelayNodes) - relays
+	bootstrap = len(h.config.Network.BootstrapNodes) - bootstrap
+	if relays == 0 && bootstrap == 0 {
+		log.Printf("ℹ️ Сервер %s уже есть в настройках", card.PeerID.ShortString())
+		return
+	}
+	if err := h.config.SaveConfig(""); err != nil {
+		log.Printf("❌ Не удалось сохранить настройки: %v", err)
+		return
+	}
+
+	log.Printf("✅ Сервер %s (%s) добавлен: ретрансляторов +%d, bootstrap-узлов +%d",
+		card.PeerID.ShortString(), strings.Join(card.Roles, ", "), relays, bootstrap)
+	if fingerprint, err := core.Fingerprint(card.PeerID); err == nil {
+		log.Printf("   отпечаток: %s", fingerprint)
+	}
+	if card.Operator != "" {
+		log.Printf("   оператор: %s", card.Operator)
+	}
+	log.Println("   Изменения вступят в силу после перезапуска")
+}
+
+// handleAdmin обрабатывает /admin <узел> <stats|rotate-logs|ban <peer>|unban <peer>|reload>:
+// управляет своим сервером, где мы указаны в daemon.admin_peers
+func (h *Handler) handleAdmin(args []string) {
+	if len(args) < 2 {
+		log.Println("❌ Использование: /admin <узел> <stats|rotate-logs|ban <peer>|unban <peer>|reload>")
+		return
+	}
+	server, err := h.resolvePeer(args[0])
+	if err != nil {
+		log.Printf("❌ %v", err)
+		return
+	}
+	command, commandArgs := args[1], args[2:]
+	if command == core.AdminBan || command == core.AdminUnban {
+		if len(commandArgs) != 1 {
+			log.Printf("❌ Использование: /admin <узел> %s <peer>", command)
+			return
+		}
+		target, err := h.resolvePeer(commandArgs[0])
+		if err != nil {
+			log.Printf("❌ %v", err)
+			return
+		}
+		commandArgs = []string{target.String()}
+	}
+
+	go func() {
+		result, err := h.node.SendAdminCommand(context.Background(), server, command, commandArgs...)
+		if err != nil {
+			log.Printf("❌ %s: %v", h.DisplayName(server), err)
+			return
+		}
+		if len(result) == 0 {
+			log.Printf("✅ %s: %s выполнено", h.DisplayName(server), command)
+			return
+		}
+		var pretty bytes.Buffer
+		if json.Indent(&pretty, result, "", "  ") != nil {
+			pretty.Write(result)
+		}
+		log.Printf("🛠️ %s: %s\n%s", h.DisplayName(server), command, pretty.String())
+	}()
+}
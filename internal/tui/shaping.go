@@ -0,0 +1,149 @@
+package tui
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"OwlWhisper/internal/core"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+// shapeConversation обрабатывает /shaping [pad on|off|cover <интервал>|off]:
+// защита открытого диалога от анализа трафика; без аргументов - текущее
+// состояние и сколько трафика она стоила
+func (h *Handler) shapeConversation(args []string) {
+	prefs, ok := h.currentPrefs()
+	if !ok {
+		return
+	}
+	id, err := peer.Decode(prefs.PeerID)
+	if err != nil {
+		log.Printf("❌ %v", err)
+		return
+	}
+
+	if len(args) == 0 {
+		h.printShaping(id)
+		return
+	}
+	if len(args) != 2 {
+		log.Println("❌ Использование: /shaping [pad on|off|cover <30s|off>]")
+		return
+	}
+
+	switch args[0] {
+	case "pad":
+		switch args[1] {
+		case "on":
+			prefs.Padding = true
+		case "off":
+			prefs.Padding = false
+		default:
+			log.Println("❌ Использование: /shaping pad on|off")
+			return
+		}
+	case "cover":
+		if args[1] == "off" {
+			prefs.CoverTraffic = 0
+			break
+		}
+		d, err := time.ParseDuration(args[1])
+		if err != nil || d <= 0 {
+			log.Println("❌ Использование: /shaping cover <30s|off>")
+			return
+		}
+		prefs.CoverTraffic = d
+	default:
+		log.Println("❌ Использование: /shaping [pad on|off|cover <30s|off>]")
+		return
+	}
+
+	shaping := core.TrafficShaping{Padding: prefs.Padding, CoverInterval: prefs.CoverTraffic}
+	if err := h.node.SetTrafficShaping(id, shaping); err != nil {
+		log.Printf("❌ %v", err)
+		return
+	}
+	if err := h.prefs.SavePrefs(context.Background(), prefs); err != nil {
+		log.Printf("❌ Не удалось сохранить настройки диалога: %v", err)
+		return
+	}
+	h.printShaping(id)
+}
+
+// printShaping выводит защиту трафика диалога и ее стоимость
+func (h *Handler) printShaping(id peer.ID) {
+	shaping := h.node.TrafficShaping(id)
+	padding := "выключено"
+	if shaping.Padding {
+		padding = "включено"
+	}
+	cover := "выключены"
+	if shaping.CoverInterval > 0 {
+		cover = "в среднем раз в " + shaping.CoverInterval.String()
+	}
+	log.Printf("🧱 Дополнение сообщений: %s, фиктивные сообщения: %s", padding, cover)
+
+	stats := h.node.ShapingStats(id)
+	if stats.PayloadBytes == 0 && stats.CoverMessages == 0 {
+		return
+	}
+	log.Printf("   За сессию: полезных %s, дополнение %s, фиктивных сообщений %d (%s)",
+		formatBytes(stats.PayloadBytes), formatBytes(stats.PaddingBytes),
+		stats.CoverMessages, formatBytes(stats.CoverBytes))
+	if stats.PayloadBytes > 0 {
+		log.Printf("   Лишний трафик: +%.0f%% к полезному", stats.Overhead()*100)
+	}
+}
+
+// sealConversation обрабатывает /sealed [on|off]: скрытый отправитель в
+// открытом диалоге; без аргументов - текущее состояние
+func (h *Handler) sealConversation(args []string) {
+	prefs, ok := h.currentPrefs()
+	if !ok {
+		return
+	}
+	id, err := peer.Decode(prefs.PeerID)
+	if err != nil {
+		log.Printf("❌ %v", err)
+		return
+	}
+
+	if len(args) == 0 {
+		h.printSealed(id)
+		return
+	}
+	switch args[0] {
+	case "on":
+		if err := h.node.SealedSenderReady(id); err != nil {
+			log.Printf("❌ %v", err)
+			return
+		}
+		prefs.SealedSender = true
+	case "off":
+		prefs.SealedSender = false
+	default:
+		log.Println("❌ Использование: /sealed [on|off]")
+		return
+	}
+
+	h.node.SetSealedSender(id, prefs.SealedSender)
+	if err := h.prefs.SavePrefs(context.Background(), prefs); err != nil {
+		log.Printf("❌ Не удалось сохранить настройки диалога: %v", err)
+		return
+	}
+	h.printSealed(id)
+}
+
+// printSealed выводит состояние скрытого отправителя диалога
+func (h *Handler) printSealed(id peer.ID) {
+	if !h.node.SealedSender(id) {
+		log.Println("🕶️ Скрытый отправитель: выключен")
+		return
+	}
+	log.Println("🕶️ Скрытый отправитель: включен, сообщения уходят с одноразового PeerID")
+	if err := h.node.SealedSenderReady(id); err != nil {
+		log.Printf("⚠️ Сейчас отправка не удастся: %v", err)
+	}
+}
@@ -0,0 +1,113 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"OwlWhisper/internal/storage"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+// showStorageUsage обрабатывает /storage: место, занятое диалогами
+func (h *Handler) showStorageUsage() {
+	usage, err := h.messages.GetStorageUsage(context.Background(), h.node.GetHost().ID().String())
+	if err != nil {
+		log.Printf("❌ %v", err)
+		return
+	}
+
+	log.Printf("💾 История: %s (файл %s), вложения: %s",
+		formatBytes(usage.MessageBytes), formatBytes(usage.HistoryFileBytes), formatBytes(usage.MediaBytes))
+	for _, conversation := range usage.Conversations {
+		name := shortID(conversation.PeerID)
+		if id, err := peer.Decode(conversation.PeerID); err == nil {
+			name = h.DisplayName(id)
+		}
+		log.Printf("   %s: %d сообщ. (%s), %d файлов (%s)", name,
+			conversation.Messages, formatBytes(conversation.MessageBytes),
+			conversation.MediaFiles, formatBytes(conversation.MediaBytes))
+	}
+}
+
+// cleanupStorage обрабатывает /cleanup media <дней>, /cleanup chat [media],
+// /cleanup vacuum и /cleanup gc
+func (h *Handler) cleanupStorage(args []string) {
+	if h.cleaner == nil {
+		log.Println("❌ Очистка недоступна")
+		return
+	}
+	if len(args) == 0 {
+		log.Println("❌ Использование: /cleanup <media <дней>|chat [media]|vacuum|gc>")
+		return
+	}
+
+	var opts storage.CleanupOptions
+	switch args[0] {
+	case "media":
+		days, err := strconv.Atoi(argAt(args, 1))
+		if err != nil || days < 0 {
+			log.Println("❌ Использование: /cleanup media <дней>")
+			return
+		}
+		opts = storage.CleanupOptions{
+			Operation: storage.CleanupMedia,
+			Before:    time.Now().AddDate(0, 0, -days),
+		}
+		log.Printf("🧹 Удаляю вложения старше %d дней...", days)
+
+	case "chat":
+		h.mu.Lock()
+		current := h.current
+		h.mu.Unlock()
+		if current == "" {
+			log.Println("❌ Сначала выберите собеседника: /chat <peer>")
+			return
+		}
+		opts = storage.CleanupOptions{
+			Operation:   storage.CleanupConversation,
+			PeerID:      current.String(),
+			RemoveMedia: argAt(args, 1) == "media",
+		}
+		log.Printf("🧹 Очищаю диалог с %s...", h.DisplayName(current))
+
+	case "vacuum":
+		opts = storage.CleanupOptions{Operation: storage.CleanupVacuum}
+		log.Println("🧹 Сжимаю файл истории...")
+
+	case "gc":
+		opts = storage.CleanupOptions{Operation: storage.CleanupMediaGC}
+		log.Println("🧹 Удаляю вложения, на которые не ссылаются сообщения...")
+
+	default:
+		log.Println("❌ Использование: /cleanup <media <дней>|chat [media]|vacuum|gc>")
+		return
+	}
+
+	h.cleaner(opts)
+}
+
+// argAt возвращает аргумент по индексу или пустую строку
+func argAt(args []string, i int) string {
+	if i < len(args) {
+		return args[i]
+	}
+	return ""
+}
+
+// formatBytes переводит размер в байтах в читаемый вид
+func formatBytes(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d Б", size)
+	}
+	value, exp := float64(size)/unit, 0
+	for value >= unit && exp < 3 {
+		value /= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cБ", value, []rune("КМГТ")[exp])
+}
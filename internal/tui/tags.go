@@ -0,0 +1,62 @@
+package tui
+
+import (
+	"log"
+	"strings"
+)
+
+// tagPeer обрабатывает /tag <peer> <метка>
+func (h *Handler) tagPeer(args []string) {
+	if len(args) != 2 {
+		log.Println("❌ Использование: /tag <peer> <метка>")
+		return
+	}
+
+	id, err := h.resolvePeer(args[0])
+	if err != nil {
+		log.Printf("❌ %v", err)
+		return
+	}
+	if err := h.node.SetPeerTag(id, args[1]); err != nil {
+		log.Printf("❌ %v", err)
+		return
+	}
+	log.Printf("🏷️ %s: %s", h.DisplayName(id), strings.Join(h.node.GetPeerTags(id), ", "))
+}
+
+// untagPeer обрабатывает /untag <peer> <метка>
+func (h *Handler) untagPeer(args []string) {
+	if len(args) != 2 {
+		log.Println("❌ Использование: /untag <peer> <метка>")
+		return
+	}
+
+	id, err := h.resolvePeer(args[0])
+	if err != nil {
+		log.Printf("❌ %v", err)
+		return
+	}
+	if err := h.node.RemovePeerTag(id, args[1]); err != nil {
+		log.Printf("❌ %v", err)
+		return
+	}
+	log.Printf("🏷️ Метка %s снята с %s", args[1], h.DisplayName(id))
+}
+
+// showTagged обрабатывает /tagged <метка>
+func (h *Handler) showTagged(args []string) {
+	if len(args) != 1 {
+		log.Println("❌ Использование: /tagged <метка>")
+		return
+	}
+
+	peers := h.node.GetPeersByTag(args[0])
+	if len(peers) == 0 {
+		log.Printf("🏷️ Нет пиров с меткой %s", args[0])
+		return
+	}
+	log.Printf("🏷️ С меткой %s (%d):", args[0], len(peers))
+	for _, id := range peers {
+		log.Printf("  %s", h.DisplayName(id))
+	}
+}
@@ -0,0 +1,154 @@
+package tui
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	"OwlWhisper/internal/core"
+	"OwlWhisper/pkg/config"
+)
+
+// wizard задает вопросы первичной настройки через построчный ввод
+type wizard struct {
+	scanner *bufio.Scanner
+}
+
+// RunSetupWizard проводит первичную настройку: имя, ключ личности и сеть.
+// Ключ сохраняется в identityPath, конфигурация - в файл по умолчанию
+func RunSetupWizard(in io.Reader, cfg *config.Config, identityPath string) error {
+	w := &wizard{scanner: bufio.NewScanner(in)}
+
+	log.Println("🦉 Первый запуск Owl Whisper - давайте все настроим")
+	log.Println("   (Enter - оставить значение по умолчанию)")
+	log.Println()
+
+	cfg.Profile.Nickname = w.ask("Как вас называть?", cfg.Profile.Nickname)
+
+	if err := w.setupIdentity(identityPath); err != nil {
+		return err
+	}
+
+	log.Println()
+	log.Println("🌐 Настройки сети")
+	cfg.Network.ListenPort = w.askInt("Порт для входящих соединений (0 - автоматически)", cfg.Network.ListenPort)
+	cfg.Network.EnableNAT = w.askBool("Определять внешний адрес и работать через NAT?", cfg.Network.EnableNAT)
+	cfg.Network.EnableHolePunch = w.askBool("Пробивать NAT для прямых соединений?", cfg.Network.EnableHolePunch)
+	cfg.Network.EnableRelay = w.askBool("Использовать ретрансляторы, если прямое соединение невозможно?", cfg.Network.EnableRelay)
+
+	if err := cfg.SaveConfig(""); err != nil {
+		return fmt.Errorf("не удалось сохранить конфигурацию: %w", err)
+	}
+	log.Printf("✅ Настройки сохранены в %s", config.DefaultPath())
+	log.Println()
+	return nil
+}
+
+// setupIdentity создает новый ключ личности или импортирует существующий
+func (w *wizard) setupIdentity(identityPath string) error {
+	log.Println()
+	log.Println("🔑 Ключ личности определяет ваш PeerID - по нему вас находят контакты")
+
+	if priv, err := core.LoadIdentity(identityPath); err == nil {
+		id, _ := core.IdentityPeerID(priv)
+		if w.askBool(fmt.Sprintf("Найден ключ с PeerID %s. Использовать его?", id), true) {
+			return nil
+		}
+	}
+
+	for {
+		importPath := w.ask("Путь к существующему ключу для импорта (пусто - создать новый)", "")
+		if importPath == "" {
+			break
+		}
+
+		priv, err := core.LoadIdentity(importPath)
+		if err != nil {
+			log.Printf("❌ %v", err)
+			continue
+		}
+		if err := core.SaveIdentity(identityPath, priv); err != nil {
+			return err
+		}
+		id, _ := core.IdentityPeerID(priv)
+		log.Printf("✅ Ключ импортирован, ваш PeerID: %s", id)
+		return nil
+	}
+
+	priv, err := core.GenerateIdentity()
+	if err != nil {
+		return err
+	}
+	if err := core.SaveIdentity(identityPath, priv); err != nil {
+		return err
+	}
+	id, _ := core.IdentityPeerID(priv)
+	log.Printf("✅ Создан новый ключ, ваш PeerID: %s", id)
+	log.Printf("   Сохраните копию %s - без него PeerID не восстановить", identityPath)
+	return nil
+}
+
+// ask задает вопрос и возвращает ответ или значение по умолчанию
+func (w *wizard) ask(question, def string) string {
+	if def != "" {
+		fmt.Printf("%s [%s]: ", question, def)
+	} else {
+		fmt.Printf("%s: ", question)
+	}
+
+	if !w.scanner.Scan() {
+		fmt.Println()
+		return def
+	}
+	answer := strings.TrimSpace(w.scanner.Text())
+	if answer == "" {
+		return def
+	}
+	return answer
+}
+
+// askBool задает вопрос да/нет
+func (w *wizard) askBool(question string, def bool) bool {
+	hint := "y/N"
+	if def {
+		hint = "Y/n"
+	}
+
+	for {
+		answer := strings.ToLower(w.ask(fmt.Sprintf("%s (%s)", question, hint), ""))
+		switch answer {
+		case "":
+			return def
+		case "y", "yes", "д", "да":
+			return true
+		case "n", "no", "н", "нет":
+			return false
+		}
+		log.Println("❌ Ответьте y или n")
+	}
+}
+
+// askInt задает вопрос с числовым ответом
+func (w *wizard) askInt(question string, def int) int {
+	for {
+		answer := w.ask(question, strconv.Itoa(def))
+		value, err := strconv.Atoi(answer)
+		if err == nil && value >= 0 && value <= 65535 {
+			return value
+		}
+		log.Println("❌ Введите число от 0 до 65535")
+	}
+}
+
+// IsInteractive проверяет, подключен ли стандартный ввод к терминалу
+func IsInteractive() bool {
+	info, err := os.Stdin.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,209 @@
+package updater
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// manifestLimit - максимальный размер манифеста релиза
+	manifestLimit = 1 << 20
+	// requestTimeout - предельное время загрузки манифеста
+	requestTimeout = 30 * time.Second
+	// minInterval - не проверяем обновления чаще этого
+	minInterval = time.Hour
+)
+
+// ErrBadSignature - подпись манифеста не прошла проверку
+var ErrBadSignature = errors.New("подпись манифеста обновления недействительна")
+
+// Release - описание релиза из манифеста
+type Release struct {
+	Version   string    `json:"version"`
+	Published time.Time `json:"published"`
+	URL       string    `json:"url"`
+	Notes     string    `json:"notes"`
+}
+
+// signedManifest - манифест и подпись Ed25519 его точных байтов
+type signedManifest struct {
+	Manifest  json.RawMessage `json:"manifest"`
+	Signature string          `json:"signature"`
+}
+
+// Checker периодически проверяет наличие новой версии. Он только сообщает
+// об обновлении: установку должен подтвердить и выполнить встраивающий код
+type Checker struct {
+	manifestURL string
+	publicKey   ed25519.PublicKey
+	current     string
+	client      *http.Client
+}
+
+// NewChecker создает проверку обновлений. Манифест загружается только по HTTPS,
+// publicKey - открытый ключ Ed25519 издателя в base64
+func NewChecker(manifestURL, publicKey, current string) (*Checker, error) {
+	parsed, err := url.Parse(manifestURL)
+	if err != nil || parsed.Scheme != "https" || parsed.Host == "" {
+		return nil, fmt.Errorf("адрес манифеста должен быть https URL: %s", manifestURL)
+	}
+
+	key, err := base64.StdEncoding.DecodeString(publicKey)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("некорректный открытый ключ издателя")
+	}
+
+	return &Checker{
+		manifestURL: manifestURL,
+		publicKey:   ed25519.PublicKey(key),
+		current:     current,
+		client:      &http.Client{Timeout: requestTimeout},
+	}, nil
+}
+
+// Check загружает манифест, проверяет подпись и возвращает релиз,
+// если он новее текущей версии; иначе nil
+func (c *Checker) Check(ctx context.Context) (*Release, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.manifestURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось загрузить манифест: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("сервер обновлений ответил %s", resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, manifestLimit))
+	if err != nil {
+		return nil, fmt.Errorf("не удалось загрузить манифест: %w", err)
+	}
+
+	release, err := c.verify(data)
+	if err != nil {
+		return nil, err
+	}
+	if CompareVersions(release.Version, c.current) <= 0 {
+		return nil, nil
+	}
+	return release, nil
+}
+
+// Run проверяет обновления с интервалом interval до отмены контекста и
+// вызывает onUpdate для каждой новой найденной версии
+func (c *Checker) Run(ctx context.Context, interval time.Duration, onUpdate func(Release)) {
+	if interval < minInterval {
+		interval = minInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	announced := ""
+	for {
+		release, err := c.Check(ctx)
+		if err != nil {
+			log.Printf("⚠️ Проверка обновлений: %v", err)
+		} else if release != nil && release.Version != announced {
+			announced = release.Version
+			onUpdate(*release)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// verify проверяет подпись манифеста и разбирает описание релиза
+func (c *Checker) verify(data []byte) (*Release, error) {
+	var signed signedManifest
+	if err := json.Unmarshal(data, &signed); err != nil {
+		return nil, fmt.Errorf("некорректный манифест обновления: %w", err)
+	}
+	signature, err := base64.StdEncoding.DecodeString(signed.Signature)
+	if err != nil || !ed25519.Verify(c.publicKey, signed.Manifest, signature) {
+		return nil, ErrBadSignature
+	}
+
+	var release Release
+	if err := json.Unmarshal(signed.Manifest, &release); err != nil {
+		return nil, fmt.Errorf("некорректный манифест обновления: %w", err)
+	}
+	if release.Version == "" {
+		return nil, fmt.Errorf("в манифесте не указана версия")
+	}
+	return &release, nil
+}
+
+// CompareVersions сравнивает версии вида 1.2.3 (префикс "v" допускается).
+// Предварительная версия (1.2.3-rc1) младше релиза с теми же номерами
+func CompareVersions(a, b string) int {
+	aNums, aPre := splitVersion(a)
+	bNums, bPre := splitVersion(b)
+
+	for i := 0; i < len(aNums) || i < len(bNums); i++ {
+		var x, y int
+		if i < len(aNums) {
+			x = aNums[i]
+		}
+		if i < len(bNums) {
+			y = bNums[i]
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+
+	switch {
+	case aPre == bPre:
+		return 0
+	case aPre == "":
+		return 1
+	case bPre == "":
+		return -1
+	case aPre < bPre:
+		return -1
+	default:
+		return 1
+	}
+}
+
+// splitVersion разбирает версию на номера и суффикс предварительной версии
+func splitVersion(version string) ([]int, string) {
+	version = strings.TrimPrefix(strings.TrimSpace(version), "v")
+	pre := ""
+	if i := strings.IndexAny(version, "-+"); i >= 0 {
+		pre = version[i+1:]
+		version = version[:i]
+	}
+
+	var nums []int
+	for _, part := range strings.Split(version, ".") {
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			n = 0
+		}
+		nums = append(nums, n)
+	}
+	return nums, pre
+}
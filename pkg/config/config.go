@@ -2,21 +2,56 @@ package config
 
 import (
 	"encoding/json"
+	"fmt"
+	"net"
 	"os"
 	"path/filepath"
+	"slices"
+	"time"
+	"unicode/utf8"
+
+	"OwlWhisper/internal/core"
 )
 
 // Config представляет конфигурацию приложения
 type Config struct {
+	// Профиль пользователя
+	Profile struct {
+		Nickname string `json:"nickname"`
+		Status   string `json:"status,omitempty"` // статус для контактов ("в отпуске")
+	} `json:"profile"`
+
 	// Сетевые настройки
 	Network struct {
 		ListenPort      int      `json:"listen_port"`
 		BootstrapNodes  []string `json:"bootstrap_nodes"`
 		RelayNodes      []string `json:"relay_nodes"`
+		PinnedRelays    []string `json:"pinned_relays"` // пробуются первыми (например, свой VPS)
 		STUNServers     []string `json:"stun_servers"`
 		EnableRelay     bool     `json:"enable_relay"`
 		EnableNAT       bool     `json:"enable_nat"`
 		EnableHolePunch bool     `json:"enable_hole_punch"`
+		// PreferTransport - "quic", "tcp" или пусто (порядок libp2p по умолчанию)
+		PreferTransport string `json:"prefer_transport"`
+		// RelayMode - "", "direct" или "relay"; контакты могут переопределять
+		RelayMode string `json:"relay_mode"`
+		// DHTHelper - адрес доверенного помощника (с /p2p/<PeerID>), которому
+		// делегируется поиск в DHT; пусто - узел сам участвует в DHT
+		DHTHelper string `json:"dht_helper"`
+		// DHTHelperClients - PeerID клиентов, для которых этот узел сам работает помощником
+		DHTHelperClients []string `json:"dht_helper_clients"`
+		// Obfuscation - маскировка соединений под HTTPS для отдельных
+		// ретрансляторов, bootstrap-узлов и помощника DHT по их PeerID
+		Obfuscation map[string]Obfuscation `json:"obfuscation,omitempty"`
+		// WebSocketPort - порт WebSocket для обратного прокси с TLS, через
+		// который к этому узлу подключаются клиенты с маскировкой; 0 - выключен
+		WebSocketPort int `json:"websocket_port,omitempty"`
+		// PeerCacheSize - сколько пиров помнить в кэше адресов; 0 - по умолчанию
+		PeerCacheSize int `json:"peer_cache_size,omitempty"`
+		// Multipath - служебные сообщения по самому быстрому из соединений
+		Multipath bool `json:"multipath"`
+		// BannedPeers - PeerID, соединения с которыми запрещены
+		BannedPeers []string `json:"banned_peers,omitempty"`
 	} `json:"network"`
 
 	// Настройки чата
@@ -24,8 +59,27 @@ type Config struct {
 		MaxMessageLength int  `json:"max_message_length"`
 		MessageHistory   int  `json:"message_history"`
 		AutoSave         bool `json:"auto_save"`
+		// Prewarm - заранее соединяться с собеседником при открытии диалога
+		Prewarm bool `json:"prewarm"`
+		// PrewarmRecent - со сколькими последними собеседниками соединяться при запуске
+		PrewarmRecent int `json:"prewarm_recent"`
 	} `json:"chat"`
 
+	// Настройки передачи файлов
+	Transfers struct {
+		DownloadDir          string   `json:"download_dir"`
+		AutoAccept           bool     `json:"auto_accept"`
+		AutoAcceptMaxSizeMB  int64    `json:"auto_accept_max_size_mb"`
+		AutoAcceptPeers      []string `json:"auto_accept_peers"`
+		AutoAcceptExtensions []string `json:"auto_accept_extensions"`
+		// MaxIncomingSizeMB - файлы больше отклоняются без вопроса (0 - без ограничения)
+		MaxIncomingSizeMB int64 `json:"max_incoming_size_mb"`
+		// BlockedExtensions - расширения, которые отклоняются без вопроса
+		BlockedExtensions []string `json:"blocked_extensions"`
+		// WarnedExtensions - расширения, о которых дополнительно предупреждать
+		WarnedExtensions []string `json:"warned_extensions"`
+	} `json:"transfers"`
+
 	// Настройки безопасности
 	Security struct {
 		EnableTLS     bool   `json:"enable_tls"`
@@ -33,6 +87,80 @@ type Config struct {
 		MinTLSVersion string `json:"min_tls_version"`
 	} `json:"security"`
 
+	// Настройки приватности
+	Privacy struct {
+		LastSeen string `json:"last_seen"` // "everyone", "contacts" или "nobody"
+
+		// HideIP - соединяться с пирами только через ретрансляторы
+		HideIP bool `json:"hide_ip"`
+
+		// UserAgent - строка клиента для identify; пусто - OwlWhisper/<версия>
+		UserAgent string `json:"user_agent"`
+		// Stealth - не объявлять протоколы OwlWhisper и открывать их только контактам
+		Stealth bool `json:"stealth"`
+		// LANNickname - показывать свое имя участникам локальной сети (mDNS)
+		LANNickname bool `json:"lan_nickname"`
+
+		// Защита от запросов незнакомых пиров
+		ContactRequestDifficulty int `json:"contact_request_difficulty"` // бит proof-of-work
+		ContactRequestsPerPeer   int `json:"contact_requests_per_peer"`  // в час
+		ContactRequestsPerHour   int `json:"contact_requests_per_hour"`
+	} `json:"privacy"`
+
+	// Проверка обновлений (выключена по умолчанию)
+	Updates struct {
+		Enabled            bool   `json:"enabled"`
+		ManifestURL        string `json:"manifest_url"`
+		PublicKey          string `json:"public_key"` // Ed25519 ключ издателя в base64
+		CheckIntervalHours int    `json:"check_interval_hours"`
+	} `json:"updates"`
+
+	// Зашифрованные резервные копии (выключены по умолчанию)
+	Backups struct {
+		Enabled bool `json:"enabled"`
+		// Destination - директория, https://... (WebDAV) или
+		// s3://bucket/префикс?endpoint=https://...
+		Destination string `json:"destination"`
+		// Passphrase - пароль шифрования; если пусто, берется из переменной
+		// окружения OWLWHISPER_BACKUP_PASSPHRASE
+		Passphrase    string `json:"passphrase"`
+		Username      string `json:"username"` // WebDAV
+		Password      string `json:"password"` // WebDAV
+		S3AccessKey   string `json:"s3_access_key"`
+		S3SecretKey   string `json:"s3_secret_key"`
+		S3Region      string `json:"s3_region"`
+		IntervalHours int    `json:"interval_hours"`
+		Keep          int    `json:"keep"` // сколько последних копий хранить
+	} `json:"backups"`
+
+	// Работа без терминала (контейнеры, systemd)
+	Daemon struct {
+		// HealthListen - адрес HTTP для /healthz и /readyz ("127.0.0.1:8089");
+		// пусто - выключено (если systemd не передал сокет)
+		HealthListen string `json:"health_listen"`
+		// ServerRoles - роли узла в карточке сервера: "relay", "bootstrap".
+		// Роль relay включает службу ретрансляции узла
+		ServerRoles []string `json:"server_roles,omitempty"`
+		// Operator - контакт оператора сервера для карточки (почта, сайт)
+		Operator string `json:"operator,omitempty"`
+		// AdminPeers - PeerID клиентов, которым разрешено удаленное управление
+		// узлом (/admin); пусто - управление выключено
+		AdminPeers []string `json:"admin_peers,omitempty"`
+	} `json:"daemon"`
+
+	// Почтовые ящики для писем, пока получатель не в сети
+	Mailbox struct {
+		// Mailboxes - адреса своих почтовых ящиков (/ip4/.../p2p/<PeerID>);
+		// контакты оставляют в них письма, пока мы не в сети
+		Mailboxes []string `json:"mailboxes,omitempty"`
+		// ServeFor - PeerID владельцев, для которых узел сам служит почтовым
+		// ящиком; пусто - письма не принимаются
+		ServeFor []string `json:"serve_for,omitempty"`
+		// RetentionHours - сколько часов ящик хранит письма, которые владелец
+		// не забрал
+		RetentionHours int `json:"retention_hours"`
+	} `json:"mailbox"`
+
 	// Настройки логирования
 	Logging struct {
 		Level      string `json:"level"`
@@ -40,6 +168,14 @@ type Config struct {
 		Console    bool   `json:"console"`
 	} `json:"logging"`
 
+	// Настройки уведомлений
+	Notifications struct {
+		Enabled      bool   `json:"enabled"`
+		DoNotDisturb bool   `json:"do_not_disturb"`
+		QuietFrom    string `json:"quiet_from"` // "22:00"; пусто - без расписания
+		QuietTo      string `json:"quiet_to"`
+	} `json:"notifications"`
+
 	// Настройки UI
 	UI struct {
 		Theme          string `json:"theme"`
@@ -48,12 +184,20 @@ type Config struct {
 	} `json:"ui"`
 }
 
+// Obfuscation - подключение к конечной точке через WebSocket поверх TLS
+type Obfuscation struct {
+	Host string `json:"host"`           // домен или IP для подключения
+	Port int    `json:"port,omitempty"` // порт TLS, по умолчанию 443
+	SNI  string `json:"sni,omitempty"`  // имя в TLS SNI, по умолчанию host
+}
+
 // DefaultConfig возвращает конфигурацию по умолчанию
 func DefaultConfig() *Config {
 	config := &Config{}
 
 	// Сетевые настройки по умолчанию
 	config.Network.ListenPort = 0 // 0 означает автоматический выбор порта
+	config.Network.Multipath = true
 	config.Network.BootstrapNodes = []string{
 		"/dnsaddr/bootstrap.libp2p.io/p2p/QmNnooDu7bfjPFoTZYxMNLWUQJyrVwtbZg5gBMjTezGAJN",
 		"/dnsaddr/bootstrap.libp2p.io/p2p/QmQCU2EcMqAqQPR2i9bChDtGNJchTbq5TbXJJ16u19uLTa",
@@ -63,6 +207,7 @@ func DefaultConfig() *Config {
 		"/dnsaddr/relay.libp2p.io/p2p/QmNnooDu7bfjPFoTZYxMNLWUQJyrVwtbZg5gBMjTezGAJN",
 		"/dnsaddr/relay.libp2p.io/p2p/QmQCU2EcMqAqQPR2i9bChDtGNJchTbq5TbXJJ16u19uLTa",
 	}
+	config.Network.PinnedRelays = []string{}
 	config.Network.STUNServers = []string{
 		"stun:stun.l.google.com:19302",
 		"stun:stun1.l.google.com:19302",
@@ -71,22 +216,69 @@ func DefaultConfig() *Config {
 	config.Network.EnableRelay = true
 	config.Network.EnableNAT = true
 	config.Network.EnableHolePunch = true
+	config.Network.PreferTransport = ""
+	config.Network.RelayMode = ""
 
 	// Настройки чата по умолчанию
 	config.Chat.MaxMessageLength = 1000
 	config.Chat.MessageHistory = 100
 	config.Chat.AutoSave = true
+	config.Chat.Prewarm = true
+	config.Chat.PrewarmRecent = 5
+
+	// Настройки передачи файлов по умолчанию
+	config.Transfers.DownloadDir = "" // пусто означает ~/.owlwhisper/downloads
+	config.Transfers.AutoAccept = false
+	config.Transfers.AutoAcceptMaxSizeMB = 25
+	config.Transfers.AutoAcceptPeers = []string{}
+	config.Transfers.AutoAcceptExtensions = []string{"jpg", "jpeg", "png", "gif", "txt", "pdf"}
+	config.Transfers.MaxIncomingSizeMB = 4096
+	config.Transfers.BlockedExtensions = []string{}
+	config.Transfers.WarnedExtensions = []string{}
 
 	// Настройки безопасности по умолчанию
 	config.Security.EnableTLS = true
 	config.Security.EnableNoise = false
 	config.Security.MinTLSVersion = "1.3"
 
+	// Настройки приватности по умолчанию
+	config.Privacy.LastSeen = "everyone"
+	config.Privacy.HideIP = false
+	config.Privacy.Stealth = false
+	config.Privacy.LANNickname = false
+	config.Privacy.ContactRequestDifficulty = 20
+	config.Privacy.ContactRequestsPerPeer = 3
+	config.Privacy.ContactRequestsPerHour = 30
+
+	// Настройки обновлений по умолчанию
+	config.Updates.Enabled = false
+	config.Updates.ManifestURL = ""
+	config.Updates.PublicKey = ""
+	config.Updates.CheckIntervalHours = 24
+
+	// Настройки резервного копирования по умолчанию
+	config.Backups.Enabled = false
+	config.Backups.Destination = ""
+	config.Backups.IntervalHours = 24
+	config.Backups.Keep = 7
+
+	// Непрочитанные письма в почтовом ящике хранятся неделю
+	config.Mailbox.RetentionHours = 7 * 24
+
+	// Проверки здоровья по умолчанию выключены
+	config.Daemon.HealthListen = ""
+
 	// Настройки логирования по умолчанию
 	config.Logging.Level = "info"
 	config.Logging.OutputFile = ""
 	config.Logging.Console = true
 
+	// Настройки уведомлений по умолчанию
+	config.Notifications.Enabled = true
+	config.Notifications.DoNotDisturb = false
+	config.Notifications.QuietFrom = ""
+	config.Notifications.QuietTo = ""
+
 	// Настройки UI по умолчанию
 	config.UI.Theme = "default"
 	config.UI.ShowTimestamps = true
@@ -95,16 +287,27 @@ func DefaultConfig() *Config {
 	return config
 }
 
+// DefaultDir возвращает директорию данных приложения (~/.owlwhisper)
+func DefaultDir() string {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return filepath.Join(os.TempDir(), "owlwhisper")
+	}
+	return filepath.Join(homeDir, ".owlwhisper")
+}
+
+// DefaultPath возвращает путь к файлу конфигурации по умолчанию
+func DefaultPath() string {
+	return filepath.Join(DefaultDir(), "config.json")
+}
+
 // LoadConfig загружает конфигурацию из файла
 func LoadConfig(configPath string) (*Config, error) {
 	config := DefaultConfig()
 
 	if configPath == "" {
-		// Ищем конфиг в стандартных местах
-		homeDir, err := os.UserHomeDir()
-		if err == nil {
-			configPath = filepath.Join(homeDir, ".owlwhisper", "config.json")
-		}
+		// Ищем конфиг в стандартном месте
+		configPath = DefaultPath()
 	}
 
 	if configPath != "" {
@@ -120,14 +323,121 @@ func LoadConfig(configPath string) (*Config, error) {
 	return config, nil
 }
 
+// Validate проверяет значения конфигурации перед сохранением
+func (c *Config) Validate() error {
+	if c.Network.ListenPort < 0 || c.Network.ListenPort > 65535 {
+		return fmt.Errorf("некорректный порт: %d", c.Network.ListenPort)
+	}
+	if utf8.RuneCountInString(c.Profile.Status) > 140 {
+		return fmt.Errorf("статус длиннее 140 символов")
+	}
+	if c.Chat.MaxMessageLength <= 0 {
+		return fmt.Errorf("максимальная длина сообщения должна быть больше нуля")
+	}
+	if c.Chat.PrewarmRecent < 0 {
+		return fmt.Errorf("число прогреваемых диалогов не может быть отрицательным")
+	}
+	if c.Chat.MessageHistory < 0 {
+		return fmt.Errorf("размер истории не может быть отрицательным")
+	}
+	if c.Transfers.AutoAcceptMaxSizeMB < 0 {
+		return fmt.Errorf("лимит автоприема не может быть отрицательным")
+	}
+	if c.Transfers.MaxIncomingSizeMB < 0 {
+		return fmt.Errorf("лимит размера входящих файлов не может быть отрицательным")
+	}
+	if c.Transfers.DownloadDir != "" && !filepath.IsAbs(c.Transfers.DownloadDir) {
+		return fmt.Errorf("директория загрузок должна быть абсолютным путем: %s", c.Transfers.DownloadDir)
+	}
+	switch c.Network.PreferTransport {
+	case "", "quic", "tcp":
+	default:
+		return fmt.Errorf("некорректный транспорт: %s", c.Network.PreferTransport)
+	}
+	switch c.Network.RelayMode {
+	case "", "direct", "relay":
+	default:
+		return fmt.Errorf("некорректный режим ретрансляции: %s", c.Network.RelayMode)
+	}
+	if c.Privacy.HideIP && !c.Network.EnableRelay {
+		return fmt.Errorf("режим скрытия IP требует включенной ретрансляции")
+	}
+	if c.Network.PeerCacheSize < 0 {
+		return fmt.Errorf("размер кэша пиров не может быть отрицательным")
+	}
+	if c.Network.WebSocketPort < 0 || c.Network.WebSocketPort > 65535 {
+		return fmt.Errorf("некорректный порт WebSocket: %d", c.Network.WebSocketPort)
+	}
+	for id, o := range c.Network.Obfuscation {
+		if o.Host == "" || o.Port < 0 || o.Port > 65535 {
+			return fmt.Errorf("некорректная маскировка для %s", id)
+		}
+	}
+	if len(c.Privacy.UserAgent) > 128 {
+		return fmt.Errorf("строка клиента длиннее 128 байт")
+	}
+	switch c.Privacy.LastSeen {
+	case "", "everyone", "contacts", "nobody":
+	default:
+		return fmt.Errorf("некорректная политика last seen: %s", c.Privacy.LastSeen)
+	}
+	// Отправители отказываются вычислять штамп сложнее core.MaxStampDifficulty
+	if c.Privacy.ContactRequestDifficulty < 0 || c.Privacy.ContactRequestDifficulty > core.MaxStampDifficulty {
+		return fmt.Errorf("сложность proof-of-work должна быть от 0 до %d бит", core.MaxStampDifficulty)
+	}
+	if c.Privacy.ContactRequestsPerPeer < 0 || c.Privacy.ContactRequestsPerHour < 0 {
+		return fmt.Errorf("лимиты запросов не могут быть отрицательными")
+	}
+	if c.Daemon.HealthListen != "" {
+		if _, _, err := net.SplitHostPort(c.Daemon.HealthListen); err != nil {
+			return fmt.Errorf("некорректный адрес проверок здоровья %q: %w", c.Daemon.HealthListen, err)
+		}
+	}
+	for _, role := range c.Daemon.ServerRoles {
+		switch role {
+		case "relay", "bootstrap":
+		default:
+			return fmt.Errorf("некорректная роль сервера: %s", role)
+		}
+	}
+	if slices.Contains(c.Daemon.ServerRoles, "relay") && (!c.Network.EnableRelay || c.Privacy.HideIP) {
+		return fmt.Errorf("роль relay требует network.enable_relay и несовместима со скрытием IP")
+	}
+	if len(c.Mailbox.ServeFor) > 0 && c.Mailbox.RetentionHours <= 0 {
+		return fmt.Errorf("срок хранения писем в почтовом ящике должен быть положительным")
+	}
+	if c.Updates.Enabled && (c.Updates.ManifestURL == "" || c.Updates.PublicKey == "") {
+		return fmt.Errorf("для проверки обновлений нужны адрес манифеста и ключ издателя")
+	}
+	if (c.Notifications.QuietFrom == "") != (c.Notifications.QuietTo == "") {
+		return fmt.Errorf("тихие часы задаются парой начало/конец")
+	}
+	for _, value := range []string{c.Notifications.QuietFrom, c.Notifications.QuietTo} {
+		if _, err := time.Parse("15:04", value); value != "" && err != nil {
+			return fmt.Errorf("некорректное время тихих часов %q, ожидается ЧЧ:ММ", value)
+		}
+	}
+	return nil
+}
+
+// Clone возвращает независимую копию конфигурации
+func (c *Config) Clone() *Config {
+	clone := &Config{}
+	data, err := json.Marshal(c)
+	if err == nil {
+		err = json.Unmarshal(data, clone)
+	}
+	if err != nil {
+		// Конфигурация состоит только из сериализуемых полей
+		panic(fmt.Sprintf("не удалось скопировать конфигурацию: %v", err))
+	}
+	return clone
+}
+
 // SaveConfig сохраняет конфигурацию в файл
 func (c *Config) SaveConfig(configPath string) error {
 	if configPath == "" {
-		homeDir, err := os.UserHomeDir()
-		if err != nil {
-			return err
-		}
-		configPath = filepath.Join(homeDir, ".owlwhisper", "config.json")
+		configPath = DefaultPath()
 	}
 
 	// Создаем директорию если не существует
@@ -0,0 +1,30 @@
+package interfaces
+
+// Виды уведомлений
+const (
+	NotificationMessage  = "message"
+	NotificationCall     = "call"
+	NotificationFile     = "file"
+	NotificationTransfer = "transfer"
+)
+
+// Notification представляет уведомление для пользователя
+type Notification struct {
+	Title  string `json:"title"`
+	Body   string `json:"body"`
+	PeerID string `json:"peer_id,omitempty"`
+	Urgent bool   `json:"urgent"`
+	// Mention - уведомление адресовано пользователю лично (личное сообщение
+	// или упоминание в группе); учитывается режимом "только упоминания"
+	Mention bool   `json:"mention"`
+	Kind    string `json:"kind"` // NotificationMessage, NotificationCall, ...
+}
+
+// INotificationService определяет интерфейс для показа уведомлений фронтендом
+type INotificationService interface {
+	// Notify показывает уведомление
+	Notify(notification Notification) error
+
+	// Available сообщает, может ли сервис показывать уведомления в этой системе
+	Available() bool
+}
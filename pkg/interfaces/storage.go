@@ -14,6 +14,18 @@ type Message struct {
 	Timestamp time.Time `json:"timestamp"`
 	Type      string    `json:"type"` // "text", "file", etc.
 	IsRead    bool      `json:"is_read"`
+	// ImportedFrom - источник импортированного сообщения ("telegram", "csv")
+	ImportedFrom string `json:"imported_from,omitempty"`
+	// Attachment - ссылка на вложение сообщения типа "file"; по хешу файл
+	// находится в хранилище вложений или запрашивается у пиров заново
+	Attachment *Attachment `json:"attachment,omitempty"`
+}
+
+// Attachment - ссылка на вложение по содержимому, не зависящая от расположения файла
+type Attachment struct {
+	Hash string `json:"hash"` // SHA-256 содержимого
+	Name string `json:"name"`
+	Size int64  `json:"size"`
 }
 
 // Contact представляет контакт пользователя
@@ -23,6 +35,91 @@ type Contact struct {
 	AddedAt  time.Time `json:"added_at"`
 	LastSeen time.Time `json:"last_seen"`
 	IsOnline bool      `json:"is_online"`
+	// RelayMode - политика ретрансляции для контакта: "" (как везде), "auto"
+	// (напрямую или через ретранслятор), "direct" (только напрямую) или
+	// "relay" (только через ретранслятор; IP скрывает только hide_ip)
+	RelayMode string `json:"relay_mode,omitempty"`
+}
+
+// Draft - неотправленный текст диалога. UpdatedAt позволяет выбрать
+// более свежий черновик при синхронизации между устройствами
+type Draft struct {
+	PeerID    string    `json:"peer_id"`
+	Content   string    `json:"content"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// MessageSearchFilters ограничивают поиск по истории сообщений
+type MessageSearchFilters struct {
+	FromPeer     string    `json:"from_peer,omitempty"`    // только сообщения этого отправителя
+	Conversation string    `json:"conversation,omitempty"` // только диалог с этим пиром
+	Since        time.Time `json:"since,omitempty"`
+	Until        time.Time `json:"until,omitempty"`
+	Limit        int       `json:"limit,omitempty"` // 0 - без ограничения
+	Offset       int       `json:"offset,omitempty"`
+}
+
+// MessageHit - найденное сообщение с фрагментом текста вокруг совпадения
+type MessageHit struct {
+	Message *Message `json:"message"`
+	Snippet string   `json:"snippet"`
+}
+
+// MessageSearchResult - страница результатов поиска, от новых к старым
+type MessageSearchResult struct {
+	Hits  []MessageHit `json:"hits"`
+	Total int          `json:"total"`
+}
+
+// ConversationUsage - место, занятое одним диалогом
+type ConversationUsage struct {
+	PeerID       string `json:"peer_id"`
+	Messages     int    `json:"messages"`
+	MessageBytes int64  `json:"message_bytes"`
+	MediaFiles   int    `json:"media_files"`
+	MediaBytes   int64  `json:"media_bytes"`
+}
+
+// StorageUsage - сводка занятого места, диалоги упорядочены по убыванию размера
+type StorageUsage struct {
+	Conversations []ConversationUsage `json:"conversations"`
+	MessageBytes  int64               `json:"message_bytes"`
+	MediaBytes    int64               `json:"media_bytes"`
+	// HistoryFileBytes - размер файла истории на диске; разница с MessageBytes
+	// показывает, сколько освободит Vacuum
+	HistoryFileBytes int64 `json:"history_file_bytes"`
+}
+
+// CleanupResult - итог операции очистки
+type CleanupResult struct {
+	Messages   int   `json:"messages"`
+	Files      int   `json:"files"`
+	FreedBytes int64 `json:"freed_bytes"`
+}
+
+// CleanupProgressFunc получает число обработанных записей и их общее количество
+type CleanupProgressFunc func(done, total int)
+
+// IMediaRepository определяет интерфейс хранилища вложений с дедупликацией
+// по SHA-256 содержимого
+type IMediaRepository interface {
+	// Store переносит файл в хранилище и возвращает путь сохраненной копии
+	Store(ctx context.Context, path, hash string) (string, error)
+
+	// AddRef отмечает, что вложение используется ссылкой ref (ID сообщения)
+	AddRef(ctx context.Context, hash, ref string) error
+
+	// Release снимает ссылку и удаляет вложение, на которое больше никто не ссылается
+	Release(ctx context.Context, ref string) (freed int64, found bool, err error)
+
+	// Path возвращает путь к копии вложения, если она есть
+	Path(ctx context.Context, hash string) (string, bool)
+
+	// Owns сообщает, что путь указывает внутрь хранилища вложений
+	Owns(path string) bool
+
+	// CollectGarbage удаляет вложения без ссылок
+	CollectGarbage(ctx context.Context, progress CleanupProgressFunc) (*CleanupResult, error)
 }
 
 // IMessageRepository определяет интерфейс для работы с сообщениями
@@ -39,11 +136,212 @@ type IMessageRepository interface {
 	// DeleteMessage удаляет сообщение по ID
 	DeleteMessage(ctx context.Context, messageID string) error
 
+	// SaveDraft сохраняет черновик диалога; пустой текст удаляет черновик
+	SaveDraft(ctx context.Context, peerID, content string) error
+
+	// GetDraft возвращает черновик диалога или nil, если его нет
+	GetDraft(ctx context.Context, peerID string) (*Draft, error)
+
+	// SearchMessages ищет сообщения, содержащие все слова запроса
+	SearchMessages(ctx context.Context, query string, filters MessageSearchFilters) (*MessageSearchResult, error)
+
 	// GetUnreadCount возвращает количество непрочитанных сообщений
 	GetUnreadCount(ctx context.Context, peerID string) (int, error)
 
+	// GetUnreadCounts возвращает количество непрочитанных сообщений по диалогам
+	GetUnreadCounts(ctx context.Context) (map[string]int, error)
+
+	// GetLastRead возвращает время последнего прочитанного сообщения от пира
+	GetLastRead(ctx context.Context, peerID string) (time.Time, error)
+
 	// MarkAsRead отмечает сообщения как прочитанные
 	MarkAsRead(ctx context.Context, peerID string) error
+
+	// MarkConversationRead отмечает прочитанными сообщения от пира не новее upTo
+	MarkConversationRead(ctx context.Context, peerID string, upTo time.Time) error
+
+	// FindAttachment возвращает сообщения, ссылающиеся на вложение с хешем hash
+	FindAttachment(ctx context.Context, hash string) ([]*Message, error)
+
+	// GetStorageUsage считает место, занятое историей и вложениями, по диалогам
+	GetStorageUsage(ctx context.Context, self string) (*StorageUsage, error)
+
+	// ClearConversation удаляет все сообщения диалога и, если removeMedia, его вложения
+	ClearConversation(ctx context.Context, peer1, peer2 string, removeMedia bool, progress CleanupProgressFunc) (*CleanupResult, error)
+
+	// DeleteMediaOlderThan удаляет с диска вложения из сообщений старше before
+	DeleteMediaOlderThan(ctx context.Context, before time.Time, progress CleanupProgressFunc) (*CleanupResult, error)
+
+	// Vacuum переписывает файл истории без битых и удаленных записей
+	Vacuum(ctx context.Context) (*CleanupResult, error)
+}
+
+// ScheduledMessage - сообщение, ожидающее отправки в заданное время.
+// Сообщение самому себе служит напоминанием
+type ScheduledMessage struct {
+	ID        string    `json:"id"`
+	ToPeer    string    `json:"to_peer"`
+	Content   string    `json:"content"`
+	SendAt    time.Time `json:"send_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// IOutboxRepository определяет интерфейс хранилища отложенных сообщений
+type IOutboxRepository interface {
+	// SaveScheduled сохраняет отложенное сообщение
+	SaveScheduled(ctx context.Context, message *ScheduledMessage) error
+
+	// DeleteScheduled удаляет отложенное сообщение по ID
+	DeleteScheduled(ctx context.Context, id string) error
+
+	// GetScheduled возвращает все отложенные сообщения по времени отправки
+	GetScheduled(ctx context.Context) ([]*ScheduledMessage, error)
+}
+
+// ConversationPrefs - настройки уведомлений диалога
+type ConversationPrefs struct {
+	PeerID       string    `json:"peer_id"`
+	MutedUntil   time.Time `json:"muted_until,omitempty"`
+	MutedForever bool      `json:"muted_forever"`
+	// MentionsOnly - в групповых диалогах уведомлять только об упоминаниях
+	MentionsOnly bool `json:"mentions_only"`
+	// Padding - дополнять сообщения диалога до постоянного размера
+	Padding bool `json:"padding,omitempty"`
+	// CoverTraffic - средний интервал фиктивных сообщений; 0 - выключены
+	CoverTraffic time.Duration `json:"cover_traffic,omitempty"`
+	// SealedSender - отправлять сообщения со скрытым отправителем
+	SealedSender bool `json:"sealed_sender,omitempty"`
+}
+
+// Muted сообщает, подавлены ли уведомления диалога в момент t.
+// mentioned - сообщение упоминает пользователя
+func (p *ConversationPrefs) Muted(t time.Time, mentioned bool) bool {
+	if p == nil {
+		return false
+	}
+	if p.MutedForever || t.Before(p.MutedUntil) {
+		return true
+	}
+	return p.MentionsOnly && !mentioned
+}
+
+// IPreferenceRepository определяет интерфейс хранилища настроек диалогов
+type IPreferenceRepository interface {
+	// SavePrefs сохраняет настройки диалога
+	SavePrefs(ctx context.Context, prefs *ConversationPrefs) error
+
+	// GetPrefs возвращает настройки диалога или nil, если они не заданы
+	GetPrefs(ctx context.Context, peerID string) (*ConversationPrefs, error)
+
+	// GetAllPrefs возвращает настройки всех диалогов
+	GetAllPrefs(ctx context.Context) ([]*ConversationPrefs, error)
+}
+
+// IPeerTagRepository определяет интерфейс хранилища меток пиров
+type IPeerTagRepository interface {
+	// SavePeerTags заменяет метки пира; пустой список удаляет запись
+	SavePeerTags(ctx context.Context, peerID string, tags []string) error
+
+	// GetAllPeerTags возвращает метки всех пиров по PeerID
+	GetAllPeerTags(ctx context.Context) (map[string][]string, error)
+}
+
+// ContactMetadataValue - произвольное небольшое значение метаданных контакта
+// (звук уведомления, цвет, псевдоним, заметки). UpdatedAt позволяет выбрать
+// более свежее значение при синхронизации между устройствами
+type ContactMetadataValue struct {
+	Value     []byte    `json:"value"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// IContactMetadataRepository определяет интерфейс хранилища метаданных контактов
+type IContactMetadataRepository interface {
+	// SaveContactMetadata заменяет метаданные пира; пустой набор удаляет запись
+	SaveContactMetadata(ctx context.Context, peerID string, metadata map[string]ContactMetadataValue) error
+
+	// GetAllContactMetadata возвращает метаданные всех пиров по PeerID
+	GetAllContactMetadata(ctx context.Context) (map[string]map[string]ContactMetadataValue, error)
+}
+
+// AttestationRecord - опубликованное подтверждение личности: подписанный
+// текст и адрес, по которому он размещен во внешнем сервисе
+type AttestationRecord struct {
+	Statement string `json:"statement"`
+	ProofURL  string `json:"proof_url,omitempty"`
+}
+
+// IAttestationRepository определяет интерфейс хранилища своих подтверждений личности
+type IAttestationRepository interface {
+	// SaveAttestations заменяет список подтверждений
+	SaveAttestations(ctx context.Context, records []AttestationRecord) error
+
+	// GetAttestations возвращает сохраненные подтверждения
+	GetAttestations(ctx context.Context) ([]AttestationRecord, error)
+}
+
+// KeyTransitionRecord - запись о смене ключа личности пира, подписанная
+// старым и новым ключом
+type KeyTransitionRecord struct {
+	OldPeerID    string    `json:"old_peer_id"`
+	NewPeerID    string    `json:"new_peer_id"`
+	IssuedAt     time.Time `json:"issued_at"`
+	OldSignature []byte    `json:"old_signature"`
+	NewSignature []byte    `json:"new_signature"`
+}
+
+// IKeyTransitionRepository определяет интерфейс хранилища доверия: принятых
+// смен ключей контактов и своих смен ключа
+type IKeyTransitionRepository interface {
+	// SaveKeyTransition сохраняет запись; запись с тем же старым PeerID заменяется
+	SaveKeyTransition(ctx context.Context, record KeyTransitionRecord) error
+
+	// GetKeyTransitions возвращает все сохраненные записи
+	GetKeyTransitions(ctx context.Context) ([]KeyTransitionRecord, error)
+}
+
+// ProfileRecord - подписанная запись профиля контакта: ключ для писем и
+// почтовые ящики, через которые ему можно писать, пока он не в сети
+type ProfileRecord struct {
+	PeerID     string    `json:"peer_id"`
+	MailboxKey []byte    `json:"mailbox_key"`
+	Mailboxes  []string  `json:"mailboxes,omitempty"`
+	UpdatedAt  time.Time `json:"updated_at"`
+	Signature  []byte    `json:"signature"`
+}
+
+// IProfileRecordRepository определяет интерфейс хранилища записей профиля контактов
+type IProfileRecordRepository interface {
+	// SaveProfileRecord сохраняет запись, заменяя запись того же пира
+	SaveProfileRecord(ctx context.Context, record ProfileRecord) error
+
+	// GetProfileRecords возвращает все сохраненные записи
+	GetProfileRecords(ctx context.Context) ([]ProfileRecord, error)
+}
+
+// MailboxEnvelope - зашифрованное письмо, которое почтовый ящик хранит для
+// владельца, пока тот не в сети. Содержимое и отправитель ящику не видны
+type MailboxEnvelope struct {
+	ID string `json:"id"`
+	// To - метка владельца (HMAC от PeerID на ключе ящика), а не сам PeerID
+	To         string    `json:"to"`
+	Ciphertext []byte    `json:"ciphertext"`
+	StoredAt   time.Time `json:"stored_at"`
+}
+
+// IMailboxRepository определяет интерфейс хранилища почтового ящика
+type IMailboxRepository interface {
+	// SaveEnvelope сохраняет письмо
+	SaveEnvelope(ctx context.Context, envelope MailboxEnvelope) error
+
+	// GetEnvelopes возвращает письма владельца to, от старых к новым
+	GetEnvelopes(ctx context.Context, to string) ([]MailboxEnvelope, error)
+
+	// DeleteEnvelope удаляет доставленное письмо
+	DeleteEnvelope(ctx context.Context, id string) error
+
+	// DeleteExpiredEnvelopes удаляет письма, сохраненные раньше before,
+	// и возвращает их количество
+	DeleteExpiredEnvelopes(ctx context.Context, before time.Time) (int, error)
 }
 
 // IContactRepository определяет интерфейс для работы с контактами